@@ -18,10 +18,13 @@
 package main
 
 import (
+	"fmt"
 	"os"
-	"sync"
 
+	"github.com/tricksterproxy/trickster/pkg/config"
 	"github.com/tricksterproxy/trickster/pkg/runtime"
+	"github.com/tricksterproxy/trickster/pkg/server"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
 )
 
 var (
@@ -37,11 +40,65 @@ const (
 )
 
 var fatalStartupErrors = true
-var wg = &sync.WaitGroup{}
 
 func main() {
 	runtime.ApplicationName = applicationName
 	runtime.ApplicationVersion = applicationVersion
-	runConfig(nil, wg, nil, nil, os.Args[1:], fatalStartupErrors)
-	wg.Wait()
+	runtime.GitCommitID = applicationGitCommitID
+	runtime.BuildTime = applicationBuildTime
+	runtime.GoVersion = applicationGoVersion
+	runtime.GoArch = applicationGoArch
+	s, err := runServer(os.Args[1:], fatalStartupErrors)
+	if err != nil {
+		return
+	}
+	s.Wait()
+}
+
+// runServer loads the config from the command line arguments, and starts a server with
+// it. The -version and -validate-config commands exit once they are done
+func runServer(args []string, errorsFatal bool) (*server.Server, error) {
+
+	metrics.BuildInfo.WithLabelValues(applicationGoVersion,
+		applicationGitCommitID, applicationVersion).Set(1)
+
+	conf, flags, err := config.Load(runtime.ApplicationName, runtime.ApplicationVersion, args)
+	if err != nil {
+		fmt.Println("\nERROR: Could not load configuration:", err.Error())
+		if flags != nil && !flags.ValidateConfig {
+			PrintUsage()
+		}
+		handleStartupError(errorsFatal)
+		return nil, err
+	}
+
+	// if it's a -version command, print version and exit
+	if flags.PrintVersion {
+		PrintVersion()
+		os.Exit(0)
+	}
+
+	if flags.ValidateConfig {
+		if err = server.ValidateConfig(conf); err != nil {
+			fmt.Println("ERROR: Could not load configuration: " + err.Error())
+			handleStartupError(errorsFatal)
+			return nil, err
+		}
+		fmt.Println("Trickster configuration validation succeeded.")
+		os.Exit(0)
+	}
+
+	s := server.New(conf, server.WithArgs(args), server.WithFatalStartupErrors(errorsFatal))
+	if err = s.Start(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// handleStartupError records the failed config load, and exits when errors are fatal
+func handleStartupError(errorsFatal bool) {
+	metrics.LastReloadSuccessful.Set(0)
+	if errorsFatal {
+		os.Exit(1)
+	}
 }
@@ -17,7 +17,11 @@
 package main
 
 import (
-	"sync"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
 	"testing"
 )
 
@@ -27,12 +31,58 @@ func TestMain(t *testing.T) {
 	// Successful test criteria is that the call to main returns without timing out on wg.Wait()
 }
 
-func TestRunConfig(t *testing.T) {
-	wg := &sync.WaitGroup{}
-	runConfig(nil, wg, nil, nil, []string{}, false)
+func TestRunServer(t *testing.T) {
+	runServer([]string{}, false)
 
-	runConfig(nil, wg, nil, nil, []string{"-version"}, false)
+	runServer([]string{"-version"}, false)
 
-	runConfig(nil, wg, nil, nil, []string{"-origin-type", "rpc", "-origin-url", "http://tricksterproxy.io"}, false)
+	s, err := runServer([]string{"-origin-type", "rpc", "-origin-url", "http://tricksterproxy.io"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Stop()
+}
+
+// TestValidateConfigUnknownKeys runs -validate-config in a subprocess, since it exits
+func TestValidateConfigUnknownKeys(t *testing.T) {
+
+	if path := os.Getenv("TRK_TEST_VALIDATE_CONFIG"); path != "" {
+		runServer([]string{"-validate-config", "-config", path}, true)
+		return
+	}
+
+	tests := []struct {
+		conf     string
+		exitCode int
+	}{
+		{"[frontend]\nlisten_port = 8480\n", 0},
+		{"[frontend]\nlisten_prot = 8480\n", 1},
+	}
+
+	td, err := ioutil.TempDir("", "trickster-validate-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
 
+	for i, test := range tests {
+		path := filepath.Join(td, strconv.Itoa(i)+".conf")
+		conf := test.conf + "[origins]\n[origins.default]\norigin_type = 'rpc'\n" +
+			"origin_url = 'http://127.0.0.1/'\n"
+		if err := ioutil.WriteFile(path, []byte(conf), 0600); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(os.Args[0], "-test.run=^TestValidateConfigUnknownKeys$")
+		cmd.Env = append(os.Environ(), "TRK_TEST_VALIDATE_CONFIG="+path)
+		out, err := cmd.CombinedOutput()
+		exitCode := 0
+		if ee, ok := err.(*exec.ExitError); ok {
+			exitCode = ee.ExitCode()
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if exitCode != test.exitCode {
+			t.Errorf("test %d: expected exit code %d got %d: %s", i, test.exitCode, exitCode, out)
+		}
+	}
 }
@@ -25,7 +25,7 @@ import (
 const usageText = `
 Trickster Usage:
 
- You must provide -version, -config or both -origin-url and -origin-type.
+ You must provide -version, -config, -simulate or both -origin-url and -origin-type.
 
  Print Version Info:
  trickster -version
@@ -53,6 +53,9 @@ Trickster Usage:
  Simple ClickHouse Accelerator listening on 8123:
    trickster -origin-url http://clickhouse.example.com:8123/ -origin-type clickhouse -proxy-port 8123
 
+ Simulated Prometheus and HTTP origins, for evaluating Trickster without a real backend:
+   trickster -simulate
+
 ------
 
 Trickster listens on port 8480 by default. Set in a config file, or override using -proxy-port.
@@ -0,0 +1,355 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package admin provides the versioned Admin API, which consolidates Trickster's
+// operational actions behind bearer token authentication, with each request
+// recorded in the application log for auditing
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	ao "github.com/tricksterproxy/trickster/pkg/config/admin/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/faults"
+	"github.com/tricksterproxy/trickster/pkg/proxy/handlers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/health"
+	"github.com/tricksterproxy/trickster/pkg/runtime"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/middleware"
+)
+
+// Version is the version of the Admin API
+const Version = "v1"
+
+// adminListenerName is the name of the listener that serves the Admin API, which
+// cannot be drained through the Admin API
+const adminListenerName = "reloadListener"
+
+// ReloadFunc reloads the running configuration if it has changed, and returns true
+// if the configuration was reloaded
+type ReloadFunc func() (bool, error)
+
+// ErrNotStaged is returned when there is no staged configuration to promote or discard
+var ErrNotStaged = errors.New("no configuration is staged")
+
+// StagedConfig describes a reloaded configuration that is served on the canary listener
+// until it is promoted or discarded
+type StagedConfig struct {
+	CanaryAddress string    `json:"canary_address"`
+	StagedAt      time.Time `json:"staged_at"`
+}
+
+// Stager is the interface for promoting a staged configuration to the main listeners,
+// or discarding it
+type Stager interface {
+	// Staged returns the staged configuration, or nil when none is staged
+	Staged() *StagedConfig
+	Promote() error
+	Discard() error
+}
+
+// Listeners is the interface for draining and undraining Trickster's listeners
+type Listeners interface {
+	Drain(listenerName string) error
+	Undrain(listenerName string) error
+	DrainedStatus() map[string]bool
+}
+
+// Stats is a summary of the running instance
+type Stats struct {
+	Version     string                    `json:"version"`
+	Maintenance bool                      `json:"maintenance"`
+	Listeners   map[string]bool           `json:"listeners_drained"`
+	Caches      map[string]string         `json:"caches"`
+	Runtime     *handlers.RuntimeSnapshot `json:"runtime"`
+}
+
+// API is the Admin API
+type API struct {
+	path      string
+	handler   http.Handler
+	reload    ReloadFunc
+	caches    map[string]cache.Cache
+	listeners Listeners
+	stager    Stager
+	log       *tl.Logger
+	mux       *http.ServeMux
+}
+
+type actionFunc func(r *http.Request) (int, interface{})
+
+// New returns a new Admin API. The staging actions are only served when the stager
+// is not nil
+func New(o *ao.Options, reload ReloadFunc, caches map[string]cache.Cache,
+	listeners Listeners, stager Stager, log *tl.Logger) *API {
+	a := &API{
+		path:      strings.TrimSuffix(o.HandlerPath, "/") + "/" + Version + "/",
+		reload:    reload,
+		caches:    caches,
+		listeners: listeners,
+		stager:    stager,
+		log:       log,
+		mux:       http.NewServeMux(),
+	}
+	a.handle("reload", a.reloadConfig, http.MethodPost)
+	a.handle("purge", a.purge, http.MethodPost)
+	a.handle("drain", a.drain, http.MethodPost)
+	a.handle("undrain", a.undrain, http.MethodPost)
+	a.handle("maintenance", a.maintenance, http.MethodGet, http.MethodPost)
+	a.handle("stats", a.stats, http.MethodGet)
+	if stager != nil {
+		a.handle("staged", a.staged, http.MethodGet)
+		a.handle("promote", a.promote, http.MethodPost)
+		a.handle("discard", a.discard, http.MethodPost)
+	}
+	if o.FaultInjection() {
+		a.handle("faults", a.faults, http.MethodGet, http.MethodPost, http.MethodDelete)
+	}
+	a.handler = middleware.BearerAuth("trickster-admin", o.AuthToken, a.mux,
+		http.HandlerFunc(a.unauthorized))
+	return a
+}
+
+// Path returns the path prefix of the API's versioned endpoints
+func (a *API) Path() string {
+	return a.path
+}
+
+// ServeHTTP authenticates the request and serves it with the requested action
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.handler.ServeHTTP(w, r)
+}
+
+func (a *API) unauthorized(w http.ResponseWriter, r *http.Request) {
+	a.log.Warn("admin api request unauthorized", tl.Pairs{"path": r.URL.Path,
+		"method": r.Method, "clientIP": clientIP(r)})
+	respond(w, http.StatusUnauthorized, errorBody(http.StatusText(http.StatusUnauthorized)))
+}
+
+// handle registers the action at the API's path, and records each request to it
+// in the audit log
+func (a *API) handle(action string, f actionFunc, methods ...string) {
+	a.mux.HandleFunc(a.path+action, func(w http.ResponseWriter, r *http.Request) {
+		var code int
+		var body interface{}
+		if allowed(r.Method, methods) {
+			code, body = f(r)
+		} else {
+			w.Header().Set("Allow", strings.Join(methods, ", "))
+			code = http.StatusMethodNotAllowed
+			body = errorBody(http.StatusText(code))
+		}
+		respond(w, code, body)
+		a.log.Info("admin api request", tl.Pairs{"action": action, "method": r.Method,
+			"query": r.URL.RawQuery, "clientIP": clientIP(r), "status": code})
+	})
+}
+
+func (a *API) reloadConfig(r *http.Request) (int, interface{}) {
+	reloaded, err := a.reload()
+	if err != nil {
+		return http.StatusInternalServerError, errorBody(err.Error())
+	}
+	if a.stager != nil {
+		return http.StatusOK, map[string]bool{"reloaded": reloaded,
+			"staged": a.stager.Staged() != nil}
+	}
+	return http.StatusOK, map[string]bool{"reloaded": reloaded}
+}
+
+func (a *API) staged(r *http.Request) (int, interface{}) {
+	sc := a.stager.Staged()
+	if sc == nil {
+		return http.StatusNotFound, errorBody(ErrNotStaged.Error())
+	}
+	return http.StatusOK, sc
+}
+
+func (a *API) promote(r *http.Request) (int, interface{}) {
+	return a.unstage(a.stager.Promote, "promoted")
+}
+
+func (a *API) discard(r *http.Request) (int, interface{}) {
+	return a.unstage(a.stager.Discard, "discarded")
+}
+
+func (a *API) unstage(f func() error, result string) (int, interface{}) {
+	if err := f(); err != nil {
+		if errors.Is(err, ErrNotStaged) {
+			return http.StatusConflict, errorBody(err.Error())
+		}
+		return http.StatusInternalServerError, errorBody(err.Error())
+	}
+	return http.StatusOK, map[string]bool{result: true}
+}
+
+func (a *API) purge(r *http.Request) (int, interface{}) {
+	name := r.FormValue("cache")
+	c, ok := a.caches[name]
+	if !ok {
+		return http.StatusNotFound, errorBody("unknown cache: " + name)
+	}
+	if co := c.Configuration(); co != nil && co.ReadOnly {
+		return http.StatusConflict, errorBody("cache is read-only: " + name)
+	}
+	// FormValue has already parsed the form
+	keys := r.Form["key"]
+	if len(keys) == 0 {
+		return http.StatusBadRequest, errorBody("at least one key is required")
+	}
+	c.BulkRemove(keys)
+	return http.StatusOK, map[string]interface{}{"cache": name, "purged": keys}
+}
+
+func (a *API) drain(r *http.Request) (int, interface{}) {
+	return a.setDrained(r, true)
+}
+
+func (a *API) undrain(r *http.Request) (int, interface{}) {
+	return a.setDrained(r, false)
+}
+
+func (a *API) setDrained(r *http.Request, drained bool) (int, interface{}) {
+	name := r.FormValue("listener")
+	if name == adminListenerName {
+		return http.StatusBadRequest, errorBody("the admin api listener cannot be drained")
+	}
+	var err error
+	if drained {
+		err = a.listeners.Drain(name)
+	} else {
+		err = a.listeners.Undrain(name)
+	}
+	if err != nil {
+		return http.StatusNotFound, errorBody(err.Error() + ": " + name)
+	}
+	return http.StatusOK, map[string]interface{}{"listener": name, "drained": drained}
+}
+
+func (a *API) maintenance(r *http.Request) (int, interface{}) {
+	if r.Method == http.MethodPost {
+		enabled, err := strconv.ParseBool(r.FormValue("enabled"))
+		if err != nil {
+			return http.StatusBadRequest, errorBody("enabled must be true or false")
+		}
+		health.SetMaintenance(enabled)
+	}
+	return http.StatusOK, map[string]bool{"maintenance": health.Maintenance()}
+}
+
+func (a *API) stats(r *http.Request) (int, interface{}) {
+	s := &Stats{
+		Version:     runtime.ApplicationVersion,
+		Maintenance: health.Maintenance(),
+		Listeners:   a.listeners.DrainedStatus(),
+		Caches:      make(map[string]string, len(a.caches)),
+		Runtime:     handlers.NewRuntimeSnapshot(),
+	}
+	for k, c := range a.caches {
+		if co := c.Configuration(); co != nil {
+			s.Caches[k] = co.CacheType
+		}
+	}
+	return http.StatusOK, s
+}
+
+func (a *API) faults(r *http.Request) (int, interface{}) {
+	switch r.Method {
+	case http.MethodPost:
+		f, err := faultFromForm(r)
+		if err == nil {
+			err = faults.Set(f)
+		}
+		if err != nil {
+			return http.StatusBadRequest, errorBody(err.Error())
+		}
+	case http.MethodDelete:
+		// without a path, every fault is removed
+		if path := r.FormValue("path"); path == "" {
+			faults.Clear()
+		} else if !faults.Remove(path) {
+			return http.StatusNotFound, errorBody("no fault is set for path: " + path)
+		}
+	}
+	return http.StatusOK, map[string][]*faults.Fault{"faults": faults.List()}
+}
+
+// faultFromForm returns the Fault described by the request's form values
+func faultFromForm(r *http.Request) (*faults.Fault, error) {
+	f := &faults.Fault{Path: r.FormValue("path")}
+	ints := map[string]*int{"latency_ms": &f.LatencyMS, "error_status": &f.ErrorStatus}
+	for name, p := range ints {
+		if v := r.FormValue(name); v != "" {
+			i, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, errors.New("invalid " + name + ": " + v)
+			}
+			*p = i
+		}
+	}
+	rates := map[string]*float64{"latency_rate": &f.LatencyRate, "error_rate": &f.ErrorRate,
+		"truncate_rate": &f.TruncateRate}
+	for name, p := range rates {
+		if v := r.FormValue(name); v != "" {
+			rate, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, errors.New("invalid " + name + ": " + v)
+			}
+			*p = rate
+		}
+	}
+	return f, nil
+}
+
+func allowed(method string, methods []string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func errorBody(detail string) map[string]string {
+	return map[string]string{"error": detail}
+}
+
+func respond(w http.ResponseWriter, code int, body interface{}) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		code = http.StatusInternalServerError
+		b = []byte(`{"error":"` + http.StatusText(code) + `"}`)
+	}
+	w.Header().Set(headers.NameContentType, headers.ValueApplicationJSON)
+	w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
+	w.WriteHeader(code)
+	w.Write(b)
+}
+
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
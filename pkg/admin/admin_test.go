@@ -0,0 +1,386 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	co "github.com/tricksterproxy/trickster/pkg/cache/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/registration"
+	ao "github.com/tricksterproxy/trickster/pkg/config/admin/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/faults"
+	"github.com/tricksterproxy/trickster/pkg/proxy/health"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+const testToken = "test-token"
+
+var errUnknownListener = errors.New("unknown listener")
+
+type testListeners map[string]bool
+
+func (l testListeners) Drain(name string) error {
+	return l.set(name, true)
+}
+
+func (l testListeners) Undrain(name string) error {
+	return l.set(name, false)
+}
+
+func (l testListeners) set(name string, drained bool) error {
+	if _, ok := l[name]; !ok {
+		return errUnknownListener
+	}
+	l[name] = drained
+	return nil
+}
+
+func (l testListeners) DrainedStatus() map[string]bool {
+	return l
+}
+
+type testStager struct {
+	staged     *StagedConfig
+	promoteErr error
+}
+
+func (s *testStager) Staged() *StagedConfig {
+	return s.staged
+}
+
+func (s *testStager) Promote() error {
+	return s.unstage(s.promoteErr)
+}
+
+func (s *testStager) Discard() error {
+	return s.unstage(nil)
+}
+
+func (s *testStager) unstage(err error) error {
+	if s.staged == nil {
+		return ErrNotStaged
+	}
+	if err != nil {
+		return err
+	}
+	s.staged = nil
+	return nil
+}
+
+func testAPI(t *testing.T, reloadErr error) (*API, map[string]cache.Cache, testListeners) {
+	o := ao.NewOptions()
+	o.AuthToken = testToken
+	c := registration.NewCache("default", co.NewOptions(), tl.ConsoleLogger("error"))
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	caches := map[string]cache.Cache{"default": c}
+	l := testListeners{"httpListener": false, "reloadListener": false}
+	reload := func() (bool, error) {
+		return reloadErr == nil, reloadErr
+	}
+	return New(o, reload, caches, l, nil, tl.ConsoleLogger("error")), caches, l
+}
+
+func do(a *API, method, action, query, token string) *httptest.ResponseRecorder {
+	u := a.Path() + action
+	if query != "" {
+		u += "?" + query
+	}
+	r := httptest.NewRequest(method, u, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	return w
+}
+
+func TestPath(t *testing.T) {
+	a, _, _ := testAPI(t, nil)
+	if a.Path() != "/trickster/admin/v1/" {
+		t.Errorf("unexpected path %s", a.Path())
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	a, _, _ := testAPI(t, nil)
+
+	w := do(a, http.MethodGet, "stats", "", "")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected %d got %d", http.StatusUnauthorized, w.Code)
+	}
+	if !strings.HasPrefix(w.Header().Get("WWW-Authenticate"), "Bearer") {
+		t.Errorf("expected bearer challenge, got %s", w.Header().Get("WWW-Authenticate"))
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected json error body, got %s", ct)
+	}
+
+	w = do(a, http.MethodGet, "stats", "", "wrong")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected %d got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	w = do(a, http.MethodGet, "stats", "", testToken)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	a, _, _ := testAPI(t, nil)
+	w := do(a, http.MethodGet, "reload", "", testToken)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected %d got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+	if w.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("unexpected Allow header %s", w.Header().Get("Allow"))
+	}
+}
+
+func TestReload(t *testing.T) {
+	a, _, _ := testAPI(t, nil)
+	w := do(a, http.MethodPost, "reload", "", testToken)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	if strings.TrimSpace(w.Body.String()) != `{"reloaded":true}` {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+
+	a, _, _ = testAPI(t, errors.New("test reload error"))
+	w = do(a, http.MethodPost, "reload", "", testToken)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected %d got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	a, caches, _ := testAPI(t, nil)
+	c := caches["default"]
+	c.Store("key1", []byte("value1"), 0)
+	c.Store("key2", []byte("value2"), 0)
+
+	w := do(a, http.MethodPost, "purge", "cache=default&key=key1&key=key2", testToken)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	for _, k := range []string{"key1", "key2"} {
+		if _, _, err := c.Retrieve(k, false); err == nil {
+			t.Errorf("expected %s to be purged", k)
+		}
+	}
+
+	w = do(a, http.MethodPost, "purge", "cache=invalid&key=key1", testToken)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, w.Code)
+	}
+
+	w = do(a, http.MethodPost, "purge", "cache=default", testToken)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected %d got %d", http.StatusBadRequest, w.Code)
+	}
+
+	c.Configuration().ReadOnly = true
+	w = do(a, http.MethodPost, "purge", "cache=default&key=key1", testToken)
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected %d got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	a, _, l := testAPI(t, nil)
+
+	w := do(a, http.MethodPost, "drain", "listener=httpListener", testToken)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	if !l["httpListener"] {
+		t.Error("expected httpListener to be drained")
+	}
+
+	w = do(a, http.MethodPost, "undrain", "listener=httpListener", testToken)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	if l["httpListener"] {
+		t.Error("expected httpListener to be undrained")
+	}
+
+	w = do(a, http.MethodPost, "drain", "listener=reloadListener", testToken)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected %d got %d", http.StatusBadRequest, w.Code)
+	}
+
+	w = do(a, http.MethodPost, "drain", "listener=invalid", testToken)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+	a, _, _ := testAPI(t, nil)
+	defer health.SetMaintenance(false)
+
+	w := do(a, http.MethodPost, "maintenance", "enabled=true", testToken)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	if !health.Maintenance() {
+		t.Error("expected maintenance mode to be enabled")
+	}
+
+	w = do(a, http.MethodGet, "maintenance", "", testToken)
+	if strings.TrimSpace(w.Body.String()) != `{"maintenance":true}` {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+
+	w = do(a, http.MethodPost, "maintenance", "enabled=maybe", testToken)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected %d got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestStats(t *testing.T) {
+	a, _, _ := testAPI(t, nil)
+	w := do(a, http.MethodGet, "stats", "", testToken)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	s := &Stats{}
+	if err := json.Unmarshal(w.Body.Bytes(), s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Caches["default"] != "memory" {
+		t.Errorf("unexpected cache type %s", s.Caches["default"])
+	}
+	if len(s.Listeners) != 2 {
+		t.Errorf("expected 2 listeners got %d", len(s.Listeners))
+	}
+	if s.Runtime == nil {
+		t.Error("expected runtime snapshot")
+	}
+}
+
+func TestFaults(t *testing.T) {
+	a, _, _ := testAPI(t, nil)
+	// the faults action is only registered when fault injection is enabled
+	w := do(a, http.MethodGet, "faults", "", testToken)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, w.Code)
+	}
+
+	o := ao.NewOptions()
+	o.AuthToken = testToken
+	o.FaultInjectionEnabled = true
+	a = New(o, nil, nil, testListeners{}, nil, tl.ConsoleLogger("error"))
+	defer faults.Clear()
+
+	w = do(a, http.MethodPost, "faults",
+		"path=/prom&latency_ms=100&latency_rate=0.5&error_rate=0.1", testToken)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	l := faults.List()
+	if len(l) != 1 || l[0].Path != "/prom" || l[0].LatencyMS != 100 ||
+		l[0].ErrorStatus != faults.DefaultErrorStatus {
+		t.Errorf("unexpected faults %v", l)
+	}
+
+	for _, q := range []string{"path=/prom&error_rate=2", "path=/prom&latency_ms=x",
+		"path=prom", "path=/prom&error_status=200"} {
+		w = do(a, http.MethodPost, "faults", q, testToken)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected %d got %d", q, http.StatusBadRequest, w.Code)
+		}
+	}
+
+	w = do(a, http.MethodDelete, "faults", "path=/other", testToken)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, w.Code)
+	}
+
+	w = do(a, http.MethodDelete, "faults", "path=/prom", testToken)
+	if strings.TrimSpace(w.Body.String()) != `{"faults":[]}` {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+}
+
+func TestStaging(t *testing.T) {
+	a, _, _ := testAPI(t, nil)
+	// the staging actions are only registered with a stager
+	w := do(a, http.MethodGet, "staged", "", testToken)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, w.Code)
+	}
+
+	o := ao.NewOptions()
+	o.AuthToken = testToken
+	s := &testStager{staged: &StagedConfig{CanaryAddress: "127.0.0.1:8485"}}
+	a = New(o, func() (bool, error) { return true, nil }, nil, testListeners{}, s,
+		tl.ConsoleLogger("error"))
+
+	w = do(a, http.MethodPost, "reload", "", testToken)
+	if strings.TrimSpace(w.Body.String()) != `{"reloaded":true,"staged":true}` {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+
+	w = do(a, http.MethodGet, "staged", "", testToken)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	sc := &StagedConfig{}
+	if err := json.Unmarshal(w.Body.Bytes(), sc); err != nil {
+		t.Fatal(err)
+	}
+	if sc.CanaryAddress != "127.0.0.1:8485" {
+		t.Errorf("unexpected canary address %s", sc.CanaryAddress)
+	}
+
+	s.promoteErr = errors.New("test promote error")
+	w = do(a, http.MethodPost, "promote", "", testToken)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected %d got %d", http.StatusInternalServerError, w.Code)
+	}
+
+	s.promoteErr = nil
+	w = do(a, http.MethodPost, "promote", "", testToken)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	if strings.TrimSpace(w.Body.String()) != `{"promoted":true}` {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+
+	for _, action := range []string{"promote", "discard"} {
+		w = do(a, http.MethodPost, action, "", testToken)
+		if w.Code != http.StatusConflict {
+			t.Errorf("%s: expected %d got %d", action, http.StatusConflict, w.Code)
+		}
+	}
+	w = do(a, http.MethodGet, "staged", "", testToken)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -61,6 +61,8 @@ func (c *Cache) Connect() error {
 
 	opts := badger.DefaultOptions(c.Config.Badger.Directory)
 	opts.ValueDir = c.Config.Badger.ValueDirectory
+	// a read-only store may be shared with other processes that open it read-only
+	opts.ReadOnly = c.Config.ReadOnly
 
 	var err error
 	c.dbh, err = badger.Open(opts)
@@ -138,6 +140,9 @@ func (c *Cache) BulkRemove(cacheKeys []string) {
 
 // Close closes the Badger Cache
 func (c *Cache) Close() error {
+	if c.dbh == nil {
+		return nil
+	}
 	return c.dbh.Close()
 }
 
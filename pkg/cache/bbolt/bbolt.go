@@ -67,26 +67,42 @@ func (c *Cache) Connect() error {
 	c.lockPrefix = c.Name + ".bbolt."
 
 	var err error
-	c.dbh, err = bbolt.Open(c.Config.BBolt.Filename, 0644, &bbolt.Options{Timeout: 1 * time.Second})
+	// a read-only database is opened with a shared lock, so that other processes may read it
+	c.dbh, err = bbolt.Open(c.Config.BBolt.Filename, 0644,
+		&bbolt.Options{Timeout: 1 * time.Second, ReadOnly: c.Config.ReadOnly})
 	if err != nil {
 		return err
 	}
 
-	err = c.dbh.Update(func(tx *bbolt.Tx) error {
-		_, err2 := tx.CreateBucketIfNotExists([]byte(c.Config.BBolt.Bucket))
-		if err2 != nil {
-			return fmt.Errorf("create bucket: %s", err2)
-		}
-		return nil
-	})
+	if c.Config.ReadOnly {
+		err = c.dbh.View(func(tx *bbolt.Tx) error {
+			if tx.Bucket([]byte(c.Config.BBolt.Bucket)) == nil {
+				return fmt.Errorf("bucket not found: %s", c.Config.BBolt.Bucket)
+			}
+			return nil
+		})
+	} else {
+		err = c.dbh.Update(func(tx *bbolt.Tx) error {
+			_, err2 := tx.CreateBucketIfNotExists([]byte(c.Config.BBolt.Bucket))
+			if err2 != nil {
+				return fmt.Errorf("create bucket: %s", err2)
+			}
+			return nil
+		})
+	}
 	if err != nil {
 		return err
 	}
 
 	// Load Index here and pass bytes as param2
 	indexData, _, _ := c.retrieve(index.IndexKey, false, false)
+	// a read-only cache does not flush its index, or remove the objects that it reaps
+	bulkRemove, flush := c.BulkRemove, c.storeNoIndex
+	if c.Config.ReadOnly {
+		bulkRemove, flush = func([]string) {}, nil
+	}
 	c.Index = index.NewIndex(c.Name, c.Config.CacheType, indexData,
-		c.Config.Index, c.BulkRemove, c.storeNoIndex, c.Logger)
+		c.Config.Index, bulkRemove, flush, c.Logger)
 	return nil
 }
 
@@ -180,7 +196,9 @@ func (c *Cache) retrieve(cacheKey string, allowExpired bool,
 		return o.Value, status.LookupStatusHit, nil
 	}
 	// Cache Object has been expired but not reaped, go ahead and delete it
-	go c.remove(cacheKey, false)
+	if !c.Config.ReadOnly {
+		go c.remove(cacheKey, false)
+	}
 	metrics.ObserveCacheMiss(cacheKey, c.Name, c.Config.CacheType)
 	return nil, status.LookupStatusKeyMiss, cache.ErrKNF
 }
@@ -123,6 +123,39 @@ func TestBboltCache_ConnectBadBucketName(t *testing.T) {
 	}
 }
 
+func TestBboltCache_ConnectReadOnly(t *testing.T) {
+	cacheConfig := newCacheConfig()
+	defer os.RemoveAll(cacheConfig.BBolt.Filename)
+	bc := Cache{Config: &cacheConfig, Logger: tl.ConsoleLogger("error"), locker: locks.NewNamedLocker()}
+	if err := bc.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.Store(cacheKey, []byte("data"), time.Minute); err != nil {
+		t.Error(err)
+	}
+	bc.Close()
+
+	roConfig := cacheConfig
+	roConfig.ReadOnly = true
+	roConfig.BBolt = &bo.Options{Filename: cacheConfig.BBolt.Filename, Bucket: "trickster_missing"}
+	ro := Cache{Config: &roConfig, Logger: tl.ConsoleLogger("error"), locker: locks.NewNamedLocker()}
+	// a read-only database can't create its bucket
+	if err := ro.Connect(); err == nil || err.Error() != "bucket not found: trickster_missing" {
+		t.Errorf("expected error for missing bucket, got %v", err)
+	}
+	ro.Close()
+
+	roConfig.BBolt = cacheConfig.BBolt
+	ro = Cache{Config: &roConfig, Logger: tl.ConsoleLogger("error"), locker: locks.NewNamedLocker()}
+	if err := ro.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer ro.Close()
+	if data, _, err := ro.Retrieve(cacheKey, false); err != nil || string(data) != "data" {
+		t.Errorf("expected %s got %s (%v)", "data", string(data), err)
+	}
+}
+
 func TestBboltCache_Store(t *testing.T) {
 
 	cacheConfig := newCacheConfig()
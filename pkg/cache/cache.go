@@ -30,6 +30,10 @@ import (
 // ErrKNF represents the error "key not found in cache"
 var ErrKNF = errors.New("key not found in cache")
 
+// ErrNotConnected represents the error "cache is not connected", which is returned by
+// the operations of a cache whose connection was deferred, until it connects
+var ErrNotConnected = errors.New("cache is not connected")
+
 // Cache is the interface for the supported caching fabrics
 // When making new cache types, Retrieve() must return an error on cache miss
 type Cache interface {
@@ -62,6 +66,13 @@ type MemoryCache interface {
 	SetLocker(locks.NamedLocker)
 }
 
+// SnapshotMarshaler is implemented by ReferenceObjects that can be serialized, so that a
+// memory cache can save them in its snapshots. They are restored as serialized objects,
+// which are retrieved with Retrieve rather than RetrieveReference, until they are rewritten
+type SnapshotMarshaler interface {
+	MarshalSnapshot() ([]byte, error)
+}
+
 // ReferenceObject defines an interface for a cache object possessing the ability to report
 // the approximate comprehensive byte size of its members, to assist with cache size management
 type ReferenceObject interface {
@@ -71,8 +71,13 @@ func (c *Cache) Connect() error {
 
 	// Load Index here and pass bytes as param2
 	indexData, _, _ := c.retrieve(index.IndexKey, false, false)
+	// a read-only cache does not flush its index, or remove the objects that it reaps
+	bulkRemove, flush := c.BulkRemove, c.storeNoIndex
+	if c.Config.ReadOnly {
+		bulkRemove, flush = func([]string) {}, nil
+	}
 	c.Index = index.NewIndex(c.Name, c.Config.CacheType, indexData,
-		c.Config.Index, c.BulkRemove, c.storeNoIndex, c.Logger)
+		c.Config.Index, bulkRemove, flush, c.Logger)
 	return nil
 }
 
@@ -163,7 +168,9 @@ func (c *Cache) retrieve(cacheKey string, allowExpired bool, atime bool) ([]byte
 		return o.Value, status.LookupStatusHit, nil
 	}
 	// Cache Object has been expired but not reaped, go ahead and delete it
-	go c.remove(cacheKey, false)
+	if !c.Config.ReadOnly {
+		go c.remove(cacheKey, false)
+	}
 	metrics.ObserveCacheMiss(cacheKey, c.Name, c.Config.CacheType)
 	return nil, status.LookupStatusKeyMiss, cache.ErrKNF
 }
@@ -52,16 +52,18 @@ type Index struct {
 	flushFunc      func(cacheKey string, data []byte) `msg:"-"`
 	lastWrite      time.Time                          `msg:"-"`
 
-	isClosing     bool
-	flusherExited bool
-	reaperExited  bool
+	// the closing and exited flags are accessed atomically, since the index is closed
+	// while its subroutines run
+	isClosing     int32
+	flusherExited int32
+	reaperExited  int32
 
 	mtx sync.Mutex
 }
 
 // Close is called to signal the index to shut down any subroutines
 func (idx *Index) Close() {
-	idx.isClosing = true
+	atomic.StoreInt32(&idx.isClosing, 1)
 }
 
 // ToBytes returns a serialized byte slice representing the Index
@@ -254,15 +256,21 @@ func (idx *Index) GetExpiration(cacheKey string) time.Time {
 // flusher periodically calls the cache's index flush func that writes the cache index to disk
 func (idx *Index) flusher(log *tl.Logger) {
 	var lastFlush time.Time
-	for !idx.isClosing {
-		time.Sleep(idx.options.FlushInterval)
-		if idx.lastWrite.Before(lastFlush) {
+	for atomic.LoadInt32(&idx.isClosing) == 0 {
+		idx.mtx.Lock()
+		interval := idx.options.FlushInterval
+		idx.mtx.Unlock()
+		time.Sleep(interval)
+		idx.mtx.Lock()
+		lastWrite := idx.lastWrite
+		idx.mtx.Unlock()
+		if lastWrite.Before(lastFlush) {
 			continue
 		}
 		idx.flushOnce(log)
 		lastFlush = time.Now()
 	}
-	idx.flusherExited = true
+	atomic.StoreInt32(&idx.flusherExited, 1)
 }
 
 func (idx *Index) flushOnce(log *tl.Logger) {
@@ -279,11 +287,15 @@ func (idx *Index) flushOnce(log *tl.Logger) {
 
 // reaper continually iterates through the cache to find expired elements and removes them
 func (idx *Index) reaper(log *tl.Logger) {
-	for !idx.isClosing {
+	for atomic.LoadInt32(&idx.isClosing) == 0 {
 		idx.reap(log)
-		time.Sleep(idx.options.ReapInterval)
+		// the options are replaced under the lock when the cache config is reloaded
+		idx.mtx.Lock()
+		interval := idx.options.ReapInterval
+		idx.mtx.Unlock()
+		time.Sleep(interval)
 	}
-	idx.reaperExited = true
+	atomic.StoreInt32(&idx.reaperExited, 1)
 }
 
 type objectsAtime []*Object
@@ -18,6 +18,7 @@ package index
 
 import (
 	"sort"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -58,10 +59,10 @@ func TestNewIndex(t *testing.T) {
 
 	idx.Close()
 	time.Sleep(500 * time.Millisecond)
-	if !idx.reaperExited {
+	if atomic.LoadInt32(&idx.reaperExited) == 0 {
 		t.Error("expected true")
 	}
-	if !idx.flusherExited {
+	if atomic.LoadInt32(&idx.flusherExited) == 0 {
 		t.Error("expected true")
 	}
 
@@ -69,10 +70,10 @@ func TestNewIndex(t *testing.T) {
 	if idx2 == nil {
 		t.Errorf("nil cache index")
 	}
+	idx2.Close()
 
-	cacheConfig.Index.FlushInterval = 0
-	cacheConfig.Index.ReapInterval = 0
-	idx3 := NewIndex("test", "test", nil, cacheConfig.Index, testBulkRemoveFunc, fakeFlusherFunc, testLogger)
+	// idx2's subroutines read the options until they exit, so idx3 has its own
+	idx3 := NewIndex("test", "test", nil, &io.Options{}, testBulkRemoveFunc, fakeFlusherFunc, testLogger)
 	if idx3 == nil {
 		t.Errorf("nil cache index")
 	}
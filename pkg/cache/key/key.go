@@ -25,3 +25,7 @@ import (
 // HasherFunc is a custom function that returns a hashed key value string for cache objects
 type HasherFunc func(path string, params url.Values,
 	headers http.Header, body io.ReadCloser, extra string) (string, io.ReadCloser)
+
+// NormalizerFunc is a custom function that returns a canonical form of a request parameter
+// value, so that equivalent values share a cache key
+type NormalizerFunc func(value string) string
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package key
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Template Component Kinds
+const (
+	// TemplateMethod is replaced by the request's HTTP method
+	TemplateMethod = "method"
+	// TemplateHost is replaced by the request's Host header
+	TemplateHost = "host"
+	// TemplateHeader is replaced by the value of the named request header
+	TemplateHeader = "header"
+	// TemplateParam is replaced by the value of the named query parameter
+	TemplateParam = "param"
+	// TemplateBody is replaced by the value of the named request body field
+	TemplateBody = "body"
+)
+
+var templateKinds = map[string]bool{
+	TemplateMethod: false, TemplateHost: false,
+	TemplateHeader: true, TemplateParam: true, TemplateBody: true,
+}
+
+// Template describes the composition of a cache key from the parts of a request, like
+// '{method} {header:X-Tenant} {param:query} {body:panelId}'. Text outside of the braces
+// is included in the key as-is
+type Template struct {
+	source string
+	parts  []templatePart
+}
+
+type templatePart struct {
+	literal string
+	kind    string
+	name    string
+}
+
+// ParseTemplate returns the Template for the template string
+func ParseTemplate(s string) (*Template, error) {
+	t := &Template{source: s}
+	for i := 0; i < len(s); {
+		j := strings.IndexByte(s[i:], '{')
+		if j < 0 {
+			t.parts = append(t.parts, templatePart{literal: s[i:]})
+			break
+		}
+		if j > 0 {
+			t.parts = append(t.parts, templatePart{literal: s[i : i+j]})
+		}
+		i += j
+		k := strings.IndexByte(s[i:], '}')
+		if k < 0 {
+			return nil, fmt.Errorf("unterminated component at position %d", i)
+		}
+		c := s[i+1 : i+k]
+		kind, name := c, ""
+		if l := strings.IndexByte(c, ':'); l >= 0 {
+			kind, name = c[:l], c[l+1:]
+		}
+		named, ok := templateKinds[kind]
+		if !ok {
+			return nil, fmt.Errorf("unknown component {%s}", c)
+		}
+		if named != (name != "") {
+			if named {
+				return nil, fmt.Errorf("component {%s} requires a name", c)
+			}
+			return nil, fmt.Errorf("component {%s} does not take a name", c)
+		}
+		t.parts = append(t.parts, templatePart{kind: kind, name: name})
+		i += k + 1
+	}
+	return t, nil
+}
+
+// String returns the template string
+func (t *Template) String() string {
+	return t.source
+}
+
+// Names returns the names of the template's components of the provided kind
+func (t *Template) Names(kind string) []string {
+	var names []string
+	for _, p := range t.parts {
+		if p.kind == kind {
+			names = append(names, p.name)
+		}
+	}
+	return names
+}
+
+// Execute returns the key composition described by the template, with each component
+// replaced by the value provided by the lookup function. Values are quoted, so that a
+// value can't be mistaken for a different composition
+func (t *Template) Execute(lookup func(kind, name string) string) string {
+	var sb strings.Builder
+	for _, p := range t.parts {
+		if p.kind == "" {
+			sb.WriteString(p.literal)
+			continue
+		}
+		sb.WriteString(strconv.Quote(lookup(p.kind, p.name)))
+	}
+	return sb.String()
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package key
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTemplate(t *testing.T) {
+
+	tests := []struct {
+		template string
+		err      string
+	}{
+		{"{method} {host} {header:X-Tenant} {param:query} {body:panelId}", ""},
+		{"static", ""},
+		{"", ""},
+		{"{method", "unterminated component at position 0"},
+		{"{cookie:session}", "unknown component {cookie:session}"},
+		{"{param}", "component {param} requires a name"},
+		{"{method:GET}", "component {method:GET} does not take a name"},
+	}
+
+	for _, test := range tests {
+		tpl, err := ParseTemplate(test.template)
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("unexpected error for %s: %v", test.template, err)
+			} else if tpl.String() != test.template {
+				t.Errorf("expected %s got %s", test.template, tpl.String())
+			}
+			continue
+		}
+		if err == nil || err.Error() != test.err {
+			t.Errorf("expected error %s for %s got %v", test.err, test.template, err)
+		}
+	}
+}
+
+func TestTemplateExecute(t *testing.T) {
+
+	tpl, err := ParseTemplate("{method}/{param:query}/{param:step}/{body:panelId}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := tpl.Names(TemplateParam); strings.Join(names, ",") != "query,step" {
+		t.Errorf("expected %s got %v", "query,step", names)
+	}
+
+	values := map[string]string{"method": "GET", "param:query": "up", "param:step": "15"}
+	lookup := func(kind, name string) string {
+		if name != "" {
+			kind += ":" + name
+		}
+		return values[kind]
+	}
+
+	const expected = `"GET"/"up"/"15"/""`
+	if k := tpl.Execute(lookup); k != expected {
+		t.Errorf("expected %s got %s", expected, k)
+	}
+
+	// values with separators can't be mistaken for other values
+	values["param:query"], values["param:step"] = `up"/"15`, ""
+	if k := tpl.Execute(lookup); k == expected {
+		t.Errorf("expected distinct key, got %s", k)
+	}
+}
@@ -40,6 +40,9 @@ type Cache struct {
 	Logger     *tl.Logger
 	locker     locks.NamedLocker
 	lockPrefix string
+
+	quit      chan struct{}
+	closeOnce sync.Once
 }
 
 // Locker returns the cache's locker
@@ -64,6 +67,14 @@ func (c *Cache) Connect() error {
 	c.lockPrefix = c.Name + ".memory."
 	c.client = sync.Map{}
 	c.Index = index.NewIndex(c.Name, c.Config.CacheType, nil, c.Config.Index, c.BulkRemove, nil, c.Logger)
+	c.quit = make(chan struct{})
+	// a snapshot from the previous run restores the cache, so a restart isn't a cold start
+	if c.Config.Memory != nil && c.Config.Memory.SnapshotPath != "" {
+		c.loadSnapshot()
+		if c.Config.Memory.SnapshotInterval > 0 {
+			go c.snapshotter()
+		}
+	}
 	return nil
 }
 
@@ -142,9 +153,10 @@ func (c *Cache) retrieve(cacheKey string, allowExpired bool, atime bool) (*index
 
 	if ok {
 		o := record.(*index.Object)
-		o.Expiration = c.Index.GetExpiration(cacheKey)
+		// the object is shared with concurrent readers, so its expiration is not updated
+		exp := c.Index.GetExpiration(cacheKey)
 
-		if allowExpired || o.Expiration.IsZero() || o.Expiration.After(time.Now()) {
+		if allowExpired || exp.IsZero() || exp.After(time.Now()) {
 			c.Logger.Debug("memory cache retrieve", tl.Pairs{"cacheKey": cacheKey})
 			if atime {
 				go c.Index.UpdateObjectAccessTime(cacheKey)
@@ -192,8 +204,17 @@ func (c *Cache) BulkRemove(cacheKeys []string) {
 	wg.Wait()
 }
 
-// Close is not used for Cache, and is here to fully prototype the Cache Interface
+// Close stops the cache's background routines, and saves a final snapshot if
+// snapshots are enabled
 func (c *Cache) Close() error {
+	c.closeOnce.Do(func() {
+		if c.quit != nil {
+			close(c.quit)
+			if c.Config.Memory != nil && c.Config.Memory.SnapshotPath != "" {
+				c.saveSnapshot()
+			}
+		}
+	})
 	if c.Index != nil {
 		c.Index.Close()
 	}
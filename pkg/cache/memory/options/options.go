@@ -0,0 +1,43 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"time"
+
+	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
+)
+
+// Options is a collection of Configurations for the Memory Cache
+type Options struct {
+	// SnapshotPath is the file to which the cache's contents are periodically saved, and from
+	// which they are restored on startup. An empty path disables snapshots
+	SnapshotPath string `toml:"snapshot_path"`
+	// SnapshotIntervalSecs sets how often the cache's contents are saved to the SnapshotPath
+	SnapshotIntervalSecs int `toml:"snapshot_interval_secs"`
+
+	// SnapshotInterval is the parsed value of SnapshotIntervalSecs
+	SnapshotInterval time.Duration `toml:"-"`
+}
+
+// NewOptions returns a new Memory Options Reference with default values set
+func NewOptions() *Options {
+	return &Options{
+		SnapshotIntervalSecs: d.DefaultMemorySnapshotIntervalSecs,
+		SnapshotInterval:     d.DefaultMemorySnapshotIntervalSecs * time.Second,
+	}
+}
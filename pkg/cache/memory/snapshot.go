@@ -0,0 +1,133 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/cache/index"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// snapshotter saves the cache's contents to its snapshot path on the snapshot interval,
+// until the cache is closed
+func (c *Cache) snapshotter() {
+	ticker := time.NewTicker(c.Config.Memory.SnapshotInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.saveSnapshot()
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// saveSnapshot writes the unexpired objects in the cache to its snapshot path. Objects
+// stored by reference are included only if they implement cache.SnapshotMarshaler. The
+// snapshot is written to a temporary file first, so a crash can't leave a partial snapshot
+func (c *Cache) saveSnapshot() (int, error) {
+	now := time.Now()
+	snap := &index.Index{Objects: make(map[string]*index.Object)}
+	c.client.Range(func(k, v interface{}) bool {
+		o, ok := v.(*index.Object)
+		if !ok {
+			return true
+		}
+		key := k.(string)
+		exp := c.Index.GetExpiration(key)
+		if !exp.After(now) {
+			return true
+		}
+		b := o.Value
+		if b == nil {
+			sm, ok := o.ReferenceValue.(cache.SnapshotMarshaler)
+			if !ok {
+				return true
+			}
+			// the engines replace a document's contents while holding the write lock of
+			// its key, so it is marshaled while holding the key's read lock
+			nl, _ := c.locker.RAcquire(key)
+			var err error
+			b, err = sm.MarshalSnapshot()
+			nl.RRelease()
+			if err != nil {
+				return true
+			}
+		}
+		snap.Objects[key] = &index.Object{Key: key, Expiration: exp, Value: b}
+		snap.CacheSize += int64(len(b))
+		return true
+	})
+	snap.ObjectCount = int64(len(snap.Objects))
+
+	b, err := snap.MarshalMsg(nil)
+	if err != nil {
+		return 0, err
+	}
+	path := c.Config.Memory.SnapshotPath
+	tmp := path + ".tmp"
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		if err = ioutil.WriteFile(tmp, b, 0600); err == nil {
+			err = os.Rename(tmp, path)
+		}
+	}
+	if err != nil {
+		c.Logger.Error("memorycache snapshot failed",
+			tl.Pairs{"name": c.Name, "path": path, "detail": err.Error()})
+		return 0, err
+	}
+	c.Logger.Debug("memorycache snapshot saved", tl.Pairs{"name": c.Name, "path": path,
+		"objects": snap.ObjectCount, "bytes": snap.CacheSize})
+	return len(snap.Objects), nil
+}
+
+// loadSnapshot restores the unexpired objects in the snapshot at the cache's snapshot
+// path, with their remaining TTLs. A missing snapshot is not an error
+func (c *Cache) loadSnapshot() (int, error) {
+	path := c.Config.Memory.SnapshotPath
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	snap := &index.Index{}
+	if err == nil {
+		_, err = snap.UnmarshalMsg(b)
+	}
+	if err != nil {
+		c.Logger.Warn("memorycache snapshot could not be loaded",
+			tl.Pairs{"name": c.Name, "path": path, "detail": err.Error()})
+		return 0, err
+	}
+	now := time.Now()
+	var n int
+	for k, o := range snap.Objects {
+		if o == nil || o.Value == nil || !o.Expiration.After(now) {
+			continue
+		}
+		c.store(k, o.Value, nil, o.Expiration.Sub(now), true)
+		n++
+	}
+	c.Logger.Info("memorycache snapshot loaded",
+		tl.Pairs{"name": c.Name, "path": path, "objects": n})
+	return n, nil
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memory
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	io "github.com/tricksterproxy/trickster/pkg/cache/index/options"
+	mo "github.com/tricksterproxy/trickster/pkg/cache/memory/options"
+	co "github.com/tricksterproxy/trickster/pkg/cache/options"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+type testSnapshotObject struct {
+	testReferenceObject
+}
+
+func (r *testSnapshotObject) MarshalSnapshot() ([]byte, error) {
+	return []byte("snapshot"), nil
+}
+
+func TestSnapshot(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", cacheType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	newCache := func() *Cache {
+		cacheConfig := co.Options{CacheType: cacheType, Index: &io.Options{ReapInterval: 0},
+			Memory: &mo.Options{SnapshotPath: filepath.Join(dir, "snapshot", "cache.snap")}}
+		mc := &Cache{Config: &cacheConfig, Logger: tl.ConsoleLogger("error"), locker: testLocker}
+		if err := mc.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		return mc
+	}
+
+	// there is no snapshot to load on the first start
+	mc := newCache()
+	ttl := time.Duration(60) * time.Second
+	mc.Store("bytes", []byte("data"), ttl)
+	mc.StoreReference("snapshot", &testSnapshotObject{}, ttl)
+	mc.StoreReference("reference", &testReferenceObject{}, ttl)
+	mc.Store("expired", []byte("data"), -time.Second)
+
+	// objects are marshaled while holding the read lock of their key
+	nl, _ := testLocker.Acquire("snapshot")
+	done := make(chan struct{})
+	go func() {
+		mc.saveSnapshot()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Error("expected the snapshot to wait for the key's write lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+	nl.Release()
+	<-done
+
+	n, err := mc.saveSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expected %d got %d", 2, n)
+	}
+	mc.Close()
+
+	mc = newCache()
+	defer mc.Close()
+	for k, v := range map[string]string{"bytes": "data", "snapshot": "snapshot"} {
+		b, _, err := mc.Retrieve(k, false)
+		if err != nil {
+			t.Error(err)
+		}
+		if string(b) != v {
+			t.Errorf("expected %s got %s", v, string(b))
+		}
+	}
+	for _, k := range []string{"reference", "expired"} {
+		if _, _, err := mc.Retrieve(k, true); err == nil {
+			t.Errorf("expected %s not to be restored", k)
+		}
+	}
+	if exp := mc.Index.GetExpiration("bytes"); exp.After(time.Now().Add(ttl)) {
+		t.Errorf("expected restored ttl within %s got %s", ttl, exp)
+	}
+
+	// a corrupt snapshot is not loaded
+	if err := ioutil.WriteFile(mc.Config.Memory.SnapshotPath, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mc.loadSnapshot(); err == nil {
+		t.Error("expected error loading corrupt snapshot")
+	}
+}
@@ -0,0 +1,34 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import "github.com/tricksterproxy/trickster/pkg/util/compress"
+
+// CompressionNone is the Compression value that disables compression of
+// serialized cache documents
+const CompressionNone = "none"
+
+// CompressionFlags maps the name of each codec that may compress serialized
+// cache documents to the flag byte that prefixes them in the cache. The flags
+// are persisted with each document, so existing assignments must never change.
+// A codec is only usable when it is also registered with the compress package
+var CompressionFlags = map[string]byte{
+	compress.NameSnappy:  1,
+	compress.NameGzip:    2,
+	compress.NameDeflate: 3,
+	compress.NameBrotli:  4,
+}
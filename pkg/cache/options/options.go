@@ -21,6 +21,7 @@ import (
 	bbolt "github.com/tricksterproxy/trickster/pkg/cache/bbolt/options"
 	filesystem "github.com/tricksterproxy/trickster/pkg/cache/filesystem/options"
 	index "github.com/tricksterproxy/trickster/pkg/cache/index/options"
+	memory "github.com/tricksterproxy/trickster/pkg/cache/memory/options"
 	redis "github.com/tricksterproxy/trickster/pkg/cache/redis/options"
 	"github.com/tricksterproxy/trickster/pkg/cache/types"
 	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
@@ -34,6 +35,8 @@ type Options struct {
 	CacheType string `toml:"cache_type"`
 	// Index provides options for the Cache Index
 	Index *index.Options `toml:"index"`
+	// Memory provides options for Memory caching
+	Memory *memory.Options `toml:"memory"`
 	// Redis provides options for Redis caching
 	Redis *redis.Options `toml:"redis"`
 	// Filesystem provides options for Filesystem caching
@@ -42,6 +45,18 @@ type Options struct {
 	BBolt *bbolt.Options `toml:"bbolt"`
 	// Badger provides options for BadgerDB caching
 	Badger *badger.Options `toml:"badger"`
+	// CompressionCodec is the name of the codec used to compress serialized cache documents,
+	// or "none" to store them uncompressed. It does not apply to the memory cache
+	CompressionCodec string `toml:"compression_codec"`
+	// CompressionMinSizeBytes is the minimum serialized size of a cache document
+	// before it is compressed
+	CompressionMinSizeBytes int `toml:"compression_min_size_bytes"`
+	// StartupMode is how the cache is served when it cannot connect to its backend:
+	// 'fail', 'retry' or 'lazy'. It does not apply to the memory cache
+	StartupMode string `toml:"startup_mode"`
+	// ReadOnly indicates that the cache serves its existing objects, but does not
+	// store, update or remove any objects
+	ReadOnly bool `toml:"read_only"`
 
 	//  Synthetic Values
 
@@ -54,13 +69,16 @@ type Options struct {
 func NewOptions() *Options {
 
 	return &Options{
-		CacheType:   d.DefaultCacheType,
-		CacheTypeID: d.DefaultCacheTypeID,
-		Redis:       redis.NewOptions(),
-		Filesystem:  filesystem.NewOptions(),
-		BBolt:       bbolt.NewOptions(),
-		Badger:      badger.NewOptions(),
-		Index:       index.NewOptions(),
+		CacheType:        d.DefaultCacheType,
+		CacheTypeID:      d.DefaultCacheTypeID,
+		CompressionCodec: d.DefaultCacheCompressionCodec,
+		StartupMode:      d.DefaultCacheStartupMode,
+		Memory:           memory.NewOptions(),
+		Redis:            redis.NewOptions(),
+		Filesystem:       filesystem.NewOptions(),
+		BBolt:            bbolt.NewOptions(),
+		Badger:           badger.NewOptions(),
+		Index:            index.NewOptions(),
 	}
 }
 
@@ -71,6 +89,10 @@ func (cc *Options) Clone() *Options {
 	c.Name = cc.Name
 	c.CacheType = cc.CacheType
 	c.CacheTypeID = cc.CacheTypeID
+	c.CompressionCodec = cc.CompressionCodec
+	c.CompressionMinSizeBytes = cc.CompressionMinSizeBytes
+	c.StartupMode = cc.StartupMode
+	c.ReadOnly = cc.ReadOnly
 
 	c.Index.FlushInterval = cc.Index.FlushInterval
 	c.Index.FlushIntervalSecs = cc.Index.FlushIntervalSecs
@@ -86,6 +108,10 @@ func (cc *Options) Clone() *Options {
 
 	c.Filesystem.CachePath = cc.Filesystem.CachePath
 
+	c.Memory.SnapshotPath = cc.Memory.SnapshotPath
+	c.Memory.SnapshotIntervalSecs = cc.Memory.SnapshotIntervalSecs
+	c.Memory.SnapshotInterval = cc.Memory.SnapshotInterval
+
 	c.BBolt.Bucket = cc.BBolt.Bucket
 	c.BBolt.Filename = cc.BBolt.Filename
 
@@ -123,6 +149,7 @@ func (cc *Options) Equal(cc2 *Options) bool {
 
 	return cc.Name == cc2.Name &&
 		cc.CacheType == cc2.CacheType &&
-		cc.CacheTypeID == cc2.CacheTypeID
+		cc.CacheTypeID == cc2.CacheTypeID &&
+		cc.ReadOnly == cc2.ReadOnly
 
 }
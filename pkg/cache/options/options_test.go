@@ -38,4 +38,9 @@ func TestCloneAndEqual(t *testing.T) {
 		t.Error("expected false")
 	}
 
+	o2.ReadOnly = true
+	if o.Equal(o2) || !o2.Clone().ReadOnly {
+		t.Error("expected read-only clone to not equal writable options")
+	}
+
 }
@@ -0,0 +1,38 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+// Startup modes determine how a cache is served when it cannot connect to its backend,
+// such as an unreachable Redis server, when its config is loaded
+const (
+	// StartupModeFail fails the config load when the cache cannot connect
+	StartupModeFail = "fail"
+	// StartupModeRetry retries the connection with backoff in the background, while
+	// requests are proxied without the cache
+	StartupModeRetry = "retry"
+	// StartupModeLazy connects the cache on its first use, rather than when the config
+	// is loaded. A failed connection is retried on a later use, after a backoff, and
+	// requests are proxied without the cache until it connects
+	StartupModeLazy = "lazy"
+)
+
+// StartupModes is the set of valid startup modes
+var StartupModes = map[string]bool{
+	StartupModeFail:  true,
+	StartupModeRetry: true,
+	StartupModeLazy:  true,
+}
@@ -91,7 +91,13 @@ func (c *Cache) Connect() error {
 		c.closer = client.Close
 		c.client = client
 	}
-	return c.client.Ping().Err()
+	if err := c.client.Ping().Err(); err != nil {
+		// the client is released, so that the connection can be retried without leaking it
+		c.closer()
+		c.closer = nil
+		return err
+	}
+	return nil
 }
 
 // Store places the the data into the Redis Cache using the provided Key and TTL
@@ -145,6 +151,9 @@ func (c *Cache) BulkRemove(cacheKeys []string) {
 
 // Close disconnects from the Redis Cache
 func (c *Cache) Close() error {
+	if c.closer == nil {
+		return nil
+	}
 	c.Logger.Info("closing redis connection", tl.Pairs{})
 	return c.closer()
 }
@@ -0,0 +1,227 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registration
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/cache/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	"github.com/tricksterproxy/trickster/pkg/locks"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// the backoff between the connection attempts of a deferred cache is doubled after each
+// failed attempt, between these bounds
+var (
+	minConnectBackoff = time.Second
+	maxConnectBackoff = time.Minute
+)
+
+// deferredCache is a cache whose connection is retried in the background, or made on its
+// first use, according to its startup mode. Until it connects, its lookups fail with
+// cache.ErrNotConnected, so that requests are proxied without the cache
+type deferredCache struct {
+	name     string
+	cfg      *options.Options
+	logger   *tl.Logger
+	newCache func() cache.Cache
+	locker   locks.NamedLocker
+
+	mtx        sync.Mutex
+	c          cache.Cache
+	connecting bool
+	closed     bool
+	backoff    time.Duration
+	next       time.Time
+	quit       chan struct{}
+}
+
+func newDeferredCache(name string, cfg *options.Options, logger *tl.Logger,
+	newCache func() cache.Cache) *deferredCache {
+	return &deferredCache{name: name, cfg: cfg, logger: logger, newCache: newCache,
+		quit: make(chan struct{})}
+}
+
+// Connect connects the cache in retry mode, and when the connection fails, retries it
+// in the background. In lazy mode, the connection is deferred to the first use. In either
+// mode, Connect does not return an error for a failed connection
+func (d *deferredCache) Connect() error {
+	if d.cfg.StartupMode == options.StartupModeLazy {
+		d.logger.Info("cache connection deferred to first use",
+			tl.Pairs{"cacheName": d.name, "cacheType": d.cfg.CacheType})
+		return nil
+	}
+	if d.connect() {
+		return nil
+	}
+	go d.retry()
+	return nil
+}
+
+// retry attempts the connection after each backoff, until it connects or the cache is
+// closed
+func (d *deferredCache) retry() {
+	for {
+		d.mtx.Lock()
+		wait := d.backoff
+		d.mtx.Unlock()
+		select {
+		case <-d.quit:
+			return
+		case <-time.After(wait):
+		}
+		if d.connect() {
+			return
+		}
+	}
+}
+
+// connect attempts the connection, and returns true if the cache is connected. Only one
+// attempt is made at a time. A failed attempt sets the backoff until the next one
+func (d *deferredCache) connect() bool {
+	d.mtx.Lock()
+	if d.c != nil || d.closed || d.connecting {
+		defer d.mtx.Unlock()
+		return d.c != nil
+	}
+	d.connecting = true
+	d.mtx.Unlock()
+
+	c := d.newCache()
+	c.SetLocker(d.Locker())
+	err := c.Connect()
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.connecting = false
+	if err != nil {
+		c.Close()
+		switch {
+		case d.backoff == 0:
+			d.backoff = minConnectBackoff
+		case d.backoff < maxConnectBackoff:
+			d.backoff *= 2
+			if d.backoff > maxConnectBackoff {
+				d.backoff = maxConnectBackoff
+			}
+		}
+		d.next = time.Now().Add(d.backoff)
+		d.logger.Warn("cache connection failed, proxying without the cache",
+			tl.Pairs{"cacheName": d.name, "cacheType": d.cfg.CacheType,
+				"startupMode": d.cfg.StartupMode, "retryIn": d.backoff.String(),
+				"detail": err.Error()})
+		return false
+	}
+	if d.closed {
+		// the cache was closed while it was connecting
+		c.Close()
+		return false
+	}
+	d.c = c
+	d.logger.Info("cache connected",
+		tl.Pairs{"cacheName": d.name, "cacheType": d.cfg.CacheType})
+	return true
+}
+
+// cache returns the connected cache, or nil when it is not connected. In lazy mode, a
+// connection is attempted when the backoff since the last attempt has elapsed
+func (d *deferredCache) cache() cache.Cache {
+	d.mtx.Lock()
+	c, attempt := d.c, d.c == nil && !d.closed && !d.connecting &&
+		d.cfg.StartupMode == options.StartupModeLazy && !time.Now().Before(d.next)
+	d.mtx.Unlock()
+	if c != nil || !attempt {
+		return c
+	}
+	d.connect()
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return d.c
+}
+
+// Store places the data into the cache, when it is connected
+func (d *deferredCache) Store(cacheKey string, data []byte, ttl time.Duration) error {
+	c := d.cache()
+	if c == nil {
+		return cache.ErrNotConnected
+	}
+	return c.Store(cacheKey, data, ttl)
+}
+
+// Retrieve gets the data from the cache, when it is connected
+func (d *deferredCache) Retrieve(cacheKey string,
+	allowExpired bool) ([]byte, status.LookupStatus, error) {
+	c := d.cache()
+	if c == nil {
+		return nil, status.LookupStatusError, cache.ErrNotConnected
+	}
+	return c.Retrieve(cacheKey, allowExpired)
+}
+
+// SetTTL updates the TTL of the cache object, when the cache is connected
+func (d *deferredCache) SetTTL(cacheKey string, ttl time.Duration) {
+	if c := d.cache(); c != nil {
+		c.SetTTL(cacheKey, ttl)
+	}
+}
+
+// Remove removes the cache object, when the cache is connected
+func (d *deferredCache) Remove(cacheKey string) {
+	if c := d.cache(); c != nil {
+		c.Remove(cacheKey)
+	}
+}
+
+// BulkRemove removes the cache objects, when the cache is connected
+func (d *deferredCache) BulkRemove(cacheKeys []string) {
+	if c := d.cache(); c != nil {
+		c.BulkRemove(cacheKeys)
+	}
+}
+
+// Close stops any connection retries, and closes the cache when it is connected
+func (d *deferredCache) Close() error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	close(d.quit)
+	if d.c == nil {
+		return nil
+	}
+	return d.c.Close()
+}
+
+// Configuration returns the Configuration of the cache
+func (d *deferredCache) Configuration() *options.Options {
+	return d.cfg
+}
+
+// Locker returns the cache's locker
+func (d *deferredCache) Locker() locks.NamedLocker {
+	return d.locker
+}
+
+// SetLocker sets the cache's locker
+func (d *deferredCache) SetLocker(l locks.NamedLocker) {
+	d.locker = l
+}
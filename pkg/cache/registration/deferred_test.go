@@ -0,0 +1,171 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registration
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/cache/memory"
+	co "github.com/tricksterproxy/trickster/pkg/cache/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	"github.com/tricksterproxy/trickster/pkg/locks"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// unreachableCache is a memory cache whose connection fails while unreachable is set
+type unreachableCache struct {
+	*memory.Cache
+	unreachable *int32
+}
+
+func (c *unreachableCache) Connect() error {
+	if atomic.LoadInt32(c.unreachable) == 1 {
+		return errors.New("test cache unreachable")
+	}
+	return c.Cache.Connect()
+}
+
+func testDeferredCache(mode string) (*deferredCache, *int32) {
+	unreachable := int32(1)
+	cfg := co.NewOptions()
+	cfg.StartupMode = mode
+	logger := tl.ConsoleLogger("error")
+	d := newDeferredCache("test", cfg, logger, func() cache.Cache {
+		return &unreachableCache{Cache: &memory.Cache{Name: "test", Config: co.NewOptions(),
+			Logger: logger}, unreachable: &unreachable}
+	})
+	d.SetLocker(locks.NewNamedLocker())
+	return d, &unreachable
+}
+
+func setConnectBackoff(min, max time.Duration) func() {
+	pmin, pmax := minConnectBackoff, maxConnectBackoff
+	minConnectBackoff, maxConnectBackoff = min, max
+	return func() { minConnectBackoff, maxConnectBackoff = pmin, pmax }
+}
+
+func TestDeferredCacheRetry(t *testing.T) {
+	defer setConnectBackoff(10*time.Millisecond, 40*time.Millisecond)()
+
+	d, unreachable := testDeferredCache(co.StartupModeRetry)
+	if err := d.Connect(); err != nil {
+		t.Error(err)
+	}
+	defer d.Close()
+
+	if _, s, err := d.Retrieve("key", false); err != cache.ErrNotConnected ||
+		s != status.LookupStatusError {
+		t.Errorf("expected %v got %v", cache.ErrNotConnected, err)
+	}
+	if err := d.Store("key", []byte("value"), time.Minute); err != cache.ErrNotConnected {
+		t.Errorf("expected %v got %v", cache.ErrNotConnected, err)
+	}
+	d.Remove("key")
+	d.BulkRemove([]string{"key"})
+	d.SetTTL("key", time.Minute)
+
+	// the connection is retried in the background once the backend is reachable
+	atomic.StoreInt32(unreachable, 0)
+	for i := 0; i < 100 && d.cache() == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if d.cache() == nil {
+		t.Fatal("expected the cache to connect")
+	}
+	if err := d.Store("key", []byte("value"), time.Minute); err != nil {
+		t.Error(err)
+	}
+	if b, _, err := d.Retrieve("key", false); err != nil || string(b) != "value" {
+		t.Errorf("expected value got %s %v", string(b), err)
+	}
+}
+
+func TestDeferredCacheLazy(t *testing.T) {
+	defer setConnectBackoff(time.Hour, time.Hour)()
+
+	d, unreachable := testDeferredCache(co.StartupModeLazy)
+	if err := d.Connect(); err != nil {
+		t.Error(err)
+	}
+	defer d.Close()
+	if d.c != nil {
+		t.Error("expected the connection to be deferred")
+	}
+
+	// the first use attempts the connection, which is not attempted again until the
+	// backoff has elapsed
+	if _, _, err := d.Retrieve("key", false); err != cache.ErrNotConnected {
+		t.Errorf("expected %v got %v", cache.ErrNotConnected, err)
+	}
+	atomic.StoreInt32(unreachable, 0)
+	if _, _, err := d.Retrieve("key", false); err != cache.ErrNotConnected {
+		t.Errorf("expected %v got %v", cache.ErrNotConnected, err)
+	}
+	d.next = time.Time{}
+	if _, _, err := d.Retrieve("key", false); err != cache.ErrKNF {
+		t.Errorf("expected %v got %v", cache.ErrKNF, err)
+	}
+}
+
+func TestDeferredCacheClose(t *testing.T) {
+	defer setConnectBackoff(10*time.Millisecond, 10*time.Millisecond)()
+	d, unreachable := testDeferredCache(co.StartupModeRetry)
+	d.Connect()
+	if err := d.Close(); err != nil {
+		t.Error(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Error(err)
+	}
+	// a closed cache does not connect
+	atomic.StoreInt32(unreachable, 0)
+	if d.connect() || d.cache() != nil {
+		t.Error("expected the closed cache not to connect")
+	}
+}
+
+func TestConnectCacheStartupModes(t *testing.T) {
+	cfg := newCacheConfig(t, "filesystem")
+	cfg.Filesystem.CachePath = "/dev/null/trickster"
+	for _, mode := range []string{co.StartupModeRetry, co.StartupModeLazy} {
+		cfg.StartupMode = mode
+		c, err := ConnectCache("test", cfg, tl.ConsoleLogger("error"))
+		if err != nil {
+			t.Errorf("%s: %v", mode, err)
+		}
+		if _, ok := c.(*deferredCache); !ok {
+			t.Errorf("%s: expected a deferred cache", mode)
+		}
+		c.Close()
+	}
+
+	// the memory cache always connects
+	cfg = newCacheConfig(t, "memory")
+	cfg.StartupMode = co.StartupModeLazy
+	c, err := ConnectCache("test", cfg, tl.ConsoleLogger("error"))
+	if err != nil {
+		t.Error(err)
+	}
+	if _, ok := c.(*memory.Cache); !ok {
+		t.Error("expected a memory cache")
+	}
+	c.Close()
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registration
+
+import (
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+)
+
+// readOnlyCache is a cache that serves its existing objects, and suppresses any writes,
+// so that requests whose responses would be cached are proxied as though they were stored
+type readOnlyCache struct {
+	cache.Cache
+}
+
+// readOnlyMemoryCache is a read-only memory cache, which retrieves objects by reference
+type readOnlyMemoryCache struct {
+	readOnlyCache
+	mc cache.MemoryCache
+}
+
+func newReadOnlyCache(c cache.Cache) cache.Cache {
+	if mc, ok := c.(cache.MemoryCache); ok {
+		return &readOnlyMemoryCache{readOnlyCache: readOnlyCache{Cache: c}, mc: mc}
+	}
+	return &readOnlyCache{Cache: c}
+}
+
+// Store does not store the object
+func (c *readOnlyCache) Store(cacheKey string, data []byte, ttl time.Duration) error {
+	return nil
+}
+
+// SetTTL does not update the TTL of the object
+func (c *readOnlyCache) SetTTL(cacheKey string, ttl time.Duration) {}
+
+// Remove does not remove the object
+func (c *readOnlyCache) Remove(cacheKey string) {}
+
+// BulkRemove does not remove the objects
+func (c *readOnlyCache) BulkRemove(cacheKeys []string) {}
+
+// StoreReference does not store the object
+func (c *readOnlyMemoryCache) StoreReference(cacheKey string, data cache.ReferenceObject,
+	ttl time.Duration) error {
+	return nil
+}
+
+// RetrieveReference looks for an object in the memory cache and returns it
+func (c *readOnlyMemoryCache) RetrieveReference(cacheKey string,
+	allowExpired bool) (interface{}, status.LookupStatus, error) {
+	return c.mc.RetrieveReference(cacheKey, allowExpired)
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registration
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/cache/filesystem"
+	"github.com/tricksterproxy/trickster/pkg/cache/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+type testReference struct{}
+
+func (r *testReference) Size() int { return 1 }
+
+func TestReadOnlyCache(t *testing.T) {
+
+	td, err := ioutil.TempDir("", "trickster-readonly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	cfg := options.NewOptions()
+	cfg.CacheType = ctFilesystem
+	cfg.Filesystem.CachePath = td
+	logger := tl.ConsoleLogger("error")
+
+	// populate the cache, as a writable cache sharing its path would
+	c, err := ConnectCache("test", cfg, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Store("key1", []byte("value1"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	ro := cfg.Clone()
+	ro.ReadOnly = true
+	c, err = ConnectCache("test", ro, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, ok := c.(*readOnlyCache).Cache.(*filesystem.Cache); !ok {
+		t.Errorf("expected filesystem cache, got %T", c.(*readOnlyCache).Cache)
+	}
+
+	if b, _, err := c.Retrieve("key1", false); err != nil || string(b) != "value1" {
+		t.Errorf("expected value1 got %s (%v)", string(b), err)
+	}
+
+	if err = c.Store("key2", []byte("value2"), time.Minute); err != nil {
+		t.Error(err)
+	}
+	if _, _, err := c.Retrieve("key2", false); err != cache.ErrKNF {
+		t.Errorf("expected %v got %v", cache.ErrKNF, err)
+	}
+
+	c.Remove("key1")
+	c.BulkRemove([]string{"key1"})
+	if _, _, err := c.Retrieve("key1", false); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestReadOnlyMemoryCache(t *testing.T) {
+
+	cfg := options.NewOptions()
+	cfg.ReadOnly = true
+	c, err := ConnectCache("test", cfg, tl.ConsoleLogger("error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	mc, ok := c.(cache.MemoryCache)
+	if !ok {
+		t.Fatalf("expected %s got %T", "cache.MemoryCache", c)
+	}
+	if err = mc.StoreReference("key1", &testReference{}, time.Minute); err != nil {
+		t.Error(err)
+	}
+	if _, s, err := mc.RetrieveReference("key1", false); err != cache.ErrKNF ||
+		s != status.LookupStatusKeyMiss {
+		t.Errorf("expected %v got %v", cache.ErrKNF, err)
+	}
+}
@@ -72,24 +72,43 @@ func CloseCaches(caches map[string]cache.Cache) error {
 
 // NewCache returns a Cache object based on the provided config.CachingConfig
 func NewCache(cacheName string, cfg *options.Options, logger *tl.Logger) cache.Cache {
+	c, _ := ConnectCache(cacheName, cfg, logger)
+	return c
+}
+
+// ConnectCache returns a Cache object based on the provided config.CachingConfig,
+// along with any error encountered while connecting it. Caches other than the memory
+// cache with a 'retry' or 'lazy' startup mode do not return connection errors, and
+// instead retry their connections while requests are proxied without them. Read-only
+// caches suppress any writes
+func ConnectCache(cacheName string, cfg *options.Options, logger *tl.Logger) (cache.Cache, error) {
 
-	var c cache.Cache
+	c := newCache(cacheName, cfg, logger)
+	if _, ok := c.(*memory.Cache); !ok && (cfg.StartupMode == options.StartupModeRetry ||
+		cfg.StartupMode == options.StartupModeLazy) {
+		c = newDeferredCache(cacheName, cfg, logger, func() cache.Cache {
+			return newCache(cacheName, cfg, logger)
+		})
+	}
+	if cfg.ReadOnly {
+		c = newReadOnlyCache(c)
+	}
 
+	c.SetLocker(locks.NewNamedLocker())
+	return c, c.Connect()
+}
+
+func newCache(cacheName string, cfg *options.Options, logger *tl.Logger) cache.Cache {
 	switch cfg.CacheType {
 	case ctFilesystem:
-		c = &filesystem.Cache{Name: cacheName, Config: cfg, Logger: logger}
+		return &filesystem.Cache{Name: cacheName, Config: cfg, Logger: logger}
 	case ctRedis:
-		c = &redis.Cache{Name: cacheName, Config: cfg, Logger: logger}
+		return &redis.Cache{Name: cacheName, Config: cfg, Logger: logger}
 	case ctBBolt:
-		c = &bbolt.Cache{Name: cacheName, Config: cfg, Logger: logger}
+		return &bbolt.Cache{Name: cacheName, Config: cfg, Logger: logger}
 	case ctBadger:
-		c = &badger.Cache{Name: cacheName, Config: cfg, Logger: logger}
-	default:
-		// Default to MemoryCache
-		c = &memory.Cache{Name: cacheName, Config: cfg, Logger: logger}
+		return &badger.Cache{Name: cacheName, Config: cfg, Logger: logger}
 	}
-
-	c.SetLocker(locks.NewNamedLocker())
-	c.Connect()
-	return c
+	// Default to MemoryCache
+	return &memory.Cache{Name: cacheName, Config: cfg, Logger: logger}
 }
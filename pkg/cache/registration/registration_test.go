@@ -74,6 +74,27 @@ func TestLoadCachesFromConfig(t *testing.T) {
 
 }
 
+func TestConnectCache(t *testing.T) {
+
+	cfg := newCacheConfig(t, "filesystem")
+	defer os.RemoveAll(cfg.Filesystem.CachePath)
+
+	c, err := ConnectCache("test", cfg, tl.ConsoleLogger("error"))
+	if err != nil {
+		t.Error(err)
+	}
+	c.Close()
+
+	cfg.Filesystem.CachePath = "/dev/null/trickster"
+	c, err = ConnectCache("test", cfg, tl.ConsoleLogger("error"))
+	if err == nil {
+		t.Error("expected error for invalid cache path")
+	}
+	if c == nil {
+		t.Error("expected non-nil cache")
+	}
+}
+
 func newCacheConfig(t *testing.T, cacheType string) *co.Options {
 
 	bd := "."
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options provides options for the Admin API
+package options
+
+import "github.com/tricksterproxy/trickster/pkg/config/defaults"
+
+// Options is a collection of configurations for the Admin API
+type Options struct {
+	// HandlerPath is the path prefix of the Admin API on the reload listener. The
+	// versioned endpoints are registered beneath it (e.g., /trickster/admin/v1/stats)
+	HandlerPath string `toml:"handler_path"`
+	// AuthToken is the bearer token required to access the Admin API. The Admin API
+	// is only enabled when an AuthToken is configured
+	AuthToken string `toml:"auth_token"`
+	// FaultInjectionEnabled, when true, serves the frontend through the fault injection
+	// middleware, whose faults are set through the Admin API. It is intended for testing
+	// deployments, and should not be enabled in production
+	FaultInjectionEnabled bool `toml:"fault_injection_enabled"`
+}
+
+// NewOptions returns a new Options references with Default Values set
+func NewOptions() *Options {
+	return &Options{
+		HandlerPath: defaults.DefaultAdminHandlerPath,
+	}
+}
+
+// Clone returns an exact copy of the subject *Options
+func (o *Options) Clone() *Options {
+	return &Options{
+		HandlerPath:           o.HandlerPath,
+		AuthToken:             o.AuthToken,
+		FaultInjectionEnabled: o.FaultInjectionEnabled,
+	}
+}
+
+// Enabled returns true when the Admin API should be served
+func (o *Options) Enabled() bool {
+	return o != nil && o.AuthToken != "" && o.HandlerPath != ""
+}
+
+// FaultInjection returns true when faults should be injected into frontend requests, which
+// requires the Admin API in order to set them
+func (o *Options) FaultInjection() bool {
+	return o.Enabled() && o.FaultInjectionEnabled
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import "testing"
+
+func TestNewOptions(t *testing.T) {
+	o := NewOptions()
+	if o == nil {
+		t.Error("expected non-nil options")
+	}
+	if o.Enabled() {
+		t.Error("expected admin api to be disabled by default")
+	}
+	var o2 *Options
+	if o2.Enabled() {
+		t.Error("expected admin api to be disabled for nil options")
+	}
+	if o2.FaultInjection() {
+		t.Error("expected fault injection to be disabled for nil options")
+	}
+}
+
+func TestFaultInjection(t *testing.T) {
+	o := NewOptions()
+	o.FaultInjectionEnabled = true
+	if o.FaultInjection() {
+		t.Error("expected fault injection to require the admin api")
+	}
+	o.AuthToken = "token"
+	if !o.FaultInjection() {
+		t.Error("expected fault injection to be enabled")
+	}
+}
+
+func TestClone(t *testing.T) {
+	o := NewOptions()
+	o.AuthToken = "token"
+	o2 := o.Clone()
+	if *o2 != *o {
+		t.Error("expected identical clone")
+	}
+	if !o2.Enabled() {
+		t.Error("expected admin api to be enabled")
+	}
+}
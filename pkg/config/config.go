@@ -31,19 +31,40 @@ import (
 	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/cache/evictionmethods"
+	"github.com/tricksterproxy/trickster/pkg/cache/key"
 	cache "github.com/tricksterproxy/trickster/pkg/cache/options"
 	"github.com/tricksterproxy/trickster/pkg/cache/types"
+	admin "github.com/tricksterproxy/trickster/pkg/config/admin/options"
+	debug "github.com/tricksterproxy/trickster/pkg/config/debug/options"
 	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
 	reload "github.com/tricksterproxy/trickster/pkg/config/reload/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/forwarding"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/hosts"
+	"github.com/tricksterproxy/trickster/pkg/proxy/listeners/family"
 	origins "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	rule "github.com/tricksterproxy/trickster/pkg/proxy/origins/rule/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
+	pro "github.com/tricksterproxy/trickster/pkg/proxy/priority/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request/hooks"
 	rewriter "github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter"
 	rwopts "github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/tenancy"
 	to "github.com/tricksterproxy/trickster/pkg/proxy/tls/options"
+	spiffe "github.com/tricksterproxy/trickster/pkg/proxy/tls/spiffe/options"
+	"github.com/tricksterproxy/trickster/pkg/secrets"
+	so "github.com/tricksterproxy/trickster/pkg/secrets/options"
+	bf "github.com/tricksterproxy/trickster/pkg/timeseries/backfill/options"
+	ds "github.com/tricksterproxy/trickster/pkg/timeseries/downsample/options"
 	tracing "github.com/tricksterproxy/trickster/pkg/tracing/options"
+	accesslog "github.com/tricksterproxy/trickster/pkg/util/accesslog/options"
+	"github.com/tricksterproxy/trickster/pkg/util/compress"
+	"github.com/tricksterproxy/trickster/pkg/util/jsonpath"
+	"github.com/tricksterproxy/trickster/pkg/util/log/syslog"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+	otlpmetrics "github.com/tricksterproxy/trickster/pkg/util/metrics/otlp/options"
+	statsd "github.com/tricksterproxy/trickster/pkg/util/metrics/statsd/options"
+	slowlog "github.com/tricksterproxy/trickster/pkg/util/slowlog/options"
 
 	"github.com/BurntSushi/toml"
 )
@@ -72,6 +93,14 @@ type Config struct {
 	RequestRewriters map[string]*rwopts.Options `toml:"request_rewriters"`
 	// ReloadConfig provides configurations for in-process config reloading
 	ReloadConfig *reload.Options `toml:"reloading"`
+	// DebugConfig provides configurations for the runtime debugging endpoints
+	DebugConfig *debug.Options `toml:"debug"`
+	// AdminConfig provides configurations for the Admin API
+	AdminConfig *admin.Options `toml:"admin"`
+	// Secrets is a map of Secrets Providers from which config values can be fetched
+	Secrets map[string]*so.Options `toml:"secrets"`
+	// Spiffe provides the SPIFFE workload identity used for frontend and upstream mTLS
+	Spiffe *spiffe.Options `toml:"spiffe"`
 
 	// Resources holds runtime resources uses by the Config
 	Resources *Resources `toml:"-"`
@@ -80,6 +109,8 @@ type Config struct {
 	activeCaches       map[string]bool
 	providedOriginURL  string
 	providedOriginType string
+	secretProviders    secrets.Providers
+	secretValues       map[string]string
 
 	LoaderWarnings []string `toml:"-"`
 }
@@ -96,12 +127,41 @@ type MainConfig struct {
 	ReloadHandlerPath string `toml:"reload_handler_path"`
 	// HeatlHandlerPath provides the base Health Check Handler path
 	HealthHandlerPath string `toml:"health_handler_path"`
-	// PprofServer provides the name of the http listener that will host the pprof debugging routes
-	// Options are: "metrics", "reload", "both", or "off"; default is both
+	// LogLevelHandlerPath provides the path to register the Log Level Handler, which reports
+	// and changes the instance and origin log levels at runtime
+	LogLevelHandlerPath string `toml:"log_level_handler_path"`
+	// BatchHandlerPath provides the path to register the Batch Handler, which runs a batch of
+	// queries through the configured origins in a single request
+	BatchHandlerPath string `toml:"batch_handler_path"`
+	// BatchConcurrency is the number of the queries of a batch request that are run at a time
+	BatchConcurrency int `toml:"batch_concurrency"`
+	// InspectHandlerPath provides the path prefix to register the Query Inspection Handler,
+	// which reports how an origin's caching engine would serve a request, without serving it
+	InspectHandlerPath string `toml:"inspect_handler_path"`
+	// Simulate, when true, serves synthetic Prometheus and HTTP origins from within the process,
+	// and adds origins for them to the configuration, so that Trickster can be evaluated without
+	// a real backend
+	Simulate bool `toml:"simulate"`
+	// SimulatorListenPort is the port on the loopback interface on which the simulated
+	// origins are served
+	SimulatorListenPort int `toml:"simulator_listen_port"`
+	// PprofServer provides the name of the http listener that will host the pprof, expvar and
+	// runtime snapshot debugging routes. Options are: "metrics", "reload", "both", "debug"
+	// (a dedicated listener configured in the debug section), or "off"; default is both
 	PprofServer string `toml:"pprof_server"`
 	// ServerName represents the server name that is conveyed in Via headers to upstream origins
 	// defaults to os.Hostname
 	ServerName string `toml:"server_name"`
+	// StrictConfig causes unknown keys in the config file to fail config loading, rather than
+	// be reported as warnings
+	StrictConfig bool `toml:"strict_config"`
+	// LockHoldWarningMS is the duration in milliseconds after which a cache key lock that is
+	// still held is counted as a long hold and logged as a warning. 0 disables the warning
+	LockHoldWarningMS int `toml:"lock_hold_warning_ms"`
+	// MemoryBudgetMB is the size in megabytes of the memory that the caching engines may
+	// buffer for upstream responses and merged timeseries across all in-flight requests.
+	// While it is exceeded, new cacheable requests are proxied without caching. 0 is unlimited
+	MemoryBudgetMB int `toml:"memory_budget_mb"`
 
 	// ReloaderLock is used to lock the config for reloading
 	ReloaderLock sync.Mutex `toml:"-"`
@@ -118,12 +178,27 @@ type FrontendConfig struct {
 	ListenAddress string `toml:"listen_address"`
 	// ListenPort is TCP Port for the main http listener for the application
 	ListenPort int `toml:"listen_port"`
+	// ListenFamily is the address family (ipv4, ipv6 or dual) of the main http listener.
+	// When empty, the listener binds to the network the ListenAddress resolves to
+	ListenFamily string `toml:"listen_family"`
 	// TLSListenAddress is IP address for the tls  http listener for the application
 	TLSListenAddress string `toml:"tls_listen_address"`
 	// TLSListenPort is the TCP Port for the tls http listener for the application
 	TLSListenPort int `toml:"tls_listen_port"`
+	// TLSListenFamily is the address family (ipv4, ipv6 or dual) of the tls http listener
+	TLSListenFamily string `toml:"tls_listen_family"`
 	// ConnectionsLimit indicates how many concurrent front end connections trickster will handle at any time
 	ConnectionsLimit int `toml:"connections_limit"`
+	// SecurityHeaders is the name of the security header preset ('none' or 'standard') whose
+	// headers are added to every frontend response that does not already have them
+	SecurityHeaders string `toml:"security_headers"`
+	// ContentSecurityPolicy is the Content-Security-Policy header value added by the
+	// security header preset
+	ContentSecurityPolicy string `toml:"content_security_policy"`
+	// TLSWatchIntervalSecs is the interval at which the certificate and key files of the tls
+	// listener are checked for changes, which are then served without a config reload.
+	// 0 disables the checks
+	TLSWatchIntervalSecs int `toml:"tls_watch_interval_secs"`
 
 	// ServeTLS indicates whether to listen and serve on the TLS port, meaning
 	// at least one origin configuration has a valid certificate and key file configured.
@@ -136,6 +211,27 @@ type LoggingConfig struct {
 	LogFile string `toml:"log_file"`
 	// LogLevel provides the most granular level (e.g., DEBUG, INFO, ERROR) to log
 	LogLevel string `toml:"log_level"`
+	// LogMaxSizeMB is the size, in megabytes, at which the log file is rotated
+	LogMaxSizeMB int `toml:"log_max_size_mb"`
+	// LogMaxBackups is the number of rotated log files to retain. 0 retains all of them
+	LogMaxBackups int `toml:"log_max_backups"`
+	// LogMaxAgeDays is the number of days to retain rotated log files. 0 retains them regardless of age
+	LogMaxAgeDays int `toml:"log_max_age_days"`
+	// LogCompress indicates whether rotated log files are gzip-compressed
+	LogCompress bool `toml:"log_compress"`
+	// SyslogAddress is the address of a syslog server (e.g., udp://127.0.0.1:514 or unix:///dev/log).
+	// When set, log events are sent to the syslog server in RFC 5424 format instead of the LogFile
+	SyslogAddress string `toml:"syslog_address"`
+	// SyslogFacility is the facility of log events sent to the syslog server (e.g., daemon, local0)
+	SyslogFacility string `toml:"syslog_facility"`
+	// ErrorsToStderr indicates whether error and fatal events are written to stderr instead of
+	// stdout, when logging to the console
+	ErrorsToStderr bool `toml:"errors_to_stderr"`
+	// AccessLog provides the configuration for the structured per-request access log
+	AccessLog *accesslog.Options `toml:"access_log"`
+	// SlowLog provides the configuration for the log of origin fetches that exceed their
+	// origin's slow_log_threshold_ms
+	SlowLog *slowlog.Options `toml:"slow_log"`
 }
 
 // MetricsConfig is a collection of Metrics Collection configurations
@@ -144,12 +240,25 @@ type MetricsConfig struct {
 	ListenAddress string `toml:"listen_address"`
 	// ListenPort is TCP Port from which the Application Metrics are available for pulling at /metrics
 	ListenPort int `toml:"listen_port"`
+	// ListenFamily is the address family (ipv4, ipv6 or dual) of the metrics listener
+	ListenFamily string `toml:"listen_family"`
+	// ProxyDurationBuckets is the list of histogram bucket upper bounds, in seconds, used by
+	// the proxy request duration histogram. Changes require a process restart
+	ProxyDurationBuckets []float64 `toml:"proxy_duration_buckets"`
+	// Exemplars, when true, attaches the trace IDs of sampled requests to the request duration
+	// histograms as exemplars, and serves /metrics in the OpenMetrics format when requested
+	Exemplars bool `toml:"exemplars"`
+	// StatsD provides the configuration for pushing the Application Metrics to a StatsD server
+	StatsD *statsd.Options `toml:"statsd"`
+	// OTLP provides the configuration for exporting the Application Metrics to an OpenTelemetry Collector
+	OTLP *otlpmetrics.Options `toml:"otlp"`
 }
 
 // Resources is a collection of values used by configs at runtime that are not part of the config itself
 type Resources struct {
-	QuitChan chan bool `toml:"-"`
-	metadata *toml.MetaData
+	QuitChan        chan bool `toml:"-"`
+	SecretsQuitChan chan bool `toml:"-"`
+	metadata        *toml.MetaData
 }
 
 // NegativeCacheConfig is a collection of response codes and their TTLs
@@ -172,19 +281,33 @@ func NewConfig() *Config {
 			"default": cache.NewOptions(),
 		},
 		Logging: &LoggingConfig{
-			LogFile:  d.DefaultLogFile,
-			LogLevel: d.DefaultLogLevel,
+			LogFile:        d.DefaultLogFile,
+			LogLevel:       d.DefaultLogLevel,
+			LogMaxSizeMB:   d.DefaultLogMaxSizeMB,
+			LogMaxBackups:  d.DefaultLogMaxBackups,
+			LogMaxAgeDays:  d.DefaultLogMaxAgeDays,
+			LogCompress:    d.DefaultLogCompress,
+			SyslogFacility: d.DefaultSyslogFacility,
+			AccessLog:      accesslog.NewOptions(),
+			SlowLog:        slowlog.NewOptions(),
 		},
 		Main: &MainConfig{
-			ConfigHandlerPath: d.DefaultConfigHandlerPath,
-			PingHandlerPath:   d.DefaultPingHandlerPath,
-			ReloadHandlerPath: d.DefaultReloadHandlerPath,
-			HealthHandlerPath: d.DefaultHealthHandlerPath,
-			PprofServer:       d.DefaultPprofServerName,
-			ServerName:        hn,
+			ConfigHandlerPath:   d.DefaultConfigHandlerPath,
+			PingHandlerPath:     d.DefaultPingHandlerPath,
+			ReloadHandlerPath:   d.DefaultReloadHandlerPath,
+			HealthHandlerPath:   d.DefaultHealthHandlerPath,
+			LogLevelHandlerPath: d.DefaultLogLevelHandlerPath,
+			BatchHandlerPath:    d.DefaultBatchHandlerPath,
+			BatchConcurrency:    d.DefaultBatchConcurrency,
+			InspectHandlerPath:  d.DefaultInspectHandlerPath,
+			SimulatorListenPort: d.DefaultSimulatorListenPort,
+			PprofServer:         d.DefaultPprofServerName,
+			ServerName:          hn,
 		},
 		Metrics: &MetricsConfig{
 			ListenPort: d.DefaultMetricsListenPort,
+			StatsD:     statsd.NewOptions(),
+			OTLP:       otlpmetrics.NewOptions(),
 		},
 		Origins: map[string]*origins.Options{
 			"default": origins.NewOptions(),
@@ -202,9 +325,13 @@ func NewConfig() *Config {
 			"default": tracing.NewOptions(),
 		},
 		ReloadConfig:   reload.NewOptions(),
+		DebugConfig:    debug.NewOptions(),
+		AdminConfig:    admin.NewOptions(),
+		Spiffe:         spiffe.NewOptions(),
 		LoaderWarnings: make([]string, 0),
 		Resources: &Resources{
-			QuitChan: make(chan bool, 1),
+			QuitChan:        make(chan bool, 1),
+			SecretsQuitChan: make(chan bool, 1),
 		},
 	}
 }
@@ -231,7 +358,18 @@ func (c *Config) loadTOMLConfig(tml string, flags *Flags) error {
 		c.setDefaults(&toml.MetaData{})
 		return err
 	}
-	err = c.setDefaults(&md)
+	var errs ValidationErrors
+	// unknown keys are errors when validating a config, so that typos are caught before
+	// it is deployed
+	for _, e := range unknownKeys(&md) {
+		if c.Main.StrictConfig || flags.ValidateConfig {
+			errs = append(errs, e)
+			continue
+		}
+		c.LoaderWarnings = append(c.LoaderWarnings, e.Error())
+	}
+	errs.Append("", c.setDefaults(&md))
+	err = errs.Err()
 	if err == nil {
 		c.Main.configFilePath = flags.ConfigPath
 		c.Main.configLastModified = c.CheckFileLastModified()
@@ -255,37 +393,111 @@ func (c *Config) setDefaults(metadata *toml.MetaData) error {
 
 	c.Resources.metadata = metadata
 
-	var err error
+	var errs ValidationErrors
 
-	if err = c.processPprofConfig(); err != nil {
-		return err
+	errs.Append("main.pprof_server", c.processPprofConfig())
+
+	if err := so.ProcessSecretsOptions(c.Secrets, metadata); err != nil {
+		// secrets must be resolvable before any other values can be validated
+		errs.Append("secrets", err)
+		return errs.Err()
+	}
+
+	if err := c.resolveSecrets(); err != nil {
+		errs.Append("secrets", err)
+		return errs.Err()
 	}
 
 	if c.RequestRewriters != nil {
+		var err error
 		if c.CompiledRewriters, err = rewriter.ProcessConfigs(c.RequestRewriters); err != nil {
-			return err
+			errs.Append("request_rewriters", err)
 		}
 	}
 
-	if err = c.processOriginConfigs(metadata); err != nil {
-		return err
+	if err := c.processOriginConfigs(metadata); err != nil {
+		errs.Append("origins", err)
+		if metadata == nil {
+			return errs.Err()
+		}
 	}
 
-	tracing.ProcessTracingOptions(c.TracingConfigs, metadata)
+	errs.Append("tracing", tracing.ProcessTracingOptions(c.TracingConfigs, metadata))
 
-	if err = c.processCachingConfigs(metadata); err != nil {
-		return err
+	if c.Logging != nil {
+		errs.Append("logging", c.Logging.validate())
+	}
+	if c.Logging != nil && c.Logging.AccessLog != nil {
+		errs.Append("logging.access_log", c.Logging.AccessLog.Validate())
+	}
+	if c.Metrics != nil {
+		errs.Append("metrics.proxy_duration_buckets",
+			metrics.ValidateBuckets(c.Metrics.ProxyDurationBuckets))
+	}
+	if c.Metrics != nil && c.Metrics.StatsD != nil {
+		errs.Append("metrics.statsd", c.Metrics.StatsD.Validate())
+	}
+	if c.Metrics != nil && c.Metrics.OTLP != nil {
+		errs.Append("metrics.otlp", c.Metrics.OTLP.Validate())
 	}
 
-	if err = c.validateConfigMappings(); err != nil {
-		return err
+	if c.Spiffe != nil {
+		errs.Append("spiffe", c.Spiffe.Validate())
 	}
 
-	if err = c.validateTLSConfigs(); err != nil {
-		return err
+	errs.Append("caches", c.processCachingConfigs(metadata))
+	errs.Append("", c.validateConfigMappings())
+	errs.Append("", c.validateTLSConfigs())
+	errs.Append("", c.validateListenFamilies())
+	if c.Frontend != nil {
+		if _, ok := headers.SecurityHeaders(c.Frontend.SecurityHeaders, ""); !ok {
+			errs.Add("frontend.security_headers",
+				suggest(c.Frontend.SecurityHeaders, securityHeaderPresets),
+				"invalid security headers preset %s", c.Frontend.SecurityHeaders)
+		}
+		if c.Frontend.TLSWatchIntervalSecs < 0 {
+			errs.Add("frontend.tls_watch_interval_secs", "",
+				"invalid tls watch interval secs: %d", c.Frontend.TLSWatchIntervalSecs)
+		}
+	}
+	if c.ReloadConfig != nil {
+		_, err := c.ReloadConfig.TLSConfig()
+		errs.Append("reloading", err)
+		errs.Append("reloading", c.ReloadConfig.Validate())
+		// a staged config can only be promoted through the Admin API
+		if c.ReloadConfig.Staged() && (c.AdminConfig == nil || !c.AdminConfig.Enabled()) {
+			errs.Add("reloading.canary_listen_port", "",
+				"canary_listen_port requires the Admin API, which is enabled by admin.auth_token")
+		}
 	}
 
-	return nil
+	return errs.Err()
+}
+
+// validateListenFamilies validates the listen address and address family of each listener
+func (c *Config) validateListenFamilies() error {
+	var errs ValidationErrors
+	check := func(path, address, f string) {
+		if err := family.Validate(address, f); err != nil {
+			errs.Add(path, "", "%s", err.Error())
+		}
+	}
+	if c.Frontend != nil {
+		check("frontend.listen_family", c.Frontend.ListenAddress, c.Frontend.ListenFamily)
+		check("frontend.tls_listen_family", c.Frontend.TLSListenAddress,
+			c.Frontend.TLSListenFamily)
+	}
+	if c.Metrics != nil {
+		check("metrics.listen_family", c.Metrics.ListenAddress, c.Metrics.ListenFamily)
+	}
+	if c.ReloadConfig != nil {
+		check("reloading.listen_family", c.ReloadConfig.ListenAddress,
+			c.ReloadConfig.ListenFamily)
+	}
+	if c.DebugConfig != nil {
+		check("debug.listen_family", c.DebugConfig.ListenAddress, c.DebugConfig.ListenFamily)
+	}
+	return errs.Err()
 }
 
 // ErrInvalidPprofServerName returns an error for invalid pprof server name
@@ -293,7 +505,7 @@ var ErrInvalidPprofServerName = errors.New("invalid pprof server name")
 
 func (c *Config) processPprofConfig() error {
 	switch c.Main.PprofServer {
-	case "metrics", "reload", "off", "both":
+	case "metrics", "reload", "debug", "off", "both":
 		return nil
 	case "":
 		c.Main.PprofServer = d.DefaultPprofServerName
@@ -302,49 +514,101 @@ func (c *Config) processPprofConfig() error {
 	return ErrInvalidPprofServerName
 }
 
+// ErrInvalidLogRotation returns an error for negative log rotation settings
+var ErrInvalidLogRotation = errors.New("log rotation settings must not be negative")
+
+func (lc *LoggingConfig) validate() error {
+	if lc.LogMaxSizeMB < 0 || lc.LogMaxBackups < 0 || lc.LogMaxAgeDays < 0 {
+		return ErrInvalidLogRotation
+	}
+	if lc.SyslogAddress == "" {
+		return nil
+	}
+	if _, _, err := syslog.ParseAddress(lc.SyslogAddress); err != nil {
+		return err
+	}
+	if _, ok := syslog.Facilities[strings.ToLower(lc.SyslogFacility)]; !ok {
+		return fmt.Errorf("invalid syslog facility: %s", lc.SyslogFacility)
+	}
+	return nil
+}
+
 func (c *Config) validateTLSConfigs() error {
-	for _, oc := range c.Origins {
+	var errs ValidationErrors
+	// the SVID serves the TLS listener in place of the origins' certificates
+	if c.Spiffe.Enabled() && c.Spiffe.FrontendMTLS {
+		c.Frontend.ServeTLS = true
+	}
+	for k, oc := range c.Origins {
+		if oc.SpiffeUpstream {
+			if !c.Spiffe.Enabled() {
+				errs.Add(keyPath("origins", k, "spiffe_upstream"), "",
+					"spiffe_upstream requires the spiffe svid_cert_path")
+				continue
+			}
+			if err := spiffe.ValidateIDs(oc.SpiffeUpstreamIDs); err != nil {
+				errs.Append(keyPath("origins", k, "spiffe_upstream_ids"), err)
+				continue
+			}
+		}
 		if oc.TLS != nil {
 			b, err := oc.TLS.Validate()
 			if err != nil {
-				return err
+				errs.Append(keyPath("origins", k, "tls"), err)
+				continue
 			}
 			if b {
 				c.Frontend.ServeTLS = true
 			}
 		}
 	}
-	return nil
+	return errs.Err()
 }
 
 var pathMembers = []string{"path", "match_type", "handler", "methods", "cache_key_params",
 	"cache_key_headers", "default_ttl_secs", "request_headers", "response_headers",
 	"response_headers", "response_code", "response_body", "no_metrics", "collapsed_forwarding",
-	"req_rewriter_name",
+	"req_rewriter_name", "backfill_tolerance_secs", "cache_key_json_paths", "request_hook_name",
+	"response_hook_name", "cache_key_principal", "streaming", "stream_idle_timeout_secs",
+	"cache_key_template", "max_lookback_secs", "max_range_secs", "push_paths", "security_headers",
+	"cache_private_responses",
 }
 
+var securityHeaderPresets = []string{headers.SecurityPresetNone, headers.SecurityPresetStandard}
+
 func (c *Config) validateConfigMappings() error {
+	var errs ValidationErrors
 	for k, oc := range c.Origins {
 
 		if err := origins.ValidateOriginName(k); err != nil {
-			return err
+			errs.Append(keyPath("origins", k), err)
+			continue
+		}
+
+		if len(oc.Hosts) > 0 {
+			if _, err := hosts.Compile(oc.Hosts); err != nil {
+				errs.Append(keyPath("origins", k, "hosts"), err)
+			}
 		}
 
 		if oc.OriginType == "rule" {
 			// Rule Type Validations
 			r, ok := c.Rules[oc.RuleName]
 			if !ok {
-				return fmt.Errorf("invalid rule name [%s] provided in origin config [%s]", oc.RuleName, k)
+				errs.Add(keyPath("origins", k, "rule_name"), suggestKey(oc.RuleName, c.Rules),
+					"invalid rule name [%s] provided in origin config [%s]", oc.RuleName, k)
+				continue
 			}
 			r.Name = oc.RuleName
 			oc.RuleOptions = r
 		} else // non-Rule Type Validations
 		if _, ok := c.Caches[oc.CacheName]; !ok {
-			return fmt.Errorf("invalid cache name [%s] provided in origin config [%s]", oc.CacheName, k)
+			errs.Add(keyPath("origins", k, "cache_name"), suggestKey(oc.CacheName, c.Caches),
+				"invalid cache name [%s] provided in origin config [%s]", oc.CacheName, k)
 		}
 
 	}
-	return nil
+	return errs.Err()
 }
 
 func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
@@ -355,6 +619,8 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 
 	c.activeCaches = make(map[string]bool)
 
+	var errs ValidationErrors
+
 	for k, v := range c.Origins {
 
 		oc := origins.NewOptions()
@@ -364,8 +630,9 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 			oc.ReqRewriterName = v.ReqRewriterName
 			ri, ok := c.CompiledRewriters[oc.ReqRewriterName]
 			if !ok {
-				return fmt.Errorf("invalid rewriter name %s in origin config %s",
-					oc.ReqRewriterName, k)
+				errs.Add(keyPath("origins", k, "req_rewriter_name"),
+					suggestKey(oc.ReqRewriterName, c.RequestRewriters),
+					"invalid rewriter name %s in origin config %s", oc.ReqRewriterName, k)
 			}
 			oc.ReqRewriter = ri
 		}
@@ -403,6 +670,14 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 			oc.RequireTLS = v.RequireTLS
 		}
 
+		if metadata.IsDefined("origins", k, "spiffe_upstream") {
+			oc.SpiffeUpstream = v.SpiffeUpstream
+		}
+
+		if metadata.IsDefined("origins", k, "spiffe_upstream_ids") {
+			oc.SpiffeUpstreamIDs = v.SpiffeUpstreamIDs
+		}
+
 		if metadata.IsDefined("origins", k, "cache_name") {
 			oc.CacheName = v.CacheName
 		}
@@ -424,6 +699,10 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 			oc.TimeoutSecs = v.TimeoutSecs
 		}
 
+		if metadata.IsDefined("origins", k, "deadline_header") {
+			oc.DeadlineHeader = v.DeadlineHeader
+		}
+
 		if metadata.IsDefined("origins", k, "max_idle_conns") {
 			oc.MaxIdleConns = v.MaxIdleConns
 		}
@@ -443,6 +722,178 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 			}
 		}
 
+		if metadata.IsDefined("origins", k, "timeseries_max_extents") {
+			oc.TimeseriesMaxExtents = v.TimeseriesMaxExtents
+		}
+
+		if metadata.IsDefined("origins", k, "timeseries_shard_window_secs") {
+			oc.TimeseriesShardWindowSecs = v.TimeseriesShardWindowSecs
+		}
+
+		if metadata.IsDefined("origins", k, "timeseries_max_age_secs") {
+			oc.TimeseriesMaxAgeSecs = v.TimeseriesMaxAgeSecs
+		}
+
+		if metadata.IsDefined("origins", k, "retention_interval_secs") {
+			oc.RetentionIntervalSecs = v.RetentionIntervalSecs
+		}
+
+		if metadata.IsDefined("origins", k, "timeseries_cache_encoding") {
+			oc.TimeseriesCacheEncoding = strings.ToLower(v.TimeseriesCacheEncoding)
+		}
+
+		if metadata.IsDefined("origins", k, "range_snap") {
+			oc.RangeSnapName = strings.ToLower(v.RangeSnapName)
+		}
+
+		if metadata.IsDefined("origins", k, "min_step_secs") {
+			oc.MinStepSecs = v.MinStepSecs
+		}
+
+		if metadata.IsDefined("origins", k, "max_result_series") {
+			oc.MaxResultSeries = v.MaxResultSeries
+		}
+
+		if metadata.IsDefined("origins", k, "max_result_samples") {
+			oc.MaxResultSamples = v.MaxResultSamples
+		}
+
+		if metadata.IsDefined("origins", k, "max_data_points") {
+			oc.MaxDataPoints = v.MaxDataPoints
+		}
+
+		if metadata.IsDefined("origins", k, "data_point_aggregation") {
+			oc.DataPointAggregationName = strings.ToLower(v.DataPointAggregationName)
+		}
+
+		if metadata.IsDefined("origins", k, "value_precision") {
+			oc.ValuePrecision = v.ValuePrecision
+		}
+
+		if metadata.IsDefined("origins", k, "value_precision_on_serve") {
+			oc.ValuePrecisionOnServe = v.ValuePrecisionOnServe
+		}
+
+		if metadata.IsDefined("origins", k, "dedupe_labels") {
+			oc.DedupeLabels = v.DedupeLabels
+		}
+
+		if metadata.IsDefined("origins", k, "injected_labels") {
+			oc.InjectedLabels = v.InjectedLabels
+		}
+
+		if metadata.IsDefined("origins", k, "injected_labels_jwt_secret") {
+			oc.InjectedLabelsJWTSecret = v.InjectedLabelsJWTSecret
+		}
+
+		if metadata.IsDefined("origins", k, "injected_labels_jwt_key_path") {
+			oc.InjectedLabelsJWTKeyPath = v.InjectedLabelsJWTKeyPath
+		}
+
+		if metadata.IsDefined("origins", k, "injected_labels_trusted_proxies") {
+			oc.InjectedLabelsTrustedProxies = v.InjectedLabelsTrustedProxies
+		}
+
+		if metadata.IsDefined("origins", k, "result_limit_action") {
+			oc.ResultLimitActionName = strings.ToLower(v.ResultLimitActionName)
+		}
+
+		if metadata.IsDefined("origins", k, "max_lookback_secs") {
+			oc.MaxLookbackSecs = v.MaxLookbackSecs
+		}
+
+		if metadata.IsDefined("origins", k, "max_range_secs") {
+			oc.MaxRangeSecs = v.MaxRangeSecs
+		}
+
+		if metadata.IsDefined("origins", k, "time_range_limit_action") {
+			oc.TimeRangeLimitActionName = strings.ToLower(v.TimeRangeLimitActionName)
+		}
+
+		if metadata.IsDefined("origins", k, "delta_fetch_chunks") {
+			oc.DeltaFetchChunks = v.DeltaFetchChunks
+		}
+
+		if metadata.IsDefined("origins", k, "delta_fetch_min_chunk_secs") {
+			oc.DeltaFetchMinChunkSecs = v.DeltaFetchMinChunkSecs
+		}
+
+		if metadata.IsDefined("origins", k, "delta_fetch_parallelism") {
+			oc.DeltaFetchParallelism = v.DeltaFetchParallelism
+		}
+
+		if metadata.IsDefined("origins", k, "delta_verify_steps") {
+			oc.DeltaVerifySteps = v.DeltaVerifySteps
+		}
+
+		if metadata.IsDefined("origins", k, "delta_verify_invalidate") {
+			oc.DeltaVerifyInvalidate = v.DeltaVerifyInvalidate
+		}
+
+		if metadata.IsDefined("origins", k, "warm_connections") {
+			oc.WarmConnections = v.WarmConnections
+		}
+
+		if metadata.IsDefined("origins", k, "hedge_urls") {
+			oc.HedgeURLs = v.HedgeURLs
+		}
+
+		if metadata.IsDefined("origins", k, "hedge_quantile") {
+			oc.HedgeQuantile = v.HedgeQuantile
+		}
+
+		if metadata.IsDefined("origins", k, "hedge_min_delay_ms") {
+			oc.HedgeMinDelayMS = v.HedgeMinDelayMS
+		}
+
+		if metadata.IsDefined("origins", k, "target_urls") {
+			oc.TargetURLs = v.TargetURLs
+		}
+
+		if metadata.IsDefined("origins", k, "target_selection") {
+			oc.TargetSelection = v.TargetSelection
+		}
+
+		if metadata.IsDefined("origins", k, "target_weights") {
+			oc.TargetWeights = v.TargetWeights
+		}
+
+		if metadata.IsDefined("origins", k, "cache_buster_params") {
+			oc.CacheBusterParams = v.CacheBusterParams
+		}
+
+		if metadata.IsDefined("origins", k, "static_hosts") {
+			oc.StaticHosts = v.StaticHosts
+		}
+
+		if metadata.IsDefined("origins", k, "dns_resolver") {
+			oc.DNSResolver = v.DNSResolver
+		}
+
+		if metadata.IsDefined("origins", k, "priority_header") {
+			oc.PriorityHeader = v.PriorityHeader
+		}
+
+		if metadata.IsDefined("origins", k, "default_priority_class") {
+			oc.DefaultPriorityClass = v.DefaultPriorityClass
+		}
+
+		if metadata.IsDefined("origins", k, "priority_classes") {
+			oc.PriorityClasses = make(map[string]*pro.Options)
+			for l, c := range v.PriorityClasses {
+				c.Name = l
+				oc.PriorityClasses[l] = c
+			}
+		}
+
+		if metadata.IsDefined("origins", k, "downsample_tiers") {
+			oc.DownsampleTiers = make(map[string]*ds.Options)
+			for l, t := range v.DownsampleTiers {
+				t.Name = l
+				oc.DownsampleTiers[l] = t
+			}
+		}
+
 		if metadata.IsDefined("origins", k, "timeseries_ttl_secs") {
 			oc.TimeseriesTTLSecs = v.TimeseriesTTLSecs
 		}
@@ -455,6 +906,26 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 			oc.FastForwardTTLSecs = v.FastForwardTTLSecs
 		}
 
+		if metadata.IsDefined("origins", k, "empty_quarantine_ttl_secs") {
+			oc.EmptyQuarantineTTLSecs = v.EmptyQuarantineTTLSecs
+		}
+
+		if metadata.IsDefined("origins", k, "select_cache_ttl_secs") {
+			oc.SelectCacheTTLSecs = v.SelectCacheTTLSecs
+		}
+
+		if metadata.IsDefined("origins", k, "status_cache_ttl_secs") {
+			oc.StatusCacheTTLSecs = v.StatusCacheTTLSecs
+		}
+
+		if metadata.IsDefined("origins", k, "federate_cache_ttl_secs") {
+			oc.FederateCacheTTLSecs = v.FederateCacheTTLSecs
+		}
+
+		if metadata.IsDefined("origins", k, "remote_write_invalidation_secs") {
+			oc.RemoteWriteInvalidationSecs = v.RemoteWriteInvalidationSecs
+		}
+
 		if metadata.IsDefined("origins", k, "fast_forward_disable") {
 			oc.FastForwardDisable = v.FastForwardDisable
 		}
@@ -463,6 +934,14 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 			oc.BackfillToleranceSecs = v.BackfillToleranceSecs
 		}
 
+		if metadata.IsDefined("origins", k, "backfill_tolerance_rules") {
+			oc.BackfillToleranceRules = make(map[string]*bf.Options)
+			for l, r := range v.BackfillToleranceRules {
+				r.Name = l
+				oc.BackfillToleranceRules[l] = r
+			}
+		}
+
 		if metadata.IsDefined("origins", k, "paths") {
 			var j = 0
 			for l, p := range v.Paths {
@@ -470,11 +949,35 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 					p.ReqRewriterName != "" {
 					ri, ok := c.CompiledRewriters[p.ReqRewriterName]
 					if !ok {
-						return fmt.Errorf("invalid rewriter name %s in path %s of origin config %s",
+						errs.Add(keyPath("origins", k, "paths", l, "req_rewriter_name"),
+							suggestKey(p.ReqRewriterName, c.RequestRewriters),
+							"invalid rewriter name %s in path %s of origin config %s",
 							p.ReqRewriterName, l, k)
 					}
 					p.ReqRewriter = ri
 				}
+				if metadata.IsDefined("origins", k, "paths", l, "request_hook_name") &&
+					p.RequestHookName != "" {
+					h, ok := hooks.GetRequestHook(p.RequestHookName)
+					if !ok {
+						errs.Add(keyPath("origins", k, "paths", l, "request_hook_name"),
+							suggest(p.RequestHookName, hooks.RequestHookNames()),
+							"invalid request hook name %s in path %s of origin config %s",
+							p.RequestHookName, l, k)
+					}
+					p.RequestHook = []hooks.RequestHook{h}
+				}
+				if metadata.IsDefined("origins", k, "paths", l, "response_hook_name") &&
+					p.ResponseHookName != "" {
+					h, ok := hooks.GetResponseHook(p.ResponseHookName)
+					if !ok {
+						errs.Add(keyPath("origins", k, "paths", l, "response_hook_name"),
+							suggest(p.ResponseHookName, hooks.ResponseHookNames()),
+							"invalid response hook name %s in path %s of origin config %s",
+							p.ResponseHookName, l, k)
+					}
+					p.ResponseHook = []hooks.ResponseHook{h}
+				}
 				if len(p.Methods) == 0 {
 					p.Methods = []string{http.MethodGet, http.MethodHead}
 				}
@@ -488,9 +991,102 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 					p.ResponseBodyBytes = []byte(p.ResponseBody)
 					p.HasCustomResponseBody = true
 				}
+				if metadata.IsDefined("origins", k, "paths", l, "backfill_tolerance_secs") {
+					p.BackfillTolerance = time.Duration(p.BackfillToleranceSecs) * time.Second
+					p.HasBackfillTolerance = true
+				}
+				if metadata.IsDefined("origins", k, "paths", l, "max_lookback_secs") {
+					if p.MaxLookbackSecs < 0 {
+						errs.Add(keyPath("origins", k, "paths", l, "max_lookback_secs"), "",
+							"invalid max lookback secs %d in path %s of origin config %s",
+							p.MaxLookbackSecs, l, k)
+					}
+					p.MaxLookback = time.Duration(p.MaxLookbackSecs) * time.Second
+					p.HasMaxLookback = true
+				}
+				if metadata.IsDefined("origins", k, "paths", l, "max_range_secs") {
+					if p.MaxRangeSecs < 0 {
+						errs.Add(keyPath("origins", k, "paths", l, "max_range_secs"), "",
+							"invalid max range secs %d in path %s of origin config %s",
+							p.MaxRangeSecs, l, k)
+					}
+					p.MaxRange = time.Duration(p.MaxRangeSecs) * time.Second
+					p.HasMaxRange = true
+				}
+				if metadata.IsDefined("origins", k, "paths", l, "stream_idle_timeout_secs") {
+					if p.StreamIdleTimeoutSecs < 0 {
+						errs.Add(keyPath("origins", k, "paths", l, "stream_idle_timeout_secs"), "",
+							"invalid stream idle timeout secs %d in path %s of origin config %s",
+							p.StreamIdleTimeoutSecs, l, k)
+					}
+					p.StreamIdleTimeout = time.Duration(p.StreamIdleTimeoutSecs) * time.Second
+				}
+				if metadata.IsDefined("origins", k, "paths", l, "security_headers") {
+					h, ok := headers.SecurityHeaders(p.SecurityHeaders,
+						c.Frontend.ContentSecurityPolicy)
+					if !ok {
+						errs.Add(keyPath("origins", k, "paths", l, "security_headers"),
+							suggest(p.SecurityHeaders, securityHeaderPresets),
+							"invalid security headers preset %s in path %s of origin config %s",
+							p.SecurityHeaders, l, k)
+					}
+					p.SecurityHeaderSet = h
+				}
+				if metadata.IsDefined("origins", k, "paths", l, "push_paths") {
+					for _, pp := range p.PushPaths {
+						if !strings.HasPrefix(pp, "/") {
+							errs.Add(keyPath("origins", k, "paths", l, "push_paths"), "",
+								"invalid push path %s in path %s of origin config %s", pp, l, k)
+						}
+					}
+				}
+				if p.Streaming && p.ResponseHookName != "" {
+					errs.Add(keyPath("origins", k, "paths", l, "response_hook_name"), "",
+						"response hook %s can't be used by streaming path %s of origin config %s",
+						p.ResponseHookName, l, k)
+				}
+				if metadata.IsDefined("origins", k, "paths", l, "cache_key_json_paths") {
+					p.JSONPaths = make([]jsonpath.Path, 0, len(p.CacheKeyJSONPaths))
+					for _, expr := range p.CacheKeyJSONPaths {
+						jp, err := jsonpath.Parse(expr)
+						if err != nil {
+							errs.Add(keyPath("origins", k, "paths", l, "cache_key_json_paths"), "",
+								"invalid cache key json path: %s", expr)
+							continue
+						}
+						p.JSONPaths = append(p.JSONPaths, jp)
+					}
+				}
+				if metadata.IsDefined("origins", k, "paths", l, "cache_key_principal") &&
+					p.CacheKeyPrincipal != "" {
+					src, err := tenancy.ParseSource(p.CacheKeyPrincipal)
+					if err != nil {
+						errs.Add(keyPath("origins", k, "paths", l, "cache_key_principal"), "",
+							"invalid cache key principal %s in path %s of origin config %s",
+							p.CacheKeyPrincipal, l, k)
+					}
+					p.Principal = src
+				}
+				if p.CachePrivateResponses && p.CacheKeyPrincipal == "" {
+					errs.Add(keyPath("origins", k, "paths", l, "cache_private_responses"), "",
+						"cache_private_responses requires a cache_key_principal in path %s of origin config %s",
+						l, k)
+				}
+				if metadata.IsDefined("origins", k, "paths", l, "cache_key_template") &&
+					p.CacheKeyTemplate != "" {
+					tpl, err := key.ParseTemplate(p.CacheKeyTemplate)
+					if err != nil {
+						errs.Add(keyPath("origins", k, "paths", l, "cache_key_template"), "",
+							"invalid cache key template in path %s of origin config %s: %s",
+							l, k, err.Error())
+					}
+					p.KeyTemplate = tpl
+				}
 				if metadata.IsDefined("origins", k, "paths", l, "collapsed_forwarding") {
 					if _, ok := forwarding.CollapsedForwardingTypeNames[p.CollapsedForwardingName]; !ok {
-						return fmt.Errorf("invalid collapsed_forwarding name: %s", p.CollapsedForwardingName)
+						errs.Add(keyPath("origins", k, "paths", l, "collapsed_forwarding"),
+							suggestKey(p.CollapsedForwardingName, forwarding.CollapsedForwardingTypeNames),
+							"invalid collapsed_forwarding name: %s", p.CollapsedForwardingName)
 					}
 					p.CollapsedForwardingType =
 						forwarding.GetCollapsedForwardingType(p.CollapsedForwardingName)
@@ -517,6 +1113,14 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 			oc.TracingConfigName = v.TracingConfigName
 		}
 
+		if metadata.IsDefined("origins", k, "log_level") {
+			oc.LogLevel = v.LogLevel
+		}
+
+		if metadata.IsDefined("origins", k, "slow_log_threshold_ms") {
+			oc.SlowLogThresholdMS = v.SlowLogThresholdMS
+		}
+
 		if metadata.IsDefined("origins", k, "health_check_upstream_path") {
 			oc.HealthCheckUpstreamPath = v.HealthCheckUpstreamPath
 		}
@@ -533,10 +1137,30 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 			oc.HealthCheckHeaders = v.HealthCheckHeaders
 		}
 
+		if metadata.IsDefined("origins", k, "health_check_interval_secs") {
+			oc.HealthCheckIntervalSecs = v.HealthCheckIntervalSecs
+		}
+
+		if metadata.IsDefined("origins", k, "health_check_failure_threshold") {
+			oc.HealthCheckFailureThreshold = v.HealthCheckFailureThreshold
+		}
+
+		if metadata.IsDefined("origins", k, "degraded_mode") {
+			oc.DegradedMode = v.DegradedMode
+		}
+
 		if metadata.IsDefined("origins", k, "max_object_size_bytes") {
 			oc.MaxObjectSizeBytes = v.MaxObjectSizeBytes
 		}
 
+		if metadata.IsDefined("origins", k, "cache_max_body_bytes") {
+			oc.CacheMaxBodyBytes = v.CacheMaxBodyBytes
+		}
+
+		if metadata.IsDefined("origins", k, "cache_content_types") {
+			oc.CacheContentTypes = v.CacheContentTypes
+		}
+
 		if metadata.IsDefined("origins", k, "revalidation_factor") {
 			oc.RevalidationFactor = v.RevalidationFactor
 		}
@@ -549,6 +1173,18 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 			oc.DearticulateUpstreamRanges = v.DearticulateUpstreamRanges
 		}
 
+		if metadata.IsDefined("origins", k, "cache_state_headers") {
+			oc.CacheStateHeaders = v.CacheStateHeaders
+		}
+
+		if metadata.IsDefined("origins", k, "exemplars_disabled") {
+			oc.ExemplarsDisabled = v.ExemplarsDisabled
+		}
+
+		if metadata.IsDefined("origins", k, "query_normalization_disabled") {
+			oc.QueryNormalizationDisabled = v.QueryNormalizationDisabled
+		}
+
 		if metadata.IsDefined("origins", k, "tls") {
 			oc.TLS = &to.Options{
 				InsecureSkipVerify:        v.TLS.InsecureSkipVerify,
@@ -560,15 +1196,25 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 			}
 		}
 
+		if metadata.IsDefined("origins", k, "auth") {
+			oc.Auth = v.Auth
+		}
+
+		if metadata.IsDefined("origins", k, "adaptive_concurrency") {
+			oc.AdaptiveConcurrency = v.AdaptiveConcurrency
+		}
+
 		c.Origins[k] = oc
 	}
-	return nil
+	return errs.Err()
 }
 
 func (c *Config) processCachingConfigs(metadata *toml.MetaData) error {
 
 	// setCachingDefaults assumes that processOriginConfigs was just ran
 
+	var errs ValidationErrors
+
 	for k, v := range c.Caches {
 
 		if _, ok := c.activeCaches[k]; !ok {
@@ -587,6 +1233,38 @@ func (c *Config) processCachingConfigs(metadata *toml.MetaData) error {
 			}
 		}
 
+		if metadata.IsDefined("caches", k, "compression_codec") {
+			cc.CompressionCodec = strings.ToLower(v.CompressionCodec)
+		}
+
+		if cc.CompressionCodec != cache.CompressionNone {
+			if _, ok := cache.CompressionFlags[cc.CompressionCodec]; !ok {
+				errs.Add(keyPath("caches", k, "compression_codec"),
+					suggestKey(cc.CompressionCodec, cache.CompressionFlags),
+					"invalid compression codec: %s", cc.CompressionCodec)
+			} else if _, ok := compress.Get(cc.CompressionCodec); !ok {
+				errs.Add(keyPath("caches", k, "compression_codec"), "",
+					"compression codec is not available in this build: %s", cc.CompressionCodec)
+			}
+		}
+
+		if metadata.IsDefined("caches", k, "compression_min_size_bytes") {
+			cc.CompressionMinSizeBytes = v.CompressionMinSizeBytes
+		}
+
+		if metadata.IsDefined("caches", k, "startup_mode") {
+			cc.StartupMode = strings.ToLower(v.StartupMode)
+		}
+		if !cache.StartupModes[cc.StartupMode] {
+			errs.Add(keyPath("caches", k, "startup_mode"),
+				suggestKey(cc.StartupMode, cache.StartupModes),
+				"invalid startup mode: %s", cc.StartupMode)
+		}
+
+		if metadata.IsDefined("caches", k, "read_only") {
+			cc.ReadOnly = v.ReadOnly
+		}
+
 		if metadata.IsDefined("caches", k, "index", "reap_interval_secs") {
 			cc.Index.ReapIntervalSecs = v.Index.ReapIntervalSecs
 		}
@@ -604,7 +1282,8 @@ func (c *Config) processCachingConfigs(metadata *toml.MetaData) error {
 		}
 
 		if cc.Index.MaxSizeBytes > 0 && cc.Index.MaxSizeBackoffBytes > cc.Index.MaxSizeBytes {
-			return errors.New("MaxSizeBackoffBytes can't be larger than MaxSizeBytes")
+			errs.Add(keyPath("caches", k, "index", "max_size_backoff_bytes"), "",
+				"MaxSizeBackoffBytes can't be larger than MaxSizeBytes")
 		}
 
 		if metadata.IsDefined("caches", k, "index", "max_size_objects") {
@@ -616,7 +1295,8 @@ func (c *Config) processCachingConfigs(metadata *toml.MetaData) error {
 		}
 
 		if cc.Index.MaxSizeObjects > 0 && cc.Index.MaxSizeBackoffObjects > cc.Index.MaxSizeObjects {
-			return errors.New("MaxSizeBackoffObjects can't be larger than MaxSizeObjects")
+			errs.Add(keyPath("caches", k, "index", "max_size_backoff_objects"), "",
+				"MaxSizeBackoffObjects can't be larger than MaxSizeObjects")
 		}
 
 		if cc.CacheTypeID == types.CacheTypeRedis {
@@ -716,6 +1396,19 @@ func (c *Config) processCachingConfigs(metadata *toml.MetaData) error {
 			}
 		}
 
+		if metadata.IsDefined("caches", k, "memory", "snapshot_path") {
+			cc.Memory.SnapshotPath = v.Memory.SnapshotPath
+		}
+
+		if metadata.IsDefined("caches", k, "memory", "snapshot_interval_secs") {
+			cc.Memory.SnapshotIntervalSecs = v.Memory.SnapshotIntervalSecs
+		}
+
+		if cc.Memory.SnapshotPath != "" && cc.Memory.SnapshotIntervalSecs <= 0 {
+			errs.Add(keyPath("caches", k, "memory", "snapshot_interval_secs"), "",
+				"invalid snapshot interval secs: %d", cc.Memory.SnapshotIntervalSecs)
+		}
+
 		if metadata.IsDefined("caches", k, "filesystem", "cache_path") {
 			cc.Filesystem.CachePath = v.Filesystem.CachePath
 		}
@@ -738,7 +1431,7 @@ func (c *Config) processCachingConfigs(metadata *toml.MetaData) error {
 
 		c.Caches[k] = cc
 	}
-	return nil
+	return errs.Err()
 }
 
 // Clone returns an exact copy of the subject *Config
@@ -753,8 +1446,17 @@ func (c *Config) Clone() *Config {
 	nc.Main.PingHandlerPath = c.Main.PingHandlerPath
 	nc.Main.ReloadHandlerPath = c.Main.ReloadHandlerPath
 	nc.Main.HealthHandlerPath = c.Main.HealthHandlerPath
+	nc.Main.LogLevelHandlerPath = c.Main.LogLevelHandlerPath
+	nc.Main.BatchHandlerPath = c.Main.BatchHandlerPath
+	nc.Main.BatchConcurrency = c.Main.BatchConcurrency
+	nc.Main.InspectHandlerPath = c.Main.InspectHandlerPath
+	nc.Main.Simulate = c.Main.Simulate
+	nc.Main.SimulatorListenPort = c.Main.SimulatorListenPort
 	nc.Main.PprofServer = c.Main.PprofServer
 	nc.Main.ServerName = c.Main.ServerName
+	nc.Main.StrictConfig = c.Main.StrictConfig
+	nc.Main.LockHoldWarningMS = c.Main.LockHoldWarningMS
+	nc.Main.MemoryBudgetMB = c.Main.MemoryBudgetMB
 
 	nc.Main.configFilePath = c.Main.configFilePath
 	nc.Main.configLastModified = c.Main.configLastModified
@@ -762,19 +1464,66 @@ func (c *Config) Clone() *Config {
 
 	nc.Logging.LogFile = c.Logging.LogFile
 	nc.Logging.LogLevel = c.Logging.LogLevel
+	nc.Logging.LogMaxSizeMB = c.Logging.LogMaxSizeMB
+	nc.Logging.LogMaxBackups = c.Logging.LogMaxBackups
+	nc.Logging.LogMaxAgeDays = c.Logging.LogMaxAgeDays
+	nc.Logging.LogCompress = c.Logging.LogCompress
+	nc.Logging.SyslogAddress = c.Logging.SyslogAddress
+	nc.Logging.SyslogFacility = c.Logging.SyslogFacility
+	nc.Logging.ErrorsToStderr = c.Logging.ErrorsToStderr
+	if c.Logging.AccessLog != nil {
+		nc.Logging.AccessLog = c.Logging.AccessLog.Clone()
+	}
+	if c.Logging.SlowLog != nil {
+		nc.Logging.SlowLog = c.Logging.SlowLog.Clone()
+	}
 
 	nc.Metrics.ListenAddress = c.Metrics.ListenAddress
 	nc.Metrics.ListenPort = c.Metrics.ListenPort
+	nc.Metrics.ListenFamily = c.Metrics.ListenFamily
+	nc.Metrics.Exemplars = c.Metrics.Exemplars
+	if c.Metrics.ProxyDurationBuckets != nil {
+		nc.Metrics.ProxyDurationBuckets = make([]float64, len(c.Metrics.ProxyDurationBuckets))
+		copy(nc.Metrics.ProxyDurationBuckets, c.Metrics.ProxyDurationBuckets)
+	}
+	if c.Metrics.StatsD != nil {
+		nc.Metrics.StatsD = c.Metrics.StatsD.Clone()
+	}
+	if c.Metrics.OTLP != nil {
+		nc.Metrics.OTLP = c.Metrics.OTLP.Clone()
+	}
+
+	if c.DebugConfig != nil {
+		nc.DebugConfig = c.DebugConfig.Clone()
+	}
+
+	if c.Spiffe != nil {
+		nc.Spiffe = c.Spiffe.Clone()
+	}
+
+	if c.AdminConfig != nil {
+		nc.AdminConfig = c.AdminConfig.Clone()
+	}
+
+	if c.ReloadConfig != nil {
+		nc.ReloadConfig = c.ReloadConfig.Clone()
+	}
 
 	nc.Frontend.ListenAddress = c.Frontend.ListenAddress
 	nc.Frontend.ListenPort = c.Frontend.ListenPort
+	nc.Frontend.ListenFamily = c.Frontend.ListenFamily
 	nc.Frontend.TLSListenAddress = c.Frontend.TLSListenAddress
 	nc.Frontend.TLSListenPort = c.Frontend.TLSListenPort
+	nc.Frontend.TLSListenFamily = c.Frontend.TLSListenFamily
 	nc.Frontend.ConnectionsLimit = c.Frontend.ConnectionsLimit
+	nc.Frontend.SecurityHeaders = c.Frontend.SecurityHeaders
+	nc.Frontend.ContentSecurityPolicy = c.Frontend.ContentSecurityPolicy
+	nc.Frontend.TLSWatchIntervalSecs = c.Frontend.TLSWatchIntervalSecs
 	nc.Frontend.ServeTLS = c.Frontend.ServeTLS
 
 	nc.Resources = &Resources{
-		QuitChan: make(chan bool, 1),
+		QuitChan:        make(chan bool, 1),
+		SecretsQuitChan: make(chan bool, 1),
 	}
 
 	for k, v := range c.Origins {
@@ -807,6 +1556,21 @@ func (c *Config) Clone() *Config {
 		}
 	}
 
+	if len(c.Secrets) > 0 {
+		nc.Secrets = make(map[string]*so.Options)
+		for k, v := range c.Secrets {
+			nc.Secrets[k] = v.Clone()
+		}
+	}
+
+	nc.secretProviders = c.secretProviders
+	if c.secretValues != nil {
+		nc.secretValues = make(map[string]string)
+		for k, v := range c.secretValues {
+			nc.secretValues[k] = v
+		}
+	}
+
 	return nc
 }
 
@@ -846,15 +1610,78 @@ func (c *Config) String() string {
 				for _, w := range v.Paths {
 					w.Handler = nil
 					w.KeyHasher = nil
+					w.KeyNormalizers = nil
 				}
 			}
 			// also strip out potentially sensitive headers
 			hideAuthorizationCredentials(v.HealthCheckHeaders)
+			cp.hideSecretValues(v.HealthCheckHeaders)
 
 			if v.Paths != nil {
 				for _, p := range v.Paths {
 					hideAuthorizationCredentials(p.RequestHeaders)
 					hideAuthorizationCredentials(p.ResponseHeaders)
+					cp.hideSecretValues(p.RequestHeaders)
+				}
+			}
+
+			if v.InjectedLabelsJWTSecret != "" {
+				v.InjectedLabelsJWTSecret = "*****"
+			}
+
+			// origin credentials are always masked, including any injected headers
+			if v.Auth != nil {
+				for _, s := range []*string{&v.Auth.Token, &v.Auth.Password, &v.Auth.ClientSecret} {
+					if *s != "" {
+						*s = "*****"
+					}
+				}
+				for k := range v.Auth.Headers {
+					v.Auth.Headers[k] = "*****"
+				}
+			}
+		}
+	}
+
+	// strip tracing credentials
+	for _, v := range cp.TracingConfigs {
+		if v != nil && v.CollectorPass != "" {
+			v.CollectorPass = "*****"
+		}
+		if v != nil && v.OTLPOptions != nil {
+			hideAuthorizationCredentials(v.OTLPOptions.Headers)
+			cp.hideSecretValues(v.OTLPOptions.Headers)
+		}
+	}
+
+	// strip debug endpoint credentials
+	if cp.DebugConfig != nil && cp.DebugConfig.Password != "" {
+		cp.DebugConfig.Password = "*****"
+	}
+
+	if cp.AdminConfig != nil && cp.AdminConfig.AuthToken != "" {
+		cp.AdminConfig.AuthToken = "*****"
+	}
+
+	if cp.ReloadConfig != nil && cp.ReloadConfig.AuthToken != "" {
+		cp.ReloadConfig.AuthToken = "*****"
+	}
+
+	// strip metrics exporter credentials
+	if cp.Metrics != nil && cp.Metrics.OTLP != nil {
+		hideAuthorizationCredentials(cp.Metrics.OTLP.Headers)
+		cp.hideSecretValues(cp.Metrics.OTLP.Headers)
+	}
+
+	// strip any rewriter instruction values that were fetched from a secrets provider
+	for _, v := range cp.RequestRewriters {
+		if v == nil {
+			continue
+		}
+		for _, in := range v.Instructions {
+			for i := range in {
+				if cp.isSecretValue(in[i]) {
+					in[i] = "*****"
 				}
 			}
 		}
@@ -867,6 +1694,13 @@ func (c *Config) String() string {
 		}
 	}
 
+	// strip secrets provider credentials
+	for _, v := range cp.Secrets {
+		if v != nil {
+			v.HideCredentials()
+		}
+	}
+
 	var buf bytes.Buffer
 	e := toml.NewEncoder(&buf)
 	e.Encode(cp)
@@ -25,6 +25,7 @@ import (
 	"time"
 
 	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
+	ao "github.com/tricksterproxy/trickster/pkg/proxy/auth/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	rule "github.com/tricksterproxy/trickster/pkg/proxy/origins/rule/options"
@@ -84,11 +85,20 @@ func TestString(t *testing.T) {
 	c1.Origins["default"].Paths["test"] = &po.Options{}
 
 	c1.Caches["default"].Redis.Password = "plaintext-password"
+	c1.ReloadConfig.AuthToken = "plaintext-token"
+	c1.Origins["default"].Auth = &ao.Options{ClientSecret: "plaintext-secret",
+		Headers: map[string]string{"X-Api-Key": "plaintext-key"}}
 
 	s := c1.String()
 	if !strings.Contains(s, `password = "*****"`) {
 		t.Errorf("missing password mask: %s", "*****")
 	}
+	if strings.Contains(s, "plaintext-secret") || strings.Contains(s, "plaintext-token") || strings.Contains(s, "plaintext-key") {
+		t.Errorf("unmasked origin credentials: %s", s)
+	}
+	if c1.Origins["default"].Auth.ClientSecret != "plaintext-secret" {
+		t.Error("expected the original config to be unmodified")
+	}
 }
 
 func TestHideAuthorizationCredentials(t *testing.T) {
@@ -154,6 +164,35 @@ func TestProcessPprofConfig(t *testing.T) {
 
 }
 
+func TestValidateLoggingConfig(t *testing.T) {
+
+	lc := NewConfig().Logging
+	if err := lc.validate(); err != nil {
+		t.Error(err)
+	}
+
+	lc.SyslogAddress = "udp://127.0.0.1:514"
+	if err := lc.validate(); err != nil {
+		t.Error(err)
+	}
+
+	lc.SyslogFacility = "x"
+	if err := lc.validate(); err == nil {
+		t.Error("expected error for invalid syslog facility")
+	}
+
+	lc.SyslogAddress = "127.0.0.1:514"
+	if err := lc.validate(); err == nil {
+		t.Error("expected error for invalid syslog address")
+	}
+
+	lc.LogMaxBackups = -1
+	if err := lc.validate(); err != ErrInvalidLogRotation {
+		t.Errorf("expected %v got %v", ErrInvalidLogRotation, err)
+	}
+
+}
+
 func TestSetDefaults(t *testing.T) {
 
 	c, _ := emptyTestConfig()
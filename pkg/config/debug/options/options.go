@@ -0,0 +1,71 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options provides options for the runtime debugging endpoints
+package options
+
+import "github.com/tricksterproxy/trickster/pkg/config/defaults"
+
+// Options is a collection of configurations for the runtime debugging endpoints
+type Options struct {
+	// ListenAddress is IP address of the dedicated debug listener, used when
+	// main.pprof_server is 'debug'
+	ListenAddress string `toml:"listen_address"`
+	// ListenPort is TCP Port of the dedicated debug listener, used when
+	// main.pprof_server is 'debug'
+	ListenPort int `toml:"listen_port"`
+	// ListenFamily is the address family (ipv4, ipv6 or dual) of the dedicated debug listener
+	ListenFamily string `toml:"listen_family"`
+	// Username is the HTTP Basic Auth username required to access the debugging endpoints.
+	// When empty, the endpoints do not require authentication
+	Username string `toml:"username"`
+	// Password is the HTTP Basic Auth password required to access the debugging endpoints
+	Password string `toml:"password"`
+	// RuntimeEndpoints enables the expvar (/debug/vars) and runtime snapshot
+	// (/debug/snapshot) endpoints alongside pprof. They are disabled by default
+	RuntimeEndpoints bool `toml:"runtime_endpoints"`
+}
+
+// NewOptions returns a new Options references with Default Values set
+func NewOptions() *Options {
+	return &Options{
+		ListenAddress: defaults.DefaultDebugListenAddress,
+		ListenPort:    defaults.DefaultDebugListenPort,
+	}
+}
+
+// Clone returns an exact copy of the subject *Options
+func (o *Options) Clone() *Options {
+	return &Options{
+		ListenAddress:    o.ListenAddress,
+		ListenPort:       o.ListenPort,
+		ListenFamily:     o.ListenFamily,
+		Username:         o.Username,
+		Password:         o.Password,
+		RuntimeEndpoints: o.RuntimeEndpoints,
+	}
+}
+
+// RuntimeEndpointsEnabled returns true when the expvar and runtime snapshot endpoints
+// are served alongside pprof
+func (o *Options) RuntimeEndpointsEnabled() bool {
+	return o != nil && o.RuntimeEndpoints
+}
+
+// AuthEnabled returns true when the debugging endpoints require authentication
+func (o *Options) AuthEnabled() bool {
+	return o != nil && o.Username != ""
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import "testing"
+
+func TestNewOptions(t *testing.T) {
+	o := NewOptions()
+	if o == nil {
+		t.Error("expected non-nil options")
+	}
+	if o.AuthEnabled() {
+		t.Error("expected auth to be disabled by default")
+	}
+	if o.RuntimeEndpointsEnabled() {
+		t.Error("expected runtime endpoints to be disabled by default")
+	}
+}
+
+func TestClone(t *testing.T) {
+	o := NewOptions()
+	o.Username = "user"
+	o.Password = "pass"
+	o.RuntimeEndpoints = true
+	o2 := o.Clone()
+	if *o2 != *o {
+		t.Error("expected identical clone")
+	}
+	if !o2.AuthEnabled() {
+		t.Error("expected auth to be enabled")
+	}
+}
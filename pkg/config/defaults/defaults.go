@@ -27,6 +27,16 @@ const (
 	DefaultLogFile = ""
 	// DefaultLogLevel is the default level for logging
 	DefaultLogLevel = "INFO"
+	// DefaultLogMaxSizeMB is the default size, in megabytes, at which the log file is rotated
+	DefaultLogMaxSizeMB = 256
+	// DefaultLogMaxBackups is the default number of rotated log files to retain
+	DefaultLogMaxBackups = 80
+	// DefaultLogMaxAgeDays is the default number of days to retain rotated log files
+	DefaultLogMaxAgeDays = 7
+	// DefaultLogCompress is the default setting for compressing rotated log files
+	DefaultLogCompress = true
+	// DefaultSyslogFacility is the default facility for log events sent to syslog
+	DefaultSyslogFacility = "local0"
 
 	// DefaultProxyListenPort is the default port that the HTTP frontend will listen on
 	DefaultProxyListenPort = 8480
@@ -38,13 +48,33 @@ const (
 	// DefaultMetricsListenAddress is the default address that the HTTP metrics endpoint will listen on
 	DefaultMetricsListenAddress = ""
 
-	// 8482 is reserved for mockster, allowing the default TLS port to end with 3
+	// DefaultStatsDFlavor is the default line protocol used to push metrics to StatsD
+	DefaultStatsDFlavor = "statsd"
+	// DefaultStatsDPrefix is the default prefix of metric names pushed to StatsD
+	DefaultStatsDPrefix = "trickster"
+	// DefaultStatsDFlushIntervalMS is the default interval at which metrics are pushed to StatsD
+	DefaultStatsDFlushIntervalMS = 10000
+	// DefaultOTLPMetricsIntervalMS is the default interval at which metrics are exported via OTLP
+	DefaultOTLPMetricsIntervalMS = 60000
+
+	// DefaultSpiffeRefreshIntervalSecs is the default interval at which the SPIFFE SVID and
+	// bundle files are checked for rotation
+	DefaultSpiffeRefreshIntervalSecs = 30
+
+	// DefaultSimulatorListenPort is the default port on which the simulated origins are served,
+	// which is also mockster's port, allowing the default TLS port to end with 3
+	DefaultSimulatorListenPort = 8482
 
 	// DefaultTLSProxyListenPort is the default port that the TLS frontend endpoint will listen on
 	DefaultTLSProxyListenPort = 8483
 	// DefaultTLSProxyListenAddress is the default address that the TLS frontend endpoint will listen on
 	DefaultTLSProxyListenAddress = ""
 
+	// DefaultDebugListenPort is the default port that the dedicated Debug listener will listen on
+	DefaultDebugListenPort = 8485
+	// DefaultDebugListenAddress is the default address that the dedicated Debug listener will listen on
+	DefaultDebugListenAddress = "127.0.0.1"
+
 	// DefaultReloadPort is the default port that the Reload endpoint will listen on
 	DefaultReloadPort = 8484
 	// DefaultReloadAddress is the default address that the Reload endpoint will listen on
@@ -61,16 +91,32 @@ const (
 	// DefaultTracerServiceName is the default service name under which traces are registered
 	DefaultTracerServiceName = "trickster"
 
+	// DefaultTracerSampler is the default trace sampling strategy
+	DefaultTracerSampler = "ratio"
+
+	// DefaultTracerStartupMode is the default startup mode of tracers whose collector is
+	// unreachable
+	DefaultTracerStartupMode = "lazy"
+
 	// DefaultCacheType is the default cache type for any defined cache
 	DefaultCacheType = "memory"
 	// DefaultCacheTypeID is the default cache type ID for any defined cache
 	// and should align with DefaultCacheType
 	DefaultCacheTypeID = types.CacheTypeMemory
+	// DefaultCacheCompressionCodec is the default codec for compressing serialized cache documents
+	DefaultCacheCompressionCodec = "snappy"
+	// DefaultCacheStartupMode is the default startup mode of caches that cannot connect
+	DefaultCacheStartupMode = "fail"
 
 	// DefaultTimeseriesTTLSecs is the default Cache TTL for Time Series Objects
 	DefaultTimeseriesTTLSecs = 21600
 	// DefaultFastForwardTTLSecs is the default Cache TTL for Time Series Fast Forward Objects
 	DefaultFastForwardTTLSecs = 15
+	// DefaultStatusCacheTTLSecs is the default Cache TTL for responses from origin status endpoints
+	DefaultStatusCacheTTLSecs = 30
+	// DefaultFederateCacheTTLSecs is the default Cache TTL for responses from origin federation
+	// endpoints, which matches the default Prometheus scrape interval
+	DefaultFederateCacheTTLSecs = 15
 	// DefaultMaxTTLSecs is the default Maximum TTL of any cache object
 	DefaultMaxTTLSecs = 86400
 	// DefaultRevalidationFactor is the default Cache Object Freshness Lifetime to TTL multiplier
@@ -89,6 +135,8 @@ const (
 	DefaultCacheIndexReap = 3
 	// DefaultCacheIndexFlush is the default Cache Index Flush interval (in seconds)
 	DefaultCacheIndexFlush = 5
+	// DefaultMemorySnapshotIntervalSecs is the default Memory Cache Snapshot interval (in seconds)
+	DefaultMemorySnapshotIntervalSecs = 300
 	// DefaultCacheMaxSizeBytes is the default Max Cache Size in Bytes
 	DefaultCacheMaxSizeBytes = 536870912
 	// DefaultMaxSizeBackoffBytes is the default Max Cache Backoff Size in Bytes
@@ -101,10 +149,68 @@ const (
 	DefaultMaxObjectSizeBytes = 524288
 	// DefaultOriginTRF is the default Timeseries Retention Factor for Time Series-based Origins
 	DefaultOriginTRF = 1024
+	// DefaultTimeseriesMaxExtents is the default maximum number of extents in a timeseries cache object
+	DefaultTimeseriesMaxExtents = 0
+	// DefaultTimeseriesShardWindowSecs is the default window of timeseries cache object shards
+	DefaultTimeseriesShardWindowSecs = 0
+	// DefaultRetentionIntervalSecs is the default interval of the timeseries retention janitor
+	DefaultRetentionIntervalSecs = 300
 	// DefaultOriginTEM is the default Timeseries Eviction Method for Time Series-based Origins
 	DefaultOriginTEM = evictionmethods.EvictionMethodOldest
 	// DefaultOriginTEMName is the default Timeseries Eviction Method name for Time Series-based Origins
 	DefaultOriginTEMName = "oldest"
+	// DefaultTimeseriesCacheEncoding is the default binary encoding of cached timeseries
+	DefaultTimeseriesCacheEncoding = "msgpack"
+	// DefaultRangeSnapName is the default method of aligning timeseries query ranges to their step
+	DefaultRangeSnapName = "floor"
+	// DefaultMinStepSecs is the default minimum step of timeseries queries
+	DefaultMinStepSecs = 0
+	// DefaultMaxResultSeries is the default maximum number of series in a timeseries response
+	DefaultMaxResultSeries = 0
+	// DefaultMaxResultSamples is the default maximum number of samples in a timeseries response
+	DefaultMaxResultSamples = 0
+	// DefaultResultLimitActionName is the default handling of timeseries responses that exceed the limits
+	DefaultResultLimitActionName = "truncate"
+	// DefaultTimeRangeLimitActionName is the default handling of timeseries queries that exceed
+	// the time range limits
+	DefaultTimeRangeLimitActionName = "clamp"
+	// DefaultMaxDataPoints is the default maximum number of timestamps in a timeseries response
+	DefaultMaxDataPoints = 0
+	// DefaultDataPointAggregationName is the default function for aggregating thinned timeseries responses
+	DefaultDataPointAggregationName = "avg"
+	// DefaultDeltaFetchChunks is the default number of sub-ranges missing timeseries data is fetched in
+	DefaultDeltaFetchChunks = 1
+	// DefaultDeltaFetchMinChunkSecs is the default minimum duration of a timeseries fetch sub-range
+	DefaultDeltaFetchMinChunkSecs = 21600
+	// DefaultDeltaFetchParallelism is the default limit of concurrent timeseries fetches to an Origin
+	DefaultDeltaFetchParallelism = 0
+	// DefaultHedgeQuantile is the default quantile of recent Origin latencies after which a request is hedged
+	DefaultHedgeQuantile = 0.95
+	// DefaultHedgeMinDelayMS is the default minimum delay before a request to an Origin is hedged
+	DefaultHedgeMinDelayMS = 50
+	// DefaultTargetSelection is the default policy by which an Origin's request target is selected
+	DefaultTargetSelection = "latency"
+	// DefaultPriorityHeader is the default request header that tags a request with an Origin's priority class
+	DefaultPriorityHeader = "X-Trickster-Priority"
+	// DefaultPriorityQueueTimeoutMS is the default duration that a request waits for a turn in its priority class
+	DefaultPriorityQueueTimeoutMS = 10000
+	// DefaultAdaptiveConcurrencyInitialLimit is the default starting concurrency limit of an Origin's adaptive limiter
+	DefaultAdaptiveConcurrencyInitialLimit = 20
+	// DefaultAdaptiveConcurrencyMinLimit is the default lower bound of an Origin's adaptive concurrency limit
+	DefaultAdaptiveConcurrencyMinLimit = 1
+	// DefaultAdaptiveConcurrencyMaxLimit is the default upper bound of an Origin's adaptive concurrency limit
+	DefaultAdaptiveConcurrencyMaxLimit = 200
+	// DefaultAdaptiveConcurrencyBackoffRatio is the default factor applied to an Origin's adaptive
+	// concurrency limit when the Origin is overloaded
+	DefaultAdaptiveConcurrencyBackoffRatio = 0.9
+	// DefaultAdaptiveConcurrencyLatencyTolerance is the default multiple of an Origin's baseline latency
+	// beyond which it is considered overloaded
+	DefaultAdaptiveConcurrencyLatencyTolerance = 2.0
+	// DefaultHealthCheckIntervalSecs is the default interval of an Origin's background health checks
+	DefaultHealthCheckIntervalSecs = 10
+	// DefaultHealthCheckFailureThreshold is the default number of consecutive failed background
+	// health checks after which an Origin is considered down
+	DefaultHealthCheckFailureThreshold = 3
 	// DefaultOriginTimeoutSecs is the default Upstream Request Timeout for Origins
 	DefaultOriginTimeoutSecs = 180
 	// DefaultOriginCacheName is the default Cache Name for Origins
@@ -133,12 +239,34 @@ const (
 	DefaultReloadHandlerPath = "/trickster/config/reload"
 	// DefaultHealthHandlerPath defines the default path for the Health Handler
 	DefaultHealthHandlerPath = "/trickster/health"
+	// DefaultLogLevelHandlerPath defines the default path for the Log Level Handler
+	DefaultLogLevelHandlerPath = "/trickster/log/level"
+	// DefaultBatchHandlerPath defines the default path for the Batch Handler
+	DefaultBatchHandlerPath = "/trickster/batch"
+	// DefaultBatchConcurrency is the default number of the queries of a batch that are run at a time
+	DefaultBatchConcurrency = 8
+	// DefaultInspectHandlerPath defines the default path prefix for the Query Inspection Handler
+	DefaultInspectHandlerPath = "/trickster/inspect"
+	// DefaultAdminHandlerPath defines the default path prefix for the Admin API
+	DefaultAdminHandlerPath = "/trickster/admin"
 	// DefaultMaxRuleExecutions is the default value for the number of allowed Rule executions per Request
 	DefaultMaxRuleExecutions = 16
 	// DefaultPprofServerName defines the default Pprof Server Name
 	DefaultPprofServerName = "both"
 	// DefaultForwardedHeaders defines which class of 'Forwarded' headers are attached to upstream requests
 	DefaultForwardedHeaders = "standard"
+
+	// DefaultSecretsProviderType is the default backend type for a secrets provider
+	DefaultSecretsProviderType = "env"
+	// DefaultSecretsTimeoutSecs is the default timeout for a secrets provider to fetch a secret
+	DefaultSecretsTimeoutSecs = 10
+	// DefaultSecretsRefreshIntervalSecs is the default interval at which secrets are re-fetched
+	// to detect rotation. 0 disables refreshing
+	DefaultSecretsRefreshIntervalSecs = 0
+	// DefaultVaultKVVersion is the default version of the Vault KV Secrets Engine
+	DefaultVaultKVVersion = 2
+	// DefaultGCPSecretVersion is the default Google Cloud Secret Manager secret version to access
+	DefaultGCPSecretVersion = "latest"
 )
 
 // DefaultCompressableTypes returns a list of types that Trickster should compress before caching
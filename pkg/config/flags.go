@@ -33,6 +33,7 @@ const (
 	cfOriginType  = "origin-type"
 	cfProxyPort   = "proxy-port"
 	cfMetricsPort = "metrics-port"
+	cfSimulate    = "simulate"
 )
 
 // Flags holds the values for whitelisted flags
@@ -47,6 +48,7 @@ type Flags struct {
 	Origin            string
 	OriginType        string
 	LogLevel          string
+	Simulate          bool
 }
 
 func parseFlags(applicationName string, arguments []string) (*Flags, error) {
@@ -73,6 +75,8 @@ func parseFlags(applicationName string, arguments []string) (*Flags, error) {
 		"Port that the primary Proxy server will listen on")
 	flagSet.IntVar(&flags.MetricsListenPort, cfMetricsPort, 0,
 		"Port that the /metrics endpoint will listen on")
+	flagSet.BoolVar(&flags.Simulate, cfSimulate, false,
+		"Serves simulated Prometheus and HTTP origins, for evaluating Trickster without a real backend")
 
 	err := flagSet.Parse(arguments)
 	if err != nil {
@@ -109,4 +113,7 @@ func (c *Config) loadFlags(flags *Flags) {
 	if flags.InstanceID > 0 {
 		c.Main.InstanceID = flags.InstanceID
 	}
+	if flags.Simulate {
+		c.Main.Simulate = true
+	}
 }
@@ -18,11 +18,22 @@ package config
 
 import (
 	"errors"
-	"fmt"
+	"io/ioutil"
 	"net/url"
 	"strconv"
 	"strings"
 	"time"
+
+	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
+	"github.com/tricksterproxy/trickster/pkg/proxy/resolver"
+	"github.com/tricksterproxy/trickster/pkg/proxy/targets"
+	"github.com/tricksterproxy/trickster/pkg/proxy/tenancy"
+	"github.com/tricksterproxy/trickster/pkg/proxy/urls"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	bf "github.com/tricksterproxy/trickster/pkg/timeseries/backfill/options"
+	ds "github.com/tricksterproxy/trickster/pkg/timeseries/downsample/options"
+
+	"github.com/prometheus/common/model"
 )
 
 // Load returns the Application Configuration, starting with a default config,
@@ -45,6 +56,10 @@ func Load(applicationName string, applicationVersion string, arguments []string)
 	c.loadEnvVars()
 	c.loadFlags(flags) // load parsed flags to override file and envs
 
+	if c.Main.Simulate {
+		c.addSimulatedOrigins()
+	}
+
 	// set the default origin url from the flags
 	if d, ok := c.Origins["default"]; ok {
 		if c.providedOriginURL != "" {
@@ -75,14 +90,18 @@ func Load(applicationName string, applicationVersion string, arguments []string)
 		return nil, flags, errors.New("no valid origins configured")
 	}
 
+	if c.Main.Simulate {
+		c.setSimulatedDefaultOrigin()
+	}
+
+	var errs ValidationErrors
+
 	for k, n := range c.NegativeCacheConfigs {
 		for c := range n {
 			ci, err := strconv.Atoi(c)
-			if err != nil {
-				return nil, flags, fmt.Errorf(`invalid negative cache config in %s: %s is not a valid status code`, k, c)
-			}
-			if ci < 400 || ci >= 600 {
-				return nil, flags, fmt.Errorf(`invalid negative cache config in %s: %s is not a valid status code`, k, c)
+			if err != nil || ci < 400 || ci >= 600 {
+				errs.Add(keyPath("negative_caches", k, c), "",
+					`invalid negative cache config in %s: %s is not a valid status code`, k, c)
 			}
 		}
 	}
@@ -90,16 +109,25 @@ func Load(applicationName string, applicationVersion string, arguments []string)
 	for k, o := range c.Origins {
 
 		if o.OriginType == "" {
-			return nil, flags, fmt.Errorf(`missing origin-type for origin "%s"`, k)
+			errs.Add(keyPath("origins", k, "origin_type"), "",
+				`missing origin-type for origin "%s"`, k)
+			continue
 		}
 
 		if o.OriginType != "rule" && o.OriginURL == "" {
-			return nil, flags, fmt.Errorf(`missing origin-url for origin "%s"`, k)
+			errs.Add(keyPath("origins", k, "origin_url"), "",
+				`missing origin-url for origin "%s"`, k)
+			continue
 		}
 
 		url, err := url.Parse(o.OriginURL)
 		if err != nil {
-			return nil, flags, err
+			errs.Append(keyPath("origins", k, "origin_url"), err)
+			continue
+		}
+		if err := urls.ValidateHost(url.Host); err != nil {
+			errs.Append(keyPath("origins", k, "origin_url"), err)
+			continue
 		}
 
 		if strings.HasSuffix(url.Path, "/") {
@@ -114,8 +142,410 @@ func Load(applicationName string, applicationVersion string, arguments []string)
 		o.BackfillTolerance = time.Duration(o.BackfillToleranceSecs) * time.Second
 		o.TimeseriesRetention = time.Duration(o.TimeseriesRetentionFactor)
 		o.TimeseriesTTL = time.Duration(o.TimeseriesTTLSecs) * time.Second
+		o.TimeseriesShardWindow = time.Duration(o.TimeseriesShardWindowSecs) * time.Second
+		o.TimeseriesMaxAge = time.Duration(o.TimeseriesMaxAgeSecs) * time.Second
+		o.RetentionInterval = time.Duration(o.RetentionIntervalSecs) * time.Second
 		o.FastForwardTTL = time.Duration(o.FastForwardTTLSecs) * time.Second
+		o.EmptyQuarantineTTL = time.Duration(o.EmptyQuarantineTTLSecs) * time.Second
 		o.MaxTTL = time.Duration(o.MaxTTLSecs) * time.Second
+		o.SlowLogThreshold = time.Duration(o.SlowLogThresholdMS) * time.Millisecond
+		o.DeltaFetchMinChunk = time.Duration(o.DeltaFetchMinChunkSecs) * time.Second
+		o.MinStep = time.Duration(o.MinStepSecs) * time.Second
+		o.RemoteWriteInvalidation = time.Duration(o.RemoteWriteInvalidationSecs) * time.Second
+
+		sm, ok := timeseries.SnapModeNames[o.RangeSnapName]
+		if !ok {
+			errs.Add(keyPath("origins", k, "range_snap"),
+				suggestKey(o.RangeSnapName, timeseries.SnapModeNames),
+				`invalid range snap: %s`, o.RangeSnapName)
+			continue
+		}
+		o.RangeSnap = sm
+
+		la, ok := timeseries.LimitActionNames[o.ResultLimitActionName]
+		if !ok {
+			errs.Add(keyPath("origins", k, "result_limit_action"),
+				suggestKey(o.ResultLimitActionName, timeseries.LimitActionNames),
+				`invalid result limit action: %s`, o.ResultLimitActionName)
+			continue
+		}
+		o.ResultLimitAction = la
+
+		la, ok = timeseries.TimeRangeLimitActionNames[o.TimeRangeLimitActionName]
+		if !ok {
+			errs.Add(keyPath("origins", k, "time_range_limit_action"),
+				suggestKey(o.TimeRangeLimitActionName, timeseries.TimeRangeLimitActionNames),
+				`invalid time range limit action: %s`, o.TimeRangeLimitActionName)
+			continue
+		}
+		o.TimeRangeLimitAction = la
+
+		ag, ok := timeseries.AggregationNames[o.DataPointAggregationName]
+		if !ok {
+			errs.Add(keyPath("origins", k, "data_point_aggregation"),
+				suggestKey(o.DataPointAggregationName, timeseries.AggregationNames),
+				`invalid data point aggregation: %s`, o.DataPointAggregationName)
+			continue
+		}
+		o.DataPointAggregation = ag
+
+		if o.MaxDataPoints < 0 {
+			errs.Add(keyPath("origins", k, "max_data_points"), "",
+				`invalid max data points: %d`, o.MaxDataPoints)
+			continue
+		}
+
+		if o.ValuePrecision < 0 || o.ValuePrecision > timeseries.MaxSignificantDigits {
+			errs.Add(keyPath("origins", k, "value_precision"), "",
+				`invalid value precision: %d`, o.ValuePrecision)
+			continue
+		}
+
+		if o.MaxResultSeries < 0 {
+			errs.Add(keyPath("origins", k, "max_result_series"), "",
+				`invalid max result series: %d`, o.MaxResultSeries)
+			continue
+		}
+
+		if o.MaxResultSamples < 0 {
+			errs.Add(keyPath("origins", k, "max_result_samples"), "",
+				`invalid max result samples: %d`, o.MaxResultSamples)
+			continue
+		}
+
+		if o.MaxLookbackSecs < 0 {
+			errs.Add(keyPath("origins", k, "max_lookback_secs"), "",
+				`invalid max lookback secs: %d`, o.MaxLookbackSecs)
+			continue
+		}
+		o.MaxLookback = time.Duration(o.MaxLookbackSecs) * time.Second
+
+		if o.MaxRangeSecs < 0 {
+			errs.Add(keyPath("origins", k, "max_range_secs"), "",
+				`invalid max range secs: %d`, o.MaxRangeSecs)
+			continue
+		}
+		o.MaxRange = time.Duration(o.MaxRangeSecs) * time.Second
+
+		if o.DeltaFetchChunks < 1 {
+			errs.Add(keyPath("origins", k, "delta_fetch_chunks"), "",
+				`invalid delta fetch chunks: %d`, o.DeltaFetchChunks)
+			continue
+		}
+
+		if o.DeltaFetchParallelism < 0 {
+			errs.Add(keyPath("origins", k, "delta_fetch_parallelism"), "",
+				`invalid delta fetch parallelism: %d`, o.DeltaFetchParallelism)
+			continue
+		}
+
+		if o.EmptyQuarantineTTLSecs < 0 {
+			errs.Add(keyPath("origins", k, "empty_quarantine_ttl_secs"), "",
+				`invalid empty quarantine ttl secs: %d`, o.EmptyQuarantineTTLSecs)
+			continue
+		}
+
+		if o.DeltaVerifySteps < 0 {
+			errs.Add(keyPath("origins", k, "delta_verify_steps"), "",
+				`invalid delta verify steps: %d`, o.DeltaVerifySteps)
+			continue
+		}
+
+		if o.StatusCacheTTLSecs < 0 {
+			errs.Add(keyPath("origins", k, "status_cache_ttl_secs"), "",
+				`invalid status cache ttl secs: %d`, o.StatusCacheTTLSecs)
+			continue
+		}
+
+		if o.CacheMaxBodyBytes < 0 {
+			errs.Add(keyPath("origins", k, "cache_max_body_bytes"), "",
+				`invalid cache max body bytes: %d`, o.CacheMaxBodyBytes)
+			continue
+		}
+
+		if o.FederateCacheTTLSecs < 0 {
+			errs.Add(keyPath("origins", k, "federate_cache_ttl_secs"), "",
+				`invalid federate cache ttl secs: %d`, o.FederateCacheTTLSecs)
+			continue
+		}
+
+		if o.RemoteWriteInvalidationSecs < 0 {
+			errs.Add(keyPath("origins", k, "remote_write_invalidation_secs"), "",
+				`invalid remote write invalidation secs: %d`, o.RemoteWriteInvalidationSecs)
+			continue
+		}
+
+		if o.WarmConnections < 0 {
+			errs.Add(keyPath("origins", k, "warm_connections"), "",
+				`invalid warm connections: %d`, o.WarmConnections)
+			continue
+		}
+
+		if o.HealthCheckIntervalSecs < 1 {
+			errs.Add(keyPath("origins", k, "health_check_interval_secs"), "",
+				`invalid health check interval secs: %d`, o.HealthCheckIntervalSecs)
+			continue
+		}
+
+		if o.HealthCheckFailureThreshold < 1 {
+			errs.Add(keyPath("origins", k, "health_check_failure_threshold"), "",
+				`invalid health check failure threshold: %d`, o.HealthCheckFailureThreshold)
+			continue
+		}
+
+		o.HealthCheckInterval = time.Duration(o.HealthCheckIntervalSecs) * time.Second
+
+		if o.HedgeQuantile <= 0 || o.HedgeQuantile > 1 {
+			errs.Add(keyPath("origins", k, "hedge_quantile"), "",
+				`invalid hedge quantile: %g`, o.HedgeQuantile)
+			continue
+		}
+
+		if o.HedgeMinDelayMS < 0 {
+			errs.Add(keyPath("origins", k, "hedge_min_delay_ms"), "",
+				`invalid hedge min delay ms: %d`, o.HedgeMinDelayMS)
+			continue
+		}
+
+		if err := resolver.ValidateHosts(o.StaticHosts); err != nil {
+			errs.Add(keyPath("origins", k, "static_hosts"), "",
+				`invalid static hosts: %s`, err.Error())
+			continue
+		}
+
+		if o.DNSResolver != "" {
+			if _, err := resolver.NormalizeAddress(o.DNSResolver); err != nil {
+				errs.Add(keyPath("origins", k, "dns_resolver"), "",
+					`invalid dns resolver: %s`, o.DNSResolver)
+				continue
+			}
+		}
+
+		if o.Auth != nil {
+			if err := o.Auth.Validate(); err != nil {
+				errs.Add(keyPath("origins", k, "auth"), "",
+					`invalid auth: %s`, err.Error())
+				continue
+			}
+		}
+
+		if o.AdaptiveConcurrency != nil {
+			o.AdaptiveConcurrency.SetDefaults()
+			if err := o.AdaptiveConcurrency.Validate(); err != nil {
+				errs.Add(keyPath("origins", k, "adaptive_concurrency"), "",
+					`invalid adaptive concurrency: %s`, err.Error())
+				continue
+			}
+		}
+
+		o.HedgeMinDelay = time.Duration(o.HedgeMinDelayMS) * time.Millisecond
+		o.HedgeTargets = o.HedgeTargets[:0]
+		for _, h := range o.HedgeURLs {
+			hu, err := url.Parse(h)
+			if err != nil || hu.Scheme == "" || hu.Host == "" ||
+				urls.ValidateHost(hu.Host) != nil {
+				errs.Add(keyPath("origins", k, "hedge_urls"), "",
+					`invalid hedge url: %s`, h)
+				continue
+			}
+			hu.Path = strings.TrimSuffix(hu.Path, "/")
+			o.HedgeTargets = append(o.HedgeTargets, hu)
+		}
+
+		o.TargetSelector = nil
+		if len(o.TargetURLs) > 0 {
+			if o.TargetSelection != targets.PolicyLatency &&
+				o.TargetSelection != targets.PolicyWeight {
+				errs.Add(keyPath("origins", k, "target_selection"), "",
+					`invalid target selection: %s`, o.TargetSelection)
+				continue
+			}
+			// the origin's own URL is the first target
+			ts := []*targets.Target{{URL: urls.Clone(url), Weight: 1}}
+			byURL := map[string]*targets.Target{o.OriginURL: ts[0]}
+			for _, t := range o.TargetURLs {
+				tu, err := url.Parse(t)
+				if err != nil || tu.Scheme == "" || tu.Host == "" ||
+					urls.ValidateHost(tu.Host) != nil {
+					errs.Add(keyPath("origins", k, "target_urls"), "",
+						`invalid target url: %s`, t)
+					continue
+				}
+				tu.Path = strings.TrimSuffix(tu.Path, "/")
+				target := &targets.Target{URL: tu, Weight: 1}
+				ts = append(ts, target)
+				byURL[t] = target
+			}
+			if len(ts) != len(o.TargetURLs)+1 {
+				continue
+			}
+			valid := true
+			for u, w := range o.TargetWeights {
+				target, ok := byURL[u]
+				if !ok || w < 0 {
+					errs.Add(keyPath("origins", k, "target_weights"), "",
+						`invalid target weight for %s: %d`, u, w)
+					valid = false
+					continue
+				}
+				target.Weight = w
+			}
+			if !valid {
+				continue
+			}
+			o.TargetSelector = targets.NewSelector(k, o.TargetSelection, ts,
+				o.HealthCheckFailureThreshold)
+		}
+
+		if o.TimeseriesMaxExtents < 0 {
+			errs.Add(keyPath("origins", k, "timeseries_max_extents"), "",
+				`invalid timeseries max extents: %d`, o.TimeseriesMaxExtents)
+			continue
+		}
+
+		if o.TimeseriesShardWindowSecs < 0 {
+			errs.Add(keyPath("origins", k, "timeseries_shard_window_secs"), "",
+				`invalid timeseries shard window secs: %d`, o.TimeseriesShardWindowSecs)
+			continue
+		}
+
+		if o.TimeseriesMaxAgeSecs < 0 {
+			errs.Add(keyPath("origins", k, "timeseries_max_age_secs"), "",
+				`invalid timeseries max age secs: %d`, o.TimeseriesMaxAgeSecs)
+			continue
+		}
+
+		if o.RetentionIntervalSecs <= 0 {
+			errs.Add(keyPath("origins", k, "retention_interval_secs"), "",
+				`invalid retention interval secs: %d`, o.RetentionIntervalSecs)
+			continue
+		}
+
+		var tierErr bool
+		for l, t := range o.DownsampleTiers {
+			t.Name = l
+			t.Step = time.Duration(t.StepSecs) * time.Second
+			t.MinAge = time.Duration(t.MinAgeSecs) * time.Second
+			if t.StepSecs <= 0 {
+				errs.Add(keyPath("origins", k, "downsample_tiers", l, "step_secs"), "",
+					`invalid downsample tier step: %d`, t.StepSecs)
+				tierErr = true
+			}
+			if t.MinAgeSecs <= 0 {
+				errs.Add(keyPath("origins", k, "downsample_tiers", l, "min_age_secs"), "",
+					`invalid downsample tier min age: %d`, t.MinAgeSecs)
+				tierErr = true
+			}
+		}
+		if tierErr {
+			continue
+		}
+		// each tier must hold older data than the last, at a coarser step
+		o.DownsampleTierList = ds.NewTiers(o.DownsampleTiers)
+		for i := 1; i < len(o.DownsampleTierList); i++ {
+			t, prev := o.DownsampleTierList[i], o.DownsampleTierList[i-1]
+			if t.MinAge == prev.MinAge || t.Step <= prev.Step {
+				errs.Add(keyPath("origins", k, "downsample_tiers", t.Name), "",
+					`downsample tier must be older and coarser than tier %s`, prev.Name)
+				tierErr = true
+			}
+		}
+		if tierErr {
+			continue
+		}
+
+		var classErr bool
+		for l, c := range o.PriorityClasses {
+			c.Name = l
+			if c.MaxConcurrency <= 0 {
+				errs.Add(keyPath("origins", k, "priority_classes", l, "max_concurrency"), "",
+					`invalid priority class max concurrency: %d`, c.MaxConcurrency)
+				classErr = true
+			}
+			if c.MaxQueueLength < 0 {
+				errs.Add(keyPath("origins", k, "priority_classes", l, "max_queue_length"), "",
+					`invalid priority class max queue length: %d`, c.MaxQueueLength)
+				classErr = true
+			}
+			if c.QueueTimeoutMS < 0 {
+				errs.Add(keyPath("origins", k, "priority_classes", l, "queue_timeout_ms"), "",
+					`invalid priority class queue timeout ms: %d`, c.QueueTimeoutMS)
+				classErr = true
+			} else if c.QueueTimeoutMS == 0 {
+				c.QueueTimeoutMS = d.DefaultPriorityQueueTimeoutMS
+			}
+			c.QueueTimeout = time.Duration(c.QueueTimeoutMS) * time.Millisecond
+		}
+		if o.DefaultPriorityClass != "" {
+			if _, ok := o.PriorityClasses[o.DefaultPriorityClass]; !ok {
+				errs.Add(keyPath("origins", k, "default_priority_class"),
+					suggestKey(o.DefaultPriorityClass, o.PriorityClasses),
+					`invalid default priority class: %s`, o.DefaultPriorityClass)
+				classErr = true
+			}
+		}
+		if classErr {
+			continue
+		}
+
+		var ruleErr bool
+		for l, r := range o.BackfillToleranceRules {
+			r.Name = l
+			r.BackfillTolerance = time.Duration(r.BackfillToleranceSecs) * time.Second
+			if err := r.Compile(); err != nil {
+				errs.Add(keyPath("origins", k, "backfill_tolerance_rules", l, "pattern"), "",
+					`invalid backfill tolerance rule pattern: %s`, r.Pattern)
+				ruleErr = true
+			}
+		}
+		if ruleErr {
+			continue
+		}
+		o.BackfillToleranceRuleList = bf.NewRules(o.BackfillToleranceRules)
+
+		var labelErr bool
+		sources := make(map[string]*tenancy.Source, len(o.InjectedLabels))
+		for l, v := range o.InjectedLabels {
+			if !model.LabelName(l).IsValid() {
+				errs.Add(keyPath("origins", k, "injected_labels", l), "",
+					`invalid injected label name: %s`, l)
+				labelErr = true
+				continue
+			}
+			s, err := tenancy.ParseSource(v)
+			if err != nil {
+				errs.Append(keyPath("origins", k, "injected_labels", l), err)
+				labelErr = true
+				continue
+			}
+			sources[l] = s
+		}
+		if labelErr {
+			continue
+		}
+		if len(sources) > 0 {
+			var v *tenancy.Verifier
+			var err error
+			if o.InjectedLabelsJWTSecret != "" || o.InjectedLabelsJWTKeyPath != "" {
+				var key []byte
+				if o.InjectedLabelsJWTKeyPath != "" {
+					if key, err = ioutil.ReadFile(o.InjectedLabelsJWTKeyPath); err != nil {
+						errs.Append(keyPath("origins", k, "injected_labels_jwt_key_path"), err)
+						continue
+					}
+				}
+				if v, err = tenancy.NewVerifier(o.InjectedLabelsJWTSecret, key); err != nil {
+					errs.Append(keyPath("origins", k, "injected_labels_jwt_key_path"), err)
+					continue
+				}
+			}
+			e, err := tenancy.NewEnforcer(sources, v, o.InjectedLabelsTrustedProxies)
+			if err != nil {
+				errs.Append(keyPath("origins", k, "injected_labels"), err)
+				continue
+			}
+			o.InjectedLabelEnforcer = e
+		}
 
 		if o.CompressableTypeList != nil {
 			o.CompressableTypes = make(map[string]bool)
@@ -128,9 +558,19 @@ func Load(applicationName string, applicationVersion string, arguments []string)
 			o.CacheKeyPrefix = o.Host
 		}
 
+		if _, ok := timeseries.BinaryFormatNames[o.TimeseriesCacheEncoding]; !ok {
+			errs.Add(keyPath("origins", k, "timeseries_cache_encoding"),
+				suggestKey(o.TimeseriesCacheEncoding, timeseries.BinaryFormatNames),
+				`invalid timeseries cache encoding: %s`, o.TimeseriesCacheEncoding)
+			continue
+		}
+
 		nc, ok := c.NegativeCacheConfigs[o.NegativeCacheName]
 		if !ok {
-			return nil, flags, fmt.Errorf(`invalid negative cache name: %s`, o.NegativeCacheName)
+			errs.Add(keyPath("origins", k, "negative_cache_name"),
+				suggestKey(o.NegativeCacheName, c.NegativeCacheConfigs),
+				`invalid negative cache name: %s`, o.NegativeCacheName)
+			continue
 		}
 
 		nc2 := map[int]time.Duration{}
@@ -151,11 +591,28 @@ func Load(applicationName string, applicationVersion string, arguments []string)
 			o.FastForwardTTLSecs = o.MaxTTLSecs
 			o.FastForwardTTL = o.MaxTTL
 		}
+
+		if o.SelectCacheTTLSecs > o.MaxTTLSecs {
+			o.SelectCacheTTLSecs = o.MaxTTLSecs
+		}
+
+		if o.StatusCacheTTLSecs > o.MaxTTLSecs {
+			o.StatusCacheTTLSecs = o.MaxTTLSecs
+		}
+
+		if o.FederateCacheTTLSecs > o.MaxTTLSecs {
+			o.FederateCacheTTLSecs = o.MaxTTLSecs
+		}
+	}
+
+	if err := errs.Err(); err != nil {
+		return nil, flags, err
 	}
 
 	for _, c := range c.Caches {
 		c.Index.FlushInterval = time.Duration(c.Index.FlushIntervalSecs) * time.Second
 		c.Index.ReapInterval = time.Duration(c.Index.ReapIntervalSecs) * time.Second
+		c.Memory.SnapshotInterval = time.Duration(c.Memory.SnapshotIntervalSecs) * time.Second
 	}
 
 	return c, flags, nil
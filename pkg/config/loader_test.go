@@ -24,6 +24,7 @@ import (
 
 	"github.com/tricksterproxy/trickster/pkg/cache/evictionmethods"
 	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
 )
 
 func TestLoadConfiguration(t *testing.T) {
@@ -86,6 +87,158 @@ func TestLoadConfigurationFileFailures(t *testing.T) {
 			"../../testdata/test.invalid-pcf-name.conf",
 			`invalid collapsed_forwarding name: INVALID`,
 		},
+		{ // Case 8
+			"../../testdata/test.invalid-timeseries-cache-encoding.conf",
+			`invalid timeseries cache encoding: gorila (did you mean 'gorilla'?)`,
+		},
+		{ // Case 9
+			"../../testdata/test.invalid-delta-fetch-parallelism.conf",
+			`invalid delta fetch parallelism: -1`,
+		},
+		{ // Case 10
+			"../../testdata/test.invalid-downsample-tiers.conf",
+			`downsample tier must be older and coarser than tier 5m`,
+		},
+		{ // Case 11
+			"../../testdata/test.invalid-range-snap.conf",
+			`invalid range snap: outwards (did you mean 'outward'?)`,
+		},
+		{ // Case 12
+			"../../testdata/test.invalid-backfill-tolerance-rule.conf",
+			`invalid backfill tolerance rule pattern: rate(`,
+		},
+		{ // Case 13
+			"../../testdata/test.invalid-timeseries-max-extents.conf",
+			`invalid timeseries max extents: -1`,
+		},
+		{ // Case 14
+			"../../testdata/test.invalid-result-limit-action.conf",
+			`invalid result limit action: truncated (did you mean 'truncate'?)`,
+		},
+		{ // Case 15
+			"../../testdata/test.invalid-data-point-aggregation.conf",
+			`invalid data point aggregation: average`,
+		},
+		{ // Case 16
+			"../../testdata/test.invalid-timeseries-shard-window.conf",
+			`invalid timeseries shard window secs: -86400`,
+		},
+		{ // Case 17
+			"../../testdata/test.invalid-cache-key-json-path.conf",
+			`invalid cache key json path: queries[0].expr`,
+		},
+		{ // Case 18
+			"../../testdata/test.invalid-request-hook-name.conf",
+			`invalid request hook name no-such-hook in path query of origin config default`,
+		},
+		{ // Case 19
+			"../../testdata/test.invalid-injected-label-source.conf",
+			`invalid label source: cookie:tenant`,
+		},
+		{ // Case 20
+			"../../testdata/test.invalid-cache-key-principal.conf",
+			`invalid cache key principal cookie:session in path query of origin config default`,
+		},
+		{ // Case 21
+			"../../testdata/test.invalid-hedge-url.conf",
+			`invalid hedge url: replica-1:9090`,
+		},
+		{ // Case 22
+			"../../testdata/test.invalid-stream-idle-timeout.conf",
+			`invalid stream idle timeout secs -1 in path query of origin config default`,
+		},
+		{ // Case 23
+			"../../testdata/test.invalid-origin-auth.conf",
+			`invalid auth: only one of token, username or token_url may be set`,
+		},
+		{ // Case 24
+			"../../testdata/test.invalid-health-check-interval.conf",
+			`invalid health check interval secs: 0`,
+		},
+		{ // Case 25
+			"../../testdata/test.invalid-reload-client-ca.conf",
+			`client_ca_paths requires tls_cert_path and tls_key_path`,
+		},
+		{ // Case 26
+			"../../testdata/test.invalid-listener-drain-timeout.conf",
+			`invalid listener name in listener_drain_timeout_secs: frontend`,
+		},
+		{ // Case 27
+			"../../testdata/test.invalid-static-hosts.conf",
+			`invalid static hosts: invalid address prometheus-1 for host prometheus.example.com`,
+		},
+		{ // Case 28
+			"../../testdata/test.invalid-cache-key-template.conf",
+			`invalid cache key template in path query of origin config default: unknown component {cookie:session}`,
+		},
+		{ // Case 29
+			"../../testdata/test.invalid-cache-compression.conf",
+			`invalid compression codec: zstd`,
+		},
+		{ // Case 30
+			"../../testdata/test.invalid-spiffe-upstream.conf",
+			`spiffe_upstream requires the spiffe svid_cert_path`,
+		},
+		{ // Case 31
+			"../../testdata/test.invalid-push-paths.conf",
+			`invalid push path api/v1/labels in path query of origin config default`,
+		},
+		{ // Case 32
+			"../../testdata/test.invalid-default-priority-class.conf",
+			`invalid default priority class: interactve (did you mean 'interactive'?)`,
+		},
+		{ // Case 33
+			"../../testdata/test.invalid-adaptive-concurrency.conf",
+			`invalid adaptive concurrency: backoff_ratio must be between 0 and 1`,
+		},
+		{ // Case 34
+			"../../testdata/test.invalid-origin-url-ipv6.conf",
+			`IPv6 literal must be enclosed in brackets in host "::1:9090"`,
+		},
+		{ // Case 35
+			"../../testdata/test.invalid-listen-family.conf",
+			`listen family "dual" requires an empty listen address, not "127.0.0.1"`,
+		},
+		{ // Case 36
+			"../../testdata/test.invalid-target-weights.conf",
+			`invalid target weight for http://unknown:9090: 2`,
+		},
+		{ // Case 37
+			"../../testdata/test.invalid-security-headers.conf",
+			`invalid security headers preset strict in path root of origin config default`,
+		},
+		{ // Case 38
+			"../../testdata/test.invalid-delta-verify-steps.conf",
+			`invalid delta verify steps: -1`,
+		},
+		{ // Case 39
+			"../../testdata/test.invalid-empty-quarantine-ttl.conf",
+			`invalid empty quarantine ttl secs: -1`,
+		},
+		{ // Case 40
+			"../../testdata/test.invalid-canary-without-admin.conf",
+			`reloading.canary_listen_port: canary_listen_port requires the Admin API, which is enabled by admin.auth_token`,
+		},
+		{ // Case 41
+			"../../testdata/test.invalid-tls-watch-interval.conf",
+			`invalid tls watch interval secs: -1`,
+		},
+		{ // Case 42
+			"../../testdata/test.invalid-cache-startup-mode.conf",
+			`caches.default.startup_mode: invalid startup mode: retries`,
+		},
+		{ // Case 43
+			"../../testdata/test.invalid-cache-private-responses.conf",
+			`cache_private_responses requires a cache_key_principal in path query of origin config default`,
+		},
+		{ // Case 44
+			"../../testdata/test.invalid-injected-label-basic.conf",
+			`basic auth is not a trusted source for label namespace`,
+		},
+		{ // Case 45
+			"../../testdata/test.invalid-injected-label-untrusted.conf",
+			`header:X-Tenant for label namespace requires a trusted proxy`,
+		},
 	}
 
 	for i, test := range tests {
@@ -127,6 +280,26 @@ func TestFullLoadConfiguration(t *testing.T) {
 		t.Errorf("expected 38821, got %d", conf.Frontend.TLSListenPort)
 	}
 
+	if conf.Frontend.ListenFamily != "ipv4" {
+		t.Errorf("expected ipv4, got %s", conf.Frontend.ListenFamily)
+	}
+
+	if conf.Frontend.TLSListenFamily != "ipv6" {
+		t.Errorf("expected ipv6, got %s", conf.Frontend.TLSListenFamily)
+	}
+
+	if conf.Frontend.SecurityHeaders != "standard" {
+		t.Errorf("expected standard, got %s", conf.Frontend.SecurityHeaders)
+	}
+
+	if conf.Frontend.ContentSecurityPolicy != "default-src 'none'" {
+		t.Errorf("expected default-src 'none', got %s", conf.Frontend.ContentSecurityPolicy)
+	}
+
+	if conf.Frontend.TLSWatchIntervalSecs != 30 {
+		t.Errorf("expected 30, got %d", conf.Frontend.TLSWatchIntervalSecs)
+	}
+
 	// Test Metrics Server
 	if conf.Metrics.ListenPort != 57822 {
 		t.Errorf("expected 57821, got %d", conf.Metrics.ListenPort)
@@ -136,6 +309,23 @@ func TestFullLoadConfiguration(t *testing.T) {
 		t.Errorf("expected test, got %s", conf.Metrics.ListenAddress)
 	}
 
+	if conf.Metrics.ListenFamily != "ipv4" {
+		t.Errorf("expected ipv4, got %s", conf.Metrics.ListenFamily)
+	}
+
+	if !conf.Metrics.Exemplars {
+		t.Errorf("expected exemplars true, got %t", conf.Metrics.Exemplars)
+	}
+
+	// Test Reload Listener
+	if conf.ReloadConfig.AuthToken != "test_reload_token" ||
+		conf.ReloadConfig.TLSKeyPath != "../../testdata/test.01.key.pem" ||
+		len(conf.ReloadConfig.ClientCAPaths) != 1 ||
+		!conf.ReloadConfig.DrainForceCloseDisabled ||
+		conf.ReloadConfig.DrainTimeout("metrics") != 5*time.Second {
+		t.Errorf("unexpected reload options %v", conf.ReloadConfig)
+	}
+
 	// Test Logging
 	if conf.Logging.LogLevel != "test_log_level" {
 		t.Errorf("expected test_log_level, got %s", conf.Logging.LogLevel)
@@ -181,10 +371,81 @@ func TestFullLoadConfiguration(t *testing.T) {
 		t.Errorf("expected %s, got %s", evictionmethods.EvictionMethodLRU, o.TimeseriesEvictionMethod)
 	}
 
+	if o.TimeseriesMaxExtents != 64 {
+		t.Errorf("expected %d, got %d", 64, o.TimeseriesMaxExtents)
+	}
+
+	if o.TimeseriesShardWindow != time.Duration(86400)*time.Second {
+		t.Errorf("expected %d, got %d", 86400, o.TimeseriesShardWindowSecs)
+	}
+
+	if o.TimeseriesMaxAge != time.Duration(3024000)*time.Second {
+		t.Errorf("expected %d, got %d", 3024000, o.TimeseriesMaxAgeSecs)
+	}
+
+	if o.RetentionInterval != time.Duration(600)*time.Second {
+		t.Errorf("expected %d, got %d", 600, o.RetentionIntervalSecs)
+	}
+
+	if len(o.DedupeLabels) != 2 || o.DedupeLabels[1] != "prometheus_replica" {
+		t.Errorf("unexpected dedupe labels %v", o.DedupeLabels)
+	}
+
+	if o.MaxDataPoints != 1500 {
+		t.Errorf("expected %d got %d", 1500, o.MaxDataPoints)
+	}
+
+	if o.ValuePrecision != 4 || !o.ValuePrecisionOnServe {
+		t.Errorf("expected value precision %d on serve, got %d %t", 4,
+			o.ValuePrecision, o.ValuePrecisionOnServe)
+	}
+
+	if o.DeltaVerifySteps != 3 || !o.DeltaVerifyInvalidate {
+		t.Errorf("expected delta verify steps %d with invalidation, got %d %t", 3,
+			o.DeltaVerifySteps, o.DeltaVerifyInvalidate)
+	}
+
+	if o.DataPointAggregation != timeseries.AggregationMax {
+		t.Errorf("expected %s got %s", timeseries.AggregationMax, o.DataPointAggregation)
+	}
+
 	if !o.FastForwardDisable {
 		t.Errorf("expected fast_forward_disable true, got %t", o.FastForwardDisable)
 	}
 
+	if !o.CacheStateHeaders {
+		t.Errorf("expected cache_state_headers true, got %t", o.CacheStateHeaders)
+	}
+
+	if !o.QueryNormalizationDisabled {
+		t.Errorf("expected query_normalization_disabled true, got %t", o.QueryNormalizationDisabled)
+	}
+
+	if !o.ExemplarsDisabled {
+		t.Errorf("expected exemplars_disabled true, got %t", o.ExemplarsDisabled)
+	}
+
+	if o.InjectedLabelEnforcer == nil {
+		t.Fatal("expected injected label enforcer")
+	}
+
+	if s, ok := o.InjectedLabelEnforcer.Sources["namespace"]; !ok || s.String() != "header:X-Tenant" {
+		t.Errorf("expected injected label source %s got %v", "header:X-Tenant", s)
+	}
+
+	if s, ok := o.InjectedLabelEnforcer.Sources["cluster"]; !ok || s.String() != "jwt:cluster" {
+		t.Errorf("expected injected label source %s got %v", "jwt:cluster", s)
+	}
+
+	if len(o.InjectedLabelsTrustedProxies) != 1 || o.InjectedLabelsTrustedProxies[0] != "10.0.0.0/8" {
+		t.Errorf("expected injected labels trusted proxies %v", []string{"10.0.0.0/8"})
+	}
+
+	if o.InjectedLabelsJWTSecret != "test-secret" {
+		t.Errorf("expected injected labels jwt secret %s got %s", "test-secret",
+			o.InjectedLabelsJWTSecret)
+	}
+
 	if o.BackfillToleranceSecs != 301 {
 		t.Errorf("expected 301, got %d", o.BackfillToleranceSecs)
 	}
@@ -193,6 +454,76 @@ func TestFullLoadConfiguration(t *testing.T) {
 		t.Errorf("expected 37, got %d", o.TimeoutSecs)
 	}
 
+	if o.DeadlineHeader != "grpc-timeout" {
+		t.Errorf("expected %s got %s", "grpc-timeout", o.DeadlineHeader)
+	}
+
+	if len(o.HedgeTargets) != 2 || o.HedgeTargets[0].Host != "replica-1:9090" ||
+		o.HedgeTargets[0].Path != "" || o.HedgeTargets[1].Path != "/prometheus" {
+		t.Errorf("unexpected hedge targets %v", o.HedgeTargets)
+	}
+
+	if len(o.TargetURLs) != 2 || o.TargetSelection != "weight" ||
+		o.TargetWeights["http://eu-west:9090/prom"] != 3 {
+		t.Errorf("unexpected target options %v %s %v", o.TargetURLs, o.TargetSelection,
+			o.TargetWeights)
+	}
+
+	if o.TargetSelector == nil || len(o.TargetSelector.Targets()) != 3 {
+		t.Fatal("expected a target selector with 3 targets")
+	}
+	if ts := o.TargetSelector.Targets(); ts[0].URL.Host != o.Host || ts[1].URL.Path != "" ||
+		ts[2].URL.Path != "/prom" || ts[2].Weight != 3 || ts[1].Weight != 1 {
+		t.Errorf("unexpected targets %v", ts)
+	}
+	if s := o.TargetSelector.Select(); s.URL.Host != "eu-west:9090" {
+		t.Errorf("expected eu-west:9090 got %s", s.URL.Host)
+	}
+
+	if len(o.CacheBusterParams) != 2 || o.CacheBusterParams[1] != "nocache" {
+		t.Errorf("unexpected cache buster params %v", o.CacheBusterParams)
+	}
+
+	if o.CacheMaxBodyBytes != 1048576 {
+		t.Errorf("expected %d, got %d", 1048576, o.CacheMaxBodyBytes)
+	}
+
+	if len(o.CacheContentTypes) != 1 || o.CacheContentTypes[0] != "application/json" {
+		t.Errorf("unexpected cache content types %v", o.CacheContentTypes)
+	}
+
+	if ips := o.StaticHosts["prometheus.example.com"]; len(ips) != 2 || ips[1] != "10.0.0.2" {
+		t.Errorf("unexpected static hosts %v", o.StaticHosts)
+	}
+
+	if o.DNSResolver != "10.0.0.53:53" {
+		t.Errorf("expected %s got %s", "10.0.0.53:53", o.DNSResolver)
+	}
+
+	if o.WarmConnections != 4 {
+		t.Errorf("expected %d got %d", 4, o.WarmConnections)
+	}
+
+	if o.HedgeQuantile != 0.9 {
+		t.Errorf("expected %g got %g", 0.9, o.HedgeQuantile)
+	}
+
+	if o.HedgeMinDelay != 25*time.Millisecond {
+		t.Errorf("expected %s got %s", 25*time.Millisecond, o.HedgeMinDelay)
+	}
+
+	if !o.DegradedMode || o.HealthCheckInterval != 15*time.Second ||
+		o.HealthCheckFailureThreshold != 5 {
+		t.Errorf("unexpected degraded mode options %t %s %d", o.DegradedMode,
+			o.HealthCheckInterval, o.HealthCheckFailureThreshold)
+	}
+
+	if o.Auth == nil || o.Auth.TokenURL != "https://auth.example.com/oauth2/token" ||
+		o.Auth.ClientID != "trickster" || o.Auth.ClientSecret != "test_client_secret" ||
+		len(o.Auth.Scopes) != 1 || o.Auth.Headers["X-Scope-OrgID"] != "test-tenant" {
+		t.Errorf("unexpected auth options %v", o.Auth)
+	}
+
 	if o.IsDefault != true {
 		t.Errorf("expected true got %t", o.IsDefault)
 	}
@@ -205,6 +536,59 @@ func TestFullLoadConfiguration(t *testing.T) {
 		t.Errorf("expected %d got %d", 7, o.KeepAliveTimeoutSecs)
 	}
 
+	if len(o.DownsampleTierList) != 2 || o.DownsampleTierList[0].Name != "5m" ||
+		o.DownsampleTierList[0].Step != 5*time.Minute || o.DownsampleTierList[1].MinAge != 7*24*time.Hour {
+		t.Errorf("unexpected downsample tiers %v", o.DownsampleTierList)
+	}
+
+	if len(o.BackfillToleranceRuleList) != 1 ||
+		o.BackfillToleranceRuleList[0].BackfillTolerance != 5*time.Minute ||
+		!o.BackfillToleranceRuleList[0].Matches("sum(rate(requests_total[5m]))") {
+		t.Errorf("unexpected backfill tolerance rules %v", o.BackfillToleranceRuleList)
+	}
+
+	if p, ok := o.Paths["/series-GET-HEAD"]; !ok || !p.HasBackfillTolerance ||
+		p.BackfillTolerance != 2*time.Minute {
+		t.Errorf("expected path backfill tolerance %s", 2*time.Minute)
+	}
+
+	if p, ok := o.Paths["/series-GET-HEAD"]; !ok || !p.HasMaxRange || p.MaxRange != 24*time.Hour ||
+		p.HasMaxLookback {
+		t.Errorf("expected path max range %s", 24*time.Hour)
+	}
+
+	if p, ok := o.Paths["/series-GET-HEAD"]; !ok || len(p.JSONPaths) != 2 ||
+		p.JSONPaths[0].String() != "$.queries[0].expr" {
+		t.Errorf("expected path json paths %v", []string{"$.queries[0].expr", "$.range.from"})
+	}
+
+	if p, ok := o.Paths["/series-GET-HEAD"]; !ok || p.Principal == nil ||
+		p.Principal.String() != "jwt:sub" {
+		t.Errorf("expected path cache key principal %s", "jwt:sub")
+	}
+	if p, ok := o.Paths["/series-GET-HEAD"]; !ok || !p.CachePrivateResponses {
+		t.Errorf("expected path cache private responses %t", true)
+	}
+
+	if p, ok := o.Paths["/series-GET-HEAD"]; !ok || p.KeyTemplate == nil ||
+		p.KeyTemplate.String() != "{method} {header:X-Tenant} {param:query}" {
+		t.Errorf("expected path cache key template %s", "{method} {header:X-Tenant} {param:query}")
+	}
+
+	if p, ok := o.Paths["/series-GET-HEAD"]; !ok || !p.Streaming ||
+		p.StreamIdleTimeout != 10*time.Minute {
+		t.Errorf("expected streaming path with idle timeout %s", 10*time.Minute)
+	}
+
+	if p, ok := o.Paths["/series-GET-HEAD"]; !ok || len(p.PushPaths) != 1 || p.PushPaths[0] != "/label" {
+		t.Errorf("expected path push paths %v", []string{"/label"})
+	}
+
+	if p, ok := o.Paths["/series-GET-HEAD"]; !ok || p.SecurityHeaders != "none" ||
+		p.SecurityHeaderSet == nil || len(p.SecurityHeaderSet) != 0 {
+		t.Errorf("expected path security headers preset %s", "none")
+	}
+
 	// MaxTTLSecs is 300, thus should override TimeseriesTTLSecs = 8666
 	if o.TimeseriesTTLSecs != 300 {
 		t.Errorf("expected 300, got %d", o.TimeseriesTTLSecs)
@@ -215,6 +599,39 @@ func TestFullLoadConfiguration(t *testing.T) {
 		t.Errorf("expected 300, got %d", o.FastForwardTTLSecs)
 	}
 
+	if o.EmptyQuarantineTTL != 20*time.Second {
+		t.Errorf("expected 20s, got %s", o.EmptyQuarantineTTL)
+	}
+
+	if o.StatusCacheTTLSecs != 10 {
+		t.Errorf("expected 10, got %d", o.StatusCacheTTLSecs)
+	}
+
+	if o.FederateCacheTTLSecs != 30 {
+		t.Errorf("expected 30, got %d", o.FederateCacheTTLSecs)
+	}
+
+	if o.RemoteWriteInvalidation != 45*time.Second {
+		t.Errorf("expected %s, got %s", 45*time.Second, o.RemoteWriteInvalidation)
+	}
+
+	if o.MaxLookback != 720*time.Hour {
+		t.Errorf("expected %s, got %s", 720*time.Hour, o.MaxLookback)
+	}
+
+	if o.MaxRange != 168*time.Hour {
+		t.Errorf("expected %s, got %s", 168*time.Hour, o.MaxRange)
+	}
+
+	if o.TimeRangeLimitAction != timeseries.LimitActionReject {
+		t.Errorf("expected %s, got %s", timeseries.LimitActionReject, o.TimeRangeLimitAction)
+	}
+
+	// MaxTTLSecs is 300, thus should override SelectCacheTTLSecs = 600
+	if o.SelectCacheTTLSecs != 300 {
+		t.Errorf("expected 300, got %d", o.SelectCacheTTLSecs)
+	}
+
 	if o.TLS == nil {
 		t.Errorf("expected tls config for origin %s, got nil", "test")
 	}
@@ -239,6 +656,55 @@ func TestFullLoadConfiguration(t *testing.T) {
 		t.Errorf("expected test_client_key got %s", o.TLS.ClientKeyPath)
 	}
 
+	if o.PriorityHeader != "X-Test-Priority" {
+		t.Errorf("expected X-Test-Priority got %s", o.PriorityHeader)
+	}
+
+	if o.DefaultPriorityClass != "interactive" {
+		t.Errorf("expected interactive got %s", o.DefaultPriorityClass)
+	}
+
+	if c, ok := o.PriorityClasses["interactive"]; !ok || c.MaxConcurrency != 32 ||
+		c.QueueTimeout != 10*time.Second {
+		t.Errorf("expected priority class %s with default queue timeout", "interactive")
+	}
+
+	if c, ok := o.PriorityClasses["bulk"]; !ok || c.Name != "bulk" || len(c.Paths) != 1 ||
+		c.MaxConcurrency != 4 || c.MaxQueueLength != 100 || c.QueueTimeout != 30*time.Second {
+		t.Errorf("expected priority class %s", "bulk")
+	}
+
+	if ac := o.AdaptiveConcurrency; ac == nil || ac.InitialLimit != 20 || ac.MinLimit != 1 ||
+		ac.MaxLimit != 50 || ac.BackoffRatio != 0.8 || ac.LatencyTolerance != 2 {
+		t.Errorf("expected adaptive concurrency max_limit %d", 50)
+	}
+
+	if !o.SpiffeUpstream {
+		t.Errorf("expected true got %t", o.SpiffeUpstream)
+	}
+
+	if len(o.SpiffeUpstreamIDs) != 1 || o.SpiffeUpstreamIDs[0] != "spiffe://example.org/prometheus" {
+		t.Errorf("expected spiffe://example.org/prometheus got %v", o.SpiffeUpstreamIDs)
+	}
+
+	// Test SPIFFE
+
+	if conf.Spiffe.SVIDCertPath != "test_svid_cert" {
+		t.Errorf("expected test_svid_cert got %s", conf.Spiffe.SVIDCertPath)
+	}
+
+	if conf.Spiffe.TrustDomain != "example.org" {
+		t.Errorf("expected example.org got %s", conf.Spiffe.TrustDomain)
+	}
+
+	if conf.Spiffe.RefreshIntervalSecs != 45 {
+		t.Errorf("expected 45 got %d", conf.Spiffe.RefreshIntervalSecs)
+	}
+
+	if len(conf.Spiffe.FrontendAllowedIDs) != 1 {
+		t.Errorf("expected 1 got %d", len(conf.Spiffe.FrontendAllowedIDs))
+	}
+
 	// Test Caches
 
 	c, ok := conf.Caches["test"]
@@ -251,6 +717,22 @@ func TestFullLoadConfiguration(t *testing.T) {
 		t.Errorf("expected redis, got %s", c.CacheType)
 	}
 
+	if c.CompressionCodec != "br" {
+		t.Errorf("expected br, got %s", c.CompressionCodec)
+	}
+
+	if c.CompressionMinSizeBytes != 1024 {
+		t.Errorf("expected 1024, got %d", c.CompressionMinSizeBytes)
+	}
+
+	if c.StartupMode != "retry" {
+		t.Errorf("expected retry, got %s", c.StartupMode)
+	}
+
+	if !c.ReadOnly {
+		t.Errorf("expected %t, got %t", true, c.ReadOnly)
+	}
+
 	if c.Index.ReapIntervalSecs != 4 {
 		t.Errorf("expected 4, got %d", c.Index.ReapIntervalSecs)
 	}
@@ -355,6 +837,14 @@ func TestFullLoadConfiguration(t *testing.T) {
 		t.Errorf("expected test_cache_path, got %s", c.Filesystem.CachePath)
 	}
 
+	if c.Memory.SnapshotPath != "test_snapshot_path" {
+		t.Errorf("expected test_snapshot_path, got %s", c.Memory.SnapshotPath)
+	}
+
+	if c.Memory.SnapshotInterval != time.Duration(600)*time.Second {
+		t.Errorf("expected %s, got %s", time.Duration(600)*time.Second, c.Memory.SnapshotInterval)
+	}
+
 	if c.BBolt.Filename != "test_filename" {
 		t.Errorf("expected test_filename, got %s", c.BBolt.Filename)
 	}
@@ -475,6 +965,10 @@ func TestEmptyLoadConfiguration(t *testing.T) {
 		t.Errorf("expected %s, got %s", d.DefaultCacheType, c.CacheType)
 	}
 
+	if c.CompressionCodec != d.DefaultCacheCompressionCodec {
+		t.Errorf("expected %s, got %s", d.DefaultCacheCompressionCodec, c.CompressionCodec)
+	}
+
 	if c.Index.ReapIntervalSecs != d.DefaultCacheIndexReap {
 		t.Errorf("expected %d, got %d", d.DefaultCacheIndexReap, c.Index.ReapIntervalSecs)
 	}
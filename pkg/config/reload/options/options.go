@@ -17,7 +17,17 @@
 // Package options provides options for configuration reload support
 package options
 
-import "github.com/tricksterproxy/trickster/pkg/config/defaults"
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/config/defaults"
+	"github.com/tricksterproxy/trickster/pkg/util/strings"
+)
 
 // Options is a collection of configurations for in-process config reloading
 type Options struct {
@@ -25,16 +35,42 @@ type Options struct {
 	ListenAddress string `toml:"listen_address"`
 	// ListenPort is TCP Port from which the Reload API is available at ReloadHandlerPath
 	ListenPort int `toml:"listen_port"`
+	// ListenFamily is the address family (ipv4, ipv6 or dual) of the Reload API listener
+	ListenFamily string `toml:"listen_family"`
 	// ReloadHandlerPath provides the path to register the Config Reload Handler
 	HandlerPath string `toml:"handler_path"`
 	// DrainTimeoutSecs provides the duration to wait for all sessions to drain before closing
 	// old resources following a reload
 	DrainTimeoutSecs int `toml:"drain_timeout_secs"`
+	// ListenerDrainTimeoutSecs overrides DrainTimeoutSecs for the named listeners, which are
+	// 'http', 'tls', 'metrics', 'reload', 'debug' and 'canary'
+	ListenerDrainTimeoutSecs map[string]int `toml:"listener_drain_timeout_secs"`
+	// DrainForceCloseDisabled, when true, lets a closing listener wait indefinitely for its
+	// in-flight requests to complete, rather than closing their connections once the
+	// drain timeout has elapsed
+	DrainForceCloseDisabled bool `toml:"drain_force_close_disabled"`
 	// RateLimitSecs limits the # of handled config reload HTTP requests to 1 per CheckRateSecs
 	// if multiple HTTP requests are received in the rate limit window, only the first is handled
 	// This prevents a bad actor from stating the config file with millions of concurrent requets
 	// The rate limit does not apply to SIGHUP-based reload requests
 	RateLimitSecs int `toml:"rate_limit_secs"`
+	// AuthToken is the bearer token required for every request to the reload listener, other
+	// than those to the Admin API, which requires its own token
+	AuthToken string `toml:"auth_token"`
+	// TLSCertPath is the path of the certificate file of the reload listener. When it and
+	// TLSKeyPath are set, the reload listener serves HTTPS
+	TLSCertPath string `toml:"tls_cert_path"`
+	// TLSKeyPath is the path of the private key file of the reload listener
+	TLSKeyPath string `toml:"tls_key_path"`
+	// ClientCAPaths is a list of certificate authorities. When set, every client of the reload
+	// listener must present a certificate that is signed by one of them
+	ClientCAPaths []string `toml:"client_ca_paths"`
+	// CanaryListenAddress is IP address of the canary listener
+	CanaryListenAddress string `toml:"canary_listen_address"`
+	// CanaryListenPort is TCP Port of the canary listener. When set, a reloaded config is
+	// staged on the canary listener, and is only applied to the main listeners once it is
+	// promoted through the Admin API
+	CanaryListenPort int `toml:"canary_listen_port"`
 }
 
 // NewOptions returns a new Options references with Default Values set
@@ -47,3 +83,123 @@ func NewOptions() *Options {
 		RateLimitSecs:    defaults.DefaultRateLimitSecs,
 	}
 }
+
+// Clone returns an exact copy of the subject *Options
+func (o *Options) Clone() *Options {
+	var caps []string
+	if o.ClientCAPaths != nil {
+		caps = make([]string, len(o.ClientCAPaths))
+		copy(caps, o.ClientCAPaths)
+	}
+	var ldt map[string]int
+	if o.ListenerDrainTimeoutSecs != nil {
+		ldt = make(map[string]int, len(o.ListenerDrainTimeoutSecs))
+		for k, v := range o.ListenerDrainTimeoutSecs {
+			ldt[k] = v
+		}
+	}
+	return &Options{
+		ListenAddress:            o.ListenAddress,
+		ListenPort:               o.ListenPort,
+		ListenFamily:             o.ListenFamily,
+		HandlerPath:              o.HandlerPath,
+		DrainTimeoutSecs:         o.DrainTimeoutSecs,
+		ListenerDrainTimeoutSecs: ldt,
+		DrainForceCloseDisabled:  o.DrainForceCloseDisabled,
+		RateLimitSecs:            o.RateLimitSecs,
+		AuthToken:                o.AuthToken,
+		TLSCertPath:              o.TLSCertPath,
+		TLSKeyPath:               o.TLSKeyPath,
+		ClientCAPaths:            caps,
+		CanaryListenAddress:      o.CanaryListenAddress,
+		CanaryListenPort:         o.CanaryListenPort,
+	}
+}
+
+// listenerNames are the names of the listeners whose drain timeout can be overridden
+var listenerNames = map[string]bool{
+	"http":    true,
+	"tls":     true,
+	"metrics": true,
+	"reload":  true,
+	"debug":   true,
+	"canary":  true,
+}
+
+// Staged returns true when reloaded configs are staged on the canary listener
+func (o *Options) Staged() bool {
+	return o.CanaryListenPort > 0
+}
+
+// DrainTimeout returns the time that the named listener is given to complete its in-flight
+// requests when it is closed
+func (o *Options) DrainTimeout(listenerName string) time.Duration {
+	if secs, ok := o.ListenerDrainTimeoutSecs[listenerName]; ok {
+		return time.Duration(secs) * time.Second
+	}
+	return time.Duration(o.DrainTimeoutSecs) * time.Second
+}
+
+// Validate returns an error if the drain timeouts or canary listener port are invalid
+func (o *Options) Validate() error {
+	if o.DrainTimeoutSecs < 0 {
+		return errors.New("drain_timeout_secs must be >= 0")
+	}
+	if o.CanaryListenPort < 0 {
+		return errors.New("canary_listen_port must be >= 0")
+	}
+	for k, v := range o.ListenerDrainTimeoutSecs {
+		if !listenerNames[k] {
+			return fmt.Errorf("invalid listener name in listener_drain_timeout_secs: %s", k)
+		}
+		if v < 0 {
+			return fmt.Errorf("listener_drain_timeout_secs for %s must be >= 0", k)
+		}
+	}
+	return nil
+}
+
+// ListenerEqual returns true if the options of the reload listener itself, rather than of
+// its handlers, are equal, so that the running listener can be reused
+func (o *Options) ListenerEqual(o2 *Options) bool {
+	return o2 != nil && o.ListenAddress == o2.ListenAddress && o.ListenPort == o2.ListenPort &&
+		o.ListenFamily == o2.ListenFamily &&
+		o.TLSCertPath == o2.TLSCertPath && o.TLSKeyPath == o2.TLSKeyPath &&
+		strings.Equal(o.ClientCAPaths, o2.ClientCAPaths)
+}
+
+// ErrClientCAWithoutTLS is returned when client certificates are required, but the reload
+// listener does not serve TLS
+var ErrClientCAWithoutTLS = errors.New("client_ca_paths requires tls_cert_path and tls_key_path")
+
+// TLSConfig returns the TLS configuration of the reload listener, or nil when it does not
+// serve TLS. When ClientCAPaths are set, clients must present a certificate signed by one
+// of them
+func (o *Options) TLSConfig() (*tls.Config, error) {
+	if o.TLSCertPath == "" && o.TLSKeyPath == "" {
+		if len(o.ClientCAPaths) > 0 {
+			return nil, ErrClientCAWithoutTLS
+		}
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(o.TLSCertPath, o.TLSKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	tc := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if len(o.ClientCAPaths) == 0 {
+		return tc, nil
+	}
+	tc.ClientCAs = x509.NewCertPool()
+	for _, path := range o.ClientCAPaths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if !tc.ClientCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("unable to append to client CA certs from file %s", path)
+		}
+	}
+	tc.ClientAuth = tls.RequireAndVerifyClientCert
+	return tc, nil
+}
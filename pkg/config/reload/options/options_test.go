@@ -16,7 +16,12 @@
 
 package options
 
-import "testing"
+import (
+	"crypto/tls"
+	"reflect"
+	"testing"
+	"time"
+)
 
 func TestNewOptions(t *testing.T) {
 	o := NewOptions()
@@ -24,3 +29,126 @@ func TestNewOptions(t *testing.T) {
 		t.Error("expected non-nil options")
 	}
 }
+
+func TestClone(t *testing.T) {
+	o := NewOptions()
+	o.AuthToken = "token"
+	o.ClientCAPaths = []string{"ca.pem"}
+	o.ListenerDrainTimeoutSecs = map[string]int{"metrics": 5}
+	o.CanaryListenPort = 8485
+	o2 := o.Clone()
+	if !reflect.DeepEqual(o, o2) {
+		t.Errorf("expected %v got %v", o, o2)
+	}
+	if !o.ListenerEqual(o2) {
+		t.Error("expected equal listener options")
+	}
+	o2.AuthToken = "other"
+	if !o.ListenerEqual(o2) {
+		t.Error("expected equal listener options")
+	}
+	o2.ClientCAPaths[0] = "other.pem"
+	if o.ListenerEqual(o2) || o.ListenerEqual(nil) {
+		t.Error("expected unequal listener options")
+	}
+	o2 = o.Clone()
+	o2.ListenFamily = "ipv6"
+	if o.ListenerEqual(o2) {
+		t.Error("expected unequal listener options")
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	o := NewOptions()
+	o.ListenerDrainTimeoutSecs = map[string]int{"metrics": 0, "reload": 5}
+	if err := o.Validate(); err != nil {
+		t.Error(err)
+	}
+	tests := map[string]time.Duration{
+		"http":    30 * time.Second,
+		"metrics": 0,
+		"reload":  5 * time.Second,
+	}
+	for k, expected := range tests {
+		if d := o.DrainTimeout(k); d != expected {
+			t.Errorf("%s: expected %s got %s", k, expected, d)
+		}
+	}
+
+	o.ListenerDrainTimeoutSecs["reload"] = -1
+	if err := o.Validate(); err == nil {
+		t.Error("expected error for negative timeout")
+	}
+	o.ListenerDrainTimeoutSecs = map[string]int{"unknown": 1}
+	if err := o.Validate(); err == nil {
+		t.Error("expected error for unknown listener name")
+	}
+	o.ListenerDrainTimeoutSecs = nil
+	o.DrainTimeoutSecs = -1
+	if err := o.Validate(); err == nil {
+		t.Error("expected error for negative timeout")
+	}
+}
+
+func TestStaged(t *testing.T) {
+	o := NewOptions()
+	if o.Staged() {
+		t.Error("expected unstaged reloads by default")
+	}
+	o.CanaryListenPort = 8485
+	if !o.Staged() {
+		t.Error("expected staged reloads")
+	}
+	if err := o.Validate(); err != nil {
+		t.Error(err)
+	}
+	o.CanaryListenPort = -1
+	if err := o.Validate(); err == nil {
+		t.Error("expected error for negative canary listen port")
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+
+	o := NewOptions()
+	tc, err := o.TLSConfig()
+	if tc != nil || err != nil {
+		t.Errorf("expected nil config and error, got %v %v", tc, err)
+	}
+
+	o.ClientCAPaths = []string{"../../../../testdata/test.rootca.pem"}
+	if _, err = o.TLSConfig(); err != ErrClientCAWithoutTLS {
+		t.Errorf("expected %v got %v", ErrClientCAWithoutTLS, err)
+	}
+
+	o.TLSCertPath = "../../../../testdata/test.01.cert.pem"
+	o.TLSKeyPath = "../../../../testdata/test.01.key.pem"
+	tc, err = o.TLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.ClientAuth != tls.RequireAndVerifyClientCert || len(tc.Certificates) != 1 {
+		t.Errorf("unexpected tls config %v", tc)
+	}
+
+	o.ClientCAPaths = nil
+	tc, err = o.TLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tc.ClientAuth != tls.NoClientCert {
+		t.Errorf("expected %v got %v", tls.NoClientCert, tc.ClientAuth)
+	}
+
+	for _, caps := range [][]string{{"nonexistent.pem"}, {"../../../../testdata/test.01.key.pem"}} {
+		o.ClientCAPaths = caps
+		if _, err = o.TLSConfig(); err == nil {
+			t.Errorf("expected error for client ca paths %v", caps)
+		}
+	}
+
+	o.TLSKeyPath = "nonexistent.pem"
+	if _, err = o.TLSConfig(); err == nil {
+		t.Error("expected error for invalid key path")
+	}
+}
@@ -0,0 +1,308 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/secrets"
+	"github.com/tricksterproxy/trickster/pkg/secrets/registration"
+	"github.com/tricksterproxy/trickster/pkg/util/sha1"
+)
+
+// secretsDirPrefix is the prefix of the name of the private directory, under the OS temp
+// dir, where secrets that must be presented to consumers as file paths (e.g., TLS keys)
+// are materialized
+const secretsDirPrefix = "trickster-secrets-"
+
+// secretsTempDir is swappable for testing
+var secretsTempDir = os.TempDir
+
+// secretsDir is the private directory of this process where secrets are materialized.
+// It is created with an unpredictable name by the first config that materializes a secret,
+// and is reused by reloaded configs for as long as it remains private
+var (
+	secretsDir    string
+	secretsDirMtx sync.Mutex
+)
+
+// privateSecretsDir returns the process's private secrets directory, creating it when it
+// does not yet exist
+func privateSecretsDir() (string, error) {
+	secretsDirMtx.Lock()
+	defer secretsDirMtx.Unlock()
+	if secretsDir != "" {
+		err := checkPrivateDir(secretsDir)
+		if err == nil || !os.IsNotExist(err) {
+			return secretsDir, err
+		}
+	}
+	dir, err := ioutil.TempDir(secretsTempDir(), secretsDirPrefix)
+	if err != nil {
+		return "", err
+	}
+	if err := checkPrivateDir(dir); err != nil {
+		return "", err
+	}
+	secretsDir = dir
+	return dir, nil
+}
+
+// checkPrivateDir returns an error if the path is not a directory that only the current
+// user can access. Symlinks are refused, since they may point anywhere
+func checkPrivateDir(dir string) error {
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("secrets directory %s is a symlink", dir)
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("secrets directory %s is not a directory", dir)
+	}
+	return checkDirOwnership(dir, fi)
+}
+
+// resolveSecrets registers the configured secrets providers and replaces any secret
+// references in supported config fields with the values fetched from their providers
+func (c *Config) resolveSecrets() error {
+
+	c.secretValues = make(map[string]string)
+
+	if len(c.Secrets) > 0 {
+		p, err := registration.RegisterAll(c.Secrets)
+		if err != nil {
+			return err
+		}
+		c.secretProviders = p
+	} else {
+		c.secretProviders = make(secrets.Providers)
+	}
+
+	resolve := func(s *string) error {
+		if s == nil || !secrets.IsReference(*s) {
+			return nil
+		}
+		v, err := c.secretProviders.Resolve(*s)
+		if err != nil {
+			return err
+		}
+		c.secretValues[*s] = v
+		*s = v
+		return nil
+	}
+
+	resolveMap := func(m map[string]string) error {
+		for k, v := range m {
+			if err := resolve(&v); err != nil {
+				return err
+			}
+			m[k] = v
+		}
+		return nil
+	}
+
+	// resolveFile writes a secret to a private file and replaces the reference with its path
+	resolveFile := func(s *string) error {
+		if s == nil || !secrets.IsReference(*s) {
+			return nil
+		}
+		ref := *s
+		if err := resolve(s); err != nil {
+			return err
+		}
+		dir, err := privateSecretsDir()
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, sha1.Checksum(ref))
+		if err := ioutil.WriteFile(path, []byte(*s), 0600); err != nil {
+			return err
+		}
+		*s = path
+		return nil
+	}
+
+	for _, cc := range c.Caches {
+		if cc != nil && cc.Redis != nil {
+			if err := resolve(&cc.Redis.Password); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, tc := range c.TracingConfigs {
+		if tc == nil {
+			continue
+		}
+		if err := resolve(&tc.CollectorUser); err != nil {
+			return err
+		}
+		if err := resolve(&tc.CollectorPass); err != nil {
+			return err
+		}
+		if tc.OTLPOptions != nil {
+			if err := resolveMap(tc.OTLPOptions.Headers); err != nil {
+				return err
+			}
+		}
+	}
+
+	if c.DebugConfig != nil {
+		if err := resolve(&c.DebugConfig.Password); err != nil {
+			return err
+		}
+	}
+
+	if c.AdminConfig != nil {
+		if err := resolve(&c.AdminConfig.AuthToken); err != nil {
+			return err
+		}
+	}
+
+	if c.ReloadConfig != nil {
+		if err := resolve(&c.ReloadConfig.AuthToken); err != nil {
+			return err
+		}
+		for _, s := range []*string{&c.ReloadConfig.TLSCertPath, &c.ReloadConfig.TLSKeyPath} {
+			if err := resolveFile(s); err != nil {
+				return err
+			}
+		}
+	}
+
+	if c.Metrics != nil && c.Metrics.OTLP != nil {
+		if err := resolveMap(c.Metrics.OTLP.Headers); err != nil {
+			return err
+		}
+	}
+
+	for _, rw := range c.RequestRewriters {
+		if rw == nil {
+			continue
+		}
+		for _, in := range rw.Instructions {
+			for i := range in {
+				if err := resolve(&in[i]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for _, oc := range c.Origins {
+		if oc == nil {
+			continue
+		}
+		if err := resolveMap(oc.HealthCheckHeaders); err != nil {
+			return err
+		}
+		if err := resolve(&oc.InjectedLabelsJWTSecret); err != nil {
+			return err
+		}
+		for _, p := range oc.Paths {
+			if p == nil {
+				continue
+			}
+			if err := resolveMap(p.RequestHeaders); err != nil {
+				return err
+			}
+		}
+		if oc.Auth != nil {
+			for _, s := range []*string{&oc.Auth.Token, &oc.Auth.Username, &oc.Auth.Password,
+				&oc.Auth.ClientID, &oc.Auth.ClientSecret} {
+				if err := resolve(s); err != nil {
+					return err
+				}
+			}
+			if err := resolveMap(oc.Auth.Headers); err != nil {
+				return err
+			}
+		}
+		if oc.TLS != nil {
+			for _, s := range []*string{&oc.TLS.FullChainCertPath, &oc.TLS.PrivateKeyPath,
+				&oc.TLS.ClientCertPath, &oc.TLS.ClientKeyPath} {
+				if err := resolveFile(s); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// SecretsChanged re-fetches each secret that was resolved when the config was loaded
+// and returns true if any of their values have changed
+func (c *Config) SecretsChanged() (bool, error) {
+	for ref, v := range c.secretValues {
+		v2, err := c.secretProviders.Resolve(ref)
+		if err != nil {
+			return false, err
+		}
+		if v2 != v {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SecretsRefreshInterval returns the shortest refresh interval of any secrets provider
+// that resolved a value in the config, or 0 if refreshing is not required
+func (c *Config) SecretsRefreshInterval() time.Duration {
+	var d time.Duration
+	used := make(map[string]bool)
+	for ref := range c.secretValues {
+		if r, err := secrets.ParseReference(ref); err == nil {
+			used[r.ProviderName] = true
+		}
+	}
+	for k, o := range c.Secrets {
+		if !used[k] || o == nil || o.RefreshInterval <= 0 {
+			continue
+		}
+		if d == 0 || o.RefreshInterval < d {
+			d = o.RefreshInterval
+		}
+	}
+	return d
+}
+
+// isSecretValue returns true if the value was resolved from a secrets provider
+func (c *Config) isSecretValue(v string) bool {
+	for _, sv := range c.secretValues {
+		if v == sv {
+			return true
+		}
+	}
+	return false
+}
+
+// hideSecretValues masks any values in the map that were resolved from a secrets provider
+func (c *Config) hideSecretValues(m map[string]string) {
+	for k, v := range m {
+		if c.isSecretValue(v) {
+			m[k] = "*****"
+		}
+	}
+}
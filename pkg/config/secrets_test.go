@@ -0,0 +1,239 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecretsTOML = `
+[origins]
+  [origins.test]
+  origin_type = 'reverseproxycache'
+  origin_url = 'http://1'
+  health_check_headers = { Authorization = 'secret://env/TRK_TEST_ORIGIN_TOKEN', X-Plain = 'plain' }
+    [origins.test.tls]
+    client_cert_path = '../../testdata/test.01.cert.pem'
+    client_key_path = 'secret://env/TRK_TEST_CLIENT_KEY'
+[caches]
+  [caches.default]
+  cache_type = 'redis'
+    [caches.default.redis]
+    password = 'secret://env/TRK_TEST_REDIS#password'
+[metrics]
+  [metrics.otlp]
+  headers = { X-Api-Key = 'secret://env/TRK_TEST_ORIGIN_TOKEN' }
+[admin]
+  auth_token = 'secret://env/TRK_TEST_ADMIN_TOKEN'
+[secrets]
+  [secrets.env]
+  provider_type = 'env'
+  refresh_interval_secs = 30
+  [secrets.unused]
+  provider_type = 'file'
+  refresh_interval_secs = 5
+`
+
+func TestResolveSecrets(t *testing.T) {
+
+	os.Setenv("TRK_TEST_ORIGIN_TOKEN", "Bearer abc123")
+	os.Setenv("TRK_TEST_CLIENT_KEY", "PRIVATE KEY DATA")
+	os.Setenv("TRK_TEST_REDIS", `{"password":"r3dis"}`)
+	os.Setenv("TRK_TEST_ADMIN_TOKEN", "adm1n")
+	defer func() {
+		os.Unsetenv("TRK_TEST_ADMIN_TOKEN")
+		os.Unsetenv("TRK_TEST_ORIGIN_TOKEN")
+		os.Unsetenv("TRK_TEST_CLIENT_KEY")
+		os.Unsetenv("TRK_TEST_REDIS")
+	}()
+
+	td, err := ioutil.TempDir("", "trickster-secrets-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+	secretsTempDir = func() string { return td }
+	secretsDir = ""
+	defer func() {
+		secretsTempDir = os.TempDir
+		secretsDir = ""
+	}()
+
+	c := NewConfig()
+	err = c.loadTOMLConfig(testSecretsTOML, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oc := c.Origins["test"]
+	if v := oc.HealthCheckHeaders["Authorization"]; v != "Bearer abc123" {
+		t.Errorf("expected %s got %s", "Bearer abc123", v)
+	}
+	if v := oc.HealthCheckHeaders["X-Plain"]; v != "plain" {
+		t.Errorf("expected %s got %s", "plain", v)
+	}
+	if v := c.Metrics.OTLP.Headers["X-Api-Key"]; v != "Bearer abc123" {
+		t.Errorf("expected %s got %s", "Bearer abc123", v)
+	}
+	if v := c.Caches["default"].Redis.Password; v != "r3dis" {
+		t.Errorf("expected %s got %s", "r3dis", v)
+	}
+	if v := c.AdminConfig.AuthToken; v != "adm1n" {
+		t.Errorf("expected %s got %s", "adm1n", v)
+	}
+
+	if !strings.HasPrefix(oc.TLS.ClientKeyPath, td) {
+		t.Errorf("expected client key to be materialized under %s, got %s", td, oc.TLS.ClientKeyPath)
+	}
+	b, err := ioutil.ReadFile(oc.TLS.ClientKeyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "PRIVATE KEY DATA" {
+		t.Errorf("unexpected client key file contents: %s", string(b))
+	}
+
+	// only providers that resolved a value factor into the refresh interval
+	if d := c.SecretsRefreshInterval(); d != 30*time.Second {
+		t.Errorf("expected %s got %s", 30*time.Second, d)
+	}
+
+	s := c.String()
+	if strings.Contains(s, "abc123") || strings.Contains(s, "r3dis") ||
+		strings.Contains(s, "adm1n") {
+		t.Error("expected secret values to be masked in config output")
+	}
+
+	changed, err := c.SecretsChanged()
+	if err != nil {
+		t.Error(err)
+	}
+	if changed {
+		t.Error("expected unchanged secrets")
+	}
+
+	os.Setenv("TRK_TEST_REDIS", `{"password":"rotated"}`)
+	changed, err = c.SecretsChanged()
+	if err != nil {
+		t.Error(err)
+	}
+	if !changed {
+		t.Error("expected changed secrets")
+	}
+
+	os.Unsetenv("TRK_TEST_REDIS")
+	_, err = c.SecretsChanged()
+	if err == nil {
+		t.Error("expected error for unresolvable secret")
+	}
+}
+
+func TestResolveSecretsErrors(t *testing.T) {
+
+	c := NewConfig()
+	err := c.loadTOMLConfig(`
+[origins]
+  [origins.test]
+  origin_type = 'reverseproxycache'
+  origin_url = 'http://1'
+  health_check_headers = { Authorization = 'secret://missing/TOKEN' }
+`, &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "unknown secrets provider") {
+		t.Errorf("expected unknown provider error, got %v", err)
+	}
+
+	c = NewConfig()
+	err = c.loadTOMLConfig(`
+[secrets]
+  [secrets.bad]
+  provider_type = 'invalid'
+`, &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid provider_type") {
+		t.Errorf("expected invalid provider_type error, got %v", err)
+	}
+
+	c = NewConfig()
+	err = c.loadTOMLConfig(`
+[secrets]
+  [secrets.vault]
+  provider_type = 'vault'
+`, &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "vault address is required") {
+		t.Errorf("expected missing address error, got %v", err)
+	}
+}
+
+func TestPrivateSecretsDir(t *testing.T) {
+
+	td, err := ioutil.TempDir("", "trickster-secrets-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+	secretsTempDir = func() string { return td }
+	secretsDir = ""
+	defer func() {
+		secretsTempDir = os.TempDir
+		secretsDir = ""
+	}()
+
+	dir, err := privateSecretsDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(dir) != td || !strings.HasPrefix(filepath.Base(dir), secretsDirPrefix) {
+		t.Errorf("unexpected secrets directory %s", dir)
+	}
+	// the directory is reused while it remains private
+	if dir2, err := privateSecretsDir(); err != nil || dir2 != dir {
+		t.Errorf("expected %s got %s (%v)", dir, dir2, err)
+	}
+	// and replaced when it has been removed
+	os.RemoveAll(dir)
+	if dir2, err := privateSecretsDir(); err != nil || dir2 == dir {
+		t.Errorf("expected a new secrets directory, got %s (%v)", dir2, err)
+	}
+
+	// a directory that has been swapped for a symlink is refused
+	secretsDir = filepath.Join(td, "link")
+	if err := os.Symlink(td, secretsDir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := privateSecretsDir(); err == nil || !strings.HasSuffix(err.Error(), "is a symlink") {
+		t.Errorf("expected symlink error, got %v", err)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	// as is one that other users can access
+	secretsDir = filepath.Join(td, "shared")
+	if err := os.Mkdir(secretsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.Chmod(secretsDir, 0755)
+	if _, err := privateSecretsDir(); err == nil ||
+		!strings.Contains(err.Error(), "is accessible by other users") {
+		t.Errorf("expected permissions error, got %v", err)
+	}
+}
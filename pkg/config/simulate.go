@@ -0,0 +1,44 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import "github.com/tricksterproxy/trickster/pkg/simulator"
+
+// addSimulatedOrigins adds the origins served by the simulator to the config, except
+// for any that the config already defines
+func (c *Config) addSimulatedOrigins() {
+	for k, o := range simulator.Origins(c.Main.SimulatorListenPort) {
+		if _, ok := c.Origins[k]; ok {
+			continue
+		}
+		o.Name = k
+		c.Origins[k] = o
+	}
+}
+
+// setSimulatedDefaultOrigin makes the simulated Prometheus origin the default origin, when
+// the config has no other origins
+func (c *Config) setSimulatedDefaultOrigin() {
+	for k := range c.Origins {
+		if k != simulator.PrometheusOriginName && k != simulator.ReverseProxyCacheOriginName {
+			return
+		}
+	}
+	if o, ok := c.Origins[simulator.PrometheusOriginName]; ok {
+		o.IsDefault = true
+	}
+}
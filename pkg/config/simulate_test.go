@@ -0,0 +1,69 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"testing"
+
+	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
+	"github.com/tricksterproxy/trickster/pkg/simulator"
+)
+
+func TestLoadSimulate(t *testing.T) {
+
+	conf, _, err := Load("trickster-test", "0", []string{"-simulate"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !conf.Main.Simulate {
+		t.Error("expected simulate to be enabled")
+	}
+	if len(conf.Origins) != 2 {
+		t.Errorf("expected 2 origins got %d", len(conf.Origins))
+	}
+	p, ok := conf.Origins[simulator.PrometheusOriginName]
+	if !ok {
+		t.Fatal("expected simulated prometheus origin")
+	}
+	if !p.IsDefault {
+		t.Error("expected simulated prometheus origin to be the default")
+	}
+	if p.Host != "127.0.0.1:8482" || p.PathPrefix != simulator.PrometheusPath {
+		t.Errorf("unexpected origin url %s", p.OriginURL)
+	}
+	if o := conf.Origins[simulator.ReverseProxyCacheOriginName]; o == nil ||
+		o.OriginType != "reverseproxycache" || o.IsDefault {
+		t.Error("expected simulated reverseproxycache origin")
+	}
+}
+
+func TestSimulatedDefaultOrigin(t *testing.T) {
+
+	c := NewConfig()
+	c.Main.Simulate = true
+	c.Main.SimulatorListenPort = d.DefaultSimulatorListenPort
+	c.Origins["test"] = c.Origins["default"]
+	delete(c.Origins, "default")
+	c.addSimulatedOrigins()
+	c.setSimulatedDefaultOrigin()
+	if len(c.Origins) != 3 {
+		t.Errorf("expected 3 origins got %d", len(c.Origins))
+	}
+	if c.Origins[simulator.PrometheusOriginName].IsDefault {
+		t.Error("expected simulated prometheus origin not to be the default")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"crypto/tls"
 
 	origins "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/tls/spiffe"
 )
 
 // TLSCertConfig returns the crypto/tls configuration object with a list of name-bound
@@ -29,6 +30,9 @@ func (c *Config) TLSCertConfig() (*tls.Config, error) {
 	if !c.Frontend.ServeTLS {
 		return nil, nil
 	}
+	if c.Spiffe.Enabled() && c.Spiffe.FrontendMTLS {
+		return spiffe.ServerTLSConfig(), nil
+	}
 	to := []*origins.Options{}
 	for _, oc := range c.Origins {
 		if oc.TLS.ServeTLS {
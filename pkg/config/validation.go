@@ -0,0 +1,232 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ValidationError describes a single problem found in a configuration
+type ValidationError struct {
+	// Path is the dot-separated key path of the offending config value (e.g., origins.prom1.cache_name)
+	Path string
+	// Message describes the problem
+	Message string
+	// Suggestion is an optional value that the user may have intended
+	Suggestion string
+}
+
+// ValidationErrors is a list of all problems found in a configuration
+type ValidationErrors []*ValidationError
+
+func (e *ValidationError) Error() string {
+	s := e.Message
+	if e.Path != "" {
+		s = e.Path + ": " + s
+	}
+	if e.Suggestion != "" {
+		s += fmt.Sprintf(" (did you mean '%s'?)", e.Suggestion)
+	}
+	return s
+}
+
+func (e ValidationErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	lines := make([]string, len(e))
+	for i, v := range e {
+		lines[i] = "  - " + v.Error()
+	}
+	return fmt.Sprintf("%d configuration errors:\n%s", len(e), strings.Join(lines, "\n"))
+}
+
+// Add appends a new ValidationError to the list
+func (e *ValidationErrors) Add(path, suggestion, format string, args ...interface{}) {
+	*e = append(*e, &ValidationError{Path: path, Message: fmt.Sprintf(format, args...),
+		Suggestion: suggestion})
+}
+
+// Append adds err to the list. If err is a ValidationErrors or *ValidationError, its members
+// are appended as-is; otherwise, err is appended as a ValidationError with the provided path
+func (e *ValidationErrors) Append(path string, err error) {
+	switch v := err.(type) {
+	case nil:
+		return
+	case ValidationErrors:
+		*e = append(*e, v...)
+	case *ValidationError:
+		*e = append(*e, v)
+	default:
+		*e = append(*e, &ValidationError{Path: path, Message: err.Error()})
+	}
+}
+
+// Err returns the list sorted by path, or nil if the list is empty
+func (e ValidationErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	sort.SliceStable(e, func(i, j int) bool { return e[i].Path < e[j].Path })
+	return e
+}
+
+// keyPath returns a dot-separated key path from the provided parts
+func keyPath(parts ...string) string {
+	return strings.Join(parts, ".")
+}
+
+// suggest returns the candidate that most closely resembles input, provided
+// it is similar enough to likely be what the user intended
+func suggest(input string, candidates []string) string {
+	best, bestDist := "", -1
+	for _, c := range candidates {
+		d := levenshtein(strings.ToLower(input), strings.ToLower(c))
+		if bestDist == -1 || d < bestDist || (d == bestDist && c < best) {
+			best, bestDist = c, d
+		}
+	}
+	max := len(input) / 3
+	if max < 2 {
+		max = 2
+	}
+	if bestDist == -1 || bestDist > max {
+		return ""
+	}
+	return best
+}
+
+// suggestKey returns the key of m that most closely resembles input
+func suggestKey(input string, m interface{}) string {
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Map {
+		return ""
+	}
+	keys := make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		if k.Kind() == reflect.String {
+			keys = append(keys, k.String())
+		}
+	}
+	return suggest(input, keys)
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// unknownKeys returns a ValidationError for each key in the TOML document that does
+// not map to a config field. Only the top-most unknown key of any unknown table is reported.
+func unknownKeys(md *toml.MetaData) ValidationErrors {
+	if md == nil {
+		return nil
+	}
+	undecoded := md.Undecoded()
+	seen := make(map[string]bool, len(undecoded))
+	var errs ValidationErrors
+	for _, k := range undecoded {
+		ks := k.String()
+		seen[ks] = true
+		if len(k) > 1 && seen[toml.Key(k[:len(k)-1]).String()] {
+			continue
+		}
+		name := k[len(k)-1]
+		errs.Add(ks, suggest(name, knownKeys(reflect.TypeOf(Config{}), k[:len(k)-1])),
+			"unknown config key '%s'", name)
+	}
+	return errs
+}
+
+// knownKeys returns the TOML key names available in the table at path, which
+// is navigated from type t by struct tag names, with map keys consuming one part
+func knownKeys(t reflect.Type, path toml.Key) []string {
+	for _, p := range path {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Struct:
+			f, ok := fieldByTag(t, p)
+			if !ok {
+				return nil
+			}
+			t = f.Type
+		default:
+			return nil
+		}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if n := tagName(t.Field(i)); n != "" {
+			keys = append(keys, n)
+		}
+	}
+	return keys
+}
+
+func fieldByTag(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if tagName(t.Field(i)) == name {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func tagName(f reflect.StructField) string {
+	n := strings.Split(f.Tag.Get("toml"), ",")[0]
+	if n == "-" || f.PkgPath != "" {
+		return ""
+	}
+	return n
+}
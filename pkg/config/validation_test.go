@@ -0,0 +1,161 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidationErrors(t *testing.T) {
+
+	var errs ValidationErrors
+	if errs.Err() != nil {
+		t.Error("expected nil error for empty list")
+	}
+
+	errs.Add("origins.test.cache_name", "default", "invalid cache name [%s]", "defalt")
+	const expected = "origins.test.cache_name: invalid cache name [defalt] (did you mean 'default'?)"
+	if errs.Err().Error() != expected {
+		t.Errorf("expected %s got %s", expected, errs.Err().Error())
+	}
+
+	errs.Append("main", errors.New("plain error"))
+	errs.Append("", nil)
+	errs.Append("", ValidationErrors{{Path: "caches.x", Message: "nested"}})
+	if len(errs) != 3 {
+		t.Errorf("expected %d got %d", 3, len(errs))
+	}
+
+	err := errs.Err()
+	if !strings.HasPrefix(err.Error(), "3 configuration errors:\n  - caches.x: nested\n  - main: plain error") {
+		t.Errorf("unexpected error output: %s", err.Error())
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	tests := []struct {
+		input      string
+		candidates []string
+		expected   string
+	}{
+		{"timeseries_ttl_sec", []string{"timeseries_ttl_secs", "max_ttl_secs"}, "timeseries_ttl_secs"},
+		{"defualt", []string{"default", "redis"}, "default"},
+		{"zzzzzzzz", []string{"default", "redis"}, ""},
+		{"x", nil, ""},
+	}
+	for i, test := range tests {
+		if s := suggest(test.input, test.candidates); s != test.expected {
+			t.Errorf("test %d: expected '%s' got '%s'", i, test.expected, s)
+		}
+	}
+}
+
+const testUnknownKeysTOML = `
+[main]
+strict_config = %s
+[origins]
+  [origins.test]
+  origin_type = 'reverseproxycache'
+  origin_url = 'http://1'
+  timeseries_ttl_sec = 30
+    [origins.test.bogus]
+    a = 1
+    b = 2
+[caches]
+  [caches.default]
+  cache_typ = 'memory'
+`
+
+func TestUnknownKeys(t *testing.T) {
+
+	c := NewConfig()
+	err := c.loadTOMLConfig(strings.Replace(testUnknownKeysTOML, "%s", "false", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.LoaderWarnings) != 3 {
+		t.Fatalf("expected %d warnings got %d: %v", 3, len(c.LoaderWarnings), c.LoaderWarnings)
+	}
+	expected := map[string]bool{
+		"origins.test.timeseries_ttl_sec: unknown config key 'timeseries_ttl_sec' (did you mean 'timeseries_ttl_secs'?)": true,
+		"origins.test.bogus: unknown config key 'bogus'":                                                                 true,
+		"caches.default.cache_typ: unknown config key 'cache_typ' (did you mean 'cache_type'?)":                          true,
+	}
+	for _, w := range c.LoaderWarnings {
+		if !expected[w] {
+			t.Errorf("unexpected warning: %s", w)
+		}
+	}
+
+	c = NewConfig()
+	err = c.loadTOMLConfig(strings.Replace(testUnknownKeysTOML, "%s", "true", 1), &Flags{})
+	if err == nil {
+		t.Fatal("expected error for unknown keys in strict mode")
+	}
+	if verrs, ok := err.(ValidationErrors); !ok || len(verrs) != 3 {
+		t.Errorf("expected 3 validation errors, got %v", err)
+	}
+
+	// unknown keys are always errors when validating a config
+	c = NewConfig()
+	err = c.loadTOMLConfig(strings.Replace(testUnknownKeysTOML, "%s", "false", 1),
+		&Flags{ValidateConfig: true})
+	if verrs, ok := err.(ValidationErrors); !ok || len(verrs) != 3 {
+		t.Errorf("expected 3 validation errors, got %v", err)
+	}
+}
+
+func TestValidationCollectsAllErrors(t *testing.T) {
+
+	c := NewConfig()
+	err := c.loadTOMLConfig(`
+[origins]
+  [origins.test1]
+  origin_type = 'reverseproxycache'
+  origin_url = 'http://1'
+  cache_name = 'defualt'
+  [origins.test2]
+  origin_type = 'rule'
+  rule_name = 'missing'
+    [origins.test2.paths]
+      [origins.test2.paths.root]
+      path = '/'
+      collapsed_forwarding = 'progresive'
+`, &Flags{})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	verrs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("expected ValidationErrors, got %T", err)
+	}
+	expected := []string{
+		"origins.test1.cache_name: invalid cache name [defualt] provided in origin config [test1] (did you mean 'default'?)",
+		"origins.test2.paths.root.collapsed_forwarding: invalid collapsed_forwarding name: progresive (did you mean 'progressive'?)",
+		"origins.test2.rule_name: invalid rule name [missing] provided in origin config [test2]",
+	}
+	if len(verrs) != len(expected) {
+		t.Fatalf("expected %d errors got %d: %s", len(expected), len(verrs), err.Error())
+	}
+	for i, e := range expected {
+		if verrs[i].Error() != e {
+			t.Errorf("expected `%s` got `%s`", e, verrs[i].Error())
+		}
+	}
+}
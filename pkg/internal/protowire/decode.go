@@ -0,0 +1,91 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package protowire
+
+import "encoding/binary"
+
+// Fataler is the part of testing.TB that the decoder reports malformed messages to
+type Fataler interface {
+	Fatalf(format string, args ...interface{})
+}
+
+// Field is a decoded field. Varint and fixed64 values are held in Varint, and
+// length-delimited values in Data
+type Field struct {
+	Num, WireType int
+	Varint        uint64
+	Data          []byte
+}
+
+// DecodeFields parses a single level of fields of the message, for verifying encoded
+// messages in tests. A malformed message fails the test
+func DecodeFields(t Fataler, b []byte) []Field {
+	var fields []Field
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			t.Fatalf("invalid tag")
+		}
+		b = b[n:]
+		f := Field{Num: int(tag >> 3), WireType: int(tag & 7)}
+		switch f.WireType {
+		case WireVarint:
+			f.Varint, n = binary.Uvarint(b)
+			if n <= 0 {
+				t.Fatalf("invalid varint in field %d", f.Num)
+			}
+			b = b[n:]
+		case WireFixed64:
+			if len(b) < 8 {
+				t.Fatalf("short fixed64 in field %d", f.Num)
+			}
+			f.Varint = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case WireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				t.Fatalf("invalid length in field %d", f.Num)
+			}
+			b = b[n:]
+			f.Data = b[:l]
+			b = b[l:]
+		default:
+			t.Fatalf("unexpected wire type %d", f.WireType)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// FieldByNum returns the first of the fields with the field number, or nil
+func FieldByNum(fields []Field, num int) *Field {
+	for i := range fields {
+		if fields[i].Num == num {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// DecodePacked decodes the values of a packed repeated fixed64 or double field
+func DecodePacked(b []byte) []uint64 {
+	out := make([]uint64, len(b)/8)
+	for i := range out {
+		out[i] = binary.LittleEndian.Uint64(b[i*8:])
+	}
+	return out
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package protowire provides a minimal Protocol Buffers wire format encoder, so that
+// OTLP messages can be exported without importing the generated OTLP packages, and a
+// decoder for verifying the encoded messages in tests
+package protowire
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// Protocol Buffers Wire Types
+const (
+	WireVarint  = 0
+	WireFixed64 = 1
+	WireBytes   = 2
+)
+
+// Buffer is an encoded Protocol Buffers message
+type Buffer []byte
+
+// Tag writes the key of a field
+func (b *Buffer) Tag(field, wireType int) {
+	b.Varint(uint64(field<<3 | wireType))
+}
+
+// Varint writes a varint value
+func (b *Buffer) Varint(v uint64) {
+	var scratch [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(scratch[:], v)
+	*b = append(*b, scratch[:n]...)
+}
+
+// Fixed64 writes a fixed64 value
+func (b *Buffer) Fixed64(v uint64) {
+	var scratch [8]byte
+	binary.LittleEndian.PutUint64(scratch[:], v)
+	*b = append(*b, scratch[:]...)
+}
+
+// BytesField writes a bytes field, unless it is empty
+func (b *Buffer) BytesField(field int, v []byte) {
+	if len(v) == 0 {
+		return
+	}
+	b.Tag(field, WireBytes)
+	b.Varint(uint64(len(v)))
+	*b = append(*b, v...)
+}
+
+// StringField writes a string field, unless it is empty
+func (b *Buffer) StringField(field int, v string) {
+	if v == "" {
+		return
+	}
+	b.StringValue(field, v)
+}
+
+// StringValue writes a string field, even when it is empty, for string fields that are
+// members of a oneof, where the presence of an empty value is meaningful
+func (b *Buffer) StringValue(field int, v string) {
+	b.Tag(field, WireBytes)
+	b.Varint(uint64(len(v)))
+	*b = append(*b, v...)
+}
+
+// VarintField writes a varint field, unless it is 0
+func (b *Buffer) VarintField(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.Tag(field, WireVarint)
+	b.Varint(v)
+}
+
+// Fixed64Field writes a fixed64 field, unless it is 0
+func (b *Buffer) Fixed64Field(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.Tag(field, WireFixed64)
+	b.Fixed64(v)
+}
+
+// DoubleField always writes the value, since the OTLP double fields are either
+// members of a oneof or optional, where the presence of a zero value is meaningful
+func (b *Buffer) DoubleField(field int, v float64) {
+	b.Tag(field, WireFixed64)
+	b.Fixed64(math.Float64bits(v))
+}
+
+// PackedFixed64Field writes a packed repeated fixed64 or double field
+func (b *Buffer) PackedFixed64Field(field int, v []uint64) {
+	if len(v) == 0 {
+		return
+	}
+	b.Tag(field, WireBytes)
+	b.Varint(uint64(len(v) * 8))
+	for _, n := range v {
+		b.Fixed64(n)
+	}
+}
+
+// MessageField writes an embedded message; unlike BytesField, an
+// empty message is still written, since its presence is meaningful
+func (b *Buffer) MessageField(field int, m Buffer) {
+	b.Tag(field, WireBytes)
+	b.Varint(uint64(len(m)))
+	*b = append(*b, m...)
+}
+
+// UnixNano returns the time as an OTLP fixed64 timestamp, which is 0 for times
+// before the epoch
+func UnixNano(t time.Time) uint64 {
+	n := t.UnixNano()
+	if n < 0 {
+		return 0
+	}
+	return uint64(n)
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package protowire
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBuffer(t *testing.T) {
+
+	var m Buffer
+	m.StringField(1, "inner")
+
+	var b Buffer
+	b.StringField(1, "test")
+	b.StringField(2, "")
+	b.StringValue(3, "")
+	b.BytesField(4, []byte{1, 2})
+	b.BytesField(5, nil)
+	b.VarintField(6, 300)
+	b.VarintField(7, 0)
+	b.Fixed64Field(8, UnixNano(time.Unix(1, 0)))
+	b.Fixed64Field(9, UnixNano(time.Unix(-1, 0)))
+	b.DoubleField(10, 0)
+	b.PackedFixed64Field(11, []uint64{1, math.Float64bits(2.5)})
+	b.PackedFixed64Field(12, nil)
+	b.MessageField(13, m)
+	b.MessageField(14, nil)
+
+	fields := DecodeFields(t, b)
+	if len(fields) != 9 {
+		t.Fatalf("expected %d fields got %d", 9, len(fields))
+	}
+
+	// empty and zero values are omitted, other than string values, doubles and messages
+	for _, num := range []int{2, 5, 7, 9, 12} {
+		if FieldByNum(fields, num) != nil {
+			t.Errorf("expected field %d to be omitted", num)
+		}
+	}
+	if f := FieldByNum(fields, 1); f.WireType != WireBytes || string(f.Data) != "test" {
+		t.Errorf("unexpected string field %+v", f)
+	}
+	if f := FieldByNum(fields, 3); f == nil || len(f.Data) != 0 {
+		t.Errorf("expected empty string value got %+v", f)
+	}
+	if f := FieldByNum(fields, 4); len(f.Data) != 2 || f.Data[1] != 2 {
+		t.Errorf("unexpected bytes field %+v", f)
+	}
+	if f := FieldByNum(fields, 6); f.WireType != WireVarint || f.Varint != 300 {
+		t.Errorf("unexpected varint field %+v", f)
+	}
+	if f := FieldByNum(fields, 8); f.WireType != WireFixed64 || f.Varint != uint64(1e9) {
+		t.Errorf("unexpected fixed64 field %+v", f)
+	}
+	if f := FieldByNum(fields, 10); f == nil || math.Float64frombits(f.Varint) != 0 {
+		t.Errorf("expected zero double got %+v", f)
+	}
+	if v := DecodePacked(FieldByNum(fields, 11).Data); len(v) != 2 ||
+		math.Float64frombits(v[1]) != 2.5 {
+		t.Errorf("unexpected packed values %v", v)
+	}
+	inner := DecodeFields(t, FieldByNum(fields, 13).Data)
+	if f := FieldByNum(inner, 1); f == nil || string(f.Data) != "inner" {
+		t.Errorf("unexpected message field %+v", inner)
+	}
+	if f := FieldByNum(fields, 14); f == nil || len(f.Data) != 0 {
+		t.Errorf("expected empty message got %+v", f)
+	}
+}
+
+type testFataler struct {
+	failed bool
+}
+
+func (f *testFataler) Fatalf(format string, args ...interface{}) {
+	f.failed = true
+	panic(format)
+}
+
+func TestDecodeFieldsMalformed(t *testing.T) {
+	for _, b := range [][]byte{{0x80}, {0x0a, 0x05, 0x01}, {0x09, 0x01}, {0x0b}} {
+		f := &testFataler{}
+		func() {
+			defer func() { recover() }()
+			DecodeFields(f, b)
+		}()
+		if !f.failed {
+			t.Errorf("expected malformed message %v to fail", b)
+		}
+	}
+}
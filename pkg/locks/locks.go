@@ -16,28 +16,70 @@
 package locks
 
 import (
+	"context"
 	"fmt"
+	"hash/fnv"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
 )
 
 // NamedLocker provides a locker for handling Named Locks
 type NamedLocker interface {
 	Acquire(string) (NamedLock, error)
 	RAcquire(string) (NamedLock, error)
+	AcquireCtx(context.Context, string, time.Duration) (NamedLock, error)
+	RAcquireCtx(context.Context, string, time.Duration) (NamedLock, error)
+	TryAcquire(string) (NamedLock, bool, error)
+	TryRAcquire(string) (NamedLock, bool, error)
+}
+
+// AcquireError is returned when a named lock is not acquired because the context was
+// done, or the maximum wait elapsed, before the lock became available
+type AcquireError struct {
+	// Name is the name of the lock
+	Name string
+	// Err is the context's error, or context.DeadlineExceeded when the maximum wait elapsed
+	Err error
 }
 
+func (e *AcquireError) Error() string {
+	return fmt.Sprintf("unable to acquire lock %s: %s", e.Name, e.Err.Error())
+}
+
+// lockShardCount is the number of shards of the lock map of a namedLocker. Each shard has its own
+// mutex, so that lookups of different lock names rarely contend with each other
+const lockShardCount = 64
+
 type namedLocker struct {
+	shards [lockShardCount]*lockShard
+}
+
+// lockShard holds the named locks whose names hash to the shard
+type lockShard struct {
 	locks   map[string]*namedLock
 	mapLock *sync.Mutex
 }
 
 // NewNamedLocker returns a new Named Locker
 func NewNamedLocker() NamedLocker {
-	return &namedLocker{
-		locks:   make(map[string]*namedLock),
-		mapLock: &sync.Mutex{},
+	lk := &namedLocker{}
+	for i := range lk.shards {
+		lk.shards[i] = &lockShard{
+			locks:   make(map[string]*namedLock),
+			mapLock: &sync.Mutex{},
+		}
 	}
+	return lk
+}
+
+// shard returns the lock map shard of the lock name
+func (lk *namedLocker) shard(lockName string) *lockShard {
+	h := fnv.New32a()
+	h.Write([]byte(lockName))
+	return lk.shards[h.Sum32()%lockShardCount]
 }
 
 // NamedLock defines the interface for implementing Named Locks
@@ -47,13 +89,16 @@ type NamedLock interface {
 	Upgrade() (NamedLock, error)
 	WriteLockCounter() int
 	WriteLockMode() bool
+	Waiters() int
+	SetValue(interface{})
+	Value() (interface{}, int)
 }
 
-func newNamedLock(name string, locker *namedLocker) *namedLock {
+func newNamedLock(name string, shard *lockShard) *namedLock {
 	return &namedLock{
 		name:    name,
 		RWMutex: &sync.RWMutex{},
-		locker:  locker,
+		shard:   shard,
 	}
 }
 
@@ -63,7 +108,16 @@ type namedLock struct {
 	queueSize      int32
 	writeLockMode  int32
 	writeLockCount int
-	locker         *namedLocker
+	// writers is the number of callers holding or waiting on the write lock. It is
+	// incremented while holding the shard's mapLock, so that TryRAcquire can tell that
+	// the read lock is immediately available without sync.RWMutex.TryRLock
+	writers      int32
+	shard        *lockShard
+	value        interface{}
+	valueCounter int
+	holdLock     sync.Mutex
+	readers      int
+	holdTimer    *time.Timer
 }
 
 // Release releases the write lock on the subject Named Lock
@@ -73,15 +127,20 @@ func (nl *namedLock) Release() error {
 		return errInvalidLockName(nl.name)
 	}
 
+	nl.endHold(false)
 	atomic.StoreInt32(&nl.writeLockMode, 0)
+	metrics.LockQueueSize.Dec()
 	qs := atomic.AddInt32(&nl.queueSize, -1)
 	if qs == 0 {
-		nl.locker.mapLock.Lock()
-		delete(nl.locker.locks, nl.name)
-		nl.locker.mapLock.Unlock()
+		nl.shard.mapLock.Lock()
+		delete(nl.shard.locks, nl.name)
+		nl.shard.mapLock.Unlock()
 	}
 
 	nl.Unlock()
+	// the writer is only uncounted once the lock is unlocked, so that a TryRAcquire
+	// never waits on it
+	atomic.AddInt32(&nl.writers, -1)
 	return nil
 }
 
@@ -92,11 +151,13 @@ func (nl *namedLock) RRelease() error {
 		return errInvalidLockName(nl.name)
 	}
 
+	nl.endHold(true)
+	metrics.LockQueueSize.Dec()
 	qs := atomic.AddInt32(&nl.queueSize, -1)
 	if qs == 0 {
-		nl.locker.mapLock.Lock()
-		delete(nl.locker.locks, nl.name)
-		nl.locker.mapLock.Unlock()
+		nl.shard.mapLock.Lock()
+		delete(nl.shard.locks, nl.name)
+		nl.shard.mapLock.Unlock()
 	}
 
 	nl.RUnlock()
@@ -115,6 +176,29 @@ func (nl *namedLock) WriteLockMode() bool {
 	return atomic.LoadInt32(&nl.writeLockMode) == 1
 }
 
+// Waiters returns the number of callers, other than the caller, that hold or are waiting on
+// the namedLock. This function should only be called by a goroutine holding the lock
+func (nl *namedLock) Waiters() int {
+	return int(atomic.LoadInt32(&nl.queueSize)) - 1
+}
+
+// SetValue publishes a value through the namedLock to the callers waiting on it, such as the
+// result of work that the other callers would otherwise repeat once they acquire the lock.
+// The value is kept until the namedLock is no longer held or waited on by any caller.
+// This function should only be called by a goroutine actively holding a write lock
+func (nl *namedLock) SetValue(v interface{}) {
+	nl.value = v
+	nl.valueCounter = nl.writeLockCount
+}
+
+// Value returns the value most recently published through the namedLock, and the write lock
+// counter at the time it was published. By comparing the counter to the one observed before
+// waiting on the lock, the caller can tell if the value was published while it was waiting.
+// This function should only be called by a goroutine actively holding a write lock
+func (nl *namedLock) Value() (interface{}, int) {
+	return nl.value, nl.valueCounter
+}
+
 // Upgrade will upgrade the current read-lock to a write lock without losing the reference to the
 // underlying sync map, enabling goroutines, after receiving a write lock, to know how many other
 // goroutines acquired a write lock (naturally or upgraded) during the time this routine released
@@ -122,14 +206,21 @@ func (nl *namedLock) WriteLockMode() bool {
 // state checks are required (e.g., re-querying a cache that might have changed) before proceeding.
 func (nl *namedLock) Upgrade() (NamedLock, error) {
 
+	start := time.Now()
 	ch := make(chan bool, 1)
 	wg := &sync.WaitGroup{}
 	wg.Add(1)
 	go func() {
+		nl.shard.mapLock.Lock()
+		atomic.AddInt32(&nl.writers, 1)
+		nl.shard.mapLock.Unlock()
 		atomic.AddInt32(&nl.queueSize, 1)
+		metrics.LockQueueSize.Inc()
 		ch <- true
 		atomic.StoreInt32(&nl.writeLockMode, 1)
 		nl.Lock()
+		observeWait(modeUpgrade, start)
+		nl.startHold(modeUpgrade)
 		nl.writeLockCount++
 		wg.Done()
 	}()
@@ -151,17 +242,23 @@ func (lk *namedLocker) Acquire(lockName string) (NamedLock, error) {
 		return nil, errInvalidLockName(lockName)
 	}
 
-	lk.mapLock.Lock()
-	nl, ok := lk.locks[lockName]
+	start := time.Now()
+	ls := lk.shard(lockName)
+	ls.mapLock.Lock()
+	nl, ok := ls.locks[lockName]
 	if !ok {
-		nl = newNamedLock(lockName, lk)
-		lk.locks[lockName] = nl
+		nl = newNamedLock(lockName, ls)
+		ls.locks[lockName] = nl
 	}
 	atomic.AddInt32(&nl.queueSize, 1)
-	lk.mapLock.Unlock()
+	atomic.AddInt32(&nl.writers, 1)
+	ls.mapLock.Unlock()
+	metrics.LockQueueSize.Inc()
 	atomic.StoreInt32(&nl.writeLockMode, 1)
 
 	nl.Lock()
+	observeWait(modeWrite, start)
+	nl.startHold(modeWrite)
 
 	nl.writeLockCount++
 	return nl, nil
@@ -173,21 +270,129 @@ func (lk *namedLocker) RAcquire(lockName string) (NamedLock, error) {
 		return nil, errInvalidLockName(lockName)
 	}
 
-	lk.mapLock.Lock()
-	nl, ok := lk.locks[lockName]
+	start := time.Now()
+	ls := lk.shard(lockName)
+	ls.mapLock.Lock()
+	nl, ok := ls.locks[lockName]
 	if !ok {
-		nl = newNamedLock(lockName, lk)
-		lk.locks[lockName] = nl
+		nl = newNamedLock(lockName, ls)
+		ls.locks[lockName] = nl
 	}
 
 	atomic.AddInt32(&nl.queueSize, 1)
-	lk.mapLock.Unlock()
+	ls.mapLock.Unlock()
+	metrics.LockQueueSize.Inc()
 	atomic.StoreInt32(&nl.writeLockMode, 0)
 
 	nl.RLock()
+	observeWait(modeRead, start)
+	nl.startHold(modeRead)
 	return nl, nil
 }
 
+// AcquireCtx locks the named lock for writing, and blocks until the wlock is acquired, the
+// context is done, or maxWait elapses (when greater than 0), whichever is first
+func (lk *namedLocker) AcquireCtx(ctx context.Context, lockName string,
+	maxWait time.Duration) (NamedLock, error) {
+	return lk.acquireCtx(ctx, lockName, maxWait, lk.Acquire, NamedLock.Release)
+}
+
+// RAcquireCtx locks the named lock for reading, and blocks until the rlock is acquired, the
+// context is done, or maxWait elapses (when greater than 0), whichever is first
+func (lk *namedLocker) RAcquireCtx(ctx context.Context, lockName string,
+	maxWait time.Duration) (NamedLock, error) {
+	return lk.acquireCtx(ctx, lockName, maxWait, lk.RAcquire, NamedLock.RRelease)
+}
+
+// acquireCtx waits on the lock in a separate goroutine, so that the caller can give up on it.
+// Since a sync.RWMutex can't be abandoned while waiting, a lock that is acquired after the
+// caller gave up is released as soon as it is acquired
+func (lk *namedLocker) acquireCtx(ctx context.Context, lockName string, maxWait time.Duration,
+	acquire func(string) (NamedLock, error), release func(NamedLock) error) (NamedLock, error) {
+
+	if lockName == "" {
+		return nil, errInvalidLockName(lockName)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, &AcquireError{Name: lockName, Err: err}
+	}
+
+	ch := make(chan NamedLock, 1)
+	go func() {
+		nl, _ := acquire(lockName)
+		ch <- nl
+	}()
+
+	var timeout <-chan time.Time
+	if maxWait > 0 {
+		t := time.NewTimer(maxWait)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	var err error
+	select {
+	case nl := <-ch:
+		return nl, nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = context.DeadlineExceeded
+	}
+
+	go func() {
+		release(<-ch)
+	}()
+	return nil, &AcquireError{Name: lockName, Err: err}
+}
+
+// TryAcquire locks the named lock for writing only if it is immediately available. It returns
+// false, without waiting, when the lock is held or a writer is waiting on it
+func (lk *namedLocker) TryAcquire(lockName string) (NamedLock, bool, error) {
+	return lk.tryAcquire(lockName, true)
+}
+
+// TryRAcquire locks the named lock for reading only if it is immediately available. It returns
+// false, without waiting, when the lock is held for writing or a writer is waiting on it
+func (lk *namedLocker) TryRAcquire(lockName string) (NamedLock, bool, error) {
+	return lk.tryAcquire(lockName, false)
+}
+
+func (lk *namedLocker) tryAcquire(lockName string, write bool) (NamedLock, bool, error) {
+	if lockName == "" {
+		return nil, false, errInvalidLockName(lockName)
+	}
+
+	// every caller holding or waiting on a named lock is counted while holding the shard's
+	// mapLock. So while it is held here, a named lock that is not in the map is free, and the
+	// read lock of one without writers is shared only with readers
+	ls := lk.shard(lockName)
+	ls.mapLock.Lock()
+	defer ls.mapLock.Unlock()
+	nl, ok := ls.locks[lockName]
+	if ok && (write || atomic.LoadInt32(&nl.writers) > 0) {
+		return nil, false, nil
+	}
+	if !ok {
+		nl = newNamedLock(lockName, ls)
+		ls.locks[lockName] = nl
+	}
+	atomic.AddInt32(&nl.queueSize, 1)
+	metrics.LockQueueSize.Inc()
+	if write {
+		atomic.AddInt32(&nl.writers, 1)
+		atomic.StoreInt32(&nl.writeLockMode, 1)
+		nl.Lock()
+		nl.writeLockCount++
+		nl.startHold(modeWrite)
+	} else {
+		atomic.StoreInt32(&nl.writeLockMode, 0)
+		nl.RLock()
+		nl.startHold(modeRead)
+	}
+	return nl, true, nil
+}
+
 func errInvalidLockName(name string) error {
 	return fmt.Errorf("invalid lock name: %s", name)
 }
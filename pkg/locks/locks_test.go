@@ -14,7 +14,9 @@
 package locks
 
 import (
+	"context"
 	"math/rand"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -176,6 +178,30 @@ func TestWriteLockMode(t *testing.T) {
 
 }
 
+func TestWaiters(t *testing.T) {
+
+	locker := NewNamedLocker()
+	nl, _ := locker.Acquire("test")
+	if nl.Waiters() != 0 {
+		t.Errorf("expected %d got %d", 0, nl.Waiters())
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		nl2, _ := locker.Acquire("test")
+		nl2.Release()
+		close(acquired)
+	}()
+	for nl.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if nl.Waiters() != 1 {
+		t.Errorf("expected %d got %d", 1, nl.Waiters())
+	}
+	nl.Release()
+	<-acquired
+}
+
 func TestUpgrade(t *testing.T) {
 
 	locker := NewNamedLocker()
@@ -188,3 +214,207 @@ func TestUpgrade(t *testing.T) {
 		t.Errorf("expected 1 got %d", nl.WriteLockCounter())
 	}
 }
+
+func TestAcquireCtx(t *testing.T) {
+
+	lk := NewNamedLocker()
+
+	_, err := lk.AcquireCtx(context.Background(), "", 0)
+	if err == nil || err.Error() != "invalid lock name: " {
+		t.Errorf("expected invalid lock name error got %v", err)
+	}
+
+	nl, _ := lk.Acquire("test")
+
+	// the write lock is held, so both lock modes time out after the max wait
+	_, err = lk.AcquireCtx(context.Background(), "test", time.Millisecond*10)
+	if ae, ok := err.(*AcquireError); !ok || ae.Err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded error got %v", err)
+	}
+	_, err = lk.RAcquireCtx(context.Background(), "test", time.Millisecond*10)
+	if ae, ok := err.(*AcquireError); !ok || ae.Err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded error got %v", err)
+	}
+
+	// and stop waiting when the context is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		cancel()
+	}()
+	_, err = lk.AcquireCtx(ctx, "test", 0)
+	if ae, ok := err.(*AcquireError); !ok || ae.Err != context.Canceled {
+		t.Errorf("expected canceled error got %v", err)
+	}
+	expected := "unable to acquire lock test: context canceled"
+	if err.Error() != expected {
+		t.Errorf("expected %s got %s", expected, err.Error())
+	}
+
+	// an already-done context fails without waiting
+	_, err = lk.RAcquireCtx(ctx, "test", 0)
+	if ae, ok := err.(*AcquireError); !ok || ae.Err != context.Canceled {
+		t.Errorf("expected canceled error got %v", err)
+	}
+
+	// the abandoned acquisitions release the lock once they get it, so it can be acquired again
+	nl.Release()
+	nl, err = lk.AcquireCtx(context.Background(), "test", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nl.Release()
+
+	nl, err = lk.RAcquireCtx(context.Background(), "test", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nl2, err := lk.RAcquireCtx(context.Background(), "test", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nl.RRelease()
+	nl2.RRelease()
+}
+
+func TestTryAcquire(t *testing.T) {
+
+	lk := NewNamedLocker()
+
+	_, _, err := lk.TryAcquire("")
+	if err == nil || err.Error() != "invalid lock name: " {
+		t.Errorf("expected invalid lock name error got %v", err)
+	}
+
+	nl, ok, err := lk.TryAcquire("test")
+	if err != nil || !ok {
+		t.Fatalf("expected lock to be acquired, got %t %v", ok, err)
+	}
+
+	// the write lock is held, so neither lock mode is available
+	if _, ok, _ = lk.TryAcquire("test"); ok {
+		t.Error("expected write lock to not be acquired")
+	}
+	if _, ok, _ = lk.TryRAcquire("test"); ok {
+		t.Error("expected read lock to not be acquired")
+	}
+	nl.Release()
+
+	// read locks are shared, but exclude writers
+	nl, ok, _ = lk.TryRAcquire("test")
+	if !ok {
+		t.Fatal("expected read lock to be acquired")
+	}
+	nl2, ok, _ := lk.TryRAcquire("test")
+	if !ok {
+		t.Fatal("expected read lock to be acquired")
+	}
+	if _, ok, _ = lk.TryAcquire("test"); ok {
+		t.Error("expected write lock to not be acquired")
+	}
+	nl.RRelease()
+	nl2.RRelease()
+
+	// once all locks are released, the named lock is removed
+	if n := len(lk.(*namedLocker).shard("test").locks); n != 0 {
+		t.Errorf("expected %d got %d", 0, n)
+	}
+
+	// and blocking acquisitions work with try-acquired locks
+	nl, _, _ = lk.TryAcquire("test")
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		nl2, _ := lk.Acquire("test")
+		nl2.Release()
+		wg.Done()
+	}()
+	time.Sleep(time.Millisecond * 10)
+	nl.Release()
+	wg.Wait()
+}
+
+func TestLockShards(t *testing.T) {
+
+	lk := NewNamedLocker().(*namedLocker)
+
+	// the same name always maps to the same shard
+	if lk.shard(testKey) != lk.shard(testKey) {
+		t.Error("expected the same shard")
+	}
+
+	// and many names are spread across the shards
+	shards := make(map[*lockShard]bool)
+	locks := make([]NamedLock, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		nl, _ := lk.Acquire(testKey + strconv.Itoa(i))
+		locks = append(locks, nl)
+		shards[lk.shard(testKey+strconv.Itoa(i))] = true
+	}
+	if len(shards) != lockShardCount {
+		t.Errorf("expected %d got %d", lockShardCount, len(shards))
+	}
+
+	var n int
+	for _, ls := range lk.shards {
+		n += len(ls.locks)
+	}
+	if n != 1000 {
+		t.Errorf("expected %d got %d", 1000, n)
+	}
+
+	for _, nl := range locks {
+		nl.Release()
+	}
+	for i, ls := range lk.shards {
+		if len(ls.locks) != 0 {
+			t.Errorf("expected shard %d to be empty, got %d", i, len(ls.locks))
+		}
+	}
+}
+
+func BenchmarkAcquireParallel(b *testing.B) {
+	lk := NewNamedLocker()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			nl, _ := lk.Acquire(testKey + strconv.Itoa(i%10000))
+			nl.Release()
+			i++
+		}
+	})
+}
+
+func TestLockValue(t *testing.T) {
+
+	lk := NewNamedLocker()
+
+	nl, _ := lk.Acquire(testKey)
+	if v, vc := nl.Value(); v != nil || vc != 0 {
+		t.Errorf("expected no value, got %v %d", v, vc)
+	}
+
+	// a caller waiting on the lock receives the value set by the holder
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		nl3, _ := lk.Acquire(testKey)
+		v, vc := nl3.Value()
+		if v != "test" || vc != 1 {
+			t.Errorf("expected test 1 got %v %d", v, vc)
+		}
+		nl3.Release()
+	}()
+	time.Sleep(time.Millisecond * 10)
+	nl.SetValue("test")
+	nl.Release()
+	wg.Wait()
+
+	// the value is discarded along with the lock, once no caller holds or waits on it
+	nl, _ = lk.Acquire(testKey)
+	if v, _ := nl.Value(); v != nil {
+		t.Errorf("expected no value, got %v", v)
+	}
+	nl.Release()
+}
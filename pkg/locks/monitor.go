@@ -0,0 +1,101 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package locks
+
+import (
+	"sync"
+	"time"
+
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+)
+
+// lock modes, as labeled in the lock metrics
+const (
+	modeRead    = "read"
+	modeWrite   = "write"
+	modeUpgrade = "upgrade"
+)
+
+var longHold struct {
+	sync.RWMutex
+	threshold time.Duration
+	logger    *tl.Logger
+}
+
+// SetLongHoldThreshold sets the duration after which a named lock that is still held is counted
+// as a long hold, and logged as a warning that includes the lock name. A lock held for reading
+// is held from when its first reader acquires it until its last reader releases it. A threshold
+// of 0 disables long hold detection. Only locks acquired after the call use the new threshold
+func SetLongHoldThreshold(d time.Duration, logger *tl.Logger) {
+	longHold.Lock()
+	longHold.threshold = d
+	longHold.logger = logger
+	longHold.Unlock()
+}
+
+func longHoldThreshold() (time.Duration, *tl.Logger) {
+	longHold.RLock()
+	defer longHold.RUnlock()
+	return longHold.threshold, longHold.logger
+}
+
+// observeWait records the time since start as time spent waiting for a lock in the mode
+func observeWait(mode string, start time.Time) {
+	metrics.LockWaitDuration.WithLabelValues(mode).Observe(time.Since(start).Seconds())
+}
+
+// startHold begins timing a hold of the named lock in the mode, for long hold detection.
+// Overlapping read holds are timed as a single hold
+func (nl *namedLock) startHold(mode string) {
+	nl.holdLock.Lock()
+	defer nl.holdLock.Unlock()
+	if mode == modeRead {
+		nl.readers++
+		if nl.readers > 1 {
+			return
+		}
+	}
+	d, logger := longHoldThreshold()
+	if d <= 0 {
+		return
+	}
+	name := nl.name
+	nl.holdTimer = time.AfterFunc(d, func() {
+		metrics.LockLongHolds.WithLabelValues(mode).Inc()
+		if logger != nil {
+			logger.Warn("named lock held longer than threshold",
+				tl.Pairs{"lockName": name, "mode": mode, "threshold": d})
+		}
+	})
+}
+
+// endHold ends the timing of a hold of the named lock
+func (nl *namedLock) endHold(read bool) {
+	nl.holdLock.Lock()
+	defer nl.holdLock.Unlock()
+	if read {
+		nl.readers--
+		if nl.readers > 0 {
+			return
+		}
+	}
+	if nl.holdTimer != nil {
+		nl.holdTimer.Stop()
+		nl.holdTimer = nil
+	}
+}
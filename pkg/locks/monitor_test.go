@@ -0,0 +1,120 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package locks
+
+import (
+	"testing"
+	"time"
+
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func metricValue(t *testing.T, c prometheus.Metric) *dto.Metric {
+	m := &dto.Metric{}
+	if err := c.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestLockWaitMetrics(t *testing.T) {
+
+	lk := NewNamedLocker()
+
+	waits := metricValue(t, metrics.LockWaitDuration.WithLabelValues(modeWrite).(prometheus.Metric)).
+		GetHistogram().GetSampleCount()
+	queued := metricValue(t, metrics.LockQueueSize).GetGauge().GetValue()
+
+	nl, _ := lk.Acquire(testKey)
+	if v := metricValue(t, metrics.LockQueueSize).GetGauge().GetValue(); v != queued+1 {
+		t.Errorf("expected %f got %f", queued+1, v)
+	}
+	nl.Release()
+	if v := metricValue(t, metrics.LockQueueSize).GetGauge().GetValue(); v != queued {
+		t.Errorf("expected %f got %f", queued, v)
+	}
+
+	v := metricValue(t, metrics.LockWaitDuration.WithLabelValues(modeWrite).(prometheus.Metric)).
+		GetHistogram().GetSampleCount()
+	if v != waits+1 {
+		t.Errorf("expected %d got %d", waits+1, v)
+	}
+
+	// upgrades are recorded separately from write locks
+	waits = metricValue(t, metrics.LockWaitDuration.WithLabelValues(modeUpgrade).(prometheus.Metric)).
+		GetHistogram().GetSampleCount()
+	nl, _ = lk.RAcquire(testKey)
+	nl, _ = nl.Upgrade()
+	nl.Release()
+	v = metricValue(t, metrics.LockWaitDuration.WithLabelValues(modeUpgrade).(prometheus.Metric)).
+		GetHistogram().GetSampleCount()
+	if v != waits+1 {
+		t.Errorf("expected %d got %d", waits+1, v)
+	}
+	if v := metricValue(t, metrics.LockQueueSize).GetGauge().GetValue(); v != queued {
+		t.Errorf("expected %f got %f", queued, v)
+	}
+}
+
+func TestLongHolds(t *testing.T) {
+
+	SetLongHoldThreshold(time.Millisecond*10, tl.ConsoleLogger("error"))
+	defer SetLongHoldThreshold(0, nil)
+
+	lk := NewNamedLocker()
+
+	longHolds := func(mode string) float64 {
+		return metricValue(t, metrics.LockLongHolds.WithLabelValues(mode).(prometheus.Metric)).
+			GetCounter().GetValue()
+	}
+	writes, reads := longHolds(modeWrite), longHolds(modeRead)
+
+	// a lock released within the threshold is not a long hold
+	nl, _ := lk.Acquire(testKey)
+	nl.Release()
+
+	// but one held beyond it is, while it is still held
+	nl, _ = lk.Acquire(testKey)
+	time.Sleep(time.Millisecond * 50)
+	if v := longHolds(modeWrite); v != writes+1 {
+		t.Errorf("expected %f got %f", writes+1, v)
+	}
+	nl.Release()
+
+	// overlapping readers hold the lock until the last of them releases it
+	nl, _ = lk.RAcquire(testKey)
+	nl2, _ := lk.RAcquire(testKey)
+	time.Sleep(time.Millisecond * 50)
+	nl.RRelease()
+	nl2.RRelease()
+	if v := longHolds(modeRead); v != reads+1 {
+		t.Errorf("expected %f got %f", reads+1, v)
+	}
+
+	// no long holds are recorded once detection is disabled
+	SetLongHoldThreshold(0, nil)
+	nl, _ = lk.Acquire(testKey)
+	time.Sleep(time.Millisecond * 20)
+	nl.Release()
+	if v := longHolds(modeWrite); v != writes+1 {
+		t.Errorf("expected %f got %f", writes+1, v)
+	}
+}
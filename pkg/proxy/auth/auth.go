@@ -0,0 +1,156 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package auth provides an http.RoundTripper that attaches an origin's credentials to each
+// upstream request, replacing any Authorization presented by the client
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/auth/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+// expiryDelta is how long before its expiration an OAuth2 access token is refreshed
+const expiryDelta = 10 * time.Second
+
+// Transport is an http.RoundTripper that injects the configured credentials into requests
+type Transport struct {
+	next    http.RoundTripper
+	options *options.Options
+	client  *http.Client
+
+	mtx     sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewTransport returns a Transport that sends requests via next, after attaching the
+// credentials in o. OAuth2 token requests are also sent via next, and time out after timeout
+func NewTransport(next http.RoundTripper, o *options.Options, timeout time.Duration) *Transport {
+	return &Transport{
+		next:    next,
+		options: o,
+		client:  &http.Client{Transport: next, Timeout: timeout},
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+
+	// per the http.RoundTripper contract, the credentials are set on a copy of the request
+	r = r.Clone(r.Context())
+	for k, v := range t.options.Headers {
+		r.Header.Set(k, v)
+	}
+
+	switch {
+	case t.options.Token != "":
+		r.Header.Set(headers.NameAuthorization, "Bearer "+t.options.Token)
+	case t.options.Username != "":
+		r.SetBasicAuth(t.options.Username, t.options.Password)
+	case t.options.TokenURL != "":
+		token, err := t.accessToken(r.Context())
+		if err != nil {
+			return nil, err
+		}
+		r.Header.Set(headers.NameAuthorization, "Bearer "+token)
+		resp, err := t.next.RoundTrip(r)
+		// a rejected token is discarded so that the next request fetches a new one
+		if err == nil && resp.StatusCode == http.StatusUnauthorized {
+			t.expire(token)
+		}
+		return resp, err
+	}
+
+	return t.next.RoundTrip(r)
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// accessToken returns the cached OAuth2 access token, or requests a new one from the token
+// endpoint using the client credentials grant when it is missing or about to expire
+func (t *Transport) accessToken(ctx context.Context) (string, error) {
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.token != "" && (t.expires.IsZero() || time.Now().Before(t.expires)) {
+		return t.token, nil
+	}
+
+	v := url.Values{"grant_type": {"client_credentials"}}
+	if len(t.options.Scopes) > 0 {
+		v.Set("scope", strings.Join(t.options.Scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.options.TokenURL,
+		strings.NewReader(v.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(headers.NameContentType, headers.ValueXFormURLEncoded)
+	req.SetBasicAuth(url.QueryEscape(t.options.ClientID), url.QueryEscape(t.options.ClientSecret))
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oauth2 token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("oauth2 token request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oauth2 token request failed with status %d", resp.StatusCode)
+	}
+
+	tr := &tokenResponse{}
+	if err := json.Unmarshal(b, tr); err != nil {
+		return "", fmt.Errorf("invalid oauth2 token response: %w", err)
+	}
+	if tr.AccessToken == "" {
+		return "", fmt.Errorf("oauth2 token response has no access_token")
+	}
+
+	t.token = tr.AccessToken
+	t.expires = time.Time{}
+	if tr.ExpiresIn > 0 {
+		t.expires = time.Now().Add(time.Duration(tr.ExpiresIn)*time.Second - expiryDelta)
+	}
+	return t.token, nil
+}
+
+// expire discards the cached access token, if it is still the provided token
+func (t *Transport) expire(token string) {
+	t.mtx.Lock()
+	if t.token == token {
+		t.token = ""
+	}
+	t.mtx.Unlock()
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/auth/options"
+)
+
+func echoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer rejected" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+		w.Write([]byte(r.Header.Get("Authorization") + "|" + r.Header.Get("X-Api-Key")))
+	}))
+}
+
+func roundTrip(t *testing.T, tr http.RoundTripper, u string) (string, *http.Request) {
+	r, _ := http.NewRequest(http.MethodGet, u, nil)
+	r.Header.Set("Authorization", "Bearer from-client")
+	resp, err := tr.RoundTrip(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b := make([]byte, 256)
+	n, _ := resp.Body.Read(b)
+	return string(b[:n]), r
+}
+
+func TestRoundTrip(t *testing.T) {
+
+	ts := echoServer()
+	defer ts.Close()
+
+	tests := []struct {
+		o        *options.Options
+		expected string
+	}{
+		{
+			o:        &options.Options{Token: "abc"},
+			expected: "Bearer abc|",
+		},
+		{
+			o:        &options.Options{Username: "user", Password: "pass"},
+			expected: "Basic dXNlcjpwYXNz|",
+		},
+		{
+			o:        &options.Options{Headers: map[string]string{"X-Api-Key": "key"}},
+			expected: "Bearer from-client|key",
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			s, r := roundTrip(t, NewTransport(http.DefaultTransport, test.o, time.Second), ts.URL)
+			if s != test.expected {
+				t.Errorf("expected %s got %s", test.expected, s)
+			}
+			// the client's request is not modified
+			if r.Header.Get("Authorization") != "Bearer from-client" {
+				t.Errorf("expected %s got %s", "Bearer from-client", r.Header.Get("Authorization"))
+			}
+		})
+	}
+}
+
+func TestRoundTripOAuth2(t *testing.T) {
+
+	ts := echoServer()
+	defer ts.Close()
+
+	var count int32
+	var expiresIn = 3600
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		if id != "client" || secret != "secret" || r.FormValue("grant_type") != "client_credentials" ||
+			r.FormValue("scope") != "read write" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		n := atomic.AddInt32(&count, 1)
+		token := fmt.Sprintf("token%d", n)
+		if n == 3 {
+			token = "rejected"
+		}
+		fmt.Fprintf(w, `{"access_token":"%s","token_type":"bearer","expires_in":%d}`, token, expiresIn)
+	}))
+	defer tokens.Close()
+
+	o := &options.Options{TokenURL: tokens.URL, ClientID: "client", ClientSecret: "secret",
+		Scopes: []string{"read", "write"}}
+	tr := NewTransport(http.DefaultTransport, o, time.Second)
+
+	// the token is fetched once and reused until it expires
+	for i := 0; i < 2; i++ {
+		if s, _ := roundTrip(t, tr, ts.URL); s != "Bearer token1|" {
+			t.Errorf("expected %s got %s", "Bearer token1|", s)
+		}
+	}
+
+	// an expired token is refreshed
+	tr.expires = time.Now().Add(-time.Second)
+	if s, _ := roundTrip(t, tr, ts.URL); s != "Bearer token2|" {
+		t.Errorf("expected %s got %s", "Bearer token2|", s)
+	}
+
+	// a token rejected by the origin is discarded
+	tr.expire("token2")
+	if s, _ := roundTrip(t, tr, ts.URL); s != "Bearer rejected|" {
+		t.Errorf("expected %s got %s", "Bearer rejected|", s)
+	}
+	if s, _ := roundTrip(t, tr, ts.URL); s != "Bearer token4|" {
+		t.Errorf("expected %s got %s", "Bearer token4|", s)
+	}
+
+	// a token without an expiration is never refreshed
+	expiresIn = 0
+	tr.expire("token4")
+	roundTrip(t, tr, ts.URL)
+	if !tr.expires.IsZero() {
+		t.Errorf("expected zero expiration got %s", tr.expires)
+	}
+}
+
+func TestRoundTripOAuth2Errors(t *testing.T) {
+
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/status":
+			w.WriteHeader(http.StatusForbidden)
+		case "/empty":
+			w.Write([]byte(`{}`))
+		default:
+			w.Write([]byte(`not json`))
+		}
+	}))
+	defer tokens.Close()
+
+	for _, u := range []string{tokens.URL + "/status", tokens.URL + "/empty", tokens.URL,
+		"http://127.0.0.1:0/", "\x7f"} {
+		tr := NewTransport(http.DefaultTransport,
+			&options.Options{TokenURL: u, ClientID: "client"}, time.Second)
+		r, _ := http.NewRequest(http.MethodGet, "http://origin/", nil)
+		if _, err := tr.RoundTrip(r); err == nil {
+			t.Errorf("expected error for token url %s", u)
+		}
+	}
+}
+
+type errBody struct{}
+
+func (errBody) Read([]byte) (int, error) { return 0, errors.New("test error") }
+func (errBody) Close() error             { return nil }
+
+type errBodyTransport struct{}
+
+func (errBodyTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK, Body: errBody{}, Header: http.Header{}}, nil
+}
+
+func TestAccessTokenReadError(t *testing.T) {
+	tr := NewTransport(errBodyTransport{},
+		&options.Options{TokenURL: "http://tokens/", ClientID: "client"}, time.Second)
+	r, _ := http.NewRequest(http.MethodGet, "http://origin/", nil)
+	if _, err := tr.RoundTrip(r); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options provides the configuration of the credentials that Trickster attaches
+// to requests it makes to an origin
+package options
+
+import (
+	"errors"
+	"net/url"
+)
+
+// Options is a collection of credentials that are injected into upstream requests, so that
+// clients of Trickster do not need to hold the origin's credentials
+type Options struct {
+	// Token is a bearer token sent in the Authorization header of each upstream request
+	Token string `toml:"token"`
+	// Username is the username for HTTP Basic authentication against the origin
+	Username string `toml:"username"`
+	// Password is the password for HTTP Basic authentication against the origin
+	Password string `toml:"password"`
+	// Headers is a map of additional headers, such as API keys, set on each upstream request
+	Headers map[string]string `toml:"headers"`
+	// TokenURL is the endpoint from which an OAuth2 access token is requested using the
+	// client credentials grant. The token is refreshed shortly before it expires
+	TokenURL string `toml:"token_url"`
+	// ClientID is the OAuth2 client identifier
+	ClientID string `toml:"client_id"`
+	// ClientSecret is the OAuth2 client secret
+	ClientSecret string `toml:"client_secret"`
+	// Scopes is the list of OAuth2 scopes requested with the access token
+	Scopes []string `toml:"scopes"`
+}
+
+// NewOptions will return a *Options with the default settings
+func NewOptions() *Options {
+	return &Options{Headers: make(map[string]string)}
+}
+
+// Clone returns an exact copy of the subject *Options
+func (o *Options) Clone() *Options {
+
+	var headers map[string]string
+	if o.Headers != nil {
+		headers = make(map[string]string, len(o.Headers))
+		for k, v := range o.Headers {
+			headers[k] = v
+		}
+	}
+
+	var scopes []string
+	if o.Scopes != nil {
+		scopes = make([]string, len(o.Scopes))
+		copy(scopes, o.Scopes)
+	}
+
+	return &Options{
+		Token:        o.Token,
+		Username:     o.Username,
+		Password:     o.Password,
+		Headers:      headers,
+		TokenURL:     o.TokenURL,
+		ClientID:     o.ClientID,
+		ClientSecret: o.ClientSecret,
+		Scopes:       scopes,
+	}
+}
+
+// Validate returns an error if the options are incomplete or combine more than one
+// Authorization scheme
+func (o *Options) Validate() error {
+
+	var n int
+	for _, s := range []string{o.Token, o.Username, o.TokenURL} {
+		if s != "" {
+			n++
+		}
+	}
+	if n > 1 {
+		return errors.New("only one of token, username or token_url may be set")
+	}
+
+	if o.Password != "" && o.Username == "" {
+		return errors.New("password requires a username")
+	}
+
+	if o.TokenURL != "" {
+		u, err := url.Parse(o.TokenURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.New("invalid token_url: " + o.TokenURL)
+		}
+		if o.ClientID == "" {
+			return errors.New("token_url requires a client_id")
+		}
+	} else if o.ClientID != "" || o.ClientSecret != "" || len(o.Scopes) > 0 {
+		return errors.New("client_id, client_secret and scopes require a token_url")
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	o := &Options{Token: "a", Username: "b", Password: "c", Headers: map[string]string{"d": "e"},
+		TokenURL: "f", ClientID: "g", ClientSecret: "h", Scopes: []string{"i"}}
+	o2 := o.Clone()
+	if !reflect.DeepEqual(o, o2) {
+		t.Errorf("expected %v got %v", o, o2)
+	}
+	o2.Headers["d"] = "x"
+	if o.Headers["d"] != "e" {
+		t.Errorf("expected %s got %s", "e", o.Headers["d"])
+	}
+	if o3 := NewOptions().Clone(); len(o3.Headers) != 0 || o3.Scopes != nil {
+		t.Errorf("unexpected clone %v", o3)
+	}
+}
+
+func TestValidate(t *testing.T) {
+
+	tests := []struct {
+		o   *Options
+		err bool
+	}{
+		{o: NewOptions()},
+		{o: &Options{Token: "a"}},
+		{o: &Options{Username: "a", Password: "b"}},
+		{o: &Options{TokenURL: "https://tokens/", ClientID: "a", Scopes: []string{"b"}}},
+		{o: &Options{Token: "a", Username: "b"}, err: true},
+		{o: &Options{Password: "a"}, err: true},
+		{o: &Options{TokenURL: "tokens"}, err: true},
+		{o: &Options{TokenURL: "https://tokens/"}, err: true},
+		{o: &Options{ClientID: "a"}, err: true},
+	}
+
+	for i, test := range tests {
+		if err := test.o.Validate(); (err != nil) != test.err {
+			t.Errorf("test %d: unexpected error %v", i, err)
+		}
+	}
+}
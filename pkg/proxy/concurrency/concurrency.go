@@ -0,0 +1,220 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package concurrency provides an http.RoundTripper that limits the concurrent requests
+// to an origin to an adaptive limit, which is raised additively while the origin responds
+// promptly and lowered multiplicatively when its latency rises or it fails (AIMD), so
+// that an overloaded origin is protected without a hand-tuned static limit
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/concurrency/options"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+)
+
+// ErrLimitExceeded is returned for requests that are shed because the origin's
+// concurrency limit has been reached
+var ErrLimitExceeded = errors.New("origin concurrency limit exceeded")
+
+// baselineWindow is the number of responses over which the baseline latency is smoothed
+const baselineWindow = 100
+
+// minSamples is the number of responses that must be observed before their latency is
+// compared to the baseline
+const minSamples = 10
+
+// Limiter is an adaptive concurrency limit
+type Limiter struct {
+	opts       *options.Options
+	originName string
+
+	mtx          sync.Mutex
+	limit        float64
+	inflight     int
+	baseline     float64
+	samples      int
+	lastDecrease time.Time
+}
+
+// NewLimiter returns a Limiter for the origin, starting at the options' initial limit
+func NewLimiter(originName string, o *options.Options) *Limiter {
+	l := &Limiter{opts: o, originName: originName, limit: float64(o.InitialLimit)}
+	metrics.ProxyConcurrencyLimit.WithLabelValues(originName).Set(l.limit)
+	return l
+}
+
+// Limit returns the current concurrency limit
+func (l *Limiter) Limit() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return int(l.limit)
+}
+
+// Acquire returns true and counts a request as in flight if the limit has not been
+// reached. Each acquired request must be released with Release
+func (l *Limiter) Acquire() bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.inflight >= int(l.limit) {
+		return false
+	}
+	l.inflight++
+	return true
+}
+
+// Release ends an acquired request, and adjusts the limit by its latency and whether
+// it was dropped by the origin (i.e., failed or was rejected due to load)
+func (l *Limiter) Release(latency time.Duration, dropped bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	utilized := l.inflight*2 >= int(l.limit)
+	l.inflight--
+
+	now := time.Now()
+	if !dropped {
+		lat := float64(latency)
+		if l.samples == 0 {
+			l.baseline = lat
+		}
+		dropped = l.samples >= minSamples && lat > l.baseline*l.opts.LatencyTolerance
+		l.baseline += (lat - l.baseline) / baselineWindow
+		l.samples++
+	}
+
+	limit := l.limit
+	switch {
+	case dropped:
+		// the limit is lowered at most once per baseline latency, so that the responses
+		// to requests sent before it was lowered do not lower it again
+		if now.Sub(l.lastDecrease) < time.Duration(l.baseline) {
+			return
+		}
+		l.lastDecrease = now
+		limit = limit * l.opts.BackoffRatio
+		if limit < float64(l.opts.MinLimit) {
+			limit = float64(l.opts.MinLimit)
+		}
+	case utilized:
+		// the limit is only raised while it is being used, and is raised by about 1
+		// for each limit's worth of prompt responses
+		limit += 1 / limit
+		if limit > float64(l.opts.MaxLimit) {
+			limit = float64(l.opts.MaxLimit)
+		}
+	}
+	if int(limit) != int(l.limit) {
+		metrics.ProxyConcurrencyLimit.WithLabelValues(l.originName).Set(float64(int(limit)))
+	}
+	l.limit = limit
+}
+
+// Cancel ends an acquired request without adjusting the limit
+func (l *Limiter) Cancel() {
+	l.mtx.Lock()
+	l.inflight--
+	l.mtx.Unlock()
+}
+
+// Transport is an http.RoundTripper that sends requests via next within the Limiter.
+// Requests beyond the limit fail with ErrLimitExceeded
+type Transport struct {
+	next    http.RoundTripper
+	limiter *Limiter
+}
+
+// NewTransport returns a Transport that sends requests via next within the limiter
+func NewTransport(next http.RoundTripper, limiter *Limiter) *Transport {
+	return &Transport{next: next, limiter: limiter}
+}
+
+// RoundTrip implements http.RoundTripper. The request is held within the limit, and
+// its latency measured, until its response body has been read or closed
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if !t.limiter.Acquire() {
+		metrics.ProxyConcurrencyRejected.WithLabelValues(t.limiter.originName).Inc()
+		return nil, ErrLimitExceeded
+	}
+	start := time.Now()
+	resp, err := t.next.RoundTrip(r)
+	if err != nil {
+		t.release(r, start, true)
+		return resp, err
+	}
+	if resp.Body == nil || resp.Body == http.NoBody {
+		t.release(r, start, isDropped(resp.StatusCode))
+		return resp, err
+	}
+	resp.Body = &body{ReadCloser: resp.Body, t: t, r: r, start: start,
+		dropped: isDropped(resp.StatusCode)}
+	return resp, err
+}
+
+// release ends the acquired request r, sent at start
+func (t *Transport) release(r *http.Request, start time.Time, dropped bool) {
+	if dropped && r.Context().Err() == context.Canceled {
+		// the request was canceled by the client, which says nothing of the origin's load,
+		// unlike a request that timed out
+		t.limiter.Cancel()
+		return
+	}
+	t.limiter.Release(time.Since(start), dropped)
+}
+
+// body is a response body that releases its request from the limit once it has been
+// read to EOF, has failed, or is closed
+type body struct {
+	io.ReadCloser
+	t       *Transport
+	r       *http.Request
+	start   time.Time
+	dropped bool
+	once    sync.Once
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.done(false)
+	} else if err != nil {
+		b.done(true)
+	}
+	return n, err
+}
+
+func (b *body) Close() error {
+	err := b.ReadCloser.Close()
+	b.done(false)
+	return err
+}
+
+// done releases the request once, as dropped if the body failed to be read
+func (b *body) done(failed bool) {
+	b.once.Do(func() { b.t.release(b.r, b.start, b.dropped || failed) })
+}
+
+// isDropped returns true for response codes with which an overloaded origin sheds load
+func isDropped(code int) bool {
+	return code == http.StatusTooManyRequests || code == http.StatusServiceUnavailable ||
+		code == http.StatusGatewayTimeout
+}
@@ -0,0 +1,242 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/concurrency/options"
+)
+
+func testOptions() *options.Options {
+	o := options.NewOptions()
+	o.InitialLimit = 4
+	o.MinLimit = 2
+	o.MaxLimit = 5
+	return o
+}
+
+func TestAcquire(t *testing.T) {
+	l := NewLimiter("test", testOptions())
+	for i := 0; i < 4; i++ {
+		if !l.Acquire() {
+			t.Fatalf("(%d) expected request to be acquired", i)
+		}
+	}
+	if l.Acquire() {
+		t.Error("expected request beyond the limit to not be acquired")
+	}
+	l.Cancel()
+	if !l.Acquire() {
+		t.Error("expected request to be acquired")
+	}
+}
+
+func TestAdditiveIncrease(t *testing.T) {
+	l := NewLimiter("test", testOptions())
+
+	// the limit is not raised while it is not being used
+	l.Acquire()
+	l.Release(time.Millisecond, false)
+	if l.Limit() != 4 {
+		t.Errorf("expected %d got %d", 4, l.Limit())
+	}
+
+	// it is raised by 1 after about a limit's worth of prompt responses while in use
+	for i := 0; i < 5; i++ {
+		l.Acquire()
+		l.Acquire()
+		l.Release(time.Millisecond, false)
+		l.Cancel()
+	}
+	if l.Limit() != 5 {
+		t.Errorf("expected %d got %d", 5, l.Limit())
+	}
+
+	// and not beyond the max limit
+	for i := 0; i < 20; i++ {
+		l.Acquire()
+		l.Acquire()
+		l.Acquire()
+		l.Release(time.Millisecond, false)
+		l.Cancel()
+		l.Cancel()
+	}
+	if l.Limit() != 5 {
+		t.Errorf("expected %d got %d", 5, l.Limit())
+	}
+}
+
+func TestMultiplicativeDecrease(t *testing.T) {
+	l := NewLimiter("test", testOptions())
+
+	l.Acquire()
+	l.Release(time.Millisecond, true)
+	if l.Limit() != 3 {
+		t.Errorf("expected %d got %d", 3, l.Limit())
+	}
+
+	// drops within a baseline latency of the last decrease do not lower the limit again
+	l.baseline = float64(time.Hour)
+	l.Acquire()
+	l.Release(time.Millisecond, true)
+	if l.Limit() != 3 {
+		t.Errorf("expected %d got %d", 3, l.Limit())
+	}
+
+	// the limit is not lowered beyond the min limit
+	l.baseline = 0
+	for i := 0; i < 20; i++ {
+		l.Acquire()
+		l.Release(time.Millisecond, true)
+	}
+	if l.Limit() != 2 {
+		t.Errorf("expected %d got %d", 2, l.Limit())
+	}
+}
+
+func TestLatencyGradient(t *testing.T) {
+	l := NewLimiter("test", testOptions())
+
+	for i := 0; i < minSamples; i++ {
+		l.Acquire()
+		l.Release(10*time.Millisecond, false)
+	}
+	if l.Limit() != 4 {
+		t.Errorf("expected %d got %d", 4, l.Limit())
+	}
+
+	// a response much slower than the baseline lowers the limit
+	l.Acquire()
+	l.Release(100*time.Millisecond, false)
+	if l.Limit() != 3 {
+		t.Errorf("expected %d got %d", 3, l.Limit())
+	}
+}
+
+func TestTransport(t *testing.T) {
+
+	status := http.StatusOK
+	release := make(chan struct{})
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		w.WriteHeader(status)
+	}))
+	defer svr.Close()
+
+	o := testOptions()
+	o.InitialLimit = 2
+	l := NewLimiter("test", o)
+	client := &http.Client{Transport: NewTransport(http.DefaultTransport, l)}
+
+	// requests beyond the limit are shed
+	done := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		go func() {
+			resp, err := client.Get(svr.URL + "/slow")
+			if err == nil {
+				resp.Body.Close()
+			}
+			done <- struct{}{}
+		}()
+	}
+	for l.Acquire() {
+		l.Cancel()
+		time.Sleep(time.Millisecond)
+	}
+	_, err := client.Get(svr.URL + "/")
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("expected %v got %v", ErrLimitExceeded, err)
+	}
+	close(release)
+	<-done
+	<-done
+
+	// responses that shed load lower the limit
+	status = http.StatusServiceUnavailable
+	resp, err := client.Get(svr.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if l.Limit() != 2 {
+		t.Errorf("expected %d got %d", 2, l.Limit())
+	}
+
+	// canceled requests do not adjust the limit
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, svr.URL+"/", nil)
+	if _, err = client.Do(req); err == nil {
+		t.Error("expected error for canceled request")
+	}
+	if l.inflight != 0 {
+		t.Errorf("expected %d got %d", 0, l.inflight)
+	}
+}
+
+func TestTransportBody(t *testing.T) {
+
+	release := make(chan struct{})
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte("body"))
+	}))
+	defer svr.Close()
+
+	l := NewLimiter("test", testOptions())
+	client := &http.Client{Transport: NewTransport(http.DefaultTransport, l)}
+
+	resp, err := client.Get(svr.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the request is held within the limit until its body has been read
+	if l.inflight != 1 {
+		t.Errorf("expected %d got %d", 1, l.inflight)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	if _, err = ioutil.ReadAll(resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	if l.inflight != 0 {
+		t.Errorf("expected %d got %d", 0, l.inflight)
+	}
+	// and its latency includes the transfer of the body
+	if time.Duration(l.baseline) < 20*time.Millisecond {
+		t.Errorf("expected baseline of at least %s got %s", 20*time.Millisecond,
+			time.Duration(l.baseline))
+	}
+
+	// closing the body releases the request only once
+	resp.Body.Close()
+	if l.inflight != 0 {
+		t.Errorf("expected %d got %d", 0, l.inflight)
+	}
+}
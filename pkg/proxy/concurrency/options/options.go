@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options provides options for the adaptive concurrency limit of an origin
+package options
+
+import (
+	"errors"
+
+	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
+)
+
+// Options is a collection of configurations for an origin's adaptive concurrency limit,
+// which is raised additively while the origin responds promptly, and lowered
+// multiplicatively when its latency rises or it fails
+type Options struct {
+	// InitialLimit is the concurrency limit before any requests have been observed
+	InitialLimit int `toml:"initial_limit"`
+	// MinLimit is the lowest that the concurrency limit is lowered to
+	MinLimit int `toml:"min_limit"`
+	// MaxLimit is the highest that the concurrency limit is raised to
+	MaxLimit int `toml:"max_limit"`
+	// BackoffRatio is the factor by which the concurrency limit is lowered when the
+	// origin is overloaded
+	BackoffRatio float64 `toml:"backoff_ratio"`
+	// LatencyTolerance is the multiple of the origin's baseline latency beyond which a
+	// response indicates that the origin is overloaded
+	LatencyTolerance float64 `toml:"latency_tolerance"`
+}
+
+// NewOptions returns a new Options reference with Default Values set
+func NewOptions() *Options {
+	return &Options{
+		InitialLimit:     d.DefaultAdaptiveConcurrencyInitialLimit,
+		MinLimit:         d.DefaultAdaptiveConcurrencyMinLimit,
+		MaxLimit:         d.DefaultAdaptiveConcurrencyMaxLimit,
+		BackoffRatio:     d.DefaultAdaptiveConcurrencyBackoffRatio,
+		LatencyTolerance: d.DefaultAdaptiveConcurrencyLatencyTolerance,
+	}
+}
+
+// Clone returns an exact copy of the subject *Options
+func (o *Options) Clone() *Options {
+	return &Options{
+		InitialLimit:     o.InitialLimit,
+		MinLimit:         o.MinLimit,
+		MaxLimit:         o.MaxLimit,
+		BackoffRatio:     o.BackoffRatio,
+		LatencyTolerance: o.LatencyTolerance,
+	}
+}
+
+// SetDefaults sets the default value of each option that is not set. The default
+// initial limit is kept within the configured bounds
+func (o *Options) SetDefaults() {
+	if o.MinLimit == 0 {
+		o.MinLimit = d.DefaultAdaptiveConcurrencyMinLimit
+	}
+	if o.MaxLimit == 0 {
+		o.MaxLimit = d.DefaultAdaptiveConcurrencyMaxLimit
+	}
+	if o.InitialLimit == 0 {
+		o.InitialLimit = d.DefaultAdaptiveConcurrencyInitialLimit
+		if o.InitialLimit > o.MaxLimit {
+			o.InitialLimit = o.MaxLimit
+		}
+		if o.InitialLimit < o.MinLimit {
+			o.InitialLimit = o.MinLimit
+		}
+	}
+	if o.BackoffRatio == 0 {
+		o.BackoffRatio = d.DefaultAdaptiveConcurrencyBackoffRatio
+	}
+	if o.LatencyTolerance == 0 {
+		o.LatencyTolerance = d.DefaultAdaptiveConcurrencyLatencyTolerance
+	}
+}
+
+// Validate returns an error if the options are not valid
+func (o *Options) Validate() error {
+	if o.MinLimit < 1 {
+		return errors.New("min_limit must be at least 1")
+	}
+	if o.MaxLimit < o.MinLimit {
+		return errors.New("max_limit must be at least min_limit")
+	}
+	if o.InitialLimit < o.MinLimit || o.InitialLimit > o.MaxLimit {
+		return errors.New("initial_limit must be between min_limit and max_limit")
+	}
+	if o.BackoffRatio <= 0 || o.BackoffRatio >= 1 {
+		return errors.New("backoff_ratio must be between 0 and 1")
+	}
+	if o.LatencyTolerance <= 1 {
+		return errors.New("latency_tolerance must be greater than 1")
+	}
+	return nil
+}
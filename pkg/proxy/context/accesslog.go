@@ -0,0 +1,35 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package context
+
+import (
+	"context"
+)
+
+// WithAccessLogRecord returns a copy of the provided context that also includes the
+// request's access log record, so that handlers can add details to it
+func WithAccessLogRecord(ctx context.Context, r interface{}) context.Context {
+	if r != nil {
+		return context.WithValue(ctx, accessLogKey, r)
+	}
+	return ctx
+}
+
+// AccessLogRecord returns the interface reference to the Request's access log record
+func AccessLogRecord(ctx context.Context) interface{} {
+	return ctx.Value(accessLogKey)
+}
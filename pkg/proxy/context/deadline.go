@@ -0,0 +1,38 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package context
+
+import (
+	"context"
+	"time"
+)
+
+// WithOriginDeadline returns a copy of the provided context that also includes the time
+// by which Trickster will give up on the request to the origin
+func WithOriginDeadline(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, originDeadlineKey, t)
+}
+
+// OriginDeadline returns the time by which Trickster will give up on the request to the
+// origin, which is the earlier of the context's deadline and the origin deadline, if any
+func OriginDeadline(ctx context.Context) (time.Time, bool) {
+	d, ok := ctx.Deadline()
+	if t, ok2 := ctx.Value(originDeadlineKey).(time.Time); ok2 && (!ok || t.Before(d)) {
+		return t, true
+	}
+	return d, ok
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package context
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestOriginDeadline(t *testing.T) {
+
+	ctx := context.Background()
+	if _, ok := OriginDeadline(ctx); ok {
+		t.Error("expected no deadline")
+	}
+
+	now := time.Now()
+	ctx1 := WithOriginDeadline(ctx, now.Add(time.Minute))
+	if d, ok := OriginDeadline(ctx1); !ok || !d.Equal(now.Add(time.Minute)) {
+		t.Errorf("expected %v got %v", now.Add(time.Minute), d)
+	}
+
+	// the earlier of the context deadline and the origin deadline is used
+	ctx2, cancel := context.WithDeadline(ctx1, now.Add(time.Second))
+	defer cancel()
+	if d, ok := OriginDeadline(ctx2); !ok || !d.Equal(now.Add(time.Second)) {
+		t.Errorf("expected %v got %v", now.Add(time.Second), d)
+	}
+
+	ctx3, cancel2 := context.WithDeadline(ctx, now.Add(time.Hour))
+	defer cancel2()
+	ctx3 = WithOriginDeadline(ctx3, now.Add(time.Minute))
+	if d, ok := OriginDeadline(ctx3); !ok || !d.Equal(now.Add(time.Minute)) {
+		t.Errorf("expected %v got %v", now.Add(time.Minute), d)
+	}
+}
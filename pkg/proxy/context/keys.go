@@ -22,4 +22,10 @@ const (
 	resourcesKey contextKey = iota
 	hopsKey
 	healthCheckKey
+	accessLogKey
+	traceIDKey
+	originDeadlineKey
+	memoryReservationKey
+	inspectionKey
+	securityHeadersKey
 )
@@ -0,0 +1,36 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package context
+
+import (
+	"context"
+)
+
+// WithMemoryReservation returns a copy of the provided context that also includes the
+// reservation against which the bytes buffered from the request's upstream responses are
+// counted
+func WithMemoryReservation(ctx context.Context, r interface{}) context.Context {
+	if r != nil {
+		return context.WithValue(ctx, memoryReservationKey, r)
+	}
+	return ctx
+}
+
+// MemoryReservation returns the interface reference to the Request's memory reservation
+func MemoryReservation(ctx context.Context) interface{} {
+	return ctx.Value(memoryReservationKey)
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package context
+
+import (
+	"context"
+	"net/http"
+)
+
+// WithSecurityHeaders returns a copy of the provided context that also includes a
+// reference to the security headers that will be added to the response, so that the
+// handlers of a path can replace them with the path's own
+func WithSecurityHeaders(ctx context.Context, h *http.Header) context.Context {
+	if h != nil {
+		return context.WithValue(ctx, securityHeadersKey, h)
+	}
+	return ctx
+}
+
+// SecurityHeaders returns the reference to the security headers that will be added to
+// the Request's response, if any
+func SecurityHeaders(ctx context.Context) *http.Header {
+	if h, ok := ctx.Value(securityHeadersKey).(*http.Header); ok {
+		return h
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package context
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+
+	ctx := context.Background()
+
+	// cover nil short circuit case
+	ctx = WithSecurityHeaders(ctx, nil)
+	if SecurityHeaders(ctx) != nil {
+		t.Error("expected nil headers")
+	}
+
+	h := http.Header{"X-Frame-Options": []string{"DENY"}}
+	ctx = WithSecurityHeaders(ctx, &h)
+	*SecurityHeaders(ctx) = http.Header{}
+
+	if len(h) != 0 {
+		t.Errorf("expected %d got %d", 0, len(h))
+	}
+}
@@ -0,0 +1,39 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package context
+
+import (
+	"context"
+)
+
+// WithTraceIDRecorder returns a copy of the provided context that also includes a
+// reference to which the request's trace ID is written once its span is started, so
+// that handlers wrapping the tracer can attach the trace ID to their metrics
+func WithTraceIDRecorder(ctx context.Context, id *string) context.Context {
+	if id != nil {
+		return context.WithValue(ctx, traceIDKey, id)
+	}
+	return ctx
+}
+
+// TraceIDRecorder returns the reference to which the Request's trace ID is written, if any
+func TraceIDRecorder(ctx context.Context) *string {
+	if id, ok := ctx.Value(traceIDKey).(*string); ok {
+		return id
+	}
+	return nil
+}
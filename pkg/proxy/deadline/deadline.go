@@ -0,0 +1,104 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package deadline propagates the time remaining before Trickster gives up on an
+// origin request to the origin in a request header, so the origin can abandon work
+// that no one is waiting on anymore
+package deadline
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	tctx "github.com/tricksterproxy/trickster/pkg/proxy/context"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+// maxGRPCTimeoutDigits is the maximum number of digits in a grpc-timeout value
+const maxGRPCTimeoutDigits = 8
+
+var grpcTimeoutUnits = []struct {
+	unit time.Duration
+	name string
+}{
+	{time.Nanosecond, "n"},
+	{time.Microsecond, "u"},
+	{time.Millisecond, "m"},
+	{time.Second, "S"},
+	{time.Minute, "M"},
+	{time.Hour, "H"},
+}
+
+// Transport is an http.RoundTripper that sets the time remaining before the request's
+// deadline in a header of each request to the origin
+type Transport struct {
+	next   http.RoundTripper
+	header string
+}
+
+// NewTransport returns a Transport that sets the remaining time in the named header of
+// requests made with next
+func NewTransport(next http.RoundTripper, header string) *Transport {
+	return &Transport{next: next, header: header}
+}
+
+// RoundTrip implements http.RoundTripper. Requests without a deadline are sent as-is,
+// and requests whose deadline has already passed are not sent at all
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	d, ok := tctx.OriginDeadline(r.Context())
+	if !ok {
+		return t.next.RoundTrip(r)
+	}
+	remaining := time.Until(d)
+	if remaining <= 0 {
+		return nil, context.DeadlineExceeded
+	}
+	// per the http.RoundTripper contract, the header is set on a copy of the request
+	r = r.Clone(r.Context())
+	r.Header.Set(t.header, FormatTimeout(t.header, remaining))
+	return t.next.RoundTrip(r)
+}
+
+// FormatTimeout returns the remaining time as a value for the named header: in the
+// gRPC timeout format for the grpc-timeout header, and in milliseconds otherwise
+func FormatTimeout(header string, remaining time.Duration) string {
+	if strings.EqualFold(header, headers.NameGRPCTimeout) {
+		return formatGRPCTimeout(remaining)
+	}
+	ms := int64(remaining / time.Millisecond)
+	if ms < 1 {
+		ms = 1
+	}
+	return strconv.FormatInt(ms, 10)
+}
+
+// formatGRPCTimeout returns the duration in the finest unit whose value fits in the
+// 8 digits allowed by the gRPC over HTTP/2 protocol
+func formatGRPCTimeout(d time.Duration) string {
+	for _, u := range grpcTimeoutUnits {
+		v := int64(d / u.unit)
+		if len(strconv.FormatInt(v, 10)) <= maxGRPCTimeoutDigits {
+			if v < 1 {
+				v = 1
+			}
+			return strconv.FormatInt(v, 10) + u.name
+		}
+	}
+	return "99999999H"
+}
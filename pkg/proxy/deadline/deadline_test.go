@@ -0,0 +1,93 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deadline
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	tctx "github.com/tricksterproxy/trickster/pkg/proxy/context"
+)
+
+func TestFormatTimeout(t *testing.T) {
+	tests := []struct {
+		header   string
+		d        time.Duration
+		expected string
+	}{
+		{"X-Request-Timeout", 1500 * time.Millisecond, "1500"},
+		{"X-Request-Timeout", time.Microsecond, "1"},
+		{"grpc-timeout", 1500 * time.Millisecond, "1500000u"},
+		{"Grpc-Timeout", 50 * time.Millisecond, "50000000n"},
+		{"grpc-timeout", 300 * time.Second, "300000m"},
+		{"grpc-timeout", 48 * time.Hour, "172800S"},
+	}
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if v := FormatTimeout(test.header, test.d); v != test.expected {
+				t.Errorf("expected %s got %s", test.expected, v)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+
+	var v string
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v = r.Header.Get("X-Request-Timeout")
+	}))
+	defer svr.Close()
+
+	tr := NewTransport(http.DefaultTransport, "X-Request-Timeout")
+
+	// requests without a deadline are sent as-is
+	r, _ := http.NewRequest(http.MethodGet, svr.URL, nil)
+	r.Header.Set("X-Request-Timeout", "1")
+	resp, err := tr.RoundTrip(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if v != "1" {
+		t.Errorf("expected %s got %s", "1", v)
+	}
+
+	r = r.WithContext(tctx.WithOriginDeadline(r.Context(), time.Now().Add(10*time.Second)))
+	resp, err = tr.RoundTrip(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if ms, _ := strconv.Atoi(v); ms <= 9000 || ms > 10000 {
+		t.Errorf("expected about %d got %s", 10000, v)
+	}
+	if r.Header.Get("X-Request-Timeout") != "1" {
+		t.Error("expected the original request to be unmodified")
+	}
+
+	// requests past their deadline are not sent
+	r = r.WithContext(tctx.WithOriginDeadline(context.Background(), time.Now().Add(-time.Second)))
+	if _, err = tr.RoundTrip(r); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v got %v", context.DeadlineExceeded, err)
+	}
+}
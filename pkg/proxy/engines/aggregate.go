@@ -0,0 +1,62 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"time"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// aggregationStep returns the step that the response to the time range query must be
+// aggregated to, so that it has no more timestamps than the maximum number of data points
+// requested by the client, or configured for the origin. Since the aggregated buckets are
+// aligned to the epoch, at least 2 data points are always allowed. It returns 0 when the
+// response does not need to be aggregated
+func aggregationStep(oc *oo.Options, trq *timeseries.TimeRangeQuery) time.Duration {
+	max := trq.MaxDataPoints
+	if max <= 0 {
+		max = oc.MaxDataPoints
+	}
+	if max <= 0 || trq.Step <= 0 {
+		return 0
+	}
+	if max < 2 {
+		max = 2
+	}
+	points := int(trq.Extent.End.Sub(trq.Extent.Start)/trq.Step) + 1
+	if points <= max {
+		return 0
+	}
+	// n epoch-aligned buckets of this step always cover the (points-1) steps of the range
+	factor := (points + max - 3) / (max - 1)
+	return trq.Step * time.Duration(factor)
+}
+
+// aggregateResult thins the timeseries response to the provided step, using the origin's
+// data point aggregation, when the timeseries supports it
+func aggregateResult(pr *proxyRequest, oc *oo.Options, ts timeseries.Timeseries, step time.Duration) {
+	a, ok := ts.(timeseries.Aggregator)
+	if !ok {
+		return
+	}
+	a.Aggregate(step, oc.DataPointAggregation)
+	pr.Logger.Debug("aggregated timeseries result",
+		tl.Pairs{"step": step, "aggregation": oc.DataPointAggregation})
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+func TestAggregationStep(t *testing.T) {
+
+	step := time.Duration(60) * time.Second
+	start := time.Unix(3600, 0)
+
+	tests := []struct {
+		originMax, queryMax int
+		end                 time.Time
+		expected            time.Duration
+	}{
+		{0, 0, start.Add(time.Hour), 0},
+		{100, 0, start.Add(time.Hour), 0},
+		{10, 0, start.Add(time.Hour), step * 7},
+		{100, 10, start.Add(time.Hour), step * 7},
+		{0, 61, start.Add(time.Hour), 0},
+		{0, 1, start.Add(time.Hour), step * 60},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			oc := oo.NewOptions()
+			oc.MaxDataPoints = test.originMax
+			trq := &timeseries.TimeRangeQuery{Step: step, MaxDataPoints: test.queryMax,
+				Extent: timeseries.Extent{Start: start, End: test.end}}
+			s := aggregationStep(oc, trq)
+			if s != test.expected {
+				t.Errorf("expected %s got %s", test.expected, s)
+			}
+			if s > 0 {
+				max := test.queryMax
+				if max <= 0 {
+					max = test.originMax
+				}
+				if max < 2 {
+					max = 2
+				}
+				// the worst case count of epoch-aligned buckets spanned by the range
+				n := int((test.end.Sub(start)+s-1)/s) + 1
+				if n > max {
+					t.Errorf("expected no more than %d buckets got %d", max, n)
+				}
+			}
+		})
+	}
+}
+
+func TestDeltaProxyCacheRequestMaxDataPoints(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	oc.FastForwardDisable = true
+	oc.MaxDataPoints = 10
+
+	step := time.Duration(60) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(1) * time.Hour), End: end}
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	// the 61 data points of the range are aggregated down to no more than 10
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusOK)
+	if err != nil {
+		t.Error(err)
+	}
+
+	me, err := client.UnmarshalTimeseries(bodyBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me.ValueCount() == 0 || me.ValueCount() > 10 {
+		t.Errorf("expected between 1 and %d values got %d", 10, me.ValueCount())
+	}
+}
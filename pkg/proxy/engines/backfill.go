@@ -0,0 +1,61 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"time"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+// backfillTolerance returns the backfill tolerance for the time range query. In order of
+// precedence, it is the tolerance set in the query's comments, that of the first origin
+// rule matching the query statement, that of the path, or that of the origin
+func backfillTolerance(pc *po.Options, oc *oo.Options,
+	trq *timeseries.TimeRangeQuery) time.Duration {
+	bt := oc.BackfillTolerance
+	if r := oc.BackfillToleranceRuleList.Match(trq.Statement); r != nil {
+		bt = r.BackfillTolerance
+	} else if pc != nil && pc.HasBackfillTolerance {
+		bt = pc.BackfillTolerance
+	}
+	return trq.GetBackfillTolerance(bt)
+}
+
+// trimWrittenExtents returns a copy of the cached timeseries cropped to end before the
+// earliest sample that was recently written through the origin for a metric in the query,
+// so the written range is refetched rather than masked by stale cached data. The cached
+// timeseries is not modified, since the memory cache shares it with concurrent readers.
+// It returns false if nothing was written within the timeseries' extents
+func trimWrittenExtents(oc *oo.Options, trq *timeseries.TimeRangeQuery,
+	cts timeseries.Timeseries, now time.Time) (timeseries.Timeseries, bool) {
+	el := cts.Extents()
+	if len(el) == 0 {
+		return cts, false
+	}
+	span := timeseries.Extent{Start: el[0].Start, End: el[len(el)-1].End}
+	t, ok := oc.WriteWindows().Earliest(trq.Statement, span, now)
+	if !ok {
+		return cts, false
+	}
+	// the query points at and after the earliest written sample are refetched
+	cts = cts.Clone()
+	cts.CropToRange(timeseries.Extent{Start: span.Start, End: t.Add(-1).Truncate(trq.Step)})
+	return cts, true
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"testing"
+	"time"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	bf "github.com/tricksterproxy/trickster/pkg/timeseries/backfill/options"
+
+	mockprom "github.com/tricksterproxy/mockster/pkg/mocks/prometheus"
+)
+
+func TestBackfillTolerance(t *testing.T) {
+
+	oc := oo.NewOptions()
+	oc.BackfillTolerance = time.Minute
+	oc.BackfillToleranceRules = map[string]*bf.Options{
+		"counters": {Name: "counters", Pattern: `rate\(`, BackfillTolerance: 5 * time.Minute},
+	}
+	for _, r := range oc.BackfillToleranceRules {
+		if err := r.Compile(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oc.BackfillToleranceRuleList = bf.NewRules(oc.BackfillToleranceRules)
+
+	pc := po.NewOptions()
+	pc2 := po.NewOptions()
+	pc2.BackfillTolerance = 2 * time.Minute
+	pc2.HasBackfillTolerance = true
+
+	tests := []struct {
+		pc        *po.Options
+		statement string
+		expected  time.Duration
+	}{
+		{nil, "up", time.Minute},
+		{pc, "up", time.Minute},
+		{pc2, "up", 2 * time.Minute},
+		{pc2, "rate(requests_total[5m])", 5 * time.Minute},
+		{pc2, "rate(requests_total[5m]) # trickster-backfill-tolerance:30", 30 * time.Second},
+	}
+
+	for i, test := range tests {
+		trq := &timeseries.TimeRangeQuery{Statement: test.statement}
+		bt := backfillTolerance(test.pc, oc, trq)
+		if bt != test.expected {
+			t.Errorf("test %d: expected %s got %s", i, test.expected, bt)
+		}
+	}
+}
+
+func TestTrimWrittenExtents(t *testing.T) {
+
+	step := time.Minute
+	now := time.Unix(7200, 0)
+	e := timeseries.Extent{Start: time.Unix(0, 0), End: time.Unix(3600, 0)}
+	trq := &timeseries.TimeRangeQuery{Statement: "sum(some_query_here)", Step: step, Extent: e}
+
+	body, _, _ := mockprom.GetTimeSeriesData(queryReturnsOKNoLatency, e.Start, e.End, step)
+	cts, err := (&TestClient{}).UnmarshalTimeseries([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cts.SetExtents(timeseries.ExtentList{e})
+	cts.SetStep(step)
+
+	oc := oo.NewOptions()
+	if _, ok := trimWrittenExtents(oc, trq, cts, now); ok {
+		t.Error("expected no trim when remote write invalidation is disabled")
+	}
+
+	oc.RemoteWriteInvalidation = time.Minute
+	oc.WriteWindows().Add("other_metric",
+		timeseries.Extent{Start: time.Unix(600, 0), End: time.Unix(700, 0)}, now)
+	if _, ok := trimWrittenExtents(oc, trq, cts, now); ok {
+		t.Error("expected no trim for unrelated metric")
+	}
+
+	oc.WriteWindows().Add("some_query_here",
+		timeseries.Extent{Start: time.Unix(1830, 0), End: time.Unix(1900, 0)}, now)
+	tts, ok := trimWrittenExtents(oc, trq, cts, now)
+	if !ok {
+		t.Fatal("expected trim")
+	}
+	expected := timeseries.ExtentList{{Start: e.Start, End: time.Unix(1800, 0)}}
+	if tts.Extents().String() != expected.String() {
+		t.Errorf("expected %s got %s", expected, tts.Extents())
+	}
+	// the cached timeseries is not modified
+	if cts.Extents().String() != (timeseries.ExtentList{e}).String() {
+		t.Errorf("expected %s got %s", e, cts.Extents())
+	}
+}
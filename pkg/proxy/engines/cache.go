@@ -20,25 +20,64 @@ import (
 	"context"
 	"mime"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/cache"
+	co "github.com/tricksterproxy/trickster/pkg/cache/options"
 	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	"github.com/tricksterproxy/trickster/pkg/locks"
 	tc "github.com/tricksterproxy/trickster/pkg/proxy/context"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/ranges/byterange"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	tspan "github.com/tricksterproxy/trickster/pkg/tracing/span"
+	"github.com/tricksterproxy/trickster/pkg/util/compress"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/pool"
 
-	"github.com/golang/snappy"
 	"go.opentelemetry.io/otel/api/kv"
+	"go.opentelemetry.io/otel/api/trace"
+	"google.golang.org/grpc/codes"
 )
 
+// cacheCodecs maps the flag byte that prefixes serialized cache documents to the
+// compression codec used to compress them. A flag of 0 indicates no compression.
+// Documents are decoded by their flag rather than the configured codec, so
+// changing a cache's codec does not invalidate what it already holds
+var cacheCodecs = func() map[byte]string {
+	m := make(map[byte]string, len(co.CompressionFlags))
+	for name, flag := range co.CompressionFlags {
+		m[flag] = name
+	}
+	return m
+}()
+
+// bodyEncodedFlag is set in the flag byte of a serialized cache document when only its
+// body is compressed, using the codec of the remaining bits, so that the compressed body
+// can be passed through to clients that accept its content coding
+const bodyEncodedFlag byte = 0x80
+
+// contentCodings are the codecs whose encodings are also HTTP content codings. Bodies
+// compressed with them are stored apart from the rest of the document
+var contentCodings = map[string]bool{
+	compress.NameGzip:    true,
+	compress.NameDeflate: true,
+	compress.NameBrotli:  true,
+}
+
 // QueryCache queries the cache for an HTTPDocument and returns it
 func QueryCache(ctx context.Context, c cache.Cache, key string,
 	ranges byterange.Ranges) (*HTTPDocument, status.LookupStatus, byterange.Ranges, error) {
+	return queryCache(ctx, c, key, ranges, "")
+}
+
+// queryCache is QueryCache. A document body that is stored compressed with a content
+// coding accepted by acceptEncoding, the value of a client's Accept-Encoding header, is
+// not decompressed, and the document's bodyEncoding is set to the coding
+func queryCache(ctx context.Context, c cache.Cache, key string, ranges byterange.Ranges,
+	acceptEncoding string) (*HTTPDocument, status.LookupStatus, byterange.Ranges, error) {
 
 	rsc := tc.Resources(ctx).(*request.Resources)
 
@@ -46,16 +85,20 @@ func QueryCache(ctx context.Context, c cache.Cache, key string,
 	if span != nil {
 		defer span.End()
 	}
+	tspan.SetAttributes(rsc.Tracer, span, kv.String("cache.key", key))
 
 	d := &HTTPDocument{}
 	var lookupStatus status.LookupStatus
 	var bytes []byte
 	var err error
 
-	if c.Configuration().CacheType == "memory" {
+	var ifc interface{}
+	isMemory := c.Configuration().CacheType == "memory"
+	if isMemory {
 		mc := c.(cache.MemoryCache)
-		var ifc interface{}
+		_, rspan := tspan.NewChildSpan(ctx, rsc.Tracer, "CacheRetrieve")
 		ifc, lookupStatus, err = mc.RetrieveReference(key, true)
+		endCacheSpan(rspan, err, kv.String("cache.status", lookupStatus.String()))
 
 		if err != nil || (lookupStatus != status.LookupStatusHit) {
 			var nr byterange.Ranges
@@ -70,18 +113,23 @@ func QueryCache(ctx context.Context, c cache.Cache, key string,
 
 		if ifc != nil {
 			d, _ = ifc.(*HTTPDocument)
-		} else {
-			tspan.SetAttributes(rsc.Tracer, span, kv.String("cache.status", status.LookupStatusKeyMiss.String()))
-			return d, status.LookupStatusKeyMiss, ranges, err
 		}
+	}
 
-	} else {
+	// memory cache objects restored from a snapshot are serialized until they are rewritten
+	if !isMemory || ifc == nil {
 
+		_, rspan := tspan.NewChildSpan(ctx, rsc.Tracer, "CacheRetrieve")
 		bytes, lookupStatus, err = c.Retrieve(key, true)
+		if isMemory && err == nil && len(bytes) == 0 {
+			lookupStatus = status.LookupStatusKeyMiss
+		}
 		// normalize any cache miss errors to cache.ErrKNF.
 		if err != nil && err != cache.ErrKNF && strings.HasSuffix(err.Error(), "not in cache") {
 			err = cache.ErrKNF
 		}
+		endCacheSpan(rspan, err, kv.String("cache.status", lookupStatus.String()),
+			kv.Int("bytesRead", len(bytes)))
 
 		if err != nil || (lookupStatus != status.LookupStatusHit) {
 			var nr byterange.Ranges
@@ -93,28 +141,66 @@ func QueryCache(ctx context.Context, c cache.Cache, key string,
 			return d, lookupStatus, nr, err
 		}
 
-		var inflate bool
-		// check and remove compression bit
+		var codec, bodyCodec string
+		// check and remove compression flag
 		if len(bytes) > 0 {
-			if bytes[0] == 1 {
-				inflate = true
+			if bytes[0]&bodyEncodedFlag != 0 {
+				bodyCodec = cacheCodecs[bytes[0]&^bodyEncodedFlag]
+				if bodyCodec == "" {
+					err = compress.ErrUnknownCodec(strconv.Itoa(int(bytes[0])))
+				}
+			} else {
+				codec = cacheCodecs[bytes[0]]
 			}
 			bytes = bytes[1:]
 		}
 
-		if inflate {
-			rsc.Logger.Debug("decompressing cached data", tl.Pairs{"cacheKey": key})
-			b, err := snappy.Decode(nil, bytes)
+		// the decompressed document is only needed until it is unmarshaled,
+		// since unmarshaling copies its contents, so its buffer is pooled
+		var pooled []byte
+		if codec != "" {
+			rsc.Logger.Debug("decompressing cached data", tl.Pairs{"cacheKey": key, "codec": codec})
+			b, err := compress.AppendDecode(codec, pool.GetBytes(), bytes)
 			if err == nil {
+				pooled = b
+				if span != nil {
+					span.AddEvent(
+						ctx,
+						"Cache Decompress",
+						kv.Int("compressedBytes", len(bytes)),
+						kv.Int("bytes", len(b)),
+					)
+				}
 				bytes = b
 			}
 		}
-		_, err = d.UnmarshalMsg(bytes)
+		if err == nil {
+			_, err = d.UnmarshalMsg(bytes)
+		}
+		if pooled != nil {
+			pool.PutBytes(pooled)
+		}
+		if err == nil && bodyCodec != "" {
+			if acceptsEncoding(acceptEncoding, bodyCodec) {
+				d.bodyEncoding = bodyCodec
+			} else {
+				rsc.Logger.Debug("decompressing cached body",
+					tl.Pairs{"cacheKey": key, "codec": bodyCodec})
+				d.Body, err = compress.Decode(bodyCodec, d.Body)
+			}
+		}
 		if err != nil {
 			rsc.Logger.Error("error unmarshaling cache document", tl.Pairs{
 				"cacheKey": key,
 				"detail":   err.Error(),
 			})
+			if span != nil {
+				span.AddEvent(
+					ctx,
+					"Cache Unmarshal Failure",
+					kv.String("Error", err.Error()),
+				)
+			}
 			tspan.SetAttributes(rsc.Tracer, span, kv.String("cache.status", status.LookupStatusKeyMiss.String()))
 			return d, status.LookupStatusKeyMiss, ranges, err
 		}
@@ -125,9 +211,10 @@ func QueryCache(ctx context.Context, c cache.Cache, key string,
 
 	// Fulfillment is when we have a range stored, but a subsequent user wants the whole body, so
 	// we must inflate the requested range to be the entire object in order to get the correct delta.
-	d.isFulfillment = (d.Ranges != nil && len(d.Ranges) > 0) && (ranges == nil || len(ranges) == 0)
+	// It is not recorded in the document, which may be shared with other requests by a memory cache
+	isFulfillment := (d.Ranges != nil && len(d.Ranges) > 0) && (ranges == nil || len(ranges) == 0)
 
-	if d.isFulfillment {
+	if isFulfillment {
 		if span != nil {
 			span.AddEvent(
 				ctx,
@@ -152,6 +239,56 @@ func QueryCache(ctx context.Context, c cache.Cache, key string,
 	return d, lookupStatus, delta, nil
 }
 
+// endCacheSpan attaches the provided attributes to a cache operation span and ends it,
+// marking the span as failed when err is anything other than a cache miss
+func endCacheSpan(span trace.Span, err error, kvs ...kv.KeyValue) {
+	if span == nil {
+		return
+	}
+	if len(kvs) > 0 {
+		span.SetAttributes(kvs...)
+	}
+	if err != nil && err != cache.ErrKNF {
+		span.SetStatus(codes.Internal, err.Error())
+	}
+	span.End()
+}
+
+// acquireCacheReadLock acquires a read lock on the cache key, recording the time
+// spent waiting for it as an AcquireLock span. It stops waiting when the context is
+// done (e.g., the client disconnected), and returns a *locks.AcquireError
+func acquireCacheReadLock(ctx context.Context, locker locks.NamedLocker,
+	key string) (locks.NamedLock, error) {
+	rsc := tc.Resources(ctx).(*request.Resources)
+	_, span := tspan.NewChildSpan(ctx, rsc.Tracer, "AcquireLock")
+	nl, err := locker.RAcquireCtx(ctx, key, 0)
+	endCacheSpan(span, err, kv.String("lock.mode", "read"))
+	return nl, err
+}
+
+// upgradeCacheLock swaps a read lock for a write lock, recording the time spent waiting
+// for it as an UpgradeLock span. It returns true when this request was the first to
+// acquire the write lock since its read lock was granted. Otherwise, it also returns the
+// value shared through the lock by the request that held the write lock just before it, if any
+func upgradeCacheLock(ctx context.Context,
+	nl locks.NamedLock) (locks.NamedLock, bool, interface{}) {
+	rsc := tc.Resources(ctx).(*request.Resources)
+	_, span := tspan.NewChildSpan(ctx, rsc.Tracer, "UpgradeLock")
+	cwc := nl.WriteLockCounter()
+	nl, err := nl.Upgrade()
+	first := nl.WriteLockCounter()-cwc == 1
+	var shared interface{}
+	if !first {
+		// the value is only current if it was shared by the most recent holder of the write lock
+		if v, vc := nl.Value(); vc > cwc && vc == nl.WriteLockCounter()-1 {
+			shared = v
+		}
+	}
+	endCacheSpan(span, err, kv.String("lock.mode", "write"), kv.Bool("lock.contended", !first),
+		kv.Bool("lock.shared", shared != nil))
+	return nl, first, shared
+}
+
 func stripConditionalHeaders(h http.Header) {
 	h.Del(headers.NameIfMatch)
 	h.Del(headers.NameIfUnmodifiedSince)
@@ -169,6 +306,7 @@ func WriteCache(ctx context.Context, c cache.Cache, key string, d *HTTPDocument,
 	if span != nil {
 		defer span.End()
 	}
+	tspan.SetAttributes(rsc.Tracer, span, kv.String("cache.key", key))
 
 	d.headerLock.Lock()
 	h := http.Header(d.Headers)
@@ -181,25 +319,27 @@ func WriteCache(ctx context.Context, c cache.Cache, key string, d *HTTPDocument,
 
 	var bytes []byte
 	var err error
-	var compress bool
+	var shouldCompress bool
 
-	if (ce == "" || ce == "identity") &&
+	cc := c.Configuration()
+	flag, ok := co.CompressionFlags[cc.CompressionCodec]
+
+	if ok && (ce == "" || ce == "identity") &&
 		(d.CachingPolicy == nil || !d.CachingPolicy.NoTransform) {
 		if mt, _, err := mime.ParseMediaType(d.ContentType); err == nil {
 			if _, ok := compressTypes[mt]; ok {
-				compress = true
+				shouldCompress = true
 			}
 		}
 	}
 
 	// for memory cache, don't serialize the document, since we can retrieve it by reference.
-	if c.Configuration().CacheType == "memory" {
+	if cc.CacheType == "memory" {
 		mc := c.(cache.MemoryCache)
 
 		if d != nil {
 			// during unmarshal, these would come back as false, so lets set them as such even for direct access
 			d.rangePartsLoaded = false
-			d.isFulfillment = false
 			d.isLoaded = false
 			d.RangeParts = nil
 
@@ -208,26 +348,80 @@ func WriteCache(ctx context.Context, c cache.Cache, key string, d *HTTPDocument,
 			}
 		}
 
-		return mc.StoreReference(key, d, ttl)
+		_, sspan := tspan.NewChildSpan(ctx, rsc.Tracer, "CacheStore")
+		err = mc.StoreReference(key, d, ttl)
+		endCacheSpan(sspan, err)
+		return err
 	}
 
-	// for non-memory, we have to seralize the document to a byte slice to store
-	bytes, err = d.MarshalMsg(nil)
-	if err != nil {
-		rsc.Logger.Error("error marshaling cache document", tl.Pairs{
-			"cacheKey": key,
-			"detail":   err.Error(),
-		})
+	// a body compressed with a content coding is stored apart from the rest of the document,
+	// so that it can be passed through to clients that accept the coding. A body that was
+	// read from the cache still compressed is stored as it is
+	if d.bodyEncoding != "" || (shouldCompress && contentCodings[cc.CompressionCodec] &&
+		len(d.Ranges) == 0 && len(d.Body) >= cc.CompressionMinSizeBytes) {
+		rsc.Logger.Debug("compressing cache body",
+			tl.Pairs{"cacheKey": key, "codec": cc.CompressionCodec})
+		shouldCompress = false
+		bytes, err = marshalBodyEncoded(d, cc.CompressionCodec)
+		if err != nil {
+			rsc.Logger.Error("error marshaling cache document", tl.Pairs{
+				"cacheKey": key,
+				"detail":   err.Error(),
+			})
+			return err
+		}
+	} else {
+		// for non-memory, we have to seralize the document to a byte slice to store.
+		// the slice passed to the cache may be retained by it, so only the intermediate
+		// buffer used when compressing is pooled; otherwise the document is marshaled
+		// directly after the compression flag to avoid copying it
+		var mb []byte
+		if shouldCompress {
+			mb = pool.GetBytes()
+		} else {
+			mb = make([]byte, 1, d.Msgsize()+1)
+		}
+		bytes, err = d.MarshalMsg(mb)
+		if err != nil {
+			rsc.Logger.Error("error marshaling cache document", tl.Pairs{
+				"cacheKey": key,
+				"detail":   err.Error(),
+			})
+		}
 	}
 
-	if compress {
-		rsc.Logger.Debug("compressing cache data", tl.Pairs{"cacheKey": key})
-		bytes = append([]byte{1}, snappy.Encode(nil, bytes)...)
-	} else {
-		bytes = append([]byte{0}, bytes...)
+	if shouldCompress && len(bytes) < cc.CompressionMinSizeBytes {
+		// the document was marshaled without room for the flag byte
+		b := append(make([]byte, 1, len(bytes)+1), bytes...)
+		pool.PutBytes(bytes)
+		bytes = b
+		shouldCompress = false
+	}
+
+	if shouldCompress {
+		rsc.Logger.Debug("compressing cache data",
+			tl.Pairs{"cacheKey": key, "codec": cc.CompressionCodec})
+		l := len(bytes)
+		b, err := compress.AppendEncode(cc.CompressionCodec, []byte{flag}, bytes)
+		if err == nil {
+			pool.PutBytes(bytes)
+			bytes = b
+		} else {
+			bytes = append([]byte{0}, bytes...)
+		}
+		if span != nil {
+			span.AddEvent(
+				ctx,
+				"Cache Compress",
+				kv.Int("bytes", l),
+				kv.Int("compressedBytes", len(bytes)),
+			)
+		}
 	}
 
+	_, sspan := tspan.NewChildSpan(ctx, rsc.Tracer, "CacheStore")
 	err = c.Store(key, bytes, ttl)
+	endCacheSpan(sspan, err, kv.Int("bytesWritten", len(bytes)))
 	if err != nil {
 		if span != nil {
 			span.AddEvent(
@@ -249,6 +443,63 @@ func WriteCache(ctx context.Context, c cache.Cache, key string, d *HTTPDocument,
 
 }
 
+// marshalBodyEncoded serializes the document after a flag byte, with its body compressed
+// using the codec, unless the body is already compressed with its bodyEncoding
+func marshalBodyEncoded(d *HTTPDocument, codec string) ([]byte, error) {
+	body := d.Body
+	if d.bodyEncoding != "" {
+		codec = d.bodyEncoding
+	} else {
+		var err error
+		if body, err = compress.Encode(codec, d.Body); err != nil {
+			return nil, err
+		}
+	}
+	sd := &HTTPDocument{
+		StatusCode:    d.StatusCode,
+		Status:        d.Status,
+		Headers:       d.SafeHeaderClone(),
+		Body:          body,
+		ContentLength: d.ContentLength,
+		ContentType:   d.ContentType,
+		CachingPolicy: d.CachingPolicy,
+	}
+	b, err := sd.MarshalMsg(make([]byte, 1, sd.Msgsize()+1))
+	if err != nil {
+		return nil, err
+	}
+	b[0] = co.CompressionFlags[codec] | bodyEncodedFlag
+	return b, nil
+}
+
+// acceptsEncoding returns true if the value of an Accept-Encoding header accepts the
+// content coding, either by name or by a wildcard, with a non-zero quality value
+func acceptsEncoding(acceptEncoding, coding string) bool {
+	var wildcard bool
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params := part, ""
+		if i := strings.Index(part, ";"); i >= 0 {
+			name, params = part[:i], part[i+1:]
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		accepted := true
+		for _, p := range strings.Split(params, ";") {
+			p = strings.TrimSpace(p)
+			if len(p) > 2 && (p[0] == 'q' || p[0] == 'Q') && p[1] == '=' {
+				q, err := strconv.ParseFloat(p[2:], 64)
+				accepted = err == nil && q > 0
+			}
+		}
+		if name == coding {
+			return accepted
+		}
+		if name == "*" {
+			wildcard = accepted
+		}
+	}
+	return wildcard
+}
+
 // DocumentFromHTTPResponse returns an HTTPDocument from the provided HTTP Response and Body
 func DocumentFromHTTPResponse(resp *http.Response, body []byte, cp *CachingPolicy, log *tl.Logger) *HTTPDocument {
 	d := &HTTPDocument{}
@@ -17,6 +17,7 @@
 package engines
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"io/ioutil"
@@ -25,9 +26,12 @@ import (
 	"net/http/httptest"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/cache/memory"
 	co "github.com/tricksterproxy/trickster/pkg/cache/options"
 	"github.com/tricksterproxy/trickster/pkg/cache/registration"
 	cr "github.com/tricksterproxy/trickster/pkg/cache/registration"
@@ -38,6 +42,11 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/ranges/byterange"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/util/compress"
+
+	"go.opentelemetry.io/otel/api/kv"
+	"go.opentelemetry.io/otel/api/trace"
+	"google.golang.org/grpc/codes"
 )
 
 const testRangeBody = "This is a test file, to see how the byte range requests work.\n"
@@ -438,6 +447,225 @@ func TestQueryCache(t *testing.T) {
 
 }
 
+func newSerializingTestCache(tb testing.TB) (context.Context, cache.Cache, func()) {
+	conf, _, err := config.Load("trickster", "test", []string{"-origin-url", "http://1", "-origin-type", "test"})
+	if err != nil {
+		tb.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	caches := registration.LoadCachesFromConfig(conf, testLogger)
+	c := caches["default"]
+	// make the memory cache use the serialization path
+	c.Configuration().CacheType = "test"
+	ctx := tc.WithResources(context.Background(),
+		&request.Resources{OriginConfig: conf.Origins["default"], Logger: testLogger})
+	return ctx, c, func() { registration.CloseCaches(caches) }
+}
+
+func newSerializingTestDocument(size int) *HTTPDocument {
+	resp := &http.Response{StatusCode: 200, Header: make(http.Header)}
+	body := []byte(strings.Repeat(`{"metric":{"__name__":"up"},"values":[[1,"1"]]},`, size/48+1))[:size]
+	d := DocumentFromHTTPResponse(resp, body, nil, testLogger)
+	d.ContentType = headers.ValueApplicationJSON
+	return d
+}
+
+func TestQueryCachePooledBuffers(t *testing.T) {
+
+	ctx, c, closer := newSerializingTestCache(t)
+	defer closer()
+
+	ct := map[string]bool{headers.ValueApplicationJSON: true}
+	d1 := newSerializingTestDocument(65536)
+	d2 := newSerializingTestDocument(32768)
+	d2.Body[0] = '['
+
+	if err := WriteCache(ctx, c, "key1", d1, time.Minute, ct); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteCache(ctx, c, "key2", d2, time.Minute, ct); err != nil {
+		t.Fatal(err)
+	}
+
+	r1, _, _, err := QueryCache(ctx, c, "key1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// reading a second document must not overwrite the first, even if it
+	// was decompressed into the same pooled buffer
+	r2, _, _, err := QueryCache(ctx, c, "key2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r1.Body, d1.Body) {
+		t.Error("expected first document body to be unchanged")
+	}
+	if !bytes.Equal(r2.Body, d2.Body) {
+		t.Error("expected second document body to match")
+	}
+
+	// stored values are prefixed with the compression flag
+	b, _, _ := c.Retrieve("key1", false)
+	if len(b) == 0 || b[0] != co.CompressionFlags[c.Configuration().CompressionCodec] {
+		t.Errorf("expected compressed cache value")
+	}
+	if err = WriteCache(ctx, c, "key3", d1, time.Minute, nil); err != nil {
+		t.Fatal(err)
+	}
+	b, _, _ = c.Retrieve("key3", false)
+	if len(b) == 0 || b[0] != 0 {
+		t.Errorf("expected uncompressed cache value")
+	}
+	r1, _, _, err = QueryCache(ctx, c, "key3", nil)
+	if err != nil || !bytes.Equal(r1.Body, d1.Body) {
+		t.Error("expected uncompressed document body to match")
+	}
+}
+
+// newCompressionTestCache returns a memory cache with its own configuration, which uses the
+// serialization path, so that tests can change its compression options
+func newCompressionTestCache(t *testing.T) (context.Context, cache.Cache, func()) {
+	conf := co.NewOptions()
+	conf.CacheType = "test"
+	c := &memory.Cache{Name: "compression", Config: conf, Logger: testLogger}
+	c.SetLocker(locks.NewNamedLocker())
+	if err := c.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	ctx := tc.WithResources(context.Background(), &request.Resources{Logger: testLogger})
+	return ctx, c, func() { c.Close() }
+}
+
+func TestWriteCacheCompression(t *testing.T) {
+
+	ctx, c, closer := newCompressionTestCache(t)
+	defer closer()
+
+	ct := map[string]bool{headers.ValueApplicationJSON: true}
+	d := newSerializingTestDocument(65536)
+
+	tests := []struct {
+		codec    string
+		minSize  int
+		expected byte
+	}{
+		{compress.NameBrotli, 0, 4 | bodyEncodedFlag},
+		{compress.NameGzip, 0, 2 | bodyEncodedFlag},
+		{compress.NameDeflate, 0, 3 | bodyEncodedFlag},
+		{compress.NameSnappy, 0, 1},
+		{compress.NameBrotli, 1 << 20, 0},
+		{co.CompressionNone, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.codec, func(t *testing.T) {
+			c.Configuration().CompressionCodec = test.codec
+			c.Configuration().CompressionMinSizeBytes = test.minSize
+			if err := WriteCache(ctx, c, "key", d, time.Minute, ct); err != nil {
+				t.Fatal(err)
+			}
+			b, _, _ := c.Retrieve("key", false)
+			if len(b) == 0 || b[0] != test.expected {
+				t.Errorf("expected flag %d", test.expected)
+			}
+			// documents are decoded by their flag, not the configured codec
+			c.Configuration().CompressionCodec = compress.NameSnappy
+			r, _, _, err := QueryCache(ctx, c, "key", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(r.Body, d.Body) {
+				t.Error("expected document body to match")
+			}
+		})
+	}
+}
+
+func TestQueryCacheBodyEncoding(t *testing.T) {
+
+	ctx, c, closer := newCompressionTestCache(t)
+	defer closer()
+	c.Configuration().CompressionCodec = compress.NameGzip
+
+	ct := map[string]bool{headers.ValueApplicationJSON: true}
+	d := newSerializingTestDocument(65536)
+	if err := WriteCache(ctx, c, "key", d, time.Minute, ct); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		acceptEncoding string
+		expected       string
+	}{
+		{"", ""},
+		{"br", ""},
+		{"gzip", compress.NameGzip},
+		{"br, GZIP;q=0.5", compress.NameGzip},
+		{"gzip;q=0", ""},
+		{"*", compress.NameGzip},
+		{"*, gzip;q=0", ""},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			r, _, _, err := queryCache(ctx, c, "key", nil, test.acceptEncoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.bodyEncoding != test.expected {
+				t.Errorf("expected body encoding %s got %s", test.expected, r.bodyEncoding)
+			}
+			body := r.Body
+			if r.bodyEncoding != "" {
+				if body, err = compress.Decode(r.bodyEncoding, r.Body); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !bytes.Equal(body, d.Body) {
+				t.Error("expected document body to match")
+			}
+		})
+	}
+
+	// a document read with its body still encoded is stored again without re-encoding it,
+	// even once the configured codec has changed
+	r, _, _, err := queryCache(ctx, c, "key", nil, compress.NameGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Configuration().CompressionCodec = compress.NameBrotli
+	if err = WriteCache(ctx, c, "key", r, time.Minute, ct); err != nil {
+		t.Fatal(err)
+	}
+	b, _, _ := c.Retrieve("key", false)
+	if len(b) == 0 || b[0] != 2|bodyEncodedFlag {
+		t.Errorf("expected flag %d", 2|bodyEncodedFlag)
+	}
+	r, _, _, err = QueryCache(ctx, c, "key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(r.Body, d.Body) {
+		t.Error("expected document body to match")
+	}
+}
+
+func BenchmarkWriteQueryCache(b *testing.B) {
+	ctx, c, closer := newSerializingTestCache(b)
+	defer closer()
+	ct := map[string]bool{headers.ValueApplicationJSON: true}
+	d := newSerializingTestDocument(1 << 20)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := WriteCache(ctx, c, "key", d, time.Minute, ct); err != nil {
+			b.Fatal(err)
+		}
+		if _, _, _, err := QueryCache(ctx, c, "key", nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 // Mock Cache for testing error conditions
 type testCache struct {
 	configuration *co.Options
@@ -465,3 +693,115 @@ func (tc *testCache) Close() error                              { return errTest
 func (tc *testCache) Configuration() *co.Options                { return tc.configuration }
 func (tc *testCache) Locker() locks.NamedLocker                 { return tc.locker }
 func (tc *testCache) SetLocker(l locks.NamedLocker)             { tc.locker = l }
+
+type testCacheSpan struct {
+	trace.NoopSpan
+	attrs []kv.KeyValue
+	code  codes.Code
+	ended bool
+}
+
+func (s *testCacheSpan) SetAttributes(kvs ...kv.KeyValue)      { s.attrs = append(s.attrs, kvs...) }
+func (s *testCacheSpan) SetStatus(code codes.Code, msg string) { s.code = code }
+func (s *testCacheSpan) End(...trace.EndOption)                { s.ended = true }
+
+func TestEndCacheSpan(t *testing.T) {
+
+	// nil spans are safely ignored
+	endCacheSpan(nil, errors.New("test"))
+
+	s := &testCacheSpan{}
+	endCacheSpan(s, cache.ErrKNF, kv.Int("bytesRead", 0))
+	if !s.ended {
+		t.Error("expected span to be ended")
+	}
+	if s.code != codes.OK {
+		t.Errorf("expected %s got %s", codes.OK, s.code)
+	}
+	if len(s.attrs) != 1 {
+		t.Errorf("expected %d got %d", 1, len(s.attrs))
+	}
+
+	s = &testCacheSpan{}
+	endCacheSpan(s, errors.New("test"))
+	if s.code != codes.Internal {
+		t.Errorf("expected %s got %s", codes.Internal, s.code)
+	}
+}
+
+func TestUpgradeCacheLock(t *testing.T) {
+
+	r := httptest.NewRequest("GET", "http://127.0.0.1/", nil)
+	ctx := tc.WithResources(r.Context(), request.NewResources(nil, nil, nil, nil, nil, nil, nil))
+
+	locker := locks.NewNamedLocker()
+	nl, err := acquireCacheReadLock(ctx, locker, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nl, first, _ := upgradeCacheLock(ctx, nl)
+	if !first {
+		t.Error("expected first write lock")
+	}
+	nl.Release()
+}
+
+func TestAcquireCacheReadLockCanceled(t *testing.T) {
+
+	r := httptest.NewRequest("GET", "http://127.0.0.1/", nil)
+	ctx, cancel := context.WithCancel(r.Context())
+	ctx = tc.WithResources(ctx, request.NewResources(nil, nil, nil, nil, nil, nil, nil))
+
+	locker := locks.NewNamedLocker()
+	nl, _ := locker.Acquire("test")
+	defer nl.Release()
+
+	cancel()
+	_, err := acquireCacheReadLock(ctx, locker, "test")
+	if _, ok := err.(*locks.AcquireError); !ok {
+		t.Errorf("expected lock acquire error got %v", err)
+	}
+}
+
+func TestUpgradeCacheLockShared(t *testing.T) {
+
+	r := httptest.NewRequest("GET", "http://127.0.0.1/", nil)
+	ctx := tc.WithResources(r.Context(), request.NewResources(nil, nil, nil, nil, nil, nil, nil))
+
+	locker := locks.NewNamedLocker()
+
+	// upgrades two concurrent read locks, where the first to get the write lock shares a value
+	upgrade := func(share bool) interface{} {
+		nl1, _ := acquireCacheReadLock(ctx, locker, "test")
+		nl2, _ := acquireCacheReadLock(ctx, locker, "test")
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nl, first, _ := upgradeCacheLock(ctx, nl1)
+			if !first {
+				t.Error("expected first write lock")
+			}
+			if share {
+				nl.SetValue("shared")
+			}
+			nl.Release()
+		}()
+		// ensures the first upgrade is waiting on the write lock before the second
+		time.Sleep(time.Millisecond * 10)
+		nl, first, shared := upgradeCacheLock(ctx, nl2)
+		if first {
+			t.Error("expected contended write lock")
+		}
+		nl.Release()
+		wg.Wait()
+		return shared
+	}
+
+	if v := upgrade(true); v != "shared" {
+		t.Errorf("expected %s got %v", "shared", v)
+	}
+	if v := upgrade(false); v != nil {
+		t.Errorf("expected nil got %v", v)
+	}
+}
@@ -317,6 +317,36 @@ func (cp *CachingPolicy) parseCacheControlDirectives(directives string) {
 
 }
 
+// withoutPrivateDirective returns the headers with any private Cache-Control directive
+// removed. The headers are copied when the directive is present, so that it is still sent
+// to the client
+func withoutPrivateDirective(h http.Header) http.Header {
+	v := h.Get(headers.NameCacheControl)
+	if v == "" {
+		return h
+	}
+	dl := strings.Split(v, ",")
+	out := make([]string, 0, len(dl))
+	for _, d := range dl {
+		d = strings.TrimSpace(d)
+		ld := strings.ToLower(d)
+		if ld == headers.ValuePrivate || strings.HasPrefix(ld, headers.ValuePrivate+"=") {
+			continue
+		}
+		out = append(out, d)
+	}
+	if len(out) == len(dl) {
+		return h
+	}
+	h = h.Clone()
+	if len(out) == 0 {
+		h.Del(headers.NameCacheControl)
+	} else {
+		h.Set(headers.NameCacheControl, strings.Join(out, ", "))
+	}
+	return h
+}
+
 func hasPragmaNoCache(h http.Header) bool {
 	if v := h.Get(headers.NamePragma); v != "" {
 		return v == headers.ValueNoCache
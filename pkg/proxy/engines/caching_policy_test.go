@@ -314,3 +314,30 @@ func TestCheckIfNoneMatch(t *testing.T) {
 	}
 
 }
+
+func TestWithoutPrivateDirective(t *testing.T) {
+
+	tests := []struct {
+		value, expected string
+	}{
+		{"", ""},
+		{"max-age=60", "max-age=60"},
+		{"private", ""},
+		{"Private, max-age=60", "max-age=60"},
+		{`max-age=60, private="Set-Cookie", must-revalidate`, "max-age=60, must-revalidate"},
+	}
+
+	for i, test := range tests {
+		h := http.Header{}
+		if test.value != "" {
+			h.Set(headers.NameCacheControl, test.value)
+		}
+		h2 := withoutPrivateDirective(h)
+		if v := h2.Get(headers.NameCacheControl); v != test.expected {
+			t.Errorf("%d: expected %s got %s", i, test.expected, v)
+		}
+		if v := h.Get(headers.NameCacheControl); v != test.value {
+			t.Errorf("%d: expected original header %s got %s", i, test.value, v)
+		}
+	}
+}
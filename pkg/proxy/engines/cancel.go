@@ -0,0 +1,90 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/tricksterproxy/trickster/pkg/locks"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+)
+
+// fetchCanceler cancels the upstream work of a request whose client disconnects before it
+// is responded to, unless other requests are waiting on the cache lock under which the work
+// is being done, since they would use its result in place of fetching it themselves
+type fetchCanceler struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	mtx      sync.Mutex
+	lock     locks.NamedLock
+	stopped  bool
+	canceled bool
+	done     chan struct{}
+}
+
+// newFetchCanceler returns a fetchCanceler whose context, derived from the upstream
+// request's context, is canceled when the client request's context is done
+func newFetchCanceler(r, upstreamRequest *http.Request) *fetchCanceler {
+	fc := &fetchCanceler{done: make(chan struct{})}
+	fc.ctx, fc.cancel = context.WithCancel(upstreamRequest.Context())
+	go func() {
+		select {
+		case <-r.Context().Done():
+		case <-fc.done:
+			return
+		}
+		fc.mtx.Lock()
+		defer fc.mtx.Unlock()
+		if fc.stopped || (fc.lock != nil && fc.lock.Waiters() > 0) {
+			return
+		}
+		fc.canceled = true
+		fc.cancel()
+		if rsc := request.GetResources(r); rsc != nil && rsc.OriginConfig != nil {
+			metrics.ProxyCanceledFetches.WithLabelValues(rsc.OriginConfig.Name,
+				rsc.OriginConfig.OriginType, r.URL.Path).Inc()
+		}
+	}()
+	return fc
+}
+
+// share notes the write lock under which the upstream work is done, so that the work is not
+// canceled while other requests are waiting on the lock
+func (fc *fetchCanceler) share(nl locks.NamedLock) {
+	fc.mtx.Lock()
+	fc.lock = nl
+	fc.mtx.Unlock()
+}
+
+// isCanceled returns true if the upstream work was canceled
+func (fc *fetchCanceler) isCanceled() bool {
+	fc.mtx.Lock()
+	defer fc.mtx.Unlock()
+	return fc.canceled
+}
+
+// stop stops watching the client request once it has been responded to, which leaves the
+// context uncanceled for any work that continues in the background, like writing the cache
+func (fc *fetchCanceler) stop() {
+	fc.mtx.Lock()
+	fc.stopped = true
+	fc.mtx.Unlock()
+	close(fc.done)
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/locks"
+	tc "github.com/tricksterproxy/trickster/pkg/proxy/context"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+)
+
+func testCancelRequest() (context.CancelFunc, *proxyRequest) {
+	oc := oo.NewOptions()
+	oc.Name = "test"
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "http://127.0.0.1/", nil)
+	ctx, cancel := context.WithCancel(r.Context())
+	r = r.WithContext(tc.WithResources(ctx,
+		request.NewResources(oc, nil, nil, nil, nil, nil, testLogger)))
+	return cancel, newProxyRequest(r, w)
+}
+
+func TestFetchCanceler(t *testing.T) {
+
+	cancel, pr := testCancelRequest()
+	fc := newFetchCanceler(pr.Request, pr.upstreamRequest)
+	defer fc.stop()
+
+	if fc.isCanceled() {
+		t.Error("expected fetch to not be canceled")
+	}
+
+	cancel()
+	select {
+	case <-fc.ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected fetch context to be canceled")
+	}
+	if !fc.isCanceled() {
+		t.Error("expected fetch to be canceled")
+	}
+	if request.GetResources(pr.upstreamRequest.WithContext(fc.ctx)) == nil {
+		t.Error("expected fetch context to retain the request resources")
+	}
+}
+
+func TestFetchCancelerShared(t *testing.T) {
+
+	locker := locks.NewNamedLocker()
+	nl, _ := locker.Acquire("test")
+
+	acquired := make(chan struct{})
+	go func() {
+		nl2, _ := locker.Acquire("test")
+		nl2.Release()
+		close(acquired)
+	}()
+	for nl.Waiters() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel, pr := testCancelRequest()
+	fc := newFetchCanceler(pr.Request, pr.upstreamRequest)
+	fc.share(nl)
+
+	// another request is waiting on the lock, so the fetch is not canceled
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	if fc.isCanceled() || fc.ctx.Err() != nil {
+		t.Error("expected shared fetch to not be canceled")
+	}
+	fc.stop()
+	nl.Release()
+	<-acquired
+}
+
+func TestFetchCancelerStopped(t *testing.T) {
+
+	cancel, pr := testCancelRequest()
+	fc := newFetchCanceler(pr.Request, pr.upstreamRequest)
+	fc.stop()
+
+	// the client request's context is canceled once it is responded to
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	if fc.isCanceled() || fc.ctx.Err() != nil {
+		t.Error("expected stopped fetch to not be canceled")
+	}
+}
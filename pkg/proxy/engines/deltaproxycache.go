@@ -19,9 +19,11 @@ package engines
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
 	"sync"
 	"time"
 
@@ -33,11 +35,17 @@ import (
 	tpe "github.com/tricksterproxy/trickster/pkg/proxy/errors"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/push"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/tracing"
 	tspan "github.com/tricksterproxy/trickster/pkg/tracing/span"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/md5"
 	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+	"github.com/tricksterproxy/trickster/pkg/util/slowlog"
 
 	"go.opentelemetry.io/otel/api/kv"
 	"go.opentelemetry.io/otel/api/trace"
@@ -72,16 +80,62 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 		DoProxy(w, r, true)
 		return
 	}
+	rsc.TimeRangeQuery = trq
+
+	// queries exceeding the time range limits are clamped before anything is derived
+	// from their extent, so the upstream request and cache key reflect the clamped range
+	rangeWarning, err := limitTimeRange(pc, oc, trq, time.Now())
+	if err != nil {
+		rsc.Logger.Debug("rejected timeseries query exceeding time range limits",
+			tl.Pairs{"start": trq.Extent.Start, "end": trq.Extent.End, "detail": err.Error()})
+		h := http.Header{headers.NameContentType: []string{headers.ValueTextPlain}}
+		recordDPCResult(r, status.LookupStatusProxyOnly, http.StatusUnprocessableEntity,
+			r.URL.Path, "", 0, nil, h)
+		Respond(w, http.StatusUnprocessableEntity, h, []byte(err.Error()))
+		return
+	}
+	if rangeWarning != "" {
+		client.SetExtent(r, trq, &trq.Extent)
+	}
+
+	// while the memory budget is exceeded, the query is streamed from the origin uncached,
+	// within the time range limits. The result limits can only be applied to a buffered
+	// result, so queries to an origin with result limits are shed instead
+	if overMemoryBudget(r) {
+		if oc.MaxResultSeries > 0 || oc.MaxResultSamples > 0 {
+			h := http.Header{headers.NameContentType: []string{headers.ValueTextPlain},
+				headers.NameRetryAfter: []string{"1"}}
+			recordDPCResult(r, status.LookupStatusProxyOnly, http.StatusServiceUnavailable,
+				r.URL.Path, "", 0, nil, h)
+			Respond(w, http.StatusServiceUnavailable, h,
+				[]byte("memory budget exceeded for a query with result limits"))
+			return
+		}
+		if rangeWarning != "" {
+			w.Header().Add(headers.NameWarning, `199 trickster "`+rangeWarning+`"`)
+		}
+		DoProxy(w, r, true)
+		return
+	}
 
 	var cacheStatus status.LookupStatus
 
 	pr := newProxyRequest(r, w)
+	defer pr.memory.release()
+	// the upstream fetches and merge are abandoned if the client disconnects, unless they
+	// are shared with other requests waiting on the cache lock
+	fc := newFetchCanceler(r, pr.upstreamRequest)
+	defer fc.stop()
+	pr.fetchCtx = fc.ctx
+	pr.upstreamRequest = pr.upstreamRequest.WithContext(fc.ctx)
 	trq.FastForwardDisable = oc.FastForwardDisable || trq.FastForwardDisable
-	trq.NormalizeExtent()
+	applyMinStep(pr, trq)
+	selectDownsampleTier(pr, trq, time.Now())
+	trq.SnapExtent(oc.RangeSnap)
 
 	// this is used to ensure the head of the cache respects the BackFill Tolerance
 	bf := timeseries.Extent{Start: time.Unix(0, 0), End: trq.Extent.End}
-	bt := trq.GetBackfillTolerance(oc.BackfillTolerance)
+	bt := backfillTolerance(rsc.PathConfig, oc, trq)
 
 	if !trq.IsOffset && bt > 0 {
 		bf.End = bf.End.Add(-bt)
@@ -110,7 +164,17 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 
 	client.SetExtent(pr.upstreamRequest, trq, &trq.Extent)
 	key := oc.CacheKeyPrefix + ".dpc." + pr.DeriveCacheKey(trq.TemplateURL, "")
-	pr.cacheLock, _ = locker.RAcquire(key)
+	if tctx.Inspection(r.Context()) {
+		inspectTimeseries(w, pr, trq, key, bt, timeseriesShardWindow(oc, trq), bf.End, rangeWarning)
+		return
+	}
+	pr.cacheLock, err = acquireCacheReadLock(ctx, locker, key)
+	if err != nil {
+		// the request context is done, so there is no client left to respond to
+		pr.Logger.Debug("cache lock not acquired", tl.Pairs{"detail": err.Error()})
+		return
+	}
+	sw := timeseriesShardWindow(oc, trq)
 
 	// this is used to determine if Fast Forward should be activated for this request
 	normalizedNow := &timeseries.TimeRangeQuery{
@@ -122,6 +186,9 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 	var cts timeseries.Timeseries
 	var doc *HTTPDocument
 	var elapsed time.Duration
+	// cached is true while cts is the timeseries held by reference in a memory cache, which
+	// other requests may be streaming from, and so must be cloned before it is modified
+	var cached bool
 
 	coReq := GetRequestCachingPolicy(r.Header)
 	if coReq.NoCache {
@@ -132,8 +199,12 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 			)
 		}
 		cacheStatus = status.LookupStatusPurge
-		go cache.Remove(key)
-		cts, doc, elapsed, err = fetchTimeseries(pr, trq, client)
+		if sw > 0 {
+			go removeTimeseriesShards(cache, key, sw, trq.Step, trq.Extent)
+		} else {
+			go cache.Remove(key)
+		}
+		cts, doc, elapsed, err = fetchTimeseries(pr, trq, client, cacheStatus)
 		if err != nil {
 			pr.cacheLock.RRelease()
 			h := doc.SafeHeaderClone()
@@ -143,9 +214,13 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 			return // fetchTimeseries logs the error
 		}
 	} else {
-		doc, cacheStatus, _, err = QueryCache(ctx, cache, key, nil)
+		if sw > 0 {
+			cts, doc, cacheStatus, err = queryTimeseriesShards(ctx, pr, key, sw, trq)
+		} else {
+			doc, cacheStatus, _, err = QueryCache(ctx, cache, key, nil)
+		}
 		if cacheStatus == status.LookupStatusKeyMiss && err == tc.ErrKNF {
-			cts, doc, elapsed, err = fetchTimeseries(pr, trq, client)
+			cts, doc, elapsed, err = fetchTimeseries(pr, trq, client, cacheStatus)
 			if err != nil {
 				pr.cacheLock.RRelease()
 				h := doc.SafeHeaderClone()
@@ -155,21 +230,20 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 				return // fetchTimeseries logs the error
 			}
 		} else {
-			// Load the Cached Timeseries
-			if doc == nil {
-				err = tpe.ErrEmptyDocumentBody
-			} else {
-				if cc.CacheType == "memory" {
-					cts = doc.timeseries
+			// Load the Cached Timeseries. Sharded timeseries were loaded from their shards
+			if sw == 0 {
+				if doc == nil {
+					err = tpe.ErrEmptyDocumentBody
 				} else {
-					cts, err = client.UnmarshalTimeseries(doc.Body)
+					cts, err = cachedTimeseries(client, doc)
+					cached = err == nil && doc.timeseries != nil
 				}
 			}
 			if err != nil {
 				pr.Logger.Error("cache object unmarshaling failed",
 					tl.Pairs{"key": key, "originName": client.Name(), "detail": err.Error()})
 				go cache.Remove(key)
-				cts, doc, elapsed, err = fetchTimeseries(pr, trq, client)
+				cts, doc, elapsed, err = fetchTimeseries(pr, trq, client, status.LookupStatusKeyMiss)
 				if err != nil {
 					pr.cacheLock.RRelease()
 					h := doc.SafeHeaderClone()
@@ -213,6 +287,12 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 	// Find the ranges that we want, but which are not currently cached
 	var missRanges timeseries.ExtentList
 	if cacheStatus == status.LookupStatusPartialHit {
+		var trimmed bool
+		if cts, trimmed = trimWrittenExtents(oc, trq, cts, now); trimmed {
+			cached = false
+			pr.Logger.Debug("cached timeseries trimmed for remote write",
+				tl.Pairs{"key": key, "extents": cts.Extents().String()})
+		}
 		missRanges = trq.CalculateDeltas(cts.Extents())
 	}
 
@@ -227,6 +307,18 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	tspan.SetAttributes(rsc.Tracer, span, kv.String("cache.status", cacheStatus.String()))
+	if span != nil {
+		var cachedExtents int
+		if cts != nil {
+			cachedExtents = len(cts.Extents())
+		}
+		span.AddEvent(
+			ctx,
+			"Delta Calculated",
+			kv.Int("extents.cached", cachedExtents),
+			kv.Int("extents.missing", len(missRanges)),
+		)
+	}
 
 	var writeLock locks.NamedLock
 
@@ -239,12 +331,13 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 		// which will have the same cacheStatus, from causing the same or similar HTTP requests
 		// to be made against the origin, since just one should do.
 
-		// to do this we can ask the lock object how many write locks have been acquired. since
-		// we have a read lock, this number can't be updated again until all reads are released.
-		cwc := pr.cacheLock.WriteLockCounter()
-		// acquire a write lock via the Upgrade method, which will swap your read lock for a
+		// to do this, upgradeCacheLock asks the lock object how many write locks have been acquired.
+		// since we have a read lock, this number can't be updated again until all reads are released.
+		// it then acquires a write lock via the Upgrade method, which will swap your read lock for a
 		// write lock, ensuring that write lock counter state is intact during the upgrade
-		pr.cacheLock, _ = pr.cacheLock.Upgrade()
+		var first bool
+		var shared interface{}
+		pr.cacheLock, first, shared = upgradeCacheLock(ctx, pr.cacheLock)
 		// now we have the write lock. so we can check if the write lock counter incremented by 1
 		// or more. If the difference is just 1, that means this request was the first to acquire
 		// a write lock following all of the read locks being released. That means it is good to
@@ -255,13 +348,59 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 		// case the updated cache might benefit them.
 
 		// now check if we were the first request for this url to upgrade from a reader to writer
-		if pr.cacheLock.WriteLockCounter()-cwc != 1 {
-			// we weren't first, so quickly drop our write lock, and re-run the request
-			pr.cacheLock.Release()
-			DeltaProxyCacheRequest(w, r)
-			return
+		if !first {
+			sr, ok := shared.(*dpcResult)
+			if !ok {
+				// we weren't first, so quickly drop our write lock, and re-run the request
+				pr.cacheLock.Release()
+				DeltaProxyCacheRequest(w, r)
+				return
+			}
+			// the request that was first shared the timeseries it just cached, which is used in
+			// place of re-reading the cache, so that only the ranges it did not cover are fetched
+			cts, doc = sr.cts.Clone(), sr.doc
+			missRanges = trq.CalculateDeltas(cts.Extents())
+			pr.Logger.Debug("using timeseries shared by concurrent request",
+				tl.Pairs{"cacheKey": key, "missRanges": len(missRanges)})
+			if len(missRanges) == 0 {
+				elapsed = time.Since(now)
+				cacheStatus = status.LookupStatusHit
+				pr.cacheLock.Release()
+			} else {
+				cacheStatus = status.LookupStatusPartialHit
+				writeLock = pr.cacheLock
+			}
+		} else {
+			writeLock = pr.cacheLock
+			// the fetched data is merged into, and the result cropped, in a copy of the
+			// cached timeseries, since concurrent hits may still be streaming the original
+			if cached {
+				cts = cts.Clone()
+				pr.memory.add(cts.Size())
+				cached = false
+			}
+		}
+		if writeLock != nil {
+			fc.share(writeLock)
 		}
-		writeLock = pr.cacheLock
+	}
+
+	// while the origin is down, the missing ranges are not fetched, and the response is
+	// served from the overlapping cached data, with the gaps noted in its headers
+	var gaps timeseries.ExtentList
+	if cacheStatus == status.LookupStatusPartialHit && len(missRanges) > 0 && oc.Down() {
+		gaps, missRanges = missRanges, nil
+		trq.FastForwardDisable = true
+		if writeLock != nil {
+			writeLock.Release()
+			writeLock = nil
+		}
+	}
+
+	// delta fetches are extended back into the cached data they follow, so that it is verified
+	var overlaps []deltaOverlap
+	if oc.DeltaVerifySteps > 0 && cacheStatus == status.LookupStatusPartialHit {
+		missRanges, overlaps = extendDeltas(cts, missRanges, trq.Step, oc.DeltaVerifySteps)
 	}
 
 	ffStatus := "off"
@@ -289,21 +428,27 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 	if len(missRanges) > 0 {
 		dpStatus["extentsFetched"] = missRanges.String()
 	}
+	if len(gaps) > 0 {
+		dpStatus["degradedGaps"] = gaps.String()
+	}
+
+	// large gaps are split into sub-ranges that are fetched concurrently
+	fetchRanges := missRanges.Split(trq.Step, oc.DeltaFetchMinChunk, oc.DeltaFetchChunks)
 
 	// maintain a list of timeseries to merge into the main timeseries
-	mts := make([]timeseries.Timeseries, 0, len(missRanges))
+	mts := make([]timeseries.Timeseries, 0, len(fetchRanges))
 	wg := sync.WaitGroup{}
 	appendLock := sync.Mutex{}
 	uncachedValueCount := 0
 
 	// iterate each time range that the client needs and fetch from the upstream origin
-	for i := range missRanges {
+	for i := range fetchRanges {
 		wg.Add(1)
 		// This fetches the gaps from the origin and adds their datasets to the merge list
 		go func(e *timeseries.Extent, rq *proxyRequest) {
 			defer wg.Done()
 			rq.upstreamRequest = rq.WithContext(tctx.WithResources(
-				trace.ContextWithSpan(context.Background(), span),
+				trace.ContextWithSpan(rq.fetchContext(), span),
 				request.NewResources(oc, pc, cc, cache, client, rsc.Tracer, pr.Logger)))
 			client.SetExtent(rq.upstreamRequest, trq, e)
 
@@ -311,24 +456,41 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 			if spanMR != nil {
 				rq.upstreamRequest = rq.upstreamRequest.WithContext(ctxMR)
 				defer spanMR.End()
+				spanMR.SetAttributes(kv.String("extent", e.String()))
 			}
 
-			body, resp, _ := rq.Fetch()
+			release := oc.AcquireDeltaFetchSlot()
+			body, resp, elapsed := rq.Fetch()
+			release()
+			logSlowFetch(rq, trq, *e, resp, len(body), elapsed, cacheStatus)
+			if spanMR != nil {
+				spanMR.SetAttributes(kv.Int("bytesFetched", len(body)))
+				spanMR.SetStatus(tracing.HTTPToCode(resp.StatusCode), "")
+			}
 			if resp.StatusCode == http.StatusOK && len(body) > 0 {
-				nts, err := client.UnmarshalTimeseries(body)
+				if err := validateCacheable(request.GetResources(rq.Request), resp.Header,
+					upstreamBodyLength(rq.Request, resp), body); err != nil {
+					logUncacheable(rq, err)
+					return
+				}
+				nts, err := unmarshalUpstreamTimeseries(client, resp.Header, body)
+				if errors.Is(err, timeseries.ErrUnsupportedResponseFormat) {
+					logUncacheable(rq, err)
+					return
+				}
 				if err != nil {
 					pr.Logger.Error("proxy object unmarshaling failed",
 						tl.Pairs{"body": string(body)})
 					return
 				}
-				uncachedValueCount += nts.ValueCount()
 				nts.SetStep(trq.Step)
 				nts.SetExtents([]timeseries.Extent{*e})
 				appendLock.Lock()
+				uncachedValueCount += nts.ValueCount()
 				mts = append(mts, nts)
 				appendLock.Unlock()
 			}
-		}(&missRanges[i], pr.Clone())
+		}(&fetchRanges[i], pr.Clone())
 	}
 
 	var hasFastForwardData bool
@@ -370,6 +532,7 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 						tl.Pairs{"body": string(body)})
 					return
 				}
+				deduplicateTimeseries(oc, ffts)
 				ffts.SetStep(trq.Step)
 				x := ffts.Extents()
 				if isHit {
@@ -386,6 +549,30 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 
 	wg.Wait()
 
+	// the client is gone, and no other request is waiting to use the fetched data
+	if fc.isCanceled() {
+		if writeLock != nil {
+			writeLock.Release()
+		}
+		pr.Logger.Debug("timeseries fetch canceled by client disconnect",
+			tl.Pairs{"cacheKey": key, "cacheStatus": cacheStatus})
+		return
+	}
+
+	quarantined := isSuspectEmpty(oc, cacheStatus, cts, mts)
+	if quarantined {
+		pr.Logger.Warn("fetched timeseries has no series, caching for the quarantine ttl",
+			tl.Pairs{"originName": oc.Name, "cacheKey": key,
+				"extentsFetched": missRanges.String(), "ttl": oc.EmptyQuarantineTTL})
+	}
+
+	var invalid timeseries.ExtentList
+	if len(overlaps) > 0 {
+		cts, invalid = verifyDeltas(pr, oc, key, cts, mts, overlaps, trq.Step)
+	}
+
+	_, mspan := tspan.NewChildSpan(ctx, rsc.Tracer, "MergeTimeseries")
+
 	// Merge the new delta timeseries into the cached timeseries
 	if len(mts) > 0 {
 		// on phit, elapsed records the time spent waiting for all upstream requests to complete
@@ -393,102 +580,408 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 		cts.Merge(true, mts...)
 	}
 
-	// cts is the cacheable time series, rts is the user's response timeseries
-	rts := cts.Clone()
-
 	// if it was a cache key miss, there is no need to undergo Crop since the extents are identical
+	var crop *timeseries.Extent
 	if cacheStatus != status.LookupStatusKeyMiss {
-		rts.CropToRange(trq.Extent)
-	}
-	cachedValueCount := rts.ValueCount() - uncachedValueCount
-
-	if uncachedValueCount > 0 {
-		metrics.ProxyRequestElements.WithLabelValues(oc.Name,
-			oc.OriginType, "uncached", r.URL.Path).Add(float64(uncachedValueCount))
-	}
-
-	if cachedValueCount > 0 {
-		metrics.ProxyRequestElements.WithLabelValues(oc.Name,
-			oc.OriginType, "cached", r.URL.Path).Add(float64(cachedValueCount))
+		crop = &trq.Extent
 	}
 
 	// Merge Fast Forward data if present. This must be done after the Downstream Crop since
 	// the cropped extent was normalized to stepboundaries and would remove fast forward data
 	// If the fast forward data point is older (e.g. cached) than the last datapoint in the
 	// returned time series, it will not be merged
-	if hasFastForwardData && len(ffts.Extents()) == 1 &&
-		ffts.Extents()[0].Start.Truncate(time.Second).After(normalizedNow.Extent.End) {
-		rts.Merge(false, ffts)
-	}
-	rts.SetExtents(nil) // so they are not included in the client response json
-	rts.SetStep(0)
-	rdata, err := client.MarshalTimeseries(rts)
-	rh := doc.SafeHeaderClone()
+	if !hasFastForwardData || len(ffts.Extents()) != 1 ||
+		!ffts.Extents()[0].Start.Truncate(time.Second).After(normalizedNow.Extent.End) {
+		ffts = nil
+	}
+
+	// clients that support streaming encode the response directly from the cacheable
+	// time series, rather than from a cropped copy of it, so that the per-request
+	// memory does not include a second copy of the dataset and its full encoding.
+	// Responses are not streamed when they are aggregated, rounded or the origin limits
+	// their size, since these are applied to the cropped copy
+	aggStep := aggregationStep(oc, trq)
+	roundServed := oc.ValuePrecision > 0 && oc.ValuePrecisionOnServe
+	streamer, isStreaming := client.(origins.TimeseriesStreamer)
+	isStreaming = isStreaming && aggStep == 0 && !roundServed &&
+		oc.MaxResultSeries == 0 && oc.MaxResultSamples == 0
+
+	var rh http.Header
+	var rdata []byte
+	var valueCount int
+	var limitErr error
+	if !isStreaming {
+		rh = doc.SafeHeaderClone()
+		// cts is the cacheable time series, rts is the user's response timeseries
+		rts := cts.Clone()
+		if crop != nil {
+			rts.CropToRange(*crop)
+		}
+		valueCount = rts.ValueCount()
+		if ffts != nil {
+			rts.Merge(false, ffts)
+		}
+		if roundServed {
+			reducePrecision(oc, rts)
+		}
+		if aggStep > 0 {
+			aggregateResult(pr, oc, rts, aggStep)
+		}
+		limitErr = limitResult(pr, rts, rh)
+		rts.SetExtents(nil) // so they are not included in the client response json
+		rts.SetStep(0)
+		if limitErr == nil {
+			rdata, err = client.MarshalTimeseries(rts)
+			pr.memory.add(len(rdata))
+		}
+	}
+
+	if mspan != nil {
+		mspan.SetAttributes(kv.Int("series.merged", len(mts)))
+		if !isStreaming {
+			mspan.SetAttributes(kv.Int("values", valueCount))
+		}
+		mspan.End()
+	}
+
+	if rh == nil {
+		rh = doc.SafeHeaderClone()
+	}
 	sc := doc.StatusCode
+	if limitErr != nil {
+		sc = http.StatusUnprocessableEntity
+		rdata = []byte(limitErr.Error())
+		rh.Del(headers.NameContentLength)
+		rh.Set(headers.NameContentType, headers.ValueTextPlain)
+	}
 
-	if writeLock != nil {
-		// if the mutex is still locked, it means we need to write the time series to cache
-		go func() {
-			// Crop the Cache Object down to the Sample Size or Age Retention Policy and the
-			// Backfill Tolerance before storing to cache
-			switch oc.TimeseriesEvictionMethod {
-			case evictionmethods.EvictionMethodLRU:
-				cts.CropToSize(oc.TimeseriesRetentionFactor, bf.End, trq.Extent)
-			default:
-				cts.CropToRange(timeseries.Extent{End: bf.End, Start: OldestRetainedTimestamp})
+	// any ETag from the origin only describes the fetched portion of the response, so
+	// it is replaced with a strong ETag of the merged response body, which lets clients
+	// and intermediaries revalidate unchanged responses with If-None-Match. A streamed
+	// response is not encoded before it is sent, so its ETag is instead derived from the
+	// version of the cached timeseries it is wholly served from, and the extent served
+	version := rh.Get(headerCacheVersion)
+	rh.Del(headerCacheVersion)
+	rh.Del(headers.NameETag)
+	var etag string
+	if limitErr == nil && sc == http.StatusOK {
+		if !isStreaming {
+			etag = md5.ChecksumBytes(rdata)
+		} else if version != "" && cacheStatus == status.LookupStatusHit && ffts == nil &&
+			len(gaps) == 0 && sw == 0 {
+			etag = md5.Checksum(key + "." + version + "." + trq.Extent.String())
+		}
+	}
+	if etag != "" {
+		rh.Set(headers.NameETag, `"`+etag+`"`)
+		// the response exists, so If-None-Match: * is treated as matching it, as for a hit
+		if inm := r.Header.Get(headers.NameIfNoneMatch); inm != "" &&
+			!CheckIfNoneMatch(etag, inm, status.LookupStatusHit) {
+			sc = http.StatusNotModified
+			rdata = nil
+			rh.Del(headers.NameContentLength)
+		}
+	}
+
+	if oc.CacheStateHeaders && (sc == http.StatusOK || sc == http.StatusNotModified) {
+		setCacheStateHeaders(rh, cacheStatus, trq, bt, oc.TimeseriesTTL, time.Now())
+	}
+
+	if len(gaps) > 0 && sc == http.StatusOK {
+		setDegradedHeaders(rh, gaps)
+	}
+
+	if rangeWarning != "" && sc == http.StatusOK {
+		rh.Add(headers.NameWarning, `199 trickster "`+rangeWarning+`"`)
+	}
+
+	// writeCache crops and stores the cacheable time series, then releases the write lock
+	writeCache := func(cts timeseries.Timeseries) {
+		// cached data that did not match the verified delta fetches is refetched when next requested
+		for _, e := range invalid {
+			cts = removeExtent(cts, e, trq.Step)
+		}
+		// values are rounded before they are stored, including in the downsample tiers
+		reducePrecision(oc, cts)
+		// Values older than the downsample boundary for the step are re-stored at coarser steps
+		downsampleTimeseries(ctx, pr, trq, cts, doc, now)
+		// Crop the Cache Object down to the Sample Size or Age Retention Policy and the
+		// Backfill Tolerance before storing to cache
+		switch oc.TimeseriesEvictionMethod {
+		case evictionmethods.EvictionMethodLRU:
+			cts.CropToSize(oc.TimeseriesRetentionFactor, bf.End, trq.Extent)
+		default:
+			cts.CropToRange(timeseries.Extent{End: bf.End, Start: OldestRetainedTimestamp})
+		}
+		if oc.TimeseriesMaxAge > 0 {
+			cts.CropToRange(timeseries.Extent{End: bf.End, Start: now.Add(-oc.TimeseriesMaxAge)})
+		}
+		limitExtents(pr, cts, trq.Step, oc.TimeseriesMaxExtents)
+		// Sharded cache objects only rewrite the shards holding the newly fetched data
+		if sw > 0 {
+			el := append(missRanges, invalid...)
+			if cacheStatus == status.LookupStatusKeyMiss || cacheStatus == status.LookupStatusPurge {
+				el = timeseries.ExtentList{trq.Extent}
 			}
-			// Don't cache datasets with empty extents
-			// (everything was cropped so there is nothing to cache)
+			writeTimeseriesShards(ctx, pr, key, sw, trq.Step, cts, doc, el,
+				timeseriesTTL(oc, quarantined))
 			if len(cts.Extents()) > 0 {
-				if cc.CacheType == "memory" {
-					doc.timeseries = cts
-				} else {
-					cdata, err := client.MarshalTimeseries(cts)
-					if err != nil {
-						pr.Logger.Error("error marshaling timeseries", tl.Pairs{
-							"cacheKey": key,
-							"detail":   err.Error(),
-						})
-						return
-					}
-					doc.Body = cdata
-				}
-				if err := WriteCache(ctx, cache, key, doc, oc.TimeseriesTTL, oc.CompressableTypes); err != nil {
-					pr.Logger.Error("error writing object to cache",
-						tl.Pairs{
-							"originName": oc.Name,
-							"cacheName":  cache.Configuration().Name,
-							"cacheKey":   key,
-							"detail":     err.Error(),
-						},
-					)
-				}
+				writeLock.SetValue(&dpcResult{cts: cts, doc: doc})
 			}
 			writeLock.Release()
-		}()
+			return
+		}
+		// Don't cache datasets with empty extents
+		// (everything was cropped so there is nothing to cache)
+		if len(cts.Extents()) > 0 {
+			doc := versionedDocument(doc)
+			if cc.CacheType == "memory" {
+				doc.setTimeseries(client, cts)
+			} else {
+				cdata, err := marshalCachedTimeseries(client, cts)
+				if err != nil {
+					pr.Logger.Error("error marshaling timeseries", tl.Pairs{
+						"cacheKey": key,
+						"detail":   err.Error(),
+					})
+					writeLock.Release()
+					return
+				}
+				doc.Body = cdata
+			}
+			if err := WriteCache(ctx, cache, key, doc, timeseriesTTL(oc, quarantined),
+				oc.CompressableTypes); err != nil {
+				pr.Logger.Error("error writing object to cache",
+					tl.Pairs{
+						"originName": oc.Name,
+						"cacheName":  cache.Configuration().Name,
+						"cacheKey":   key,
+						"detail":     err.Error(),
+					},
+				)
+			} else {
+				retainKey(oc, key, now)
+				// requests for the same key that are waiting on the write lock use the
+				// cached timeseries, rather than re-reading it from the cache
+				writeLock.SetValue(&dpcResult{cts: cts, doc: doc})
+			}
+		}
+		writeLock.Release()
 	}
 
 	// Respond to the user. Using the response headers from a Delta Response,
 	// so as to not map conflict with cacheData on WriteCache
 	logDeltaRoutine(pr.Logger, dpStatus)
 	recordDPCResult(r, cacheStatus, sc, r.URL.Path, ffStatus, elapsed.Seconds(), missRanges, rh)
-	Respond(w, sc, rh, rdata)
+
+	if cacheStatus == status.LookupStatusHit || cacheStatus == status.LookupStatusPartialHit {
+		pushCompanions(r, oc, pc, sc)
+	}
+
+	if !isStreaming {
+		recordDPCElements(oc, r.URL.Path, valueCount, uncachedValueCount)
+		if writeLock != nil {
+			// if the mutex is still locked, it means we need to write the time series to cache
+			go writeCache(cts)
+		}
+		Respond(w, sc, rh, rdata)
+		return
+	}
+
+	// the cache object is cropped when it is written, so a copy of it is written while the
+	// response is streamed from the original. This way the write lock is released as soon
+	// as the cache is written, rather than being held for the client's transfer
+	if writeLock != nil {
+		wts := cts.Clone()
+		pr.memory.add(wts.Size())
+		go writeCache(wts)
+	}
+	if sc == http.StatusNotModified {
+		Respond(w, sc, rh, nil)
+		return
+	}
+	rh.Del(headers.NameContentLength)
+	valueCount, err = streamer.StreamTimeseries(PrepareResponseWriter(w, sc, rh), cts, crop, ffts)
+	if err != nil {
+		pr.Logger.Error("error streaming timeseries response",
+			tl.Pairs{"cacheKey": key, "detail": err.Error()})
+	}
+	recordDPCElements(oc, r.URL.Path, valueCount, uncachedValueCount)
+}
+
+// headerCacheVersion is the header in which a cached timeseries document stores its version,
+// which changes each time the document is written. It is not sent to clients
+const headerCacheVersion = "X-Trickster-Cache-Version"
+
+// versionedDocument returns a copy of the document, without its body, to be written to the
+// cache with a new version. The document itself is not modified, since it may be shared with
+// requests reading it from a memory cache
+func versionedDocument(doc *HTTPDocument) *HTTPDocument {
+	h := doc.SafeHeaderClone()
+	h.Set(headerCacheVersion, strconv.FormatInt(time.Now().UnixNano(), 36))
+	return &HTTPDocument{
+		StatusCode:    doc.StatusCode,
+		Status:        doc.Status,
+		Headers:       h,
+		ContentLength: doc.ContentLength,
+		ContentType:   doc.ContentType,
+		CachingPolicy: doc.CachingPolicy,
+	}
+}
+
+// dpcResult is a timeseries that a request wrote to the cache, which it shares through the
+// cache key's lock with the concurrent requests waiting to upgrade to the write lock
+type dpcResult struct {
+	cts timeseries.Timeseries
+	doc *HTTPDocument
+}
+
+// pushCompanions pushes the path's companion responses to HTTP/2 clients, ahead of a
+// successful response, so that they are not requested in a separate round trip
+func pushCompanions(r *http.Request, oc *oo.Options, pc *po.Options, statusCode int) {
+	if pc == nil || len(pc.PushPaths) == 0 || statusCode < 200 || statusCode >= 300 {
+		return
+	}
+	if n := push.Push(r, pc.Path, pc.PushPaths); n > 0 {
+		metrics.ProxyPushes.WithLabelValues(oc.Name, oc.OriginType, r.URL.Path).Add(float64(n))
+	}
+}
+
+// recordDPCElements records the number of cached and uncached values in the response
+func recordDPCElements(oc *oo.Options, path string, valueCount, uncachedValueCount int) {
+	cachedValueCount := valueCount - uncachedValueCount
+	if uncachedValueCount > 0 {
+		metrics.ProxyRequestElements.WithLabelValues(oc.Name,
+			oc.OriginType, "uncached", path).Add(float64(uncachedValueCount))
+	}
+	if cachedValueCount > 0 {
+		metrics.ProxyRequestElements.WithLabelValues(oc.Name,
+			oc.OriginType, "cached", path).Add(float64(cachedValueCount))
+	}
+}
+
+// setCacheStateHeaders sets the X-Cache, Age and Cache-Control headers describing the cache
+// status and freshness of a timeseries response. A response whose next data point would fall
+// within the backfill tolerance may still change, so its age is the time since its last step,
+// and it is fresh until the next step. Older responses will not change, and are fresh for the
+// timeseries TTL
+func setCacheStateHeaders(h http.Header, cacheStatus status.LookupStatus,
+	trq *timeseries.TimeRangeQuery, bt, ttl time.Duration, now time.Time) {
+
+	switch cacheStatus {
+	case status.LookupStatusHit:
+		h.Set(headers.NameXCache, "HIT")
+	case status.LookupStatusPartialHit:
+		h.Set(headers.NameXCache, "PARTIAL")
+	default:
+		h.Set(headers.NameXCache, "MISS")
+	}
+
+	h.Del(headers.NameExpires)
+	if !trq.Extent.End.Add(trq.Step + bt).After(now) {
+		h.Del(headers.NameAge)
+		h.Set(headers.NameCacheControl, headers.ValueMaxAge+"="+
+			strconv.FormatInt(int64(ttl.Seconds()), 10))
+		return
+	}
+
+	age := now.Sub(trq.Extent.End)
+	if age < 0 {
+		age = 0
+	} else if age > trq.Step {
+		age = trq.Step
+	}
+	h.Set(headers.NameAge, strconv.FormatInt(int64(age.Seconds()), 10))
+	h.Set(headers.NameCacheControl, headers.ValueMaxAge+"="+
+		strconv.FormatInt(int64(trq.Step.Seconds()), 10))
+}
+
+// setDegradedHeaders marks a timeseries response that was served only from the cache while its
+// origin is down. The X-Trickster-Degraded header lists the ranges missing from the response,
+// and the response is not cacheable by clients, so that it is replaced once the origin is up
+func setDegradedHeaders(h http.Header, gaps timeseries.ExtentList) {
+	b := make([]byte, 0, 32*len(gaps))
+	b = append(b, "gaps=["...)
+	for i, e := range gaps {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = strconv.AppendInt(b, e.Start.Unix(), 10)
+		b = append(b, ':')
+		b = strconv.AppendInt(b, e.End.Unix(), 10)
+	}
+	b = append(b, ']')
+	h.Set(headers.NameTricksterDegraded, string(b))
+	h.Set(headers.NameWarning, `199 trickster "origin unavailable; response includes only cached data"`)
+	h.Del(headers.NameAge)
+	h.Set(headers.NameCacheControl, headers.ValueNoCache)
 }
 
 func logDeltaRoutine(log *tl.Logger, p tl.Pairs) { log.Debug("delta routine completed", p) }
 
 func fetchTimeseries(pr *proxyRequest, trq *timeseries.TimeRangeQuery,
-	client origins.TimeseriesClient) (timeseries.Timeseries, *HTTPDocument, time.Duration, error) {
+	client origins.TimeseriesClient,
+	cacheStatus status.LookupStatus) (timeseries.Timeseries, *HTTPDocument, time.Duration, error) {
+
+	rsc := request.GetResources(pr.Request)
+	oc := rsc.OriginConfig
+
+	ranges := timeseries.ExtentList{trq.Extent}.Split(trq.Step,
+		oc.DeltaFetchMinChunk, oc.DeltaFetchChunks)
+	if len(ranges) < 2 {
+		return fetchTimeseriesRange(pr, trq, trq.Extent, client, cacheStatus)
+	}
+
+	// the requested range is large enough to be fetched in concurrent sub-ranges,
+	// which are merged back into a single timeseries
+	tss := make([]timeseries.Timeseries, len(ranges))
+	docs := make([]*HTTPDocument, len(ranges))
+	errs := make([]error, len(ranges))
+	now := time.Now()
+	wg := sync.WaitGroup{}
+	for i := range ranges {
+		wg.Add(1)
+		go func(i int, rq *proxyRequest) {
+			defer wg.Done()
+			client.SetExtent(rq.upstreamRequest, trq, &ranges[i])
+			tss[i], docs[i], _, errs[i] = fetchTimeseriesRange(rq, trq, ranges[i], client, cacheStatus)
+		}(i, pr.Clone())
+	}
+	wg.Wait()
+	elapsed := time.Since(now)
+
+	for i := range errs {
+		if errs[i] != nil {
+			return nil, docs[i], time.Duration(0), errs[i]
+		}
+	}
+
+	ts := tss[0]
+	ts.Merge(true, tss[1:]...)
+	ts.SetExtents([]timeseries.Extent{trq.Extent})
+
+	return ts, docs[0], elapsed, nil
+}
+
+// fetchTimeseriesRange fetches extent e of the timeseries request from the origin, using
+// the upstream request as-is. The caller is responsible for setting the extent on it
+func fetchTimeseriesRange(pr *proxyRequest, trq *timeseries.TimeRangeQuery,
+	e timeseries.Extent, client origins.TimeseriesClient,
+	cacheStatus status.LookupStatus) (timeseries.Timeseries, *HTTPDocument, time.Duration, error) {
 
 	rsc := request.GetResources(pr.Request)
 
 	ctx, span := tspan.NewChildSpan(pr.upstreamRequest.Context(), rsc.Tracer, "FetchTimeSeries")
 	if span != nil {
 		defer span.End()
+		span.SetAttributes(kv.String("extent", e.String()))
 	}
 	pr.upstreamRequest = pr.upstreamRequest.WithContext(ctx)
 
+	release := rsc.OriginConfig.AcquireDeltaFetchSlot()
 	body, resp, elapsed := pr.Fetch()
+	release()
+	logSlowFetch(pr, trq, e, resp, len(body), elapsed, cacheStatus)
 
 	d := &HTTPDocument{
 		Status:     resp.Status,
@@ -497,6 +990,11 @@ func fetchTimeseries(pr *proxyRequest, trq *timeseries.TimeRangeQuery,
 		Body:       body,
 	}
 
+	// the fetch was canceled because the client disconnected, so there is nothing to report
+	if err := pr.upstreamRequest.Context().Err(); err != nil {
+		return nil, d, time.Duration(0), err
+	}
+
 	if resp.StatusCode != 200 {
 		pr.Logger.Error("unexpected upstream response",
 			tl.Pairs{
@@ -514,18 +1012,128 @@ func fetchTimeseries(pr *proxyRequest, trq *timeseries.TimeRangeQuery,
 		return nil, d, time.Duration(0), tpe.ErrUnexpectedUpstreamResponse
 	}
 
-	ts, err := client.UnmarshalTimeseries(body)
+	if err := validateCacheable(rsc, resp.Header, upstreamBodyLength(pr.Request, resp),
+		body); err != nil {
+		logUncacheable(pr, err)
+		return nil, d, time.Duration(0), err
+	}
+
+	ts, err := unmarshalUpstreamTimeseries(client, resp.Header, body)
+	if errors.Is(err, timeseries.ErrUnsupportedResponseFormat) {
+		logUncacheable(pr, err)
+		return nil, d, time.Duration(0), err
+	}
 	if err != nil {
 		pr.Logger.Error("proxy object unmarshaling failed", tl.Pairs{"body": string(body)})
 		return nil, d, time.Duration(0), err
 	}
+	deduplicateTimeseries(rsc.OriginConfig, ts)
 
-	ts.SetExtents([]timeseries.Extent{trq.Extent})
+	ts.SetExtents([]timeseries.Extent{e})
 	ts.SetStep(trq.Step)
 
 	return ts, d, elapsed, nil
 }
 
+// deduplicateTimeseries removes the labels that distinguish the origin's replicas from a
+// timeseries fetched from the origin, so that it merges with those fetched from other replicas
+func deduplicateTimeseries(oc *oo.Options, ts timeseries.Timeseries) {
+	if len(oc.DedupeLabels) == 0 {
+		return
+	}
+	if d, ok := ts.(timeseries.Deduplicator); ok {
+		d.Deduplicate(oc.DedupeLabels)
+	}
+}
+
+// unmarshalUpstreamTimeseries decodes a Timeseries from an upstream response, detecting
+// its format when the client's origin responds in more than one
+func unmarshalUpstreamTimeseries(client origins.TimeseriesClient, h http.Header,
+	body []byte) (timeseries.Timeseries, error) {
+	if ru, ok := client.(origins.TimeseriesResponseUnmarshaler); ok {
+		return ru.UnmarshalTimeseriesResponse(h, body)
+	}
+	return client.UnmarshalTimeseries(body)
+}
+
+// marshalCachedTimeseries encodes the Timeseries for cache storage, using the binary
+// cache format when the client supports it
+func marshalCachedTimeseries(client origins.TimeseriesClient,
+	ts timeseries.Timeseries) ([]byte, error) {
+	if bm, ok := client.(origins.TimeseriesBinaryMarshaler); ok {
+		return bm.MarshalTimeseriesBinary(ts)
+	}
+	return client.MarshalTimeseries(ts)
+}
+
+// unmarshalCachedTimeseries decodes a Timeseries read from the cache. JSON entries
+// written before the client supported the binary cache format are still decoded, and
+// are rewritten in the binary format the next time they are updated
+func unmarshalCachedTimeseries(client origins.TimeseriesClient,
+	data []byte) (timeseries.Timeseries, error) {
+	if !timeseries.IsBinaryFormat(data) {
+		return client.UnmarshalTimeseries(data)
+	}
+	if bm, ok := client.(origins.TimeseriesBinaryMarshaler); ok {
+		return bm.UnmarshalTimeseriesBinary(data)
+	}
+	return nil, timeseries.ErrUnsupportedBinaryFormat
+}
+
+// limitExtents keeps the number of extents in the cached timeseries within max, so that a
+// fragmented cache object does not slow down every merge. The extent list is compacted first,
+// and if that is not enough, the oldest extents and their data are cropped from the timeseries
+func limitExtents(pr *proxyRequest, ts timeseries.Timeseries, step time.Duration, max int) {
+	el := ts.Extents()
+	if max <= 0 || len(el) <= max {
+		return
+	}
+	count := len(el)
+	el = el.Compact(step)
+	ts.SetExtents(el)
+	if len(el) > max {
+		ts.CropToRange(timeseries.Extent{Start: el[len(el)-max].Start, End: el[len(el)-1].End})
+	}
+	pr.Logger.Debug("limited extents of cached timeseries",
+		tl.Pairs{"extents": count, "compacted": len(el), "maxExtents": max})
+}
+
+// logSlowFetch records the fetch of extent e in the slow query log when it took longer
+// than the origin's slow query threshold
+func logSlowFetch(pr *proxyRequest, trq *timeseries.TimeRangeQuery, e timeseries.Extent,
+	resp *http.Response, bytes int, elapsed time.Duration, cacheStatus status.LookupStatus) {
+	rsc := request.GetResources(pr.upstreamRequest)
+	if rsc == nil || rsc.OriginConfig == nil {
+		return
+	}
+	oc := rsc.OriginConfig
+	if oc.SlowLogThreshold <= 0 || elapsed < oc.SlowLogThreshold || !slowlog.Enabled() {
+		return
+	}
+	rec := &slowlog.Record{
+		Time:        time.Now(),
+		Origin:      oc.Name,
+		OriginType:  oc.OriginType,
+		Query:       trq.Statement,
+		Start:       e.Start,
+		End:         e.End,
+		Step:        trq.Step,
+		Duration:    elapsed,
+		Threshold:   oc.SlowLogThreshold,
+		Bytes:       bytes,
+		CacheStatus: cacheStatus.String(),
+	}
+	if resp != nil {
+		rec.Status = resp.StatusCode
+	}
+	if sc := trace.SpanFromContext(pr.upstreamRequest.Context()).SpanContext(); sc.HasTraceID() {
+		rec.TraceID = sc.TraceID.String()
+	}
+	if err := slowlog.Log(rec); err != nil {
+		pr.Logger.Error("unable to write slow query log", tl.Pairs{"detail": err.Error()})
+	}
+}
+
 func recordDPCResult(r *http.Request, cacheStatus status.LookupStatus, httpStatus int, path,
 	ffStatus string, elapsed float64, needed []timeseries.Extent, header http.Header) {
 	recordResults(r, "DeltaProxyCache", cacheStatus, httpStatus, path, ffStatus, elapsed,
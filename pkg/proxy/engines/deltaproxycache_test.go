@@ -18,16 +18,29 @@ package engines
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/prometheus/common/model"
 	mockprom "github.com/tricksterproxy/mockster/pkg/mocks/prometheus"
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/util/slowlog"
+	slowlogopts "github.com/tricksterproxy/trickster/pkg/util/slowlog/options"
 	tu "github.com/tricksterproxy/trickster/pkg/util/testing"
 )
 
@@ -1579,3 +1592,899 @@ func TestDeltaProxyCacheRequestFFTTLBiggerThanStep(t *testing.T) {
 	}
 
 }
+
+func TestDeltaProxyCacheRequestSlowLog(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	td, err := ioutil.TempDir("", "trickster-slowlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+	so := slowlogopts.NewOptions()
+	so.Output = filepath.Join(td, "slow.log")
+	l := slowlog.New(so)
+	slowlog.SetLogger(l)
+	defer slowlog.SetLogger(nil)
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	oc.FastForwardDisable = true
+	oc.SlowLogThreshold = time.Nanosecond
+
+	step := time.Duration(300) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(18) * time.Hour), End: end}
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusOK)
+	if err != nil {
+		t.Error(err)
+	}
+
+	l.Close()
+	b, err := ioutil.ReadFile(so.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`"cache_status":"kmiss"`, `"step_secs":300`, `"status":200`,
+		`"origin_type":"prometheus"`} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("expected %s in slow log: %s", s, string(b))
+		}
+	}
+}
+
+// binaryTestClient is a TestClient that supports the binary cache format, by prefixing
+// its JSON encoding with the format version byte
+type binaryTestClient struct {
+	*TestClient
+}
+
+func (c *binaryTestClient) MarshalTimeseriesBinary(ts timeseries.Timeseries) ([]byte, error) {
+	b, err := c.MarshalTimeseries(ts)
+	return append([]byte{timeseries.BinaryFormatVersion}, b...), err
+}
+
+func (c *binaryTestClient) UnmarshalTimeseriesBinary(data []byte) (timeseries.Timeseries, error) {
+	_, b, err := timeseries.BinaryBody(data)
+	if err != nil {
+		return nil, err
+	}
+	return c.UnmarshalTimeseries(b)
+}
+
+func TestCachedTimeseriesFormat(t *testing.T) {
+
+	jsonClient := &TestClient{}
+	binaryClient := &binaryTestClient{TestClient: jsonClient}
+	ts := &MatrixEnvelope{Status: "success", Data: MatrixData{ResultType: "matrix"}}
+
+	jb, err := marshalCachedTimeseries(jsonClient, ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timeseries.IsBinaryFormat(jb) {
+		t.Error("expected json format")
+	}
+
+	bb, err := marshalCachedTimeseries(binaryClient, ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !timeseries.IsBinaryFormat(bb) {
+		t.Error("expected binary format")
+	}
+
+	// binary clients can read both formats, so existing json entries are migrated
+	for _, b := range [][]byte{jb, bb} {
+		ts2, err := unmarshalCachedTimeseries(binaryClient, b)
+		if err != nil {
+			t.Error(err)
+		} else if ts2.(*MatrixEnvelope).Status != "success" {
+			t.Errorf("unexpected status %s", ts2.(*MatrixEnvelope).Status)
+		}
+	}
+
+	_, err = unmarshalCachedTimeseries(jsonClient, bb)
+	if err != timeseries.ErrUnsupportedBinaryFormat {
+		t.Errorf("expected %v got %v", timeseries.ErrUnsupportedBinaryFormat, err)
+	}
+}
+
+// streamingTestClient is a TestClient that streams its responses
+type streamingTestClient struct {
+	*TestClient
+	streamed int32
+}
+
+func (c *streamingTestClient) StreamTimeseries(w io.Writer, ts timeseries.Timeseries,
+	e *timeseries.Extent, ff timeseries.Timeseries) (int, error) {
+	atomic.AddInt32(&c.streamed, 1)
+	rts := ts.Clone()
+	if e != nil {
+		rts.CropToRange(*e)
+	}
+	n := rts.ValueCount()
+	if ff != nil {
+		rts.Merge(false, ff)
+	}
+	rts.SetExtents(nil)
+	rts.SetStep(0)
+	b, err := c.MarshalTimeseries(rts)
+	if err != nil {
+		return 0, err
+	}
+	_, err = w.Write(b)
+	return n, err
+}
+
+func TestDeltaProxyCacheRequestStreaming(t *testing.T) {
+
+	ts, _, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	sc := &streamingTestClient{TestClient: client}
+	rsc.OriginClient = sc
+	oc := rsc.OriginConfig
+	oc.FastForwardDisable = true
+	// a max age makes the origin record the keys it writes
+	oc.TimeseriesMaxAge = time.Duration(720) * time.Hour
+
+	step := time.Duration(300) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(18) * time.Hour), End: end}
+	extn := timeseries.Extent{Start: extr.Start.Truncate(step), End: extr.End.Truncate(step)}
+
+	expected, _, _ := mockprom.GetTimeSeriesData(queryReturnsOKNoLatency, extn.Start, extn.End, step)
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+	r.URL = client.BuildUpstreamURL(r)
+
+	for i, status := range []string{"kmiss", "hit"} {
+		w := httptest.NewRecorder()
+		DeltaProxyCacheRequest(w, r)
+		resp := w.Result()
+
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if err = testStringMatch(string(bodyBytes), expected); err != nil {
+			t.Error(err)
+		}
+		if err = testStatusCodeMatch(resp.StatusCode, http.StatusOK); err != nil {
+			t.Error(err)
+		}
+		if err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": status}); err != nil {
+			t.Error(err)
+		}
+		if resp.Header.Get(headers.NameContentLength) != "" {
+			t.Error("expected no content length for a streamed response")
+		}
+		if n := atomic.LoadInt32(&sc.streamed); n != int32(i+1) {
+			t.Errorf("expected %d streamed responses got %d", i+1, n)
+		}
+		// Give time for the object to be written to cache in a separate goroutine from response
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+// blockingStreamClient is a streamingTestClient whose first streamed response blocks
+// until it is released
+type blockingStreamClient struct {
+	*streamingTestClient
+	calls     int32
+	streaming chan struct{}
+	release   chan struct{}
+	// ts is the timeseries that the blocked response is streamed from
+	ts timeseries.Timeseries
+}
+
+func (c *blockingStreamClient) StreamTimeseries(w io.Writer, ts timeseries.Timeseries,
+	e *timeseries.Extent, ff timeseries.Timeseries) (int, error) {
+	if atomic.AddInt32(&c.calls, 1) == 1 {
+		c.ts = ts
+		close(c.streaming)
+		<-c.release
+	}
+	return c.streamingTestClient.StreamTimeseries(w, ts, e, ff)
+}
+
+func TestDeltaProxyCacheRequestStreamingReleasesLock(t *testing.T) {
+
+	ts, _, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	bc := &blockingStreamClient{streamingTestClient: &streamingTestClient{TestClient: client},
+		streaming: make(chan struct{}), release: make(chan struct{})}
+	rsc.OriginClient = bc
+	rsc.OriginConfig.FastForwardDisable = true
+
+	step := time.Duration(300) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(18) * time.Hour), End: end}
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+	r.URL = client.BuildUpstreamURL(r)
+	newRequest := func() *http.Request {
+		return request.SetResources(r.Clone(r.Context()), request.NewResources(rsc.OriginConfig,
+			rsc.PathConfig, rsc.CacheConfig, rsc.CacheClient, bc, rsc.Tracer, rsc.Logger))
+	}
+
+	// the first response is stuck streaming to its client
+	first := make(chan struct{})
+	go func() {
+		DeltaProxyCacheRequest(httptest.NewRecorder(), newRequest())
+		close(first)
+	}()
+	defer func() {
+		close(bc.release)
+		<-first
+	}()
+	<-bc.streaming
+
+	// which does not hold the cache key's write lock, so a second request is served
+	// from the cache in the meantime
+	second := make(chan *http.Response)
+	go func() {
+		w := httptest.NewRecorder()
+		DeltaProxyCacheRequest(w, newRequest())
+		second <- w.Result()
+	}()
+	select {
+	case resp := <-second:
+		if err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": "hit"}); err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected request to not wait on a response being streamed")
+	}
+}
+
+func TestDeltaProxyCacheRequestStreamingConcurrentMerge(t *testing.T) {
+
+	ts, _, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	sc := &streamingTestClient{TestClient: client}
+	bc := &blockingStreamClient{streamingTestClient: sc,
+		streaming: make(chan struct{}), release: make(chan struct{})}
+	rsc.OriginConfig.FastForwardDisable = true
+
+	step := time.Duration(300) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour).Truncate(step)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(6) * time.Hour), End: end}
+	expected, _, _ := mockprom.GetTimeSeriesData(queryReturnsOKNoLatency, extr.Start, extr.End, step)
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	r.URL = client.BuildUpstreamURL(r)
+	newRequest := func(e timeseries.Extent, c origins.Client) *http.Request {
+		rq := r.Clone(r.Context())
+		rq.URL.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+			int(step.Seconds()), e.Start.Unix(), e.End.Unix(), queryReturnsOKNoLatency)
+		return request.SetResources(rq, request.NewResources(rsc.OriginConfig,
+			rsc.PathConfig, rsc.CacheConfig, rsc.CacheClient, c, rsc.Tracer, rsc.Logger))
+	}
+
+	DeltaProxyCacheRequest(httptest.NewRecorder(), newRequest(extr, sc))
+	time.Sleep(time.Millisecond * 10)
+
+	// a hit is stuck streaming the cached timeseries to its client
+	hit := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		DeltaProxyCacheRequest(hit, newRequest(extr, bc))
+		close(done)
+	}()
+	<-bc.streaming
+	streamed := bc.ts.Extents().String()
+
+	// while a partial hit for an earlier range of the same key merges its fetched data
+	// and writes the result back to the cache
+	w := httptest.NewRecorder()
+	DeltaProxyCacheRequest(w, newRequest(timeseries.Extent{
+		Start: extr.Start.Add(-time.Duration(2) * time.Hour), End: extr.End}, sc))
+	if err = testResultHeaderPartMatch(w.Header(), map[string]string{"status": "phit"}); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(time.Millisecond * 10)
+
+	if e := bc.ts.Extents().String(); e != streamed {
+		t.Errorf("expected the streamed timeseries to be unchanged at %s got %s", streamed, e)
+	}
+	close(bc.release)
+	<-done
+	if err = testStringMatch(hit.Body.String(), expected); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeltaProxyCacheRequestChunkedFetch(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	rsc.CacheConfig.CacheType = "test"
+
+	client.RangeCacheKey = "test-range-key-chunked"
+	client.InstantCacheKey = "test-instant-key-chunked"
+
+	oc.FastForwardDisable = true
+	oc.DeltaFetchChunks = 4
+	oc.DeltaFetchMinChunk = time.Duration(3) * time.Hour
+	oc.DeltaFetchParallelism = 2
+
+	step := time.Duration(300) * time.Second
+
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(18) * time.Hour), End: end}
+	extn := timeseries.Extent{Start: normalizeTime(extr.Start, step), End: normalizeTime(extr.End, step)}
+
+	expected, _, _ := mockprom.GetTimeSeriesData(queryReturnsOKNoLatency, extn.Start, extn.End, step)
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s&rk=%s&ik=%s", int(step.Seconds()),
+		extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency, client.RangeCacheKey, client.InstantCacheKey)
+
+	// the 18h key miss is fetched in 4 sub-ranges
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testStringMatch(string(bodyBytes), expected)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusOK)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": "kmiss"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	// the 6h upper fragment of the partial hit is fetched in 2 sub-ranges
+	phitStart := normalizeTime(extr.End.Add(step), step)
+	extr.End = extr.End.Add(time.Duration(6) * time.Hour)
+	extn.End = normalizeTime(extr.End, step)
+
+	expectedFetched := fmt.Sprintf("[%d:%d]", phitStart.Unix(), extn.End.Unix())
+	expected, _, _ = mockprom.GetTimeSeriesData(queryReturnsOKNoLatency, extn.Start, extn.End, step)
+
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s&rk=%s&ik=%s", int(step.Seconds()),
+		extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency, client.RangeCacheKey, client.InstantCacheKey)
+
+	r.URL = u
+
+	time.Sleep(time.Millisecond * 10)
+
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	resp = w.Result()
+
+	bodyBytes, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testStringMatch(string(bodyBytes), expected)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": "phit"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testResultHeaderPartMatch(resp.Header, map[string]string{"fetched": expectedFetched})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLimitExtents(t *testing.T) {
+
+	ts, w, r, _, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	pr := newProxyRequest(r, w)
+	step := time.Duration(30) * time.Second
+
+	values := make([]model.SamplePair, 0, 9)
+	for _, s := range []int64{0, 30, 60, 90, 180, 210, 300, 330, 420} {
+		values = append(values, model.SamplePair{Timestamp: model.TimeFromUnix(s), Value: 1})
+	}
+
+	me := &MatrixEnvelope{
+		Data: MatrixData{ResultType: "matrix",
+			Result: model.Matrix{&model.SampleStream{Metric: model.Metric{"a": "1"}, Values: values}}},
+		ExtentList: timeseries.ExtentList{
+			timeseries.Extent{Start: time.Unix(0, 0), End: time.Unix(30, 0), LastUsed: time.Unix(1, 0)},
+			timeseries.Extent{Start: time.Unix(60, 0), End: time.Unix(90, 0), LastUsed: time.Unix(2, 0)},
+			timeseries.Extent{Start: time.Unix(180, 0), End: time.Unix(210, 0)},
+			timeseries.Extent{Start: time.Unix(300, 0), End: time.Unix(330, 0)},
+			timeseries.Extent{Start: time.Unix(420, 0), End: time.Unix(420, 0)},
+		},
+		StepDuration: step,
+	}
+
+	// no limit
+	limitExtents(pr, me, step, 0)
+	if len(me.ExtentList) != 5 {
+		t.Errorf("expected %d got %d", 5, len(me.ExtentList))
+	}
+
+	// compaction alone is enough
+	me2 := me.Clone().(*MatrixEnvelope)
+	limitExtents(pr, me2, step, 4)
+	if me2.ExtentList.String() != "0-90;180-210;300-330;420-420" {
+		t.Errorf("unexpected extents %s", me2.ExtentList.String())
+	}
+	if me2.ValueCount() != 9 {
+		t.Errorf("expected %d got %d", 9, me2.ValueCount())
+	}
+
+	// the oldest extents are dropped
+	limitExtents(pr, me, step, 3)
+	if me.ExtentList.String() != "180-210;300-330;420-420" {
+		t.Errorf("unexpected extents %s", me.ExtentList.String())
+	}
+	if me.ValueCount() != 5 {
+		t.Errorf("expected %d got %d", 5, me.ValueCount())
+	}
+}
+
+func TestDeltaProxyCacheRequestDedupeLabels(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	oc.FastForwardDisable = true
+	oc.DedupeLabels = []string{"series_id"}
+
+	step := time.Duration(60) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(1) * time.Hour), End: end}
+
+	const query = "some_query_here{latency_ms=0,range_latency_ms=0,series_count=3}"
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), query)
+
+	// the 3 series differ only by the dedupe label, so they are merged into 1
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusOK)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if strings.Contains(string(bodyBytes), "series_id") {
+		t.Errorf("expected dedupe label to be removed from %s", string(bodyBytes))
+	}
+
+	me, err := client.UnmarshalTimeseries(bodyBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if me.SeriesCount() != 1 {
+		t.Errorf("expected %d got %d", 1, me.SeriesCount())
+	}
+}
+
+func TestDeltaProxyCacheRequestConcurrentMiss(t *testing.T) {
+
+	ts, _, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	oc.FastForwardDisable = true
+	// a max age makes the origin record the keys it writes
+	oc.TimeseriesMaxAge = time.Duration(720) * time.Hour
+
+	step := time.Duration(300) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(18) * time.Hour), End: end}
+	extn := timeseries.Extent{Start: extr.Start.Truncate(step), End: extr.End.Truncate(step)}
+
+	const query = "some_query_here{latency_ms=0,range_latency_ms=1}"
+	expected, _, _ := mockprom.GetTimeSeriesData(query, extn.Start, extn.End, step)
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), query)
+
+	// the requests that wait on the write lock of the first use the timeseries that it cached
+	const n = 4
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(r *http.Request) {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			client.QueryRangeHandler(w, r)
+			resp := w.Result()
+			bodyBytes, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Error(err)
+			}
+			if err = testStringMatch(string(bodyBytes), expected); err != nil {
+				t.Error(err)
+			}
+			if err = testStatusCodeMatch(resp.StatusCode, http.StatusOK); err != nil {
+				t.Error(err)
+			}
+		}(request.SetResources(r.Clone(r.Context()), request.NewResources(oc, rsc.PathConfig,
+			rsc.CacheConfig, rsc.CacheClient, client, rsc.Tracer, rsc.Logger)))
+	}
+	wg.Wait()
+}
+
+func TestDeltaProxyCacheRequestETag(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	oc.FastForwardDisable = true
+	// a max age makes the origin record the keys it writes
+	oc.TimeseriesMaxAge = time.Duration(720) * time.Hour
+
+	step := time.Duration(300) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(6) * time.Hour), End: end}
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if err = testStatusCodeMatch(resp.StatusCode, http.StatusOK); err != nil {
+		t.Error(err)
+	}
+	etag := resp.Header.Get(headers.NameETag)
+	if etag == "" || etag[0] != '"' {
+		t.Fatalf("expected strong etag got %s", etag)
+	}
+
+	tests := []struct {
+		ifNoneMatch string
+		code        int
+	}{
+		{etag, http.StatusNotModified},
+		{`"other", ` + etag, http.StatusNotModified},
+		{"W/" + etag, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{`"other"`, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.ifNoneMatch, func(t *testing.T) {
+			// the response is now served from cache, and must have the same etag
+			w := httptest.NewRecorder()
+			r.Header.Set(headers.NameIfNoneMatch, test.ifNoneMatch)
+			client.QueryRangeHandler(w, r)
+			resp := w.Result()
+			b, _ := ioutil.ReadAll(resp.Body)
+			if err := testStatusCodeMatch(resp.StatusCode, test.code); err != nil {
+				t.Error(err)
+			}
+			if v := resp.Header.Get(headers.NameETag); v != etag {
+				t.Errorf("expected %s got %s", etag, v)
+			}
+			if test.code == http.StatusNotModified && len(b) != 0 {
+				t.Errorf("expected empty body got %d bytes", len(b))
+			}
+			if test.code == http.StatusOK && string(b) != string(body) {
+				t.Error("expected body to match the first response")
+			}
+		})
+	}
+}
+
+func TestSetCacheStateHeaders(t *testing.T) {
+
+	now := time.Unix(1600000000, 0)
+	step := time.Minute
+
+	tests := []struct {
+		status       status.LookupStatus
+		end          time.Time
+		bt           time.Duration
+		xCache       string
+		age          string
+		cacheControl string
+	}{
+		{status.LookupStatusHit, now.Add(-15 * time.Second), 0, "HIT", "15", "max-age=60"},
+		{status.LookupStatusPartialHit, now, 0, "PARTIAL", "0", "max-age=60"},
+		{status.LookupStatusKeyMiss, now.Add(time.Minute), 0, "MISS", "0", "max-age=60"},
+		{status.LookupStatusRangeMiss, now.Add(-2 * time.Minute), 0, "MISS", "", "max-age=3600"},
+		// the data is within the backfill tolerance, so it is already stale
+		{status.LookupStatusHit, now.Add(-2 * time.Minute), 5 * time.Minute, "HIT", "60", "max-age=60"},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			h := http.Header{headers.NameAge: []string{"500"},
+				headers.NameExpires: []string{"Thu, 01 Jan 1970 00:00:00 GMT"}}
+			trq := &timeseries.TimeRangeQuery{Step: step,
+				Extent: timeseries.Extent{Start: test.end.Add(-time.Hour), End: test.end}}
+			setCacheStateHeaders(h, test.status, trq, test.bt, time.Hour, now)
+			if v := h.Get(headers.NameXCache); v != test.xCache {
+				t.Errorf("expected %s got %s", test.xCache, v)
+			}
+			if v := h.Get(headers.NameAge); v != test.age {
+				t.Errorf("expected %s got %s", test.age, v)
+			}
+			if v := h.Get(headers.NameCacheControl); v != test.cacheControl {
+				t.Errorf("expected %s got %s", test.cacheControl, v)
+			}
+			if v := h.Get(headers.NameExpires); v != "" {
+				t.Errorf("expected empty Expires header got %s", v)
+			}
+		})
+	}
+}
+
+func TestDeltaProxyCacheRequestCacheStateHeaders(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	oc.FastForwardDisable = true
+	oc.CacheStateHeaders = true
+
+	step := time.Duration(300) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(6) * time.Hour), End: end}
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+	if err = testStatusCodeMatch(resp.StatusCode, http.StatusOK); err != nil {
+		t.Error(err)
+	}
+	if v := resp.Header.Get(headers.NameXCache); v != "MISS" {
+		t.Errorf("expected %s got %s", "MISS", v)
+	}
+	expected := fmt.Sprintf("max-age=%d", int(oc.TimeseriesTTL.Seconds()))
+	if v := resp.Header.Get(headers.NameCacheControl); v != expected {
+		t.Errorf("expected %s got %s", expected, v)
+	}
+
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	resp = w.Result()
+	if v := resp.Header.Get(headers.NameXCache); v != "HIT" {
+		t.Errorf("expected %s got %s", "HIT", v)
+	}
+}
+
+func TestDeltaProxyCacheRequestDegraded(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	rsc.CacheConfig.CacheType = "test"
+
+	client.RangeCacheKey = "test-range-key-degraded"
+	client.InstantCacheKey = "test-instant-key-degraded"
+
+	oc.FastForwardDisable = true
+
+	step := time.Duration(300) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(18) * time.Hour), End: end}
+	extn := timeseries.Extent{Start: normalizeTime(extr.Start, step), End: normalizeTime(extr.End, step)}
+
+	expected, _, _ := mockprom.GetTimeSeriesData(queryReturnsOKNoLatency, extn.Start, extn.End, step)
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s&rk=%s&ik=%s", int(step.Seconds()),
+		extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency, client.RangeCacheKey, client.InstantCacheKey)
+
+	client.QueryRangeHandler(w, r)
+	if err = testResultHeaderPartMatch(w.Result().Header, map[string]string{"status": "kmiss"}); err != nil {
+		t.Error(err)
+	}
+
+	// while the origin is down, the extended range is served from the cached data only
+	oc.SetDown(true)
+	defer oc.SetDown(false)
+	gapStart := normalizeTime(extr.End.Add(step), step)
+	extr.End = extr.End.Add(time.Duration(1) * time.Hour)
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s&rk=%s&ik=%s", int(step.Seconds()),
+		extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency, client.RangeCacheKey, client.InstantCacheKey)
+	r.URL = u
+
+	time.Sleep(time.Millisecond * 10)
+
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+	if err = testStringMatch(string(bodyBytes), expected); err != nil {
+		t.Error(err)
+	}
+	if err = testStatusCodeMatch(resp.StatusCode, http.StatusOK); err != nil {
+		t.Error(err)
+	}
+	if err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": "phit"}); err != nil {
+		t.Error(err)
+	}
+	if strings.Contains(resp.Header.Get(headers.NameTricksterResult), "fetched") {
+		t.Errorf("unexpected fetched ranges: %s", resp.Header.Get(headers.NameTricksterResult))
+	}
+
+	expectedGaps := fmt.Sprintf("gaps=[%d:%d]", gapStart.Unix(), normalizeTime(extr.End, step).Unix())
+	if v := resp.Header.Get(headers.NameTricksterDegraded); v != expectedGaps {
+		t.Errorf("expected %s got %s", expectedGaps, v)
+	}
+	if v := resp.Header.Get(headers.NameWarning); !strings.HasPrefix(v, "199 trickster") {
+		t.Errorf("unexpected warning header %s", v)
+	}
+	if v := resp.Header.Get(headers.NameCacheControl); v != headers.ValueNoCache {
+		t.Errorf("expected %s got %s", headers.ValueNoCache, v)
+	}
+}
+
+func TestDeltaProxyCacheRequestSnapshotDocument(t *testing.T) {
+
+	ts, _, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	rsc.CacheConfig.CacheType = "memory"
+	oc.FastForwardDisable = true
+	// a max age makes the origin record the keys it writes
+	oc.TimeseriesMaxAge = time.Duration(720) * time.Hour
+
+	step := time.Duration(300) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(6) * time.Hour), End: end}
+	extn := timeseries.Extent{Start: extr.Start.Truncate(step), End: extr.End.Truncate(step)}
+	expected, _, _ := mockprom.GetTimeSeriesData(queryReturnsOKNoLatency, extn.Start, extn.End, step)
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	request := func(expectedStatus string) {
+		w := httptest.NewRecorder()
+		client.QueryRangeHandler(w, r)
+		resp := w.Result()
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if err = testStringMatch(string(bodyBytes), expected); err != nil {
+			t.Error(err)
+		}
+		err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": expectedStatus})
+		if err != nil {
+			t.Error(err)
+		}
+		// Give time for the object to be written to cache in a separate goroutine from response
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	request("kmiss")
+
+	// replace the cached reference with its serialized form, as restored from a snapshot
+	mc := rsc.CacheClient.(cache.MemoryCache)
+	var key string
+	for k := range oc.RetainedKeys().Keys(time.Now()) {
+		key = k
+	}
+	ifc, _, err := mc.RetrieveReference(key, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ifc.(*HTTPDocument).MarshalSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc.Remove(key)
+	mc.Store(key, b, time.Minute)
+
+	request("hit")
+}
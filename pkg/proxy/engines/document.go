@@ -27,6 +27,7 @@ import (
 
 	txe "github.com/tricksterproxy/trickster/pkg/proxy/errors"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
 	"github.com/tricksterproxy/trickster/pkg/proxy/ranges/byterange"
 	"github.com/tricksterproxy/trickster/pkg/timeseries"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
@@ -50,12 +51,64 @@ type HTTPDocument struct {
 	StoredRangeParts map[string]*byterange.MultipartByteRange `msg:"range_parts"`
 
 	rangePartsLoaded bool
-	isFulfillment    bool
 	isLoaded         bool
+	// bodyEncoding is the content coding of a body read from the cache still compressed,
+	// to be passed through to a client that accepts it
+	bodyEncoding     string
 	timeseries       timeseries.Timeseries
+	timeseriesClient origins.TimeseriesClient
 	headerLock       sync.Mutex
 }
 
+var errNoTimeseriesClient = errors.New("document has no client to marshal its timeseries")
+
+// setTimeseries sets the timeseries that the document holds by reference in a memory cache,
+// along with the client that can marshal it when the cache is snapshotted
+func (d *HTTPDocument) setTimeseries(client origins.TimeseriesClient, ts timeseries.Timeseries) {
+	d.timeseries = ts
+	d.timeseriesClient = client
+}
+
+// cachedTimeseries returns the timeseries held by a document read from the cache, by
+// reference from a memory cache, or otherwise by unmarshaling the document's body. Documents
+// restored from a memory cache snapshot hold a body until they are rewritten
+func cachedTimeseries(client origins.TimeseriesClient,
+	d *HTTPDocument) (timeseries.Timeseries, error) {
+	if d.timeseries != nil {
+		return d.timeseries, nil
+	}
+	return unmarshalCachedTimeseries(client, d.Body)
+}
+
+// MarshalSnapshot serializes the document as it is stored in non-memory caches, so that it
+// can be saved in a memory cache snapshot. A timeseries held by reference is marshaled into
+// the body of the serialized document
+func (d *HTTPDocument) MarshalSnapshot() ([]byte, error) {
+	sd := &HTTPDocument{
+		StatusCode:       d.StatusCode,
+		Status:           d.Status,
+		Headers:          d.SafeHeaderClone(),
+		Body:             d.Body,
+		ContentLength:    d.ContentLength,
+		ContentType:      d.ContentType,
+		CachingPolicy:    d.CachingPolicy,
+		Ranges:           d.Ranges,
+		StoredRangeParts: d.StoredRangeParts,
+	}
+	if d.timeseries != nil {
+		if d.timeseriesClient == nil {
+			return nil, errNoTimeseriesClient
+		}
+		b, err := marshalCachedTimeseries(d.timeseriesClient, d.timeseries)
+		if err != nil {
+			return nil, err
+		}
+		sd.Body = b
+	}
+	// the leading 0 is the flag for an uncompressed document
+	return sd.MarshalMsg(make([]byte, 1, sd.Msgsize()+1))
+}
+
 // SafeHeaderClone returns a threadsafe copy of the Document Header
 func (d *HTTPDocument) SafeHeaderClone() http.Header {
 	d.headerLock.Lock()
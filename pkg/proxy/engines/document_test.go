@@ -230,3 +230,28 @@ func TestLoadRangeParts(t *testing.T) {
 	}
 
 }
+
+func TestMarshalSnapshot(t *testing.T) {
+
+	d := &HTTPDocument{StatusCode: 200, Headers: http.Header{"Test": {"1"}}, Body: []byte("1234")}
+	b, err := d.MarshalSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b[0] != 0 {
+		t.Errorf("expected uncompressed flag got %d", b[0])
+	}
+	d2 := &HTTPDocument{}
+	if _, err = d2.UnmarshalMsg(b[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if string(d2.Body) != "1234" || d2.StatusCode != 200 || d2.Headers["Test"][0] != "1" {
+		t.Errorf("unexpected document %v", d2)
+	}
+
+	// a timeseries can't be marshaled without its client
+	d.setTimeseries(nil, &MatrixEnvelope{})
+	if _, err = d.MarshalSnapshot(); err != errNoTimeseriesClient {
+		t.Errorf("expected %v got %v", errNoTimeseriesClient, err)
+	}
+}
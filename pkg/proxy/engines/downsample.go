@@ -0,0 +1,174 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"context"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	ds "github.com/tricksterproxy/trickster/pkg/timeseries/downsample/options"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// selectDownsampleTier raises the step of the time range query to that of the downsample
+// tier holding the oldest part of its range, so that long look-backs are served from the
+// tier's cache entry at the best resolution available for the whole range. It returns
+// true when the step was changed
+func selectDownsampleTier(pr *proxyRequest, trq *timeseries.TimeRangeQuery, now time.Time) bool {
+	rsc := request.GetResources(pr.Request)
+	t := rsc.OriginConfig.DownsampleTierList.ForAge(now.Sub(trq.Extent.Start))
+	if t == nil || t.Step <= trq.Step {
+		return false
+	}
+	requestedStep := trq.Step
+	if !setQueryStep(pr, trq, t.Step) {
+		return false
+	}
+	pr.Logger.Debug("serving query from downsample tier",
+		tl.Pairs{"tier": t.Name, "requestedStep": requestedStep, "step": t.Step})
+	return true
+}
+
+// downsampleTimeseries moves the values in the cacheable timeseries that are older than
+// the first downsample tier coarser than its step into the cache entries of the tiers,
+// re-stored at their coarser steps. Since queries reaching that far back are served from
+// the tiers, the timeseries is cropped to the remaining range, which keeps the size of
+// each cache entry bounded
+func downsampleTimeseries(ctx context.Context, pr *proxyRequest, trq *timeseries.TimeRangeQuery,
+	cts timeseries.Timeseries, doc *HTTPDocument, now time.Time) {
+
+	rsc := request.GetResources(pr.Request)
+	tiers := rsc.OriginConfig.DownsampleTierList.CoarserThan(trq.Step)
+	if len(tiers) == 0 {
+		return
+	}
+	stepper, ok := rsc.OriginClient.(origins.TimeseriesStepper)
+	if !ok {
+		return
+	}
+	if _, ok := cts.(timeseries.Downsampler); !ok {
+		return
+	}
+
+	el := cts.Extents()
+	if len(el) == 0 {
+		return
+	}
+	boundary := now.Add(-tiers[0].MinAge).Truncate(tiers[0].Step)
+	if !el[0].Start.Before(boundary) {
+		return
+	}
+
+	// each tier receives the values between its own boundary and that of the next tier
+	for i, t := range tiers {
+		if t.Step%trq.Step != 0 {
+			// the timeseries does not include every timestamp at the tier's step
+			continue
+		}
+		e := timeseries.Extent{
+			Start: time.Unix(0, 0),
+			End:   now.Add(-t.MinAge).Truncate(t.Step).Add(-trq.Step),
+		}
+		if i+1 < len(tiers) {
+			e.Start = now.Add(-tiers[i+1].MinAge).Truncate(tiers[i+1].Step)
+		}
+		if el.OutsideOf(e) {
+			continue
+		}
+		rts := cts.Clone()
+		rts.CropToRange(e)
+		dts := rts.(timeseries.Downsampler).Downsample(t.Step)
+		if len(dts.Extents()) == 0 {
+			continue
+		}
+		storeDownsampledTimeseries(ctx, pr, stepper, trq, t, dts, doc, now)
+	}
+
+	cts.CropToRange(timeseries.Extent{Start: boundary, End: el[len(el)-1].End})
+}
+
+// storeDownsampledTimeseries merges the downsampled timeseries into the cache entry of the
+// downsample tier, which is keyed by the request with its step changed to the tier's step
+func storeDownsampledTimeseries(ctx context.Context, pr *proxyRequest, stepper origins.TimeseriesStepper,
+	trq *timeseries.TimeRangeQuery, t *ds.Options, dts timeseries.Timeseries, rdoc *HTTPDocument,
+	now time.Time) {
+
+	rsc := request.GetResources(pr.Request)
+	oc := rsc.OriginConfig
+	cache := rsc.CacheClient
+	client := rsc.OriginClient.(origins.TimeseriesClient)
+
+	rq := pr.Clone()
+	stepper.SetStep(rq.upstreamRequest, trq, t.Step)
+	key := oc.CacheKeyPrefix + ".dpc." + rq.DeriveCacheKey(trq.TemplateURL, "")
+
+	nl, _ := cache.Locker().Acquire(key)
+	defer nl.Release()
+
+	var ts timeseries.Timeseries
+	doc, _, _, err := QueryCache(ctx, cache, key, nil)
+	if err == nil && doc != nil {
+		ts, err = cachedTimeseries(client, doc)
+	}
+	if err == nil && doc != nil && doc.timeseries != nil {
+		// the cached reference may still be read by requests that loaded it earlier
+		ts = ts.Clone()
+	}
+	if err != nil || ts == nil {
+		doc = &HTTPDocument{
+			Status:     rdoc.Status,
+			StatusCode: rdoc.StatusCode,
+			Headers:    rdoc.SafeHeaderClone(),
+		}
+		ts = dts
+	} else {
+		ts.Merge(true, dts)
+	}
+
+	// the tier is held to the origin's retention factor at the tier's step
+	ts.CropToRange(timeseries.Extent{
+		Start: now.Truncate(t.Step).Add(-(t.Step * oc.TimeseriesRetention)),
+		End:   now,
+	})
+	if len(ts.Extents()) == 0 {
+		return
+	}
+
+	if rsc.CacheConfig.CacheType == "memory" {
+		doc.setTimeseries(client, ts)
+	} else {
+		doc.Body, err = marshalCachedTimeseries(client, ts)
+		if err != nil {
+			pr.Logger.Error("error marshaling timeseries",
+				tl.Pairs{"cacheKey": key, "detail": err.Error()})
+			return
+		}
+	}
+	if err = WriteCache(ctx, cache, key, doc, oc.TimeseriesTTL, oc.CompressableTypes); err != nil {
+		pr.Logger.Error("error writing object to cache",
+			tl.Pairs{
+				"originName": oc.Name,
+				"cacheName":  cache.Configuration().Name,
+				"cacheKey":   key,
+				"detail":     err.Error(),
+			},
+		)
+	}
+}
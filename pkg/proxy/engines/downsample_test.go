@@ -0,0 +1,190 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	ds "github.com/tricksterproxy/trickster/pkg/timeseries/downsample/options"
+
+	mockprom "github.com/tricksterproxy/mockster/pkg/mocks/prometheus"
+)
+
+func testDownsampleTiers() ds.Tiers {
+	return ds.NewTiers(map[string]*ds.Options{
+		"1h": {Name: "1h", Step: time.Hour, MinAge: 24 * time.Hour},
+	})
+}
+
+func TestDeltaProxyCacheRequestDownsampleTier(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	oc.FastForwardDisable = true
+	oc.DownsampleTierList = testDownsampleTiers()
+	rsc.PathConfig.CacheKeyParams = []string{upQuery, upStep}
+
+	step := time.Duration(300) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(18) * time.Hour), End: end}
+
+	// the start of the query is older than the tier's min age, so it is served at its step
+	expected, _, _ := mockprom.GetTimeSeriesData(queryReturnsOKNoLatency,
+		normalizeTime(extr.Start, time.Hour), normalizeTime(extr.End, time.Hour), time.Hour)
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testStringMatch(string(bodyBytes), expected)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusOK)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": "kmiss"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	// the start of the query is within the tier's min age, so it is served as requested
+	extr.Start = end.Add(-time.Duration(6) * time.Hour)
+	expected, _, _ = mockprom.GetTimeSeriesData(queryReturnsOKNoLatency,
+		normalizeTime(extr.Start, step), normalizeTime(extr.End, step), step)
+
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+	r.URL = u
+
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	resp = w.Result()
+
+	bodyBytes, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testStringMatch(string(bodyBytes), expected)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDownsampleTimeseries(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	oc.DownsampleTierList = testDownsampleTiers()
+	rsc.PathConfig.CacheKeyParams = []string{upQuery, upStep}
+
+	step := time.Duration(300) * time.Second
+	now := normalizeTime(time.Now(), time.Hour)
+	e := timeseries.Extent{Start: now.Add(-time.Duration(48) * time.Hour), End: now}
+
+	r.URL.Path = "/prometheus/api/v1/query_range"
+	r.URL.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), e.Start.Unix(), e.End.Unix(), queryReturnsOKNoLatency)
+
+	pr := newProxyRequest(r, w)
+	trq, err := client.ParseTimeRangeQuery(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _, _ := mockprom.GetTimeSeriesData(queryReturnsOKNoLatency, e.Start, e.End, step)
+	cts, err := client.UnmarshalTimeseries([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cts.SetExtents(timeseries.ExtentList{e})
+	cts.SetStep(step)
+
+	doc := &HTTPDocument{StatusCode: http.StatusOK, Status: "200 OK", Headers: http.Header{}}
+	downsampleTimeseries(r.Context(), pr, trq, cts, doc, now)
+
+	// the cached timeseries keeps only the values within the tier's min age
+	expected := timeseries.ExtentList{{Start: now.Add(-time.Duration(24) * time.Hour), End: now}}
+	if cts.Extents().String() != expected.String() {
+		t.Errorf("expected %s got %s", expected, cts.Extents())
+	}
+
+	// and the older values are re-stored in the tier's cache entry at its step
+	rq := pr.Clone()
+	client.SetStep(rq.upstreamRequest, trq, time.Hour)
+	key := oc.CacheKeyPrefix + ".dpc." + rq.DeriveCacheKey(trq.TemplateURL, "")
+	tdoc, _, _, err := QueryCache(r.Context(), rsc.CacheClient, key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tts := tdoc.timeseries
+	if tts == nil {
+		tts, err = unmarshalCachedTimeseries(client, tdoc.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	te := timeseries.Extent{Start: e.Start, End: now.Add(-time.Duration(25) * time.Hour)}
+	expected = timeseries.ExtentList{te}
+	if tts.Extents().String() != expected.String() {
+		t.Errorf("expected %s got %s", expected, tts.Extents())
+	}
+
+	tts = tts.Clone()
+	tts.SetExtents(nil)
+	tts.SetStep(0)
+	b, err := client.MarshalTimeseries(tts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedBody, _, _ := mockprom.GetTimeSeriesData(queryReturnsOKNoLatency, te.Start, te.End, time.Hour)
+	if err = testStringMatch(string(b), expectedBody); err != nil {
+		t.Error(err)
+	}
+}
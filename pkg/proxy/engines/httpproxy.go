@@ -18,6 +18,7 @@ package engines
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"io/ioutil"
 	"math"
@@ -28,6 +29,8 @@ import (
 	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	"github.com/tricksterproxy/trickster/pkg/proxy/concurrency"
+	tctx "github.com/tricksterproxy/trickster/pkg/proxy/context"
 	"github.com/tricksterproxy/trickster/pkg/proxy/forwarding"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/methods"
@@ -35,6 +38,7 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	"github.com/tricksterproxy/trickster/pkg/timeseries"
 	"github.com/tricksterproxy/trickster/pkg/tracing"
+	tpropagation "github.com/tricksterproxy/trickster/pkg/tracing/propagation"
 	tspan "github.com/tricksterproxy/trickster/pkg/tracing/span"
 	"github.com/tricksterproxy/trickster/pkg/util/log"
 	"github.com/tricksterproxy/trickster/pkg/util/metrics"
@@ -56,6 +60,13 @@ func DoProxy(w io.Writer, r *http.Request, closeResponse bool) *http.Response {
 	rsc := request.GetResources(r)
 	oc := rsc.OriginConfig
 
+	if tctx.Inspection(r.Context()) {
+		in := newInspection(rsc, "HTTPProxy")
+		in.CacheName = ""
+		writeInspection(w, in)
+		return nil
+	}
+
 	start := time.Now()
 
 	var span trace.Span
@@ -74,12 +85,12 @@ func DoProxy(w io.Writer, r *http.Request, closeResponse bool) *http.Response {
 	var reader io.ReadCloser
 
 	if pc == nil || pc.CollapsedForwardingType != forwarding.CFTypeProgressive ||
-		!methods.IsCacheable(r.Method) {
-		reader, resp, _ = PrepareFetchReader(r)
+		!methods.IsCacheable(r.Method) || pc.Streaming {
+		reader, resp, _ = prepareFetchReader(r, true)
 		cacheStatusCode = setStatusHeader(resp.StatusCode, resp.Header)
 		writer := PrepareResponseWriter(w, resp.StatusCode, resp.Header)
 		if writer != nil && reader != nil {
-			io.Copy(writer, reader)
+			streamResponse(writer, reader, resp, pc != nil && pc.Streaming)
 		}
 	} else {
 		pr := newProxyRequest(r, w)
@@ -87,11 +98,11 @@ func DoProxy(w io.Writer, r *http.Request, closeResponse bool) *http.Response {
 		result, ok := reqs.Load(key)
 		if !ok {
 			var contentLength int64
-			reader, resp, contentLength = PrepareFetchReader(r)
+			reader, resp, contentLength = prepareFetchReader(r, true)
 			cacheStatusCode = setStatusHeader(resp.StatusCode, resp.Header)
 			writer := PrepareResponseWriter(w, resp.StatusCode, resp.Header)
 			// Check if we know the content length and if it is less than our max object size.
-			if contentLength != 0 && contentLength < int64(oc.MaxObjectSizeBytes) {
+			if contentLength > 0 && contentLength < int64(oc.MaxObjectSizeBytes) {
 				pcf := NewPCF(resp, contentLength)
 				reqs.Store(key, pcf)
 				// Blocks until server completes
@@ -106,6 +117,9 @@ func DoProxy(w io.Writer, r *http.Request, closeResponse bool) *http.Response {
 					}
 				}()
 				pcf.AddClient(writer)
+			} else if writer != nil && reader != nil {
+				// responses of unknown or large size are streamed to the client without collapsing
+				streamResponse(writer, reader, resp, false)
 			}
 		} else {
 			pcf, _ := result.(ProgressiveCollapseForwarder)
@@ -142,6 +156,14 @@ func PrepareResponseWriter(w io.Writer, code int, header http.Header) io.Writer
 // provide the response data, the response object and the content length.
 // Used in Fetch.
 func PrepareFetchReader(r *http.Request) (io.ReadCloser, *http.Response, int64) {
+	return prepareFetchReader(r, false)
+}
+
+// prepareFetchReader is PrepareFetchReader. When stream is true, the origin's timeout only
+// limits the wait for the response headers, so that long-lived responses are not cut off.
+// For streaming paths, the path's idle timeout instead limits the wait for the headers and
+// between each read of the response body
+func prepareFetchReader(r *http.Request, stream bool) (io.ReadCloser, *http.Response, int64) {
 
 	rsc := request.GetResources(r)
 	oc := rsc.OriginConfig
@@ -165,28 +187,72 @@ func PrepareFetchReader(r *http.Request) (io.ReadCloser, *http.Response, int64)
 	r.Close = false
 	r.RequestURI = ""
 
+	ctx, doSpan := tspan.NewChildSpan(ctx, rsc.Tracer, "ProxyRequest")
+	if doSpan != nil {
+		defer doSpan.End()
+	}
+
 	if rsc.Tracer != nil {
 		// Processing traces for proxies
 		// https://www.w3.org/TR/trace-context-1/#alternative-processing
+		// the origin request is a child of the ProxyRequest span
 		ctx, r = othttptrace.W3C(ctx, r)
-		othttptrace.Inject(ctx, r)
-	}
-
-	ctx, doSpan := tspan.NewChildSpan(r.Context(), rsc.Tracer, "ProxyRequest")
-	if doSpan != nil {
-		defer doSpan.End()
+		tpropagation.Inject(ctx, rsc.Tracer.Propagators, r.Header)
 	}
 
 	// clear the Host header before proxying or it will be forwarded upstream
 	r.Host = ""
 
-	resp, err := oc.HTTPClient.Do(r)
+	client := oc.HTTPClient
+	// timeout is how long Trickster will wait for the origin's response headers
+	timeout := client.Timeout
+	var wd *idleWatchdog
+	if stream {
+		if client.Timeout > 0 {
+			c := *client
+			c.Timeout = 0
+			client = &c
+		}
+		headerTimeout, idleTimeout := oc.Timeout, time.Duration(0)
+		if pc != nil && pc.Streaming {
+			idleTimeout = oc.Timeout
+			if pc.StreamIdleTimeout > 0 {
+				idleTimeout = pc.StreamIdleTimeout
+			}
+			headerTimeout = idleTimeout
+		}
+		wd, r = newIdleWatchdog(r, headerTimeout, idleTimeout)
+		timeout = headerTimeout
+	}
+
+	// the origin is told when Trickster will give up on the request, so it can too
+	if oc.DeadlineHeader != "" && timeout > 0 {
+		r = r.WithContext(tctx.WithOriginDeadline(r.Context(), time.Now().Add(timeout)))
+	}
+
+	resp, err := client.Do(r)
+	if wd != nil {
+		if err != nil {
+			wd.Close()
+		} else {
+			resp.Body = wd.watch(resp.Body)
+		}
+	}
+	// the caching engines count the bytes they read from the response against the memory budget
+	if mr, ok := tctx.MemoryReservation(r.Context()).(*memoryReservation); ok && err == nil {
+		resp.Body = &budgetedReader{ReadCloser: resp.Body, memory: mr}
+	}
 	if err != nil {
 		rsc.Logger.Error("error downloading url", log.Pairs{"url": r.URL.String(), "detail": err.Error()})
 		// if there is an err and the response is nil, the server could not be reached
 		// so make a 502 for the downstream response
 		if resp == nil {
-			resp = &http.Response{StatusCode: http.StatusBadGateway, Request: r, Header: make(http.Header)}
+			sc := http.StatusBadGateway
+			// requests shed by the origin's adaptive concurrency limit may be retried
+			if errors.Is(err, concurrency.ErrLimitExceeded) {
+				sc = http.StatusServiceUnavailable
+			}
+			resp = &http.Response{StatusCode: sc, Request: r, Header: make(http.Header)}
 		}
 
 		if pc != nil {
@@ -250,6 +316,29 @@ func PrepareFetchReader(r *http.Request) (io.ReadCloser, *http.Response, int64)
 	return rc, resp, originalLen
 }
 
+// streamResponse copies the response body to the client. Streaming paths, event streams and
+// responses of unknown length are flushed to the client as they are read, when the writer
+// supports it
+func streamResponse(w io.Writer, reader io.Reader, resp *http.Response, flush bool) {
+	if f, ok := w.(http.Flusher); ok && (flush || resp.ContentLength < 0 ||
+		strings.HasPrefix(resp.Header.Get(headers.NameContentType), headers.ValueTextEventStream)) {
+		w = &flushWriter{w: w, f: f}
+	}
+	io.Copy(w, reader)
+}
+
+// flushWriter flushes each write to the client
+type flushWriter struct {
+	w io.Writer
+	f http.Flusher
+}
+
+func (fw *flushWriter) Write(b []byte) (int, error) {
+	n, err := fw.w.Write(b)
+	fw.f.Flush()
+	return n, err
+}
+
 // Respond sends an HTTP Response down to the requesting client
 func Respond(w io.Writer, code int, header http.Header, body []byte) {
 	PrepareResponseWriter(w, code, header)
@@ -278,9 +367,21 @@ func recordResults(r *http.Request, engine string, cacheStatus status.LookupStat
 		httpStatus := strconv.Itoa(statusCode)
 		metrics.ProxyRequestStatus.WithLabelValues(oc.Name, oc.OriginType, r.Method, status, httpStatus, path).Inc()
 		if elapsed > 0 {
-			metrics.ProxyRequestDuration.WithLabelValues(oc.Name, oc.OriginType,
-				r.Method, status, httpStatus, path).Observe(elapsed)
+			var traceID string
+			if sc := trace.SpanFromContext(r.Context()).SpanContext(); !oc.ExemplarsDisabled && sc.IsSampled() {
+				traceID = sc.TraceID.String()
+			}
+			metrics.ObserveWithTraceID(metrics.ProxyRequestDuration.WithLabelValues(oc.Name,
+				oc.OriginType, r.Method, status, httpStatus, path), elapsed, traceID)
 		}
 	}
 	headers.SetResultsHeader(header, engine, status, ffStatus, extents)
+
+	if rsc.Tracer != nil {
+		res := tspan.Results{CacheStatus: status, Fetched: extents}
+		if rsc.TimeRangeQuery != nil {
+			res.Requested = &rsc.TimeRangeQuery.Extent
+		}
+		tspan.SetResultAttributes(rsc.Tracer, trace.SpanFromContext(r.Context()), res)
+	}
 }
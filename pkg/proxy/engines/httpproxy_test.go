@@ -21,10 +21,12 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/proxy"
 	tc "github.com/tricksterproxy/trickster/pkg/proxy/context"
 	"github.com/tricksterproxy/trickster/pkg/proxy/forwarding"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
@@ -303,3 +305,185 @@ func TestPrepareFetchReaderErr(t *testing.T) {
 		t.Errorf("expected 0 got %d", i)
 	}
 }
+
+func TestPrepareFetchReaderDeadlineHeader(t *testing.T) {
+
+	var v string
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v = r.Header.Get("X-Request-Timeout")
+	}))
+	defer es.Close()
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-origin-url", es.URL, "-origin-type", "test", "-log-level", "debug"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	oc := conf.Origins["default"]
+	oc.DeadlineHeader = "X-Request-Timeout"
+	oc.HTTPClient, err = proxy.NewHTTPClient(oc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", es.URL, nil)
+	r = r.WithContext(tc.WithResources(r.Context(),
+		request.NewResources(oc, nil, nil, nil, nil, nil, testLogger)))
+	rc, _, _ := PrepareFetchReader(r)
+	if rc == nil {
+		t.Fatal("expected response body")
+	}
+	rc.Close()
+
+	timeout := int(oc.Timeout / time.Millisecond)
+	if ms, _ := strconv.Atoi(v); ms <= timeout-1000 || ms > timeout {
+		t.Errorf("expected about %d got %s", timeout, v)
+	}
+}
+
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed chan string
+}
+
+func (f *flushRecorder) Flush() {
+	f.ResponseRecorder.Flush()
+	f.flushed <- f.Body.String()
+}
+
+func TestDoProxyStream(t *testing.T) {
+
+	release := make(chan struct{})
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headers.NameContentType, headers.ValueTextEventStream)
+		w.Write([]byte("data: 1\n\n"))
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte("data: 2\n\n"))
+	}))
+	defer es.Close()
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-origin-url", es.URL, "-origin-type", "test", "-log-level", "debug"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	for _, cf := range []forwarding.CollapsedForwardingType{forwarding.CFTypeBasic,
+		forwarding.CFTypeProgressive} {
+
+		oc := conf.Origins["default"]
+		pc := &po.Options{
+			Path:                    "/",
+			RequestHeaders:          map[string]string{},
+			ResponseHeaders:         map[string]string{},
+			CollapsedForwardingType: cf,
+		}
+
+		// the client timeout does not cut off the streamed response
+		oc.HTTPClient = &http.Client{Timeout: 50 * time.Millisecond}
+		w := &flushRecorder{ResponseRecorder: httptest.NewRecorder(), flushed: make(chan string, 10)}
+		r := httptest.NewRequest("GET", es.URL, nil)
+		r = r.WithContext(tc.WithResources(r.Context(),
+			request.NewResources(oc, pc, nil, nil, nil, nil, testLogger)))
+
+		done := make(chan struct{})
+		go func() {
+			DoProxy(w, r, true)
+			close(done)
+		}()
+
+		// the first event is received before the origin completes the response
+		select {
+		case s := <-w.flushed:
+			if s != "data: 1\n\n" {
+				t.Errorf("expected %q got %q", "data: 1\n\n", s)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the first event")
+		}
+
+		time.Sleep(100 * time.Millisecond)
+		release <- struct{}{}
+		<-done
+
+		if s := w.Body.String(); s != "data: 1\n\ndata: 2\n\n" {
+			t.Errorf("expected %q got %q", "data: 1\n\ndata: 2\n\n", s)
+		}
+	}
+}
+
+func TestDoProxyStreamingPath(t *testing.T) {
+
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/watch" {
+			// a long-poll that waits longer than the origin timeout before responding
+			time.Sleep(150 * time.Millisecond)
+			w.Header().Set(headers.NameContentLength, "10")
+			w.Write([]byte("event-1\n"))
+			w.(http.Flusher).Flush()
+			time.Sleep(150 * time.Millisecond)
+			w.Write([]byte("e2"))
+			return
+		}
+		// a stream that stalls after its first chunk
+		w.Write([]byte("chunk-1\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer es.Close()
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-origin-url", es.URL, "-origin-type", "test", "-log-level", "debug"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	oc := conf.Origins["default"]
+	oc.HTTPClient = http.DefaultClient
+	oc.Timeout = 50 * time.Millisecond
+
+	pc := &po.Options{
+		Path:                    "/",
+		RequestHeaders:          map[string]string{},
+		ResponseHeaders:         map[string]string{},
+		CollapsedForwardingType: forwarding.CFTypeProgressive,
+		Streaming:               true,
+		StreamIdleTimeout:       time.Second,
+	}
+
+	w := &flushRecorder{ResponseRecorder: httptest.NewRecorder(), flushed: make(chan string, 10)}
+	r := httptest.NewRequest("GET", es.URL+"/watch", nil)
+	r = r.WithContext(tc.WithResources(r.Context(),
+		request.NewResources(oc, pc, nil, nil, nil, nil, testLogger)))
+
+	// the idle timeout overrides the origin timeout, and each chunk is flushed
+	// even though the response length is known
+	DoProxy(w, r, true)
+	if s := w.Body.String(); s != "event-1\ne2" {
+		t.Errorf("expected %q got %q", "event-1\ne2", s)
+	}
+	if len(w.flushed) == 0 {
+		t.Error("expected response to be flushed")
+	}
+
+	// the stream is ended when it is idle for longer than the idle timeout
+	pc.StreamIdleTimeout = 100 * time.Millisecond
+	w = &flushRecorder{ResponseRecorder: httptest.NewRecorder(), flushed: make(chan string, 10)}
+	r = httptest.NewRequest("GET", es.URL+"/stall", nil)
+	r = r.WithContext(tc.WithResources(r.Context(),
+		request.NewResources(oc, pc, nil, nil, nil, nil, testLogger)))
+
+	start := time.Now()
+	DoProxy(w, r, true)
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("expected the idle stream to end, took %s", d)
+	}
+	if s := w.Body.String(); s != "chunk-1\n" {
+		t.Errorf("expected %q got %q", "chunk-1\n", s)
+	}
+}
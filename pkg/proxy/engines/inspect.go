@@ -0,0 +1,166 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+// Inspection reports how the caching engines would serve an inspected request, without
+// fetching anything from the origin or writing to the cache
+type Inspection struct {
+	OriginName     string                `json:"origin_name"`
+	OriginType     string                `json:"origin_type"`
+	Engine         string                `json:"engine"`
+	CacheName      string                `json:"cache_name,omitempty"`
+	CacheKey       string                `json:"cache_key,omitempty"`
+	CacheStatus    string                `json:"cache_status,omitempty"`
+	Query          *InspectedQuery       `json:"query,omitempty"`
+	CachedExtents  timeseries.ExtentList `json:"cached_extents,omitempty"`
+	MissingExtents timeseries.ExtentList `json:"missing_extents,omitempty"`
+	Settings       *InspectedSettings    `json:"settings,omitempty"`
+	Warning        string                `json:"warning,omitempty"`
+}
+
+// InspectedQuery is the time range query of an inspected timeseries request, after it has
+// been clamped to the time range limits and aligned to the step
+type InspectedQuery struct {
+	Statement string    `json:"statement"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Step      string    `json:"step"`
+}
+
+// InspectedSettings are the cache settings that apply to an inspected request
+type InspectedSettings struct {
+	TimeseriesTTL       string     `json:"timeseries_ttl,omitempty"`
+	BackfillTolerance   string     `json:"backfill_tolerance,omitempty"`
+	BackfillCutoff      *time.Time `json:"backfill_cutoff,omitempty"`
+	FastForwardDisabled bool       `json:"fast_forward_disabled,omitempty"`
+	ShardWindow         string     `json:"shard_window,omitempty"`
+	EvictionMethod      string     `json:"eviction_method,omitempty"`
+	MaxTTL              string     `json:"max_ttl,omitempty"`
+}
+
+func newInspection(rsc *request.Resources, engine string) *Inspection {
+	in := &Inspection{Engine: engine}
+	if rsc.OriginConfig != nil {
+		in.OriginName = rsc.OriginConfig.Name
+		in.OriginType = rsc.OriginConfig.OriginType
+	}
+	if rsc.CacheConfig != nil {
+		in.CacheName = rsc.CacheConfig.Name
+	}
+	return in
+}
+
+// writeInspection writes the inspection report in place of the response
+func writeInspection(w io.Writer, in *Inspection) {
+	if hw, ok := w.(http.ResponseWriter); ok {
+		hw.Header().Set(headers.NameContentType, headers.ValueApplicationJSON)
+		hw.WriteHeader(http.StatusOK)
+	}
+	b, _ := json.MarshalIndent(in, "", "  ")
+	w.Write(b)
+}
+
+// inspectTimeseries reports the cached extents of the timeseries request's cache object,
+// and the deltas that would be fetched from the origin to serve it
+func inspectTimeseries(w io.Writer, pr *proxyRequest, trq *timeseries.TimeRangeQuery,
+	key string, bt, sw time.Duration, backfillCutoff time.Time, warning string) {
+
+	rsc := request.GetResources(pr.Request)
+	oc := rsc.OriginConfig
+	client := rsc.OriginClient.(origins.TimeseriesClient)
+	ctx := pr.upstreamRequest.Context()
+
+	in := newInspection(rsc, "DeltaProxyCache")
+	in.CacheKey = key
+	in.Warning = warning
+	in.Query = &InspectedQuery{Statement: trq.Statement, Start: trq.Extent.Start,
+		End: trq.Extent.End, Step: trq.Step.String()}
+	in.Settings = &InspectedSettings{
+		TimeseriesTTL:       oc.TimeseriesTTL.String(),
+		BackfillTolerance:   bt.String(),
+		BackfillCutoff:      &backfillCutoff,
+		FastForwardDisabled: trq.FastForwardDisable,
+		EvictionMethod:      oc.TimeseriesEvictionMethod.String(),
+	}
+	if sw > 0 {
+		in.Settings.ShardWindow = sw.String()
+	}
+
+	var cts timeseries.Timeseries
+	var err error
+	cacheStatus := status.LookupStatusKeyMiss
+	if GetRequestCachingPolicy(pr.Header).NoCache {
+		cacheStatus = status.LookupStatusPurge
+	} else if sw > 0 {
+		cts, _, _, err = queryTimeseriesShards(ctx, pr, key, sw, trq)
+	} else {
+		var doc *HTTPDocument
+		doc, _, _, err = QueryCache(ctx, rsc.CacheClient, key, nil)
+		if err == nil && doc != nil {
+			cts, err = cachedTimeseries(client, doc)
+		}
+	}
+
+	in.MissingExtents = timeseries.ExtentList{trq.Extent}
+	if err == nil && cts != nil {
+		cts, _ = trimWrittenExtents(oc, trq, cts, time.Now())
+		in.CachedExtents = cts.Extents()
+		in.MissingExtents = trq.CalculateDeltas(in.CachedExtents)
+		switch {
+		case len(in.MissingExtents) == 0:
+			cacheStatus = status.LookupStatusHit
+		case len(in.MissingExtents) == 1 &&
+			in.MissingExtents[0].Start.Equal(trq.Extent.Start) &&
+			in.MissingExtents[0].End.Equal(trq.Extent.End):
+			cacheStatus = status.LookupStatusRangeMiss
+		default:
+			cacheStatus = status.LookupStatusPartialHit
+		}
+	}
+	in.CacheStatus = cacheStatus.String()
+	writeInspection(w, in)
+}
+
+// inspectObject reports the cache status of the object request's cache object, and
+// returns it
+func inspectObject(w io.Writer, pr *proxyRequest) status.LookupStatus {
+	rsc := request.GetResources(pr.Request)
+	in := newInspection(rsc, "ObjectProxyCache")
+	in.CacheKey = pr.key
+	in.Settings = &InspectedSettings{MaxTTL: rsc.OriginConfig.MaxTTL.String()}
+	cacheStatus := status.LookupStatusPurge
+	if !pr.cachingPolicy.NoCache {
+		_, cacheStatus, _, _ = QueryCache(pr.upstreamRequest.Context(), rsc.CacheClient,
+			pr.key, pr.wantedRanges)
+	}
+	in.CacheStatus = cacheStatus.String()
+	writeInspection(w, in)
+	return cacheStatus
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tctx "github.com/tricksterproxy/trickster/pkg/proxy/context"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+func inspect(t *testing.T, h http.HandlerFunc, r *http.Request) *Inspection {
+	w := httptest.NewRecorder()
+	h(w, r.WithContext(tctx.WithInspection(r.Context(), true)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	in := &Inspection{}
+	if err := json.Unmarshal(w.Body.Bytes(), in); err != nil {
+		t.Fatal(err)
+	}
+	return in
+}
+
+func TestInspectTimeseries(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	oc.FastForwardDisable = true
+	step := time.Duration(300) * time.Second
+
+	end := time.Now().Add(-time.Duration(12) * time.Hour).Truncate(step)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(6) * time.Hour), End: end}
+
+	r.URL.Path = "/prometheus/api/v1/query_range"
+	r.URL.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	in := inspect(t, client.QueryRangeHandler, r)
+	if in.Engine != "DeltaProxyCache" || in.CacheStatus != "kmiss" || in.CacheKey == "" {
+		t.Errorf("unexpected inspection %+v", in)
+	}
+	if len(in.MissingExtents) != 1 || !in.MissingExtents[0].Start.Equal(extr.Start) {
+		t.Errorf("expected missing extent %s got %v", extr, in.MissingExtents)
+	}
+	if in.Settings == nil || in.Settings.TimeseriesTTL != oc.TimeseriesTTL.String() {
+		t.Errorf("unexpected settings %+v", in.Settings)
+	}
+
+	// the inspection must not have written to the cache
+	if _, _, err := rsc.CacheClient.Retrieve(in.CacheKey, false); err == nil {
+		t.Error("expected inspection to leave the cache untouched")
+	}
+
+	client.QueryRangeHandler(w, r)
+	time.Sleep(time.Millisecond * 10)
+
+	if in = inspect(t, client.QueryRangeHandler, r); in.CacheStatus != "hit" ||
+		len(in.MissingExtents) != 0 || len(in.CachedExtents) != 1 {
+		t.Errorf("unexpected inspection %+v", in)
+	}
+
+	// extending the range back an hour should report a partial hit with a single delta
+	r.URL.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Add(-time.Hour).Unix(), extr.End.Unix(),
+		queryReturnsOKNoLatency)
+	in = inspect(t, client.QueryRangeHandler, r)
+	if in.CacheStatus != "phit" || len(in.MissingExtents) != 1 ||
+		!in.MissingExtents[0].End.Equal(extr.Start.Add(-step)) {
+		t.Errorf("unexpected inspection %+v", in)
+	}
+}
+
+func TestInspectObject(t *testing.T) {
+
+	ts, _, r, _, err := setupTestHarnessOPC("", "test", http.StatusOK, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+
+	in := inspect(t, ObjectProxyCacheRequest, r)
+	if in.Engine != "ObjectProxyCache" || in.CacheStatus != "kmiss" || in.CacheKey == "" {
+		t.Errorf("unexpected inspection %+v", in)
+	}
+
+	in = inspect(t, func(w http.ResponseWriter, r *http.Request) { DoProxy(w, r, true) }, r)
+	if in.Engine != "HTTPProxy" || in.CacheKey != "" {
+		t.Errorf("unexpected inspection %+v", in)
+	}
+}
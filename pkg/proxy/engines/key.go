@@ -27,8 +27,13 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/tinylib/msgp/msgp"
+	"github.com/tricksterproxy/trickster/pkg/cache/key"
 	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/params"
+	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	"github.com/tricksterproxy/trickster/pkg/util/md5"
 )
@@ -45,32 +50,38 @@ func (pr *proxyRequest) DeriveCacheKey(templateURL *url.URL, extra string) strin
 		return md5.Checksum(pr.URL.Path + extra)
 	}
 
-	var params url.Values
+	var qp url.Values
 	r := pr.Request
 
 	if pr.upstreamRequest != nil {
 		r = pr.upstreamRequest
 		if r.URL == nil {
 			r.URL = pr.URL
-			params = pr.URL.Query()
+			qp = pr.URL.Query()
 		}
 	}
 
 	var b []byte
-	if r.Method == http.MethodPost {
+	if _, ok := methodsWithBody[r.Method]; ok && r.Body != nil {
+		// the body is shared with the downstream request, so it must be restored
+		// for any method, not just POST, or it won't be proxied upstream
 		b, _ = ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+
+	if r.Method == http.MethodPost {
 		r.ParseForm()
-		params = r.PostForm
+		qp = r.PostForm
 		r.Body = ioutil.NopCloser(bytes.NewReader(b))
 	} else if templateURL != nil {
-		params = templateURL.Query()
+		qp = templateURL.Query()
 	} else if r.URL != nil {
-		params = r.URL.Query()
+		qp = r.URL.Query()
 	}
 
 	if pc.KeyHasher != nil && len(pc.KeyHasher) == 1 {
 		var k string
-		k, r.Body = pc.KeyHasher[0](r.URL.Path, params, r.Header, r.Body, extra)
+		k, r.Body = pc.KeyHasher[0](r.URL.Path, qp, r.Header, r.Body, extra)
 		return k
 	}
 
@@ -80,16 +91,30 @@ func (pr *proxyRequest) DeriveCacheKey(templateURL *url.URL, extra string) strin
 		vals = append(vals, fmt.Sprintf("%s.%s.", headers.NameAuthorization, v))
 	}
 
+	// partition the cache key by the request's principal, when the path is configured for it
+	if pc.Principal != nil {
+		if v, ok := pc.Principal.Principal(r); ok {
+			vals = append(vals, fmt.Sprintf("%s.%s.", "principal", v))
+			pr.hasPrincipal = true
+		}
+	}
+
+	// a path's cache key template replaces the fixed composition of the key
+	if pc.KeyTemplate != nil {
+		return md5.Checksum(pr.URL.Path + "." + strings.Join(vals, "") +
+			pr.executeKeyTemplate(pc, r, qp, b) + extra)
+	}
+
 	// Append the http method to the slice for creating the derived cache key
 	vals = append(vals, fmt.Sprintf("%s.%s.", "method", r.Method))
 
 	if len(pc.CacheKeyParams) == 1 && pc.CacheKeyParams[0] == "*" {
-		for p := range params {
-			vals = append(vals, fmt.Sprintf("%s.%s.", p, params.Get(p)))
+		for p := range qp {
+			vals = append(vals, fmt.Sprintf("%s.%s.", p, keyParamValue(pc, qp, p)))
 		}
 	} else {
 		for _, p := range pc.CacheKeyParams {
-			if v := params.Get(p); v != "" {
+			if v := keyParamValue(pc, qp, p); v != "" {
 				vals = append(vals, fmt.Sprintf("%s.%s.", p, v))
 			}
 		}
@@ -101,29 +126,9 @@ func (pr *proxyRequest) DeriveCacheKey(templateURL *url.URL, extra string) strin
 		}
 	}
 
-	if _, ok := methodsWithBody[r.Method]; ok && pc.CacheKeyFormFields != nil && len(pc.CacheKeyFormFields) > 0 {
-		ct := r.Header.Get(headers.NameContentType)
-		if ct == headers.ValueXFormURLEncoded ||
-			strings.HasPrefix(ct, headers.ValueMultipartFormData) || ct == headers.ValueApplicationJSON {
-			if strings.HasPrefix(ct, headers.ValueMultipartFormData) {
-				pr.ParseMultipartForm(1024 * 1024)
-			} else if ct == headers.ValueApplicationJSON {
-				var document map[string]interface{}
-				err := json.Unmarshal(b, &document)
-				if err == nil {
-					for _, f := range pc.CacheKeyFormFields {
-						v, err := deepSearch(document, f)
-						if err == nil {
-							if pr.Form == nil {
-								pr.Form = url.Values{}
-							}
-							pr.Form.Set(f, v)
-						}
-					}
-				}
-			}
-			pr.Body = ioutil.NopCloser(bytes.NewReader(b))
-		}
+	if _, ok := methodsWithBody[r.Method]; ok &&
+		(len(pc.CacheKeyFormFields) > 0 || len(pc.JSONPaths) > 0) {
+		pr.parseBodyFields(pc, r, b, pc.CacheKeyFormFields)
 
 		for _, f := range pc.CacheKeyFormFields {
 			if _, ok := pr.Form[f]; ok {
@@ -132,12 +137,169 @@ func (pr *proxyRequest) DeriveCacheKey(templateURL *url.URL, extra string) strin
 				}
 			}
 		}
+
+		for _, jp := range pc.JSONPaths {
+			if v, ok := pr.Form[jp.String()]; ok && len(v) > 0 && v[0] != "" {
+				vals = append(vals, fmt.Sprintf("%s.%s.", jp.String(), v[0]))
+			}
+		}
 	}
 
 	sort.Strings(vals)
 	return md5.Checksum(pr.URL.Path + "." + strings.Join(vals, "") + extra)
 }
 
+// executeKeyTemplate returns the composition of the request's cache key described by
+// the path's cache key template
+func (pr *proxyRequest) executeKeyTemplate(pc *po.Options, r *http.Request,
+	qp url.Values, b []byte) string {
+	fields := pc.KeyTemplate.Names(key.TemplateBody)
+	if _, ok := methodsWithBody[r.Method]; ok && len(fields) > 0 {
+		pr.parseBodyFields(pc, r, b, fields)
+	}
+	return pc.KeyTemplate.Execute(func(kind, name string) string {
+		switch kind {
+		case key.TemplateMethod:
+			return r.Method
+		case key.TemplateHost:
+			return pr.Host
+		case key.TemplateHeader:
+			return r.Header.Get(name)
+		case key.TemplateParam:
+			return normalizeKeyValue(pc, name, qp.Get(name))
+		case key.TemplateBody:
+			if _, ok := pr.Form[name]; ok {
+				return pr.FormValue(name)
+			}
+		}
+		return ""
+	})
+}
+
+// parseBodyFields populates the request's form with the named fields of the request body, which
+// is a form or a structured document, and with the values of the path's JSONPath expressions
+func (pr *proxyRequest) parseBodyFields(pc *po.Options, r *http.Request, b []byte, fields []string) {
+	ct := r.Header.Get(headers.NameContentType)
+	if ct == headers.ValueXFormURLEncoded ||
+		strings.HasPrefix(ct, headers.ValueMultipartFormData) || isDocumentBody(ct) {
+		if strings.HasPrefix(ct, headers.ValueMultipartFormData) {
+			// only text fields are parsed, so file parts are never buffered to disk
+			pr.Body = ioutil.NopCloser(bytes.NewReader(b))
+			if pr.Form == nil {
+				pr.Form = url.Values{}
+			}
+			for k, v := range params.GetRequestValues(pr.Request) {
+				pr.Form[k] = v
+			}
+		} else if isDocumentBody(ct) {
+			document, err := decodeBodyDocument(ct, b, pc.ProtobufMessage)
+			if err == nil {
+				if pr.Form == nil {
+					pr.Form = url.Values{}
+				}
+				for _, f := range fields {
+					v, err := deepSearch(document, f)
+					if err == nil {
+						pr.Form.Set(f, v)
+					}
+				}
+				// values extracted by JSONPath are stored in the form under their
+				// expression so that origins can also use them for time range parsing
+				for _, jp := range pc.JSONPaths {
+					v, err := jp.Get(document)
+					if err != nil {
+						continue
+					}
+					if s, ok := documentValueString(v); ok {
+						pr.Form.Set(jp.String(), s)
+					} else if j, err := json.Marshal(v); err == nil {
+						// objects and arrays are keyed by their json encoding,
+						// which is deterministic since map keys are sorted
+						pr.Form.Set(jp.String(), string(j))
+					}
+				}
+			}
+		}
+		pr.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+}
+
+// keyParamValue returns the normalized value of the named query parameter for the cache key.
+// Every value of a repeated parameter, like Prometheus' match[], is included in the key
+func keyParamValue(pc *po.Options, qp url.Values, name string) string {
+	vs := qp[name]
+	switch len(vs) {
+	case 0:
+		return ""
+	case 1:
+		if vs[0] == "" {
+			return ""
+		}
+		return normalizeKeyValue(pc, name, vs[0])
+	}
+	out := make([]string, len(vs))
+	for i, v := range vs {
+		out[i] = normalizeKeyValue(pc, name, v)
+	}
+	return strings.Join(out, "&")
+}
+
+// normalizeKeyValue returns the value of the named parameter as normalized by the
+// path's key normalizer for the parameter, if it has one
+func normalizeKeyValue(pc *po.Options, name, value string) string {
+	if n, ok := pc.KeyNormalizers[name]; ok && n != nil {
+		return n(value)
+	}
+	return value
+}
+
+// isDocumentBody returns true if the content type is a structured document
+// that can be decoded by decodeBodyDocument
+func isDocumentBody(ct string) bool {
+	return ct == headers.ValueApplicationJSON || ct == headers.ValueApplicationMsgPack ||
+		ct == headers.ValueApplicationProtobuf
+}
+
+// decodeBodyDocument decodes a JSON, MessagePack or Protocol Buffers request body
+// into a generic document that can be walked by deepSearch. Protocol Buffers bodies
+// require a message prototype to be registered in the Path Config
+func decodeBodyDocument(ct string, b []byte,
+	pm proto.Message) (map[string]interface{}, error) {
+
+	var document map[string]interface{}
+
+	switch ct {
+	case headers.ValueApplicationMsgPack:
+		v, _, err := msgp.ReadIntfBytes(b)
+		if err != nil {
+			return nil, err
+		}
+		var ok bool
+		if document, ok = v.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("invalid msgpack document type: %T", v)
+		}
+		return document, nil
+	case headers.ValueApplicationProtobuf:
+		if pm == nil {
+			return nil, fmt.Errorf("no protobuf message registered for content type: %s", ct)
+		}
+		m := proto.Clone(pm)
+		m.Reset()
+		if err := proto.Unmarshal(b, m); err != nil {
+			return nil, err
+		}
+		// round trip the message through its json tags to get a generic document
+		j, err := json.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+		b = j
+	}
+
+	err := json.Unmarshal(b, &document)
+	return document, err
+}
+
 func deepSearch(document map[string]interface{}, key string) (string, error) {
 
 	if key == "" {
@@ -159,18 +321,31 @@ func deepSearch(document map[string]interface{}, key string) (string, error) {
 			continue
 		}
 
-		if s, ok := v.(string); ok {
+		if s, ok := documentValueString(v); ok {
 			return s, nil
 		}
 
-		if i, ok := v.(float64); ok {
-			return strconv.FormatFloat(i, 'f', 4, 64), nil
-		}
-
-		if b, ok := v.(bool); ok {
-			return fmt.Sprintf("%t", b), nil
-		}
-
 	}
 	return "", errors.CouldNotFindKey(key)
 }
+
+// documentValueString returns the cache key representation of a scalar value
+// from a decoded body document, and false if the value is not a scalar
+func documentValueString(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, true
+	case float64:
+		return strconv.FormatFloat(t, 'f', 4, 64), true
+	case float32:
+		return strconv.FormatFloat(float64(t), 'f', 4, 64), true
+	// msgpack documents decode integers without converting them to float64
+	case int64:
+		return strconv.FormatFloat(float64(t), 'f', 4, 64), true
+	case uint64:
+		return strconv.FormatFloat(float64(t), 'f', 4, 64), true
+	case bool:
+		return fmt.Sprintf("%t", t), true
+	}
+	return "", false
+}
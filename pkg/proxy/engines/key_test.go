@@ -20,20 +20,27 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/tinylib/msgp/msgp"
 	"github.com/tricksterproxy/trickster/pkg/cache/key"
 	ct "github.com/tricksterproxy/trickster/pkg/proxy/context"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/proxy/tenancy"
+	"github.com/tricksterproxy/trickster/pkg/util/jsonpath"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
 	tu "github.com/tricksterproxy/trickster/pkg/util/testing"
 )
@@ -152,7 +159,8 @@ func TestDeriveCacheKey(t *testing.T) {
 		t.Errorf("expected %s got %s", "407aba34f02c87f6898a6d80b01f38a4", ck)
 	}
 
-	const expected = "1cbe3eb3b2a3c2cb5b445c80c98e76ee"
+	// the urlencoded body's field1 is included via the wildcard cache key params
+	const expected = "cb84ad010abb4d0f864470540a46f137"
 
 	tr = httptest.NewRequest(http.MethodPost, "http://127.0.0.1/", bytes.NewReader([]byte("field1=value1")))
 	tr = tr.WithContext(ct.WithResources(context.Background(), newResources()))
@@ -173,6 +181,12 @@ func TestDeriveCacheKey(t *testing.T) {
 		t.Errorf("expected %s got %s", "4766201eee9ef1916f57309deae22f90", ck)
 	}
 
+	// the multipart body must still be available to be proxied upstream
+	b, _ := ioutil.ReadAll(pr.upstreamRequest.Body)
+	if string(b) != testMultipartBody {
+		t.Errorf("expected upstream body %s got %s", testMultipartBody, string(b))
+	}
+
 	_, _, tr, _, _ = tu.NewTestInstance("", nil, 0, "", nil, "rpc", "http://127.0.0.1/", "INFO")
 	tr.Method = http.MethodPost
 	tr.Body = ioutil.NopCloser(bytes.NewReader([]byte(testJSONDocument)))
@@ -206,6 +220,181 @@ func TestDeriveCacheKey(t *testing.T) {
 	}
 }
 
+func TestDeriveCacheKeyDocumentBodies(t *testing.T) {
+
+	rpath := &po.Options{
+		Path:               "/",
+		CacheKeyParams:     []string{},
+		CacheKeyHeaders:    []string{},
+		CacheKeyFormFields: []string{"field1", "query/options/batchSize", "seconds"},
+	}
+
+	cfg := &oo.Options{
+		Paths: map[string]*po.Options{
+			"root": rpath,
+		},
+	}
+
+	deriveKey := func(contentType string, body []byte) string {
+		rsc := request.NewResources(cfg, cfg.Paths["root"], nil, nil, nil, nil, tl.ConsoleLogger("error"))
+		tr := httptest.NewRequest(http.MethodPost, "http://127.0.0.1/", bytes.NewReader(body))
+		tr = tr.WithContext(ct.WithResources(context.Background(), rsc))
+		tr.Header.Set(headers.NameContentType, contentType)
+		tr.Header.Set(headers.NameContentLength, strconv.Itoa(len(body)))
+		return newProxyRequest(tr, nil).DeriveCacheKey(nil, "extra")
+	}
+
+	var document map[string]interface{}
+	err := json.Unmarshal([]byte(testJSONDocument), &document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// use an integer so the msgpack encoding differs from the json float
+	document["query"].(map[string]interface{})["options"].(map[string]interface{})["batchSize"] = 20
+	mb, err := msgp.AppendIntf(nil, document)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := deriveKey(headers.ValueApplicationJSON, []byte(testJSONDocument))
+	ck := deriveKey(headers.ValueApplicationMsgPack, mb)
+	if ck != expected {
+		t.Errorf("expected %s got %s", expected, ck)
+	}
+
+	pb, err := proto.Marshal(&duration.Duration{Seconds: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// without a registered message, the body can't contribute to the key
+	expected = deriveKey(headers.ValueApplicationJSON, []byte("{}"))
+	ck = deriveKey(headers.ValueApplicationProtobuf, pb)
+	if ck != expected {
+		t.Errorf("expected %s got %s", expected, ck)
+	}
+
+	rpath.ProtobufMessage = &duration.Duration{}
+	expected = deriveKey(headers.ValueApplicationJSON, []byte(`{"seconds":20}`))
+	ck = deriveKey(headers.ValueApplicationProtobuf, pb)
+	if ck != expected {
+		t.Errorf("expected %s got %s", expected, ck)
+	}
+
+	// the registered prototype must not be modified by decoding
+	if rpath.ProtobufMessage.(*duration.Duration).Seconds != 0 {
+		t.Error("expected registered protobuf message to be unmodified")
+	}
+}
+
+const testDSQueryDocument = `{
+	"queries": [
+		{ "refId": "A", "expr": "%s", "datasource": { "uid": "prom1" }, "requestId": "%s" }
+	],
+	"range": { "from": "1589904000000", "to": "1589907600000" }
+}`
+
+func TestDeriveCacheKeyJSONPaths(t *testing.T) {
+
+	rpath := &po.Options{
+		Path:              "/api/ds/query",
+		CacheKeyParams:    []string{},
+		CacheKeyHeaders:   []string{},
+		CacheKeyJSONPaths: []string{"$.queries[0].expr", "$.queries[0].datasource", "$.range.from"},
+	}
+	for _, expr := range rpath.CacheKeyJSONPaths {
+		jp, err := jsonpath.Parse(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rpath.JSONPaths = append(rpath.JSONPaths, jp)
+	}
+
+	cfg := &oo.Options{
+		Paths: map[string]*po.Options{
+			"root": rpath,
+		},
+	}
+
+	newProxyRequestWithBody := func(body string) *proxyRequest {
+		rsc := request.NewResources(cfg, cfg.Paths["root"], nil, nil, nil, nil, tl.ConsoleLogger("error"))
+		tr := httptest.NewRequest(http.MethodPost, "http://127.0.0.1/api/ds/query", bytes.NewReader([]byte(body)))
+		tr = tr.WithContext(ct.WithResources(context.Background(), rsc))
+		tr.Header.Set(headers.NameContentType, headers.ValueApplicationJSON)
+		return newProxyRequest(tr, nil)
+	}
+
+	pr := newProxyRequestWithBody(fmt.Sprintf(testDSQueryDocument, "up", "Q100"))
+	ck1 := pr.DeriveCacheKey(nil, "extra")
+
+	if v := pr.Form.Get("$.range.from"); v != "1589904000000" {
+		t.Errorf("expected %s got %s", "1589904000000", v)
+	}
+
+	if v := pr.Form.Get("$.queries[0].datasource"); v != `{"uid":"prom1"}` {
+		t.Errorf("expected %s got %s", `{"uid":"prom1"}`, v)
+	}
+
+	// fields not selected by a JSONPath do not affect the key
+	ck2 := newProxyRequestWithBody(fmt.Sprintf(testDSQueryDocument, "up", "Q101")).
+		DeriveCacheKey(nil, "extra")
+	if ck1 != ck2 {
+		t.Errorf("expected %s got %s", ck1, ck2)
+	}
+
+	ck2 = newProxyRequestWithBody(fmt.Sprintf(testDSQueryDocument, "down", "Q100")).
+		DeriveCacheKey(nil, "extra")
+	if ck1 == ck2 {
+		t.Errorf("expected key other than %s", ck1)
+	}
+
+	// an undecodable body falls back to the method and path
+	rpath.JSONPaths = nil
+	expected := newProxyRequestWithBody("{}").DeriveCacheKey(nil, "extra")
+	rpath.JSONPaths = []jsonpath.Path{{}}
+	ck := newProxyRequestWithBody("{").DeriveCacheKey(nil, "extra")
+	if ck != expected {
+		t.Errorf("expected %s got %s", expected, ck)
+	}
+}
+
+func TestDecodeBodyDocument(t *testing.T) {
+
+	_, err := decodeBodyDocument(headers.ValueApplicationMsgPack, []byte{0xc1}, nil)
+	if err == nil {
+		t.Error("expected error for invalid msgpack")
+	}
+
+	b := msgp.AppendString(nil, "trickster")
+	_, err = decodeBodyDocument(headers.ValueApplicationMsgPack, b, nil)
+	if err == nil {
+		t.Error("expected error for non-map msgpack document")
+	}
+
+	_, err = decodeBodyDocument(headers.ValueApplicationProtobuf, []byte{0x08, 0x14}, nil)
+	if err == nil {
+		t.Error("expected error for unregistered protobuf message")
+	}
+
+	_, err = decodeBodyDocument(headers.ValueApplicationProtobuf, []byte{0xff}, &duration.Duration{})
+	if err == nil {
+		t.Error("expected error for invalid protobuf")
+	}
+
+	document, err := decodeBodyDocument(headers.ValueApplicationProtobuf,
+		[]byte{0x08, 0x14}, &duration.Duration{})
+	if err != nil {
+		t.Error(err)
+	}
+	v, err := deepSearch(document, "seconds")
+	if err != nil {
+		t.Error(err)
+	}
+	if v != "20.0000" {
+		t.Errorf("expected %s got %s", "20.0000", v)
+	}
+}
+
 func exampleKeyHasher(path string, params url.Values, headers http.Header,
 	body io.ReadCloser, extra string) (string, io.ReadCloser) {
 	return "test-key", nil
@@ -243,6 +432,162 @@ func TestDeriveCacheKeyAuthHeader(t *testing.T) {
 
 }
 
+func TestDeriveCacheKeyPrincipal(t *testing.T) {
+
+	client := &TestClient{
+		config: &oo.Options{
+			Paths: map[string]*po.Options{
+				"root": {
+					Path:           "/",
+					CacheKeyParams: []string{"query"},
+					Principal:      &tenancy.Source{Kind: tenancy.KindHeader, Name: "X-User"},
+				},
+			},
+		},
+	}
+
+	newRequest := func(user string) *proxyRequest {
+		tr := httptest.NewRequest("GET", "http://127.0.0.1/?query=12345", nil)
+		tr = tr.WithContext(ct.WithResources(context.Background(),
+			request.NewResources(client.Configuration(), client.Configuration().Paths["root"],
+				nil, nil, nil, nil, tl.ConsoleLogger("error"))))
+		if user != "" {
+			tr.Header.Set("X-User", user)
+		}
+		return newProxyRequest(tr, nil)
+	}
+
+	pr1 := newRequest("user-a")
+	k1 := pr1.DeriveCacheKey(nil, "")
+	if !pr1.hasPrincipal {
+		t.Error("expected request to have a principal")
+	}
+
+	pr2 := newRequest("user-b")
+	if k2 := pr2.DeriveCacheKey(nil, ""); k2 == k1 {
+		t.Errorf("expected different keys for different principals, got %s", k2)
+	}
+
+	pr3 := newRequest("")
+	if k3 := pr3.DeriveCacheKey(nil, ""); k3 == k1 {
+		t.Errorf("expected different keys with and without a principal, got %s", k3)
+	}
+	if pr3.hasPrincipal {
+		t.Error("expected request to have no principal")
+	}
+
+}
+
+func TestDeriveCacheKeyNormalizers(t *testing.T) {
+
+	rpath := &po.Options{
+		Path:           "/",
+		CacheKeyParams: []string{"query", "step"},
+		KeyNormalizers: map[string]key.NormalizerFunc{"query": strings.ToLower},
+	}
+	cfg := &oo.Options{Paths: map[string]*po.Options{"root": rpath}}
+
+	deriveKey := func(u string) string {
+		tr := httptest.NewRequest(http.MethodGet, u, nil)
+		tr = tr.WithContext(ct.WithResources(context.Background(),
+			request.NewResources(cfg, rpath, nil, nil, nil, nil, tl.ConsoleLogger("error"))))
+		return newProxyRequest(tr, nil).DeriveCacheKey(nil, "")
+	}
+
+	k1 := deriveKey("http://127.0.0.1/?query=UP&step=15")
+	k2 := deriveKey("http://127.0.0.1/?query=up&step=15")
+	if k1 != k2 {
+		t.Errorf("expected normalized queries to share key %s got %s", k1, k2)
+	}
+
+	// only the named params are normalized
+	if k3 := deriveKey("http://127.0.0.1/?query=up&step=15S"); k3 == k2 {
+		t.Errorf("expected distinct keys for step values, got %s", k3)
+	}
+
+	rpath.CacheKeyParams = []string{"*"}
+	if k1, k2 = deriveKey("http://127.0.0.1/?query=UP"), deriveKey("http://127.0.0.1/?query=up"); k1 != k2 {
+		t.Errorf("expected normalized queries to share key %s got %s", k1, k2)
+	}
+}
+
+func TestDeriveCacheKeyRepeatedParams(t *testing.T) {
+
+	rpath := &po.Options{
+		Path:           "/",
+		CacheKeyParams: []string{"match[]"},
+	}
+	cfg := &oo.Options{Paths: map[string]*po.Options{"root": rpath}}
+
+	deriveKey := func(u string) string {
+		tr := httptest.NewRequest(http.MethodGet, u, nil)
+		tr = tr.WithContext(ct.WithResources(context.Background(),
+			request.NewResources(cfg, rpath, nil, nil, nil, nil, tl.ConsoleLogger("error"))))
+		return newProxyRequest(tr, nil).DeriveCacheKey(nil, "")
+	}
+
+	k1 := deriveKey("http://127.0.0.1/federate?match[]=up")
+	k2 := deriveKey("http://127.0.0.1/federate?match[]=up&match[]=go_goroutines")
+	if k1 == k2 {
+		t.Errorf("expected distinct keys for repeated params, got %s", k1)
+	}
+	if k3 := deriveKey("http://127.0.0.1/federate?match[]=up&match[]=go_goroutines"); k3 != k2 {
+		t.Errorf("expected %s got %s", k2, k3)
+	}
+}
+
+func TestDeriveCacheKeyTemplate(t *testing.T) {
+
+	tpl, err := key.ParseTemplate("{method} {host} {header:X-Tenant} {param:query} {body:panelId}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rpath := &po.Options{
+		Path:           "/",
+		CacheKeyParams: []string{"*"},
+		KeyTemplate:    tpl,
+	}
+	cfg := &oo.Options{Paths: map[string]*po.Options{"root": rpath}}
+
+	deriveKey := func(method, u, body string) string {
+		var tr *http.Request
+		if body != "" {
+			tr = httptest.NewRequest(method, u, bytes.NewReader([]byte(body)))
+			tr.Header.Set(headers.NameContentType, headers.ValueApplicationJSON)
+		} else {
+			tr = httptest.NewRequest(method, u, nil)
+		}
+		tr.Header.Set("X-Tenant", "a")
+		tr = tr.WithContext(ct.WithResources(context.Background(),
+			request.NewResources(cfg, rpath, nil, nil, nil, nil, tl.ConsoleLogger("error"))))
+		return newProxyRequest(tr, nil).DeriveCacheKey(nil, "")
+	}
+
+	// cache busting params not in the template don't affect the key
+	k1 := deriveKey(http.MethodGet, "http://127.0.0.1/?query=up&_=1600000000", "")
+	k2 := deriveKey(http.MethodGet, "http://127.0.0.1/?query=up&_=1600000001", "")
+	if k1 != k2 {
+		t.Errorf("expected shared key %s got %s", k1, k2)
+	}
+
+	if k3 := deriveKey(http.MethodGet, "http://127.0.0.1/?query=down", ""); k3 == k1 {
+		t.Errorf("expected distinct key for query param, got %s", k3)
+	}
+
+	if k3 := deriveKey(http.MethodGet, "http://trickster/?query=up", ""); k3 == k1 {
+		t.Errorf("expected distinct key for host, got %s", k3)
+	}
+
+	k1 = deriveKey(http.MethodPost, "http://127.0.0.1/?query=up", `{"panelId":"4","ts":1}`)
+	k2 = deriveKey(http.MethodPost, "http://127.0.0.1/?query=up", `{"panelId":"4","ts":2}`)
+	if k1 != k2 {
+		t.Errorf("expected shared key %s got %s", k1, k2)
+	}
+	if k3 := deriveKey(http.MethodPost, "http://127.0.0.1/?query=up", `{"panelId":"5"}`); k3 == k1 {
+		t.Errorf("expected distinct key for body field, got %s", k3)
+	}
+}
+
 func TestDeriveCacheKeyNoPathConfig(t *testing.T) {
 
 	client := &TestClient{
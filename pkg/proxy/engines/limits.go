@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// limitResult applies the origin's series and sample limits to the timeseries response.
+// When the response exceeds a limit, it is truncated and a warning is added to it and to
+// the response headers, unless the origin rejects oversized results or the timeseries can't
+// be truncated, in which case an error describing the exceeded limit is returned
+func limitResult(pr *proxyRequest, ts timeseries.Timeseries, h http.Header) error {
+	oc := request.GetResources(pr.Request).OriginConfig
+	seriesCount, valueCount := ts.SeriesCount(), ts.ValueCount()
+	var msg string
+	switch {
+	case oc.MaxResultSeries > 0 && seriesCount > oc.MaxResultSeries:
+		msg = "result exceeds the limit of " + strconv.Itoa(oc.MaxResultSeries) + " series"
+	case oc.MaxResultSamples > 0 && valueCount > oc.MaxResultSamples:
+		msg = "result exceeds the limit of " + strconv.Itoa(oc.MaxResultSamples) + " samples"
+	default:
+		return nil
+	}
+	pairs := tl.Pairs{"series": seriesCount, "values": valueCount,
+		"maxSeries": oc.MaxResultSeries, "maxSamples": oc.MaxResultSamples}
+	t, ok := ts.(timeseries.Truncator)
+	if !ok || oc.ResultLimitAction == timeseries.LimitActionReject {
+		pr.Logger.Debug("rejected timeseries result exceeding limits", pairs)
+		return errors.New(msg)
+	}
+	t.Truncate(oc.MaxResultSeries, oc.MaxResultSamples)
+	warning := msg + " and was truncated"
+	t.AddWarning(warning)
+	h.Set(headers.NameWarning, fmt.Sprintf(`199 trickster "%s"`, warning))
+	pr.Logger.Debug("truncated timeseries result exceeding limits", pairs)
+	return nil
+}
+
+// limitTimeRange applies the maximum lookback and time range of the path, or else of the
+// origin, to the time range query. When the query exceeds a limit, its extent is clamped
+// to the limits and a warning describing the exceeded limit is returned, unless the origin
+// rejects out-of-range queries or no part of the query is within the lookback, in which
+// case an error describing the exceeded limit is returned
+func limitTimeRange(pc *po.Options, oc *oo.Options, trq *timeseries.TimeRangeQuery,
+	now time.Time) (string, error) {
+	lookback, maxRange := oc.MaxLookback, oc.MaxRange
+	if pc != nil && pc.HasMaxLookback {
+		lookback = pc.MaxLookback
+	}
+	if pc != nil && pc.HasMaxRange {
+		maxRange = pc.MaxRange
+	}
+	e := trq.Extent
+	var msg string
+	if lookback > 0 && e.Start.Before(now.Add(-lookback)) {
+		msg = "query range exceeds the maximum lookback of " + lookback.String()
+		if e.End.Before(now.Add(-lookback)) {
+			return "", errors.New(msg)
+		}
+		e.Start = now.Add(-lookback)
+	}
+	if maxRange > 0 && e.End.Sub(e.Start) > maxRange {
+		if msg == "" {
+			msg = "query range exceeds the maximum range of " + maxRange.String()
+		}
+		// the most recent portion of the range is kept
+		e.Start = e.End.Add(-maxRange)
+	}
+	if msg == "" {
+		return "", nil
+	}
+	if oc.TimeRangeLimitAction == timeseries.LimitActionReject {
+		return "", errors.New(msg)
+	}
+	// the clamped start is aligned to the step, inside of the limits
+	if trq.Step > 0 {
+		if t := e.Start.Truncate(trq.Step); t.Before(e.Start) {
+			e.Start = t.Add(trq.Step)
+		}
+	}
+	trq.Extent.Start = e.Start
+	return msg + " and was clamped", nil
+}
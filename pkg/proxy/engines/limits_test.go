@@ -0,0 +1,248 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+func TestDeltaProxyCacheRequestResultLimits(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	oc.FastForwardDisable = true
+	oc.MaxResultSamples = 10
+
+	step := time.Duration(60) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(1) * time.Hour), End: end}
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	// the result is truncated to 10 samples, with a warning
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusOK)
+	if err != nil {
+		t.Error(err)
+	}
+
+	me := &MatrixEnvelope{}
+	if err = json.Unmarshal(bodyBytes, me); err != nil {
+		t.Fatal(err)
+	}
+	if me.ValueCount() != 10 {
+		t.Errorf("expected %d got %d", 10, me.ValueCount())
+	}
+	const expectedWarning = "result exceeds the limit of 10 samples and was truncated"
+	if len(me.Warnings) != 1 || me.Warnings[0] != expectedWarning {
+		t.Errorf("unexpected warnings %v", me.Warnings)
+	}
+	if h := resp.Header.Get(headers.NameWarning); !strings.Contains(h, expectedWarning) {
+		t.Errorf("unexpected warning header %s", h)
+	}
+
+	// the result is rejected
+	oc.ResultLimitAction = timeseries.LimitActionReject
+	r.URL = u
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	resp = w.Result()
+
+	bodyBytes, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusUnprocessableEntity)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testStringMatch(string(bodyBytes), "result exceeds the limit of 10 samples")
+	if err != nil {
+		t.Error(err)
+	}
+
+	// the result is within the limits
+	oc.MaxResultSamples = 0
+	oc.MaxResultSeries = 10
+	r.URL = u
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	resp = w.Result()
+
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusOK)
+	if err != nil {
+		t.Error(err)
+	}
+	if h := resp.Header.Get(headers.NameWarning); h != "" {
+		t.Errorf("unexpected warning header %s", h)
+	}
+}
+
+func TestLimitTimeRange(t *testing.T) {
+
+	now := time.Unix(86400, 0)
+	oc := oo.NewOptions()
+	oc.MaxLookback = 6 * time.Hour
+	oc.MaxRange = 2 * time.Hour
+	pc := po.NewOptions()
+	pc.MaxRange = 4 * time.Hour
+	pc.HasMaxRange = true
+
+	tests := []struct {
+		pc            *po.Options
+		start, end    int64
+		action        timeseries.LimitAction
+		expectedStart int64
+		warning       string
+		err           string
+	}{
+		{nil, 82800, 86400, timeseries.LimitActionTruncate, 82800, "", ""},
+		{nil, 75600, 86400, timeseries.LimitActionTruncate, 79200,
+			"query range exceeds the maximum range of 2h0m0s and was clamped", ""},
+		{pc, 75600, 86400, timeseries.LimitActionTruncate, 75600, "", ""},
+		{pc, 0, 86400, timeseries.LimitActionTruncate, 72000,
+			"query range exceeds the maximum lookback of 6h0m0s and was clamped", ""},
+		{nil, 0, 86400, timeseries.LimitActionReject, 0, "",
+			"query range exceeds the maximum lookback of 6h0m0s"},
+		{nil, 0, 3600, timeseries.LimitActionTruncate, 0, "",
+			"query range exceeds the maximum lookback of 6h0m0s"},
+		{nil, 79170, 86430, timeseries.LimitActionTruncate, 79260,
+			"query range exceeds the maximum range of 2h0m0s and was clamped", ""},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			oc.TimeRangeLimitAction = test.action
+			trq := &timeseries.TimeRangeQuery{Step: time.Minute,
+				Extent: timeseries.Extent{Start: time.Unix(test.start, 0), End: time.Unix(test.end, 0)}}
+			warning, err := limitTimeRange(test.pc, oc, trq, now)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("expected error %s got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if warning != test.warning {
+				t.Errorf("expected warning %s got %s", test.warning, warning)
+			}
+			if trq.Extent.Start.Unix() != test.expectedStart {
+				t.Errorf("expected start %d got %d", test.expectedStart, trq.Extent.Start.Unix())
+			}
+		})
+	}
+}
+
+func TestDeltaProxyCacheRequestTimeRangeLimits(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	oc.FastForwardDisable = true
+	oc.MaxRange = 30 * time.Minute
+
+	step := time.Duration(60) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour).Truncate(step)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(1) * time.Hour), End: end}
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	// the range is clamped to the most recent 30 minutes, with a warning
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusOK)
+	if err != nil {
+		t.Error(err)
+	}
+	me := &MatrixEnvelope{}
+	if err = json.Unmarshal(bodyBytes, me); err != nil {
+		t.Fatal(err)
+	}
+	if c := me.TimestampCount(); c != 31 {
+		t.Errorf("expected %d got %d", 31, c)
+	}
+	const expectedWarning = "query range exceeds the maximum range of 30m0s and was clamped"
+	if h := resp.Header.Get(headers.NameWarning); !strings.Contains(h, expectedWarning) {
+		t.Errorf("unexpected warning header %s", h)
+	}
+
+	// the query is rejected
+	oc.TimeRangeLimitAction = timeseries.LimitActionReject
+	r.URL = u
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	resp = w.Result()
+
+	bodyBytes, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusUnprocessableEntity)
+	if err != nil {
+		t.Error(err)
+	}
+	err = testStringMatch(string(bodyBytes), "query range exceeds the maximum range of 30m0s")
+	if err != nil {
+		t.Error(err)
+	}
+}
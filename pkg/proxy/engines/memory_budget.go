@@ -0,0 +1,101 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+)
+
+// memoryBudget limits the bytes of upstream responses that the caching engines buffer
+// in memory across all in-flight requests, and that their merged responses are encoded to.
+// While the budget is exceeded, new cacheable requests are proxied without caching, which
+// streams the upstream response to the client in constant memory
+var memoryBudget struct {
+	limit int64
+	inUse int64
+}
+
+// SetMemoryBudget sets the maximum bytes the caching engines buffer across all in-flight
+// requests before new requests are proxied without caching. 0 disables the budget
+func SetMemoryBudget(n int64) {
+	atomic.StoreInt64(&memoryBudget.limit, n)
+}
+
+// overMemoryBudget returns true, and records that the request was degraded to proxy-only,
+// if the memory budget is exceeded
+func overMemoryBudget(r *http.Request) bool {
+	limit := atomic.LoadInt64(&memoryBudget.limit)
+	if limit <= 0 {
+		return false
+	}
+	inUse := atomic.LoadInt64(&memoryBudget.inUse)
+	if inUse < limit {
+		return false
+	}
+	if rsc := request.GetResources(r); rsc != nil && rsc.OriginConfig != nil {
+		oc := rsc.OriginConfig
+		metrics.ProxyMemoryBudgetDegraded.WithLabelValues(oc.Name, oc.OriginType, r.URL.Path).Inc()
+		rsc.Logger.Debug("memory budget exceeded, proxying request without caching",
+			tl.Pairs{"budgetBytes": limit, "inUseBytes": inUse, "path": r.URL.Path})
+	}
+	return true
+}
+
+// memoryReservation counts the bytes buffered on behalf of a request against the memory
+// budget, until the request releases them
+type memoryReservation struct {
+	n int64
+}
+
+// add counts n more bytes against the memory budget
+func (m *memoryReservation) add(n int) {
+	if m == nil || n <= 0 {
+		return
+	}
+	atomic.AddInt64(&m.n, int64(n))
+	atomic.AddInt64(&memoryBudget.inUse, int64(n))
+	metrics.ProxyBufferedBytes.Add(float64(n))
+}
+
+// release returns the reserved bytes to the memory budget
+func (m *memoryReservation) release() {
+	if m == nil {
+		return
+	}
+	n := atomic.SwapInt64(&m.n, 0)
+	atomic.AddInt64(&memoryBudget.inUse, -n)
+	metrics.ProxyBufferedBytes.Sub(float64(n))
+}
+
+// budgetedReader counts the bytes read from an upstream response body against a request's
+// memory reservation
+type budgetedReader struct {
+	io.ReadCloser
+	memory *memoryReservation
+}
+
+func (br *budgetedReader) Read(b []byte) (int, error) {
+	n, err := br.ReadCloser.Read(b)
+	br.memory.add(n)
+	return n, err
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	tc "github.com/tricksterproxy/trickster/pkg/proxy/context"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+func TestMemoryBudget(t *testing.T) {
+
+	oc := oo.NewOptions()
+	oc.Name = "test"
+	r := httptest.NewRequest("GET", "http://127.0.0.1/", nil)
+	r = r.WithContext(tc.WithResources(r.Context(),
+		request.NewResources(oc, nil, nil, nil, nil, nil, testLogger)))
+
+	defer SetMemoryBudget(0)
+
+	m := &memoryReservation{}
+	m.add(20)
+	defer m.release()
+
+	// the budget is unlimited by default
+	if overMemoryBudget(r) {
+		t.Error("expected request to be within the memory budget")
+	}
+
+	SetMemoryBudget(10)
+	if !overMemoryBudget(r) {
+		t.Error("expected request to exceed the memory budget")
+	}
+
+	// requests exceeding the budget are proxied without caching
+	if _, cs := fetchViaObjectProxyCache(httptest.NewRecorder(), r, true); cs != status.LookupStatusProxyOnly {
+		t.Errorf("expected %s got %s", status.LookupStatusProxyOnly, cs)
+	}
+
+	m.release()
+	if overMemoryBudget(r) {
+		t.Error("expected request to be within the memory budget")
+	}
+}
+
+func TestDeltaProxyCacheRequestMemoryBudget(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	oc.FastForwardDisable = true
+	oc.MaxRange = 30 * time.Minute
+
+	defer SetMemoryBudget(0)
+	SetMemoryBudget(10)
+	m := &memoryReservation{}
+	m.add(20)
+	defer m.release()
+
+	step := time.Duration(60) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour).Truncate(step)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(1) * time.Hour), End: end}
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	// the query is proxied uncached, but still clamped to the time range limits
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusOK)
+	if err != nil {
+		t.Error(err)
+	}
+	err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": "proxy-only"})
+	if err != nil {
+		t.Error(err)
+	}
+	me := &MatrixEnvelope{}
+	if err = json.Unmarshal(bodyBytes, me); err != nil {
+		t.Fatal(err)
+	}
+	if c := me.TimestampCount(); c != 31 {
+		t.Errorf("expected %d got %d", 31, c)
+	}
+	const expectedWarning = "query range exceeds the maximum range of 30m0s and was clamped"
+	if h := resp.Header.Get(headers.NameWarning); !strings.Contains(h, expectedWarning) {
+		t.Errorf("unexpected warning header %s", h)
+	}
+
+	// or rejected by them
+	oc.TimeRangeLimitAction = timeseries.LimitActionReject
+	r.URL = u
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	resp = w.Result()
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusUnprocessableEntity)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// queries to an origin with result limits are shed, since the limits cannot be
+	// applied to an uncached response
+	oc.MaxRange = 0
+	oc.MaxResultSamples = 10
+	r.URL = u
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	resp = w.Result()
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusServiceUnavailable)
+	if err != nil {
+		t.Error(err)
+	}
+	if h := resp.Header.Get(headers.NameRetryAfter); h == "" {
+		t.Error("expected Retry-After header")
+	}
+}
+
+func TestBudgetedReader(t *testing.T) {
+
+	m := &memoryReservation{}
+	br := &budgetedReader{ReadCloser: ioutil.NopCloser(bytes.NewBufferString("trickster")), memory: m}
+	b, err := ioutil.ReadAll(br)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.n != int64(len(b)) {
+		t.Errorf("expected %d got %d", len(b), m.n)
+	}
+	m.release()
+	if m.n != 0 {
+		t.Errorf("expected %d got %d", 0, m.n)
+	}
+}
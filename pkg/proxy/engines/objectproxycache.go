@@ -25,6 +25,7 @@ import (
 
 	"github.com/tricksterproxy/trickster/pkg/cache"
 	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	tctx "github.com/tricksterproxy/trickster/pkg/proxy/context"
 	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
 	"github.com/tricksterproxy/trickster/pkg/proxy/forwarding"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
@@ -237,6 +238,10 @@ func handleTrueCacheHit(pr *proxyRequest) error {
 		headers.Merge(pr.upstreamResponse.Header, h)
 		pr.upstreamReader = bytes.NewBuffer(b)
 	} else {
+		if d.bodyEncoding != "" {
+			pr.upstreamResponse.Header.Set(headers.NameContentEncoding, d.bodyEncoding)
+			pr.upstreamResponse.Header.Add(headers.NameVary, headers.NameAcceptEncoding)
+		}
 		pr.upstreamReader = bytes.NewBuffer(d.Body)
 	}
 
@@ -313,8 +318,7 @@ func handlePCF(pr *proxyRequest) error {
 		reqs.Store(pr.key, pcf)
 		// Blocks until server completes
 
-		pr.cachingPolicy.Merge(GetResponseCachingPolicy(pr.upstreamResponse.StatusCode,
-			rsc.OriginConfig.NegativeCache, pr.upstreamResponse.Header))
+		pr.cachingPolicy.Merge(pr.responseCachingPolicy(rsc.OriginConfig.NegativeCache))
 		pr.determineCacheability()
 
 		go func() {
@@ -336,6 +340,16 @@ func handlePCF(pr *proxyRequest) error {
 }
 
 func handleAllWrites(pr *proxyRequest) error {
+	// the upstream headers and length are captured before the response is prepared for the
+	// client, so that the body can be validated as the upstream sent it
+	var h http.Header
+	cl := int64(-1)
+	if pr.writeToCache && pr.upstreamResponse != nil {
+		h = pr.upstreamResponse.Header.Clone()
+		if !pr.isPartialResponse && !pr.wasReconstituted {
+			cl = upstreamBodyLength(pr.Request, pr.upstreamResponse)
+		}
+	}
 	handleResponse(pr)
 	if pr.writeToCache {
 		if pr.cacheDocument == nil || !pr.cacheDocument.isLoaded {
@@ -346,6 +360,11 @@ func handleAllWrites(pr *proxyRequest) error {
 			} else {
 				d.Body = pr.cacheBuffer.Bytes()
 			}
+			if err := validateCacheable(request.GetResources(pr.Request), h, cl, d.Body); err != nil {
+				logUncacheable(pr, err)
+				pr.writeToCache = false
+				return nil
+			}
 		}
 		pr.store()
 	}
@@ -374,13 +393,23 @@ func init() {
 	}
 }
 
-func fetchViaObjectProxyCache(w io.Writer, r *http.Request) (*http.Response, status.LookupStatus) {
+// fetchViaObjectProxyCache fetches the object from the cache or origin. When passthrough is
+// true, a cached body that is stored compressed with a content coding the client accepts is
+// written to the client without decompressing it
+func fetchViaObjectProxyCache(w io.Writer, r *http.Request,
+	passthrough bool) (*http.Response, status.LookupStatus) {
 
 	rsc := request.GetResources(r)
 	oc := rsc.OriginConfig
 	cc := rsc.CacheClient
 
+	// while the memory budget is exceeded, the response is streamed from the origin uncached
+	if overMemoryBudget(r) {
+		return nil, status.LookupStatusProxyOnly
+	}
+
 	pr := newProxyRequest(r, w)
+	defer pr.memory.release()
 
 	_, span := tspan.NewChildSpan(r.Context(), rsc.Tracer, "ObjectProxyCacheRequest")
 	if span != nil {
@@ -393,6 +422,9 @@ func fetchViaObjectProxyCache(w io.Writer, r *http.Request) (*http.Response, sta
 	pr.cachingPolicy = GetRequestCachingPolicy(pr.Header)
 
 	pr.key = oc.CacheKeyPrefix + ".opc." + pr.DeriveCacheKey(nil, "")
+	if tctx.Inspection(r.Context()) {
+		return nil, inspectObject(w, pr)
+	}
 
 	// if a PCF entry exists, or the client requested no-cache for this object, proxy out to it
 	pcfResult, pcfExists := reqs.Load(pr.key)
@@ -413,13 +445,25 @@ func fetchViaObjectProxyCache(w io.Writer, r *http.Request) (*http.Response, sta
 	pr.cachingPolicy.ParseClientConditionals()
 
 	if !rsc.NoLock {
-		pr.cacheLock, _ = cc.Locker().RAcquire(pr.key)
+		nl, err := acquireCacheReadLock(pr.upstreamRequest.Context(), cc.Locker(), pr.key)
+		if err != nil {
+			pr.Logger.Debug("cache lock not acquired", log.Pairs{"detail": err.Error()})
+			return nil, status.LookupStatusProxyOnly
+		}
+		pr.cacheLock = nl
 		pr.hasReadLock = true
 	}
 
+	// response hooks rewrite the decompressed body, so it is only passed through without them
+	var acceptEncoding string
+	if passthrough && !pr.wantsRanges && methods.IsCacheable(pr.Method) &&
+		(rsc.PathConfig == nil || len(rsc.PathConfig.ResponseHook) == 0) {
+		acceptEncoding = pr.Header.Get(headers.NameAcceptEncoding)
+	}
+
 	var err error
 	pr.cacheDocument, pr.cacheStatus, pr.neededRanges, err =
-		QueryCache(pr.upstreamRequest.Context(), cc, pr.key, pr.wantedRanges)
+		queryCache(pr.upstreamRequest.Context(), cc, pr.key, pr.wantedRanges, acceptEncoding)
 	if err == nil || err == cache.ErrKNF {
 		if f, ok := cacheResponseHandlers[pr.cacheStatus]; ok {
 			f(pr)
@@ -454,7 +498,7 @@ func fetchViaObjectProxyCache(w io.Writer, r *http.Request) (*http.Response, sta
 
 // ObjectProxyCacheRequest provides a Basic HTTP Reverse Proxy/Cache
 func ObjectProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
-	_, cacheStatus := fetchViaObjectProxyCache(w, r)
+	_, cacheStatus := fetchViaObjectProxyCache(w, r, true)
 	if cacheStatus == status.LookupStatusProxyOnly {
 		DoProxy(w, r, true)
 	}
@@ -464,7 +508,7 @@ func ObjectProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 // writes the object to the cache, and returns the object to the caller
 func FetchViaObjectProxyCache(r *http.Request) ([]byte, *http.Response, bool) {
 	w := bytes.NewBuffer(nil)
-	resp, cacheStatus := fetchViaObjectProxyCache(w, r)
+	resp, cacheStatus := fetchViaObjectProxyCache(w, r, false)
 	if cacheStatus == status.LookupStatusProxyOnly {
 		resp = DoProxy(w, r, false)
 	}
@@ -485,11 +529,11 @@ func recordOPCResult(pr *proxyRequest, cacheStatus status.LookupStatus, httpStat
 
 func upgradeLock(pr *proxyRequest) (bool, bool) {
 	if pr.hasReadLock && !pr.hasWriteLock {
-		cwc := pr.cacheLock.WriteLockCounter()
-		pr.cacheLock.Upgrade()
+		var first bool
+		pr.cacheLock, first, _ = upgradeCacheLock(pr.upstreamRequest.Context(), pr.cacheLock)
 		pr.hasReadLock = false
 		pr.hasWriteLock = true
-		if pr.cacheLock.WriteLockCounter()-cwc != 1 {
+		if !first {
 			return true, false
 		}
 		return true, true
@@ -35,6 +35,8 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/proxy/tenancy"
+	"github.com/tricksterproxy/trickster/pkg/util/compress"
 	tu "github.com/tricksterproxy/trickster/pkg/util/testing"
 )
 
@@ -591,6 +593,63 @@ func TestObjectProxyCacheTrueHitNoDocumentErr(t *testing.T) {
 	}
 }
 
+func TestObjectProxyCacheRequestBodyEncoding(t *testing.T) {
+
+	body := strings.Repeat("trickster ", 1024)
+	hdrs := map[string]string{"Cache-Control": "max-age=60", "Content-Type": "text/plain"}
+	ts, _, r, rsc, err := setupTestHarnessOPC("", body, http.StatusOK, hdrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+
+	_, c, closer := newCompressionTestCache(t)
+	defer closer()
+	c.Configuration().CompressionCodec = compress.NameGzip
+	rsc.CacheClient = c
+	rsc.OriginConfig.CompressableTypes = map[string]bool{"text/plain": true}
+
+	tests := []struct {
+		acceptEncoding string
+		status         string
+		encoding       string
+	}{
+		{"", "kmiss", ""},
+		{"gzip, br", "hit", compress.NameGzip},
+		{"br", "hit", ""},
+		{"", "hit", ""},
+	}
+
+	for i, test := range tests {
+		rr := r.Clone(r.Context())
+		if test.acceptEncoding != "" {
+			rr.Header.Set(headers.NameAcceptEncoding, test.acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		ObjectProxyCacheRequest(w, rr)
+		resp := w.Result()
+		if !strings.Contains(resp.Header.Get(headers.NameTricksterResult), "status="+test.status) {
+			t.Errorf("case %d: expected status %s got %s", i, test.status,
+				resp.Header.Get(headers.NameTricksterResult))
+		}
+		if ce := resp.Header.Get(headers.NameContentEncoding); ce != test.encoding {
+			t.Errorf("case %d: expected content encoding %s got %s", i, test.encoding, ce)
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		if test.encoding != "" {
+			if resp.Header.Get(headers.NameVary) != headers.NameAcceptEncoding {
+				t.Errorf("case %d: expected vary %s", i, headers.NameAcceptEncoding)
+			}
+			if b, err = compress.Decode(test.encoding, b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if string(b) != body {
+			t.Errorf("case %d: expected body to match", i)
+		}
+	}
+}
+
 func TestObjectProxyCacheRequestClientNoCache(t *testing.T) {
 
 	ts, _, r, _, err := setupTestHarnessOPC("", "test", http.StatusOK, nil)
@@ -643,6 +702,60 @@ func TestObjectProxyCacheRequestOriginNoCache(t *testing.T) {
 	}
 }
 
+func TestObjectProxyCacheRequestPrincipal(t *testing.T) {
+
+	hdrs := map[string]string{"Cache-Control": "private, max-age=60"}
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "test", http.StatusOK, hdrs)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	// without a principal in the cache key, the private response is not cached
+	_, e := testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	rsc.PathConfig.Principal = &tenancy.Source{Kind: tenancy.KindHeader, Name: "X-User"}
+	r.Header.Set("X-User", "user-a")
+
+	// private responses are only cached when the path allows it
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	rsc.PathConfig.CachePrivateResponses = true
+
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	w, e := testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "hit"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	if v := w.Header().Get(headers.NameCacheControl); v != "private, max-age=60" {
+		t.Errorf("expected %s got %s", "private, max-age=60", v)
+	}
+
+	// another principal does not share the cached response
+	r.Header.Set("X-User", "user-b")
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+}
+
 func TestObjectProxyCacheIMS(t *testing.T) {
 
 	hdrs := map[string]string{"Cache-Control": "max-age=1"}
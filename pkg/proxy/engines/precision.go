@@ -0,0 +1,33 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+// reducePrecision rounds the values of the timeseries to the origin's value precision,
+// when one is configured and the timeseries supports it
+func reducePrecision(oc *oo.Options, ts timeseries.Timeseries) {
+	if oc.ValuePrecision <= 0 {
+		return
+	}
+	if p, ok := ts.(timeseries.PrecisionReducer); ok {
+		p.ReducePrecision(oc.ValuePrecision)
+	}
+}
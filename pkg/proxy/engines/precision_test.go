@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+func TestDeltaProxyCacheRequestValuePrecision(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	oc.FastForwardDisable = true
+	oc.ValuePrecision = 1
+	oc.ValuePrecisionOnServe = true
+
+	step := time.Duration(60) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(1) * time.Hour), End: end}
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	// the fetched response is rounded when it is served, and again when it is cached
+	for i, expected := range []string{"kmiss", "hit"} {
+		if i > 0 {
+			time.Sleep(time.Millisecond * 10)
+			w = httptest.NewRecorder()
+		}
+		client.QueryRangeHandler(w, r)
+		resp := w.Result()
+
+		err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": expected})
+		if err != nil {
+			t.Error(err)
+		}
+
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		err = testStatusCodeMatch(resp.StatusCode, http.StatusOK)
+		if err != nil {
+			t.Error(err)
+		}
+
+		rts, err := client.UnmarshalTimeseries(bodyBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		me := rts.(*MatrixEnvelope)
+		if me.ValueCount() == 0 {
+			t.Fatal("expected values")
+		}
+		for _, ss := range me.Data.Result {
+			for _, sp := range ss.Values {
+				if v := float64(sp.Value); timeseries.RoundSignificant(v, 1) != v {
+					t.Errorf("expected value %v to be rounded to 1 significant digit", v)
+				}
+			}
+		}
+	}
+}
@@ -78,6 +78,12 @@ type proxyRequest struct {
 	collapsedForwarder ProgressiveCollapseForwarder
 	cachingPolicy      *CachingPolicy
 
+	// fetchCtx is the context from which upstream requests are derived. It is not derived
+	// from the client request's context, so that upstream requests outlive the client request
+	fetchCtx context.Context
+	// memory counts the bytes buffered from upstream responses against the memory budget
+	memory *memoryReservation
+
 	Logger            *tl.Logger
 	isPCF             bool
 	writeToCache      bool
@@ -87,19 +93,24 @@ type proxyRequest struct {
 	wantsRanges       bool
 	isPartialResponse bool
 	wasReconstituted  bool
+	hasPrincipal      bool
 }
 
 // newProxyRequest accepts the original inbound HTTP Request and Response
 // and returns a proxyRequest object
 func newProxyRequest(r *http.Request, w io.Writer) *proxyRequest {
 	rsc := request.GetResources(r)
+	mr := &memoryReservation{}
+	fetchCtx := tctx.WithMemoryReservation(context.Background(), mr)
 	pr := &proxyRequest{
 		Request: r,
 		upstreamRequest: r.Clone(
 			tctx.WithResources(
-				trace.ContextWithSpan(context.Background(),
+				trace.ContextWithSpan(fetchCtx,
 					trace.SpanFromContext(r.Context())),
 				rsc)),
+		fetchCtx:       fetchCtx,
+		memory:         mr,
 		contentLength:  -1,
 		responseWriter: w,
 		started:        time.Now(),
@@ -121,9 +132,11 @@ func (pr *proxyRequest) Clone() *proxyRequest {
 				rsc)),
 		upstreamRequest: pr.upstreamRequest.Clone(
 			tctx.WithResources(
-				trace.ContextWithSpan(context.Background(),
+				trace.ContextWithSpan(pr.fetchContext(),
 					trace.SpanFromContext(pr.upstreamRequest.Context())),
 				rsc)),
+		fetchCtx:           pr.fetchCtx,
+		memory:             pr.memory,
 		Logger:             pr.Logger,
 		cacheDocument:      pr.cacheDocument,
 		key:                pr.key,
@@ -142,6 +155,14 @@ func (pr *proxyRequest) Clone() *proxyRequest {
 	}
 }
 
+// fetchContext returns the context from which the proxyRequest's upstream requests are derived
+func (pr *proxyRequest) fetchContext() context.Context {
+	if pr.fetchCtx != nil {
+		return pr.fetchCtx
+	}
+	return context.Background()
+}
+
 // Fetch makes an HTTP request to the provided Origin URL, bypassing the Cache, and returns the
 // response and elapsed time to the caller.
 func (pr *proxyRequest) Fetch() ([]byte, *http.Response, time.Duration) {
@@ -185,7 +206,7 @@ func (pr *proxyRequest) prepareRevalidationRequest() {
 
 	rsc := request.GetResources(pr.upstreamRequest)
 	pr.revalidation = RevalStatusInProgress
-	pr.revalidationRequest = request.SetResources(pr.upstreamRequest.Clone(context.Background()),
+	pr.revalidationRequest = request.SetResources(pr.upstreamRequest.Clone(pr.fetchContext()),
 		request.GetResources(pr.Request))
 
 	_, span := tspan.NewChildSpan(pr.revalidationRequest.Context(), rsc.Tracer, "FetchRevlidation")
@@ -264,7 +285,7 @@ func (pr *proxyRequest) prepareUpstreamRequests() {
 	// if we are articulating the origin range requests, break those out here
 	if pr.neededRanges != nil && len(pr.neededRanges) > 0 && rsc.OriginConfig.DearticulateUpstreamRanges {
 		for _, r := range pr.neededRanges {
-			req := request.SetResources(pr.upstreamRequest.Clone(context.Background()), rsc)
+			req := request.SetResources(pr.upstreamRequest.Clone(pr.fetchContext()), rsc)
 			req.Header.Set(headers.NameRange, "bytes="+r.String())
 			pr.originRequests = append(pr.originRequests, req)
 		}
@@ -727,9 +748,25 @@ func (pr *proxyRequest) reconstituteResponses() {
 	// now we merge the caching policy of the new upstreams
 	if pr.upstreamResponse.StatusCode != http.StatusNotModified {
 		rsc := request.GetResources(pr.Request)
-		pr.cachingPolicy.Merge(GetResponseCachingPolicy(pr.upstreamResponse.StatusCode,
-			rsc.OriginConfig.NegativeCache, pr.upstreamResponse.Header))
+		pr.cachingPolicy.Merge(pr.responseCachingPolicy(rsc.OriginConfig.NegativeCache))
 
 	}
 
 }
+
+// responseCachingPolicy returns the caching policy of the upstream response. When the cache
+// key is partitioned by the request's principal, and the path allows it, a private response
+// is cacheable
+func (pr *proxyRequest) responseCachingPolicy(negativeCache map[int]time.Duration) *CachingPolicy {
+	h := pr.upstreamResponse.Header
+	pc := request.GetResources(pr.Request).PathConfig
+	if !pr.hasPrincipal || pc == nil || !pc.CachePrivateResponses {
+		return GetResponseCachingPolicy(pr.upstreamResponse.StatusCode, negativeCache, h)
+	}
+	h2 := withoutPrivateDirective(h)
+	cp := GetResponseCachingPolicy(pr.upstreamResponse.StatusCode, negativeCache, h2)
+	if h.Get(headers.NameDate) == "" && h2.Get(headers.NameDate) != "" {
+		h.Set(headers.NameDate, h2.Get(headers.NameDate))
+	}
+	return cp
+}
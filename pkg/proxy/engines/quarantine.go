@@ -0,0 +1,54 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+// isSuspectEmpty returns true when every timeseries fetched to fill the ranges missing from
+// the cache has no series, though the cached timeseries does. An origin that suddenly
+// returns nothing for a query that had data is more likely to be having a transient issue
+// than to have lost the data, so the result is quarantined rather than cached for the
+// origin's full timeseries TTL
+func isSuspectEmpty(oc *oo.Options, cacheStatus status.LookupStatus,
+	cts timeseries.Timeseries, mts []timeseries.Timeseries) bool {
+	if oc.EmptyQuarantineTTL <= 0 || len(mts) == 0 || cts == nil || cts.SeriesCount() == 0 ||
+		(cacheStatus != status.LookupStatusPartialHit &&
+			cacheStatus != status.LookupStatusRangeMiss) {
+		return false
+	}
+	for _, ts := range mts {
+		if ts.SeriesCount() > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// timeseriesTTL returns the TTL for writing the timeseries to the cache, which is the
+// origin's empty quarantine TTL when the newly fetched data was suspiciously empty
+func timeseriesTTL(oc *oo.Options, quarantined bool) time.Duration {
+	if quarantined && oc.EmptyQuarantineTTL < oc.TimeseriesTTL {
+		return oc.EmptyQuarantineTTL
+	}
+	return oc.TimeseriesTTL
+}
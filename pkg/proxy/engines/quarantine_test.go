@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+func TestIsSuspectEmpty(t *testing.T) {
+
+	step := time.Minute
+	oc := oo.NewOptions()
+	oc.EmptyQuarantineTTL = 10 * time.Second
+
+	e := timeseries.Extent{Start: time.Unix(600, 0), End: time.Unix(1200, 0)}
+	cts := testVerifyMatrix(step, e, func(time.Time) float64 { return 1 })
+	empty := &MatrixEnvelope{StepDuration: step}
+	fetched := testVerifyMatrix(step, timeseries.Extent{Start: time.Unix(1260, 0),
+		End: time.Unix(1800, 0)}, func(time.Time) float64 { return 1 })
+
+	tests := []struct {
+		cacheStatus status.LookupStatus
+		cts         timeseries.Timeseries
+		mts         []timeseries.Timeseries
+		expected    bool
+	}{
+		{status.LookupStatusPartialHit, cts, []timeseries.Timeseries{empty}, true},
+		{status.LookupStatusRangeMiss, cts, []timeseries.Timeseries{empty, empty}, true},
+		{status.LookupStatusPartialHit, cts, []timeseries.Timeseries{empty, fetched}, false},
+		{status.LookupStatusPartialHit, cts, nil, false},
+		{status.LookupStatusPartialHit, empty, []timeseries.Timeseries{empty}, false},
+		{status.LookupStatusKeyMiss, nil, []timeseries.Timeseries{empty}, false},
+	}
+
+	for i, test := range tests {
+		if v := isSuspectEmpty(oc, test.cacheStatus, test.cts, test.mts); v != test.expected {
+			t.Errorf("%d: expected %t got %t", i, test.expected, v)
+		}
+	}
+
+	oc.EmptyQuarantineTTL = 0
+	if isSuspectEmpty(oc, status.LookupStatusPartialHit, cts,
+		[]timeseries.Timeseries{empty}) {
+		t.Error("expected no quarantine when it is disabled")
+	}
+}
+
+func TestTimeseriesTTL(t *testing.T) {
+
+	oc := oo.NewOptions()
+	oc.TimeseriesTTL = time.Hour
+	oc.EmptyQuarantineTTL = 10 * time.Second
+
+	if ttl := timeseriesTTL(oc, false); ttl != time.Hour {
+		t.Errorf("expected %s got %s", time.Hour, ttl)
+	}
+	if ttl := timeseriesTTL(oc, true); ttl != 10*time.Second {
+		t.Errorf("expected %s got %s", 10*time.Second, ttl)
+	}
+
+	// the quarantine TTL never extends the timeseries TTL
+	oc.EmptyQuarantineTTL = 2 * time.Hour
+	if ttl := timeseriesTTL(oc, true); ttl != time.Hour {
+		t.Errorf("expected %s got %s", time.Hour, ttl)
+	}
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	tc "github.com/tricksterproxy/trickster/pkg/proxy/context"
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// retainKey records that the timeseries cache object at key was written, so the
+// origin's RetentionJanitor trims it until it expires
+func retainKey(oc *oo.Options, key string, now time.Time) {
+	oc.RetainedKeys().Add(key, now.Add(oc.TimeseriesTTL))
+}
+
+// RetentionJanitor periodically trims the data older than the origin's TimeseriesMaxAge from
+// the timeseries cache objects written for it, and rewrites them with their remaining TTL, so
+// that objects that are kept alive by frequent queries do not grow without bound. Objects that
+// are entirely older than the max age are removed. Only objects written since the janitor's
+// origin was loaded are visited, since caches cannot list their keys
+type RetentionJanitor struct {
+	client origins.TimeseriesClient
+	cache  cache.Cache
+	logger *tl.Logger
+
+	quit chan struct{}
+	once sync.Once
+}
+
+// NewRetentionJanitor returns a RetentionJanitor for the client's origin and cache
+func NewRetentionJanitor(client origins.TimeseriesClient, c cache.Cache,
+	logger *tl.Logger) *RetentionJanitor {
+	return &RetentionJanitor{
+		client: client,
+		cache:  c,
+		logger: logger,
+		quit:   make(chan struct{}),
+	}
+}
+
+// Start trims the origin's cache objects on its RetentionInterval, until the
+// RetentionJanitor is stopped
+func (j *RetentionJanitor) Start() {
+	go func() {
+		ticker := time.NewTicker(j.client.Configuration().RetentionInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				j.Sweep(time.Now())
+			case <-j.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the RetentionJanitor's trimming
+func (j *RetentionJanitor) Stop() {
+	j.once.Do(func() { close(j.quit) })
+}
+
+// Sweep trims each of the origin's unexpired cache objects down to the data newer than
+// now minus the origin's TimeseriesMaxAge, and returns the number of objects that were
+// rewritten and removed
+func (j *RetentionJanitor) Sweep(now time.Time) (int, int) {
+	oc := j.client.Configuration()
+	rk := oc.RetainedKeys()
+	if rk == nil {
+		return 0, 0
+	}
+	cc := j.cache.Configuration()
+	ctx := tc.WithResources(context.Background(),
+		request.NewResources(oc, nil, cc, j.cache, j.client, nil, j.logger))
+	cutoff := now.Add(-oc.TimeseriesMaxAge)
+	var trimmed, removed int
+	for key, expires := range rk.Keys(now) {
+		switch j.trim(ctx, key, cutoff, expires.Sub(now)) {
+		case trimResultRewritten:
+			trimmed++
+		case trimResultRemoved:
+			removed++
+			rk.Remove(key)
+		case trimResultMissing:
+			rk.Remove(key)
+		}
+	}
+	if trimmed > 0 || removed > 0 {
+		j.logger.Debug("trimmed timeseries cache objects to max age",
+			tl.Pairs{"originName": oc.Name, "maxAge": oc.TimeseriesMaxAge,
+				"rewritten": trimmed, "removed": removed})
+	}
+	return trimmed, removed
+}
+
+type trimResult int
+
+const (
+	trimResultUnchanged = trimResult(iota)
+	trimResultRewritten
+	trimResultRemoved
+	trimResultMissing
+)
+
+// trim crops the cache object at key to the data at or after cutoff, under the key's write lock
+func (j *RetentionJanitor) trim(ctx context.Context, key string, cutoff time.Time,
+	ttl time.Duration) trimResult {
+	nl, err := j.cache.Locker().Acquire(key)
+	if err != nil {
+		return trimResultUnchanged
+	}
+	defer nl.Release()
+
+	doc, lookupStatus, _, err := QueryCache(ctx, j.cache, key, nil)
+	if err != nil || lookupStatus != status.LookupStatusHit || doc == nil {
+		return trimResultMissing
+	}
+
+	cts, err := cachedTimeseries(j.client, doc)
+	if err != nil {
+		return trimResultMissing
+	}
+	if doc.timeseries != nil {
+		// the cached reference may still be read by requests that loaded it earlier
+		cts = cts.Clone()
+	}
+
+	el := cts.Extents()
+	if len(el) == 0 || !el[0].Start.Before(cutoff) {
+		return trimResultUnchanged
+	}
+	cts.CropToRange(timeseries.Extent{Start: cutoff, End: el[len(el)-1].End})
+	if len(cts.Extents()) == 0 {
+		j.cache.Remove(key)
+		return trimResultRemoved
+	}
+
+	nd := &HTTPDocument{
+		Status:     doc.Status,
+		StatusCode: doc.StatusCode,
+		Headers:    doc.SafeHeaderClone(),
+	}
+	if j.cache.Configuration().CacheType == "memory" {
+		nd.setTimeseries(j.client, cts)
+	} else {
+		nd.Body, err = marshalCachedTimeseries(j.client, cts)
+		if err != nil {
+			return trimResultUnchanged
+		}
+	}
+	oc := j.client.Configuration()
+	if err := WriteCache(ctx, j.cache, key, nd, ttl, oc.CompressableTypes); err != nil {
+		j.logger.Error("error writing object to cache", tl.Pairs{"originName": oc.Name,
+			"cacheKey": key, "detail": err.Error()})
+		return trimResultUnchanged
+	}
+	return trimResultRewritten
+}
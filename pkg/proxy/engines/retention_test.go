@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+func TestRetentionJanitor(t *testing.T) {
+	for _, cacheType := range []string{"memory", "test"} {
+		t.Run(cacheType, func(t *testing.T) {
+			testRetentionJanitor(t, cacheType)
+		})
+	}
+}
+
+func testRetentionJanitor(t *testing.T, cacheType string) {
+
+	ts, _, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	rsc.CacheConfig.CacheType = cacheType
+	oc.FastForwardDisable = true
+	oc.TimeseriesTTL = time.Duration(48) * time.Hour
+	oc.TimeseriesMaxAge = time.Duration(24) * time.Hour
+
+	step := time.Duration(300) * time.Second
+	now := time.Now()
+	end := now.Add(-time.Duration(12) * time.Hour).Truncate(step)
+	start := end.Add(-time.Duration(6) * time.Hour)
+
+	r.URL.Path = "/prometheus/api/v1/query_range"
+	r.URL.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), start.Unix(), end.Unix(), queryReturnsOKNoLatency)
+	client.QueryRangeHandler(httptest.NewRecorder(), r)
+
+	// Give time for the object to be written to cache in a separate goroutine from response
+	time.Sleep(time.Millisecond * 10)
+
+	keys := oc.RetainedKeys().Keys(now)
+	if len(keys) != 1 {
+		t.Fatalf("expected %d got %d", 1, len(keys))
+	}
+	var key string
+	for k := range keys {
+		key = k
+	}
+
+	j := NewRetentionJanitor(client, rsc.CacheClient, rsc.Logger)
+
+	// nothing is older than the max age yet
+	if tr, rm := j.Sweep(now); tr != 0 || rm != 0 {
+		t.Errorf("expected 0, 0 got %d, %d", tr, rm)
+	}
+
+	// the first half of the object is older than the max age
+	later := now.Add(time.Duration(9) * time.Hour)
+	if tr, rm := j.Sweep(later); tr != 1 || rm != 0 {
+		t.Errorf("expected 1, 0 got %d, %d", tr, rm)
+	}
+	doc, _, _, err := QueryCache(r.Context(), rsc.CacheClient, key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cts := doc.timeseries
+	if cacheType != "memory" {
+		cts, err = unmarshalCachedTimeseries(client, doc.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	el := cts.Extents()
+	cutoff := later.Add(-oc.TimeseriesMaxAge)
+	if len(el) != 1 || el[0].Start.Before(cutoff) || !el[0].End.Equal(end) {
+		t.Errorf("expected extent within %s got %s",
+			timeseries.Extent{Start: cutoff, End: end}, el)
+	}
+
+	// the whole object is older than the max age
+	if tr, rm := j.Sweep(now.Add(time.Duration(15) * time.Hour)); tr != 0 || rm != 1 {
+		t.Errorf("expected 0, 1 got %d, %d", tr, rm)
+	}
+	if len(oc.RetainedKeys().Keys(now)) != 0 {
+		t.Error("expected removed key to no longer be retained")
+	}
+}
@@ -0,0 +1,173 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	tc "github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// timeseriesShardWindow returns the window of the shards that the cache object of the time
+// range query is split into, or 0 when it is not sharded. Only queries whose step evenly
+// divides the window are sharded, so that each of their timestamps belongs to exactly one shard
+func timeseriesShardWindow(oc *oo.Options, trq *timeseries.TimeRangeQuery) time.Duration {
+	w := oc.TimeseriesShardWindow
+	if w <= 0 || trq.Step <= 0 || w%trq.Step != 0 {
+		return 0
+	}
+	return w
+}
+
+// shardExtents returns the extents of the shards of window w that overlap any of the extents
+// in el, in chronological order. Each shard extent ends on the last step before the next shard
+func shardExtents(w, step time.Duration, el timeseries.ExtentList) timeseries.ExtentList {
+	seen := make(map[int64]bool)
+	out := make(timeseries.ExtentList, 0, len(el))
+	for _, e := range el {
+		for t := e.Start.Truncate(w); !t.After(e.End); t = t.Add(w) {
+			if seen[t.UnixNano()] {
+				continue
+			}
+			seen[t.UnixNano()] = true
+			out = append(out, timeseries.Extent{Start: t, End: t.Add(w - step)})
+		}
+	}
+	sort.Sort(out)
+	return out
+}
+
+// shardKey returns the cache key of the shard starting at start of the cache object at key
+func shardKey(key string, start time.Time) string {
+	return key + ".shard." + strconv.FormatInt(start.Unix(), 10)
+}
+
+// queryTimeseriesShards reads the shards of the cache object at key that overlap the time
+// range query, and merges them into a single timeseries. Shards that are missing, or can't be
+// read, are treated as uncached ranges. The document of the first shard found is returned for
+// its headers, and the lookup status is a key miss when no shard was found
+func queryTimeseriesShards(ctx context.Context, pr *proxyRequest, key string, w time.Duration,
+	trq *timeseries.TimeRangeQuery) (timeseries.Timeseries, *HTTPDocument, status.LookupStatus, error) {
+
+	rsc := request.GetResources(pr.Request)
+	cache := rsc.CacheClient
+	client := rsc.OriginClient.(origins.TimeseriesClient)
+
+	var cts timeseries.Timeseries
+	var cdoc *HTTPDocument
+	tss := make([]timeseries.Timeseries, 0)
+	for _, e := range shardExtents(w, trq.Step, timeseries.ExtentList{trq.Extent}) {
+		sk := shardKey(key, e.Start)
+		doc, lookupStatus, _, err := QueryCache(ctx, cache, sk, nil)
+		if err != nil || lookupStatus != status.LookupStatusHit || doc == nil {
+			continue
+		}
+		ts, err := cachedTimeseries(client, doc)
+		if err == nil && doc.timeseries != nil {
+			// the shard is cloned, since merging would otherwise modify the cached reference
+			ts = ts.Clone()
+		}
+		if err != nil || ts == nil {
+			pr.Logger.Error("cache object unmarshaling failed",
+				tl.Pairs{"key": sk, "originName": client.Name()})
+			go cache.Remove(sk)
+			continue
+		}
+		if cts == nil {
+			cts, cdoc = ts, doc
+			continue
+		}
+		tss = append(tss, ts)
+	}
+
+	if cts == nil {
+		return nil, nil, status.LookupStatusKeyMiss, tc.ErrKNF
+	}
+	if len(tss) > 0 {
+		cts.Merge(true, tss...)
+	}
+	return cts, cdoc, status.LookupStatusHit, nil
+}
+
+// writeTimeseriesShards writes the shards of the cacheable timeseries that overlap any of the
+// extents in el to the cache object at key, with the provided ttl. Its other shards are left in
+// the cache as they are, so that adding new data to a cache object only rewrites the shards
+// holding the new data
+func writeTimeseriesShards(ctx context.Context, pr *proxyRequest, key string, w, step time.Duration,
+	cts timeseries.Timeseries, rdoc *HTTPDocument, el timeseries.ExtentList, ttl time.Duration) {
+
+	rsc := request.GetResources(pr.Request)
+	oc := rsc.OriginConfig
+	cache := rsc.CacheClient
+	client := rsc.OriginClient.(origins.TimeseriesClient)
+
+	for _, e := range shardExtents(w, step, el) {
+		ts := cts.Clone()
+		ts.CropToRange(e)
+		if len(ts.Extents()) == 0 {
+			continue
+		}
+		sk := shardKey(key, e.Start)
+		doc := &HTTPDocument{
+			Status:     rdoc.Status,
+			StatusCode: rdoc.StatusCode,
+			Headers:    rdoc.SafeHeaderClone(),
+		}
+		if rsc.CacheConfig.CacheType == "memory" {
+			doc.setTimeseries(client, ts)
+		} else {
+			var err error
+			doc.Body, err = marshalCachedTimeseries(client, ts)
+			if err != nil {
+				pr.Logger.Error("error marshaling timeseries",
+					tl.Pairs{"cacheKey": sk, "detail": err.Error()})
+				continue
+			}
+		}
+		if err := WriteCache(ctx, cache, sk, doc, ttl, oc.CompressableTypes); err != nil {
+			pr.Logger.Error("error writing object to cache",
+				tl.Pairs{
+					"originName": oc.Name,
+					"cacheName":  cache.Configuration().Name,
+					"cacheKey":   sk,
+					"detail":     err.Error(),
+				},
+			)
+			continue
+		}
+		retainKey(oc, sk, time.Now())
+	}
+}
+
+// removeTimeseriesShards removes the shards of the cache object at key that overlap extent e
+func removeTimeseriesShards(cache tc.Cache, key string, w, step time.Duration, e timeseries.Extent) {
+	el := shardExtents(w, step, timeseries.ExtentList{e})
+	keys := make([]string, len(el))
+	for i := range el {
+		keys[i] = shardKey(key, el[i].Start)
+	}
+	cache.BulkRemove(keys)
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+
+	mockprom "github.com/tricksterproxy/mockster/pkg/mocks/prometheus"
+)
+
+func TestTimeseriesShardWindow(t *testing.T) {
+
+	tests := []struct {
+		window, step, expected time.Duration
+	}{
+		{0, time.Minute, 0},
+		{time.Hour, time.Minute, time.Hour},
+		{time.Hour, 7 * time.Minute, 0},
+		{time.Hour, 0, 0},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			oc := oo.NewOptions()
+			oc.TimeseriesShardWindow = test.window
+			w := timeseriesShardWindow(oc, &timeseries.TimeRangeQuery{Step: test.step})
+			if w != test.expected {
+				t.Errorf("expected %s got %s", test.expected, w)
+			}
+		})
+	}
+}
+
+func TestShardExtents(t *testing.T) {
+
+	w := time.Duration(3600) * time.Second
+	step := time.Duration(60) * time.Second
+
+	el := timeseries.ExtentList{
+		{Start: time.Unix(9000, 0), End: time.Unix(9600, 0)},
+		{Start: time.Unix(1800, 0), End: time.Unix(7500, 0)},
+	}
+	expected := timeseries.ExtentList{
+		{Start: time.Unix(0, 0), End: time.Unix(3540, 0)},
+		{Start: time.Unix(3600, 0), End: time.Unix(7140, 0)},
+		{Start: time.Unix(7200, 0), End: time.Unix(10740, 0)},
+	}
+
+	out := shardExtents(w, step, el)
+	if out.String() != expected.String() {
+		t.Errorf("expected %s got %s", expected.String(), out.String())
+	}
+
+	if k := shardKey("test", time.Unix(3600, 0)); k != "test.shard.3600" {
+		t.Errorf("expected %s got %s", "test.shard.3600", k)
+	}
+}
+
+func TestDeltaProxyCacheRequestShards(t *testing.T) {
+	for _, cacheType := range []string{"memory", "test"} {
+		t.Run(cacheType, func(t *testing.T) {
+			testDeltaProxyCacheRequestShards(t, cacheType)
+		})
+	}
+}
+
+func testDeltaProxyCacheRequestShards(t *testing.T, cacheType string) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	rsc.CacheConfig.CacheType = cacheType
+	oc.FastForwardDisable = true
+	oc.TimeseriesShardWindow = time.Duration(1) * time.Hour
+
+	step := time.Duration(300) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+
+	request := func(extr timeseries.Extent, expectedStatus string) {
+		extn := timeseries.Extent{Start: extr.Start.Truncate(step), End: extr.End.Truncate(step)}
+		expected, _, _ := mockprom.GetTimeSeriesData(queryReturnsOKNoLatency, extn.Start, extn.End, step)
+
+		r.URL = u
+		u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+			int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+		w = httptest.NewRecorder()
+		client.QueryRangeHandler(w, r)
+		resp := w.Result()
+
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		err = testStringMatch(string(bodyBytes), expected)
+		if err != nil {
+			t.Error(err)
+		}
+
+		err = testStatusCodeMatch(resp.StatusCode, http.StatusOK)
+		if err != nil {
+			t.Error(err)
+		}
+
+		err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": expectedStatus})
+		if err != nil {
+			t.Error(err)
+		}
+
+		// Give time for the shards to be written to cache in a separate goroutine from response
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	// the first request is stored in 7 shards, which are reassembled for the second
+	request(timeseries.Extent{Start: end.Add(-time.Duration(6) * time.Hour), End: end}, "kmiss")
+	request(timeseries.Extent{Start: end.Add(-time.Duration(6) * time.Hour), End: end}, "hit")
+
+	// a request for a subset of the shards is served from just those shards
+	request(timeseries.Extent{Start: end.Add(-time.Duration(3) * time.Hour), End: end}, "hit")
+
+	// extending the range only fetches, and writes the shards of, the uncached range
+	request(timeseries.Extent{Start: end.Add(-time.Duration(6) * time.Hour), End: end.Add(time.Hour)}, "phit")
+	request(timeseries.Extent{Start: end.Add(-time.Duration(6) * time.Hour), End: end.Add(time.Hour)}, "hit")
+}
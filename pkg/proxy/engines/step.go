@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// applyMinStep raises the step of the time range query to the origin's minimum step, so
+// that the very small, jittered steps requested while zooming in share a cache entry.
+// It returns true when the step was changed
+func applyMinStep(pr *proxyRequest, trq *timeseries.TimeRangeQuery) bool {
+	rsc := request.GetResources(pr.Request)
+	minStep := rsc.OriginConfig.MinStep
+	if minStep <= 0 || trq.Step >= minStep {
+		return false
+	}
+	requestedStep := trq.Step
+	if !setQueryStep(pr, trq, minStep) {
+		return false
+	}
+	pr.Logger.Debug("raised query step to origin minimum",
+		tl.Pairs{"requestedStep": requestedStep, "step": minStep})
+	return true
+}
+
+// setQueryStep changes the step of the time range query and its upstream request, when
+// the origin client supports it. It returns true when the step was changed
+func setQueryStep(pr *proxyRequest, trq *timeseries.TimeRangeQuery, step time.Duration) bool {
+	rsc := request.GetResources(pr.Request)
+	stepper, ok := rsc.OriginClient.(origins.TimeseriesStepper)
+	if !ok {
+		return false
+	}
+	stepper.SetStep(pr.upstreamRequest, trq, step)
+	trq.Step = step
+	return true
+}
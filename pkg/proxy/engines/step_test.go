@@ -0,0 +1,108 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+
+	mockprom "github.com/tricksterproxy/mockster/pkg/mocks/prometheus"
+)
+
+func TestDeltaProxyCacheRequestMinStep(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	oc.FastForwardDisable = true
+	oc.MinStep = time.Duration(60) * time.Second
+	oc.RangeSnap = timeseries.SnapOutward
+
+	step := time.Duration(15) * time.Second
+	// the end is between step boundaries, so that it is always snapped up to the next one
+	end := time.Now().Add(-time.Duration(12) * time.Hour).Truncate(oc.MinStep).
+		Add(time.Duration(30) * time.Second)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(1) * time.Hour), End: end}
+
+	// the query is served at the minimum step, with its end snapped up to the next step
+	expected, _, _ := mockprom.GetTimeSeriesData(queryReturnsOKNoLatency,
+		normalizeTime(extr.Start, oc.MinStep), normalizeTime(extr.End, oc.MinStep).Add(oc.MinStep), oc.MinStep)
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testStringMatch(string(bodyBytes), expected)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusOK)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestApplyMinStep(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	pr := newProxyRequest(r, w)
+	trq := &timeseries.TimeRangeQuery{Step: time.Duration(15) * time.Second}
+
+	if applyMinStep(pr, trq) {
+		t.Error("expected no change without a minimum step")
+	}
+
+	rsc.OriginConfig.MinStep = time.Duration(60) * time.Second
+	if !applyMinStep(pr, trq) {
+		t.Error("expected the step to be raised")
+	}
+	if trq.Step != rsc.OriginConfig.MinStep {
+		t.Errorf("expected %s got %s", rsc.OriginConfig.MinStep, trq.Step)
+	}
+	if v := pr.upstreamRequest.URL.Query().Get(upStep); v != "60" {
+		t.Errorf("expected %s got %s", "60", v)
+	}
+
+	if applyMinStep(pr, trq) {
+		t.Error("expected no change for a step at the minimum")
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+var errMalformedJSON = errors.New("malformed json body")
+
+// validateCacheable checks that an upstream response body is fit to be cached for the
+// origin, so that truncated or malformed responses, like error pages from a load balancer
+// in front of the origin, are proxied without being stored and served until they expire.
+// contentLength is the body length declared by the upstream, or -1 when it is unknown
+func validateCacheable(rsc *request.Resources, h http.Header, contentLength int64,
+	body []byte) error {
+	oc := rsc.OriginConfig
+	if contentLength >= 0 && int64(len(body)) != contentLength {
+		return fmt.Errorf("truncated body: read %d of %d bytes", len(body), contentLength)
+	}
+	if oc.CacheMaxBodyBytes > 0 && len(body) > oc.CacheMaxBodyBytes {
+		return fmt.Errorf("body size %d exceeds the limit of %d bytes",
+			len(body), oc.CacheMaxBodyBytes)
+	}
+	ct := h.Get(headers.NameContentType)
+	mt, _, _ := mime.ParseMediaType(ct)
+	if len(oc.CacheContentTypes) > 0 && !containsMediaType(oc.CacheContentTypes, mt) {
+		return fmt.Errorf("content type %q is not cacheable", ct)
+	}
+	// encoded bodies can't be inspected, and other origin types may proxy arbitrary content
+	if _, ok := rsc.OriginClient.(origins.TimeseriesClient); ok && len(body) > 0 &&
+		h.Get(headers.NameContentEncoding) == "" && isJSONMediaType(mt) && !json.Valid(body) {
+		return errMalformedJSON
+	}
+	return nil
+}
+
+// upstreamBodyLength returns the body length declared by the upstream response, when it
+// is a full response whose body is proxied as-is, or else -1
+func upstreamBodyLength(r *http.Request, resp *http.Response) int64 {
+	if resp == nil || resp.StatusCode != http.StatusOK || r.Method == http.MethodHead {
+		return -1
+	}
+	if pc := request.GetResources(r).PathConfig; pc != nil && pc.HasCustomResponseBody {
+		return -1
+	}
+	return resp.ContentLength
+}
+
+// logUncacheable logs an upstream response that failed validation and won't be cached
+func logUncacheable(pr *proxyRequest, err error) {
+	pr.Logger.Warn("upstream response failed validation and was not cached",
+		tl.Pairs{"originName": request.GetResources(pr.Request).OriginConfig.Name,
+			"url": pr.URL.String(), "detail": err.Error()})
+}
+
+func containsMediaType(list []string, mt string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, mt) {
+			return true
+		}
+	}
+	return false
+}
+
+func isJSONMediaType(mt string) bool {
+	return mt == headers.ValueApplicationJSON || strings.HasSuffix(mt, "+json")
+}
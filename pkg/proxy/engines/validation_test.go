@@ -0,0 +1,119 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+)
+
+func TestValidateCacheable(t *testing.T) {
+
+	jsonHeader := http.Header{headers.NameContentType: []string{"application/json; charset=utf-8"}}
+	textHeader := http.Header{headers.NameContentType: []string{"text/html"}}
+	gzipHeader := http.Header{headers.NameContentType: []string{headers.ValueApplicationJSON},
+		headers.NameContentEncoding: []string{"gzip"}}
+
+	tests := []struct {
+		maxBytes int
+		types    []string
+		isTS     bool
+		h        http.Header
+		cl       int64
+		body     string
+		valid    bool
+	}{
+		{0, nil, true, jsonHeader, -1, `{"status":"success"}`, true},
+		{0, nil, true, jsonHeader, 20, `{"status":"success"}`, true},
+		{0, nil, true, jsonHeader, 32, `{"status":"success"}`, false},
+		{0, nil, true, jsonHeader, -1, `{"status":"succ`, false},
+		{0, nil, true, gzipHeader, -1, `{"status":"succ`, true},
+		{0, nil, false, jsonHeader, -1, `{"status":"succ`, true},
+		{0, nil, true, textHeader, -1, `<html>`, true},
+		{10, nil, true, textHeader, -1, `<html>`, true},
+		{5, nil, true, textHeader, -1, `<html>`, false},
+		{0, []string{"application/json"}, true, textHeader, -1, `<html>`, false},
+		{0, []string{"text/html", "application/json"}, true, jsonHeader, -1, `[]`, true},
+	}
+
+	for i, test := range tests {
+		oc := oo.NewOptions()
+		oc.CacheMaxBodyBytes = test.maxBytes
+		oc.CacheContentTypes = test.types
+		rsc := &request.Resources{OriginConfig: oc}
+		if test.isTS {
+			rsc.OriginClient = &TestClient{}
+		}
+		err := validateCacheable(rsc, test.h, test.cl, []byte(test.body))
+		if test.valid && err != nil {
+			t.Errorf("test %d: unexpected error: %s", i, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("test %d: expected error", i)
+		}
+	}
+}
+
+func TestObjectProxyCacheRequestMalformedJSON(t *testing.T) {
+
+	hdrs := map[string]string{"Cache-Control": "max-age=60",
+		headers.NameContentType: headers.ValueApplicationJSON}
+	ts, _, r, _, err := setupTestHarnessOPC("", `{"status":"succ`, http.StatusOK, hdrs)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	// the malformed response is proxied, but not cached
+	for i := 0; i < 2; i++ {
+		_, e := testFetchOPC(r, http.StatusOK, `{"status":"succ`, map[string]string{"status": "kmiss"})
+		for _, err = range e {
+			t.Error(err)
+		}
+	}
+}
+
+func TestObjectProxyCacheRequestContentTypes(t *testing.T) {
+
+	hdrs := map[string]string{"Cache-Control": "max-age=60", headers.NameContentType: "text/html"}
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "<html>", http.StatusOK, hdrs)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	rsc.OriginConfig.CacheContentTypes = []string{headers.ValueApplicationJSON}
+	for i := 0; i < 2; i++ {
+		_, e := testFetchOPC(r, http.StatusOK, "<html>", map[string]string{"status": "kmiss"})
+		for _, err = range e {
+			t.Error(err)
+		}
+	}
+
+	rsc.OriginConfig.CacheContentTypes = []string{"text/html"}
+	_, e := testFetchOPC(r, http.StatusOK, "<html>", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	_, e = testFetchOPC(r, http.StatusOK, "<html>", map[string]string{"status": "hit"})
+	for _, err = range e {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"time"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+)
+
+// deltaOverlap is the portion of a cached extent that a delta fetch was extended back into,
+// so that the samples fetched for it can be compared with the cached samples
+type deltaOverlap struct {
+	cached  timeseries.Extent
+	overlap timeseries.Extent
+}
+
+// extendDeltas extends each range missing from the cache back by up to the number of
+// steps into the cached extent that it immediately follows, and returns the extended ranges
+// along with the overlaps to verify once they are fetched. The ranges are unchanged if the
+// cached timeseries can't be compared
+func extendDeltas(cts timeseries.Timeseries, missRanges timeseries.ExtentList,
+	step time.Duration, steps int) (timeseries.ExtentList, []deltaOverlap) {
+	if _, ok := cts.(timeseries.Differ); !ok || len(missRanges) == 0 || step <= 0 {
+		return missRanges, nil
+	}
+	el := cts.Extents()
+	out := make(timeseries.ExtentList, len(missRanges))
+	var overlaps []deltaOverlap
+	for i, m := range missRanges {
+		out[i] = m
+		for _, c := range el {
+			// the cached extent ends at the step before the missing range
+			if !c.End.Before(m.Start) || c.End.Before(m.Start.Add(-step)) {
+				continue
+			}
+			start := c.End.Add(-step * time.Duration(steps-1))
+			if start.Before(c.Start) {
+				start = c.Start
+			}
+			out[i].Start = start
+			overlaps = append(overlaps, deltaOverlap{cached: c,
+				overlap: timeseries.Extent{Start: start, End: c.End}})
+			break
+		}
+	}
+	return out, overlaps
+}
+
+// verifyDeltas compares the samples fetched for each overlap with the cached samples, and
+// logs and counts any mismatches, which indicate that the origin's data changed after it was
+// cached, as when samples are backfilled or a counter is reset. The cached samples of a
+// mismatched overlap are removed from the returned timeseries, so that the fetched samples
+// replace them. When the origin invalidates mismatched extents, the rest of each of those
+// cached extents is also returned, to be removed from the cache
+func verifyDeltas(pr *proxyRequest, oc *oo.Options, key string, cts timeseries.Timeseries,
+	mts []timeseries.Timeseries, overlaps []deltaOverlap,
+	step time.Duration) (timeseries.Timeseries, timeseries.ExtentList) {
+	var invalid timeseries.ExtentList
+	for _, o := range overlaps {
+		fts := fetchedTimeseries(mts, o.overlap)
+		if fts == nil {
+			continue
+		}
+		n := cts.(timeseries.Differ).Diff(fts, o.overlap)
+		if n == 0 {
+			metrics.ProxyDeltaVerifications.WithLabelValues(oc.Name, oc.OriginType, "match").Inc()
+			continue
+		}
+		metrics.ProxyDeltaVerifications.WithLabelValues(oc.Name, oc.OriginType, "mismatch").Inc()
+		cts = removeExtent(cts, o.overlap, step)
+		invalidate := oc.DeltaVerifyInvalidate && o.overlap.Start.After(o.cached.Start)
+		if invalidate {
+			invalid = append(invalid, timeseries.Extent{Start: o.cached.Start,
+				End: o.overlap.Start.Add(-step)})
+		}
+		pr.Logger.Warn("delta fetch did not match the cached data it overlaps",
+			tl.Pairs{"originName": oc.Name, "cacheKey": key, "extent": o.overlap.String(),
+				"mismatchedSamples": n, "invalidated": invalidate})
+	}
+	return cts, invalid
+}
+
+// fetchedTimeseries returns the fetched timeseries whose extents include the extent
+func fetchedTimeseries(mts []timeseries.Timeseries, e timeseries.Extent) timeseries.Timeseries {
+	for _, ts := range mts {
+		for _, x := range ts.Extents() {
+			if !x.Start.After(e.Start) && !x.End.Before(e.End) {
+				return ts
+			}
+		}
+	}
+	return nil
+}
+
+// removeExtent returns the timeseries without its data within the extent. The provided
+// timeseries is not modified
+func removeExtent(ts timeseries.Timeseries, e timeseries.Extent,
+	step time.Duration) timeseries.Timeseries {
+	el := ts.Extents()
+	if len(el) == 0 {
+		return ts
+	}
+	first, last := el[0].Start, el[len(el)-1].End
+	var out timeseries.Timeseries
+	if e.Start.After(first) {
+		out = ts.Clone()
+		out.CropToRange(timeseries.Extent{Start: first, End: e.Start.Add(-step)})
+	}
+	if e.End.Before(last) {
+		tail := ts.Clone()
+		tail.CropToRange(timeseries.Extent{Start: e.End.Add(step), End: last})
+		if out != nil {
+			// the head is merged into the tail, since appending to the tail's values does
+			// not overwrite the values that the clones share with the provided timeseries
+			tail.Merge(true, out)
+		}
+		return tail
+	}
+	if out == nil {
+		// the extent includes all of the data, so it is cropped to a range outside of it
+		out = ts.Clone()
+		after := last.Add(step)
+		out.CropToRange(timeseries.Extent{Start: after, End: after})
+	}
+	return out
+}
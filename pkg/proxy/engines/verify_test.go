@@ -0,0 +1,219 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+
+	"github.com/prometheus/common/model"
+	mockprom "github.com/tricksterproxy/mockster/pkg/mocks/prometheus"
+)
+
+func testVerifyMatrix(step time.Duration, e timeseries.Extent,
+	value func(time.Time) float64) *MatrixEnvelope {
+	var values []model.SamplePair
+	for t := e.Start; !t.After(e.End); t = t.Add(step) {
+		values = append(values, model.SamplePair{Timestamp: model.TimeFromUnix(t.Unix()),
+			Value: model.SampleValue(value(t))})
+	}
+	return &MatrixEnvelope{
+		Data: MatrixData{ResultType: "matrix", Result: model.Matrix{
+			&model.SampleStream{Metric: model.Metric{"__name__": "a"}, Values: values}}},
+		ExtentList:   timeseries.ExtentList{e},
+		StepDuration: step,
+	}
+}
+
+func TestExtendDeltas(t *testing.T) {
+
+	step := time.Minute
+	cached := timeseries.Extent{Start: time.Unix(600, 0), End: time.Unix(1200, 0)}
+	cts := testVerifyMatrix(step, cached, func(time.Time) float64 { return 1 })
+
+	missRanges := timeseries.ExtentList{
+		{Start: time.Unix(0, 0), End: time.Unix(540, 0)},
+		{Start: time.Unix(1260, 0), End: time.Unix(1800, 0)},
+	}
+
+	el, overlaps := extendDeltas(cts, missRanges, step, 3)
+	expected := timeseries.ExtentList{missRanges[0],
+		{Start: time.Unix(1080, 0), End: time.Unix(1800, 0)}}
+	if el.String() != expected.String() {
+		t.Errorf("expected %s got %s", expected, el)
+	}
+	if len(overlaps) != 1 || overlaps[0].cached != cached || overlaps[0].overlap !=
+		(timeseries.Extent{Start: time.Unix(1080, 0), End: cached.End}) {
+		t.Errorf("unexpected overlaps %v", overlaps)
+	}
+	// the original ranges are not modified
+	if !missRanges[1].Start.Equal(time.Unix(1260, 0)) {
+		t.Errorf("expected %d got %d", 1260, missRanges[1].Start.Unix())
+	}
+
+	// the overlap does not extend beyond the cached extent
+	el, overlaps = extendDeltas(cts, missRanges, step, 100)
+	if !el[1].Start.Equal(cached.Start) || len(overlaps) != 1 {
+		t.Errorf("expected %d got %d", cached.Start.Unix(), el[1].Start.Unix())
+	}
+}
+
+func TestVerifyDeltas(t *testing.T) {
+
+	step := time.Minute
+	oc := oo.NewOptions()
+	oc.DeltaVerifyInvalidate = true
+	pr := &proxyRequest{Logger: testLogger}
+
+	cached := timeseries.Extent{Start: time.Unix(600, 0), End: time.Unix(1200, 0)}
+	overlap := timeseries.Extent{Start: time.Unix(1080, 0), End: cached.End}
+	fetched := timeseries.Extent{Start: overlap.Start, End: time.Unix(1800, 0)}
+	overlaps := []deltaOverlap{{cached: cached, overlap: overlap}}
+	cts := testVerifyMatrix(step, cached, func(time.Time) float64 { return 1 })
+
+	// matching samples leave the cached timeseries as it is
+	mts := []timeseries.Timeseries{testVerifyMatrix(step, fetched,
+		func(time.Time) float64 { return 1 })}
+	vts, invalid := verifyDeltas(pr, oc, "key", cts, mts, overlaps, step)
+	if vts != cts || len(invalid) != 0 {
+		t.Errorf("expected no changes, got %s %s", vts.Extents(), invalid)
+	}
+
+	// a backfilled sample replaces the overlapping cached samples, and the rest of the
+	// cached extent is invalidated
+	mts = []timeseries.Timeseries{testVerifyMatrix(step, fetched, func(t time.Time) float64 {
+		if t.Unix() == 1140 {
+			return 2
+		}
+		return 1
+	})}
+	vts, invalid = verifyDeltas(pr, oc, "key", cts, mts, overlaps, step)
+	expected := timeseries.ExtentList{{Start: cached.Start, End: time.Unix(1020, 0)}}
+	if vts.Extents().String() != expected.String() {
+		t.Errorf("expected %s got %s", expected, vts.Extents())
+	}
+	if invalid.String() != expected.String() {
+		t.Errorf("expected %s got %s", expected, invalid)
+	}
+	if cts.ValueCount() != 11 {
+		t.Errorf("expected %d got %d", 11, cts.ValueCount())
+	}
+
+	oc.DeltaVerifyInvalidate = false
+	_, invalid = verifyDeltas(pr, oc, "key", cts, mts, overlaps, step)
+	if len(invalid) != 0 {
+		t.Errorf("expected no invalid extents, got %s", invalid)
+	}
+}
+
+func TestRemoveExtent(t *testing.T) {
+
+	step := time.Minute
+	e := timeseries.Extent{Start: time.Unix(600, 0), End: time.Unix(1200, 0)}
+	ts := testVerifyMatrix(step, e, func(time.Time) float64 { return 1 })
+
+	tests := []struct {
+		remove   timeseries.Extent
+		expected string
+		values   int
+	}{
+		{timeseries.Extent{Start: time.Unix(780, 0), End: time.Unix(900, 0)}, "600-720;960-1200", 8},
+		{timeseries.Extent{Start: time.Unix(0, 0), End: time.Unix(900, 0)}, "960-1200", 5},
+		{timeseries.Extent{Start: time.Unix(1080, 0), End: time.Unix(1200, 0)}, "600-1020", 8},
+		{e, "", 0},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			out := removeExtent(ts, test.remove, step)
+			if s := out.Extents().String(); s != test.expected {
+				t.Errorf("expected %s got %s", test.expected, s)
+			}
+			if n := out.ValueCount(); n != test.values {
+				t.Errorf("expected %d got %d", test.values, n)
+			}
+		})
+	}
+
+	if ts.ValueCount() != 11 {
+		t.Errorf("expected %d got %d", 11, ts.ValueCount())
+	}
+}
+
+func TestDeltaProxyCacheRequestDeltaVerify(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	oc.FastForwardDisable = true
+	oc.DeltaVerifySteps = 3
+
+	step := time.Duration(60) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour).Truncate(step)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(1) * time.Hour), End: end}
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+	client.QueryRangeHandler(w, r)
+	if err := testResultHeaderPartMatch(w.Result().Header,
+		map[string]string{"status": "kmiss"}); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(time.Millisecond * 10)
+
+	// the delta fetch is extended back by 2 steps, to include 3 cached samples
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Add(time.Hour).Unix(),
+		queryReturnsOKNoLatency)
+	r.URL = u
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+	if err := testStatusCodeMatch(resp.StatusCode, http.StatusOK); err != nil {
+		t.Error(err)
+	}
+	expected := fmt.Sprintf("[%d:%d]", extr.End.Add(-2*step).Unix(),
+		extr.End.Add(time.Hour).Unix())
+	if err := testResultHeaderPartMatch(resp.Header,
+		map[string]string{"status": "phit", "fetched": expected}); err != nil {
+		t.Error(err)
+	}
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+	expected, _, _ = mockprom.GetTimeSeriesData(queryReturnsOKNoLatency, extr.Start,
+		extr.End.Add(time.Hour), step)
+	if err := testStringMatch(string(bodyBytes), expected); err != nil {
+		t.Error(err)
+	}
+}
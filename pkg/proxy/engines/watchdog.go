@@ -0,0 +1,88 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// idleWatchdog cancels an upstream request when it waits too long for the response headers,
+// or, once the headers are received, for the next read of the response body
+type idleWatchdog struct {
+	cancel context.CancelFunc
+	timer  *time.Timer
+	idle   time.Duration
+	once   sync.Once
+}
+
+// newIdleWatchdog returns a watchdog for the request, and the request with its cancelable
+// context. A zero timeout is not enforced
+func newIdleWatchdog(r *http.Request, headerTimeout,
+	idleTimeout time.Duration) (*idleWatchdog, *http.Request) {
+	ctx, cancel := context.WithCancel(r.Context())
+	wd := &idleWatchdog{cancel: cancel, idle: idleTimeout}
+	if headerTimeout > 0 {
+		wd.timer = time.AfterFunc(headerTimeout, cancel)
+	}
+	return wd, r.WithContext(ctx)
+}
+
+// watch returns the response body, which resets the idle timeout with each read, and
+// releases the watchdog when it is closed
+func (wd *idleWatchdog) watch(body io.ReadCloser) io.ReadCloser {
+	if wd.timer != nil {
+		wd.timer.Stop()
+	}
+	wd.timer = nil
+	if wd.idle > 0 {
+		wd.timer = time.AfterFunc(wd.idle, wd.cancel)
+	}
+	return &watchedBody{ReadCloser: body, wd: wd}
+}
+
+// Close stops the watchdog and cancels the request's context
+func (wd *idleWatchdog) Close() {
+	wd.once.Do(func() {
+		if wd.timer != nil {
+			wd.timer.Stop()
+		}
+		wd.cancel()
+	})
+}
+
+type watchedBody struct {
+	io.ReadCloser
+	wd *idleWatchdog
+}
+
+func (b *watchedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if b.wd.timer != nil {
+		b.wd.timer.Reset(b.wd.idle)
+	}
+	return n, err
+}
+
+func (b *watchedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.wd.Close()
+	return err
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdleWatchdog(t *testing.T) {
+
+	r := httptest.NewRequest("GET", "/", nil)
+
+	// the header timeout cancels the request
+	_, r2 := newIdleWatchdog(r, 10*time.Millisecond, 0)
+	select {
+	case <-r2.Context().Done():
+	case <-time.After(time.Second):
+		t.Error("expected the request to be canceled")
+	}
+
+	// no timeouts
+	wd, r2 := newIdleWatchdog(r, 0, 0)
+	body := wd.watch(ioutil.NopCloser(strings.NewReader("test")))
+	time.Sleep(20 * time.Millisecond)
+	if b, err := ioutil.ReadAll(body); err != nil || string(b) != "test" {
+		t.Errorf("expected test got %s %v", string(b), err)
+	}
+	if r2.Context().Err() != nil {
+		t.Error("expected the request not to be canceled")
+	}
+	body.Close()
+	if r2.Context().Err() == nil {
+		t.Error("expected the request to be canceled when the body is closed")
+	}
+
+	// the idle timeout is reset by each read, and cancels the request when it expires
+	wd, r2 = newIdleWatchdog(r, time.Second, 50*time.Millisecond)
+	body = wd.watch(ioutil.NopCloser(strings.NewReader("test")))
+	for i := 0; i < 4; i++ {
+		time.Sleep(20 * time.Millisecond)
+		body.Read(make([]byte, 1))
+	}
+	if r2.Context().Err() != nil {
+		t.Error("expected the request not to be canceled")
+	}
+	select {
+	case <-r2.Context().Done():
+	case <-time.After(time.Second):
+		t.Error("expected the request to be canceled")
+	}
+	body.Close()
+	wd.Close()
+}
@@ -0,0 +1,224 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package faults injects latency, errors and truncated responses into requests, so that
+// the behavior of dashboards and alerting can be validated while Trickster or its origins
+// are degraded. Faults are set at runtime through the Admin API
+package faults
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+// DefaultErrorStatus is the status of injected error responses when a Fault does not
+// provide one
+const DefaultErrorStatus = http.StatusServiceUnavailable
+
+// Fault describes the faults injected into requests whose path begins with Path. Each
+// rate is the fraction, from 0 to 1, of those requests into which the fault is injected
+type Fault struct {
+	// Path is the path prefix of the requests the Fault applies to
+	Path string `json:"path"`
+	// Latency is the delay added before the request is served
+	Latency time.Duration `json:"-"`
+	// LatencyMS is Latency in milliseconds
+	LatencyMS int `json:"latency_ms"`
+	// LatencyRate is the fraction of requests that are delayed by Latency
+	LatencyRate float64 `json:"latency_rate"`
+	// ErrorRate is the fraction of requests that receive an ErrorStatus response instead of
+	// being served
+	ErrorRate float64 `json:"error_rate"`
+	// ErrorStatus is the HTTP status of injected error responses
+	ErrorStatus int `json:"error_status"`
+	// TruncateRate is the fraction of responses whose body is cut off partway through, after
+	// which the connection is closed
+	TruncateRate float64 `json:"truncate_rate"`
+}
+
+// ErrInvalidRate is returned when a Fault has a rate outside of 0 to 1
+var ErrInvalidRate = errors.New("rates must be between 0 and 1")
+
+// ErrInvalidStatus is returned when a Fault has an error status that is not an HTTP error
+var ErrInvalidStatus = errors.New("error status must be between 400 and 599")
+
+// ErrInvalidPath is returned when a Fault has a path that does not begin with a slash
+var ErrInvalidPath = errors.New("path must begin with /")
+
+var (
+	faults   = make(map[string]*Fault)
+	faultsMu sync.RWMutex
+	randMu   sync.Mutex
+	rng      = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// Set validates the Fault, and injects it into requests matching its path, replacing any
+// Fault previously set for the same path
+func Set(f *Fault) error {
+	if !strings.HasPrefix(f.Path, "/") {
+		return ErrInvalidPath
+	}
+	for _, r := range []float64{f.LatencyRate, f.ErrorRate, f.TruncateRate} {
+		if r < 0 || r > 1 {
+			return ErrInvalidRate
+		}
+	}
+	if f.ErrorStatus == 0 {
+		f.ErrorStatus = DefaultErrorStatus
+	}
+	if f.ErrorStatus < 400 || f.ErrorStatus > 599 {
+		return ErrInvalidStatus
+	}
+	if f.LatencyMS < 0 {
+		f.LatencyMS = 0
+	}
+	f.Latency = time.Duration(f.LatencyMS) * time.Millisecond
+	faultsMu.Lock()
+	faults[f.Path] = f
+	faultsMu.Unlock()
+	return nil
+}
+
+// Remove stops injecting the Fault set for the path, and returns false if there was none
+func Remove(path string) bool {
+	faultsMu.Lock()
+	defer faultsMu.Unlock()
+	if _, ok := faults[path]; !ok {
+		return false
+	}
+	delete(faults, path)
+	return true
+}
+
+// Clear stops injecting all Faults
+func Clear() {
+	faultsMu.Lock()
+	faults = make(map[string]*Fault)
+	faultsMu.Unlock()
+}
+
+// List returns the Faults that are set, sorted by path
+func List() []*Fault {
+	faultsMu.RLock()
+	l := make([]*Fault, 0, len(faults))
+	for _, f := range faults {
+		l = append(l, f)
+	}
+	faultsMu.RUnlock()
+	sort.Slice(l, func(i, j int) bool { return l[i].Path < l[j].Path })
+	return l
+}
+
+// match returns the Fault with the longest path prefix of the provided path
+func match(path string) *Fault {
+	faultsMu.RLock()
+	defer faultsMu.RUnlock()
+	var m *Fault
+	for p, f := range faults {
+		if strings.HasPrefix(path, p) && (m == nil || len(p) > len(m.Path)) {
+			m = f
+		}
+	}
+	return m
+}
+
+func roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	randMu.Lock()
+	v := rng.Float64()
+	randMu.Unlock()
+	return v < rate
+}
+
+// Handler returns a handler that injects any Fault matching the request's path before
+// serving it with next
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f := match(r.URL.Path)
+		if f == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if f.Latency > 0 && roll(f.LatencyRate) {
+			t := time.NewTimer(f.Latency)
+			select {
+			case <-t.C:
+			case <-r.Context().Done():
+				t.Stop()
+				return
+			}
+		}
+		if roll(f.ErrorRate) {
+			w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
+			http.Error(w, "fault injected: "+http.StatusText(f.ErrorStatus), f.ErrorStatus)
+			return
+		}
+		if roll(f.TruncateRate) {
+			serveTruncated(w, r, next)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serveTruncated serves the request into a buffer, then sends the response with a
+// Content-Length of the full body, but only the first half of the body, before
+// aborting the connection
+func serveTruncated(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	bw := &bufferWriter{header: make(http.Header), code: http.StatusOK}
+	next.ServeHTTP(bw, r)
+	h := w.Header()
+	for k, v := range bw.header {
+		h[k] = v
+	}
+	h.Set(headers.NameContentLength, strconv.Itoa(bw.body.Len()))
+	w.WriteHeader(bw.code)
+	w.Write(bw.body.Bytes()[:bw.body.Len()/2])
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	// the server closes the connection without logging a panic
+	panic(http.ErrAbortHandler)
+}
+
+// bufferWriter is an http.ResponseWriter that buffers the response
+type bufferWriter struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (bw *bufferWriter) Header() http.Header {
+	return bw.header
+}
+
+func (bw *bufferWriter) WriteHeader(code int) {
+	bw.code = code
+}
+
+func (bw *bufferWriter) Write(b []byte) (int, error) {
+	return bw.body.Write(b)
+}
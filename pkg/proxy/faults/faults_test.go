@@ -0,0 +1,121 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package faults
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testBody = strings.Repeat("0123456789", 10)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(testBody))
+})
+
+func TestSet(t *testing.T) {
+	defer Clear()
+	tests := []struct {
+		f   *Fault
+		err error
+	}{
+		{&Fault{Path: "/", ErrorRate: 0.5}, nil},
+		{&Fault{Path: "/prom", LatencyMS: 10, LatencyRate: 1}, nil},
+		{&Fault{Path: "prom"}, ErrInvalidPath},
+		{&Fault{Path: "/", TruncateRate: -1}, ErrInvalidRate},
+		{&Fault{Path: "/", LatencyRate: 1.5}, ErrInvalidRate},
+		{&Fault{Path: "/", ErrorStatus: 302}, ErrInvalidStatus},
+	}
+	for i, test := range tests {
+		if err := Set(test.f); err != test.err {
+			t.Errorf("test %d: expected %v got %v", i, test.err, err)
+		}
+	}
+	l := List()
+	if len(l) != 2 || l[0].Path != "/" || l[1].Latency != 10*time.Millisecond {
+		t.Errorf("unexpected faults %v", l)
+	}
+	if l[0].ErrorStatus != DefaultErrorStatus {
+		t.Errorf("expected %d got %d", DefaultErrorStatus, l[0].ErrorStatus)
+	}
+	if match("/prom/api/v1/query") != l[1] || match("/influx") != l[0] {
+		t.Error("expected longest path prefix match")
+	}
+	if !Remove("/") || Remove("/") {
+		t.Error("expected a single removal")
+	}
+	if match("/influx") != nil {
+		t.Error("expected no match")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	defer Clear()
+	h := Handler(okHandler)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/prom/api/v1/query", nil))
+	if w.Code != http.StatusOK || w.Body.String() != testBody {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+
+	Set(&Fault{Path: "/prom", LatencyMS: 50, LatencyRate: 1, ErrorRate: 1,
+		ErrorStatus: http.StatusBadGateway})
+	start := time.Now()
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/prom/api/v1/query", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected %d got %d", http.StatusBadGateway, w.Code)
+	}
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("expected latency of at least 50ms, got %s", d)
+	}
+
+	// other paths are unaffected
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/influx/query", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestHandlerTruncate(t *testing.T) {
+	defer Clear()
+	Set(&Fault{Path: "/", TruncateRate: 1})
+	s := httptest.NewServer(Handler(okHandler))
+	defer s.Close()
+
+	resp, err := http.Get(s.URL + "/prom/api/v1/query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.ContentLength != int64(len(testBody)) {
+		t.Errorf("expected %d got %d", len(testBody), resp.ContentLength)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err == nil {
+		t.Error("expected truncated body error")
+	}
+	if len(b) != len(testBody)/2 {
+		t.Errorf("expected %d got %d", len(testBody)/2, len(b))
+	}
+}
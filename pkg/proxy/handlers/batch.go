@@ -0,0 +1,224 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+// MaxBatchQueries is the maximum number of queries in a single batch request
+const MaxBatchQueries = 100
+
+// OriginRouter returns the router of the named origin, which serves the origin's paths
+// without its path prefix, or nil when there is no such origin
+type OriginRouter func(originName string) http.Handler
+
+// BatchRequest is a batch of queries, in the style of Grafana's /api/ds/query payload
+type BatchRequest struct {
+	Queries []*BatchQuery `json:"queries"`
+}
+
+// BatchQuery is a single query in a BatchRequest, which is routed by the named origin's
+// router as though it were a request for the origin's path with the provided parameters
+type BatchQuery struct {
+	RefID  string            `json:"refId"`
+	Origin string            `json:"origin"`
+	Path   string            `json:"path"`
+	Method string            `json:"method,omitempty"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// BatchResponse holds the result of each query in a BatchRequest, by RefID
+type BatchResponse struct {
+	Results map[string]*BatchResult `json:"results"`
+}
+
+// BatchResult is the response to a single query in a BatchRequest. The body is included
+// as-is when it is JSON, and as a string otherwise
+type BatchResult struct {
+	Status      int             `json:"status"`
+	ContentType string          `json:"contentType,omitempty"`
+	Body        json.RawMessage `json:"body,omitempty"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// batchHeaderExclusions are the request headers that are not passed on to the
+// queries of a batch, since they describe the batch request itself
+var batchHeaderExclusions = map[string]bool{
+	headers.NameContentType:    true,
+	headers.NameContentLength:  true,
+	headers.NameAcceptEncoding: true,
+}
+
+// BatchHandleFunc responds to POST requests holding a BatchRequest, by running its queries
+// through the routers of their origins, up to concurrency at a time, and returning their
+// results in a BatchResponse. The queries share the batch request's context and headers,
+// so they are traced and authorized as part of the batch
+func BatchHandleFunc(origins OriginRouter,
+	concurrency int) func(http.ResponseWriter, *http.Request) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed),
+				http.StatusMethodNotAllowed)
+			return
+		}
+		br := &BatchRequest{}
+		if err := json.NewDecoder(r.Body).Decode(br); err != nil {
+			http.Error(w, "invalid batch request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(br.Queries) == 0 || len(br.Queries) > MaxBatchQueries {
+			http.Error(w, "invalid batch request: must have 1 to "+
+				strconv.Itoa(MaxBatchQueries)+" queries", http.StatusBadRequest)
+			return
+		}
+		resp := &BatchResponse{Results: make(map[string]*BatchResult, len(br.Queries))}
+		for _, q := range br.Queries {
+			if q == nil || q.RefID == "" {
+				http.Error(w, "invalid batch request: missing refId", http.StatusBadRequest)
+				return
+			}
+			if _, ok := resp.Results[q.RefID]; ok {
+				http.Error(w, "invalid batch request: duplicate refId: "+q.RefID,
+					http.StatusBadRequest)
+				return
+			}
+			resp.Results[q.RefID] = nil
+		}
+
+		results := make([]*BatchResult, len(br.Queries))
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, concurrency)
+		for i, q := range br.Queries {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int, q *BatchQuery) {
+				results[i] = runBatchQuery(origins, r, q)
+				<-sem
+				wg.Done()
+			}(i, q)
+		}
+		wg.Wait()
+		for i, q := range br.Queries {
+			resp.Results[q.RefID] = results[i]
+		}
+
+		b, err := json.Marshal(resp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(headers.NameContentType, headers.ValueApplicationJSON)
+		w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+	}
+}
+
+// runBatchQuery routes the query through its origin's router, as a request derived from
+// the batch request, and returns the query's result. Since the origin is resolved by name,
+// queries reach origins that are only routed by their hosts
+func runBatchQuery(origins OriginRouter, br *http.Request, q *BatchQuery) *BatchResult {
+	if q.Origin == "" || !strings.HasPrefix(q.Path, "/") {
+		return &BatchResult{Status: http.StatusBadRequest,
+			Error: "query requires an origin and an absolute path"}
+	}
+	router := origins(q.Origin)
+	if router == nil {
+		return &BatchResult{Status: http.StatusNotFound, Error: "unknown origin: " + q.Origin}
+	}
+	method := strings.ToUpper(q.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	v := url.Values{}
+	for k, p := range q.Params {
+		v.Set(k, p)
+	}
+	u := &url.URL{Path: q.Path}
+	var body *strings.Reader
+	if method == http.MethodPost {
+		body = strings.NewReader(v.Encode())
+	} else {
+		u.RawQuery = v.Encode()
+		body = strings.NewReader("")
+	}
+	r, err := http.NewRequestWithContext(br.Context(), method, u.String(), body)
+	if err != nil {
+		return &BatchResult{Status: http.StatusBadRequest, Error: err.Error()}
+	}
+	for k, vals := range br.Header {
+		if !batchHeaderExclusions[k] {
+			r.Header[k] = vals
+		}
+	}
+	if method == http.MethodPost {
+		r.Header.Set(headers.NameContentType, headers.ValueXFormURLEncoded)
+	}
+	r.Host = br.Host
+	r.RemoteAddr = br.RemoteAddr
+
+	bw := &batchWriter{h: http.Header{}}
+	router.ServeHTTP(bw, r)
+
+	res := &BatchResult{Status: bw.code, ContentType: bw.h.Get(headers.NameContentType)}
+	if res.Status == 0 {
+		res.Status = http.StatusOK
+	}
+	b := bw.body.Bytes()
+	if json.Valid(b) {
+		res.Body = b
+	} else if len(b) > 0 {
+		res.Body, _ = json.Marshal(string(b))
+	}
+	return res
+}
+
+// batchWriter is an http.ResponseWriter that records the response to a batch query
+type batchWriter struct {
+	h    http.Header
+	code int
+	body bytes.Buffer
+}
+
+func (w *batchWriter) Header() http.Header {
+	return w.h
+}
+
+func (w *batchWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *batchWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.body.Write(b)
+}
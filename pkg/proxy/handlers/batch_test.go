@@ -0,0 +1,144 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatchHandleFunc(t *testing.T) {
+
+	// the origin's router serves its paths without the origin's path prefix
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/query", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"query":"` + r.FormValue("query") + `"}`))
+	})
+	mux.HandleFunc("/federate", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("up 1"))
+	})
+	h := BatchHandleFunc(func(originName string) http.Handler {
+		if originName == "prom1" {
+			return mux
+		}
+		return nil
+	}, 4)
+
+	tests := []struct {
+		method, body string
+		expectedCode int
+		expected     map[string]BatchResult
+	}{
+		{http.MethodGet, "", http.StatusMethodNotAllowed, nil},
+		{http.MethodPost, "{", http.StatusBadRequest, nil},
+		{http.MethodPost, `{"queries":[]}`, http.StatusBadRequest, nil},
+		{http.MethodPost, `{"queries":[{"refId":"A"},{"refId":"A"}]}`, http.StatusBadRequest, nil},
+		{http.MethodPost, `{"queries":[
+			{"refId":"A","origin":"prom1","path":"/api/v1/query","params":{"query":"up"}},
+			{"refId":"B","origin":"prom1","path":"/api/v1/query","method":"post","params":{"query":"go"}},
+			{"refId":"C","origin":"prom1","path":"/federate"},
+			{"refId":"D","origin":"prom1","path":"/missing"},
+			{"refId":"E","path":"/api/v1/query"},
+			{"refId":"F","origin":"prom2","path":"/api/v1/query"}]}`,
+			http.StatusOK, map[string]BatchResult{
+				"A": {Status: 200, Body: json.RawMessage(`{"query":"up"}`)},
+				"B": {Status: 200, Body: json.RawMessage(`{"query":"go"}`)},
+				"C": {Status: 200, Body: json.RawMessage(`"up 1"`)},
+				"D": {Status: 404, Body: json.RawMessage(`"404 page not found\n"`)},
+				"E": {Status: 400},
+				"F": {Status: 404},
+			}},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(test.method, "http://0/trickster/batch",
+			strings.NewReader(test.body))
+		r.Header.Set("Authorization", "Bearer test")
+		h(w, r)
+		if w.Code != test.expectedCode {
+			t.Errorf("expected %d got %d", test.expectedCode, w.Code)
+			continue
+		}
+		if test.expected == nil {
+			continue
+		}
+		br := &BatchResponse{}
+		if err := json.Unmarshal(w.Body.Bytes(), br); err != nil {
+			t.Fatal(err)
+		}
+		if len(br.Results) != len(test.expected) {
+			t.Errorf("expected %d results got %d", len(test.expected), len(br.Results))
+		}
+		for k, e := range test.expected {
+			res, ok := br.Results[k]
+			if !ok {
+				t.Errorf("missing result %s", k)
+				continue
+			}
+			if res.Status != e.Status || string(res.Body) != string(e.Body) {
+				t.Errorf("expected %d %s for %s got %d %s", e.Status, e.Body, k,
+					res.Status, res.Body)
+			}
+		}
+	}
+}
+
+func TestBatchHandleFuncConcurrency(t *testing.T) {
+
+	var lock sync.Mutex
+	var running, max int
+	h := BatchHandleFunc(func(originName string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			running++
+			if running > max {
+				max = running
+			}
+			lock.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			lock.Lock()
+			running--
+			lock.Unlock()
+		})
+	}, 2)
+
+	queries := make([]string, 10)
+	for i := range queries {
+		queries[i] = `{"refId":"` + strconv.Itoa(i) + `","origin":"prom1","path":"/"}`
+	}
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPost, "http://0/trickster/batch",
+		strings.NewReader(`{"queries":[`+strings.Join(queries, ",")+`]}`)))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	if max > 2 {
+		t.Errorf("expected %d queries at a time got %d", 2, max)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+// startTime is used to report the process uptime in runtime snapshots
+var startTime = time.Now()
+
+// RuntimeSnapshot is a point-in-time summary of the Go runtime's goroutine and heap usage
+type RuntimeSnapshot struct {
+	Time          time.Time `json:"time"`
+	UptimeSecs    float64   `json:"uptime_secs"`
+	GoVersion     string    `json:"go_version"`
+	NumCPU        int       `json:"num_cpu"`
+	GOMAXPROCS    int       `json:"gomaxprocs"`
+	NumGoroutine  int       `json:"num_goroutine"`
+	HeapAlloc     uint64    `json:"heap_alloc_bytes"`
+	HeapInuse     uint64    `json:"heap_inuse_bytes"`
+	HeapIdle      uint64    `json:"heap_idle_bytes"`
+	HeapReleased  uint64    `json:"heap_released_bytes"`
+	HeapObjects   uint64    `json:"heap_objects"`
+	StackInuse    uint64    `json:"stack_inuse_bytes"`
+	Sys           uint64    `json:"sys_bytes"`
+	TotalAlloc    uint64    `json:"total_alloc_bytes"`
+	Mallocs       uint64    `json:"mallocs"`
+	Frees         uint64    `json:"frees"`
+	NumGC         uint32    `json:"num_gc"`
+	LastGC        time.Time `json:"last_gc"`
+	PauseTotalNs  uint64    `json:"gc_pause_total_ns"`
+	NextGC        uint64    `json:"next_gc_bytes"`
+	GCCPUFraction float64   `json:"gc_cpu_fraction"`
+}
+
+// NewRuntimeSnapshot returns a RuntimeSnapshot of the current process
+func NewRuntimeSnapshot() *RuntimeSnapshot {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	now := time.Now()
+	s := &RuntimeSnapshot{
+		Time:          now,
+		UptimeSecs:    now.Sub(startTime).Seconds(),
+		GoVersion:     runtime.Version(),
+		NumCPU:        runtime.NumCPU(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		NumGoroutine:  runtime.NumGoroutine(),
+		HeapAlloc:     ms.HeapAlloc,
+		HeapInuse:     ms.HeapInuse,
+		HeapIdle:      ms.HeapIdle,
+		HeapReleased:  ms.HeapReleased,
+		HeapObjects:   ms.HeapObjects,
+		StackInuse:    ms.StackInuse,
+		Sys:           ms.Sys,
+		TotalAlloc:    ms.TotalAlloc,
+		Mallocs:       ms.Mallocs,
+		Frees:         ms.Frees,
+		NumGC:         ms.NumGC,
+		PauseTotalNs:  ms.PauseTotalNs,
+		NextGC:        ms.NextGC,
+		GCCPUFraction: ms.GCCPUFraction,
+	}
+	if ms.LastGC > 0 {
+		s.LastGC = time.Unix(0, int64(ms.LastGC))
+	}
+	return s
+}
+
+// RuntimeSnapshotHandleFunc responds to the HTTP request with a JSON RuntimeSnapshot.
+// When the request includes the gc=true query parameter, a garbage collection is run
+// before the snapshot is taken, so that the heap figures only include live objects
+func RuntimeSnapshotHandleFunc() func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("gc") == "true" {
+			runtime.GC()
+		}
+		b, err := json.MarshalIndent(NewRuntimeSnapshot(), "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(headers.NameContentType, headers.ValueApplicationJSON)
+		w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+	}
+}
@@ -0,0 +1,50 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRuntimeSnapshotHandler(t *testing.T) {
+
+	h := RuntimeSnapshotHandleFunc()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "http://0/debug/snapshot?gc=true", nil)
+
+	h(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != 200 {
+		t.Errorf("expected 200 got %d.", resp.StatusCode)
+	}
+
+	s := &RuntimeSnapshot{}
+	if err := json.NewDecoder(resp.Body).Decode(s); err != nil {
+		t.Fatal(err)
+	}
+	if s.NumGoroutine == 0 || s.HeapAlloc == 0 {
+		t.Errorf("unexpected snapshot values: %+v", s)
+	}
+	// gc=true runs a collection before the snapshot
+	if s.NumGC == 0 || s.LastGC.IsZero() {
+		t.Error("expected a completed garbage collection")
+	}
+}
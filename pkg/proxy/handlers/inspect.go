@@ -0,0 +1,47 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	tctx "github.com/tricksterproxy/trickster/pkg/proxy/context"
+)
+
+// InspectHandleFunc responds to requests under the path, in the form of
+// <path>/<origin>/<origin path>, by routing them through the router to the origin path,
+// marked for inspection. Rather than serving the request, the origin's caching engine
+// responds with a report of how it would be served: its cache key, the extents already
+// cached, the deltas that would be fetched and the applicable cache settings. Nothing is
+// fetched from the origin or written to the cache
+func InspectHandleFunc(path string, router http.Handler) func(http.ResponseWriter, *http.Request) {
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	return func(w http.ResponseWriter, r *http.Request) {
+		p := strings.TrimPrefix(r.URL.Path, prefix)
+		if p == r.URL.Path || p == "" || strings.HasPrefix(p, "/") {
+			http.Error(w, "inspected requests must be in the form of "+prefix+
+				"<origin>/<path>", http.StatusBadRequest)
+			return
+		}
+		r2 := r.Clone(tctx.WithInspection(r.Context(), true))
+		r2.URL.Path = "/" + p
+		r2.URL.RawPath = ""
+		r2.RequestURI = r2.URL.RequestURI()
+		router.ServeHTTP(w, r2)
+	}
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	tctx "github.com/tricksterproxy/trickster/pkg/proxy/context"
+)
+
+func TestInspectHandleFunc(t *testing.T) {
+
+	var path string
+	var inspected bool
+	router := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		inspected = tctx.Inspection(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	f := InspectHandleFunc("/trickster/inspect", router)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet,
+		"http://0/trickster/inspect/prom1/api/v1/query_range?query=up", nil)
+	f(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	if path != "/prom1/api/v1/query_range" {
+		t.Errorf("expected %s got %s", "/prom1/api/v1/query_range", path)
+	}
+	if !inspected {
+		t.Error("expected request to be marked for inspection")
+	}
+	if tctx.Inspection(r.Context()) {
+		t.Error("expected original request to be unmarked")
+	}
+
+	for _, p := range []string{"/trickster/inspect/", "/trickster/inspect//prom1/"} {
+		w = httptest.NewRecorder()
+		f(w, httptest.NewRequest(http.MethodGet, "http://0"+p, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected %d got %d for %s", http.StatusBadRequest, w.Code, p)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// LogLevels reports the log level of the instance and of each origin
+type LogLevels struct {
+	Level   string            `json:"level"`
+	Origins map[string]string `json:"origins"`
+}
+
+// LogLevelHandleFunc responds to GET requests with the current LogLevels. POST and PUT
+// requests change the log level to the value of the level parameter, for the origin named
+// in the origin parameter or, when no origin is provided, for the instance. Setting an
+// origin's level to an empty value reverts it to following the instance's level. Changes
+// made with this handler last until the next configuration reload
+func LogLevelHandleFunc(log *tl.Logger) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPost, http.MethodPut:
+			lvl := strings.ToLower(r.FormValue("level"))
+			origin := r.FormValue("origin")
+			if (lvl != "" || origin == "") && !tl.IsValidLevel(lvl) {
+				http.Error(w, "invalid log level: "+lvl, http.StatusBadRequest)
+				return
+			}
+			if origin == "" {
+				log.SetLogLevel(lvl)
+			} else if !log.SetOriginLogLevel(origin, lvl) {
+				http.Error(w, "unknown origin: "+origin, http.StatusNotFound)
+				return
+			}
+			log.Info("log level changed", tl.Pairs{"originName": origin, "level": lvl})
+		default:
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed),
+				http.StatusMethodNotAllowed)
+			return
+		}
+		b, err := json.MarshalIndent(&LogLevels{Level: log.Level(),
+			Origins: log.OriginLogLevels()}, "", "  ")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(headers.NameContentType, headers.ValueApplicationJSON)
+		w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+	}
+}
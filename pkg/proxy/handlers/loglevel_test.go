@@ -0,0 +1,81 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+func TestLogLevelHandleFunc(t *testing.T) {
+
+	log := tl.ConsoleLogger("info")
+	log.OriginLogger("origin1", "")
+	log.OriginLogger("origin2", "error")
+	h := LogLevelHandleFunc(log)
+
+	tests := []struct {
+		method, url    string
+		expectedCode   int
+		expectedLevels *LogLevels
+	}{
+		{http.MethodGet, "/", http.StatusOK,
+			&LogLevels{"info", map[string]string{"origin1": "info", "origin2": "error"}}},
+		{http.MethodPut, "/?level=warn", http.StatusOK,
+			&LogLevels{"warn", map[string]string{"origin1": "warn", "origin2": "error"}}},
+		{http.MethodPost, "/?level=DEBUG&origin=origin1", http.StatusOK,
+			&LogLevels{"warn", map[string]string{"origin1": "debug", "origin2": "error"}}},
+		{http.MethodPost, "/?level=&origin=origin2", http.StatusOK,
+			&LogLevels{"warn", map[string]string{"origin1": "debug", "origin2": "warn"}}},
+		{http.MethodPost, "/?level=info&origin=origin3", http.StatusNotFound, nil},
+		{http.MethodPost, "/?level=invalid", http.StatusBadRequest, nil},
+		{http.MethodPost, "/", http.StatusBadRequest, nil},
+		{http.MethodDelete, "/", http.StatusMethodNotAllowed, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method+" "+test.url, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h(w, httptest.NewRequest(test.method, test.url, nil))
+			resp := w.Result()
+			if resp.StatusCode != test.expectedCode {
+				t.Fatalf("expected %d got %d", test.expectedCode, resp.StatusCode)
+			}
+			if test.expectedLevels == nil {
+				return
+			}
+			b, _ := ioutil.ReadAll(resp.Body)
+			ll := &LogLevels{}
+			if err := json.Unmarshal(b, ll); err != nil {
+				t.Fatal(err)
+			}
+			if ll.Level != test.expectedLevels.Level {
+				t.Errorf("expected %s got %s", test.expectedLevels.Level, ll.Level)
+			}
+			for k, v := range test.expectedLevels.Origins {
+				if ll.Origins[k] != v {
+					t.Errorf("expected %s got %s for origin %s", v, ll.Origins[k], k)
+				}
+			}
+		})
+	}
+}
@@ -32,24 +32,36 @@ func ReloadHandleFunc(f reload.ReloaderFunc, conf *config.Config, wg *sync.WaitG
 	log *tl.Logger, caches map[string]cache.Cache,
 	args []string) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if conf != nil {
-			conf.Main.ReloaderLock.Lock()
-			defer conf.Main.ReloaderLock.Unlock()
-			if conf.IsStale() {
-				log.Warn("configuration reload starting now", tl.Pairs{"source": "reloadEndpoint"})
-				err := f(conf, wg, log, caches, args, false)
-				if err == nil {
-					w.Header().Set(headers.NameContentType, headers.ValueTextPlain)
-					w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte("configuration reloaded"))
-					return
-				}
-			}
-		}
 		w.Header().Set(headers.NameContentType, headers.ValueTextPlain)
 		w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
 		w.WriteHeader(http.StatusOK)
+		reloaded, err := Reload(f, conf, wg, log, caches, args, "reloadEndpoint")
+		if reloaded {
+			w.Write([]byte("configuration reloaded"))
+			return
+		}
+		if err != nil {
+			// a failed reload leaves the running configuration intact
+			w.Write([]byte("configuration NOT reloaded: " + err.Error()))
+			return
+		}
 		w.Write([]byte("configuration NOT reloaded"))
 	}
 }
+
+// Reload reloads the running configuration if it has changed, and returns true if
+// the configuration was reloaded. The source identifies the requester in the log
+func Reload(f reload.ReloaderFunc, conf *config.Config, wg *sync.WaitGroup,
+	log *tl.Logger, caches map[string]cache.Cache, args []string, source string) (bool, error) {
+	if conf == nil {
+		return false, nil
+	}
+	conf.Main.ReloaderLock.Lock()
+	defer conf.Main.ReloaderLock.Unlock()
+	if !conf.IsStale() {
+		return false, nil
+	}
+	log.Warn("configuration reload starting now", tl.Pairs{"source": source})
+	err := f(conf, wg, log, caches, args, false)
+	return err == nil, err
+}
@@ -17,6 +17,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -64,4 +65,19 @@ func TestReloadHandleFunc(t *testing.T) {
 	ioutil.WriteFile(testFile, []byte(string(tml)), 0666)
 	time.Sleep(time.Millisecond * 500)
 	f(w, r)
+
+	// a failed reload reports its error
+	var failFunc = func(*config.Config, *sync.WaitGroup, *tl.Logger,
+		map[string]cache.Cache, []string, bool) error {
+		return errors.New("caches: test failure")
+	}
+	cfg, _, _ = config.Load("testing", "testing", []string{"-config", testFile})
+	cfg.ReloadConfig.RateLimitSecs = 0
+	time.Sleep(time.Millisecond * 500)
+	ioutil.WriteFile(testFile, []byte(string(tml)), 0666)
+	w = httptest.NewRecorder()
+	ReloadHandleFunc(failFunc, cfg, nil, log, nil, nil)(w, r)
+	if body := w.Body.String(); body != "configuration NOT reloaded: caches: test failure" {
+		t.Errorf("unexpected response body %s", body)
+	}
 }
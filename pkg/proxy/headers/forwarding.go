@@ -198,9 +198,9 @@ func SetVia(r *http.Request, hop *Hop) {
 		return
 	}
 	if hop.Via != "" {
-		r.Header.Set(NameVia, hop.Via+", "+hop.Protocol+" "+runtime.Server)
+		r.Header.Set(NameVia, hop.Via+", "+hop.Protocol+" "+runtime.Server())
 	} else {
-		r.Header.Set(NameVia, hop.Protocol+" "+runtime.Server)
+		r.Header.Set(NameVia, hop.Protocol+" "+runtime.Server())
 	}
 }
 
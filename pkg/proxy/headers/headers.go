@@ -30,8 +30,14 @@ import (
 const (
 	// Common HTTP Header Values
 
+	// ValueApplicationCSV represents the HTTP Header Value of "application/csv"
+	ValueApplicationCSV = "application/csv"
 	// ValueApplicationJSON represents the HTTP Header Value of "application/json"
 	ValueApplicationJSON = "application/json"
+	// ValueApplicationMsgPack represents the HTTP Header Value of "application/x-msgpack"
+	ValueApplicationMsgPack = "application/x-msgpack"
+	// ValueApplicationProtobuf represents the HTTP Header Value of "application/x-protobuf"
+	ValueApplicationProtobuf = "application/x-protobuf"
 	// ValueMaxAge represents the HTTP Header Value of "max-age"
 	ValueMaxAge = "max-age"
 	// ValueMultipartFormData represents the HTTP Header Value of "multipart/form-data"
@@ -52,8 +58,12 @@ const (
 	ValuePublic = "public"
 	// ValueSharedMaxAge represents the HTTP Header Value of "s-maxage"
 	ValueSharedMaxAge = "s-maxage"
+	// ValueTextCSV represents the HTTP Header Value of "text/csv"
+	ValueTextCSV = "text/csv"
 	// ValueTextPlain represents the HTTP Header Value of "text/plain"
 	ValueTextPlain = "text/plain"
+	// ValueTextEventStream represents the HTTP Header Value of "text/event-stream"
+	ValueTextEventStream = "text/event-stream"
 	// ValueXFormURLEncoded represents the HTTP Header Value of "application/x-www-form-urlencoded"
 	ValueXFormURLEncoded = "application/x-www-form-urlencoded"
 
@@ -64,6 +74,10 @@ const (
 
 	// NameCacheControl represents the HTTP Header Name of "Cache-Control"
 	NameCacheControl = "Cache-Control"
+	// NameAge represents the HTTP Header Name of "Age"
+	NameAge = "Age"
+	// NameXCache represents the HTTP Header Name of "X-Cache"
+	NameXCache = "X-Cache"
 	// NameAllowOrigin represents the HTTP Header Name of "Access-Control-Allow-Origin"
 	NameAllowOrigin = "Access-Control-Allow-Origin"
 	// NameConnection represents the HTTP Header Name of "Connection"
@@ -80,8 +94,12 @@ const (
 	NameContentRange = "Content-Range"
 	// NameTricksterResult represents the HTTP Header Name of "X-Trickster-Result"
 	NameTricksterResult = "X-Trickster-Result"
+	// NameTricksterDegraded represents the HTTP Header Name of "X-Trickster-Degraded"
+	NameTricksterDegraded = "X-Trickster-Degraded"
 	// NameAcceptEncoding represents the HTTP Header Name of "Accept-Encoding"
 	NameAcceptEncoding = "Accept-Encoding"
+	// NameVary represents the HTTP Header Name of "Vary"
+	NameVary = "Vary"
 	// NameSetCookie represents the HTTP Header Name of "Set-Cookie"
 	NameSetCookie = "Set-Cookie"
 	// NameRange represents the HTTP Header Name of "Range"
@@ -122,6 +140,12 @@ const (
 	NameTrailer = "Trailer"
 	// NameUpgrade represents the HTTP Header Name of "Upgrade"
 	NameUpgrade = "Upgrade"
+	// NameWarning represents the HTTP Header Name of "Warning"
+	NameWarning = "Warning"
+	// NameRetryAfter represents the HTTP Header Name of "Retry-After"
+	NameRetryAfter = "Retry-After"
+	// NameGRPCTimeout represents the HTTP Header Name of "grpc-timeout"
+	NameGRPCTimeout = "grpc-timeout"
 )
 
 // Merge merges the source http.Header map into destination map.
@@ -191,6 +215,20 @@ func SetResultsHeader(headers http.Header, engine, status, ffstatus string, fetc
 
 }
 
+// ResultsHeaderStatus returns the cache lookup status from the response header set by
+// SetResultsHeader, and a boolean indicating if the status was present
+func ResultsHeaderStatus(headers http.Header) (string, bool) {
+	if headers == nil {
+		return "", false
+	}
+	for _, part := range strings.Split(headers.Get(NameTricksterResult), "; ") {
+		if strings.HasPrefix(part, "status=") {
+			return part[7:], true
+		}
+	}
+	return "", false
+}
+
 // ExtractHeader returns the value for the provided header name, and a boolean indicating if the header was present
 func ExtractHeader(headers http.Header, header string) (string, bool) {
 	if Value, ok := headers[header]; ok {
@@ -149,6 +149,21 @@ func TestSetResultsHeaderEmtpy(t *testing.T) {
 	}
 }
 
+func TestResultsHeaderStatus(t *testing.T) {
+	h := http.Header{}
+	if _, ok := ResultsHeaderStatus(h); ok {
+		t.Error("expected no status")
+	}
+	SetResultsHeader(h, "test-engine", "phit", "",
+		timeseries.ExtentList{timeseries.Extent{Start: time.Unix(1, 0), End: time.Unix(2, 0)}})
+	if s, ok := ResultsHeaderStatus(h); !ok || s != "phit" {
+		t.Errorf("expected %s got %s", "phit", s)
+	}
+	if _, ok := ResultsHeaderStatus(nil); ok {
+		t.Error("expected no status")
+	}
+}
+
 func TestString(t *testing.T) {
 
 	expected := "test: test\n\n"
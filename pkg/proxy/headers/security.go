@@ -0,0 +1,60 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package headers
+
+import "net/http"
+
+const (
+	// SecurityPresetNone is the security header preset that adds no headers
+	SecurityPresetNone = "none"
+	// SecurityPresetStandard is the security header preset that adds the headers commonly
+	// required by security scans
+	SecurityPresetStandard = "standard"
+
+	// NameStrictTransportSecurity represents the HTTP Header Name of "Strict-Transport-Security"
+	NameStrictTransportSecurity = "Strict-Transport-Security"
+	// NameXContentTypeOptions represents the HTTP Header Name of "X-Content-Type-Options"
+	NameXContentTypeOptions = "X-Content-Type-Options"
+	// NameXFrameOptions represents the HTTP Header Name of "X-Frame-Options"
+	NameXFrameOptions = "X-Frame-Options"
+	// NameReferrerPolicy represents the HTTP Header Name of "Referrer-Policy"
+	NameReferrerPolicy = "Referrer-Policy"
+	// NameContentSecurityPolicy represents the HTTP Header Name of "Content-Security-Policy"
+	NameContentSecurityPolicy = "Content-Security-Policy"
+)
+
+// SecurityHeaders returns the headers of the named security header preset, including the
+// Content-Security-Policy when a policy is provided. An empty preset name is the same as
+// SecurityPresetNone. It returns false if the preset name is unknown
+func SecurityHeaders(preset, policy string) (http.Header, bool) {
+	switch preset {
+	case "", SecurityPresetNone:
+		return http.Header{}, true
+	case SecurityPresetStandard:
+		h := http.Header{
+			NameStrictTransportSecurity: []string{"max-age=31536000; includeSubDomains"},
+			NameXContentTypeOptions:     []string{"nosniff"},
+			NameXFrameOptions:           []string{"DENY"},
+			NameReferrerPolicy:          []string{"no-referrer"},
+		}
+		if policy != "" {
+			h.Set(NameContentSecurityPolicy, policy)
+		}
+		return h, true
+	}
+	return nil, false
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package headers
+
+import "testing"
+
+func TestSecurityHeaders(t *testing.T) {
+
+	h, ok := SecurityHeaders("", "")
+	if !ok || len(h) != 0 {
+		t.Errorf("expected no headers got %v", h)
+	}
+
+	h, ok = SecurityHeaders(SecurityPresetStandard, "")
+	if !ok || h.Get(NameXFrameOptions) != "DENY" || h.Get(NameContentSecurityPolicy) != "" {
+		t.Errorf("unexpected headers %v", h)
+	}
+
+	h, _ = SecurityHeaders(SecurityPresetStandard, "default-src 'self'")
+	if v := h.Get(NameContentSecurityPolicy); v != "default-src 'self'" {
+		t.Errorf("expected %s got %s", "default-src 'self'", v)
+	}
+
+	if _, ok = SecurityHeaders("strictest", ""); ok {
+		t.Error("expected unknown preset to fail")
+	}
+}
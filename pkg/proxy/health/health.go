@@ -0,0 +1,258 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package health aggregates the health of Trickster's origins and caches into a
+// single status document, suitable for use with readiness probes
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+// Health check statuses
+const (
+	// StatusOK indicates the check passed
+	StatusOK = "ok"
+	// StatusFailing indicates the check failed
+	StatusFailing = "failing"
+	// StatusMaintenance indicates the instance is in maintenance mode
+	StatusMaintenance = "maintenance"
+)
+
+// maintenance is non-zero when the instance is in maintenance mode
+var maintenance int32
+
+// SetMaintenance enables or disables maintenance mode. While in maintenance mode, the
+// aggregated health status is failing, so that the instance is taken out of rotation
+// by readiness probes, while it continues to serve requests
+func SetMaintenance(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&maintenance, 1)
+		return
+	}
+	atomic.StoreInt32(&maintenance, 0)
+}
+
+// Maintenance returns true when the instance is in maintenance mode
+func Maintenance() bool {
+	return atomic.LoadInt32(&maintenance) != 0
+}
+
+// cacheCheckKey is the cache key written and read back by cache health checks
+const cacheCheckKey = "trickster.health.check"
+
+// ErrCacheMismatch is returned when a cache health check does not read back the
+// value it wrote
+var ErrCacheMismatch = errors.New("cache returned an unexpected value")
+
+// CheckResult is the result of a single origin or cache health check
+type CheckResult struct {
+	Type       string    `json:"type"`
+	Status     string    `json:"status"`
+	StatusCode int       `json:"status_code,omitempty"`
+	LatencyMS  float64   `json:"latency_ms"`
+	LastCheck  time.Time `json:"last_check"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Status is the aggregated health of the origins and caches
+type Status struct {
+	Status  string                  `json:"status"`
+	Origins map[string]*CheckResult `json:"origins"`
+	Caches  map[string]*CheckResult `json:"caches"`
+}
+
+type origin struct {
+	originType string
+	handler    http.Handler
+}
+
+// Checker runs the health checks of its origins and caches
+type Checker struct {
+	origins map[string]*origin
+	caches  map[string]cache.Cache
+}
+
+// NewChecker returns a new Checker with no origins or caches
+func NewChecker() *Checker {
+	return &Checker{
+		origins: make(map[string]*origin),
+		caches:  make(map[string]cache.Cache),
+	}
+}
+
+// AddOrigin adds an origin to the Checker. The origin is healthy when the provided
+// health check handler responds with a 2xx status code
+func (c *Checker) AddOrigin(name, originType string, h http.Handler) {
+	c.origins[name] = &origin{originType: originType, handler: h}
+}
+
+// AddCache adds a cache to the Checker. The cache is healthy when a value can be
+// stored and retrieved
+func (c *Checker) AddCache(name string, cc cache.Cache) {
+	c.caches[name] = cc
+}
+
+// Check runs all of the health checks concurrently and returns the aggregated Status
+func (c *Checker) Check() *Status {
+	s := &Status{
+		Status:  StatusOK,
+		Origins: make(map[string]*CheckResult, len(c.origins)),
+		Caches:  make(map[string]*CheckResult, len(c.caches)),
+	}
+	var wg sync.WaitGroup
+	var mtx sync.Mutex
+	for k, o := range c.origins {
+		wg.Add(1)
+		go func(name string, o *origin) {
+			cr := checkOrigin(o)
+			mtx.Lock()
+			s.Origins[name] = cr
+			mtx.Unlock()
+			wg.Done()
+		}(k, o)
+	}
+	for k, cc := range c.caches {
+		wg.Add(1)
+		go func(name string, cc cache.Cache) {
+			cr := checkCache(cc)
+			mtx.Lock()
+			s.Caches[name] = cr
+			mtx.Unlock()
+			wg.Done()
+		}(k, cc)
+	}
+	wg.Wait()
+	if Maintenance() {
+		s.Status = StatusMaintenance
+		return s
+	}
+	for _, cr := range s.Origins {
+		if cr.Status != StatusOK {
+			s.Status = StatusFailing
+		}
+	}
+	for _, cr := range s.Caches {
+		if cr.Status != StatusOK {
+			s.Status = StatusFailing
+		}
+	}
+	return s
+}
+
+func checkOrigin(o *origin) *CheckResult {
+	cr := &CheckResult{Type: o.originType, LastCheck: time.Now()}
+	r, _ := http.NewRequest(http.MethodGet, "/", nil)
+	w := &statusWriter{h: http.Header{}}
+	o.handler.ServeHTTP(w, r)
+	cr.LatencyMS = latencyMS(cr.LastCheck)
+	cr.StatusCode = w.code
+	if cr.StatusCode == 0 {
+		cr.StatusCode = http.StatusOK
+	}
+	if cr.StatusCode >= 200 && cr.StatusCode < 300 {
+		cr.Status = StatusOK
+	} else {
+		cr.Status = StatusFailing
+		cr.Error = http.StatusText(cr.StatusCode)
+	}
+	return cr
+}
+
+func checkCache(cc cache.Cache) *CheckResult {
+	cr := &CheckResult{LastCheck: time.Now()}
+	co := cc.Configuration()
+	if co != nil {
+		cr.Type = co.CacheType
+	}
+	var err error
+	if co != nil && co.ReadOnly {
+		// a read-only cache can't store the check object, so it need only serve lookups
+		if _, _, err = cc.Retrieve(cacheCheckKey, false); err == cache.ErrKNF {
+			err = nil
+		}
+	} else if err = cc.Store(cacheCheckKey, []byte(StatusOK), time.Minute); err == nil {
+		var b []byte
+		b, _, err = cc.Retrieve(cacheCheckKey, false)
+		if err == nil && string(b) != StatusOK {
+			err = ErrCacheMismatch
+		}
+	}
+	cr.LatencyMS = latencyMS(cr.LastCheck)
+	if err != nil {
+		cr.Status = StatusFailing
+		cr.Error = err.Error()
+	} else {
+		cr.Status = StatusOK
+	}
+	return cr
+}
+
+func latencyMS(start time.Time) float64 {
+	return float64(time.Since(start)) / float64(time.Millisecond)
+}
+
+// ServeHTTP runs the health checks and responds with the aggregated Status as JSON,
+// with a 200 OK status code when all checks pass, or 503 Service Unavailable when any
+// check fails or the instance is in maintenance mode
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s := c.Check()
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(headers.NameContentType, headers.ValueApplicationJSON)
+	w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
+	if s.Status == StatusOK {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(b)
+}
+
+// statusWriter is an http.ResponseWriter that records the status code and
+// discards the response body
+type statusWriter struct {
+	h    http.Header
+	code int
+}
+
+func (w *statusWriter) Header() http.Header {
+	return w.h
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return len(b), nil
+}
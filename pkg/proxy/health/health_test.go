@@ -0,0 +1,154 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	co "github.com/tricksterproxy/trickster/pkg/cache/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/registration"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+var errTestStore = errors.New("test store error")
+
+// failingCache is a cache whose Store method always fails
+type failingCache struct {
+	cache.Cache
+}
+
+func (c *failingCache) Store(string, []byte, time.Duration) error {
+	return errTestStore
+}
+
+func (c *failingCache) Configuration() *co.Options {
+	return &co.Options{CacheType: "redis"}
+}
+
+func statusHandler(code int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	})
+}
+
+func TestChecker(t *testing.T) {
+
+	c := NewChecker()
+	c.AddOrigin("origin1", "prometheus", statusHandler(http.StatusOK))
+	c.AddOrigin("origin2", "influxdb", statusHandler(http.StatusNoContent))
+	c.AddCache("default", registration.NewCache("default", co.NewOptions(),
+		tl.ConsoleLogger("error")))
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/trickster/health", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	s := &Status{}
+	if err := json.Unmarshal(w.Body.Bytes(), s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Status != StatusOK {
+		t.Errorf("expected %s got %s", StatusOK, s.Status)
+	}
+	if len(s.Origins) != 2 || len(s.Caches) != 1 {
+		t.Fatalf("unexpected check results: %v", w.Body.String())
+	}
+	if cr := s.Origins["origin2"]; cr.Status != StatusOK || cr.StatusCode != http.StatusNoContent ||
+		cr.Type != "influxdb" || cr.LastCheck.IsZero() {
+		t.Errorf("unexpected origin result: %v", cr)
+	}
+	if cr := s.Caches["default"]; cr.Status != StatusOK || cr.Type != "memory" {
+		t.Errorf("unexpected cache result: %v", cr)
+	}
+
+	c.AddOrigin("origin3", "prometheus", statusHandler(http.StatusBadGateway))
+	c.AddCache("failing", &failingCache{})
+
+	w = httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/trickster/health", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	s = &Status{}
+	if err := json.Unmarshal(w.Body.Bytes(), s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Status != StatusFailing {
+		t.Errorf("expected %s got %s", StatusFailing, s.Status)
+	}
+	if cr := s.Origins["origin3"]; cr.Status != StatusFailing ||
+		cr.Error != http.StatusText(http.StatusBadGateway) {
+		t.Errorf("unexpected origin result: %v", cr)
+	}
+	if cr := s.Caches["failing"]; cr.Status != StatusFailing || cr.Error != errTestStore.Error() {
+		t.Errorf("unexpected cache result: %v", cr)
+	}
+}
+
+func TestCheckCacheReadOnly(t *testing.T) {
+	o := co.NewOptions()
+	o.ReadOnly = true
+	c := registration.NewCache("default", o, tl.ConsoleLogger("error"))
+	if cr := checkCache(c); cr.Status != StatusOK {
+		t.Errorf("expected %s got %s: %s", StatusOK, cr.Status, cr.Error)
+	}
+}
+
+func TestStatusWriter(t *testing.T) {
+	w := &statusWriter{h: http.Header{}}
+	w.Header().Set("a", "b")
+	if n, err := w.Write([]byte("test")); n != 4 || err != nil {
+		t.Errorf("unexpected write result: %d %v", n, err)
+	}
+	w.WriteHeader(http.StatusNotFound)
+	if w.code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.code)
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+
+	c := NewChecker()
+	c.AddOrigin("origin1", "prometheus", statusHandler(http.StatusOK))
+
+	SetMaintenance(true)
+	defer SetMaintenance(false)
+	if !Maintenance() {
+		t.Error("expected maintenance mode")
+	}
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/trickster/health", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if s := c.Check(); s.Status != StatusMaintenance || s.Origins["origin1"].Status != StatusOK {
+		t.Errorf("unexpected status: %v", s)
+	}
+
+	SetMaintenance(false)
+	if s := c.Check(); s.Status != StatusOK {
+		t.Errorf("expected %s got %s", StatusOK, s.Status)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package health
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Monitor periodically runs an origin's health check in the background, and reports the
+// origin as down after a number of consecutive failed checks, and as up again after the
+// next successful check
+type Monitor struct {
+	origin    *origin
+	interval  time.Duration
+	threshold int
+	onChange  func(down bool)
+
+	failures int
+	down     bool
+	quit     chan struct{}
+	once     sync.Once
+}
+
+// NewMonitor returns a Monitor that runs the provided health check handler on the interval,
+// and calls onChange each time the origin goes down or comes back up
+func NewMonitor(originType string, h http.Handler, interval time.Duration,
+	threshold int, onChange func(down bool)) *Monitor {
+	return &Monitor{
+		origin:    &origin{originType: originType, handler: h},
+		interval:  interval,
+		threshold: threshold,
+		onChange:  onChange,
+		quit:      make(chan struct{}),
+	}
+}
+
+// Start runs the first health check immediately, and subsequent checks on the interval,
+// until the Monitor is stopped
+func (m *Monitor) Start() {
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			m.check()
+			select {
+			case <-ticker.C:
+			case <-m.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the Monitor's health checks
+func (m *Monitor) Stop() {
+	m.once.Do(func() { close(m.quit) })
+}
+
+// check runs the health check and updates the origin's state
+func (m *Monitor) check() {
+	cr := checkOrigin(m.origin)
+	if cr.Status == StatusOK {
+		m.failures = 0
+		if m.down {
+			m.down = false
+			m.onChange(false)
+		}
+		return
+	}
+	m.failures++
+	if !m.down && m.failures >= m.threshold {
+		m.down = true
+		m.onChange(true)
+	}
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package health
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMonitor(t *testing.T) {
+
+	var code int32 = http.StatusOK
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&code)))
+	})
+
+	var changes []bool
+	m := NewMonitor("test", h, time.Hour, 2, func(down bool) {
+		changes = append(changes, down)
+	})
+
+	m.check()
+	atomic.StoreInt32(&code, http.StatusBadGateway)
+	m.check()
+	if len(changes) != 0 {
+		t.Errorf("expected no changes got %v", changes)
+	}
+
+	// the origin is down after the threshold of consecutive failures
+	m.check()
+	m.check()
+	if len(changes) != 1 || !changes[0] {
+		t.Errorf("expected [true] got %v", changes)
+	}
+
+	// and is up after the next success
+	atomic.StoreInt32(&code, http.StatusOK)
+	m.check()
+	if len(changes) != 2 || changes[1] {
+		t.Errorf("expected [true false] got %v", changes)
+	}
+}
+
+func TestMonitorStartStop(t *testing.T) {
+
+	checked := make(chan struct{}, 10)
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checked <- struct{}{}
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	down := make(chan bool, 1)
+	m := NewMonitor("test", h, time.Millisecond, 2, func(d bool) { down <- d })
+	m.Start()
+	select {
+	case d := <-down:
+		if !d {
+			t.Error("expected origin to be down")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the origin to be down")
+	}
+	m.Stop()
+	m.Stop()
+	if len(checked) < 2 {
+		t.Errorf("expected at least %d checks got %d", 2, len(checked))
+	}
+}
@@ -0,0 +1,219 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package hedging provides an http.RoundTripper that hedges slow requests to an origin by
+// issuing them to a replica of the origin, and using whichever response arrives first
+package hedging
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// windowSize is the number of recent primary latencies from which the hedge delay is derived
+const windowSize = 1000
+
+// minSamples is the number of primary latencies that must be observed before the hedge
+// delay is derived from them, rather than using the minimum delay
+const minSamples = 20
+
+// recomputeInterval is the number of primary latencies observed between recomputations
+// of the hedge delay, so that the window is not sorted for every request
+const recomputeInterval = 50
+
+// Transport is an http.RoundTripper that sends each hedgeable request to the primary origin
+// and, if no response is received within the hedge delay, to one of the replica targets
+type Transport struct {
+	next     http.RoundTripper
+	prefix   string
+	targets  []*url.URL
+	quantile float64
+	minDelay time.Duration
+
+	mtx       sync.Mutex
+	latencies []time.Duration
+	pos       int
+	stale     int
+	delay     time.Duration
+	count     uint64
+}
+
+// NewTransport returns a Transport that sends requests via next. When a request is hedged,
+// the primary origin's path prefix is replaced with the path of the target. The hedge delay
+// is the quantile of recent primary latencies, but never less than minDelay
+func NewTransport(next http.RoundTripper, prefix string, targets []*url.URL,
+	quantile float64, minDelay time.Duration) *Transport {
+	return &Transport{
+		next:      next,
+		prefix:    prefix,
+		targets:   targets,
+		quantile:  quantile,
+		minDelay:  minDelay,
+		latencies: make([]time.Duration, 0, windowSize),
+	}
+}
+
+type result struct {
+	resp   *http.Response
+	err    error
+	cancel context.CancelFunc
+	hedged bool
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+
+	if len(t.targets) == 0 || !isHedgeable(r) {
+		return t.next.RoundTrip(r)
+	}
+
+	results := make(chan *result, 2)
+	t.send(r, false, results)
+
+	timer := time.NewTimer(t.Delay())
+	select {
+	case res := <-results:
+		timer.Stop()
+		return res.finish()
+	case <-timer.C:
+	}
+
+	t.send(t.hedgeRequest(r), true, results)
+
+	// use the first successful response, or the last error when both requests fail
+	res := <-results
+	if res.err != nil {
+		res.cancel()
+		res = <-results
+		return res.finish()
+	}
+	go discard(results)
+	return res.finish()
+}
+
+// send issues the request with its own cancelable context, and delivers the result. The
+// latency of the primary request is observed whenever it completes, whether or not its
+// response is used, unless it was canceled first
+func (t *Transport) send(r *http.Request, hedged bool, results chan<- *result) {
+	ctx, cancel := context.WithCancel(r.Context())
+	r = r.WithContext(ctx)
+	go func() {
+		start := time.Now()
+		resp, err := t.next.RoundTrip(r)
+		if !hedged && (err == nil || ctx.Err() == nil) {
+			t.observe(time.Since(start))
+		}
+		results <- &result{resp: resp, err: err, cancel: cancel, hedged: hedged}
+	}()
+}
+
+// hedgeRequest returns a copy of the request that is addressed to the next replica target
+func (t *Transport) hedgeRequest(r *http.Request) *http.Request {
+	n := atomic.AddUint64(&t.count, 1)
+	target := t.targets[int((n-1)%uint64(len(t.targets)))]
+	r2 := r.Clone(r.Context())
+	u := *r.URL
+	u.Scheme = target.Scheme
+	u.Host = target.Host
+	u.Path = target.Path + strings.TrimPrefix(r.URL.Path, t.prefix)
+	u.RawPath = ""
+	r2.URL = &u
+	r2.Host = target.Host
+	return r2
+}
+
+// finish returns the result's error, or its response, whose request context is canceled
+// when the response body is closed
+func (res *result) finish() (*http.Response, error) {
+	if res.err != nil {
+		res.cancel()
+		return nil, res.err
+	}
+	res.resp.Body = &cancelBody{ReadCloser: res.resp.Body, cancel: res.cancel}
+	return res.resp, nil
+}
+
+// discard cancels the losing request and closes its response body, if any
+func discard(results <-chan *result) {
+	res := <-results
+	res.cancel()
+	if res.resp != nil && res.resp.Body != nil {
+		res.resp.Body.Close()
+	}
+}
+
+// isHedgeable returns true if the request can be safely sent to more than one target
+func isHedgeable(r *http.Request) bool {
+	return (r.Method == http.MethodGet || r.Method == http.MethodHead) &&
+		(r.Body == nil || r.Body == http.NoBody)
+}
+
+// observe records the latency of a response from the primary origin, and recomputes the
+// quantile of the window once enough latencies have been observed since it was last computed
+func (t *Transport) observe(d time.Duration) {
+	t.mtx.Lock()
+	if len(t.latencies) < windowSize {
+		t.latencies = append(t.latencies, d)
+	} else {
+		t.latencies[t.pos] = d
+		t.pos = (t.pos + 1) % windowSize
+	}
+	t.stale++
+	if len(t.latencies) < minSamples ||
+		(len(t.latencies) > minSamples && t.stale < recomputeInterval) {
+		t.mtx.Unlock()
+		return
+	}
+	t.stale = 0
+	l := make([]time.Duration, len(t.latencies))
+	copy(l, t.latencies)
+	t.mtx.Unlock()
+
+	sort.Slice(l, func(i, j int) bool { return l[i] < l[j] })
+	t.mtx.Lock()
+	t.delay = l[int(t.quantile*float64(len(l)-1))]
+	t.mtx.Unlock()
+}
+
+// Delay returns the duration to wait for the primary origin before hedging a request
+func (t *Transport) Delay() time.Duration {
+	t.mtx.Lock()
+	d := t.delay
+	t.mtx.Unlock()
+	if d < t.minDelay {
+		return t.minDelay
+	}
+	return d
+}
+
+// cancelBody cancels the context of its request when the response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,267 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hedging
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newServer(name string, delay time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		w.Write([]byte(name + ":" + r.URL.Path))
+	}))
+}
+
+func mustParse(t *testing.T, s string) *url.URL {
+	u, err := url.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func get(t *testing.T, tr http.RoundTripper, method, u string) (string, error) {
+	r, _ := http.NewRequest(method, u, nil)
+	if method == http.MethodPost {
+		r, _ = http.NewRequest(method, u, strings.NewReader("x"))
+	}
+	resp, err := tr.RoundTrip(r)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	return string(b), err
+}
+
+func TestRoundTrip(t *testing.T) {
+
+	slow := newServer("primary", 500*time.Millisecond)
+	defer slow.Close()
+	fast := newServer("replica", 0)
+	defer fast.Close()
+
+	tr := NewTransport(http.DefaultTransport, "/prom",
+		[]*url.URL{mustParse(t, fast.URL+"/replica")}, 0.95, 10*time.Millisecond)
+
+	// the slow primary is hedged to the replica, with the path prefix replaced
+	s, err := get(t, tr, http.MethodGet, slow.URL+"/prom/api/v1/query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "replica:/replica/api/v1/query" {
+		t.Errorf("expected %s got %s", "replica:/replica/api/v1/query", s)
+	}
+
+	// a request with a body is never hedged
+	tr.minDelay = time.Millisecond
+	s, err = get(t, tr, http.MethodPost, slow.URL+"/prom/api/v1/query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "primary:/prom/api/v1/query" {
+		t.Errorf("expected %s got %s", "primary:/prom/api/v1/query", s)
+	}
+
+	// a fast primary is not hedged
+	tr.minDelay = time.Second
+	s, err = get(t, tr, http.MethodGet, fast.URL+"/prom/api/v1/query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "replica:/prom/api/v1/query" {
+		t.Errorf("expected %s got %s", "replica:/prom/api/v1/query", s)
+	}
+
+	// no targets
+	tr = NewTransport(http.DefaultTransport, "", nil, 0.95, 0)
+	s, err = get(t, tr, http.MethodGet, fast.URL+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "replica:/" {
+		t.Errorf("expected %s got %s", "replica:/", s)
+	}
+}
+
+type errTransport struct {
+	delay time.Duration
+	err   error
+}
+
+func (e *errTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	time.Sleep(e.delay)
+	return nil, e.err
+}
+
+func TestRoundTripErrors(t *testing.T) {
+
+	errTest := errors.New("test error")
+	targets := []*url.URL{mustParse(t, "http://replica/")}
+
+	// the primary fails before the hedge delay
+	tr := NewTransport(&errTransport{err: errTest}, "", targets, 0.95, time.Second)
+	if _, err := get(t, tr, http.MethodGet, "http://primary/"); err != errTest {
+		t.Errorf("expected %v got %v", errTest, err)
+	}
+
+	// both requests fail after the hedge delay
+	tr = NewTransport(&errTransport{delay: 20 * time.Millisecond, err: errTest},
+		"", targets, 0.95, time.Millisecond)
+	if _, err := get(t, tr, http.MethodGet, "http://primary/"); err != errTest {
+		t.Errorf("expected %v got %v", errTest, err)
+	}
+}
+
+func TestRoundTripFirstError(t *testing.T) {
+
+	// the primary fails after the hedge delay, so the replica's response is used
+	fast := newServer("replica", 50*time.Millisecond)
+	defer fast.Close()
+
+	tr := NewTransport(&failPrimary{host: "primary", delay: 10 * time.Millisecond},
+		"", []*url.URL{mustParse(t, fast.URL)}, 0.95, time.Millisecond)
+	s, err := get(t, tr, http.MethodGet, "http://primary/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "replica:/test" {
+		t.Errorf("expected %s got %s", "replica:/test", s)
+	}
+}
+
+type failPrimary struct {
+	host  string
+	delay time.Duration
+}
+
+func (f *failPrimary) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Host == f.host {
+		time.Sleep(f.delay)
+		return nil, errors.New("primary error")
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestDelay(t *testing.T) {
+
+	tr := NewTransport(http.DefaultTransport, "", nil, 0.9, 5*time.Millisecond)
+
+	// the minimum delay is used until enough latencies are observed
+	for i := 1; i < minSamples; i++ {
+		tr.observe(time.Duration(i) * time.Second)
+	}
+	if d := tr.Delay(); d != 5*time.Millisecond {
+		t.Errorf("expected %s got %s", 5*time.Millisecond, d)
+	}
+
+	// the delay is derived as soon as enough latencies are observed
+	tr.observe(minSamples * time.Second)
+	if d := tr.Delay(); d != 18*time.Second {
+		t.Errorf("expected %s got %s", 18*time.Second, d)
+	}
+
+	for i := minSamples + 1; i <= windowSize+120; i++ {
+		tr.observe(time.Duration(i) * time.Millisecond)
+	}
+	if len(tr.latencies) != windowSize {
+		t.Errorf("expected %d got %d", windowSize, len(tr.latencies))
+	}
+	// the window holds 121ms through 1120ms
+	if d := tr.Delay(); d != 1020*time.Millisecond {
+		t.Errorf("expected %s got %s", 1020*time.Millisecond, d)
+	}
+
+	// and is then only recomputed periodically
+	for i := 1; i < recomputeInterval; i++ {
+		tr.observe(time.Hour)
+	}
+	if d := tr.Delay(); d != 1020*time.Millisecond {
+		t.Errorf("expected %s got %s", 1020*time.Millisecond, d)
+	}
+	tr.observe(time.Hour)
+	if d := tr.Delay(); d != 1070*time.Millisecond {
+		t.Errorf("expected %s got %s", 1070*time.Millisecond, d)
+	}
+
+	tr.minDelay = 2 * time.Second
+	if d := tr.Delay(); d != 2*time.Second {
+		t.Errorf("expected %s got %s", 2*time.Second, d)
+	}
+}
+
+type slowPrimary struct {
+	host  string
+	delay time.Duration
+}
+
+// RoundTrip responds from the primary after its delay, even if the request is canceled
+func (s *slowPrimary) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Host == s.host {
+		time.Sleep(s.delay)
+		return &http.Response{StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(strings.NewReader("primary"))}, nil
+	}
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestObservePrimaryLatency(t *testing.T) {
+
+	fast := newServer("replica", 0)
+	defer fast.Close()
+
+	// the primary's latency is observed when it completes, even though the replica won
+	tr := NewTransport(&slowPrimary{host: "primary", delay: 50 * time.Millisecond},
+		"", []*url.URL{mustParse(t, fast.URL)}, 0.95, time.Millisecond)
+	s, err := get(t, tr, http.MethodGet, "http://primary/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "replica:/test" {
+		t.Errorf("expected %s got %s", "replica:/test", s)
+	}
+
+	var n int
+	for i := 0; i < 100; i++ {
+		tr.mtx.Lock()
+		n = len(tr.latencies)
+		tr.mtx.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n != 1 {
+		t.Fatalf("expected %d got %d", 1, n)
+	}
+	if tr.latencies[0] < 50*time.Millisecond {
+		t.Errorf("expected latency of at least %s got %s", 50*time.Millisecond,
+			tr.latencies[0])
+	}
+}
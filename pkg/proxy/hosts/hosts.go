@@ -0,0 +1,123 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package hosts provides matching of request Host headers against the
+// hostname patterns configured for an origin
+package hosts
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// Matcher matches request hosts against a list of hostname patterns
+type Matcher struct {
+	patterns []*pattern
+}
+
+type pattern struct {
+	re      *regexp.Regexp
+	hasPort bool
+}
+
+var (
+	validPattern     = regexp.MustCompile(`^[a-z0-9_*]([a-z0-9_*.-]*[a-z0-9_*])?(:[0-9]+)?$`)
+	validIPv6Pattern = regexp.MustCompile(`^\[([0-9a-f:.]+)\](:[0-9]+)?$`)
+)
+
+// IsWildcard returns true if the hostname pattern contains a wildcard
+func IsWildcard(p string) bool {
+	return strings.Contains(p, "*")
+}
+
+// Compile returns a Matcher for the provided hostname patterns. A pattern is a
+// hostname, optionally including a port, in which '*' matches any sequence of
+// characters within a single DNS label (e.g., '*.example.com' matches
+// 'prom.example.com', but not 'a.prom.example.com' or 'example.com').
+// Patterns without a port match requests for the host on any port. IPv6 addresses
+// are bracketed, as they are in a Host header (e.g., '[::1]' or '[::1]:8480').
+func Compile(patterns []string) (*Matcher, error) {
+	m := &Matcher{patterns: make([]*pattern, 0, len(patterns))}
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		hasPort := strings.Contains(p, ":")
+		if sm := validIPv6Pattern.FindStringSubmatch(p); sm != nil {
+			if !strings.Contains(sm[1], ":") || net.ParseIP(sm[1]) == nil {
+				return nil, fmt.Errorf("invalid host pattern [%s]", p)
+			}
+			// without a port, the address is matched without its brackets
+			hasPort = sm[2] != ""
+			if !hasPort {
+				p = sm[1]
+			}
+		} else if !validPattern.MatchString(p) {
+			return nil, fmt.Errorf("invalid host pattern [%s]", p)
+		}
+		parts := strings.Split(p, "*")
+		for i := range parts {
+			parts[i] = regexp.QuoteMeta(parts[i])
+		}
+		re, err := regexp.Compile("^" + strings.Join(parts, "[^.:]*") + "$")
+		if err != nil {
+			return nil, err
+		}
+		m.patterns = append(m.patterns, &pattern{re: re, hasPort: hasPort})
+	}
+	return m, nil
+}
+
+// Match returns true if the host matches any of the Matcher's patterns
+func (m *Matcher) Match(host string) bool {
+	if m == nil || host == "" {
+		return false
+	}
+	host = strings.ToLower(host)
+	bare := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		bare = h
+	} else if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		bare = host[1 : len(host)-1]
+	}
+	for _, p := range m.patterns {
+		if p.hasPort {
+			if p.re.MatchString(host) {
+				return true
+			}
+			continue
+		}
+		if p.re.MatchString(bare) {
+			return true
+		}
+	}
+	return false
+}
+
+// Specificity returns a ranking of how specific the provided patterns are, where
+// lower values are more specific: lists of exact hostnames rank 0, lists
+// containing any wildcard rank 1, and empty lists rank 2.
+func Specificity(patterns []string) int {
+	if len(patterns) == 0 {
+		return 2
+	}
+	for _, p := range patterns {
+		if IsWildcard(p) {
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hosts
+
+import "testing"
+
+func TestCompile(t *testing.T) {
+	_, err := Compile([]string{"prom.example.com", "*.example.com", "db-*.example.com:8480",
+		"prom_1.internal", "[::1]", "[::1]:8480", "[2001:db8::10]:9090"})
+	if err != nil {
+		t.Error(err)
+	}
+	for _, p := range []string{"", "bad host", "example.com:port", "-example.com", "ex/ample.com",
+		"::1", "[::1", "[:::1]", "[127.0.0.1]", "[::1]:port", "[*::1]"} {
+		_, err = Compile([]string{p})
+		if err == nil {
+			t.Errorf("expected error for invalid pattern [%s]", p)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+
+	m, err := Compile([]string{"Prom.Example.com", "*.db.example.com", "click-*.example.com:9000",
+		"prom_1.internal", "[::1]", "[2001:DB8::10]:9090"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host     string
+		expected bool
+	}{
+		{"prom.example.com", true},
+		{"PROM.EXAMPLE.COM", true},
+		{"prom.example.com:8480", true},
+		{"prom.example.org", false},
+		{"a.prom.example.com", false},
+		{"ch.db.example.com", true},
+		{"ch.db.example.com:443", true},
+		{"a.ch.db.example.com", false},
+		{"db.example.com", false},
+		{"click-1.example.com:9000", true},
+		{"click-1.example.com:8480", false},
+		{"click-1.example.com", false},
+		{"prom_1.internal", true},
+		{"prom_1.internal:8480", true},
+		{"prom_2.internal", false},
+		{"[::1]", true},
+		{"[::1]:8480", true},
+		{"[::2]:8480", false},
+		{"[2001:db8::10]:9090", true},
+		{"[2001:db8::10]:8480", false},
+		{"[2001:db8::10]", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if m.Match(test.host) != test.expected {
+			t.Errorf("host %s: expected %t", test.host, test.expected)
+		}
+	}
+
+	var nm *Matcher
+	if nm.Match("prom.example.com") {
+		t.Error("expected nil matcher to not match")
+	}
+}
+
+func TestSpecificity(t *testing.T) {
+	if Specificity([]string{"a.example.com"}) != 0 {
+		t.Error("expected exact hosts to rank 0")
+	}
+	if Specificity([]string{"a.example.com", "*.example.com"}) != 1 {
+		t.Error("expected wildcard hosts to rank 1")
+	}
+	if Specificity(nil) != 2 {
+		t.Error("expected no hosts to rank 2")
+	}
+}
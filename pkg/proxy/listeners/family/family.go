@@ -0,0 +1,91 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package family provides the address families that a listener can bind to
+package family
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	// Any listens on the network the address resolves to, which for the wildcard
+	// address is a single socket accepting both IPv4 and IPv6 where the OS allows it
+	Any = ""
+	// IPv4 listens for IPv4 connections only
+	IPv4 = "ipv4"
+	// IPv6 listens for IPv6 connections only
+	IPv6 = "ipv6"
+	// Dual listens on the wildcard address with separate IPv4 and IPv6 sockets, for
+	// hosts where a single socket does not accept both
+	Dual = "dual"
+)
+
+// Networks returns the networks, as accepted by net.Listen, that a listener in the
+// provided address family opens a socket on
+func Networks(f string) []string {
+	switch f {
+	case IPv4:
+		return []string{"tcp4"}
+	case IPv6:
+		return []string{"tcp6"}
+	case Dual:
+		return []string{"tcp4", "tcp6"}
+	}
+	return []string{"tcp"}
+}
+
+// Host returns the listen address with the brackets of an IPv6 literal removed
+func Host(address string) string {
+	if strings.HasPrefix(address, "[") && strings.HasSuffix(address, "]") {
+		return address[1 : len(address)-1]
+	}
+	return address
+}
+
+// Validate returns an error if the address family is unknown, if the listen address
+// is a malformed IP literal, or if the address is not in the address family
+func Validate(address, f string) error {
+	switch f {
+	case Any, IPv4, IPv6, Dual:
+	default:
+		return fmt.Errorf("invalid listen family %q; must be one of %q, %q or %q",
+			f, IPv4, IPv6, Dual)
+	}
+	host := Host(address)
+	bracketed := host != address
+	if strings.HasPrefix(address, "[") != strings.HasSuffix(address, "]") {
+		return fmt.Errorf("unbalanced brackets in listen address %q", address)
+	}
+	ip := net.ParseIP(host)
+	isV6 := strings.Contains(host, ":")
+	switch {
+	case bracketed && (ip == nil || !isV6):
+		return fmt.Errorf("invalid IPv6 literal in listen address %q", address)
+	case isV6 && ip == nil:
+		return fmt.Errorf("invalid IPv6 address in listen address %q", address)
+	case f == Dual && host != "":
+		return fmt.Errorf("listen family %q requires an empty listen address, not %q",
+			f, address)
+	case f == IPv4 && isV6:
+		return fmt.Errorf("listen address %q is not an IPv4 address", address)
+	case f == IPv6 && ip != nil && !isV6:
+		return fmt.Errorf("listen address %q is not an IPv6 address", address)
+	}
+	return nil
+}
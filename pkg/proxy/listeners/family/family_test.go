@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package family
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNetworks(t *testing.T) {
+	tests := []struct {
+		family   string
+		expected []string
+	}{
+		{Any, []string{"tcp"}},
+		{IPv4, []string{"tcp4"}},
+		{IPv6, []string{"tcp6"}},
+		{Dual, []string{"tcp4", "tcp6"}},
+	}
+	for _, test := range tests {
+		if n := Networks(test.family); !reflect.DeepEqual(n, test.expected) {
+			t.Errorf("expected %v got %v for family %q", test.expected, n, test.family)
+		}
+	}
+}
+
+func TestHost(t *testing.T) {
+	tests := []struct {
+		address, expected string
+	}{
+		{"", ""},
+		{"127.0.0.1", "127.0.0.1"},
+		{"::1", "::1"},
+		{"[::1]", "::1"},
+		{"localhost", "localhost"},
+	}
+	for _, test := range tests {
+		if h := Host(test.address); h != test.expected {
+			t.Errorf("expected %s got %s", test.expected, h)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		address, family string
+		isValid         bool
+	}{
+		{"", Any, true},
+		{"127.0.0.1", Any, true},
+		{"::1", Any, true},
+		{"[::1]", Any, true},
+		{"localhost", Any, true},
+		{"", "ipx", false},
+		{"[::1", Any, false},
+		{"[127.0.0.1]", Any, false},
+		{"[localhost]", Any, false},
+		{"::1::2", Any, false},
+		{"127.0.0.1", IPv4, true},
+		{"localhost", IPv4, true},
+		{"::1", IPv4, false},
+		{"[::]", IPv6, true},
+		{"127.0.0.1", IPv6, false},
+		{"", Dual, true},
+		{"0.0.0.0", Dual, false},
+	}
+	for _, test := range tests {
+		err := Validate(test.address, test.family)
+		if test.isValid && err != nil {
+			t.Errorf("unexpected error for %q/%q: %v", test.address, test.family, err)
+		} else if !test.isValid && err == nil {
+			t.Errorf("expected error for %q/%q", test.address, test.family)
+		}
+	}
+}
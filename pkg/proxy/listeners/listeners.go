@@ -23,11 +23,15 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
 	ph "github.com/tricksterproxy/trickster/pkg/proxy/handlers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/listeners/family"
+	"github.com/tricksterproxy/trickster/pkg/proxy/push"
 	sw "github.com/tricksterproxy/trickster/pkg/proxy/tls"
 	"github.com/tricksterproxy/trickster/pkg/tracing"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
@@ -45,6 +49,22 @@ type Listener struct {
 	routeSwapper *ph.SwitchHandler
 	server       *http.Server
 	exitOnError  bool
+	drained      int32
+
+	// the listener's socket is reopened with its bind options when serving fails
+	address          string
+	listenFamily     string
+	port             int
+	connectionsLimit int
+	drainTimeout     time.Duration
+
+	// connsLock guards the connection states tracked by the server's ConnState callback
+	connsLock sync.Mutex
+	conns     map[net.Conn]http.ConnState
+	// active is the number of connections with an in-flight request
+	active int
+	// idle is closed, and replaced, each time the active count drops to 0
+	idle chan struct{}
 }
 
 type observedConnection struct {
@@ -87,16 +107,117 @@ func (l *Listener) RouteSwapper() *ph.SwitchHandler {
 	return l.routeSwapper
 }
 
+// ServeHTTP serves the request with the Listener's router, or responds with 503 Service
+// Unavailable when the Listener is drained
+func (l *Listener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if l.Drained() {
+		w.Header().Set("Connection", "close")
+		http.Error(w, "listener is drained", http.StatusServiceUnavailable)
+		return
+	}
+	l.routeSwapper.ServeHTTP(w, r)
+}
+
+// trackConnState is the server's ConnState callback, which counts the connections that
+// are serving an in-flight request
+func (l *Listener) trackConnState(c net.Conn, state http.ConnState) {
+	l.connsLock.Lock()
+	defer l.connsLock.Unlock()
+	if l.conns == nil {
+		l.conns = make(map[net.Conn]http.ConnState)
+	}
+	prev := l.conns[c]
+	if state == http.StateHijacked || state == http.StateClosed {
+		delete(l.conns, c)
+	} else {
+		l.conns[c] = state
+	}
+	switch {
+	case prev != http.StateActive && state == http.StateActive:
+		l.active++
+	case prev == http.StateActive && state != http.StateActive:
+		l.active--
+		if l.active == 0 && l.idle != nil {
+			close(l.idle)
+			l.idle = nil
+		}
+	}
+}
+
+// ActiveRequests returns the number of connections with an in-flight request
+func (l *Listener) ActiveRequests() int {
+	l.connsLock.Lock()
+	defer l.connsLock.Unlock()
+	return l.active
+}
+
+// waitIdle waits up to timeout for the Listener to have no in-flight requests, or
+// indefinitely when timeout is < 0, and returns true if it became idle
+func (l *Listener) waitIdle(timeout time.Duration) bool {
+	var expired <-chan time.Time
+	if timeout >= 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		expired = t.C
+	}
+	for {
+		l.connsLock.Lock()
+		if l.active == 0 {
+			l.connsLock.Unlock()
+			return true
+		}
+		if l.idle == nil {
+			l.idle = make(chan struct{})
+		}
+		idle := l.idle
+		l.connsLock.Unlock()
+		select {
+		case <-idle:
+		case <-expired:
+			return false
+		}
+	}
+}
+
+// Drained returns true when the Listener is drained
+func (l *Listener) Drained() bool {
+	return atomic.LoadInt32(&l.drained) != 0
+}
+
+// setDrained drains or undrains the Listener. While drained, idle keep-alive
+// connections are closed and new requests are rejected, but the Listener keeps
+// its port so that it can be undrained without a restart
+func (l *Listener) setDrained(drained bool) {
+	if drained {
+		atomic.StoreInt32(&l.drained, 1)
+	} else {
+		atomic.StoreInt32(&l.drained, 0)
+	}
+	if l.server != nil {
+		l.server.SetKeepAlivesEnabled(!drained)
+	}
+}
+
 // ListenerGroup is a collection of listeners
 type ListenerGroup struct {
 	members       map[string]*Listener
+	certWatches   map[string]*certWatch
 	listenersLock sync.Mutex
 }
 
+// certWatch is the certificate and key files that a TLS listener watches for changes
+type certWatch struct {
+	pairs    []sw.KeyPair
+	interval time.Duration
+	onSwap   func()
+	onError  func(error)
+}
+
 // NewListenerGroup returns a new ListenerGroup
 func NewListenerGroup() *ListenerGroup {
 	return &ListenerGroup{
-		members: make(map[string]*Listener),
+		members:     make(map[string]*Listener),
+		certWatches: make(map[string]*certWatch),
 	}
 }
 
@@ -114,23 +235,46 @@ func NewListenerGroup() *ListenerGroup {
 // which observes the connections to set a gauge with the current number of
 // connections (with operates with sampling through scrapes), and a set of
 // counter metrics for connections accepted, rejected and closed.
-func NewListener(listenAddress string, listenPort, connectionsLimit int,
+func NewListener(listenAddress, listenFamily string, listenPort, connectionsLimit int,
 	tlsConfig *tls.Config, drainTimeout time.Duration, log *tl.Logger) (net.Listener, error) {
 
-	var listener net.Listener
-	var err error
-
 	listenerType := "http"
-
 	if tlsConfig != nil {
 		listenerType = "https"
-		listener, err = tls.Listen("tcp", fmt.Sprintf("%s:%d", listenAddress, listenPort), tlsConfig)
-	} else {
-		listener, err = net.Listen("tcp", fmt.Sprintf("%s:%d", listenAddress, listenPort))
 	}
-	if err != nil {
-		// so we can exit one level above, this usually means that the port is in use
-		return nil, err
+
+	host := family.Host(listenAddress)
+	networks := family.Networks(listenFamily)
+	sockets := make([]net.Listener, 0, len(networks))
+	for _, network := range networks {
+		// the sockets of a dual-stack listener on an ephemeral port share the port
+		// the first one is assigned
+		if len(sockets) > 0 && listenPort == 0 {
+			if a, ok := sockets[0].Addr().(*net.TCPAddr); ok {
+				listenPort = a.Port
+			}
+		}
+		addr := net.JoinHostPort(host, strconv.Itoa(listenPort))
+		var l net.Listener
+		var err error
+		if tlsConfig != nil {
+			l, err = tls.Listen(network, addr, tlsConfig)
+		} else {
+			l, err = net.Listen(network, addr)
+		}
+		if err != nil {
+			for _, s := range sockets {
+				s.Close()
+			}
+			// so we can exit one level above, this usually means that the port is in use
+			return nil, err
+		}
+		sockets = append(sockets, l)
+	}
+
+	listener := sockets[0]
+	if len(sockets) > 1 {
+		listener = newMultiListener(sockets...)
 	}
 
 	if connectionsLimit > 0 {
@@ -142,6 +286,7 @@ func NewListener(listenAddress string, listenPort, connectionsLimit int,
 		"connectionsLimit": connectionsLimit,
 		"scheme":           listenerType,
 		"address":          listenAddress,
+		"family":           listenFamily,
 		"port":             listenPort,
 	})
 
@@ -160,14 +305,32 @@ func (lg *ListenerGroup) Get(name string) *Listener {
 	return nil
 }
 
-// StartListener starts a new HTTP listener and adds it to the listener group
-func (lg *ListenerGroup) StartListener(listenerName, address string, port int, connectionsLimit int,
+// StartListener starts a new HTTP listener and adds it to the listener group. It returns
+// once the listener is closed, or when it fails to bind
+func (lg *ListenerGroup) StartListener(listenerName, address, listenFamily string, port int,
+	connectionsLimit int,
 	tlsConfig *tls.Config, router http.Handler, wg *sync.WaitGroup, tracers tracing.Tracers,
 	exitOnError bool, drainTimeout time.Duration, log *tl.Logger) error {
-	if wg != nil {
-		defer wg.Done()
+	l, err := lg.BindListener(listenerName, address, listenFamily, port, connectionsLimit,
+		tlsConfig, router, exitOnError, drainTimeout, log)
+	if err != nil {
+		if wg != nil {
+			wg.Done()
+		}
+		return err
 	}
-	l := &Listener{routeSwapper: ph.NewSwitchHandler(router), exitOnError: exitOnError}
+	return lg.ServeListener(listenerName, l, wg, tracers, log)
+}
+
+// BindListener opens a new HTTP listener's socket and adds the listener to the listener
+// group, and returns the listener once it is bound. The listener accepts connections
+// once it is served with ServeListener
+func (lg *ListenerGroup) BindListener(listenerName, address, listenFamily string, port int,
+	connectionsLimit int, tlsConfig *tls.Config, router http.Handler, exitOnError bool,
+	drainTimeout time.Duration, log *tl.Logger) (*Listener, error) {
+	l := &Listener{routeSwapper: ph.NewSwitchHandler(router), exitOnError: exitOnError,
+		address: address, listenFamily: listenFamily, port: port,
+		connectionsLimit: connectionsLimit, drainTimeout: drainTimeout}
 	if tlsConfig != nil && len(tlsConfig.Certificates) > 0 {
 		l.tlsConfig = tlsConfig
 		l.tlsSwapper = sw.NewSwapper(tlsConfig.Certificates)
@@ -178,21 +341,45 @@ func (lg *ListenerGroup) StartListener(listenerName, address string, port int, c
 	}
 
 	var err error
-	l.Listener, err = NewListener(address, port, connectionsLimit, tlsConfig, drainTimeout, log)
+	l.Listener, err = NewListener(address, listenFamily, port, connectionsLimit, tlsConfig, drainTimeout, log)
 	if err != nil {
 		log.Error("http listener startup failed", tl.Pairs{"name": listenerName, "detail": err})
 		if exitOnError {
 			os.Exit(1)
 		}
-		return err
+		return nil, err
 	}
 	log.Info("http listener starting",
-		tl.Pairs{"name": listenerName, "port": port, "address": address})
+		tl.Pairs{"name": listenerName, "port": port, "address": address, "family": listenFamily})
+
+	// the server is set before the listener is added to the group, so that it is always
+	// drained and closed along with the listener
+	svr := &http.Server{
+		Handler:     push.Handler(handlers.CompressHandler(l)),
+		ConnState:   l.trackConnState,
+		ConnContext: push.ConnContext,
+	}
+	if tlsConfig != nil {
+		svr.TLSConfig = tlsConfig
+	}
+	l.server = svr
 
 	lg.listenersLock.Lock()
 	lg.members[listenerName] = l
+	l.watchCerts(lg.certWatches[listenerName])
 	lg.listenersLock.Unlock()
 
+	return l, nil
+}
+
+// ServeListener serves a listener that was bound with BindListener, and returns once the
+// listener is closed
+func (lg *ListenerGroup) ServeListener(listenerName string, l *Listener, wg *sync.WaitGroup,
+	tracers tracing.Tracers, log *tl.Logger) error {
+	if wg != nil {
+		defer wg.Done()
+	}
+
 	// defer the tracer flush here where the listener connection ends
 	if tracers != nil {
 		for _, v := range tracers {
@@ -202,68 +389,252 @@ func (lg *ListenerGroup) StartListener(listenerName, address string, port int, c
 		}
 	}
 
-	if tlsConfig != nil {
-		svr := &http.Server{
-			Handler:   handlers.CompressHandler(l.routeSwapper),
-			TLSConfig: tlsConfig,
+	return lg.serve(listenerName, l, log)
+}
+
+// the backoff before reopening a listener that failed is doubled on each consecutive
+// failure, between these bounds
+var (
+	minRestartBackoff = 100 * time.Millisecond
+	maxRestartBackoff = 30 * time.Second
+)
+
+// serve serves the listener until it is closed. When serving fails with a panic or an
+// error, rather than by the listener being drained and closed, the listener is reopened
+// after a backoff and served again, for as long as it remains in the group
+func (lg *ListenerGroup) serve(listenerName string, l *Listener, log *tl.Logger) error {
+
+	scheme := "http"
+	if l.tlsConfig != nil {
+		scheme = "https"
+	}
+
+	backoff := minRestartBackoff
+	for {
+		start := time.Now()
+		panicked, err := l.serveRecovered()
+		if !lg.isMember(listenerName, l) || err == http.ErrServerClosed {
+			log.Error(scheme+" listener stopping", tl.Pairs{"name": listenerName, "detail": err})
+			return err
 		}
-		l.server = svr
-		err = svr.Serve(l)
-		if err != nil {
-			log.Error("https listener stopping", tl.Pairs{"name": listenerName, "detail": err})
-			if l.exitOnError {
+
+		reason := "error"
+		if panicked {
+			reason = "panic"
+		}
+		metrics.ProxyListenerRestarts.WithLabelValues(listenerName, reason).Inc()
+		// a listener that served for a while before failing is restarted promptly
+		if time.Since(start) > maxRestartBackoff {
+			backoff = minRestartBackoff
+		}
+		log.Error(scheme+" listener failed, restarting", tl.Pairs{"name": listenerName,
+			"reason": reason, "detail": err, "backoff": backoff.String()})
+
+		for {
+			time.Sleep(backoff)
+			if backoff *= 2; backoff > maxRestartBackoff {
+				backoff = maxRestartBackoff
+			}
+			if !lg.isMember(listenerName, l) {
+				return err
+			}
+			nl, lerr := NewListener(l.address, l.listenFamily, l.port, l.connectionsLimit,
+				l.tlsConfig, l.drainTimeout, log)
+			if lerr == nil {
+				if !lg.reopen(listenerName, l, nl) {
+					return err
+				}
+				break
+			}
+			log.Error(scheme+" listener restart failed", tl.Pairs{"name": listenerName,
+				"detail": lerr, "backoff": backoff.String()})
+			// like a listener that fails to start, one that fails to reopen can exit
+			if lg.exitsOnError(l) {
 				os.Exit(1)
 			}
 		}
-		return err
 	}
+}
 
-	svr := &http.Server{
-		Handler: handlers.CompressHandler(l.routeSwapper),
-	}
-	l.server = svr
-	err = svr.Serve(l)
-	if err != nil {
-		log.Error("http listener stopping", tl.Pairs{"name": listenerName, "detail": err})
-		if l.exitOnError {
-			os.Exit(1)
+// serveRecovered serves the listener, and returns a panic in its serve loop as an error,
+// so that it does not take down the process
+func (l *Listener) serveRecovered() (panicked bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			err = fmt.Errorf("panic: %v", r)
 		}
+	}()
+	return false, l.server.Serve(l)
+}
+
+// isMember returns true if the listener is the named member of the group
+func (lg *ListenerGroup) isMember(listenerName string, l *Listener) bool {
+	lg.listenersLock.Lock()
+	defer lg.listenersLock.Unlock()
+	return lg.members[listenerName] == l
+}
+
+// reopen replaces the listener's socket with the reopened one, when the listener is still
+// the named member of the group. Otherwise the reopened socket is closed, and reopen
+// returns false
+func (lg *ListenerGroup) reopen(listenerName string, l *Listener, nl net.Listener) bool {
+	lg.listenersLock.Lock()
+	defer lg.listenersLock.Unlock()
+	if lg.members[listenerName] != l {
+		nl.Close()
+		return false
 	}
-	return err
+	l.Listener = nl
+	return true
+}
+
+// exitsOnError returns true if the listener's serving errors exit the process
+func (lg *ListenerGroup) exitsOnError(l *Listener) bool {
+	lg.listenersLock.Lock()
+	defer lg.listenersLock.Unlock()
+	return l.exitOnError
 }
 
 // StartListenerRouter starts a new HTTP listener with a new router, and adds it to the listener group
-func (lg *ListenerGroup) StartListenerRouter(listenerName, address string, port int, connectionsLimit int,
+func (lg *ListenerGroup) StartListenerRouter(listenerName, address, listenFamily string, port int,
+	connectionsLimit int,
 	tlsConfig *tls.Config, path string, handler http.Handler, wg *sync.WaitGroup,
 	tracers tracing.Tracers, exitOnError bool, drainTimeout time.Duration, log *tl.Logger) error {
 	router := http.NewServeMux()
 	router.Handle(path, handler)
-	return lg.StartListener(listenerName, address, port, connectionsLimit,
+	return lg.StartListener(listenerName, address, listenFamily, port, connectionsLimit,
 		tlsConfig, router, wg, tracers, exitOnError, drainTimeout, log)
 }
 
-// DrainAndClose drains and closes the named listener
-func (lg *ListenerGroup) DrainAndClose(listenerName string, drainWait time.Duration) error {
+// DrainAndClose drains and closes the named listener. The listener stops accepting new
+// connections and closes its idle ones right away, and gives its in-flight requests up to
+// drainWait to complete. When forceClose is true, the connections of any requests that are
+// still in flight after drainWait are closed; otherwise they are waited on indefinitely
+func (lg *ListenerGroup) DrainAndClose(listenerName string, drainWait time.Duration,
+	forceClose bool, log *tl.Logger) error {
+	l, err := lg.remove(listenerName)
+	if err != nil {
+		return err
+	}
+	if l.server != nil {
+		go l.drainAndClose(listenerName, drainWait, forceClose, log)
+	}
+	return nil
+}
+
+// Shutdown drains and closes the named listener like DrainAndClose, but returns only
+// once the listener has been closed
+func (lg *ListenerGroup) Shutdown(listenerName string, drainWait time.Duration,
+	forceClose bool, log *tl.Logger) error {
+	l, err := lg.remove(listenerName)
+	if err != nil {
+		return err
+	}
+	if l.server != nil {
+		l.drainAndClose(listenerName, drainWait, forceClose, log)
+	}
+	return nil
+}
+
+// remove removes the named listener from the group, so that its serving errors no longer
+// exit the process
+func (lg *ListenerGroup) remove(listenerName string) (*Listener, error) {
 	lg.listenersLock.Lock()
-	if l, ok := lg.members[listenerName]; ok {
-		l.exitOnError = false
-		delete(lg.members, listenerName)
-		lg.listenersLock.Unlock()
-		if l == nil || l.Listener == nil {
-			return errors.ErrNilListener
-		}
-		ctx := context.Background()
-		go func() {
-			time.Sleep(drainWait)
-			ctx.Done()
-		}()
-		if l.server != nil {
-			go l.server.Shutdown(ctx)
-		}
-		return nil
+	defer lg.listenersLock.Unlock()
+	l, ok := lg.members[listenerName]
+	if !ok {
+		return nil, errors.ErrNoSuchListener
 	}
-	lg.listenersLock.Unlock()
-	return errors.ErrNoSuchListener
+	delete(lg.members, listenerName)
+	if l == nil || l.Listener == nil {
+		return nil, errors.ErrNilListener
+	}
+	l.watchCerts(nil)
+	l.exitOnError = false
+	return l, nil
+}
+
+func (l *Listener) drainAndClose(listenerName string, drainWait time.Duration,
+	forceClose bool, log *tl.Logger) {
+	// Shutdown stops the listener and closes idle connections, and then waits for the
+	// active ones to become idle, which the ConnState tracking also observes
+	go l.server.Shutdown(context.Background())
+	if !forceClose {
+		drainWait = -1
+	}
+	if l.waitIdle(drainWait) {
+		return
+	}
+	if log != nil {
+		log.Warn("force closing listener connections after drain timeout",
+			tl.Pairs{"name": listenerName, "activeRequests": l.ActiveRequests(),
+				"drainTimeout": drainWait.String()})
+	}
+	l.server.Close()
+}
+
+// Drain drains the named listener, so that it rejects new requests until it is undrained
+func (lg *ListenerGroup) Drain(listenerName string) error {
+	return lg.setDrained(listenerName, true)
+}
+
+// Undrain resumes serving requests on the named, drained listener
+func (lg *ListenerGroup) Undrain(listenerName string) error {
+	return lg.setDrained(listenerName, false)
+}
+
+func (lg *ListenerGroup) setDrained(listenerName string, drained bool) error {
+	lg.listenersLock.Lock()
+	defer lg.listenersLock.Unlock()
+	l, ok := lg.members[listenerName]
+	if !ok || l == nil {
+		return errors.ErrNoSuchListener
+	}
+	l.setDrained(drained)
+	return nil
+}
+
+// DrainedStatus returns a map of each listener's name to whether it is drained
+func (lg *ListenerGroup) DrainedStatus() map[string]bool {
+	lg.listenersLock.Lock()
+	defer lg.listenersLock.Unlock()
+	m := make(map[string]bool, len(lg.members))
+	for k, l := range lg.members {
+		m[k] = l.Drained()
+	}
+	return m
+}
+
+// WatchCerts sets the certificate and key files of the named TLS listener, which are checked
+// for changes at the interval, and whose certificates are then swapped in without restarting
+// the listener. It applies to the running listener, and to a listener with the name that is
+// started later. Without any pairs, or with an interval of 0, the files are no longer watched
+func (lg *ListenerGroup) WatchCerts(listenerName string, pairs []sw.KeyPair,
+	interval time.Duration, onSwap func(), onError func(error)) {
+	lg.listenersLock.Lock()
+	defer lg.listenersLock.Unlock()
+	if len(pairs) == 0 || interval <= 0 {
+		delete(lg.certWatches, listenerName)
+	} else {
+		lg.certWatches[listenerName] = &certWatch{pairs: pairs, interval: interval,
+			onSwap: onSwap, onError: onError}
+	}
+	if l, ok := lg.members[listenerName]; ok && l != nil {
+		l.watchCerts(lg.certWatches[listenerName])
+	}
+}
+
+// watchCerts starts the watch of the listener's certificate files, or stops it when w is nil
+func (l *Listener) watchCerts(w *certWatch) {
+	if l.tlsSwapper == nil {
+		return
+	}
+	if w == nil {
+		l.tlsSwapper.StopWatch()
+		return
+	}
+	l.tlsSwapper.Watch(w.pairs, w.interval, w.onSwap, w.onError)
 }
 
 // UpdateFrontendRouters will swap out the routers across the named Listeners with the provided ones
@@ -274,7 +645,6 @@ func (lg *ListenerGroup) UpdateFrontendRouters(mainRouter http.Handler, adminRou
 		for k, v := range lg.members {
 			if k == "httpListener" || k == "tlsListener" {
 				v.routeSwapper.Update(mainRouter)
-				break
 			}
 		}
 	}
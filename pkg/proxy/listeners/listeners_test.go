@@ -24,19 +24,25 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/gorilla/mux"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/tricksterproxy/trickster/pkg/config"
 	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
 	"github.com/tricksterproxy/trickster/pkg/proxy/handlers"
 	ph "github.com/tricksterproxy/trickster/pkg/proxy/handlers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/listeners/family"
+	sw "github.com/tricksterproxy/trickster/pkg/proxy/tls"
 	"github.com/tricksterproxy/trickster/pkg/tracing"
 	"github.com/tricksterproxy/trickster/pkg/tracing/exporters/stdout"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
 )
 
 func testListener() net.Listener {
@@ -52,7 +58,7 @@ func TestListeners(t *testing.T) {
 	testLG := NewListenerGroup()
 
 	wg := &sync.WaitGroup{}
-	var err error
+	errs := make(chan error, 1)
 	wg.Add(1)
 	go func() {
 
@@ -60,44 +66,87 @@ func TestListeners(t *testing.T) {
 			Certificates: make([]tls.Certificate, 1),
 		}
 
-		err = testLG.StartListener("httpListener",
-			"", 0, 20, tc, http.NewServeMux(), wg, trs, false, 0, tl.ConsoleLogger("info"))
+		errs <- testLG.StartListener("httpListener",
+			"", "", 0, 20, tc, http.NewServeMux(), wg, trs, false, 0, tl.ConsoleLogger("info"))
 	}()
 
 	time.Sleep(time.Millisecond * 300)
-	l := testLG.members["httpListener"]
+	l := testLG.Get("httpListener")
 	l.Close()
-	time.Sleep(time.Millisecond * 100)
-	if err == nil {
+	time.Sleep(time.Millisecond * 50)
+	// a listener that fails is restarted, until it is removed from the group
+	testLG.DrainAndClose("httpListener", 0, true, nil)
+	if err := <-errs; err == nil {
 		t.Error("expected non-nil err")
 	}
 
 	wg.Add(1)
 	go func() {
-		err = testLG.StartListenerRouter("httpListener2",
-			"", 0, 20, nil, "/", http.HandlerFunc(handlers.HandleLocalResponse), wg,
+		errs <- testLG.StartListenerRouter("httpListener2",
+			"", "", 0, 20, nil, "/", http.HandlerFunc(handlers.HandleLocalResponse), wg,
 			nil, false, 0, tl.ConsoleLogger("info"))
 	}()
 	time.Sleep(time.Millisecond * 300)
-	l = testLG.members["httpListener2"]
+	l = testLG.Get("httpListener2")
 	l.Listener.Close()
-	time.Sleep(time.Millisecond * 100)
-	if err == nil {
+	time.Sleep(time.Millisecond * 50)
+	testLG.DrainAndClose("httpListener2", 0, true, nil)
+	if err := <-errs; err == nil {
 		t.Error("expected non-nil err")
 	}
 
 	wg.Add(1)
-	err = testLG.StartListener("testBadPort",
-		"", -31, 20, nil, http.NewServeMux(), wg, trs, false, 0, tl.ConsoleLogger("info"))
+	err := testLG.StartListener("testBadPort",
+		"", "", -31, 20, nil, http.NewServeMux(), wg, trs, false, 0, tl.ConsoleLogger("info"))
 	if err == nil {
 		t.Error("expected invalid port error")
 	}
 
 }
 
+func TestBindListener(t *testing.T) {
+
+	testLG := NewListenerGroup()
+	l, err := testLG.BindListener("httpListener", "127.0.0.1", "", 0, 0, nil,
+		http.NewServeMux(), false, 0, tl.ConsoleLogger("error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the listener is a member of the group, and accepts connections, before it is served
+	if testLG.Get("httpListener") != l {
+		t.Error("expected bound listener to be a member of the group")
+	}
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Error(err)
+	} else {
+		conn.Close()
+	}
+
+	// binding a port that is in use fails
+	port := l.Addr().(*net.TCPAddr).Port
+	_, err = testLG.BindListener("httpListener2", "127.0.0.1", "", port, 0, nil,
+		http.NewServeMux(), false, 0, tl.ConsoleLogger("error"))
+	if err == nil {
+		t.Error("expected port in use error")
+	}
+	if testLG.Get("httpListener2") != nil {
+		t.Error("expected listener that failed to bind not to be a member of the group")
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- testLG.ServeListener("httpListener", l, nil, nil, tl.ConsoleLogger("error"))
+	}()
+	testLG.DrainAndClose("httpListener", 0, true, nil)
+	if err := <-errs; err == nil {
+		t.Error("expected non-nil err")
+	}
+}
+
 func TestNewListenerErr(t *testing.T) {
 	config.NewConfig()
-	l, err := NewListener("-", 0, 0, nil, 0, tl.ConsoleLogger("error"))
+	l, err := NewListener("-", "", 0, 0, nil, 0, tl.ConsoleLogger("error"))
 	if err == nil {
 		l.Close()
 		t.Errorf("expected error: %s", `listen tcp: lookup -: no such host`)
@@ -120,7 +169,7 @@ func TestNewListenerTLS(t *testing.T) {
 		t.Error(err)
 	}
 
-	l, err := NewListener("", 0, 0, tlsConfig, 0, tl.ConsoleLogger("error"))
+	l, err := NewListener("", "", 0, 0, tlsConfig, 0, tl.ConsoleLogger("error"))
 	if err != nil {
 		t.Error(err)
 	} else {
@@ -129,6 +178,39 @@ func TestNewListenerTLS(t *testing.T) {
 
 }
 
+func TestNewListenerFamilies(t *testing.T) {
+
+	l, err := NewListener("[::1]", family.IPv6, 0, 0, nil, 0, tl.ConsoleLogger("error"))
+	if err != nil {
+		t.Skip("IPv6 loopback is unavailable:", err)
+	}
+	if a, ok := l.Addr().(*net.TCPAddr); !ok || a.IP.To4() != nil {
+		t.Errorf("expected an IPv6 address, got %v", l.Addr())
+	}
+	l.Close()
+
+	l, err = NewListener("", family.Dual, 0, 0, nil, 0, tl.ConsoleLogger("error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+	svr := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})}
+	go svr.Serve(l)
+
+	// each of the dual-stack sockets accepts connections on the same port
+	for _, host := range []string{"127.0.0.1", "[::1]"} {
+		resp, err := http.Get(fmt.Sprintf("http://%s:%d/", host, port))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		resp.Body.Close()
+	}
+}
+
 func TestListenerConnectionLimitWorks(t *testing.T) {
 
 	handler := func(w http.ResponseWriter, r *http.Request) {
@@ -177,7 +259,7 @@ func TestListenerConnectionLimitWorks(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.Name, func(t *testing.T) {
-			l, err := NewListener("", tc.ListenPort, tc.ConnectionsLimit, nil, 0, tl.ConsoleLogger("error"))
+			l, err := NewListener("", "", tc.ListenPort, tc.ConnectionsLimit, nil, 0, tl.ConsoleLogger("error"))
 			if err != nil {
 				t.Fatal(err)
 			} else {
@@ -222,6 +304,55 @@ func TestCertSwapper(t *testing.T) {
 	}
 }
 
+func TestWatchCerts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trickster-certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	p := sw.KeyPair{CertPath: filepath.Join(dir, "cert.pem"), KeyPath: filepath.Join(dir, "key.pem")}
+	mt := time.Now().Add(-time.Hour)
+	write := func(mt time.Time) {
+		for src, dst := range map[string]string{"../../../testdata/test.01.cert.pem": p.CertPath,
+			"../../../testdata/test.01.key.pem": p.KeyPath} {
+			b, _ := ioutil.ReadFile(src)
+			ioutil.WriteFile(dst, b, 0600)
+			os.Chtimes(dst, mt, mt)
+		}
+	}
+	write(mt)
+
+	certs, err := sw.LoadKeyPairs([]sw.KeyPair{p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lg := NewListenerGroup()
+	lg.members["tlsListener"] = &Listener{tlsSwapper: sw.NewSwapper(certs)}
+	swaps := make(chan struct{}, 10)
+	lg.WatchCerts("tlsListener", []sw.KeyPair{p}, 10*time.Millisecond,
+		func() { swaps <- struct{}{} }, nil)
+	if _, ok := lg.certWatches["tlsListener"]; !ok {
+		t.Error("expected a cert watch")
+	}
+
+	write(mt.Add(time.Minute))
+	select {
+	case <-swaps:
+	case <-time.After(5 * time.Second):
+		t.Error("expected the certificates to be swapped")
+	}
+
+	lg.WatchCerts("tlsListener", nil, 0, nil, nil)
+	if _, ok := lg.certWatches["tlsListener"]; ok {
+		t.Error("expected no cert watch")
+	}
+	write(mt.Add(2 * time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	if len(swaps) != 0 {
+		t.Error("expected no swaps after the watch was removed")
+	}
+}
+
 func TestRouteSwapper(t *testing.T) {
 	l := &Listener{}
 	rs := l.RouteSwapper()
@@ -247,21 +378,116 @@ func TestDrainAndClose(t *testing.T) {
 	l := &Listener{Listener: testListener(), server: &http.Server{}}
 	lg := NewListenerGroup()
 	lg.members["testing"] = l
-	err := lg.DrainAndClose("testing", 0)
+	err := lg.DrainAndClose("testing", 0, true, tl.ConsoleLogger("error"))
 	if err != nil {
 		t.Error(err)
 	}
 	lg.members["nilListener"] = &Listener{}
-	err = lg.DrainAndClose("nilListener", 0)
+	err = lg.DrainAndClose("nilListener", 0, true, nil)
 	if err != errors.ErrNilListener {
 		t.Error("expected error for nil listener")
 	}
-	err = lg.DrainAndClose("unknown", 0)
+	err = lg.DrainAndClose("unknown", 0, true, nil)
+	if err != errors.ErrNoSuchListener {
+		t.Error("expected error for no such listener")
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	l := &Listener{Listener: testListener(), server: &http.Server{}}
+	lg := NewListenerGroup()
+	lg.members["testing"] = l
+	err := lg.Shutdown("testing", 0, true, tl.ConsoleLogger("error"))
+	if err != nil {
+		t.Error(err)
+	}
+	if lg.Get("testing") != nil {
+		t.Error("expected listener to be removed")
+	}
+	err = lg.Shutdown("testing", 0, true, nil)
 	if err != errors.ErrNoSuchListener {
 		t.Error("expected error for no such listener")
 	}
 }
 
+func TestDrainAndCloseInFlight(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		forceClose bool
+		expectErr  bool
+	}{
+		{"wait for in-flight request", false, false},
+		{"force close in-flight request", true, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			started := make(chan bool)
+			release := make(chan bool)
+			defer close(release)
+			router := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				started <- true
+				<-release
+				w.WriteHeader(http.StatusOK)
+			})
+
+			lg := NewListenerGroup()
+			go lg.StartListener("testing", "127.0.0.1", "", 0, 0, nil, router, nil, nil,
+				false, 0, tl.ConsoleLogger("error"))
+			var l *Listener
+			for i := 0; i < 100 && l == nil; i++ {
+				time.Sleep(time.Millisecond * 10)
+				lg.listenersLock.Lock()
+				l = lg.members["testing"]
+				lg.listenersLock.Unlock()
+			}
+			if l == nil {
+				t.Fatal("listener did not start")
+			}
+
+			errs := make(chan error, 1)
+			go func() {
+				// other tests set a timeout on the default client
+				resp, err := (&http.Client{}).Get("http://" + l.Addr().String() + "/")
+				if err == nil {
+					resp.Body.Close()
+				}
+				errs <- err
+			}()
+			<-started
+			if n := l.ActiveRequests(); n != 1 {
+				t.Errorf("expected %d got %d", 1, n)
+			}
+
+			if err := lg.DrainAndClose("testing", time.Millisecond*100, test.forceClose,
+				tl.ConsoleLogger("error")); err != nil {
+				t.Fatal(err)
+			}
+
+			if test.forceClose {
+				if err := <-errs; err == nil {
+					t.Error("expected error for force closed request")
+				}
+				return
+			}
+
+			select {
+			case err := <-errs:
+				t.Fatalf("expected request to still be in flight, got %v", err)
+			case <-time.After(time.Millisecond * 300):
+			}
+			release <- true
+			if err := <-errs; err != nil {
+				t.Error(err)
+			}
+			if !l.waitIdle(time.Second) {
+				t.Error("expected idle listener")
+			}
+		})
+	}
+}
+
 func TestUpdateRouters(t *testing.T) {
 	testRouter := http.NotFoundHandler()
 	l := &Listener{
@@ -279,3 +505,121 @@ func TestUpdateRouters(t *testing.T) {
 		t.Error("expected non-nil handler")
 	}
 }
+
+func TestDrain(t *testing.T) {
+	l := &Listener{
+		Listener:     testListener(),
+		routeSwapper: ph.NewSwitchHandler(http.NotFoundHandler()),
+		server:       &http.Server{},
+	}
+	lg := NewListenerGroup()
+	lg.members["httpListener"] = l
+
+	if err := lg.Drain("httpListener"); err != nil {
+		t.Error(err)
+	}
+	if !lg.DrainedStatus()["httpListener"] {
+		t.Error("expected drained listener")
+	}
+	w := httptest.NewRecorder()
+	l.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if w.Header().Get("Connection") != "close" {
+		t.Error("expected connection close header")
+	}
+
+	if err := lg.Undrain("httpListener"); err != nil {
+		t.Error(err)
+	}
+	if l.Drained() {
+		t.Error("expected undrained listener")
+	}
+	w = httptest.NewRecorder()
+	l.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, w.Code)
+	}
+
+	if err := lg.Drain("unknown"); err != errors.ErrNoSuchListener {
+		t.Error("expected error for no such listener")
+	}
+}
+
+// panicListener panics on its first Accept
+type panicListener struct {
+	net.Listener
+	once sync.Once
+}
+
+func (l *panicListener) Accept() (net.Conn, error) {
+	l.once.Do(func() { panic("accept failed") })
+	return l.Listener.Accept()
+}
+
+func TestServeRestartsAfterPanic(t *testing.T) {
+
+	minRestartBackoff = time.Millisecond
+	defer func() { minRestartBackoff = 100 * time.Millisecond }()
+
+	nl, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := nl.Addr().(*net.TCPAddr).Port
+
+	router := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	l := &Listener{Listener: &panicListener{Listener: nl}, routeSwapper: ph.NewSwitchHandler(router),
+		address: "127.0.0.1", port: port}
+	l.server = &http.Server{Handler: l, ConnState: l.trackConnState}
+	lg := NewListenerGroup()
+	lg.members["testing"] = l
+
+	c := metrics.ProxyListenerRestarts.WithLabelValues("testing", "panic")
+	var before dto.Metric
+	c.Write(&before)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- lg.serve("testing", l, tl.ConsoleLogger("error"))
+	}()
+
+	// the listener is reopened on the same port after the panic
+	var resp *http.Response
+	for i := 0; i < 100; i++ {
+		resp, err = http.Get(fmt.Sprintf("http://127.0.0.1:%d/", port))
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var after dto.Metric
+	c.Write(&after)
+	if after.Counter.GetValue()-before.Counter.GetValue() != 1 {
+		t.Errorf("expected %d got %f", 1, after.Counter.GetValue()-before.Counter.GetValue())
+	}
+
+	// a listener that is closed is not restarted
+	if err = lg.Shutdown("testing", time.Second, true, nil); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err = <-errs:
+		if err != http.ErrServerClosed {
+			t.Errorf("expected %v got %v", http.ErrServerClosed, err)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected the listener to stop serving")
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package listeners
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+// multiListener accepts connections from several sockets, such as the separate IPv4 and
+// IPv6 sockets of a dual-stack listener, as a single net.Listener
+type multiListener struct {
+	listeners []net.Listener
+	conns     chan acceptResult
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var errListenerClosed = errors.New("use of closed network connection")
+
+type acceptResult struct {
+	conn net.Conn
+	err  error
+}
+
+func newMultiListener(listeners ...net.Listener) *multiListener {
+	ml := &multiListener{
+		listeners: listeners,
+		conns:     make(chan acceptResult),
+		done:      make(chan struct{}),
+	}
+	for _, l := range listeners {
+		go ml.acceptLoop(l)
+	}
+	return ml
+}
+
+func (ml *multiListener) acceptLoop(l net.Listener) {
+	for {
+		c, err := l.Accept()
+		select {
+		case ml.conns <- acceptResult{c, err}:
+		case <-ml.done:
+			if c != nil {
+				c.Close()
+			}
+			return
+		}
+		if err != nil {
+			if ne, ok := err.(net.Error); !ok || !ne.Temporary() {
+				return
+			}
+		}
+	}
+}
+
+// Accept implements net.Listener.Accept
+func (ml *multiListener) Accept() (net.Conn, error) {
+	select {
+	case r := <-ml.conns:
+		return r.conn, r.err
+	case <-ml.done:
+		return nil, errListenerClosed
+	}
+}
+
+// Close implements net.Listener.Close, and closes each of the sockets
+func (ml *multiListener) Close() error {
+	var err error
+	ml.closeOnce.Do(func() {
+		close(ml.done)
+		for _, l := range ml.listeners {
+			if lerr := l.Close(); lerr != nil && err == nil {
+				err = lerr
+			}
+		}
+	})
+	return err
+}
+
+// Addr implements net.Listener.Addr, and returns the address of the first socket
+func (ml *multiListener) Addr() net.Addr {
+	return ml.listeners[0].Addr()
+}
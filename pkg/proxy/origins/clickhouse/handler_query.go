@@ -36,5 +36,16 @@ func (c *Client) QueryHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	r.URL = urls.BuildUpstreamURL(r, c.baseUpstreamURL)
+
+	// SELECTs that are not time range queries, like dimension lookups, are cached as objects
+	if c.config != nil && c.config.SelectCacheTTLSecs > 0 {
+		if _, err := c.ParseTimeRangeQuery(r); err != nil {
+			if statement := normalizeSQL(r.URL.Query().Get(upQuery)); isCacheableSelect(statement) {
+				c.selectCacheRequest(w, r, statement)
+				return
+			}
+		}
+	}
+
 	engines.DeltaProxyCacheRequest(w, r)
 }
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clickhouse
+
+import "strings"
+
+// keywords are the SQL keywords whose case is normalized. Function names and formats are
+// not included, since some of them are case sensitive
+var keywords = map[string]bool{
+	"select": true, "from": true, "where": true, "prewhere": true, "and": true, "or": true,
+	"not": true, "in": true, "between": true, "is": true, "null": true, "like": true,
+	"as": true, "group": true, "by": true, "order": true, "asc": true, "desc": true,
+	"having": true, "limit": true, "offset": true, "with": true, "format": true,
+	"distinct": true, "join": true, "on": true, "using": true, "union": true, "all": true,
+	"settings": true,
+}
+
+// normalizeQuery returns the statement for deriving cache keys, normalized as by
+// normalizeSQL, and with its keywords in upper case
+func normalizeQuery(statement string) string {
+	statement = normalizeSQL(statement)
+	var sb strings.Builder
+	sb.Grow(len(statement))
+	for i := 0; i < len(statement); i++ {
+		c := statement[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			j := i + 1
+			for ; j < len(statement) && statement[j] != c; j++ {
+				if statement[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(statement) {
+				j = len(statement) - 1
+			}
+			sb.WriteString(statement[i : j+1])
+			i = j
+		case isIdentChar(c):
+			j := i + 1
+			for j < len(statement) && isIdentChar(statement[j]) {
+				j++
+			}
+			w := statement[i:j]
+			if keywords[strings.ToLower(w)] && (i == 0 || statement[i-1] != '.') {
+				w = strings.ToUpper(w)
+			}
+			sb.WriteString(w)
+			i = j - 1
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,37 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clickhouse
+
+import "testing"
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := []struct {
+		query, expected string
+	}{
+		{"select  count() as cnt\n from db.t -- comment\n where x in ('a  b') format JSON;",
+			`SELECT count() AS cnt FROM db.t WHERE x IN ('a  b') FORMAT JSON`},
+		{`SELECT count() AS cnt FROM db.t WHERE x IN ('a  b') FORMAT JSON`,
+			`SELECT count() AS cnt FROM db.t WHERE x IN ('a  b') FORMAT JSON`},
+		{"SELECT `select`, t.by FROM t WHERE <$TIMESTAMP1$> Group By x",
+			"SELECT `select`, t.by FROM t WHERE <$TIMESTAMP1$> GROUP BY x"},
+	}
+	for _, test := range tests {
+		if q := normalizeQuery(test.query); q != test.expected {
+			t.Errorf("expected %s got %s", test.expected, q)
+		}
+	}
+}
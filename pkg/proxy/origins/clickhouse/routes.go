@@ -19,6 +19,7 @@ package clickhouse
 import (
 	"net/http"
 
+	"github.com/tricksterproxy/trickster/pkg/cache/key"
 	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
@@ -54,5 +55,9 @@ func (c *Client) DefaultPathConfigs(oc *oo.Options) map[string]*po.Options {
 			CacheKeyParams: []string{"query", "database"},
 		},
 	}
+	// equivalent queries from different clients share cache entries
+	if oc != nil && !oc.QueryNormalizationDisabled {
+		paths["/"].KeyNormalizers = map[string]key.NormalizerFunc{upQuery: normalizeQuery}
+	}
 	return paths
 }
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clickhouse
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/engines"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+)
+
+// reNonDeterministic matches calls to functions whose results vary between executions
+// of the same query, which makes the query's results uncacheable
+var reNonDeterministic = regexp.MustCompile(`(?i)\b(now|now64|today|yesterday|rand|rand32|` +
+	`rand64|randConstant|generateUUIDv4|rowNumberInAllBlocks|runningDifference|neighbor)\s*\(`)
+
+// isCacheableSelect returns true if the normalized statement is a SELECT whose results
+// are the same each time it is run
+func isCacheableSelect(statement string) bool {
+	return len(statement) > 7 && strings.EqualFold(statement[:7], "select ") &&
+		!reNonDeterministic.MatchString(statement)
+}
+
+// selectCacheRequest serves a SELECT statement that is not a time range query through
+// the object proxy cache. The statement is normalized so that equivalent statements
+// share a cache key, and its results are cached for the origin's select_cache_ttl_secs
+func (c *Client) selectCacheRequest(w http.ResponseWriter, r *http.Request, statement string) {
+	v := r.URL.Query()
+	v.Set(upQuery, statement)
+	r.URL.RawQuery = v.Encode()
+	// ClickHouse responses have no caching headers, so they are provided by a copy of
+	// the path config for this request
+	if rsc := request.GetResources(r); rsc != nil && rsc.PathConfig != nil {
+		pc := rsc.PathConfig.Clone()
+		if pc.ResponseHeaders == nil {
+			pc.ResponseHeaders = make(map[string]string)
+		}
+		pc.ResponseHeaders[headers.NameCacheControl] = headers.ValueSharedMaxAge + "=" +
+			strconv.Itoa(c.config.SelectCacheTTLSecs)
+		rsc.PathConfig = pc
+	}
+	engines.ObjectProxyCacheRequest(w, r)
+}
+
+// normalizeSQL removes comments from the statement, collapses any whitespace outside of
+// quoted strings and identifiers to a single space, and removes trailing semicolons
+func normalizeSQL(statement string) string {
+	var sb strings.Builder
+	sb.Grow(len(statement))
+	space := false
+	for i := 0; i < len(statement); i++ {
+		c := statement[i]
+		switch {
+		case c == '-' && i+1 < len(statement) && statement[i+1] == '-':
+			j := strings.IndexByte(statement[i:], '\n')
+			if j < 0 {
+				j = len(statement) - i
+			}
+			i += j - 1
+			space = true
+			continue
+		case c == '/' && i+1 < len(statement) && statement[i+1] == '*':
+			j := strings.Index(statement[i+2:], "*/")
+			if j < 0 {
+				j = len(statement) - i - 4
+			}
+			i += j + 3
+			space = true
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			continue
+		}
+		if space && sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		space = false
+		if c == '\'' || c == '"' || c == '`' {
+			j := i + 1
+			for ; j < len(statement) && statement[j] != c; j++ {
+				if statement[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(statement) {
+				j = len(statement) - 1
+			}
+			sb.WriteString(statement[i : j+1])
+			i = j
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return strings.TrimRight(sb.String(), "; ")
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clickhouse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	tu "github.com/tricksterproxy/trickster/pkg/util/testing"
+)
+
+func TestNormalizeSQL(t *testing.T) {
+
+	tests := []struct {
+		statement, expected string
+	}{
+		{"SELECT 1", "SELECT 1"},
+		{"  SELECT\n\tname ,  value\r\nFROM  dict ;\n", "SELECT name , value FROM dict"},
+		{"SELECT 'a  b', \"c  d\", `e  f` FROM t", "SELECT 'a  b', \"c  d\", `e  f` FROM t"},
+		{`SELECT 'it\'s  ok' FROM t`, `SELECT 'it\'s  ok' FROM t`},
+		{"SELECT a -- the a column\nFROM t", "SELECT a FROM t"},
+		{"SELECT /* the\n a */ a FROM t -- end", "SELECT a FROM t"},
+		{"SELECT a FROM t /* unterminated", "SELECT a FROM t"},
+		{"SELECT 'unterminated  x", "SELECT 'unterminated  x"},
+		{"SELECT 1;;", "SELECT 1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.statement, func(t *testing.T) {
+			if v := normalizeSQL(test.statement); v != test.expected {
+				t.Errorf("expected %q got %q", test.expected, v)
+			}
+		})
+	}
+}
+
+func TestIsCacheableSelect(t *testing.T) {
+
+	tests := []struct {
+		statement string
+		expected  bool
+	}{
+		{"SELECT name FROM dict", true},
+		{"select name from dict", true},
+		{"SELECT name FROM dict WHERE d = today()", false},
+		{"SELECT NOW ()", false},
+		{"SELECT rand() FROM t", false},
+		{"SELECT snow(1) FROM t", true},
+		{"INSERT INTO t VALUES (1)", false},
+		{"SELECT", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.statement, func(t *testing.T) {
+			if v := isCacheableSelect(test.statement); v != test.expected {
+				t.Errorf("expected %t got %t", test.expected, v)
+			}
+		})
+	}
+}
+
+func TestQueryHandlerSelectCache(t *testing.T) {
+
+	client := &Client{name: "test"}
+	ts, _, r, hc, err := tu.NewTestInstance("", client.DefaultPathConfigs,
+		200, "{}", nil, "clickhouse", "/", "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+	ctx := r.Context()
+	rsc := request.GetResources(r)
+	rsc.OriginClient = client
+	client.config = rsc.OriginConfig
+	client.config.SelectCacheTTLSecs = 60
+	client.webClient = hc
+	client.config.HTTPClient = hc
+	client.baseUpstreamURL, _ = url.Parse(ts.URL)
+
+	pc := rsc.PathConfig
+	statuses := []string{"kmiss", "hit", "hit"}
+	for i, q := range []string{"SELECT DISTINCT field1 FROM testdb.dim FORMAT JSON",
+		"SELECT DISTINCT field1 FROM testdb.dim FORMAT JSON",
+		"SELECT  DISTINCT field1\nFROM testdb.dim FORMAT JSON;"} {
+		r, _ := http.NewRequest(http.MethodGet,
+			ts.URL+"/?"+url.Values{"query": {q}}.Encode(), nil)
+		rsc.PathConfig = pc
+		r = r.WithContext(ctx)
+		w := httptest.NewRecorder()
+		client.QueryHandler(w, r)
+		resp := w.Result()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected 200 got %d", resp.StatusCode)
+		}
+		result := resp.Header.Get(headers.NameTricksterResult)
+		if !strings.Contains(result, "engine=ObjectProxyCache") ||
+			!strings.Contains(result, "status="+statuses[i]) {
+			t.Errorf("%d: unexpected result header %s", i, result)
+		}
+		if v := resp.Header.Get(headers.NameCacheControl); v != "s-maxage=60" {
+			t.Errorf("expected %s got %s", "s-maxage=60", v)
+		}
+	}
+
+	// the path config shared by all requests is not modified
+	if _, ok := pc.ResponseHeaders[headers.NameCacheControl]; ok {
+		t.Error("expected the shared path config to be unmodified")
+	}
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// UnmarshalTimeseriesResponse converts an upstream response into a Timeseries. InfluxDB 1.x
+// and the 1.x compatibility API of InfluxDB 2.x respond with JSON by default, or with CSV
+// when it is requested. CSV responses are converted, and are served as JSON
+func (c Client) UnmarshalTimeseriesResponse(h http.Header,
+	data []byte) (timeseries.Timeseries, error) {
+	mt, _, _ := mime.ParseMediaType(h.Get(headers.NameContentType))
+	switch {
+	case mt == headers.ValueApplicationMsgPack:
+		return nil, unsupportedFormat(mt)
+	case bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")):
+		return c.UnmarshalTimeseries(data)
+	case mt == headers.ValueApplicationCSV || mt == headers.ValueTextCSV:
+	default:
+		return nil, unsupportedFormat(mt)
+	}
+	se, err := unmarshalCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	h.Set(headers.NameContentType, headers.ValueApplicationJSON)
+	return se, nil
+}
+
+func unsupportedFormat(format string) error {
+	if format == "" {
+		format = "unknown"
+	}
+	return fmt.Errorf("%w: %s", timeseries.ErrUnsupportedResponseFormat, format)
+}
+
+// unmarshalCSV converts an InfluxQL CSV response into a SeriesEnvelope. Each statement's
+// results begin with a name,tags,time header row, followed by a row for each value. The
+// annotated CSV responses of Flux queries are not supported
+func unmarshalCSV(data []byte) (*SeriesEnvelope, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	se := &SeriesEnvelope{Results: []Result{}}
+	var columns []string
+	var row *models.Row
+	var rowTags string
+	for _, rec := range records {
+		if len(rec) > 2 && rec[0] == "name" && rec[1] == "tags" {
+			columns = rec[2:]
+			se.Results = append(se.Results, Result{StatementID: len(se.Results)})
+			row = nil
+			continue
+		}
+		if columns == nil {
+			return nil, unsupportedFormat("csv without an influxql header")
+		}
+		if len(rec) != len(columns)+2 {
+			return nil, fmt.Errorf("csv record has %d fields, expected %d",
+				len(rec), len(columns)+2)
+		}
+		res := &se.Results[len(se.Results)-1]
+		if row == nil || row.Name != rec[0] || rowTags != rec[1] {
+			res.Series = append(res.Series, models.Row{Name: rec[0],
+				Tags: parseCSVTags(rec[1]), Columns: columns})
+			row = &res.Series[len(res.Series)-1]
+			rowTags = rec[1]
+		}
+		vals := make([]interface{}, len(columns))
+		for i, v := range rec[2:] {
+			vals[i] = parseCSVValue(v)
+		}
+		row.Values = append(row.Values, vals)
+	}
+	return se, nil
+}
+
+// parseCSVTags parses the tags field of an InfluxQL CSV record, which is formatted
+// as a comma-separated list of key=value pairs
+func parseCSVTags(s string) map[string]string {
+	if s == "" {
+		return nil
+	}
+	tags := make(map[string]string)
+	for _, kv := range strings.Split(s, ",") {
+		if i := strings.Index(kv, "="); i > 0 {
+			tags[kv[:i]] = kv[i+1:]
+		}
+	}
+	return tags
+}
+
+// parseCSVValue returns a CSV value as the type it is decoded to from a JSON response
+func parseCSVValue(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	if s == "true" || s == "false" {
+		return s == "true"
+	}
+	return s
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+func TestUnmarshalTimeseriesResponse(t *testing.T) {
+
+	client := &Client{}
+
+	expected := &SeriesEnvelope{Results: []Result{
+		{StatementID: 0, Series: []models.Row{
+			{Name: "cpu", Tags: map[string]string{"host": "a", "region": "x"},
+				Columns: []string{"time", "mean"},
+				Values:  [][]interface{}{{float64(1000), 1.5}, {float64(5000), nil}}},
+			{Name: "cpu", Tags: map[string]string{"host": "b", "region": "x"},
+				Columns: []string{"time", "mean"},
+				Values:  [][]interface{}{{float64(1000), float64(2)}}},
+		}},
+		{StatementID: 1, Series: []models.Row{
+			{Name: "up", Columns: []string{"time", "ok", "state"},
+				Values: [][]interface{}{{float64(1000), true, "running"}}},
+		}},
+	}}
+
+	csv := "name,tags,time,mean\n" +
+		"cpu,\"host=a,region=x\",1000,1.5\n" +
+		"cpu,\"host=a,region=x\",5000,\n" +
+		"cpu,\"host=b,region=x\",1000,2\n" +
+		"\n" +
+		"name,tags,time,ok,state\n" +
+		"up,,1000,true,running\n"
+
+	for _, ct := range []string{headers.ValueApplicationCSV, "text/csv; charset=utf-8"} {
+		h := http.Header{headers.NameContentType: []string{ct}}
+		ts, err := client.UnmarshalTimeseriesResponse(h, []byte(csv))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ts, expected) {
+			t.Errorf("expected %v got %v", expected, ts)
+		}
+		if v := h.Get(headers.NameContentType); v != headers.ValueApplicationJSON {
+			t.Errorf("expected %s got %s", headers.ValueApplicationJSON, v)
+		}
+	}
+
+	// JSON responses are decoded regardless of their content type
+	h := http.Header{headers.NameContentType: []string{headers.ValueTextPlain}}
+	ts, err := client.UnmarshalTimeseriesResponse(h, []byte(` {"results":[{"statement_id":0,`+
+		`"series":[{"name":"a","columns":["time","units"],"values":[[1000,1.5]]}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := ts.ValueCount(); n != 1 {
+		t.Errorf("expected %d got %d", 1, n)
+	}
+	if v := h.Get(headers.NameContentType); v != headers.ValueTextPlain {
+		t.Errorf("expected %s got %s", headers.ValueTextPlain, v)
+	}
+}
+
+func TestUnmarshalTimeseriesResponseUnsupported(t *testing.T) {
+
+	client := &Client{}
+
+	tests := []struct {
+		contentType string
+		body        string
+	}{
+		{headers.ValueApplicationMsgPack, "\x81\xa7results\x90"},
+		{headers.ValueApplicationCSV, "#datatype,string,long,dateTime:RFC3339,double\n" +
+			",result,table,_time,_value\n,,0,2020-01-01T00:00:00Z,1.5\n"},
+		{"", "cpu,host=a value=1.5 1000\n"},
+		{"text/html", "<html></html>"},
+	}
+
+	for _, test := range tests {
+		h := http.Header{headers.NameContentType: []string{test.contentType}}
+		_, err := client.UnmarshalTimeseriesResponse(h, []byte(test.body))
+		if !errors.Is(err, timeseries.ErrUnsupportedResponseFormat) {
+			t.Errorf("%s: expected %v got %v", test.contentType,
+				timeseries.ErrUnsupportedResponseFormat, err)
+		}
+	}
+
+	// a malformed CSV response returns an error that is not a format error
+	h := http.Header{headers.NameContentType: []string{headers.ValueApplicationCSV}}
+	_, err := client.UnmarshalTimeseriesResponse(h,
+		[]byte("name,tags,time,mean\ncpu,,1000\n"))
+	if err == nil || errors.Is(err, timeseries.ErrUnsupportedResponseFormat) {
+		t.Errorf("expected record length error got %v", err)
+	}
+}
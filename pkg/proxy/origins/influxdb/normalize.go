@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import "strings"
+
+// keywords are the InfluxQL keywords whose case is normalized. Keywords are not case
+// sensitive, and must be quoted to be used as identifiers, so an unquoted keyword is
+// never the name of a measurement, field or tag
+var keywords = map[string]bool{
+	"select": true, "from": true, "where": true, "and": true, "or": true, "group": true,
+	"by": true, "order": true, "asc": true, "desc": true, "limit": true, "offset": true,
+	"slimit": true, "soffset": true, "fill": true, "as": true, "into": true, "tz": true,
+}
+
+// normalizeQuery returns the InfluxQL query for deriving cache keys, with its whitespace
+// collapsed to a single space and its keywords in upper case. Quoted strings, identifiers,
+// regular expressions and comments are unchanged
+func normalizeQuery(query string) string {
+	var sb strings.Builder
+	sb.Grow(len(query))
+	space := false
+	last := func() byte {
+		if sb.Len() == 0 {
+			return 0
+		}
+		return sb.String()[sb.Len()-1]
+	}
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			space = true
+			continue
+		}
+		if space && sb.Len() > 0 && last() != '\n' {
+			sb.WriteByte(' ')
+		}
+		space = false
+		switch {
+		case c == '\'' || c == '"' || (c == '/' && isRegexStart(sb.String())):
+			j := i + 1
+			for ; j < len(query) && query[j] != c; j++ {
+				if query[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(query) {
+				j = len(query) - 1
+			}
+			sb.WriteString(query[i : j+1])
+			i = j
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			// a comment runs to the end of the line, which must be retained
+			j := strings.IndexByte(query[i:], '\n')
+			if j < 0 {
+				j = len(query) - i
+			}
+			sb.WriteString(strings.TrimRight(query[i:i+j], " \t\r"))
+			sb.WriteByte('\n')
+			i += j
+		case isIdentChar(c):
+			j := i + 1
+			for j < len(query) && isIdentChar(query[j]) {
+				j++
+			}
+			w := query[i:j]
+			if keywords[strings.ToLower(w)] {
+				w = strings.ToUpper(w)
+			}
+			sb.WriteString(w)
+			i = j - 1
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// isRegexStart returns true if a '/' following the written query starts a regular
+// expression, rather than being a division operator
+func isRegexStart(written string) bool {
+	written = strings.TrimRight(written, " \n")
+	if written == "" {
+		return true
+	}
+	switch written[len(written)-1] {
+	case '~', ',', '(':
+		return true
+	}
+	return strings.HasSuffix(written, "FROM") &&
+		(len(written) == 4 || !isIdentChar(written[len(written)-5]))
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import "testing"
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := []struct {
+		query, expected string
+	}{
+		{"select  mean(\"value\")\n from cpu where time > now() - 1h group by time(1m)",
+			`SELECT mean("value") FROM cpu WHERE time > now() - 1h GROUP BY time(1m)`},
+		{`SELECT mean("value") FROM cpu WHERE time > now() - 1h GROUP BY time(1m)`,
+			`SELECT mean("value") FROM cpu WHERE time > now() - 1h GROUP BY time(1m)`},
+		{`SELECT "Select" FROM "from  me" WHERE host = 'a  b' fill(null)`,
+			`SELECT "Select" FROM "from  me" WHERE host = 'a  b' FILL(null)`},
+		{`select x from /cpu  .*/ where host =~ /a  b/ and y / 2 > 1`,
+			`SELECT x FROM /cpu  .*/ WHERE host =~ /a  b/ AND y / 2 > 1`},
+		{"select x from cpu -- a  comment\n  where y > 1", "SELECT x FROM cpu -- a  comment\nWHERE y > 1"},
+		{`SELECT x FROM cpu WHERE <$TIME_TOKEN$>`, `SELECT x FROM cpu WHERE <$TIME_TOKEN$>`},
+		{`select x from "unterminated`, `SELECT x FROM "unterminated`},
+	}
+	for _, test := range tests {
+		if q := normalizeQuery(test.query); q != test.expected {
+			t.Errorf("expected %s got %s", test.expected, q)
+		}
+	}
+}
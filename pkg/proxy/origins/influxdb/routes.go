@@ -19,6 +19,7 @@ package influxdb
 import (
 	"net/http"
 
+	"github.com/tricksterproxy/trickster/pkg/cache/key"
 	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
@@ -77,5 +78,9 @@ func (c *Client) DefaultPathConfigs(oc *oo.Options) map[string]*po.Options {
 			MatchTypeName: "prefix",
 		},
 	}
+	// equivalent queries from different clients share cache entries
+	if oc != nil && !oc.QueryNormalizationDisabled {
+		paths["/"+mnQuery].KeyNormalizers = map[string]key.NormalizerFunc{upQuery: normalizeQuery}
+	}
 	return paths
 }
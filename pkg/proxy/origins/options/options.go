@@ -19,14 +19,25 @@ package options
 import (
 	"errors"
 	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/cache/evictionmethods"
 	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
+	ao "github.com/tricksterproxy/trickster/pkg/proxy/auth/options"
+	co "github.com/tricksterproxy/trickster/pkg/proxy/concurrency/options"
 	rule "github.com/tricksterproxy/trickster/pkg/proxy/origins/rule/options"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	pro "github.com/tricksterproxy/trickster/pkg/proxy/priority/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter"
+	"github.com/tricksterproxy/trickster/pkg/proxy/targets"
+	"github.com/tricksterproxy/trickster/pkg/proxy/tenancy"
 	to "github.com/tricksterproxy/trickster/pkg/proxy/tls/options"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	bf "github.com/tricksterproxy/trickster/pkg/timeseries/backfill/options"
+	ds "github.com/tricksterproxy/trickster/pkg/timeseries/downsample/options"
 
 	"github.com/gorilla/mux"
 )
@@ -47,6 +58,9 @@ type Options struct {
 	OriginURL string `toml:"origin_url"`
 	// TimeoutSecs defines how long the HTTP request will wait for a response before timing out
 	TimeoutSecs int64 `toml:"timeout_secs"`
+	// DeadlineHeader is the name of the request header in which the time remaining before
+	// Trickster gives up on a request is sent to the origin. When empty, no header is sent
+	DeadlineHeader string `toml:"deadline_header"`
 	// KeepAliveTimeoutSecs defines how long an open keep-alive HTTP connection remains idle before closing
 	KeepAliveTimeoutSecs int64 `toml:"keep_alive_timeout_secs"`
 	// MaxIdleConns defines maximum number of open keep-alive connections to maintain
@@ -63,6 +77,16 @@ type Options struct {
 	HealthCheckQuery string `toml:"health_check_query"`
 	// HealthCheckHeaders provides the HTTP Headers to apply when making an upstream health check
 	HealthCheckHeaders map[string]string `toml:"health_check_headers"`
+	// HealthCheckIntervalSecs is the interval, in seconds, at which the origin's health check is
+	// run in the background when DegradedMode is enabled
+	HealthCheckIntervalSecs int `toml:"health_check_interval_secs"`
+	// HealthCheckFailureThreshold is the number of consecutive failed background health checks
+	// after which the origin is considered down
+	HealthCheckFailureThreshold int `toml:"health_check_failure_threshold"`
+	// DegradedMode, when true, runs the origin's health check in the background, and while the
+	// origin is down, serves timeseries requests from any overlapping cached data, rather than
+	// fetching the missing ranges from the origin
+	DegradedMode bool `toml:"degraded_mode"`
 	// Object Proxy Cache and Delta Proxy Cache Configurations
 	// TimeseriesRetentionFactor limits the maximum the number of chronological
 	// timestamps worth of data to store in cache for each query
@@ -70,10 +94,141 @@ type Options struct {
 	// TimeseriesEvictionMethodName specifies which methodology ("oldest", "lru") is used to identify
 	//timeseries to evict from a full cache object
 	TimeseriesEvictionMethodName string `toml:"timeseries_eviction_method"`
+	// TimeseriesMaxExtents limits the number of extents in a timeseries cache object, by compacting
+	// its extent list and then dropping its oldest extents when it has more. 0 is unlimited
+	TimeseriesMaxExtents int `toml:"timeseries_max_extents"`
+	// TimeseriesShardWindowSecs splits timeseries cache objects into one shard per epoch-aligned
+	// window of this many seconds, which are stored and expire independently. 0 is unsharded
+	TimeseriesShardWindowSecs int `toml:"timeseries_shard_window_secs"`
+	// TimeseriesCacheEncoding specifies the binary encoding ("msgpack", "gorilla") of timeseries
+	// stored in non-memory caches, for origin types that support binary cache encoding
+	TimeseriesCacheEncoding string `toml:"timeseries_cache_encoding"`
+	// TimeseriesMaxAgeSecs limits how old, in seconds, the data in a timeseries cache object may
+	// be. Older extents are trimmed when objects are written, and by a background janitor that
+	// rewrites idle objects every RetentionIntervalSecs. 0 is unlimited
+	TimeseriesMaxAgeSecs int `toml:"timeseries_max_age_secs"`
+	// RetentionIntervalSecs specifies how often the background janitor trims timeseries cache
+	// objects down to TimeseriesMaxAgeSecs
+	RetentionIntervalSecs int `toml:"retention_interval_secs"`
+	// RangeSnapName specifies how ("floor", "outward", "nearest") the start and end of timeseries
+	// queries are aligned to step boundaries
+	RangeSnapName string `toml:"range_snap"`
+	// MinStepSecs is the minimum step, in seconds, of timeseries queries. Queries with a smaller step
+	// are served at this step, for origin types that support it. 0 disables the minimum
+	MinStepSecs int `toml:"min_step_secs"`
+	// MaxResultSeries limits the number of series in a timeseries response. 0 is unlimited
+	MaxResultSeries int `toml:"max_result_series"`
+	// MaxResultSamples limits the number of samples across all series in a timeseries response.
+	// 0 is unlimited
+	MaxResultSamples int `toml:"max_result_samples"`
+	// ResultLimitActionName specifies how ("truncate", "reject") a timeseries response that exceeds
+	// MaxResultSeries or MaxResultSamples is handled
+	ResultLimitActionName string `toml:"result_limit_action"`
+	// MaxLookbackSecs limits how far in the past, in seconds, the start of a timeseries query
+	// may be. 0 is unlimited
+	MaxLookbackSecs int `toml:"max_lookback_secs"`
+	// MaxRangeSecs limits the duration, in seconds, of the time range of a timeseries query.
+	// 0 is unlimited
+	MaxRangeSecs int `toml:"max_range_secs"`
+	// TimeRangeLimitActionName specifies how ("clamp", "reject") a timeseries query that exceeds
+	// MaxLookbackSecs or MaxRangeSecs is handled
+	TimeRangeLimitActionName string `toml:"time_range_limit_action"`
+	// MaxDataPoints is the default maximum number of timestamps in a timeseries response, for
+	// requests that do not provide a max_data_points parameter. 0 is unlimited
+	MaxDataPoints int `toml:"max_data_points"`
+	// DataPointAggregationName specifies the function ("avg", "min", "max") that combines the
+	// values of a series when a timeseries response is thinned to its maximum number of timestamps
+	DataPointAggregationName string `toml:"data_point_aggregation"`
+	// ValuePrecision is the number of significant digits to which timeseries values are
+	// rounded before they are cached, for origin types that support it. 0 is unrounded
+	ValuePrecision int `toml:"value_precision"`
+	// ValuePrecisionOnServe indicates whether timeseries responses are also rounded to the
+	// ValuePrecision, so that the values fetched for a response match those later served
+	// from the cache
+	ValuePrecisionOnServe bool `toml:"value_precision_on_serve"`
+	// DedupeLabels is a list of labels that distinguish the replicas of a highly-available origin,
+	// which are removed from timeseries fetched from the origin so that the series returned by
+	// different replicas are merged, for origin types that support it
+	DedupeLabels []string `toml:"dedupe_labels"`
+	// InjectedLabels maps the names of labels that are required in every selector of a query to
+	// the source of their value in each request, like 'header:X-Tenant' or 'jwt:tenant', for
+	// origin types that support it
+	InjectedLabels map[string]string `toml:"injected_labels"`
+	// InjectedLabelsJWTSecret is the HMAC secret that verifies the signatures of the bearer
+	// tokens whose claims are the values of InjectedLabels
+	InjectedLabelsJWTSecret string `toml:"injected_labels_jwt_secret"`
+	// InjectedLabelsJWTKeyPath is the path to a PEM-encoded RSA or ECDSA public key that
+	// verifies the signatures of the bearer tokens whose claims are the values of InjectedLabels
+	InjectedLabelsJWTKeyPath string `toml:"injected_labels_jwt_key_path"`
+	// InjectedLabelsTrustedProxies lists the CIDRs of the authenticating proxies whose requests'
+	// headers and bearer tokens are trusted as the values of InjectedLabels
+	InjectedLabelsTrustedProxies []string `toml:"injected_labels_trusted_proxies"`
+	// DeltaFetchChunks is the number of sub-ranges that the data missing from the cache for a
+	// timeseries request is split into and fetched concurrently from the origin. 1 disables splitting
+	DeltaFetchChunks int `toml:"delta_fetch_chunks"`
+	// DeltaFetchMinChunkSecs is the minimum duration, in seconds, of a sub-range when splitting
+	// missing data into DeltaFetchChunks sub-ranges
+	DeltaFetchMinChunkSecs int `toml:"delta_fetch_min_chunk_secs"`
+	// WarmConnections is the number of connections to the origin that are opened when its
+	// routes are registered at startup or reload, so that they are ready for the first requests
+	WarmConnections int `toml:"warm_connections"`
+	// HedgeURLs is a list of URLs of replicas of the origin, to which requests are hedged when
+	// the origin is slow to respond
+	HedgeURLs []string `toml:"hedge_urls"`
+	// HedgeQuantile is the quantile of the origin's recent response latencies after which a
+	// request is also sent to a replica
+	HedgeQuantile float64 `toml:"hedge_quantile"`
+	// HedgeMinDelayMS is the minimum duration, in milliseconds, to wait for the origin before
+	// a request is also sent to a replica
+	HedgeMinDelayMS int `toml:"hedge_min_delay_ms"`
+	// TargetURLs is a list of URLs of replicas of the origin, such as in other regions, which
+	// are targets for the origin's requests along with the OriginURL
+	TargetURLs []string `toml:"target_urls"`
+	// TargetSelection is the policy by which the target of the origin's requests is selected
+	// from among its healthy targets: 'latency' or 'weight'
+	TargetSelection string `toml:"target_selection"`
+	// TargetWeights maps the OriginURL and TargetURLs to the static weights used by the
+	// 'weight' TargetSelection policy. Targets that are not mapped have a weight of 1
+	TargetWeights map[string]int `toml:"target_weights"`
+	// CacheBusterParams is a list of query parameters that clients add to requests to defeat
+	// caching, like '_' or 'nocache', which are removed from requests before they are keyed
+	// and proxied to the origin
+	CacheBusterParams []string `toml:"cache_buster_params"`
+	// StaticHosts maps origin hostnames to the IP addresses that are dialed for them, bypassing
+	// DNS. The hostname is still used for the Host header and TLS server name
+	StaticHosts map[string][]string `toml:"static_hosts"`
+	// DNSResolver is the address of a DNS server used to resolve origin hostnames that are not
+	// in StaticHosts, instead of the system resolver
+	DNSResolver string `toml:"dns_resolver"`
+	// DeltaFetchParallelism limits the number of concurrent timeseries fetches to this origin
+	// across all requests. 0 is unlimited
+	DeltaFetchParallelism int `toml:"delta_fetch_parallelism"`
+	// DeltaVerifySteps is the number of steps that each delta fetch is extended back into the
+	// cached extent it follows, so that the overlapping samples are compared with the cached
+	// samples, for origin types that support it. 0 disables verification
+	DeltaVerifySteps int `toml:"delta_verify_steps"`
+	// DeltaVerifyInvalidate indicates whether the rest of a cached extent, whose samples did
+	// not match those fetched in the overlap, is removed from the cache to be refetched
+	DeltaVerifyInvalidate bool `toml:"delta_verify_invalidate"`
+	// PriorityHeader is the request header whose value tags a request with one of the origin's
+	// PriorityClasses
+	PriorityHeader string `toml:"priority_header"`
+	// DefaultPriorityClass is the name of the priority class of requests that are not tagged with
+	// a class by their header or path. When empty, those requests are not subject to admission control
+	DefaultPriorityClass string `toml:"default_priority_class"`
+	// PriorityClasses is a map of the classes into which requests to the origin are tagged, which
+	// each admit requests within a separate concurrency budget
+	PriorityClasses map[string]*pro.Options `toml:"priority_classes"`
+	// DownsampleTiers is a map of tiers at which cached timeseries data older than the tier's
+	// minimum age is stored and served at a coarser step, for origin types that support it
+	DownsampleTiers map[string]*ds.Options `toml:"downsample_tiers"`
 	// BackfillToleranceSecs prevents values with timestamps newer than the provided
 	// number of seconds from being cached this allows propagation of upstream backfill operations
 	// that modify recently-served data
 	BackfillToleranceSecs int64 `toml:"backfill_tolerance_secs"`
+	// BackfillToleranceRules is a map of rules that apply a different backfill tolerance to
+	// timeseries queries matching the rule's pattern, and take precedence over path and origin values
+	BackfillToleranceRules map[string]*bf.Options `toml:"backfill_tolerance_rules"`
 	// PathList is a list of Path Options that control the behavior of the given paths when requested
 	Paths map[string]*po.Options `toml:"paths"`
 	// NegativeCacheName provides the name of the Negative Cache Config to be used by this Origin
@@ -82,6 +237,24 @@ type Options struct {
 	TimeseriesTTLSecs int `toml:"timeseries_ttl_secs"`
 	// TimeseriesTTLSecs specifies the cache TTL of fast forward data
 	FastForwardTTLSecs int `toml:"fastforward_ttl_secs"`
+	// EmptyQuarantineTTLSecs specifies the cache TTL of timeseries whose newly fetched data had
+	// no series, though their cached data did, which is likely a transient origin issue. 0 caches
+	// them for the TimeseriesTTLSecs
+	EmptyQuarantineTTLSecs int `toml:"empty_quarantine_ttl_secs"`
+	// SelectCacheTTLSecs specifies the cache TTL of the results of SELECT statements that are not
+	// time range queries, for origin types that support it. 0 disables their caching
+	SelectCacheTTLSecs int `toml:"select_cache_ttl_secs"`
+	// StatusCacheTTLSecs specifies the cache TTL of responses from the origin's status endpoints,
+	// like scrape targets and TSDB status, for origin types that support it. 0 disables their caching
+	StatusCacheTTLSecs int `toml:"status_cache_ttl_secs"`
+	// FederateCacheTTLSecs specifies the cache TTL of responses from the origin's federation
+	// endpoint, for origin types that support it. It is best set to the scrape interval of the
+	// federating servers. 0 disables their caching
+	FederateCacheTTLSecs int `toml:"federate_cache_ttl_secs"`
+	// RemoteWriteInvalidationSecs specifies how long cached timeseries overlapping samples
+	// written through the origin's remote write endpoint are refetched, for origin types
+	// that support it. 0 passes writes through without affecting the cache
+	RemoteWriteInvalidationSecs int `toml:"remote_write_invalidation_secs"`
 	// MaxTTLSecs specifies the maximum allowed TTL for any cache object
 	MaxTTLSecs int `toml:"max_ttl_secs"`
 	// RevalidationFactor specifies how many times to multiply the object freshness lifetime
@@ -89,6 +262,12 @@ type Options struct {
 	RevalidationFactor float64 `toml:"revalidation_factor"`
 	// MaxObjectSizeBytes specifies the max objectsize to be accepted for any given cache object
 	MaxObjectSizeBytes int `toml:"max_object_size_bytes"`
+	// CacheMaxBodyBytes is the size, in bytes, beyond which upstream response bodies are proxied
+	// without being cached. 0 is unlimited
+	CacheMaxBodyBytes int `toml:"cache_max_body_bytes"`
+	// CacheContentTypes is a list of media types, like 'application/json', to which upstream
+	// responses must belong in order to be cached. When empty, any content type is cached
+	CacheContentTypes []string `toml:"cache_content_types"`
 	// CompressableTypeList specifies the HTTP Object Content Types that will be compressed internally
 	// when stored in the Trickster cache
 	CompressableTypeList []string `toml:"compressable_types"`
@@ -100,9 +279,26 @@ type Options struct {
 	// ReqRewriterName is the name of a configured Rewriter that will modify the request prior to
 	// processing by the origin client
 	ReqRewriterName string `toml:"req_rewriter_name"`
+	// LogLevel is the most granular level (e.g., DEBUG, INFO, ERROR) logged for requests to this origin.
+	// When empty, the instance's log level is used
+	LogLevel string `toml:"log_level"`
+	// SlowLogThresholdMS is the duration, in milliseconds, beyond which a fetch from this origin
+	// is recorded in the slow query log. 0 disables slow query logging for the origin
+	SlowLogThresholdMS int `toml:"slow_log_threshold_ms"`
 
 	// TLS is the TLS Configuration for the Frontend and Backend
 	TLS *to.Options `toml:"tls"`
+	// SpiffeUpstream, when true, authenticates the upstream connections with the process's
+	// SPIFFE SVID, and requires the upstream server to present an SVID from the trust domain
+	SpiffeUpstream bool `toml:"spiffe_upstream"`
+	// SpiffeUpstreamIDs limits the upstream servers to these SPIFFE IDs. When empty, any
+	// SVID from the trust domain is accepted
+	SpiffeUpstreamIDs []string `toml:"spiffe_upstream_ids"`
+	// AdaptiveConcurrency, when configured, limits the concurrent requests to the origin to a
+	// limit that adapts to the origin's latency and failures
+	AdaptiveConcurrency *co.Options `toml:"adaptive_concurrency"`
+	// Auth is the collection of credentials that are attached to requests to the origin
+	Auth *ao.Options `toml:"auth"`
 
 	// ForwardedHeaders indicates the class of 'Forwarded' header to attach to upstream requests
 	ForwardedHeaders string `toml:"forwarded_headers"`
@@ -123,6 +319,15 @@ type Options struct {
 	// expects a multipart response	// this optimizes Trickster to request as few bytes as possible when
 	// fronting origins that only support single range requests
 	DearticulateUpstreamRanges bool `toml:"dearticulate_upstream_ranges"`
+	// CacheStateHeaders, when true, adds Cache-Control, Age and X-Cache headers describing the
+	// cache state and freshness of the data to accelerated timeseries responses
+	CacheStateHeaders bool `toml:"cache_state_headers"`
+	// ExemplarsDisabled, when true, omits trace exemplars from this origin's request duration
+	// metrics, even when exemplars are enabled in the metrics config
+	ExemplarsDisabled bool `toml:"exemplars_disabled"`
+	// QueryNormalizationDisabled, when true, derives cache keys from queries as they are sent by
+	// clients, rather than from their normalized form, for origin types that support it
+	QueryNormalizationDisabled bool `toml:"query_normalization_disabled"`
 
 	// Synthesized Configurations
 	// These configurations are parsed versions of those defined above, and are what Trickster uses internally
@@ -152,14 +357,55 @@ type Options struct {
 	TimeseriesRetention time.Duration `toml:"-"`
 	// TimeseriesEvictionMethod is the parsed value of TimeseriesEvictionMethodName
 	TimeseriesEvictionMethod evictionmethods.TimeseriesEvictionMethod `toml:"-"`
+	// TimeseriesShardWindow is the parsed value of TimeseriesShardWindowSecs
+	TimeseriesShardWindow time.Duration `toml:"-"`
+	// TimeseriesMaxAge is the parsed value of TimeseriesMaxAgeSecs
+	TimeseriesMaxAge time.Duration `toml:"-"`
+	// RetentionInterval is the parsed value of RetentionIntervalSecs
+	RetentionInterval time.Duration `toml:"-"`
 	// TimeseriesTTL is the parsed value of TimeseriesTTLSecs
 	TimeseriesTTL time.Duration `toml:"-"`
 	// FastForwardTTL is the parsed value of FastForwardTTL
 	FastForwardTTL time.Duration `toml:"-"`
+	// EmptyQuarantineTTL is the parsed value of EmptyQuarantineTTLSecs
+	EmptyQuarantineTTL time.Duration `toml:"-"`
 	// FastForwardPath is the paths.Options to use for upstream Fast Forward Requests
 	FastForwardPath *po.Options `toml:"-"`
 	// MaxTTL is the parsed value of MaxTTLSecs
 	MaxTTL time.Duration `toml:"-"`
+	// RangeSnap is the parsed value of RangeSnapName
+	RangeSnap timeseries.SnapMode `toml:"-"`
+	// MinStep is the parsed value of MinStepSecs
+	MinStep time.Duration `toml:"-"`
+	// ResultLimitAction is the parsed value of ResultLimitActionName
+	ResultLimitAction timeseries.LimitAction `toml:"-"`
+	// MaxLookback is the parsed value of MaxLookbackSecs
+	MaxLookback time.Duration `toml:"-"`
+	// MaxRange is the parsed value of MaxRangeSecs
+	MaxRange time.Duration `toml:"-"`
+	// TimeRangeLimitAction is the parsed value of TimeRangeLimitActionName
+	TimeRangeLimitAction timeseries.LimitAction `toml:"-"`
+	// InjectedLabelEnforcer is the parsed representation of InjectedLabels and the options
+	// that authenticate their sources
+	InjectedLabelEnforcer *tenancy.Enforcer `toml:"-"`
+	// DataPointAggregation is the parsed value of DataPointAggregationName
+	DataPointAggregation timeseries.Aggregation `toml:"-"`
+	// DownsampleTierList is the list of DownsampleTiers, sorted by minimum age
+	DownsampleTierList ds.Tiers `toml:"-"`
+	// BackfillToleranceRuleList is the list of BackfillToleranceRules, sorted by name
+	BackfillToleranceRuleList bf.Rules `toml:"-"`
+	// DeltaFetchMinChunk is the parsed value of DeltaFetchMinChunkSecs
+	DeltaFetchMinChunk time.Duration `toml:"-"`
+	// HedgeTargets is the parsed value of HedgeURLs
+	HedgeTargets []*url.URL `toml:"-"`
+	// HedgeMinDelay is the parsed value of HedgeMinDelayMS
+	HedgeMinDelay time.Duration `toml:"-"`
+	// TargetSelector selects the target of the origin's requests, when TargetURLs are configured
+	TargetSelector *targets.Selector `toml:"-"`
+	// RemoteWriteInvalidation is the parsed value of RemoteWriteInvalidationSecs
+	RemoteWriteInvalidation time.Duration `toml:"-"`
+	// SlowLogThreshold is the parsed value of SlowLogThresholdMS
+	SlowLogThreshold time.Duration `toml:"-"`
 	// HTTPClient is the Client used by trickster to communicate with this origin
 	HTTPClient *http.Client `toml:"-"`
 	// CompressableTypes is the map version of CompressableTypeList for fast lookup
@@ -168,8 +414,24 @@ type Options struct {
 	RuleOptions *rule.Options `toml:"-"`
 	// ReqRewriter is the rewriter handler as indicated by RuleName
 	ReqRewriter rewriter.RewriteInstructions
+
+	// HealthCheckInterval is the parsed value of HealthCheckIntervalSecs
+	HealthCheckInterval time.Duration `toml:"-"`
+
+	// fetchSlots is the semaphore limiting concurrent fetches to DeltaFetchParallelism
+	fetchSlots chan struct{}
+	// writeWindows records the samples recently written through the origin
+	writeWindows *timeseries.WriteWindows
+	// retainedKeys records the timeseries cache objects written for the origin
+	retainedKeys *timeseries.RetainedKeys
+	// down is non-zero while the origin's background health checks report it as down
+	down int32
 }
 
+var fetchSlotsLock sync.Mutex
+var writeWindowsLock sync.Mutex
+var retainedKeysLock sync.Mutex
+
 // NewOptions will return a pointer to an OriginConfig with the default configuration settings
 func NewOptions() *Options {
 	return &Options{
@@ -181,7 +443,19 @@ func NewOptions() *Options {
 		FastForwardTTL:               d.DefaultFastForwardTTLSecs * time.Second,
 		FastForwardTTLSecs:           d.DefaultFastForwardTTLSecs,
 		ForwardedHeaders:             d.DefaultForwardedHeaders,
+		DeltaFetchChunks:             d.DefaultDeltaFetchChunks,
+		DeltaFetchMinChunk:           d.DefaultDeltaFetchMinChunkSecs * time.Second,
+		DeltaFetchMinChunkSecs:       d.DefaultDeltaFetchMinChunkSecs,
+		DeltaFetchParallelism:        d.DefaultDeltaFetchParallelism,
 		HealthCheckHeaders:           make(map[string]string),
+		HealthCheckFailureThreshold:  d.DefaultHealthCheckFailureThreshold,
+		HealthCheckInterval:          d.DefaultHealthCheckIntervalSecs * time.Second,
+		HealthCheckIntervalSecs:      d.DefaultHealthCheckIntervalSecs,
+		HedgeMinDelay:                d.DefaultHedgeMinDelayMS * time.Millisecond,
+		HedgeMinDelayMS:              d.DefaultHedgeMinDelayMS,
+		HedgeQuantile:                d.DefaultHedgeQuantile,
+		TargetSelection:              d.DefaultTargetSelection,
+		PriorityHeader:               d.DefaultPriorityHeader,
 		HealthCheckQuery:             d.DefaultHealthCheckQuery,
 		HealthCheckUpstreamPath:      d.DefaultHealthCheckPath,
 		HealthCheckVerb:              d.DefaultHealthCheckVerb,
@@ -193,58 +467,199 @@ func NewOptions() *Options {
 		NegativeCache:                make(map[int]time.Duration),
 		NegativeCacheName:            d.DefaultOriginNegativeCacheName,
 		Paths:                        make(map[string]*po.Options),
+		StatusCacheTTLSecs:           d.DefaultStatusCacheTTLSecs,
+		FederateCacheTTLSecs:         d.DefaultFederateCacheTTLSecs,
+		MinStepSecs:                  d.DefaultMinStepSecs,
+		MaxResultSeries:              d.DefaultMaxResultSeries,
+		MaxDataPoints:                d.DefaultMaxDataPoints,
+		DataPointAggregation:         timeseries.AggregationAvg,
+		DataPointAggregationName:     d.DefaultDataPointAggregationName,
+		MaxResultSamples:             d.DefaultMaxResultSamples,
+		ResultLimitAction:            timeseries.LimitActionTruncate,
+		ResultLimitActionName:        d.DefaultResultLimitActionName,
+		TimeRangeLimitAction:         timeseries.LimitActionTruncate,
+		TimeRangeLimitActionName:     d.DefaultTimeRangeLimitActionName,
+		RangeSnap:                    timeseries.SnapFloor,
+		RangeSnapName:                d.DefaultRangeSnapName,
 		RevalidationFactor:           d.DefaultRevalidationFactor,
 		TLS:                          &to.Options{},
 		Timeout:                      time.Second * d.DefaultOriginTimeoutSecs,
 		TimeoutSecs:                  d.DefaultOriginTimeoutSecs,
 		TimeseriesEvictionMethod:     d.DefaultOriginTEM,
 		TimeseriesEvictionMethodName: d.DefaultOriginTEMName,
+		TimeseriesCacheEncoding:      d.DefaultTimeseriesCacheEncoding,
 		TimeseriesRetention:          d.DefaultOriginTRF,
 		TimeseriesRetentionFactor:    d.DefaultOriginTRF,
+		TimeseriesMaxExtents:         d.DefaultTimeseriesMaxExtents,
+		TimeseriesShardWindowSecs:    d.DefaultTimeseriesShardWindowSecs,
+		RetentionInterval:            d.DefaultRetentionIntervalSecs * time.Second,
+		RetentionIntervalSecs:        d.DefaultRetentionIntervalSecs,
 		TimeseriesTTL:                d.DefaultTimeseriesTTLSecs * time.Second,
 		TimeseriesTTLSecs:            d.DefaultTimeseriesTTLSecs,
 		TracingConfigName:            d.DefaultTracingConfigName,
 	}
 }
 
+// AcquireDeltaFetchSlot blocks until the origin is below its DeltaFetchParallelism limit of
+// concurrent timeseries fetches, and returns the func that releases the acquired slot
+func (oc *Options) AcquireDeltaFetchSlot() func() {
+	if oc == nil || oc.DeltaFetchParallelism < 1 {
+		return func() {}
+	}
+	fetchSlotsLock.Lock()
+	if oc.fetchSlots == nil {
+		oc.fetchSlots = make(chan struct{}, oc.DeltaFetchParallelism)
+	}
+	slots := oc.fetchSlots
+	fetchSlotsLock.Unlock()
+	slots <- struct{}{}
+	return func() { <-slots }
+}
+
+// WriteWindows returns the windows of samples recently written through the origin,
+// or nil if RemoteWriteInvalidation is not enabled
+func (oc *Options) WriteWindows() *timeseries.WriteWindows {
+	if oc == nil || oc.RemoteWriteInvalidation <= 0 {
+		return nil
+	}
+	writeWindowsLock.Lock()
+	defer writeWindowsLock.Unlock()
+	if oc.writeWindows == nil {
+		oc.writeWindows = timeseries.NewWriteWindows(oc.RemoteWriteInvalidation)
+	}
+	return oc.writeWindows
+}
+
+// RetainedKeys returns the timeseries cache objects written for the origin, which the
+// retention janitor trims, or nil if TimeseriesMaxAge is not enabled
+func (oc *Options) RetainedKeys() *timeseries.RetainedKeys {
+	if oc == nil || oc.TimeseriesMaxAge <= 0 {
+		return nil
+	}
+	retainedKeysLock.Lock()
+	defer retainedKeysLock.Unlock()
+	if oc.retainedKeys == nil {
+		oc.retainedKeys = timeseries.NewRetainedKeys()
+	}
+	return oc.retainedKeys
+}
+
+// SetDown sets whether the origin is down, as reported by its background health checks
+func (oc *Options) SetDown(down bool) {
+	if down {
+		atomic.StoreInt32(&oc.down, 1)
+		return
+	}
+	atomic.StoreInt32(&oc.down, 0)
+}
+
+// Down returns true while the origin's background health checks report it as down
+func (oc *Options) Down() bool {
+	return oc != nil && atomic.LoadInt32(&oc.down) != 0
+}
+
 // Clone returns an exact copy of an *origins.Options
 func (oc *Options) Clone() *Options {
 
 	o := &Options{}
 	o.DearticulateUpstreamRanges = oc.DearticulateUpstreamRanges
+	o.CacheStateHeaders = oc.CacheStateHeaders
+	o.ExemplarsDisabled = oc.ExemplarsDisabled
+	o.QueryNormalizationDisabled = oc.QueryNormalizationDisabled
 	o.BackfillTolerance = oc.BackfillTolerance
 	o.BackfillToleranceSecs = oc.BackfillToleranceSecs
 	o.CacheName = oc.CacheName
 	o.CacheKeyPrefix = oc.CacheKeyPrefix
+	o.DeltaFetchChunks = oc.DeltaFetchChunks
+	o.DeltaFetchMinChunk = oc.DeltaFetchMinChunk
+	o.DeltaFetchMinChunkSecs = oc.DeltaFetchMinChunkSecs
+	o.DeltaFetchParallelism = oc.DeltaFetchParallelism
+	o.DeltaVerifySteps = oc.DeltaVerifySteps
+	o.DeltaVerifyInvalidate = oc.DeltaVerifyInvalidate
 	o.FastForwardDisable = oc.FastForwardDisable
 	o.FastForwardTTL = oc.FastForwardTTL
+	o.EmptyQuarantineTTL = oc.EmptyQuarantineTTL
+	o.EmptyQuarantineTTLSecs = oc.EmptyQuarantineTTLSecs
 	o.FastForwardTTLSecs = oc.FastForwardTTLSecs
+	o.SelectCacheTTLSecs = oc.SelectCacheTTLSecs
+	o.StatusCacheTTLSecs = oc.StatusCacheTTLSecs
+	o.FederateCacheTTLSecs = oc.FederateCacheTTLSecs
+	o.RemoteWriteInvalidationSecs = oc.RemoteWriteInvalidationSecs
+	o.RemoteWriteInvalidation = oc.RemoteWriteInvalidation
 	o.ForwardedHeaders = oc.ForwardedHeaders
 	o.HealthCheckUpstreamPath = oc.HealthCheckUpstreamPath
 	o.HealthCheckVerb = oc.HealthCheckVerb
+	o.HealthCheckInterval = oc.HealthCheckInterval
+	o.HealthCheckIntervalSecs = oc.HealthCheckIntervalSecs
+	o.HealthCheckFailureThreshold = oc.HealthCheckFailureThreshold
+	o.DegradedMode = oc.DegradedMode
 	o.HealthCheckQuery = oc.HealthCheckQuery
+	o.HedgeMinDelay = oc.HedgeMinDelay
+	o.HedgeMinDelayMS = oc.HedgeMinDelayMS
+	o.DNSResolver = oc.DNSResolver
+	o.HedgeQuantile = oc.HedgeQuantile
+	o.TargetSelection = oc.TargetSelection
+	o.TargetSelector = oc.TargetSelector
+	o.PriorityHeader = oc.PriorityHeader
+	o.DefaultPriorityClass = oc.DefaultPriorityClass
+	o.WarmConnections = oc.WarmConnections
 	o.Host = oc.Host
 	o.Name = oc.Name
 	o.IsDefault = oc.IsDefault
 	o.KeepAliveTimeoutSecs = oc.KeepAliveTimeoutSecs
+	o.LogLevel = oc.LogLevel
 	o.MaxIdleConns = oc.MaxIdleConns
 	o.MaxTTLSecs = oc.MaxTTLSecs
 	o.MaxTTL = oc.MaxTTL
 	o.MaxObjectSizeBytes = oc.MaxObjectSizeBytes
+	o.CacheMaxBodyBytes = oc.CacheMaxBodyBytes
 	o.MultipartRangesDisabled = oc.MultipartRangesDisabled
 	o.OriginType = oc.OriginType
 	o.OriginURL = oc.OriginURL
 	o.PathPrefix = oc.PathPrefix
+	o.MinStep = oc.MinStep
+	o.MinStepSecs = oc.MinStepSecs
+	o.MaxResultSeries = oc.MaxResultSeries
+	o.MaxDataPoints = oc.MaxDataPoints
+	o.DataPointAggregation = oc.DataPointAggregation
+	o.DataPointAggregationName = oc.DataPointAggregationName
+	o.ValuePrecision = oc.ValuePrecision
+	o.ValuePrecisionOnServe = oc.ValuePrecisionOnServe
+	o.MaxResultSamples = oc.MaxResultSamples
+	o.ResultLimitAction = oc.ResultLimitAction
+	o.ResultLimitActionName = oc.ResultLimitActionName
+	o.MaxLookbackSecs = oc.MaxLookbackSecs
+	o.MaxLookback = oc.MaxLookback
+	o.MaxRangeSecs = oc.MaxRangeSecs
+	o.MaxRange = oc.MaxRange
+	o.TimeRangeLimitAction = oc.TimeRangeLimitAction
+	o.TimeRangeLimitActionName = oc.TimeRangeLimitActionName
+	o.InjectedLabelsJWTSecret = oc.InjectedLabelsJWTSecret
+	o.InjectedLabelsJWTKeyPath = oc.InjectedLabelsJWTKeyPath
+	o.InjectedLabelEnforcer = oc.InjectedLabelEnforcer
+	o.RangeSnap = oc.RangeSnap
+	o.RangeSnapName = oc.RangeSnapName
 	o.ReqRewriterName = oc.ReqRewriterName
 	o.RevalidationFactor = oc.RevalidationFactor
 	o.RuleName = oc.RuleName
 	o.Scheme = oc.Scheme
+	o.SlowLogThreshold = oc.SlowLogThreshold
+	o.SlowLogThresholdMS = oc.SlowLogThresholdMS
 	o.Timeout = oc.Timeout
 	o.TimeoutSecs = oc.TimeoutSecs
+	o.DeadlineHeader = oc.DeadlineHeader
 	o.TimeseriesRetention = oc.TimeseriesRetention
 	o.TimeseriesRetentionFactor = oc.TimeseriesRetentionFactor
 	o.TimeseriesEvictionMethodName = oc.TimeseriesEvictionMethodName
+	o.TimeseriesCacheEncoding = oc.TimeseriesCacheEncoding
 	o.TimeseriesEvictionMethod = oc.TimeseriesEvictionMethod
+	o.TimeseriesMaxExtents = oc.TimeseriesMaxExtents
+	o.TimeseriesShardWindow = oc.TimeseriesShardWindow
+	o.TimeseriesShardWindowSecs = oc.TimeseriesShardWindowSecs
+	o.TimeseriesMaxAge = oc.TimeseriesMaxAge
+	o.TimeseriesMaxAgeSecs = oc.TimeseriesMaxAgeSecs
+	o.RetentionInterval = oc.RetentionInterval
+	o.RetentionIntervalSecs = oc.RetentionIntervalSecs
 	o.TimeseriesTTL = oc.TimeseriesTTL
 	o.TimeseriesTTLSecs = oc.TimeseriesTTLSecs
 	o.ValueRetention = oc.ValueRetention
@@ -261,11 +676,68 @@ func (oc *Options) Clone() *Options {
 		copy(o.Hosts, oc.Hosts)
 	}
 
+	if oc.HedgeURLs != nil {
+		o.HedgeURLs = make([]string, len(oc.HedgeURLs))
+		copy(o.HedgeURLs, oc.HedgeURLs)
+	}
+
+	if oc.TargetURLs != nil {
+		o.TargetURLs = make([]string, len(oc.TargetURLs))
+		copy(o.TargetURLs, oc.TargetURLs)
+	}
+
+	if oc.TargetWeights != nil {
+		o.TargetWeights = make(map[string]int, len(oc.TargetWeights))
+		for k, v := range oc.TargetWeights {
+			o.TargetWeights[k] = v
+		}
+	}
+
+	if oc.CacheBusterParams != nil {
+		o.CacheBusterParams = make([]string, len(oc.CacheBusterParams))
+		copy(o.CacheBusterParams, oc.CacheBusterParams)
+	}
+
+	if oc.CacheContentTypes != nil {
+		o.CacheContentTypes = make([]string, len(oc.CacheContentTypes))
+		copy(o.CacheContentTypes, oc.CacheContentTypes)
+	}
+
+	if oc.StaticHosts != nil {
+		o.StaticHosts = make(map[string][]string, len(oc.StaticHosts))
+		for h, ips := range oc.StaticHosts {
+			o.StaticHosts[h] = make([]string, len(ips))
+			copy(o.StaticHosts[h], ips)
+		}
+	}
+
+	if oc.HedgeTargets != nil {
+		o.HedgeTargets = make([]*url.URL, len(oc.HedgeTargets))
+		copy(o.HedgeTargets, oc.HedgeTargets)
+	}
+
 	if oc.CompressableTypeList != nil {
 		o.CompressableTypeList = make([]string, len(oc.CompressableTypeList))
 		copy(o.CompressableTypeList, oc.CompressableTypeList)
 	}
 
+	if oc.DedupeLabels != nil {
+		o.DedupeLabels = make([]string, len(oc.DedupeLabels))
+		copy(o.DedupeLabels, oc.DedupeLabels)
+	}
+
+	if oc.InjectedLabels != nil {
+		o.InjectedLabels = make(map[string]string, len(oc.InjectedLabels))
+		for k, v := range oc.InjectedLabels {
+			o.InjectedLabels[k] = v
+		}
+	}
+
+	if oc.InjectedLabelsTrustedProxies != nil {
+		o.InjectedLabelsTrustedProxies = make([]string, len(oc.InjectedLabelsTrustedProxies))
+		copy(o.InjectedLabelsTrustedProxies, oc.InjectedLabelsTrustedProxies)
+	}
+
 	if oc.CompressableTypes != nil {
 		o.CompressableTypes = make(map[string]bool)
 		for k := range oc.CompressableTypes {
@@ -283,6 +755,29 @@ func (oc *Options) Clone() *Options {
 		o.Paths[l] = p.Clone()
 	}
 
+	if oc.DownsampleTiers != nil {
+		o.DownsampleTiers = make(map[string]*ds.Options)
+		for l, t := range oc.DownsampleTiers {
+			o.DownsampleTiers[l] = t.Clone()
+		}
+		o.DownsampleTierList = ds.NewTiers(o.DownsampleTiers)
+	}
+
+	if oc.PriorityClasses != nil {
+		o.PriorityClasses = make(map[string]*pro.Options)
+		for l, c := range oc.PriorityClasses {
+			o.PriorityClasses[l] = c.Clone()
+		}
+	}
+
+	if oc.BackfillToleranceRules != nil {
+		o.BackfillToleranceRules = make(map[string]*bf.Options)
+		for l, r := range oc.BackfillToleranceRules {
+			o.BackfillToleranceRules[l] = r.Clone()
+		}
+		o.BackfillToleranceRuleList = bf.NewRules(o.BackfillToleranceRules)
+	}
+
 	o.NegativeCacheName = oc.NegativeCacheName
 	if oc.NegativeCache != nil {
 		m := make(map[int]time.Duration)
@@ -295,7 +790,18 @@ func (oc *Options) Clone() *Options {
 	if oc.TLS != nil {
 		o.TLS = oc.TLS.Clone()
 	}
+	if oc.Auth != nil {
+		o.Auth = oc.Auth.Clone()
+	}
+	if oc.AdaptiveConcurrency != nil {
+		o.AdaptiveConcurrency = oc.AdaptiveConcurrency.Clone()
+	}
 	o.RequireTLS = oc.RequireTLS
+	o.SpiffeUpstream = oc.SpiffeUpstream
+	if oc.SpiffeUpstreamIDs != nil {
+		o.SpiffeUpstreamIDs = make([]string, len(oc.SpiffeUpstreamIDs))
+		copy(o.SpiffeUpstreamIDs, oc.SpiffeUpstreamIDs)
+	}
 
 	if oc.FastForwardPath != nil {
 		o.FastForwardPath = oc.FastForwardPath.Clone()
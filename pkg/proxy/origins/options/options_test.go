@@ -49,6 +49,35 @@ func TestClone(t *testing.T) {
 
 }
 
+func TestAcquireDeltaFetchSlot(t *testing.T) {
+	o := NewOptions()
+	// unlimited
+	o.AcquireDeltaFetchSlot()()
+
+	o.DeltaFetchParallelism = 1
+	release := o.AcquireDeltaFetchSlot()
+
+	acquired := make(chan bool)
+	go func() {
+		o.AcquireDeltaFetchSlot()()
+		acquired <- true
+	}()
+
+	select {
+	case <-acquired:
+		t.Error("expected second fetch slot to block")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	release()
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Error("expected second fetch slot to be acquired after release")
+	}
+}
+
 func TestValidateOriginName(t *testing.T) {
 
 	err := ValidateOriginName("test")
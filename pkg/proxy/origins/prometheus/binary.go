@@ -0,0 +1,204 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/timeseries/gorilla"
+
+	"github.com/prometheus/common/model"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// ErrNotMatrix is returned when a Timeseries other than a MatrixEnvelope is provided for
+// binary marshaling
+var ErrNotMatrix = errors.New("timeseries is not a prometheus matrix")
+
+// MarshalTimeseriesBinary converts a Timeseries into Trickster's binary cache format
+func (c *Client) MarshalTimeseriesBinary(ts timeseries.Timeseries) ([]byte, error) {
+	me, ok := ts.(*MatrixEnvelope)
+	if !ok {
+		return nil, ErrNotMatrix
+	}
+	version := timeseries.BinaryFormatVersion
+	if c.config != nil {
+		if v, ok := timeseries.BinaryFormatNames[c.config.TimeseriesCacheEncoding]; ok {
+			version = v
+		}
+	}
+	return me.AppendBinary(make([]byte, 0, me.binarySize()), version), nil
+}
+
+// UnmarshalTimeseriesBinary converts Trickster's binary cache format into a Timeseries
+func (c *Client) UnmarshalTimeseriesBinary(data []byte) (timeseries.Timeseries, error) {
+	me := &MatrixEnvelope{}
+	err := me.UnmarshalBinary(data)
+	return me, err
+}
+
+// binarySize returns the approximate size of the binary encoding of the MatrixEnvelope
+func (me *MatrixEnvelope) binarySize() int {
+	n := 64 + len(me.ExtentList)*18
+	for _, s := range me.Data.Result {
+		n += 10 + len(s.Values)*18
+		for k, v := range s.Metric {
+			n += len(k) + len(v) + 10
+		}
+	}
+	return n
+}
+
+// AppendBinary appends the binary cache encoding of the MatrixEnvelope to b, in the
+// provided format version. The encoding is the format version byte, followed by
+// MessagePack values for the status, result type, extents, step and each series'
+// labels and samples. The samples are either individual timestamp and value pairs,
+// or with BinaryFormatGorilla, a single gorilla-compressed stream
+func (me *MatrixEnvelope) AppendBinary(b []byte, version byte) []byte {
+	b = append(b, version)
+	b = msgp.AppendString(b, me.Status)
+	b = msgp.AppendString(b, me.Data.ResultType)
+	b = timeseries.AppendExtentList(b, me.ExtentList)
+	b = msgp.AppendInt64(b, int64(me.StepDuration))
+	b = msgp.AppendArrayHeader(b, uint32(len(me.Data.Result)))
+	for _, s := range me.Data.Result {
+		b = msgp.AppendMapHeader(b, uint32(len(s.Metric)))
+		for k, v := range s.Metric {
+			b = msgp.AppendString(b, string(k))
+			b = msgp.AppendString(b, string(v))
+		}
+		if version == timeseries.BinaryFormatGorilla {
+			b = msgp.AppendBytes(b, encodeGorilla(s.Values))
+			continue
+		}
+		b = msgp.AppendArrayHeader(b, uint32(len(s.Values)))
+		for _, v := range s.Values {
+			b = msgp.AppendInt64(b, int64(v.Timestamp))
+			b = msgp.AppendFloat64(b, float64(v.Value))
+		}
+	}
+	return b
+}
+
+// UnmarshalBinary populates the MatrixEnvelope from its binary cache encoding
+func (me *MatrixEnvelope) UnmarshalBinary(data []byte) error {
+	version, b, err := timeseries.BinaryBody(data)
+	if err != nil {
+		return err
+	}
+	if me.Status, b, err = msgp.ReadStringBytes(b); err != nil {
+		return err
+	}
+	if me.Data.ResultType, b, err = msgp.ReadStringBytes(b); err != nil {
+		return err
+	}
+	if me.ExtentList, b, err = timeseries.ReadExtentListBytes(b); err != nil {
+		return err
+	}
+	var step int64
+	if step, b, err = msgp.ReadInt64Bytes(b); err != nil {
+		return err
+	}
+	me.StepDuration = time.Duration(step)
+
+	var sz uint32
+	if sz, b, err = msgp.ReadArrayHeaderBytes(b); err != nil {
+		return err
+	}
+	// every element occupies at least one byte, so larger headers are corrupt
+	if int(sz) > len(b) {
+		return msgp.ErrShortBytes
+	}
+	me.Data.Result = make(model.Matrix, sz)
+	for i := range me.Data.Result {
+		s := &model.SampleStream{}
+		var msz uint32
+		if msz, b, err = msgp.ReadMapHeaderBytes(b); err != nil {
+			return err
+		}
+		if int(msz) > len(b) {
+			return msgp.ErrShortBytes
+		}
+		s.Metric = make(model.Metric, msz)
+		for j := uint32(0); j < msz; j++ {
+			var k, v string
+			if k, b, err = msgp.ReadStringBytes(b); err != nil {
+				return err
+			}
+			if v, b, err = msgp.ReadStringBytes(b); err != nil {
+				return err
+			}
+			s.Metric[model.LabelName(k)] = model.LabelValue(v)
+		}
+		if version == timeseries.BinaryFormatGorilla {
+			var zc []byte
+			if zc, b, err = msgp.ReadBytesZC(b); err != nil {
+				return err
+			}
+			if s.Values, err = decodeGorilla(zc); err != nil {
+				return err
+			}
+			me.Data.Result[i] = s
+			continue
+		}
+		var vsz uint32
+		if vsz, b, err = msgp.ReadArrayHeaderBytes(b); err != nil {
+			return err
+		}
+		if int(vsz) > len(b) {
+			return msgp.ErrShortBytes
+		}
+		s.Values = make([]model.SamplePair, vsz)
+		for j := range s.Values {
+			var ts int64
+			var v float64
+			if ts, b, err = msgp.ReadInt64Bytes(b); err != nil {
+				return err
+			}
+			if v, b, err = msgp.ReadFloat64Bytes(b); err != nil {
+				return err
+			}
+			s.Values[j] = model.SamplePair{Timestamp: model.Time(ts), Value: model.SampleValue(v)}
+		}
+		me.Data.Result[i] = s
+	}
+	return nil
+}
+
+func encodeGorilla(values []model.SamplePair) []byte {
+	ts := make([]int64, len(values))
+	vs := make([]float64, len(values))
+	for i, v := range values {
+		ts[i] = int64(v.Timestamp)
+		vs[i] = float64(v.Value)
+	}
+	return gorilla.Encode(ts, vs)
+}
+
+func decodeGorilla(data []byte) ([]model.SamplePair, error) {
+	ts, vs, _, err := gorilla.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]model.SamplePair, len(ts))
+	for i := range ts {
+		values[i] = model.SamplePair{Timestamp: model.Time(ts[i]), Value: model.SampleValue(vs[i])}
+	}
+	return values, nil
+}
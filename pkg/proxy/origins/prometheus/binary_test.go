@@ -0,0 +1,206 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+
+	"github.com/prometheus/common/model"
+)
+
+// notMatrix is a Timeseries that is not a MatrixEnvelope
+type notMatrix struct {
+	timeseries.Timeseries
+}
+
+func testBinaryMatrix() *MatrixEnvelope {
+	return &MatrixEnvelope{
+		Status: "success",
+		Data: MatrixData{
+			ResultType: "matrix",
+			Result: model.Matrix{
+				&model.SampleStream{
+					Metric: model.Metric{"__name__": "a", "job": "trickster"},
+					Values: []model.SamplePair{
+						{Timestamp: 99000, Value: 1.5},
+						{Timestamp: 199000, Value: 2.25},
+						{Timestamp: 299000, Value: -3},
+					},
+				},
+				&model.SampleStream{
+					Metric: model.Metric{"__name__": "b"},
+					Values: []model.SamplePair{},
+				},
+			},
+		},
+		ExtentList:   timeseries.ExtentList{timeseries.Extent{Start: time.Unix(99, 0), End: time.Unix(299, 0)}},
+		StepDuration: 100 * time.Second,
+	}
+}
+
+func TestMarshalTimeseriesBinary(t *testing.T) {
+
+	client := &Client{}
+	me := testBinaryMatrix()
+
+	b, err := client.MarshalTimeseriesBinary(me)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !timeseries.IsBinaryFormat(b) {
+		t.Error("expected binary format version byte")
+	}
+
+	ts, err := client.UnmarshalTimeseriesBinary(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	me2 := ts.(*MatrixEnvelope)
+
+	if me2.Status != me.Status || me2.Data.ResultType != me.Data.ResultType {
+		t.Errorf("unexpected envelope %s %s", me2.Status, me2.Data.ResultType)
+	}
+	if me2.StepDuration != me.StepDuration {
+		t.Errorf("expected step %s got %s", me.StepDuration, me2.StepDuration)
+	}
+	if me2.ExtentList.String() != me.ExtentList.String() {
+		t.Errorf("expected extents %s got %s", me.ExtentList, me2.ExtentList)
+	}
+	if !reflect.DeepEqual(me.Data.Result, me2.Data.Result) {
+		t.Errorf("expected %v got %v", me.Data.Result, me2.Data.Result)
+	}
+
+	// the binary encoding must be smaller than the JSON encoding
+	j, err := client.MarshalTimeseries(me)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) >= len(j) {
+		t.Errorf("expected binary size %d to be less than json size %d", len(b), len(j))
+	}
+
+	_, err = client.MarshalTimeseriesBinary(&notMatrix{})
+	if err != ErrNotMatrix {
+		t.Errorf("expected %v got %v", ErrNotMatrix, err)
+	}
+}
+
+func TestMarshalTimeseriesBinaryGorilla(t *testing.T) {
+
+	o := oo.NewOptions()
+	o.TimeseriesCacheEncoding = "gorilla"
+	client := &Client{config: o}
+	me := testBinaryMatrix()
+
+	b, err := client.MarshalTimeseriesBinary(me)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b[0] != timeseries.BinaryFormatGorilla {
+		t.Errorf("expected format version %d got %d", timeseries.BinaryFormatGorilla, b[0])
+	}
+
+	// clients decode every format version, regardless of their configured encoding
+	ts, err := (&Client{}).UnmarshalTimeseriesBinary(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(me.Data.Result, ts.(*MatrixEnvelope).Data.Result) {
+		t.Errorf("expected %v got %v", me.Data.Result, ts.(*MatrixEnvelope).Data.Result)
+	}
+
+	bm := benchmarkMatrix()
+	b1, _ := (&Client{}).MarshalTimeseriesBinary(bm)
+	b2, _ := client.MarshalTimeseriesBinary(bm)
+	if len(b2) >= len(b1) {
+		t.Errorf("expected gorilla size %d to be less than msgpack size %d", len(b2), len(b1))
+	}
+
+	for i := 1; i < len(b); i++ {
+		if _, err = client.UnmarshalTimeseriesBinary(b[:i]); err == nil {
+			t.Errorf("expected error for data truncated to %d bytes", i)
+		}
+	}
+}
+
+func TestUnmarshalTimeseriesBinaryErrors(t *testing.T) {
+
+	client := &Client{}
+	_, err := client.UnmarshalTimeseriesBinary([]byte(`{"status":"success"}`))
+	if err != timeseries.ErrUnsupportedBinaryFormat {
+		t.Errorf("expected %v got %v", timeseries.ErrUnsupportedBinaryFormat, err)
+	}
+
+	b, err := client.MarshalTimeseriesBinary(testBinaryMatrix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every truncation of the encoding must fail to decode
+	for i := 1; i < len(b); i++ {
+		if _, err = client.UnmarshalTimeseriesBinary(b[:i]); err == nil {
+			t.Errorf("expected error for data truncated to %d bytes", i)
+		}
+	}
+}
+
+func BenchmarkUnmarshalTimeseriesJSON(b *testing.B) {
+	client := &Client{}
+	data, _ := client.MarshalTimeseries(benchmarkMatrix())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		client.UnmarshalTimeseries(data)
+	}
+}
+
+func BenchmarkUnmarshalTimeseriesBinary(b *testing.B) {
+	client := &Client{}
+	data, _ := client.MarshalTimeseriesBinary(benchmarkMatrix())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		client.UnmarshalTimeseriesBinary(data)
+	}
+}
+
+func BenchmarkUnmarshalTimeseriesGorilla(b *testing.B) {
+	o := oo.NewOptions()
+	o.TimeseriesCacheEncoding = "gorilla"
+	client := &Client{config: o}
+	data, _ := client.MarshalTimeseriesBinary(benchmarkMatrix())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		client.UnmarshalTimeseriesBinary(data)
+	}
+}
+
+func benchmarkMatrix() *MatrixEnvelope {
+	me := &MatrixEnvelope{Status: "success", Data: MatrixData{ResultType: "matrix"}}
+	for i := 0; i < 100; i++ {
+		s := &model.SampleStream{Metric: model.Metric{"__name__": "a",
+			"instance": model.LabelValue(time.Duration(i).String())}}
+		for j := 0; j < 1000; j++ {
+			s.Values = append(s.Values, model.SamplePair{Timestamp: model.Time(j * 15000),
+				Value: model.SampleValue(float64(i*j) / 7)})
+		}
+		me.Data.Result = append(me.Data.Result, s)
+	}
+	return me
+}
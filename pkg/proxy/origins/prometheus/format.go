@@ -0,0 +1,51 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+// histogramsKey is the key of the native histogram samples in a series of a range query
+// response, which Prometheus 2.40 and later may return instead of float samples. It can only
+// occur as a key, since quotes in label values are escaped
+var histogramsKey = []byte(`"histograms":`)
+
+// UnmarshalTimeseriesResponse converts an upstream range query response into a Timeseries.
+// Responses that the matrix model can't represent, like those with native histogram samples
+// or a result type other than matrix, return timeseries.ErrUnsupportedResponseFormat, so
+// that they are proxied without being cached, instead of being cached without their data
+func (c *Client) UnmarshalTimeseriesResponse(h http.Header,
+	data []byte) (timeseries.Timeseries, error) {
+	if bytes.Contains(data, histogramsKey) {
+		return nil, fmt.Errorf("%w: native histograms",
+			timeseries.ErrUnsupportedResponseFormat)
+	}
+	ts, err := c.UnmarshalTimeseries(data)
+	if err != nil {
+		return nil, err
+	}
+	if rt := ts.(*MatrixEnvelope).Data.ResultType; rt != "" && rt != "matrix" {
+		return nil, fmt.Errorf("%w: %s result type",
+			timeseries.ErrUnsupportedResponseFormat, rt)
+	}
+	return ts, nil
+}
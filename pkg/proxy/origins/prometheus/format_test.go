@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+func TestUnmarshalTimeseriesResponse(t *testing.T) {
+
+	client := &Client{}
+
+	ts, err := client.UnmarshalTimeseriesResponse(http.Header{},
+		[]byte(`{"status":"success","data":{"resultType":"matrix","result":[{"metric":`+
+			`{"__name__":"a","note":"\"histograms\":"},"values":[[1435781430,"1"]]}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := ts.ValueCount(); n != 1 {
+		t.Errorf("expected %d got %d", 1, n)
+	}
+
+	tests := []string{
+		`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"__name__":"a"},` +
+			`"histograms":[[1435781430,{"count":"1","sum":"1","buckets":[]}]]}]}}`,
+		`{"status":"success","data":{"resultType":"vector","result":[]}}`,
+	}
+	for i, test := range tests {
+		_, err := client.UnmarshalTimeseriesResponse(http.Header{}, []byte(test))
+		if !errors.Is(err, timeseries.ErrUnsupportedResponseFormat) {
+			t.Errorf("%d: expected %v got %v", i, timeseries.ErrUnsupportedResponseFormat, err)
+		}
+	}
+
+	_, err = client.UnmarshalTimeseriesResponse(http.Header{}, []byte("{"))
+	if err == nil || errors.Is(err, timeseries.ErrUnsupportedResponseFormat) {
+		t.Errorf("expected json error got %v", err)
+	}
+}
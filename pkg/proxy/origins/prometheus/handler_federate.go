@@ -0,0 +1,42 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/engines"
+	"github.com/tricksterproxy/trickster/pkg/proxy/urls"
+)
+
+// FederateHandler proxies requests for path /federate to the origin by way of the object
+// proxy cache. The match[] selectors are sorted, since their order does not affect the
+// result, so that servers federating the same selectors share a cache entry
+func (c *Client) FederateHandler(w http.ResponseWriter, r *http.Request) {
+
+	u := urls.BuildUpstreamURL(r, c.baseUpstreamURL)
+
+	params := u.Query()
+	if m, ok := params[upMatch]; ok && len(m) > 1 {
+		sort.Strings(m)
+		u.RawQuery = params.Encode()
+	}
+
+	r.URL = u
+	engines.ObjectProxyCacheRequest(w, r)
+}
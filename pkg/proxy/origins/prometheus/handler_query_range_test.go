@@ -17,10 +17,15 @@
 package prometheus
 
 import (
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	tu "github.com/tricksterproxy/trickster/pkg/util/testing"
 )
@@ -60,3 +65,77 @@ func TestQueryRangeHandler(t *testing.T) {
 		t.Errorf("expected '{}' got %s.", bodyBytes)
 	}
 }
+
+func TestQueryRangeHandlerETag(t *testing.T) {
+
+	client := &Client{name: "test"}
+	end := time.Now().Add(-time.Hour).Unix()
+	ts, _, r, hc, err := tu.NewTestInstance("",
+		client.DefaultPathConfigs, 200, "", nil, "promsim",
+		fmt.Sprintf("/prometheus/api/v1/query_range?query=up&start=%d&end=%d&step=15", end-1800, end),
+		"debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+	rsc := request.GetResources(r)
+	rsc.OriginClient = client
+	client.config = rsc.OriginConfig
+	client.config.FastForwardDisable = true
+	client.webClient = hc
+	client.config.HTTPClient = hc
+	client.baseUpstreamURL, _ = url.Parse(ts.URL)
+
+	get := func(inm string) (*http.Response, string) {
+		r := r.Clone(r.Context())
+		if inm != "" {
+			r.Header.Set(headers.NameIfNoneMatch, inm)
+		}
+		w := httptest.NewRecorder()
+		client.QueryRangeHandler(w, r)
+		resp := w.Result()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return resp, string(b)
+	}
+
+	// the streamed response that fetched the data has no etag, since it was not served
+	// from a cached version of the data
+	resp, body := get("")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, resp.StatusCode)
+	}
+	if v := resp.Header.Get(headers.NameETag); v != "" {
+		t.Errorf("expected no etag got %s", v)
+	}
+	// give time for the object to be written to cache in a separate goroutine
+	time.Sleep(50 * time.Millisecond)
+
+	// the streamed response served from the cache has an etag
+	resp, b := get("")
+	etag := resp.Header.Get(headers.NameETag)
+	if etag == "" || etag[0] != '"' {
+		t.Fatalf("expected strong etag got %s", etag)
+	}
+	if b != body {
+		t.Error("expected body to match the first response")
+	}
+	if v := resp.Header.Get("X-Trickster-Cache-Version"); v != "" {
+		t.Errorf("expected no cache version header got %s", v)
+	}
+
+	// which revalidates the unchanged response
+	resp, b = get(etag)
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("expected %d got %d", http.StatusNotModified, resp.StatusCode)
+	}
+	if b != "" {
+		t.Errorf("expected empty body got %d bytes", len(b))
+	}
+	resp, b = get(`"other"`)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, resp.StatusCode)
+	}
+	if b != body {
+		t.Error("expected body to match the first response")
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	tctx "github.com/tricksterproxy/trickster/pkg/proxy/context"
+	"github.com/tricksterproxy/trickster/pkg/proxy/engines"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/proxy/urls"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// RemoteWriteHandler passes remote write requests through to the origin. When the
+// origin has remote_write_invalidation_secs set, the time ranges of the samples in
+// each successful write are recorded, so that cached timeseries for the written
+// metrics are refetched over those ranges rather than masking the new data. When the
+// origin has injected_labels, they are set on every written series
+func (c *Client) RemoteWriteHandler(w http.ResponseWriter, r *http.Request) {
+	r.URL = urls.BuildUpstreamURL(r, c.baseUpstreamURL)
+	if e := c.config.InjectedLabelEnforcer; e != nil {
+		ms, err := e.Matchers(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if r.Body != nil {
+			b, err := ioutil.ReadAll(r.Body)
+			r.Body.Close()
+			if err == nil {
+				b, err = injectWriteLabels(b, ms)
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(b))
+			r.ContentLength = int64(len(b))
+		}
+	}
+	ww := c.config.WriteWindows()
+	// inspected writes are not sent to the origin, so their samples aren't recorded
+	if ww == nil || r.Body == nil || tctx.Inspection(r.Context()) {
+		engines.DoProxy(w, r, true)
+		return
+	}
+
+	b, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	engines.DoProxy(sw, r, true)
+	if sw.status >= http.StatusMultipleChoices {
+		return
+	}
+
+	windows, err := writeWindows(b)
+	if err != nil {
+		if rsc := request.GetResources(r); rsc != nil && rsc.Logger != nil {
+			rsc.Logger.Debug("remote write request not decoded",
+				tl.Pairs{"originName": c.name, "detail": err.Error()})
+		}
+		return
+	}
+	now := time.Now()
+	for name, e := range windows {
+		ww.Add(name, e, now)
+	}
+}
+
+// statusWriter is an http.ResponseWriter that records the response status code
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,258 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	tu "github.com/tricksterproxy/trickster/pkg/util/testing"
+
+	"github.com/golang/snappy"
+)
+
+// testWriteRequest returns a snappy-compressed remote write request with one series
+// per metric name, having samples at the provided timestamps in milliseconds
+func testWriteRequest(series map[string][]int64) []byte {
+	var wr []byte
+	for name, timestamps := range series {
+		var ts []byte
+		if name != "" {
+			ts = appendField(ts, 1, appendField(appendField(nil, 1, []byte("__name__")), 2, []byte(name)))
+		}
+		ts = appendField(ts, 1, appendField(appendField(nil, 1, []byte("job")), 2, []byte("test")))
+		for _, t := range timestamps {
+			s := appendUvarint(nil, wireFixed64|1<<3)
+			s = append(s, 0, 0, 0, 0, 0, 0, 0xf0, 0x3f) // 1.0
+			s = appendUvarint(s, wireVarint|2<<3)
+			s = appendUvarint(s, uint64(t))
+			ts = appendField(ts, 2, s)
+		}
+		wr = appendField(wr, 1, ts)
+	}
+	return snappy.Encode(nil, wr)
+}
+
+func TestWriteWindows(t *testing.T) {
+
+	b := testWriteRequest(map[string][]int64{
+		"up":         {60000, 30000, 90000},
+		"node_load1": {120000},
+		"":           {5000},
+	})
+	windows, err := writeWindows(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]timeseries.Extent{
+		"up":         {Start: time.Unix(30, 0), End: time.Unix(90, 0)},
+		"node_load1": {Start: time.Unix(120, 0), End: time.Unix(120, 0)},
+		"":           {Start: time.Unix(5, 0), End: time.Unix(5, 0)},
+	}
+	if len(windows) != len(expected) {
+		t.Errorf("expected %d windows got %d", len(expected), len(windows))
+	}
+	for k, e := range expected {
+		if w, ok := windows[k]; !ok || !w.Start.Equal(e.Start) || !w.End.Equal(e.End) {
+			t.Errorf("expected %s for %s got %s", e, k, w)
+		}
+	}
+
+	if _, err = writeWindows([]byte("trickster")); err != ErrInvalidWriteRequest {
+		t.Errorf("expected %v got %v", ErrInvalidWriteRequest, err)
+	}
+	if _, err = writeWindows(snappy.Encode(nil, []byte{0x0a, 0x05, 0x01})); err != ErrInvalidWriteRequest {
+		t.Errorf("expected %v got %v", ErrInvalidWriteRequest, err)
+	}
+}
+
+func TestRemoteWriteHandler(t *testing.T) {
+
+	client := &Client{name: "test"}
+	ts, w, r, hc, err := tu.NewTestInstance("",
+		client.DefaultPathConfigs, 204, "", nil, "prometheus", "/api/v1/write", "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+	rsc := request.GetResources(r)
+	rsc.OriginClient = client
+	client.config = rsc.OriginConfig
+	client.webClient = hc
+	client.config.HTTPClient = hc
+	client.baseUpstreamURL, _ = url.Parse(ts.URL)
+
+	body := testWriteRequest(map[string][]int64{"up": {60000}})
+	r.Method = http.MethodPost
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	// without invalidation, writes are only passed through
+	client.RemoteWriteHandler(w, r)
+	if w.Code != 204 {
+		t.Errorf("expected %d got %d", 204, w.Code)
+	}
+	if client.config.WriteWindows() != nil {
+		t.Error("expected nil write windows")
+	}
+
+	client.config.RemoteWriteInvalidation = time.Minute
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	client.RemoteWriteHandler(w, r)
+	st, ok := client.config.WriteWindows().Earliest("rate(up[5m])",
+		timeseries.Extent{End: time.Unix(120, 0)}, time.Now())
+	if !ok || !st.Equal(time.Unix(60, 0)) {
+		t.Errorf("expected write window starting at %d got %d", 60, st.Unix())
+	}
+}
+
+// writeRequestLabels returns the labels of each series in a snappy-compressed remote write
+// request, formatted as name=value
+func writeRequestLabels(t *testing.T, body []byte) [][]string {
+	b, err := snappy.Decode(nil, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out [][]string
+	err = decodeFields(b, func(field int, wire uint64, buf *fieldReader) error {
+		if field != 1 {
+			return skipField(wire, buf)
+		}
+		ts, err := buf.bytes()
+		if err != nil {
+			return err
+		}
+		var labels []string
+		err = decodeFields(ts, func(field int, wire uint64, buf *fieldReader) error {
+			if field != 1 {
+				return skipField(wire, buf)
+			}
+			m, err := buf.bytes()
+			if err != nil {
+				return err
+			}
+			var l []string
+			err = decodeFields(m, func(field int, wire uint64, buf *fieldReader) error {
+				s, err := buf.string()
+				l = append(l, s)
+				return err
+			})
+			labels = append(labels, strings.Join(l, "="))
+			return err
+		})
+		out = append(out, labels)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestRemoteWriteHandlerInjectedLabels(t *testing.T) {
+
+	var got []byte
+	var calls int
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		got, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer origin.Close()
+
+	client := newTenancyTestClient()
+	client.name = "test"
+	ts, _, r, hc, err := tu.NewTestInstance("",
+		client.DefaultPathConfigs, 204, "", nil, "prometheus", "/api/v1/write", "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+	rsc := request.GetResources(r)
+	rsc.OriginClient = client
+	rsc.OriginConfig.InjectedLabelEnforcer = client.config.InjectedLabelEnforcer
+	client.config = rsc.OriginConfig
+	client.webClient = hc
+	client.config.HTTPClient = hc
+	client.baseUpstreamURL, _ = url.Parse(origin.URL)
+
+	// a series that names another tenant is written with the request's tenant instead
+	label := func(name, value string) []byte {
+		return appendField(appendField(nil, 1, []byte(name)), 2, []byte(value))
+	}
+	var series []byte
+	series = appendField(series, 1, label("namespace", "b"))
+	series = appendField(series, 1, label("__name__", "up"))
+	series = appendField(series, 1, label("zone", "east"))
+	sample := append(appendUvarint(nil, wireFixed64|1<<3), 0, 0, 0, 0, 0, 0, 0xf0, 0x3f)
+	series = appendField(series, 2, append(appendUvarint(sample, wireVarint|2<<3), 0xe0, 0xd4, 0x03))
+
+	tests := []struct {
+		body     []byte
+		tenant   string
+		code     int
+		expected [][]string
+	}{
+		{testWriteRequest(map[string][]int64{"up": {60000}}), "a", http.StatusNoContent,
+			[][]string{{"__name__=up", "job=test", "namespace=a"}}},
+		{snappy.Encode(nil, appendField(appendField(nil, 1, series), 3, []byte("metadata"))), "a",
+			http.StatusNoContent, [][]string{{"__name__=up", "namespace=a", "zone=east"}}},
+		{testWriteRequest(map[string][]int64{"up": {60000}}), "", http.StatusForbidden, nil},
+		{[]byte("trickster"), "a", http.StatusBadRequest, nil},
+	}
+
+	for i, test := range tests {
+		got, calls = nil, 0
+		rr := r.Clone(r.Context())
+		rr.Method = http.MethodPost
+		rr.Body = ioutil.NopCloser(bytes.NewReader(test.body))
+		if test.tenant != "" {
+			rr.Header.Set("X-Tenant", test.tenant)
+		}
+		w := httptest.NewRecorder()
+		client.RemoteWriteHandler(w, rr)
+		if w.Code != test.code {
+			t.Errorf("%d: expected %d got %d", i, test.code, w.Code)
+		}
+		if test.expected == nil {
+			if calls != 0 {
+				t.Errorf("%d: expected the write not to be sent to the origin", i)
+			}
+			continue
+		}
+		if labels := writeRequestLabels(t, got); !reflect.DeepEqual(labels, test.expected) {
+			t.Errorf("%d: expected %v got %v", i, test.expected, labels)
+		}
+		// the samples and other fields are passed through as they are
+		if windows, err := writeWindows(got); err != nil || len(windows) != 1 ||
+			!windows["up"].Start.Equal(time.Unix(60, 0)) {
+			t.Errorf("%d: expected samples to be passed through got %v %v", i, windows, err)
+		}
+		if b, _ := snappy.Decode(nil, got); bytes.Contains(test.body, []byte("metadata")) !=
+			bytes.Contains(b, []byte("metadata")) {
+			t.Errorf("%d: expected other fields to be passed through", i)
+		}
+	}
+}
@@ -17,6 +17,7 @@
 package prometheus
 
 import (
+	"math"
 	"sort"
 	"sync"
 	"sync/atomic"
@@ -105,6 +106,10 @@ func (me *MatrixEnvelope) Clone() timeseries.Timeseries {
 	}
 	copy(resMe.ExtentList, me.ExtentList)
 	copy(resMe.tslist, me.tslist)
+	if me.Warnings != nil {
+		resMe.Warnings = make([]string, len(me.Warnings))
+		copy(resMe.Warnings, me.Warnings)
+	}
 
 	wg := sync.WaitGroup{}
 	mtx := sync.Mutex{}
@@ -127,6 +132,225 @@ func (me *MatrixEnvelope) Clone() timeseries.Timeseries {
 	return resMe
 }
 
+// Downsample returns a copy of the Timeseries that only includes the values, and the
+// portions of its ExtentList, whose timestamps fall on the provided step
+func (me *MatrixEnvelope) Downsample(step time.Duration) timeseries.Timeseries {
+	resMe := &MatrixEnvelope{
+		Status: me.Status,
+		Data: MatrixData{
+			ResultType: me.Data.ResultType,
+			Result:     make(model.Matrix, 0, len(me.Data.Result)),
+		},
+		StepDuration: step,
+		ExtentList:   me.ExtentList.Coarsen(step),
+	}
+	for _, ss := range me.Data.Result {
+		var values []model.SamplePair
+		for _, v := range ss.Values {
+			if t := v.Timestamp.Time(); t.Truncate(step).Equal(t) {
+				values = append(values, v)
+			}
+		}
+		if len(values) > 0 {
+			resMe.Data.Result = append(resMe.Data.Result,
+				&model.SampleStream{Metric: ss.Metric, Values: values})
+		}
+	}
+	return resMe
+}
+
+// Aggregate replaces the values of each series with one value for each bucket of the provided
+// step, aligned to the epoch and timestamped at the start of the bucket, which is computed from
+// the values in the bucket by the provided Aggregation. The ExtentList is not adjusted, so an
+// aggregated Timeseries must not be cached. The Timeseries must be sorted
+func (me *MatrixEnvelope) Aggregate(step time.Duration, a timeseries.Aggregation) {
+	if step <= 0 {
+		return
+	}
+	for i, ss := range me.Data.Result {
+		values := make([]model.SamplePair, 0, len(ss.Values))
+		var bucket time.Time
+		var v float64
+		var n int
+		flush := func() {
+			if n == 0 {
+				return
+			}
+			if a == timeseries.AggregationAvg {
+				v /= float64(n)
+			}
+			values = append(values, model.SamplePair{Timestamp: model.TimeFromUnixNano(bucket.UnixNano()),
+				Value: model.SampleValue(v)})
+		}
+		for _, sp := range ss.Values {
+			b := sp.Timestamp.Time().Truncate(step)
+			if n == 0 || !b.Equal(bucket) {
+				flush()
+				bucket, v, n = b, float64(sp.Value), 1
+				continue
+			}
+			switch a {
+			case timeseries.AggregationMin:
+				v = math.Min(v, float64(sp.Value))
+			case timeseries.AggregationMax:
+				v = math.Max(v, float64(sp.Value))
+			default:
+				v += float64(sp.Value)
+			}
+			n++
+		}
+		flush()
+		me.Data.Result[i] = &model.SampleStream{Metric: ss.Metric, Values: values}
+	}
+	me.StepDuration = step
+	me.isCounted = false
+}
+
+// ReducePrecision rounds each value to the provided number of significant digits. Each
+// series' values are replaced with a new array, since clones of the Timeseries share them
+func (me *MatrixEnvelope) ReducePrecision(digits int) {
+	if digits < 1 || digits >= timeseries.MaxSignificantDigits {
+		return
+	}
+	for i, ss := range me.Data.Result {
+		values := make([]model.SamplePair, len(ss.Values))
+		for j, sp := range ss.Values {
+			values[j] = model.SamplePair{Timestamp: sp.Timestamp,
+				Value: model.SampleValue(timeseries.RoundSignificant(float64(sp.Value), digits))}
+		}
+		me.Data.Result[i] = &model.SampleStream{Metric: ss.Metric, Values: values}
+	}
+}
+
+// Diff returns the number of samples within the extent that differ between the
+// MatrixEnvelope and the provided one, because their values differ or because only one of
+// them has the sample. Series are matched by their labels
+func (me *MatrixEnvelope) Diff(ts timeseries.Timeseries, e timeseries.Extent) int {
+	me2, ok := ts.(*MatrixEnvelope)
+	if !ok {
+		return 0
+	}
+	a := samplesWithin(me.Data.Result, e)
+	b := samplesWithin(me2.Data.Result, e)
+	var n int
+	for name, sa := range a {
+		sb := b[name]
+		for t, v := range sa {
+			if w, ok := sb[t]; !ok || !v.Equal(w) {
+				n++
+			}
+		}
+		for t := range sb {
+			if _, ok := sa[t]; !ok {
+				n++
+			}
+		}
+	}
+	for name, sb := range b {
+		if _, ok := a[name]; !ok {
+			n += len(sb)
+		}
+	}
+	return n
+}
+
+// samplesWithin maps the labels of each series to its values within the extent
+func samplesWithin(m model.Matrix, e timeseries.Extent) map[string]map[model.Time]model.SampleValue {
+	out := make(map[string]map[model.Time]model.SampleValue, len(m))
+	for _, ss := range m {
+		var values map[model.Time]model.SampleValue
+		for _, sp := range ss.Values {
+			if t := sp.Timestamp.Time(); t.Before(e.Start) || t.After(e.End) {
+				continue
+			}
+			if values == nil {
+				values = make(map[model.Time]model.SampleValue)
+			}
+			values[sp.Timestamp] = sp.Value
+		}
+		if values != nil {
+			out[ss.Metric.String()] = values
+		}
+	}
+	return out
+}
+
+// Deduplicate removes the provided labels from each series, and merges any series whose
+// remaining labels are identical, so that the series returned by different replicas of a
+// highly-available Prometheus are merged into one
+func (me *MatrixEnvelope) Deduplicate(labels []string) {
+	if len(labels) == 0 || len(me.Data.Result) == 0 {
+		return
+	}
+	series := make(map[string]*model.SampleStream, len(me.Data.Result))
+	result := make(model.Matrix, 0, len(me.Data.Result))
+	var merged bool
+	for _, ss := range me.Data.Result {
+		m := ss.Metric
+		for _, l := range labels {
+			if _, ok := ss.Metric[model.LabelName(l)]; ok {
+				m = ss.Metric.Clone()
+				for _, l := range labels {
+					delete(m, model.LabelName(l))
+				}
+				break
+			}
+		}
+		name := m.String()
+		if s, ok := series[name]; ok {
+			s.Values = append(s.Values, ss.Values...)
+			merged = true
+			continue
+		}
+		// the capacity is limited so that merging values never writes to a shared array
+		s := &model.SampleStream{Metric: m, Values: ss.Values[:len(ss.Values):len(ss.Values)]}
+		series[name] = s
+		result = append(result, s)
+	}
+	me.Data.Result = result
+	if merged {
+		me.isSorted = false
+		me.isCounted = false
+		me.Sort()
+	}
+}
+
+// Truncate removes the series beyond maxSeries, and the values beyond maxValues across all
+// series, where 0 is unlimited. It returns true if anything was removed. The ExtentList is not
+// adjusted, so a truncated Timeseries must not be cached
+func (me *MatrixEnvelope) Truncate(maxSeries, maxValues int) bool {
+	var truncated bool
+	if maxSeries > 0 && len(me.Data.Result) > maxSeries {
+		me.Data.Result = me.Data.Result[:maxSeries]
+		truncated = true
+	}
+	if maxValues > 0 {
+		remaining := maxValues
+		for i, ss := range me.Data.Result {
+			if len(ss.Values) <= remaining {
+				remaining -= len(ss.Values)
+				continue
+			}
+			if remaining > 0 {
+				me.Data.Result[i] = &model.SampleStream{Metric: ss.Metric, Values: ss.Values[:remaining]}
+				i++
+			}
+			me.Data.Result = me.Data.Result[:i]
+			truncated = true
+			break
+		}
+	}
+	if truncated {
+		me.isCounted = false
+	}
+	return truncated
+}
+
+// AddWarning adds a warning to the Timeseries, which is included in the client response
+func (me *MatrixEnvelope) AddWarning(warning string) {
+	me.Warnings = append(me.Warnings, warning)
+}
+
 // CropToSize reduces the number of elements in the Timeseries to the provided count, by evicting elements
 // using a least-recently-used methodology. Any timestamps newer than the provided time are removed before
 // sizing, in order to support backfill tolerance. The provided extent will be marked as used during crop.
@@ -1440,6 +1440,66 @@ func TestClone(t *testing.T) {
 
 }
 
+func TestDownsample(t *testing.T) {
+
+	me := &MatrixEnvelope{
+		Status: "success",
+		Data: MatrixData{
+			ResultType: "matrix",
+			Result: model.Matrix{
+				&model.SampleStream{
+					Metric: model.Metric{"__name__": "a"},
+					Values: []model.SamplePair{
+						{Timestamp: 1644001200000, Value: 1},
+						{Timestamp: 1644003000000, Value: 2},
+						{Timestamp: 1644004800000, Value: 3},
+					},
+				},
+				&model.SampleStream{
+					Metric: model.Metric{"__name__": "b"},
+					Values: []model.SamplePair{
+						{Timestamp: 1644003000000, Value: 2},
+					},
+				},
+			},
+		},
+		ExtentList: timeseries.ExtentList{
+			timeseries.Extent{Start: time.Unix(1644001200, 0), End: time.Unix(1644004800, 0)},
+		},
+		StepDuration: time.Duration(1800) * time.Second,
+	}
+
+	expected := &MatrixEnvelope{
+		Status: "success",
+		Data: MatrixData{
+			ResultType: "matrix",
+			Result: model.Matrix{
+				&model.SampleStream{
+					Metric: model.Metric{"__name__": "a"},
+					Values: []model.SamplePair{
+						{Timestamp: 1644001200000, Value: 1},
+						{Timestamp: 1644004800000, Value: 3},
+					},
+				},
+			},
+		},
+		ExtentList: timeseries.ExtentList{
+			timeseries.Extent{Start: time.Unix(1644001200, 0), End: time.Unix(1644004800, 0)},
+		},
+		StepDuration: time.Duration(3600) * time.Second,
+	}
+
+	result := me.Downsample(time.Duration(3600) * time.Second)
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("mismatch\nexpected %v\ngot      %v", expected, result)
+	}
+
+	if me.ValueCount() != 4 {
+		t.Errorf("expected source to be unmodified, got %d values", me.ValueCount())
+	}
+
+}
+
 func TestSort(t *testing.T) {
 	tests := []struct {
 		before, after *MatrixEnvelope
@@ -1602,6 +1662,211 @@ func TestValueCount(t *testing.T) {
 	}
 }
 
+func TestAggregate(t *testing.T) {
+
+	newMe := func() *MatrixEnvelope {
+		return &MatrixEnvelope{
+			Data: MatrixData{
+				ResultType: "matrix",
+				Result: model.Matrix{
+					&model.SampleStream{
+						Metric: model.Metric{"__name__": "a"},
+						Values: []model.SamplePair{
+							{Timestamp: 60000, Value: 1},
+							{Timestamp: 120000, Value: 5},
+							{Timestamp: 180000, Value: 3},
+							{Timestamp: 240000, Value: 2},
+							{Timestamp: 300000, Value: 4},
+						},
+					},
+				},
+			},
+			StepDuration: time.Minute,
+		}
+	}
+
+	tests := []struct {
+		a        timeseries.Aggregation
+		expected []model.SamplePair
+	}{
+		{timeseries.AggregationAvg, []model.SamplePair{{Timestamp: 0, Value: 1},
+			{Timestamp: 120000, Value: 4}, {Timestamp: 240000, Value: 3}}},
+		{timeseries.AggregationMin, []model.SamplePair{{Timestamp: 0, Value: 1},
+			{Timestamp: 120000, Value: 3}, {Timestamp: 240000, Value: 2}}},
+		{timeseries.AggregationMax, []model.SamplePair{{Timestamp: 0, Value: 1},
+			{Timestamp: 120000, Value: 5}, {Timestamp: 240000, Value: 4}}},
+	}
+
+	for _, test := range tests {
+		me := newMe()
+		source := me.Clone().(*MatrixEnvelope)
+		me.Aggregate(2*time.Minute, test.a)
+		if !reflect.DeepEqual(me.Data.Result[0].Values, test.expected) {
+			t.Errorf("%s: expected %v got %v", test.a, test.expected, me.Data.Result[0].Values)
+		}
+		if me.StepDuration != 2*time.Minute {
+			t.Errorf("expected %s got %s", 2*time.Minute, me.StepDuration)
+		}
+		if source.ValueCount() != 5 {
+			t.Errorf("expected %d got %d", 5, source.ValueCount())
+		}
+	}
+
+	me := newMe()
+	me.Aggregate(0, timeseries.AggregationAvg)
+	if me.ValueCount() != 5 {
+		t.Errorf("expected %d got %d", 5, me.ValueCount())
+	}
+}
+
+func TestDeduplicate(t *testing.T) {
+
+	me := &MatrixEnvelope{
+		Data: MatrixData{
+			ResultType: "matrix",
+			Result: model.Matrix{
+				&model.SampleStream{
+					Metric: model.Metric{"__name__": "a", "replica": "0"},
+					Values: []model.SamplePair{
+						{Timestamp: 99000, Value: 1.5},
+						{Timestamp: 199000, Value: 1.5},
+					},
+				},
+				&model.SampleStream{
+					Metric: model.Metric{"__name__": "b", "replica": "0"},
+					Values: []model.SamplePair{
+						{Timestamp: 99000, Value: 1.5},
+					},
+				},
+				&model.SampleStream{
+					Metric: model.Metric{"__name__": "a", "replica": "1"},
+					Values: []model.SamplePair{
+						{Timestamp: 199000, Value: 1.5},
+						{Timestamp: 299000, Value: 1.5},
+					},
+				},
+				&model.SampleStream{
+					Metric: model.Metric{"__name__": "c"},
+					Values: []model.SamplePair{
+						{Timestamp: 99000, Value: 1.5},
+					},
+				},
+			},
+		},
+	}
+
+	source := me.Clone().(*MatrixEnvelope)
+
+	me.Deduplicate(nil)
+	if me.SeriesCount() != 4 {
+		t.Errorf("expected %d got %d", 4, me.SeriesCount())
+	}
+
+	me.Deduplicate([]string{"replica", "prometheus_replica"})
+
+	expected := []string{"a", "b", "c"}
+	if me.SeriesCount() != len(expected) {
+		t.Fatalf("expected %d got %d", len(expected), me.SeriesCount())
+	}
+	for i, s := range me.Data.Result {
+		if s.Metric.String() != expected[i] {
+			t.Errorf("expected %s got %s", expected[i], s.Metric.String())
+		}
+	}
+	if len(me.Data.Result[0].Values) != 3 {
+		t.Errorf("expected %d got %d", 3, len(me.Data.Result[0].Values))
+	}
+	if me.TimestampCount() != 3 {
+		t.Errorf("expected %d got %d", 3, me.TimestampCount())
+	}
+
+	// the series and labels of the cloned source are unchanged
+	if source.SeriesCount() != 4 || source.ValueCount() != 6 ||
+		source.Data.Result[0].Metric["replica"] != "0" {
+		t.Errorf("unexpected change to source %v", source.Data.Result)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+
+	newMe := func() *MatrixEnvelope {
+		return &MatrixEnvelope{
+			Data: MatrixData{
+				ResultType: "matrix",
+				Result: model.Matrix{
+					&model.SampleStream{
+						Metric: model.Metric{"__name__": "a"},
+						Values: []model.SamplePair{
+							{Timestamp: 99000, Value: 1.5},
+							{Timestamp: 199000, Value: 1.5},
+							{Timestamp: 299000, Value: 1.5},
+						},
+					},
+					&model.SampleStream{
+						Metric: model.Metric{"__name__": "b"},
+						Values: []model.SamplePair{
+							{Timestamp: 99000, Value: 1.5},
+							{Timestamp: 199000, Value: 1.5},
+						},
+					},
+					&model.SampleStream{
+						Metric: model.Metric{"__name__": "c"},
+						Values: []model.SamplePair{
+							{Timestamp: 99000, Value: 1.5},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		maxSeries, maxValues         int
+		expected                     bool
+		expectedSeries, expectedVals int
+	}{
+		{0, 0, false, 3, 6},
+		{3, 6, false, 3, 6},
+		{2, 0, true, 2, 5},
+		{0, 4, true, 2, 4},
+		{0, 3, true, 1, 3},
+		{1, 2, true, 1, 2},
+	}
+
+	for i, test := range tests {
+		me := newMe()
+		if me.Truncate(test.maxSeries, test.maxValues) != test.expected {
+			t.Errorf("test %d: expected %t", i, test.expected)
+		}
+		if me.SeriesCount() != test.expectedSeries {
+			t.Errorf("test %d: expected %d series got %d", i, test.expectedSeries, me.SeriesCount())
+		}
+		if me.ValueCount() != test.expectedVals {
+			t.Errorf("test %d: expected %d values got %d", i, test.expectedVals, me.ValueCount())
+		}
+	}
+
+	// the source of a truncated clone is unchanged
+	me := newMe()
+	me2 := me.Clone().(*MatrixEnvelope)
+	me2.Truncate(0, 2)
+	if me.ValueCount() != 6 {
+		t.Errorf("expected %d got %d", 6, me.ValueCount())
+	}
+}
+
+func TestAddWarning(t *testing.T) {
+	me := &MatrixEnvelope{}
+	me.AddWarning("test warning")
+	if len(me.Warnings) != 1 || me.Warnings[0] != "test warning" {
+		t.Errorf("unexpected warnings %v", me.Warnings)
+	}
+	me2 := me.Clone().(*MatrixEnvelope)
+	if len(me2.Warnings) != 1 || me2.Warnings[0] != "test warning" {
+		t.Errorf("unexpected cloned warnings %v", me2.Warnings)
+	}
+}
+
 func TestTimestampCount(t *testing.T) {
 
 	tests := []struct {
@@ -1707,3 +1972,74 @@ func TestSize(t *testing.T) {
 		t.Errorf("expected %d got %d", expected, i)
 	}
 }
+
+func TestReducePrecision(t *testing.T) {
+
+	me := &MatrixEnvelope{
+		Data: MatrixData{
+			ResultType: "matrix",
+			Result: model.Matrix{
+				&model.SampleStream{
+					Metric: model.Metric{"__name__": "a"},
+					Values: []model.SamplePair{
+						{Timestamp: 99000, Value: 0.123456789},
+						{Timestamp: 199000, Value: 98765.4321},
+					},
+				},
+			},
+		},
+	}
+
+	source := me.Clone().(*MatrixEnvelope)
+
+	me.ReducePrecision(0)
+	if v := me.Data.Result[0].Values[0].Value; v != 0.123456789 {
+		t.Errorf("expected %v got %v", 0.123456789, v)
+	}
+
+	me.ReducePrecision(3)
+	if v := me.Data.Result[0].Values[0].Value; v != 0.123 {
+		t.Errorf("expected %v got %v", 0.123, v)
+	}
+	if v := me.Data.Result[0].Values[1].Value; v != 98800 {
+		t.Errorf("expected %v got %v", 98800, v)
+	}
+
+	// the clone's values are not modified
+	if v := source.Data.Result[0].Values[0].Value; v != 0.123456789 {
+		t.Errorf("expected %v got %v", 0.123456789, v)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	newMatrix := func(a, b []model.SamplePair) *MatrixEnvelope {
+		return &MatrixEnvelope{Data: MatrixData{ResultType: "matrix", Result: model.Matrix{
+			&model.SampleStream{Metric: model.Metric{"__name__": "a"}, Values: a},
+			&model.SampleStream{Metric: model.Metric{"__name__": "b"}, Values: b},
+		}}}
+	}
+	me := newMatrix(
+		[]model.SamplePair{{Timestamp: 1000, Value: 1}, {Timestamp: 2000, Value: 2},
+			{Timestamp: 3000, Value: 3}},
+		[]model.SamplePair{{Timestamp: 2000, Value: 1}},
+	)
+	me2 := newMatrix(
+		[]model.SamplePair{{Timestamp: 1000, Value: 5}, {Timestamp: 2000, Value: 2},
+			{Timestamp: 3000, Value: 4}},
+		[]model.SamplePair{{Timestamp: 2000, Value: 1}, {Timestamp: 3000, Value: 1}},
+	)
+	e := timeseries.Extent{Start: time.Unix(2, 0), End: time.Unix(3, 0)}
+
+	// a changed value and a backfilled value within the extent
+	if n := me.Diff(me2, e); n != 2 {
+		t.Errorf("expected %d got %d", 2, n)
+	}
+	if n := me.Diff(me, e); n != 0 {
+		t.Errorf("expected %d got %d", 0, n)
+	}
+	// a series missing from one of the timeseries
+	me2.Data.Result = me2.Data.Result[:1]
+	if n := me.Diff(me2, e); n != 2 {
+		t.Errorf("expected %d got %d", 2, n)
+	}
+}
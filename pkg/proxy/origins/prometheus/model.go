@@ -42,6 +42,7 @@ type VectorData struct {
 type MatrixEnvelope struct {
 	Status       string                `json:"status"`
 	Data         MatrixData            `json:"data"`
+	Warnings     []string              `json:"warnings,omitempty"`
 	ExtentList   timeseries.ExtentList `json:"extents,omitempty"`
 	StepDuration time.Duration         `json:"step,omitempty"`
 
@@ -31,6 +31,7 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
 	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
 	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/params"
 	tt "github.com/tricksterproxy/trickster/pkg/proxy/timeconv"
 	"github.com/tricksterproxy/trickster/pkg/proxy/urls"
 	"github.com/tricksterproxy/trickster/pkg/timeseries"
@@ -52,6 +53,12 @@ const (
 	mnAlerts        = "alerts"
 	mnAlertManagers = "alertmanagers"
 	mnStatus        = "status"
+	mnWrite         = "write"
+	mnFederate      = "federate"
+
+	mnStatusTSDB        = "status/tsdb"
+	mnStatusBuildInfo   = "status/buildinfo"
+	mnStatusRuntimeInfo = "status/runtimeinfo"
 )
 
 // Common URL Parameter Names
@@ -62,6 +69,12 @@ const (
 	upStep  = "step"
 	upTime  = "time"
 	upMatch = "match[]"
+
+	upState      = "state"
+	upScrapePool = "scrapePool"
+	// upMaxDataPoints is not a Prometheus parameter, but is read by Trickster to thin
+	// the response to the requested number of timestamps
+	upMaxDataPoints = "max_data_points"
 )
 
 // Client Implements Proxy Client Interface
@@ -148,13 +161,7 @@ func (c *Client) ParseTimeRangeQuery(r *http.Request) (*timeseries.TimeRangeQuer
 
 	trq := &timeseries.TimeRangeQuery{Extent: timeseries.Extent{}}
 
-	var qp url.Values
-	if r.Method == http.MethodPost {
-		r.ParseForm()
-		qp = r.PostForm
-	} else {
-		qp = r.URL.Query()
-	}
+	qp := params.GetRequestValues(r)
 
 	trq.Statement = qp.Get(upQuery)
 	if trq.Statement == "" {
@@ -191,6 +198,14 @@ func (c *Client) ParseTimeRangeQuery(r *http.Request) (*timeseries.TimeRangeQuer
 		return nil, errors.MissingURLParam(upStep)
 	}
 
+	if p := qp.Get(upMaxDataPoints); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, err
+		}
+		trq.MaxDataPoints = n
+	}
+
 	if strings.Contains(trq.Statement, " offset ") {
 		trq.IsOffset = true
 		trq.FastForwardDisable = true
@@ -172,10 +172,11 @@ func TestName(t *testing.T) {
 func TestParseTimeRangeQuery(t *testing.T) {
 
 	qp := url.Values(map[string][]string{
-		"query": {`up-` + timeseries.FastForwardUserDisableFlag},
-		"start": {strconv.Itoa(int(time.Now().Add(time.Duration(-6) * time.Hour).Unix()))},
-		"end":   {strconv.Itoa(int(time.Now().Unix()))},
-		"step":  {"15"},
+		"query":           {`up-` + timeseries.FastForwardUserDisableFlag},
+		"start":           {strconv.Itoa(int(time.Now().Add(time.Duration(-6) * time.Hour).Unix()))},
+		"end":             {strconv.Itoa(int(time.Now().Unix()))},
+		"step":            {"15"},
+		"max_data_points": {"500"},
 	})
 
 	u := &url.URL{
@@ -198,6 +199,10 @@ func TestParseTimeRangeQuery(t *testing.T) {
 		if int(res.Extent.End.Sub(res.Extent.Start).Hours()) != 6 {
 			t.Errorf("expected 6 got %d", int(res.Extent.End.Sub(res.Extent.Start).Hours()))
 		}
+
+		if res.MaxDataPoints != 500 {
+			t.Errorf("expected 500 got %d", res.MaxDataPoints)
+		}
 	}
 
 	b := bytes.NewBufferString(qp.Encode())
@@ -210,6 +215,25 @@ func TestParseTimeRangeQuery(t *testing.T) {
 	}
 }
 
+func TestParseTimeRangeQueryBadMaxDataPoints(t *testing.T) {
+	req := &http.Request{URL: &url.URL{
+		Scheme: "https",
+		Host:   "blah.com",
+		Path:   "/",
+		RawQuery: url.Values(map[string][]string{
+			"query":           {`up`},
+			"start":           {strconv.Itoa(int(time.Now().Add(time.Duration(-6) * time.Hour).Unix()))},
+			"end":             {strconv.Itoa(int(time.Now().Unix()))},
+			"step":            {"15"},
+			"max_data_points": {"many"}}).Encode(),
+	}}
+	client := &Client{}
+	_, err := client.ParseTimeRangeQuery(req)
+	if err == nil {
+		t.Error("expected error for invalid max_data_points")
+	}
+}
+
 func TestParseTimeRangeQueryMissingQuery(t *testing.T) {
 	expected := pe.MissingURLParam(upQuery).Error()
 	req := &http.Request{URL: &url.URL{
@@ -0,0 +1,314 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/util/promql"
+
+	"github.com/golang/snappy"
+)
+
+// field numbers and wire types of the remote write protobuf messages:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label { string name = 1; string value = 2; }
+//	Sample { double value = 1; int64 timestamp = 2; }
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// ErrInvalidWriteRequest indicates a remote write body that could not be decoded
+var ErrInvalidWriteRequest = errors.New("invalid remote write request")
+
+// writeWindows returns the time range of the samples in a snappy-compressed remote
+// write request, by metric name. Only the labels and sample timestamps are decoded
+func writeWindows(body []byte) (map[string]timeseries.Extent, error) {
+	b, err := snappy.Decode(nil, body)
+	if err != nil {
+		return nil, ErrInvalidWriteRequest
+	}
+	windows := make(map[string]timeseries.Extent)
+	err = decodeFields(b, func(field int, wire uint64, buf *fieldReader) error {
+		if field != 1 || wire != wireBytes {
+			return skipField(wire, buf)
+		}
+		ts, err := buf.bytes()
+		if err != nil {
+			return err
+		}
+		name, e, ok, err := decodeTimeSeries(ts)
+		if err != nil || !ok {
+			return err
+		}
+		if w, ok := windows[name]; ok {
+			if w.Start.Before(e.Start) {
+				e.Start = w.Start
+			}
+			if w.End.After(e.End) {
+				e.End = w.End
+			}
+		}
+		windows[name] = e
+		return nil
+	})
+	if err != nil {
+		return nil, ErrInvalidWriteRequest
+	}
+	return windows, nil
+}
+
+// injectWriteLabels returns the snappy-compressed remote write request with the labels of
+// the matchers set on each of its series, replacing any values the series had for them, so
+// that a tenant can only write its own series. Other fields are passed through as they are
+func injectWriteLabels(body []byte, ms []promql.Matcher) ([]byte, error) {
+	b, err := snappy.Decode(nil, body)
+	if err != nil {
+		return nil, ErrInvalidWriteRequest
+	}
+	out := make([]byte, 0, len(b)+len(b)/4)
+	err = decodeFields(b, func(field int, wire uint64, buf *fieldReader) error {
+		if field != 1 || wire != wireBytes {
+			return copyField(&out, field, wire, buf)
+		}
+		ts, err := buf.bytes()
+		if err != nil {
+			return err
+		}
+		ts, err = injectSeriesLabels(ts, ms)
+		if err != nil {
+			return err
+		}
+		out = appendField(out, 1, ts)
+		return nil
+	})
+	if err != nil {
+		return nil, ErrInvalidWriteRequest
+	}
+	return snappy.Encode(nil, out), nil
+}
+
+// injectSeriesLabels returns the TimeSeries message with the labels of the matchers, and
+// with its labels sorted by name, as remote write receivers expect
+func injectSeriesLabels(b []byte, ms []promql.Matcher) ([]byte, error) {
+	labels := make([]promql.Matcher, 0, len(ms)+8)
+	var rest []byte
+	err := decodeFields(b, func(field int, wire uint64, buf *fieldReader) error {
+		if field != 1 || wire != wireBytes {
+			return copyField(&rest, field, wire, buf)
+		}
+		m, err := buf.bytes()
+		if err != nil {
+			return err
+		}
+		var l promql.Matcher
+		err = decodeFields(m, func(field int, wire uint64, buf *fieldReader) error {
+			if wire != wireBytes || (field != 1 && field != 2) {
+				return skipField(wire, buf)
+			}
+			s, err := buf.string()
+			if field == 1 {
+				l.Name = s
+			} else {
+				l.Value = s
+			}
+			return err
+		})
+		labels = append(labels, l)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	injected := make(map[string]bool, len(ms))
+	for _, m := range ms {
+		injected[m.Name] = true
+	}
+	out := labels[:0]
+	for _, l := range labels {
+		if !injected[l.Name] {
+			out = append(out, l)
+		}
+	}
+	out = append(out, ms...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	ts := make([]byte, 0, len(b)+len(ms)*32)
+	for _, l := range out {
+		ts = appendField(ts, 1, appendField(appendField(nil, 1, []byte(l.Name)), 2, []byte(l.Value)))
+	}
+	return append(ts, rest...), nil
+}
+
+// decodeTimeSeries returns the metric name of a TimeSeries message and the time range of
+// its samples. ok is false if the series has no samples
+func decodeTimeSeries(b []byte) (name string, e timeseries.Extent, ok bool, err error) {
+	var min, max int64 = math.MaxInt64, math.MinInt64
+	err = decodeFields(b, func(field int, wire uint64, buf *fieldReader) error {
+		if wire != wireBytes || (field != 1 && field != 2) {
+			return skipField(wire, buf)
+		}
+		m, err := buf.bytes()
+		if err != nil {
+			return err
+		}
+		if field == 1 {
+			var k, v string
+			err = decodeFields(m, func(field int, wire uint64, buf *fieldReader) error {
+				if wire != wireBytes || (field != 1 && field != 2) {
+					return skipField(wire, buf)
+				}
+				s, err := buf.string()
+				if field == 1 {
+					k = s
+				} else {
+					v = s
+				}
+				return err
+			})
+			if k == "__name__" {
+				name = v
+			}
+			return err
+		}
+		return decodeFields(m, func(field int, wire uint64, buf *fieldReader) error {
+			if field != 2 || wire != wireVarint {
+				return skipField(wire, buf)
+			}
+			x, err := buf.varint()
+			if t := int64(x); t < min {
+				min = t
+			}
+			if t := int64(x); t > max {
+				max = t
+			}
+			ok = true
+			return err
+		})
+	})
+	if ok {
+		e = timeseries.Extent{Start: time.Unix(0, min*int64(time.Millisecond)),
+			End: time.Unix(0, max*int64(time.Millisecond))}
+	}
+	return
+}
+
+// decodeFields calls f with the field number and wire type of each field in the
+// protobuf message, which must consume the field's value from the reader
+func decodeFields(b []byte, f func(int, uint64, *fieldReader) error) error {
+	buf := &fieldReader{b: b}
+	for len(buf.b) > 0 {
+		key, err := buf.varint()
+		if err != nil {
+			return err
+		}
+		if err = f(int(key>>3), key&7, buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipField consumes the value of a field that is not needed
+func skipField(wire uint64, buf *fieldReader) error {
+	var err error
+	switch wire {
+	case wireVarint:
+		_, err = buf.varint()
+	case wireFixed64:
+		_, err = buf.fixed(8)
+	case wireBytes:
+		_, err = buf.bytes()
+	case wireFixed32:
+		_, err = buf.fixed(4)
+	default:
+		err = ErrInvalidWriteRequest
+	}
+	return err
+}
+
+// copyField appends the field, whose key has been consumed from the reader, to b as it is
+func copyField(b *[]byte, field int, wire uint64, buf *fieldReader) error {
+	v := buf.b
+	if err := skipField(wire, buf); err != nil {
+		return err
+	}
+	*b = appendUvarint(*b, uint64(field)<<3|wire)
+	*b = append(*b, v[:len(v)-len(buf.b)]...)
+	return nil
+}
+
+// appendUvarint appends the varint encoding of x to b
+func appendUvarint(b []byte, x uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], x)]...)
+}
+
+// appendField appends a length-delimited protobuf field to b
+func appendField(b []byte, field int, v []byte) []byte {
+	b = appendUvarint(b, uint64(field<<3|wireBytes))
+	b = appendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// fieldReader consumes protobuf field values from the front of a byte slice
+type fieldReader struct {
+	b []byte
+}
+
+func (r *fieldReader) varint() (uint64, error) {
+	x, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		return 0, ErrInvalidWriteRequest
+	}
+	r.b = r.b[n:]
+	return x, nil
+}
+
+func (r *fieldReader) fixed(n int) ([]byte, error) {
+	if len(r.b) < n {
+		return nil, ErrInvalidWriteRequest
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b, nil
+}
+
+func (r *fieldReader) bytes() ([]byte, error) {
+	l, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if l > uint64(len(r.b)) {
+		return nil, ErrInvalidWriteRequest
+	}
+	return r.fixed(int(l))
+}
+
+func (r *fieldReader) string() (string, error) {
+	b, err := r.bytes()
+	return string(b), err
+}
@@ -20,10 +20,12 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/tricksterproxy/trickster/pkg/cache/key"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	"github.com/tricksterproxy/trickster/pkg/util/promql"
 )
 
 func (c *Client) registerHandlers() {
@@ -37,6 +39,18 @@ func (c *Client) registerHandlers() {
 	c.handlers["series"] = http.HandlerFunc(c.SeriesHandler)
 	c.handlers["proxycache"] = http.HandlerFunc(c.ObjectProxyCacheHandler)
 	c.handlers["proxy"] = http.HandlerFunc(c.ProxyHandler)
+	c.handlers["remote_write"] = http.HandlerFunc(c.RemoteWriteHandler)
+	c.handlers[mnFederate] = http.HandlerFunc(c.FederateHandler)
+	// queries are limited to the series having any injected labels before they are handled.
+	// remote write bodies are protobuf rather than query parameters, so their series are
+	// given the injected labels by the remote write handler
+	if c.config != nil && c.config.InjectedLabelEnforcer != nil {
+		for k, h := range c.handlers {
+			if k != "health" && k != "remote_write" {
+				c.handlers[k] = c.injectLabels(h)
+			}
+		}
+	}
 }
 
 // Handlers returns a map of the HTTP Handlers the client has registered
@@ -59,6 +73,15 @@ func populateHeathCheckRequestValues(oc *oo.Options) {
 	}
 }
 
+// normalizeQuery returns the normalized form of the PromQL query for deriving cache keys,
+// or the query unchanged if it can't be normalized
+func normalizeQuery(query string) string {
+	if q, err := promql.Normalize(query); err == nil {
+		return q
+	}
+	return query
+}
+
 // DefaultPathConfigs returns the default PathConfigs for the given OriginType
 func (c *Client) DefaultPathConfigs(oc *oo.Options) map[string]*po.Options {
 
@@ -72,6 +95,32 @@ func (c *Client) DefaultPathConfigs(oc *oo.Options) map[string]*po.Options {
 	rhinst := map[string]string{
 		headers.NameCacheControl: fmt.Sprintf("%s=%d", headers.ValueSharedMaxAge, 30)}
 
+	// status endpoints polled by meta-monitoring dashboards are cached for a short,
+	// configurable TTL, or are proxied without caching when it is 0
+	statusHandler := "proxycache"
+	var rhstatus map[string]string
+	if oc != nil {
+		if oc.StatusCacheTTLSecs > 0 {
+			rhstatus = map[string]string{headers.NameCacheControl: fmt.Sprintf("%s=%d",
+				headers.ValueSharedMaxAge, oc.StatusCacheTTLSecs)}
+		} else {
+			statusHandler = "proxy"
+		}
+	}
+
+	// federation scrapes are cached for about one scrape interval, so that servers federating
+	// the same series through Trickster share a single scrape of the origin
+	federateHandler := mnFederate
+	var rhfederate map[string]string
+	if oc != nil {
+		if oc.FederateCacheTTLSecs > 0 {
+			rhfederate = map[string]string{headers.NameCacheControl: fmt.Sprintf("%s=%d",
+				headers.ValueSharedMaxAge, oc.FederateCacheTTLSecs)}
+		} else {
+			federateHandler = "proxy"
+		}
+	}
+
 	paths := map[string]*po.Options{
 
 		APIPath + mnQueryRange: {
@@ -131,11 +180,11 @@ func (c *Client) DefaultPathConfigs(oc *oo.Options) map[string]*po.Options {
 
 		APIPath + mnTargets: {
 			Path:            APIPath + mnTargets,
-			HandlerName:     "proxycache",
+			HandlerName:     statusHandler,
 			Methods:         []string{http.MethodGet},
-			CacheKeyParams:  []string{},
+			CacheKeyParams:  []string{upState, upScrapePool},
 			CacheKeyHeaders: []string{},
-			ResponseHeaders: rhinst,
+			ResponseHeaders: rhstatus,
 			MatchTypeName:   "exact",
 			MatchType:       matching.PathMatchTypeExact,
 		},
@@ -195,6 +244,58 @@ func (c *Client) DefaultPathConfigs(oc *oo.Options) map[string]*po.Options {
 			ResponseHeaders: rhinst,
 		},
 
+		APIPath + mnStatusTSDB: {
+			Path:            APIPath + mnStatusTSDB,
+			HandlerName:     statusHandler,
+			Methods:         []string{http.MethodGet},
+			CacheKeyParams:  []string{},
+			CacheKeyHeaders: []string{},
+			ResponseHeaders: rhstatus,
+			MatchTypeName:   "exact",
+			MatchType:       matching.PathMatchTypeExact,
+		},
+
+		APIPath + mnStatusBuildInfo: {
+			Path:            APIPath + mnStatusBuildInfo,
+			HandlerName:     statusHandler,
+			Methods:         []string{http.MethodGet},
+			CacheKeyParams:  []string{},
+			CacheKeyHeaders: []string{},
+			ResponseHeaders: rhstatus,
+			MatchTypeName:   "exact",
+			MatchType:       matching.PathMatchTypeExact,
+		},
+
+		APIPath + mnStatusRuntimeInfo: {
+			Path:            APIPath + mnStatusRuntimeInfo,
+			HandlerName:     statusHandler,
+			Methods:         []string{http.MethodGet},
+			CacheKeyParams:  []string{},
+			CacheKeyHeaders: []string{},
+			ResponseHeaders: rhstatus,
+			MatchTypeName:   "exact",
+			MatchType:       matching.PathMatchTypeExact,
+		},
+
+		APIPath + mnWrite: {
+			Path:          APIPath + mnWrite,
+			HandlerName:   "remote_write",
+			Methods:       []string{http.MethodPost},
+			MatchTypeName: "exact",
+			MatchType:     matching.PathMatchTypeExact,
+		},
+
+		"/" + mnFederate: {
+			Path:            "/" + mnFederate,
+			HandlerName:     federateHandler,
+			Methods:         []string{http.MethodGet},
+			CacheKeyParams:  []string{upMatch},
+			CacheKeyHeaders: []string{},
+			ResponseHeaders: rhfederate,
+			MatchTypeName:   "exact",
+			MatchType:       matching.PathMatchTypeExact,
+		},
+
 		APIPath: {
 			Path:          APIPath,
 			HandlerName:   "proxy",
@@ -212,6 +313,13 @@ func (c *Client) DefaultPathConfigs(oc *oo.Options) map[string]*po.Options {
 		},
 	}
 
+	// equivalent queries from different clients share cache entries
+	if oc != nil && !oc.QueryNormalizationDisabled {
+		kn := map[string]key.NormalizerFunc{upQuery: normalizeQuery}
+		paths[APIPath+mnQueryRange].KeyNormalizers = kn
+		paths[APIPath+mnQuery].KeyNormalizers = kn
+	}
+
 	oc.FastForwardPath = paths[APIPath+mnQuery].Clone()
 
 	return paths
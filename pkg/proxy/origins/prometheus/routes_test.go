@@ -19,6 +19,7 @@ package prometheus
 import (
 	"testing"
 
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	tu "github.com/tricksterproxy/trickster/pkg/util/testing"
 )
@@ -59,9 +60,43 @@ func TestDefaultPathConfigs(t *testing.T) {
 		t.Errorf("expected to find path named: %s", "/")
 	}
 
-	const expectedLen = 13
+	const expectedLen = 18
 	if len(dpc) != expectedLen {
 		t.Errorf("expected ordered length to be: %d got %d", expectedLen, len(dpc))
 	}
 
+	pc := dpc[APIPath+mnStatusTSDB]
+	if pc == nil || pc.HandlerName != "proxycache" ||
+		pc.ResponseHeaders[headers.NameCacheControl] != "s-maxage=30" {
+		t.Errorf("unexpected path config for %s: %v", APIPath+mnStatusTSDB, pc)
+	}
+
+	if pc := dpc[APIPath+mnQueryRange]; pc.KeyNormalizers[upQuery] == nil {
+		t.Errorf("expected query normalizer for %s", APIPath+mnQueryRange)
+	} else if q := pc.KeyNormalizers[upQuery]("SUM(up {b='1', a='2'})"); q != "sum(up{a='2',b='1'})" {
+		t.Errorf("expected %s got %s", "sum(up{a='2',b='1'})", q)
+	}
+
+	pc = dpc["/"+mnFederate]
+	if pc == nil || pc.HandlerName != mnFederate ||
+		pc.ResponseHeaders[headers.NameCacheControl] != "s-maxage=15" {
+		t.Errorf("unexpected path config for %s: %v", "/"+mnFederate, pc)
+	}
+
+	client.config.QueryNormalizationDisabled = true
+	client.config.FederateCacheTTLSecs = 0
+	client.config.StatusCacheTTLSecs = 0
+	dpc = client.DefaultPathConfigs(client.config)
+	for _, p := range []string{mnTargets, mnStatusTSDB, mnStatusBuildInfo, mnStatusRuntimeInfo} {
+		if pc := dpc[APIPath+p]; pc.HandlerName != "proxy" {
+			t.Errorf("expected handler %s for %s got %s", "proxy", APIPath+p, pc.HandlerName)
+		}
+	}
+	if pc := dpc["/"+mnFederate]; pc.HandlerName != "proxy" {
+		t.Errorf("expected handler %s for %s got %s", "proxy", "/"+mnFederate, pc.HandlerName)
+	}
+	if pc := dpc[APIPath+mnQueryRange]; pc.KeyNormalizers != nil {
+		t.Errorf("expected no query normalizer for %s", APIPath+mnQueryRange)
+	}
+
 }
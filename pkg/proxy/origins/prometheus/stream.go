@@ -0,0 +1,185 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+
+	"github.com/prometheus/common/model"
+)
+
+// streamBufferSize is the size of the buffer used to write streamed responses
+const streamBufferSize = 32 * 1024
+
+// StreamTimeseries writes the JSON encoding of the portion of the Timeseries within the
+// Extent to w, followed by any values in ff, without cropping or merging a copy of the
+// Timeseries or materializing the encoded body. The output is identical to cropping a
+// clone of the Timeseries to the Extent, merging ff into it and marshaling it for the
+// client. When e is nil, the Timeseries is not cropped. It returns the number of values
+// written from the Timeseries, not including those from ff. The Timeseries must be sorted
+func (c *Client) StreamTimeseries(w io.Writer, ts timeseries.Timeseries, e *timeseries.Extent,
+	ff timeseries.Timeseries) (int, error) {
+
+	me, ok := ts.(*MatrixEnvelope)
+	if !ok {
+		return 0, ErrNotMatrix
+	}
+	var ffme *MatrixEnvelope
+	if ff != nil {
+		if ffme, ok = ff.(*MatrixEnvelope); !ok {
+			return 0, ErrNotMatrix
+		}
+	}
+
+	result := me.Data.Result
+	if e != nil && (len(me.ExtentList) == 0 || me.ExtentList.OutsideOf(*e)) {
+		result = model.Matrix{}
+	}
+
+	// fast forward series are appended to the series with the same labels, and
+	// any remaining fast forward series are written after the others
+	ffSeries := make(map[string]*model.SampleStream)
+	var ffOrder []*model.SampleStream
+	if ffme != nil {
+		for _, s := range ffme.Data.Result {
+			name := s.Metric.String()
+			if _, ok := ffSeries[name]; !ok {
+				ffOrder = append(ffOrder, s)
+			}
+			ffSeries[name] = s
+		}
+	}
+
+	bw := bufio.NewWriterSize(w, streamBufferSize)
+	sw := &streamWriter{w: bw}
+
+	sw.writeString(`{"status":`)
+	sw.writeJSON(me.Status)
+	sw.writeString(`,"data":{"resultType":`)
+	sw.writeJSON(me.Data.ResultType)
+	sw.writeString(`,"result":[`)
+	var count int
+	for _, s := range result {
+		values := s.Values
+		if e != nil {
+			values = valuesInRange(values, *e)
+			if len(values) == 0 {
+				continue
+			}
+		}
+		var ffValues []model.SamplePair
+		name := s.Metric.String()
+		if fs, ok := ffSeries[name]; ok {
+			ffValues = fs.Values
+			delete(ffSeries, name)
+		}
+		sw.writeSeries(count, s.Metric, values, ffValues)
+		count++
+		sw.n += len(values)
+	}
+	for _, s := range ffOrder {
+		if _, ok := ffSeries[s.Metric.String()]; !ok {
+			continue // already appended to a series with the same labels
+		}
+		sw.writeSeries(count, s.Metric, s.Values, nil)
+		count++
+	}
+	sw.writeString("]")
+	sw.writeString("}}")
+
+	if sw.err != nil {
+		return sw.n, sw.err
+	}
+	return sw.n, bw.Flush()
+}
+
+// valuesInRange returns the subslice of the sorted values within the Extent (inclusive)
+func valuesInRange(values []model.SamplePair, e timeseries.Extent) []model.SamplePair {
+	start := model.TimeFromUnixNano(e.Start.UnixNano())
+	end := model.TimeFromUnixNano(e.End.UnixNano())
+	i := sort.Search(len(values), func(i int) bool { return values[i].Timestamp >= start })
+	j := sort.Search(len(values), func(j int) bool { return values[j].Timestamp > end })
+	if i >= j {
+		return nil
+	}
+	return values[i:j]
+}
+
+// streamWriter writes to w until the first error, which is retained in err
+type streamWriter struct {
+	w   *bufio.Writer
+	n   int
+	err error
+}
+
+func (sw *streamWriter) writeString(s string) {
+	if sw.err == nil {
+		_, sw.err = sw.w.WriteString(s)
+	}
+}
+
+func (sw *streamWriter) writeBytes(b []byte) {
+	if sw.err == nil {
+		_, sw.err = sw.w.Write(b)
+	}
+}
+
+func (sw *streamWriter) writeJSON(v interface{}) {
+	if sw.err != nil {
+		return
+	}
+	var b []byte
+	if b, sw.err = json.Marshal(v); sw.err == nil {
+		sw.writeBytes(b)
+	}
+}
+
+func (sw *streamWriter) writeSeries(i int, metric model.Metric, values, ffValues []model.SamplePair) {
+	if i > 0 {
+		sw.writeString(",")
+	}
+	sw.writeString(`{"metric":`)
+	sw.writeJSON(metric)
+	sw.writeString(`,"values":`)
+	if values == nil && ffValues == nil {
+		sw.writeString("null}")
+		return
+	}
+	sw.writeString("[")
+	var j int
+	for _, vals := range [][]model.SamplePair{values, ffValues} {
+		for _, v := range vals {
+			if j > 0 {
+				sw.writeString(",")
+			}
+			j++
+			if sw.err != nil {
+				return
+			}
+			var b []byte
+			if b, sw.err = v.MarshalJSON(); sw.err == nil {
+				sw.writeBytes(b)
+			}
+		}
+	}
+	sw.writeString("]}")
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+
+	"github.com/prometheus/common/model"
+)
+
+func testStreamMatrix() *MatrixEnvelope {
+	return &MatrixEnvelope{
+		Status: "success",
+		Data: MatrixData{
+			ResultType: "matrix",
+			Result: model.Matrix{
+				&model.SampleStream{
+					Metric: model.Metric{"__name__": "a"},
+					Values: []model.SamplePair{
+						{Timestamp: 100000, Value: 1},
+						{Timestamp: 200000, Value: 2},
+						{Timestamp: 300000, Value: 3},
+						{Timestamp: 400000, Value: 4},
+					},
+				},
+				&model.SampleStream{
+					Metric: model.Metric{"__name__": "b"},
+					Values: []model.SamplePair{
+						{Timestamp: 100000, Value: 1.5},
+					},
+				},
+				&model.SampleStream{
+					Metric: model.Metric{"__name__": "c"},
+					Values: []model.SamplePair{
+						{Timestamp: 300000, Value: 3.5},
+						{Timestamp: 400000, Value: 4.5},
+					},
+				},
+			},
+		},
+		ExtentList:   timeseries.ExtentList{timeseries.Extent{Start: time.Unix(100, 0), End: time.Unix(400, 0)}},
+		StepDuration: 100 * time.Second,
+	}
+}
+
+func testStreamFastForward() *MatrixEnvelope {
+	return &MatrixEnvelope{
+		Status: "success",
+		Data: MatrixData{
+			ResultType: "matrix",
+			Result: model.Matrix{
+				&model.SampleStream{
+					Metric: model.Metric{"__name__": "a"},
+					Values: []model.SamplePair{{Timestamp: 410000, Value: 5}},
+				},
+				&model.SampleStream{
+					Metric: model.Metric{"__name__": "d"},
+					Values: []model.SamplePair{{Timestamp: 410000, Value: 6}},
+				},
+			},
+		},
+		ExtentList: timeseries.ExtentList{timeseries.Extent{Start: time.Unix(410, 0), End: time.Unix(410, 0)}},
+	}
+}
+
+// expectedStream returns the client response encoding of ts as produced without streaming
+func expectedStream(t *testing.T, c *Client, ts timeseries.Timeseries, e *timeseries.Extent,
+	ff timeseries.Timeseries) string {
+	rts := ts.Clone()
+	if e != nil {
+		rts.CropToRange(*e)
+	}
+	if ff != nil {
+		rts.Merge(false, ff)
+	}
+	rts.SetExtents(nil)
+	rts.SetStep(0)
+	b, err := c.MarshalTimeseries(rts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestStreamTimeseries(t *testing.T) {
+
+	c := &Client{}
+
+	tests := []struct {
+		name   string
+		extent *timeseries.Extent
+		ff     bool
+		count  int
+	}{
+		{"uncropped", nil, false, 7},
+		{"cropped", &timeseries.Extent{Start: time.Unix(200, 0), End: time.Unix(300, 0)}, false, 3},
+		{"outside", &timeseries.Extent{Start: time.Unix(500, 0), End: time.Unix(600, 0)}, false, 0},
+		{"fast forward", &timeseries.Extent{Start: time.Unix(300, 0), End: time.Unix(400, 0)}, true, 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var ff timeseries.Timeseries
+			if test.ff {
+				ff = testStreamFastForward()
+			}
+			me := testStreamMatrix()
+			expected := expectedStream(t, c, me, test.extent, ff)
+
+			buf := &bytes.Buffer{}
+			n, err := c.StreamTimeseries(buf, me, test.extent, ff)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != test.count {
+				t.Errorf("expected %d values got %d", test.count, n)
+			}
+			if buf.String() != expected {
+				t.Errorf("expected %s got %s", expected, buf.String())
+			}
+			// the source timeseries must not be modified
+			if me.ValueCount() != 7 || len(me.Data.Result[0].Values) != 4 {
+				t.Error("source timeseries was modified")
+			}
+		})
+	}
+}
+
+func TestStreamTimeseriesEmpty(t *testing.T) {
+	c := &Client{}
+	me := &MatrixEnvelope{Status: "success", Data: MatrixData{ResultType: "matrix"}}
+	expected := expectedStream(t, c, me, nil, nil)
+	buf := &bytes.Buffer{}
+	if _, err := c.StreamTimeseries(buf, me, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != expected {
+		t.Errorf("expected %s got %s", expected, buf.String())
+	}
+}
+
+var errTestWrite = errors.New("test write error")
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errTestWrite
+}
+
+func TestStreamTimeseriesErrors(t *testing.T) {
+	c := &Client{}
+	if _, err := c.StreamTimeseries(&bytes.Buffer{}, &notMatrix{}, nil, nil); err != ErrNotMatrix {
+		t.Errorf("expected %v got %v", ErrNotMatrix, err)
+	}
+	if _, err := c.StreamTimeseries(&bytes.Buffer{}, testStreamMatrix(), nil,
+		&notMatrix{}); err != ErrNotMatrix {
+		t.Errorf("expected %v got %v", ErrNotMatrix, err)
+	}
+	if _, err := c.StreamTimeseries(failingWriter{}, testStreamMatrix(), nil,
+		nil); err != errTestWrite {
+		t.Errorf("expected %v got %v", errTestWrite, err)
+	}
+}
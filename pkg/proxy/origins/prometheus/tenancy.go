@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/params"
+	"github.com/tricksterproxy/trickster/pkg/util/promql"
+)
+
+// injectLabels returns a handler that injects the origin's required label matchers into
+// every selector of the request's query and match[] parameters, before the request is
+// passed to the next handler to derive its cache key and fetch it from the origin.
+// Requests missing a trusted value for any of the labels, and requests for endpoints whose
+// results can't be limited by the labels, are rejected with 403 Forbidden
+func (c *Client) injectLabels(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !labelFiltered(r.URL.Path) {
+			http.Error(w, "endpoint is not available to tenants", http.StatusForbidden)
+			return
+		}
+		ms, err := c.config.InjectedLabelEnforcer.Matchers(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		v := params.GetRequestValues(r)
+		if q, ok := v[upQuery]; ok && len(q) > 0 {
+			if q[0], err = promql.InjectMatchers(q[0], ms); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			v.Set(upQuery, q[0])
+		}
+		// the series and label endpoints list every series when there is no match[],
+		// so one is added to limit them to those having the injected labels
+		matches := v[upMatch]
+		if len(matches) == 0 && requiresMatch(r.URL.Path) {
+			matches = []string{"{}"}
+		}
+		for i := range matches {
+			if matches[i], err = promql.InjectMatchers(matches[i], ms); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if len(matches) > 0 {
+			v[upMatch] = matches
+		}
+		params.SetRequestValues(r, v)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requiresMatch returns true if the path lists series or labels for the selectors in its
+// match[] parameters
+func requiresMatch(path string) bool {
+	return strings.HasSuffix(path, "/"+mnSeries) || strings.HasSuffix(path, "/"+mnLabels) ||
+		(strings.Contains(path, "/"+mnLabel+"/") && strings.HasSuffix(path, "/values")) ||
+		strings.HasSuffix(path, "/federate")
+}
+
+// labelFiltered returns true if the path only returns series selected by its query or
+// match[] parameters, so that the injected labels limit its results to the tenant's series.
+// Other endpoints, such as targets, rules, alerts, metadata and status, are not filtered
+func labelFiltered(path string) bool {
+	return strings.HasSuffix(path, "/"+mnQuery) || strings.HasSuffix(path, "/"+mnQueryRange) ||
+		requiresMatch(path)
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/params"
+	"github.com/tricksterproxy/trickster/pkg/proxy/tenancy"
+)
+
+// newTenancyTestClient returns a client that trusts the X-Tenant header of requests from
+// the address of httptest.NewRequest
+func newTenancyTestClient() *Client {
+	e, _ := tenancy.NewEnforcer(map[string]*tenancy.Source{
+		"namespace": {Kind: tenancy.KindHeader, Name: "X-Tenant"},
+	}, nil, []string{"192.0.2.0/24"})
+	return &Client{config: &oo.Options{InjectedLabelEnforcer: e}}
+}
+
+func TestInjectLabels(t *testing.T) {
+
+	var got url.Values
+	h := newTenancyTestClient().injectLabels(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		got = params.GetRequestValues(r)
+	}))
+
+	form := url.Values{upQuery: {`sum by (job) (rate(up[5m]))`}, upStep: {"15"}}
+
+	tests := []struct {
+		method, path, body string
+		code               int
+		expected           url.Values
+	}{
+		{http.MethodGet, "/api/v1/query_range?" + form.Encode(), "", http.StatusOK,
+			url.Values{upQuery: {`sum by (job) (rate(up{namespace="a"}[5m]))`}, upStep: {"15"}}},
+		{http.MethodPost, "/api/v1/query", form.Encode(), http.StatusOK,
+			url.Values{upQuery: {`sum by (job) (rate(up{namespace="a"}[5m]))`}, upStep: {"15"}}},
+		{http.MethodGet, "/api/v1/series?match[]=up&match[]={job=\"x\"}", "", http.StatusOK,
+			url.Values{upMatch: {`up{namespace="a"}`, `{job="x",namespace="a"}`}}},
+		{http.MethodGet, "/api/v1/labels", "", http.StatusOK,
+			url.Values{upMatch: {`{namespace="a"}`}}},
+		{http.MethodGet, "/api/v1/label/job/values", "", http.StatusOK,
+			url.Values{upMatch: {`{namespace="a"}`}}},
+		{http.MethodGet, "/prom1/api/v1/series?match[]=up", "", http.StatusOK,
+			url.Values{upMatch: {`up{namespace="a"}`}}},
+		{http.MethodGet, "/federate?match[]=up", "", http.StatusOK,
+			url.Values{upMatch: {`up{namespace="a"}`}}},
+		// endpoints whose results can't be limited to the tenant's series are refused
+		{http.MethodGet, "/api/v1/targets", "", http.StatusForbidden, nil},
+		{http.MethodGet, "/api/v1/targets/metadata?match_target={job=\"x\"}", "",
+			http.StatusForbidden, nil},
+		{http.MethodGet, "/api/v1/status/config", "", http.StatusForbidden, nil},
+		{http.MethodGet, "/api/v1/metadata", "", http.StatusForbidden, nil},
+		{http.MethodGet, "/api/v1/rules", "", http.StatusForbidden, nil},
+		{http.MethodGet, "/api/v1/alerts", "", http.StatusForbidden, nil},
+		{http.MethodGet, "/api/v1/query?query=up{", "", http.StatusBadRequest, nil},
+		{http.MethodGet, "/api/v1/series?match[]=up{", "", http.StatusBadRequest, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			got = nil
+			r := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			if test.body != "" {
+				r.Header.Set(headers.NameContentType, headers.ValueXFormURLEncoded)
+			}
+			r.Header.Set("X-Tenant", "a")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != test.code {
+				t.Errorf("expected %d got %d", test.code, w.Code)
+			}
+			if test.expected != nil && got.Encode() != test.expected.Encode() {
+				t.Errorf("expected %s got %s", test.expected.Encode(), got.Encode())
+			}
+			if test.code == http.StatusForbidden && got != nil {
+				t.Error("expected the request not to be handled")
+			}
+		})
+	}
+
+	// requests without a tenant are rejected
+	got = nil
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected %d got %d", http.StatusForbidden, w.Code)
+	}
+	if got != nil {
+		t.Error("expected the request not to be handled")
+	}
+}
+
+func TestRegisterHandlersInjectLabels(t *testing.T) {
+	c := newTenancyTestClient()
+	c.registerHandlers()
+	for _, k := range []string{mnQueryRange, mnQuery, mnSeries, "proxycache", "proxy"} {
+		w := httptest.NewRecorder()
+		c.handlers[k].ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/query?query=up", nil))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s: expected %d got %d", k, http.StatusForbidden, w.Code)
+		}
+	}
+	// a tenant's request for the targets, which are proxied by the catch-all route, is refused
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/targets", nil)
+	r.Header.Set("X-Tenant", "a")
+	w := httptest.NewRecorder()
+	c.handlers["proxy"].ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected %d got %d", http.StatusForbidden, w.Code)
+	}
+}
@@ -17,37 +17,30 @@
 package prometheus
 
 import (
-	"bytes"
-	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/tricksterproxy/trickster/pkg/proxy/params"
 	"github.com/tricksterproxy/trickster/pkg/proxy/urls"
 	"github.com/tricksterproxy/trickster/pkg/timeseries"
 )
 
 // SetExtent will change the upstream request query to use the provided Extent
 func (c *Client) SetExtent(r *http.Request, trq *timeseries.TimeRangeQuery, extent *timeseries.Extent) {
-	var qp url.Values
-	if r.Method == http.MethodPost {
-		r.ParseForm()
-		qp = r.PostForm
-	} else {
-		qp = r.URL.Query()
-	}
-
+	qp := params.GetRequestValues(r)
 	qp.Set(upStart, strconv.FormatInt(extent.Start.Unix(), 10))
 	qp.Set(upEnd, strconv.FormatInt(extent.End.Unix(), 10))
-	s := qp.Encode()
+	params.SetRequestValues(r, qp)
+}
 
-	if r.Method == http.MethodPost {
-		r.ContentLength = int64(len(s))
-		r.Body = ioutil.NopCloser(bytes.NewBufferString(s))
-	} else {
-		r.URL.RawQuery = s
-	}
+// SetStep will change the upstream request's step parameter to the provided step
+func (c *Client) SetStep(r *http.Request, trq *timeseries.TimeRangeQuery, step time.Duration) {
+	qp := params.GetRequestValues(r)
+	qp.Set(upStep, strconv.FormatInt(int64(step.Seconds()), 10))
+	params.SetRequestValues(r, qp)
 }
 
 // FastForwardURL returns the url to fetch the Fast Forward value based on a timerange url
@@ -59,18 +52,7 @@ func (c *Client) FastForwardURL(r *http.Request) (*url.URL, error) {
 		u.Path = u.Path[0 : len(u.Path)-6]
 	}
 
-	var b []byte
-	var qp url.Values
-
-	if r.Method == http.MethodPost {
-		b, _ = ioutil.ReadAll(r.Body)
-		r.ParseForm()
-		qp = r.PostForm
-		r.Body = ioutil.NopCloser(bytes.NewBuffer(b))
-	} else {
-		qp = r.URL.Query()
-	}
-
+	qp := params.GetRequestValues(r)
 	qp.Del(upStart)
 	qp.Del(upEnd)
 	qp.Del(upStep)
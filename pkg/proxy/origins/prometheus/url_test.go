@@ -19,12 +19,14 @@ package prometheus
 import (
 	"bytes"
 	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"testing"
 	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/urls"
 	"github.com/tricksterproxy/trickster/pkg/timeseries"
 )
@@ -69,6 +71,54 @@ func TestSetExtent(t *testing.T) {
 		t.Errorf("expected 31 got %d", r.ContentLength)
 	}
 
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+	mw.WriteField("q", "up")
+	fw, _ := mw.CreateFormFile("upload", "data.bin")
+	fw.Write([]byte("\x00\xffdata"))
+	mw.Close()
+	r, _ = http.NewRequest(http.MethodPost, u2.String(), buf)
+	r.Header.Set(headers.NameContentType, mw.FormDataContentType())
+
+	client.SetExtent(r, nil, e)
+	if err := r.ParseMultipartForm(1024); err != nil {
+		t.Fatal(err)
+	}
+	if v := r.MultipartForm.Value[upStart]; len(v) != 1 || v[0] != startSecs {
+		t.Errorf("expected %s got %v", startSecs, v)
+	}
+	if v := r.MultipartForm.Value["q"]; len(v) != 1 || v[0] != "up" {
+		t.Errorf("expected %s got %v", "up", v)
+	}
+	if fh := r.MultipartForm.File["upload"]; len(fh) != 1 || fh[0].Size != 6 {
+		t.Error("expected file part to be preserved")
+	}
+
+}
+
+func TestClientSetStep(t *testing.T) {
+
+	expected := "query=up&step=300"
+
+	client := Client{}
+
+	u := &url.URL{RawQuery: "query=up&step=15"}
+	r, _ := http.NewRequest(http.MethodGet, u.String(), nil)
+	client.SetStep(r, nil, time.Duration(300)*time.Second)
+
+	if expected != r.URL.RawQuery {
+		t.Errorf("\nexpected [%s]\ngot [%s]", expected, r.URL.RawQuery)
+	}
+
+	u.RawQuery = ""
+	r, _ = http.NewRequest(http.MethodPost, u.String(), bytes.NewBufferString("query=up&step=15"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client.SetStep(r, nil, time.Duration(300)*time.Second)
+	if r.ContentLength != int64(len(expected)) {
+		t.Errorf("expected %d got %d", len(expected), r.ContentLength)
+	}
+
 }
 
 func TestFastForwardURL(t *testing.T) {
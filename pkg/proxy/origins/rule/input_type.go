@@ -17,7 +17,11 @@
 package rule
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/tricksterproxy/trickster/pkg/proxy/urls"
@@ -38,6 +42,7 @@ var sourceExtractionFuncs = map[inputType]extractionFunc{
 	"params":        extractParamsFromSource,
 	"param":         extractParamFromSource,
 	"header":        extractHeaderFromSource,
+	"jwt_claim":     extractJWTClaimFromSource,
 }
 
 // IsValidSourceName returns true only if the provided source name is supported by the Rules engine
@@ -125,6 +130,58 @@ func extractHeaderFromSource(r *http.Request, headerName string) string {
 	return ""
 }
 
+// extractJWTClaimFromSource returns the value of the named claim from the payload of the
+// Bearer token in the Authorization header. Nested claims can be addressed with a
+// dot-separated path (e.g., 'realm_access.role'). The token signature is not verified,
+// so claims should only be used for routing decisions and never for authorization.
+func extractJWTClaimFromSource(r *http.Request, claimName string) string {
+	if r == nil || r.Header == nil || claimName == "" {
+		return ""
+	}
+	h := r.Header.Get("Authorization")
+	if len(h) < 7 || !strings.EqualFold(h[:7], "bearer ") {
+		return ""
+	}
+	parts := strings.Split(strings.TrimSpace(h[7:]), ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return ""
+	}
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err = d.Decode(&v); err != nil {
+		return ""
+	}
+	for _, k := range strings.Split(claimName, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		if v, ok = m[k]; !ok {
+			return ""
+		}
+	}
+	switch t := v.(type) {
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	case bool:
+		return strconv.FormatBool(t)
+	case nil:
+		return ""
+	}
+	b, err = json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
 // assumes delimiter is not empty string, and part is >= 0
 func extractSourcePart(input, delimiter string, part int) string {
 	if input == "" || len(delimiter) > len(input) {
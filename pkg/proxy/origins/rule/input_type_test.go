@@ -68,6 +68,7 @@ func TestExtractions(t *testing.T) {
 		{"params", "", "", nil},
 		{"param", "param1", "", nil},
 		{"header", "Authorization", "", nil},
+		{"jwt_claim", "sub", "", nil},
 	}
 	for i, test := range tests {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
@@ -119,3 +120,44 @@ func TestExtractSourcePart(t *testing.T) {
 		t.Errorf("expected %s got %s", "", v)
 	}
 }
+
+func TestExtractJWTClaimFromSource(t *testing.T) {
+
+	// {"alg":"none","typ":"JWT"}
+	const header = "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0"
+	// {"sub":"johndoe","tier":2,"admin":true,"org":{"name":"acme"},"groups":["a","b"]}
+	const payload = "eyJzdWIiOiJqb2huZG9lIiwidGllciI6MiwiYWRtaW4iOnRydWUsIm9yZyI6eyJuYW1lIjoiYWNtZSJ9LCJncm91cHMiOlsiYSIsImIiXX0"
+	const token = header + "." + payload + ".sig"
+
+	tests := []struct {
+		authorization, claim, expected string
+	}{
+		{"Bearer " + token, "sub", "johndoe"},
+		{"bearer " + token, "tier", "2"},
+		{"Bearer " + token, "admin", "true"},
+		{"Bearer " + token, "org.name", "acme"},
+		{"Bearer " + token, "org", `{"name":"acme"}`},
+		{"Bearer " + token, "groups", `["a","b"]`},
+		{"Bearer " + token, "missing", ""},
+		{"Bearer " + token, "sub.name", ""},
+		{"Bearer " + token, "", ""},
+		{"Basic " + token, "sub", ""},
+		{"Bearer " + header + "." + payload, "sub", ""},
+		{"Bearer " + header + ".!!!.sig", "sub", ""},
+		{"Bearer " + header + "." + header[:10] + ".sig", "sub", ""},
+		{"", "sub", ""},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			r, _ := http.NewRequest("GET", "http://example.com/", nil)
+			if test.authorization != "" {
+				r.Header.Set("Authorization", test.authorization)
+			}
+			got := extractJWTClaimFromSource(r, test.claim)
+			if got != test.expected {
+				t.Errorf("\ngot      %s\nexpected %s", got, test.expected)
+			}
+		})
+	}
+}
@@ -19,7 +19,9 @@ package rule
 import (
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/tricksterproxy/trickster/pkg/proxy/timeconv"
 	"github.com/tricksterproxy/trickster/pkg/util/base64"
 	"github.com/tricksterproxy/trickster/pkg/util/md5"
 	"github.com/tricksterproxy/trickster/pkg/util/sha1"
@@ -49,6 +51,13 @@ var operationFuncs = map[operation]operationFunc{
 	// TODO: understand use case and implementation for these num funcs
 	"num-modulo": opNumModulo,
 
+	"duration-eq": opDurationEquality,
+	"duration-gt": opDurationGreaterThan,
+	"duration-lt": opDurationLessThan,
+	"duration-ge": opDurationGreaterThanEqual,
+	"duration-le": opDurationLessThanEqual,
+	"duration-bt": opDurationBetween,
+
 	"bool-eq": opBoolEquality,
 }
 
@@ -180,6 +189,86 @@ func opNumModulo(input, arg string, unused bool) string {
 	return ""
 }
 
+// parseDurationInput parses a duration expressed as a bare number of seconds
+// (e.g., '15' or '0.5'), or with units (e.g., '30s', '5m', '1d' or '1h30m')
+func parseDurationInput(input string) (time.Duration, bool) {
+	if input == "" {
+		return 0, false
+	}
+	if f, err := strconv.ParseFloat(input, 64); err == nil {
+		return time.Duration(f * float64(time.Second)), true
+	}
+	if d, err := timeconv.ParseDuration(input); err == nil {
+		return d, true
+	}
+	if d, err := time.ParseDuration(input); err == nil {
+		return d, true
+	}
+	return 0, false
+}
+
+func areDurations(input1, input2 string) (time.Duration, time.Duration, bool) {
+	out1, ok := parseDurationInput(input1)
+	if !ok {
+		return 0, 0, false
+	}
+	out2, ok := parseDurationInput(input2)
+	if !ok {
+		return 0, 0, false
+	}
+	return out1, out2, true
+}
+
+func opDurationEquality(input, arg string, negate bool) string {
+	if i, a, ok := areDurations(input, arg); ok {
+		return btos(i == a, negate)
+	}
+	return ""
+}
+
+func opDurationGreaterThan(input, arg string, negate bool) string {
+	if i, a, ok := areDurations(input, arg); ok {
+		return btos(i > a, negate)
+	}
+	return ""
+}
+
+func opDurationLessThan(input, arg string, negate bool) string {
+	if i, a, ok := areDurations(input, arg); ok {
+		return btos(i < a, negate)
+	}
+	return ""
+}
+
+func opDurationGreaterThanEqual(input, arg string, negate bool) string {
+	if i, a, ok := areDurations(input, arg); ok {
+		return btos(i >= a, negate)
+	}
+	return ""
+}
+
+func opDurationLessThanEqual(input, arg string, negate bool) string {
+	if i, a, ok := areDurations(input, arg); ok {
+		return btos(i <= a, negate)
+	}
+	return ""
+}
+
+func opDurationBetween(input, arg string, negate bool) string {
+	h := strings.Index(arg, "-")
+	if h < 1 {
+		return ""
+	}
+	i, ok := parseDurationInput(input)
+	if !ok {
+		return ""
+	}
+	if s, e, ok := areDurations(arg[:h], arg[h+1:]); ok {
+		return btos(i >= s && i <= e, negate)
+	}
+	return ""
+}
+
 func areBools(input1, input2 string) (bool, bool, bool) {
 	out1, err := strconv.ParseBool(input1)
 	if err != nil {
@@ -88,6 +88,21 @@ func TestOperations(t *testing.T) {
 		{"num-modulo", "6", "6", false, "0"},
 		{"num-modulo", "a", "4", false, ""},
 		{"num-modulo", "4", "a", false, ""},
+		{"duration-lt", "15", "30s", false, "true"},
+		{"duration-lt", "1m", "30s", false, "false"},
+		{"duration-lt", "1m", "30s", true, "true"},
+		{"duration-gt", "1h", "30m", false, "true"},
+		{"duration-eq", "60", "1m", false, "true"},
+		{"duration-eq", "1m30s", "90", false, "true"},
+		{"duration-eq", "foo", "1m", false, ""},
+		{"duration-ge", "1d", "24h", false, "true"},
+		{"duration-le", "0.5", "1s", false, "true"},
+		{"duration-le", "1s", "", false, ""},
+		{"duration-bt", "5m", "1m-1h", false, "true"},
+		{"duration-bt", "2h", "1m-1h", false, "false"},
+		{"duration-bt", "2h", "1h", false, ""},
+		{"duration-bt", "x", "1m-1h", false, ""},
+		{"duration-bt", "5m", "x-1h", false, ""},
 		{"bool-eq", "true", "true", false, "true"},
 		{"bool-eq", "a", "true", false, ""},
 		{"bool-eq", "true", "a", false, ""},
@@ -36,6 +36,7 @@ type Options struct {
 	//
 	// Input source specifies the data source used when executing the rule. Possible options:
 	//  Source           Example Source Used
+	//  method           GET
 	//  url              https://example.com:8480/path1/path2?param1=value
 	//  url_no_params    https://example.com:8480/path1/path2
 	//  scheme           https
@@ -46,13 +47,16 @@ type Options struct {
 	//  params           ?param1=value
 	//  param            [must be used with InputKey as described below]
 	//  header           [must be used with InputKey as described below]
+	//  jwt_claim        [must be used with InputKey as described below]
 	InputSource string `toml:"input_source"`
 	//
 	// InputKey is optional and provides extra information for locating the data source
-	// when the InputSource is header or param, the input key must be the target header or param name
+	// when the InputSource is header or param, the input key must be the target header or param name.
+	// when the InputSource is jwt_claim, the input key is the claim name (or dot-separated path to a
+	// nested claim) in the payload of the request's Authorization Bearer token
 	InputKey string `toml:"input_key"`
 	// InputType is optional, defaulting to string, and indicates the type of input:
-	// string, num (treated internally as float64), or bool
+	// string, num (treated internally as float64), duration (e.g., '30s' or bare seconds), or bool
 	InputType string `toml:"input_type"`
 	// InputEncoding is optional, defaulting to '', and defines any special encoding format on
 	// the input. Supported Options are: 'base64'
@@ -68,6 +72,7 @@ type Options struct {
 	// determine if any case is matched. Possible options are as follows.
 	// string:   eq, contains, suffix, prefix, md5, sha1, base64, modulo
 	// num:      eq, gt, lt, ge, le, bt (inclusive), modulo
+	// duration: eq, gt, lt, ge, le, bt (inclusive)
 	// bool:     eq
 	// any boolean operation (everything but md5, sha1, base64, modulo) can be prefixed with !
 	Operation string `toml:"operation"`
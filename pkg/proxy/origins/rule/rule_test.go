@@ -298,3 +298,84 @@ func TestEvaluateCaseArg(t *testing.T) {
 	}
 
 }
+
+func TestEvaluatePredicates(t *testing.T) {
+
+	clients := origins.Origins{"test-origin-1": &Client{router: testMux1},
+		"test-origin-2": &Client{router: testMux2}}
+
+	// {"tier":"premium"}
+	const token = "eyJhbGciOiJub25lIn0.eyJ0aWVyIjoicHJlbWl1bSJ9.sig"
+
+	tests := []struct {
+		name     string
+		opts     *ro.Options
+		url      string
+		headers  http.Header
+		expected http.Handler
+	}{
+		{
+			name: "step-below-threshold",
+			opts: &ro.Options{InputSource: "param", InputKey: "step", InputType: "duration",
+				Operation: "lt", OperationArg: "30s"},
+			url:      "http://example.com/api/v1/query_range?step=15",
+			expected: testMux1,
+		},
+		{
+			name: "step-above-threshold",
+			opts: &ro.Options{InputSource: "param", InputKey: "step", InputType: "duration",
+				Operation: "lt", OperationArg: "30s"},
+			url:      "http://example.com/api/v1/query_range?step=5m",
+			expected: testMux2,
+		},
+		{
+			name: "jwt-claim",
+			opts: &ro.Options{InputSource: "jwt_claim", InputKey: "tier", InputType: "string",
+				Operation: "eq", OperationArg: "premium"},
+			url:      "http://example.com/",
+			headers:  http.Header{"Authorization": []string{"Bearer " + token}},
+			expected: testMux1,
+		},
+		{
+			name: "jwt-claim-missing-token",
+			opts: &ro.Options{InputSource: "jwt_claim", InputKey: "tier", InputType: "string",
+				Operation: "eq", OperationArg: "premium"},
+			url:      "http://example.com/",
+			expected: testMux2,
+		},
+		{
+			name: "method",
+			opts: &ro.Options{InputSource: "method", InputType: "string",
+				Operation: "eq", OperationArg: http.MethodPost},
+			url:      "http://example.com/",
+			expected: testMux2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.opts.Name = test.name
+			test.opts.NextRoute = "test-origin-2"
+			test.opts.InputIndex = -1
+			test.opts.CaseOptions = map[string]*ro.CaseOptions{
+				"match": {Matches: []string{"true"}, NextRoute: "test-origin-1"},
+			}
+			c, _ := NewClient("test-client", oo.NewOptions(), nil, clients)
+			if err := c.parseOptions(test.opts, nil); err != nil {
+				t.Fatal(err)
+			}
+			hr, _ := http.NewRequest(http.MethodGet, test.url, nil)
+			if test.headers != nil {
+				hr.Header = test.headers
+			}
+			hr = hr.WithContext(tc.WithHops(context.Background(), 0, 20))
+			h, _, err := c.rule.evaluatorFunc(hr)
+			if err != nil {
+				t.Error(err)
+			}
+			if h != test.expected {
+				t.Errorf("unexpected route for %s", test.name)
+			}
+		})
+	}
+}
@@ -17,8 +17,10 @@
 package origins
 
 import (
+	"io"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/cache"
 	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
@@ -60,3 +62,46 @@ type TimeseriesClient interface {
 	// Router returns a Router that handles HTTP Requests for this client
 	Router() http.Handler
 }
+
+// TimeseriesBinaryMarshaler is implemented by TimeseriesClients that can encode their
+// Timeseries in Trickster's compact binary cache format, which is smaller and faster
+// to decode than JSON for large datasets. Clients that do not implement it are cached
+// using MarshalTimeseries
+type TimeseriesBinaryMarshaler interface {
+	// MarshalTimeseriesBinary will return the binary cache encoding of the provided Timeseries
+	MarshalTimeseriesBinary(timeseries.Timeseries) ([]byte, error)
+	// UnmarshalTimeseriesBinary will return a Timeseries from the provided binary cache encoding
+	UnmarshalTimeseriesBinary([]byte) (timeseries.Timeseries, error)
+}
+
+// TimeseriesStreamer is implemented by TimeseriesClients that can encode a client response
+// directly from a Timeseries, without first cropping a copy of it and materializing the
+// full response body, which bounds the memory required to serve large range queries
+type TimeseriesStreamer interface {
+	// StreamTimeseries writes the client response encoding of the portion of the Timeseries
+	// within the Extent (or all of it, when the Extent is nil), merged with the optional
+	// fast forward Timeseries, to the Writer. It returns the number of values written from
+	// the Timeseries, not including any fast forward values
+	StreamTimeseries(io.Writer, timeseries.Timeseries, *timeseries.Extent,
+		timeseries.Timeseries) (int, error)
+}
+
+// TimeseriesStepper is implemented by TimeseriesClients that can change the step of a
+// time range query, which allows the Delta Proxy Cache to serve long look-backs from the
+// origin's downsample tiers
+type TimeseriesStepper interface {
+	// SetStep will update an upstream request's step parameter to the provided step
+	SetStep(*http.Request, *timeseries.TimeRangeQuery, time.Duration)
+}
+
+// TimeseriesResponseUnmarshaler is implemented by TimeseriesClients whose origins respond
+// in more than one format, depending on the origin's version or the requested format. The
+// format of each upstream response is detected, so that an origin that is upgraded to a
+// version with a different response format is still cached, instead of failing to decode
+type TimeseriesResponseUnmarshaler interface {
+	// UnmarshalTimeseriesResponse will return a Timeseries from the provided upstream
+	// response headers and body. When the response was converted from another format, the
+	// headers are updated to describe the MarshalTimeseries encoding. Responses in a format
+	// that can't be decoded return timeseries.ErrUnsupportedResponseFormat
+	UnmarshalTimeseriesResponse(http.Header, []byte) (timeseries.Timeseries, error)
+}
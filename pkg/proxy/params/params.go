@@ -17,7 +17,22 @@
 // Package params provides support for handling URL Parameters
 package params
 
-import "net/url"
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+var methodsWithBody = map[string]bool{http.MethodPut: true, http.MethodPost: true, http.MethodPatch: true}
 
 // UpdateParams updates the provided query parameters collection with the provided updates
 func UpdateParams(params url.Values, updates map[string]string) {
@@ -41,3 +56,178 @@ func UpdateParams(params url.Values, updates map[string]string) {
 		params.Set(k, v)
 	}
 }
+
+// GetRequestValues returns the request's parameters, from the body for POST, PUT
+// and PATCH requests, or from the URL query otherwise. URL-encoded bodies are
+// parsed in full, while only the text fields of multipart bodies are returned;
+// file parts are skipped. The request body is restored, so it can be read again
+func GetRequestValues(r *http.Request) url.Values {
+	if _, ok := methodsWithBody[r.Method]; !ok {
+		if r.URL == nil {
+			return url.Values{}
+		}
+		return r.URL.Query()
+	}
+	b := readBody(r)
+	ct, boundary := contentType(r)
+	switch {
+	case boundary != "":
+		v, _ := multipartValues(b, boundary)
+		return v
+	case ct == headers.ValueXFormURLEncoded:
+		v, _ := url.ParseQuery(string(b))
+		return v
+	}
+	return url.Values{}
+}
+
+// SetRequestValues replaces the request's parameters with the provided values,
+// in the body for POST, PUT and PATCH requests, or the URL query otherwise.
+// Multipart bodies are rebuilt part-by-part with their original boundary, so
+// file parts are passed through unmodified and only the text fields change
+func SetRequestValues(r *http.Request, v url.Values) {
+	if _, ok := methodsWithBody[r.Method]; !ok {
+		r.URL.RawQuery = v.Encode()
+		return
+	}
+	var b []byte
+	if _, boundary := contentType(r); boundary != "" {
+		var err error
+		if b, err = rebuildMultipart(readBody(r), boundary, v); err != nil {
+			// leave an unparsable body as-is rather than corrupting it further
+			return
+		}
+	} else {
+		b = []byte(v.Encode())
+	}
+	r.ContentLength = int64(len(b))
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	if r.Header != nil && r.Header.Get(headers.NameContentLength) != "" {
+		r.Header.Set(headers.NameContentLength, strconv.Itoa(len(b)))
+	}
+}
+
+// readBody reads the request body and restores it for subsequent readers
+func readBody(r *http.Request) []byte {
+	if r.Body == nil {
+		return nil
+	}
+	b, _ := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	return b
+}
+
+// contentType returns the request's media type, and its boundary if it is multipart/form-data
+func contentType(r *http.Request) (string, string) {
+	if r.Header == nil {
+		return "", ""
+	}
+	ct, p, err := mime.ParseMediaType(r.Header.Get(headers.NameContentType))
+	if err != nil {
+		return "", ""
+	}
+	if ct != headers.ValueMultipartFormData {
+		return ct, ""
+	}
+	return ct, p["boundary"]
+}
+
+// isFilePart returns true if the multipart part is a file upload rather than a text field
+func isFilePart(p *multipart.Part) bool {
+	return p.FileName() != ""
+}
+
+func multipartValues(b []byte, boundary string) (url.Values, error) {
+	v := url.Values{}
+	mr := multipart.NewReader(bytes.NewReader(b), boundary)
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			return v, nil
+		}
+		if err != nil {
+			return v, err
+		}
+		name := p.FormName()
+		if name == "" || isFilePart(p) {
+			continue
+		}
+		var sb strings.Builder
+		if _, err = io.Copy(&sb, p); err != nil {
+			return v, err
+		}
+		v.Add(name, sb.String())
+	}
+}
+
+// rebuildMultipart writes a multipart body using the provided boundary, in which each
+// text field takes its value(s) from v, in the order they originally appeared. File
+// parts are copied as-is, and fields in v that were not in the original body are
+// appended as new text parts
+func rebuildMultipart(b []byte, boundary string, v url.Values) ([]byte, error) {
+
+	out := &bytes.Buffer{}
+	mw := multipart.NewWriter(out)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return nil, err
+	}
+
+	written := make(map[string]bool)
+	mr := multipart.NewReader(bytes.NewReader(b), boundary)
+	for {
+		// raw parts are used so that any Content-Transfer-Encoding is passed through
+		p, err := mr.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := p.FormName()
+		if name == "" || isFilePart(p) {
+			w, err := mw.CreatePart(p.Header)
+			if err != nil {
+				return nil, err
+			}
+			if _, err = io.Copy(w, p); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// all values for a field are written at its first occurrence
+		if written[name] {
+			continue
+		}
+		written[name] = true
+		for _, fv := range v[name] {
+			w, err := mw.CreatePart(p.Header)
+			if err != nil {
+				return nil, err
+			}
+			if _, err = io.WriteString(w, fv); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(v))
+	for k := range v {
+		if !written[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, fv := range v[k] {
+			if err := mw.WriteField(k, fv); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -17,12 +17,49 @@
 package params
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
 	"net/url"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 )
 
+const testBoundary = "d0509edbe55938c0"
+
+// testFileContent includes bytes that would not survive urlencoding or text handling
+const testFileContent = "\x00\xff\r\n--" + testBoundary + "x\r\nbinary"
+
+func testMultipartBody(t *testing.T) []byte {
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+	mw.SetBoundary(testBoundary)
+	mw.WriteField("query", "up")
+	fw, err := mw.CreateFormFile("upload", "data.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write([]byte(testFileContent))
+	mw.WriteField("start", "1")
+	mw.WriteField("query", "down")
+	mw.Close()
+	return buf.Bytes()
+}
+
+func newMultipartRequest(t *testing.T, method string) *http.Request {
+	b := testMultipartBody(t)
+	r, _ := http.NewRequest(method, "http://127.0.0.1/?q=1", bytes.NewReader(b))
+	r.Header.Set(headers.NameContentType, headers.ValueMultipartFormData+"; boundary="+testBoundary)
+	r.Header.Set(headers.NameContentLength, strconv.Itoa(len(b)))
+	return r
+}
+
 func TestUpdateParams(t *testing.T) {
 
 	params := url.Values{"param1": {"value1"}, "param3": {"value3"}, "param4": {"value4"}}
@@ -45,3 +82,104 @@ func TestUpdateParams(t *testing.T) {
 	}
 
 }
+
+func TestGetRequestValues(t *testing.T) {
+
+	r, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/?q=1", nil)
+	v := GetRequestValues(r)
+	if v.Get("q") != "1" {
+		t.Errorf("expected %s got %s", "1", v.Get("q"))
+	}
+
+	r.URL = nil
+	if v = GetRequestValues(r); len(v) != 0 {
+		t.Errorf("expected %d got %d", 0, len(v))
+	}
+
+	r, _ = http.NewRequest(http.MethodPost, "http://127.0.0.1/?q=1", strings.NewReader("query=up&step=15"))
+	r.Header.Set(headers.NameContentType, headers.ValueXFormURLEncoded)
+	v = GetRequestValues(r)
+	expected := url.Values{"query": {"up"}, "step": {"15"}}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("expected %v got %v", expected, v)
+	}
+	b, _ := ioutil.ReadAll(r.Body)
+	if string(b) != "query=up&step=15" {
+		t.Errorf("expected body to be restored, got %s", string(b))
+	}
+
+	r, _ = http.NewRequest(http.MethodPost, "http://127.0.0.1/", strings.NewReader(`{"query":"up"}`))
+	r.Header.Set(headers.NameContentType, headers.ValueApplicationJSON)
+	if v = GetRequestValues(r); len(v) != 0 {
+		t.Errorf("expected %d got %d", 0, len(v))
+	}
+
+	r = newMultipartRequest(t, http.MethodPut)
+	v = GetRequestValues(r)
+	expected = url.Values{"query": {"up", "down"}, "start": {"1"}}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("expected %v got %v", expected, v)
+	}
+	b, _ = ioutil.ReadAll(r.Body)
+	if !bytes.Equal(b, testMultipartBody(t)) {
+		t.Error("expected multipart body to be restored")
+	}
+}
+
+func TestSetRequestValues(t *testing.T) {
+
+	r, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/?q=1", nil)
+	SetRequestValues(r, url.Values{"q": {"2"}})
+	if r.URL.RawQuery != "q=2" {
+		t.Errorf("expected %s got %s", "q=2", r.URL.RawQuery)
+	}
+
+	r, _ = http.NewRequest(http.MethodPost, "http://127.0.0.1/", strings.NewReader("query=up&step=15"))
+	r.Header.Set(headers.NameContentType, headers.ValueXFormURLEncoded)
+	SetRequestValues(r, url.Values{"query": {"up"}, "step": {"300"}})
+	b, _ := ioutil.ReadAll(r.Body)
+	if string(b) != "query=up&step=300" || r.ContentLength != int64(len(b)) {
+		t.Errorf("expected %s got %s", "query=up&step=300", string(b))
+	}
+
+	r = newMultipartRequest(t, http.MethodPost)
+	v := GetRequestValues(r)
+	v.Set("start", "2")
+	v.Del("query")
+	v.Set("end", "3")
+	SetRequestValues(r, v)
+
+	b, _ = ioutil.ReadAll(r.Body)
+	if r.ContentLength != int64(len(b)) ||
+		r.Header.Get(headers.NameContentLength) != strconv.Itoa(len(b)) {
+		t.Errorf("expected content length %d got %d", len(b), r.ContentLength)
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	// the boundary is unchanged, so the request's content type remains valid
+	if err := r.ParseMultipartForm(1024); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{"start": {"2"}, "end": {"3"}}
+	if !reflect.DeepEqual(r.MultipartForm.Value, expected) {
+		t.Errorf("expected %v got %v", expected, r.MultipartForm.Value)
+	}
+	fh, ok := r.MultipartForm.File["upload"]
+	if !ok || len(fh) != 1 || fh[0].Filename != "data.bin" {
+		t.Fatalf("expected file part %s", "upload")
+	}
+	f, _ := fh[0].Open()
+	b, _ = ioutil.ReadAll(f)
+	if string(b) != testFileContent {
+		t.Errorf("expected file content %q got %q", testFileContent, string(b))
+	}
+
+	// an unparsable multipart body is left intact
+	r, _ = http.NewRequest(http.MethodPost, "http://127.0.0.1/", strings.NewReader("trickster"))
+	r.Header.Set(headers.NameContentType, headers.ValueMultipartFormData+"; boundary="+testBoundary)
+	SetRequestValues(r, url.Values{"start": {"2"}})
+	b, _ = ioutil.ReadAll(r.Body)
+	if string(b) != "trickster" {
+		t.Errorf("expected %s got %s", "trickster", string(b))
+	}
+}
@@ -18,12 +18,17 @@ package options
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/tricksterproxy/trickster/pkg/cache/key"
 	"github.com/tricksterproxy/trickster/pkg/proxy/forwarding"
 	"github.com/tricksterproxy/trickster/pkg/proxy/methods"
 	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request/hooks"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter"
+	"github.com/tricksterproxy/trickster/pkg/proxy/tenancy"
+	"github.com/tricksterproxy/trickster/pkg/util/jsonpath"
 	"github.com/tricksterproxy/trickster/pkg/util/strings"
 	ts "github.com/tricksterproxy/trickster/pkg/util/strings"
 )
@@ -46,6 +51,20 @@ type Options struct {
 	// CacheKeyFormFields provides the list of http request body fields to be included
 	// in the hash for each request's cache key
 	CacheKeyFormFields []string `toml:"cache_key_form_fields"`
+	// CacheKeyJSONPaths provides the list of JSONPath expressions (e.g., $.queries[0].expr) whose
+	// values in the request body document are included in the hash for each request's cache key
+	CacheKeyJSONPaths []string `toml:"cache_key_json_paths"`
+	// CacheKeyPrincipal identifies the authenticated principal (e.g., 'header:X-User', 'basic' or
+	// 'jwt:sub') to be included in the hash for each request's cache key, so that responses that
+	// are filtered per-user can be cached per-user
+	CacheKeyPrincipal string `toml:"cache_key_principal"`
+	// CachePrivateResponses, when set to true, allows responses that are marked as
+	// Cache-Control: private to be cached for requests that have a CacheKeyPrincipal
+	CachePrivateResponses bool `toml:"cache_private_responses"`
+	// CacheKeyTemplate describes the composition of each request's cache key from its method,
+	// host, headers, query parameters and body fields, like '{method} {param:query}', and
+	// replaces the composition from CacheKeyParams, CacheKeyHeaders and CacheKeyFormFields
+	CacheKeyTemplate string `toml:"cache_key_template"`
 	// RequestHeaders is a map of headers that will be added to requests to the upstream Origin for this path
 	RequestHeaders map[string]string `toml:"request_headers"`
 	// RequestParams is a map of headers that will be added to requests to the upstream Origin for this path
@@ -61,6 +80,27 @@ type Options struct {
 	// ReqRewriterName is the name of a configured Rewriter that will modify the request prior to
 	// processing by the origin client
 	ReqRewriterName string `toml:"req_rewriter_name"`
+	// RequestHookName is the name of a registered hooks.RequestHook that will rewrite the request
+	// prior to processing by the origin client
+	RequestHookName string `toml:"request_hook_name"`
+	// ResponseHookName is the name of a registered hooks.ResponseHook that will rewrite the
+	// response prior to it being written to the downstream client
+	ResponseHookName string `toml:"response_hook_name"`
+	// BackfillToleranceSecs overrides the origin's backfill tolerance for timeseries requests to this path
+	BackfillToleranceSecs int64 `toml:"backfill_tolerance_secs"`
+	// MaxLookbackSecs overrides the origin's maximum lookback for timeseries requests to this path
+	MaxLookbackSecs int64 `toml:"max_lookback_secs"`
+	// MaxRangeSecs overrides the origin's maximum time range for timeseries requests to this path
+	MaxRangeSecs int64 `toml:"max_range_secs"`
+	// StreamIdleTimeoutSecs overrides the origin's timeout as the maximum duration, in seconds,
+	// that a streaming path waits for the response headers and for each chunk of the body
+	StreamIdleTimeoutSecs int `toml:"stream_idle_timeout_secs"`
+	// PushPaths provides the list of companion paths (e.g., /api/v1/labels) that are pushed to
+	// HTTP/2 clients when a timeseries response for this path is served from the cache
+	PushPaths []string `toml:"push_paths"`
+	// SecurityHeaders overrides the frontend's security header preset ('none' or 'standard')
+	// for responses to this path
+	SecurityHeaders string `toml:"security_headers"`
 
 	// Handler is the HTTP Handler represented by the Path's HandlerName
 	Handler http.Handler `toml:"-"`
@@ -74,16 +114,57 @@ type Options struct {
 	// NOTE: This is used by some origins like IronDB, but is not configurable by end users.
 	// Due to a bug in the vendored toml package, this must be a slice to avoid panic
 	KeyHasher []key.HasherFunc `toml:"-"`
+	// KeyNormalizers maps the names of CacheKeyParams to functions that normalize their values
+	// before they are added to the cache key, like query normalization for some origin types.
+	// NOTE: This is registered by origins, and is not configurable by end users.
+	KeyNormalizers map[string]key.NormalizerFunc `toml:"-"`
+	// ProtobufMessage is an optional message prototype used to decode application/x-protobuf
+	// request bodies for this path so their fields can be used in CacheKeyFormFields.
+	// NOTE: This is registered by origins, and is not configurable by end users.
+	ProtobufMessage proto.Message `toml:"-"`
+	// JSONPaths is the compiled representation of CacheKeyJSONPaths
+	JSONPaths []jsonpath.Path `toml:"-"`
+	// Principal is the parsed representation of CacheKeyPrincipal
+	Principal *tenancy.Source `toml:"-"`
+	// KeyTemplate is the parsed representation of CacheKeyTemplate
+	KeyTemplate *key.Template `toml:"-"`
+	// SecurityHeaderSet is the headers of the SecurityHeaders preset, which is nil when the
+	// path uses the frontend's preset
+	SecurityHeaderSet http.Header `toml:"-"`
 	// Custom is a compiled list of any custom settings for this path from the config file
 	Custom []string `toml:"-"`
 	// ReqRewriter is the rewriter handler as indicated by RuleName
 	ReqRewriter rewriter.RewriteInstructions
+	// RequestHook is the hook indicated by RequestHookName
+	// Due to a bug in the vendored toml package, this must be a slice to avoid panic
+	RequestHook []hooks.RequestHook `toml:"-"`
+	// ResponseHook is the hook indicated by ResponseHookName
+	// Due to a bug in the vendored toml package, this must be a slice to avoid panic
+	ResponseHook []hooks.ResponseHook `toml:"-"`
 
 	// NoMetrics, when set to true, disables metrics decoration for the path
 	NoMetrics bool `toml:"no_metrics"`
+	// Streaming, when set to true, indicates that responses for the path are streams, such as
+	// Server-Sent Events or long-polls, which are flushed to the client as they are received
+	// and are only limited by an idle timeout
+	Streaming bool `toml:"streaming"`
 	// HasCustomResponseBody is a boolean indicating if the response body is custom
 	// this flag allows an empty string response to be configured as a return value
 	HasCustomResponseBody bool `toml:"-"`
+	// BackfillTolerance is the time.Duration representation of BackfillToleranceSecs
+	BackfillTolerance time.Duration `toml:"-"`
+	// HasBackfillTolerance is a boolean indicating if the path overrides the origin's backfill tolerance
+	HasBackfillTolerance bool `toml:"-"`
+	// MaxLookback is the time.Duration representation of MaxLookbackSecs
+	MaxLookback time.Duration `toml:"-"`
+	// HasMaxLookback is a boolean indicating if the path overrides the origin's maximum lookback
+	HasMaxLookback bool `toml:"-"`
+	// MaxRange is the time.Duration representation of MaxRangeSecs
+	MaxRange time.Duration `toml:"-"`
+	// HasMaxRange is a boolean indicating if the path overrides the origin's maximum time range
+	HasMaxRange bool `toml:"-"`
+	// StreamIdleTimeout is the time.Duration representation of StreamIdleTimeoutSecs
+	StreamIdleTimeout time.Duration `toml:"-"`
 }
 
 // NewOptions returns a newly-instantiated *Options
@@ -99,6 +180,8 @@ func NewOptions() *Options {
 		CacheKeyParams:          make([]string, 0),
 		CacheKeyHeaders:         make([]string, 0),
 		CacheKeyFormFields:      make([]string, 0),
+		CacheKeyJSONPaths:       make([]string, 0),
+		PushPaths:               make([]string, 0),
 		Custom:                  make([]string, 0),
 		RequestHeaders:          make(map[string]string),
 		RequestParams:           make(map[string]string),
@@ -120,6 +203,10 @@ func (o *Options) Clone() *Options {
 		RequestParams:           ts.CloneMap(o.RequestParams),
 		ReqRewriter:             o.ReqRewriter,
 		ReqRewriterName:         o.ReqRewriterName,
+		RequestHook:             o.RequestHook,
+		RequestHookName:         o.RequestHookName,
+		ResponseHook:            o.ResponseHook,
+		ResponseHookName:        o.ResponseHookName,
 		ResponseHeaders:         ts.CloneMap(o.ResponseHeaders),
 		ResponseBody:            o.ResponseBody,
 		ResponseBodyBytes:       o.ResponseBodyBytes,
@@ -127,17 +214,44 @@ func (o *Options) Clone() *Options {
 		CollapsedForwardingType: o.CollapsedForwardingType,
 		NoMetrics:               o.NoMetrics,
 		HasCustomResponseBody:   o.HasCustomResponseBody,
+		BackfillToleranceSecs:   o.BackfillToleranceSecs,
+		BackfillTolerance:       o.BackfillTolerance,
+		HasBackfillTolerance:    o.HasBackfillTolerance,
+		MaxLookbackSecs:         o.MaxLookbackSecs,
+		MaxLookback:             o.MaxLookback,
+		HasMaxLookback:          o.HasMaxLookback,
+		MaxRangeSecs:            o.MaxRangeSecs,
+		MaxRange:                o.MaxRange,
+		HasMaxRange:             o.HasMaxRange,
+		Streaming:               o.Streaming,
+		StreamIdleTimeoutSecs:   o.StreamIdleTimeoutSecs,
+		StreamIdleTimeout:       o.StreamIdleTimeout,
 		Methods:                 make([]string, len(o.Methods)),
 		CacheKeyParams:          make([]string, len(o.CacheKeyParams)),
 		CacheKeyHeaders:         make([]string, len(o.CacheKeyHeaders)),
 		CacheKeyFormFields:      make([]string, len(o.CacheKeyFormFields)),
+		CacheKeyJSONPaths:       make([]string, len(o.CacheKeyJSONPaths)),
+		PushPaths:               make([]string, len(o.PushPaths)),
+		CacheKeyPrincipal:       o.CacheKeyPrincipal,
+		Principal:               o.Principal,
+		CachePrivateResponses:   o.CachePrivateResponses,
+		CacheKeyTemplate:        o.CacheKeyTemplate,
+		KeyTemplate:             o.KeyTemplate,
+		SecurityHeaders:         o.SecurityHeaders,
+		SecurityHeaderSet:       o.SecurityHeaderSet,
+		JSONPaths:               make([]jsonpath.Path, len(o.JSONPaths)),
 		Custom:                  make([]string, len(o.Custom)),
 		KeyHasher:               o.KeyHasher,
+		KeyNormalizers:          o.KeyNormalizers,
+		ProtobufMessage:         o.ProtobufMessage,
 	}
 	copy(c.Methods, o.Methods)
 	copy(c.CacheKeyParams, o.CacheKeyParams)
 	copy(c.CacheKeyHeaders, o.CacheKeyHeaders)
 	copy(c.CacheKeyFormFields, o.CacheKeyFormFields)
+	copy(c.CacheKeyJSONPaths, o.CacheKeyJSONPaths)
+	copy(c.PushPaths, o.PushPaths)
+	copy(c.JSONPaths, o.JSONPaths)
 	copy(c.Custom, o.Custom)
 	return c
 }
@@ -166,6 +280,17 @@ func (o *Options) Merge(o2 *Options) {
 			o.CacheKeyHeaders = o2.CacheKeyHeaders
 		case "cache_key_form_fields":
 			o.CacheKeyFormFields = o2.CacheKeyFormFields
+		case "cache_key_json_paths":
+			o.CacheKeyJSONPaths = o2.CacheKeyJSONPaths
+			o.JSONPaths = o2.JSONPaths
+		case "cache_key_principal":
+			o.CacheKeyPrincipal = o2.CacheKeyPrincipal
+			o.Principal = o2.Principal
+		case "cache_private_responses":
+			o.CachePrivateResponses = o2.CachePrivateResponses
+		case "cache_key_template":
+			o.CacheKeyTemplate = o2.CacheKeyTemplate
+			o.KeyTemplate = o2.KeyTemplate
 		case "request_headers":
 			o.RequestHeaders = o2.RequestHeaders
 		case "request_params":
@@ -180,12 +305,40 @@ func (o *Options) Merge(o2 *Options) {
 			o.ResponseBodyBytes = o2.ResponseBodyBytes
 		case "no_metrics":
 			o.NoMetrics = o2.NoMetrics
+		case "security_headers":
+			o.SecurityHeaders = o2.SecurityHeaders
+			o.SecurityHeaderSet = o2.SecurityHeaderSet
 		case "collapsed_forwarding":
 			o.CollapsedForwardingName = o2.CollapsedForwardingName
 			o.CollapsedForwardingType = o2.CollapsedForwardingType
 		case "req_rewriter_name":
 			o.ReqRewriterName = o2.ReqRewriterName
 			o.ReqRewriter = o2.ReqRewriter
+		case "request_hook_name":
+			o.RequestHookName = o2.RequestHookName
+			o.RequestHook = o2.RequestHook
+		case "response_hook_name":
+			o.ResponseHookName = o2.ResponseHookName
+			o.ResponseHook = o2.ResponseHook
+		case "streaming":
+			o.Streaming = o2.Streaming
+		case "push_paths":
+			o.PushPaths = o2.PushPaths
+		case "stream_idle_timeout_secs":
+			o.StreamIdleTimeoutSecs = o2.StreamIdleTimeoutSecs
+			o.StreamIdleTimeout = o2.StreamIdleTimeout
+		case "backfill_tolerance_secs":
+			o.BackfillToleranceSecs = o2.BackfillToleranceSecs
+			o.BackfillTolerance = o2.BackfillTolerance
+			o.HasBackfillTolerance = true
+		case "max_lookback_secs":
+			o.MaxLookbackSecs = o2.MaxLookbackSecs
+			o.MaxLookback = o2.MaxLookback
+			o.HasMaxLookback = true
+		case "max_range_secs":
+			o.MaxRangeSecs = o2.MaxRangeSecs
+			o.MaxRange = o2.MaxRange
+			o.HasMaxRange = true
 		}
 	}
 	o.Custom = strings.Unique(o.Custom)
@@ -19,9 +19,11 @@ package options
 import (
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/proxy/forwarding"
 	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
+	"github.com/tricksterproxy/trickster/pkg/util/jsonpath"
 )
 
 func TestNewOptions(t *testing.T) {
@@ -61,7 +63,9 @@ func TestPathMerge(t *testing.T) {
 	pc2.Custom = []string{"path", "match_type", "handler", "methods",
 		"cache_key_params", "cache_key_headers", "cache_key_form_fields",
 		"request_headers", "request_params", "response_headers",
-		"response_code", "response_body", "no_metrics", "collapsed_forwarding"}
+		"response_code", "response_body", "no_metrics", "collapsed_forwarding",
+		"backfill_tolerance_secs", "cache_key_json_paths", "streaming",
+		"stream_idle_timeout_secs", "push_paths"}
 
 	expectedPath := "testPath"
 	expectedHandlerName := "testHandler"
@@ -73,6 +77,8 @@ func TestPathMerge(t *testing.T) {
 	pc2.CacheKeyParams = []string{"params"}
 	pc2.CacheKeyHeaders = []string{"headers"}
 	pc2.CacheKeyFormFields = []string{"fields"}
+	pc2.CacheKeyJSONPaths = []string{"$.range.from"}
+	pc2.JSONPaths = make([]jsonpath.Path, 1)
 	pc2.RequestHeaders = map[string]string{"header1": "1"}
 	pc2.RequestParams = map[string]string{"param1": "foo"}
 	pc2.ResponseHeaders = map[string]string{"header2": "2"}
@@ -81,6 +87,12 @@ func TestPathMerge(t *testing.T) {
 	pc2.NoMetrics = true
 	pc2.CollapsedForwardingName = "progressive"
 	pc2.CollapsedForwardingType = forwarding.CFTypeProgressive
+	pc2.BackfillToleranceSecs = 300
+	pc2.BackfillTolerance = 5 * time.Minute
+	pc2.Streaming = true
+	pc2.StreamIdleTimeoutSecs = 600
+	pc2.StreamIdleTimeout = 10 * time.Minute
+	pc2.PushPaths = []string{"/api/v1/labels"}
 
 	pc.Merge(pc2)
 
@@ -108,6 +120,10 @@ func TestPathMerge(t *testing.T) {
 		t.Errorf("expected %d got %d", 1, len(pc.CacheKeyFormFields))
 	}
 
+	if len(pc.CacheKeyJSONPaths) != 1 || len(pc.JSONPaths) != 1 {
+		t.Errorf("expected %d got %d", 1, len(pc.JSONPaths))
+	}
+
 	if len(pc.RequestHeaders) != 1 {
 		t.Errorf("expected %d got %d", 1, len(pc.RequestHeaders))
 	}
@@ -141,6 +157,19 @@ func TestPathMerge(t *testing.T) {
 		t.Errorf("expected %s got %s", "progressive", pc.CollapsedForwardingName)
 	}
 
+	if !pc.HasBackfillTolerance || pc.BackfillTolerance != 5*time.Minute {
+		t.Errorf("expected %s got %s", 5*time.Minute, pc.BackfillTolerance)
+	}
+
+	if !pc.Streaming || pc.StreamIdleTimeout != 10*time.Minute {
+		t.Errorf("expected streaming with idle timeout %s got %t %s", 10*time.Minute,
+			pc.Streaming, pc.StreamIdleTimeout)
+	}
+
+	if len(pc.PushPaths) != 1 || pc.Clone().PushPaths[0] != "/api/v1/labels" {
+		t.Errorf("expected push paths %v got %v", pc2.PushPaths, pc.PushPaths)
+	}
+
 }
 
 func TestMerge(t *testing.T) {
@@ -0,0 +1,62 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options provides options for the priority classes of requests to an origin
+package options
+
+import (
+	"time"
+
+	ts "github.com/tricksterproxy/trickster/pkg/util/strings"
+)
+
+// Options is a priority class, which admits requests to an origin within its own
+// concurrency budget, so that requests in other classes can not starve them
+type Options struct {
+	// Paths is a list of path prefixes whose requests belong to the class, unless they
+	// are tagged with another class by the origin's priority header
+	Paths []string `toml:"paths"`
+	// MaxConcurrency is the number of the class's requests that are handled concurrently
+	MaxConcurrency int `toml:"max_concurrency"`
+	// MaxQueueLength is the number of the class's requests that wait for a turn once
+	// MaxConcurrency is reached, beyond which requests are rejected. 0 is unlimited
+	MaxQueueLength int `toml:"max_queue_length"`
+	// QueueTimeoutMS is the duration, in milliseconds, that a request waits for a turn
+	// before it is rejected
+	QueueTimeoutMS int `toml:"queue_timeout_ms"`
+
+	// Name is the name of the class
+	Name string `toml:"-"`
+	// QueueTimeout is the parsed value of QueueTimeoutMS
+	QueueTimeout time.Duration `toml:"-"`
+}
+
+// NewOptions returns a new Options reference with Default Values set
+func NewOptions() *Options {
+	return &Options{}
+}
+
+// Clone returns an exact copy of the subject *Options
+func (o *Options) Clone() *Options {
+	return &Options{
+		Paths:          ts.CloneList(o.Paths),
+		MaxConcurrency: o.MaxConcurrency,
+		MaxQueueLength: o.MaxQueueLength,
+		QueueTimeoutMS: o.QueueTimeoutMS,
+		Name:           o.Name,
+		QueueTimeout:   o.QueueTimeout,
+	}
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package priority provides admission control for the requests to an origin, which are
+// tagged by header or path into priority classes that each have a separate concurrency
+// budget, so that bulk requests can not starve interactive ones
+package priority
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/priority/options"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+)
+
+// ErrQueueFull is returned when a request's priority class has no room in its queue
+var ErrQueueFull = errors.New("priority class queue is full")
+
+// ErrQueueTimeout is returned when a request does not get a turn in its priority
+// class before the class's queue timeout
+var ErrQueueTimeout = errors.New("priority class queue timed out")
+
+// class is a priority class and its concurrency budget
+type class struct {
+	opts   *options.Options
+	slots  chan struct{}
+	queued int32
+}
+
+// pathClass maps a path prefix to its priority class
+type pathClass struct {
+	prefix string
+	class  *class
+}
+
+// Controller admits the requests to an origin within the concurrency budgets of
+// their priority classes
+type Controller struct {
+	originName   string
+	header       string
+	classes      map[string]*class
+	paths        []pathClass
+	defaultClass *class
+}
+
+// NewController returns a Controller for the origin's priority classes, or nil if the
+// origin has none. A request is tagged with the class named by its header, or else the
+// class with the longest path prefix that matches it, or else the default class. A
+// request that is not tagged with any class is not subject to admission control
+func NewController(originName, header, defaultClass string,
+	classes map[string]*options.Options) *Controller {
+	if len(classes) == 0 {
+		return nil
+	}
+	c := &Controller{
+		originName: originName,
+		header:     header,
+		classes:    make(map[string]*class, len(classes)),
+	}
+	for name, o := range classes {
+		cl := &class{opts: o, slots: make(chan struct{}, o.MaxConcurrency)}
+		c.classes[name] = cl
+		for _, p := range o.Paths {
+			c.paths = append(c.paths, pathClass{prefix: p, class: cl})
+		}
+	}
+	sort.Slice(c.paths, func(i, j int) bool {
+		if len(c.paths[i].prefix) == len(c.paths[j].prefix) {
+			return c.paths[i].prefix < c.paths[j].prefix
+		}
+		return len(c.paths[i].prefix) > len(c.paths[j].prefix)
+	})
+	c.defaultClass = c.classes[defaultClass]
+	return c
+}
+
+// classify returns the priority class of the request, or nil if it has none
+func (c *Controller) classify(r *http.Request) *class {
+	if c.header != "" {
+		if cl, ok := c.classes[r.Header.Get(c.header)]; ok {
+			return cl
+		}
+	}
+	for _, pc := range c.paths {
+		if strings.HasPrefix(r.URL.Path, pc.prefix) {
+			return pc.class
+		}
+	}
+	return c.defaultClass
+}
+
+// acquire waits for a turn in the class, until the class's queue timeout or the
+// context is done
+func (c *Controller) acquire(ctx context.Context, cl *class) error {
+	select {
+	case cl.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	q := atomic.AddInt32(&cl.queued, 1)
+	defer atomic.AddInt32(&cl.queued, -1)
+	if cl.opts.MaxQueueLength > 0 && int(q) > cl.opts.MaxQueueLength {
+		return ErrQueueFull
+	}
+	queued := metrics.ProxyPriorityQueued.WithLabelValues(c.originName, cl.opts.Name)
+	queued.Inc()
+	defer queued.Dec()
+	t := time.NewTimer(cl.opts.QueueTimeout)
+	defer t.Stop()
+	select {
+	case cl.slots <- struct{}{}:
+		return nil
+	case <-t.C:
+		return ErrQueueTimeout
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Handler returns a handler that admits each request to next within the concurrency
+// budget of its priority class. Requests that are rejected by their class receive a
+// 503 Service Unavailable response. A nil Controller returns next
+func (c *Controller) Handler(next http.Handler) http.Handler {
+	if c == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cl := c.classify(r)
+		if cl == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err := c.acquire(r.Context(), cl); err != nil {
+			if err != ErrQueueFull && err != ErrQueueTimeout {
+				// the client is gone
+				return
+			}
+			reason := "queue_full"
+			if err == ErrQueueTimeout {
+				reason = "queue_timeout"
+			}
+			metrics.ProxyPriorityRejected.WithLabelValues(c.originName, cl.opts.Name, reason).Inc()
+			w.Header().Set(headers.NameRetryAfter,
+				strconv.Itoa(int(cl.opts.QueueTimeout.Seconds())+1))
+			http.Error(w, err.Error()+": "+cl.opts.Name, http.StatusServiceUnavailable)
+			return
+		}
+		active := metrics.ProxyPriorityActive.WithLabelValues(c.originName, cl.opts.Name)
+		active.Inc()
+		defer func() {
+			active.Dec()
+			<-cl.slots
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
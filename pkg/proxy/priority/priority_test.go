@@ -0,0 +1,170 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package priority
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/priority/options"
+)
+
+func testClasses() map[string]*options.Options {
+	return map[string]*options.Options{
+		"interactive": {Name: "interactive", MaxConcurrency: 2, QueueTimeout: time.Second},
+		"bulk": {Name: "bulk", Paths: []string{"/api/v1/export", "/api/v1/export/series"},
+			MaxConcurrency: 1, MaxQueueLength: 1, QueueTimeout: 50 * time.Millisecond},
+		"series": {Name: "series", Paths: []string{"/api/v1/export/series/"},
+			MaxConcurrency: 1, QueueTimeout: time.Second},
+	}
+}
+
+func TestNewController(t *testing.T) {
+	if c := NewController("test", "X-Priority", "", nil); c != nil {
+		t.Error("expected nil controller")
+	}
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	var c *Controller
+	if c.Handler(h) == nil {
+		t.Error("expected next handler")
+	}
+}
+
+func TestClassify(t *testing.T) {
+
+	c := NewController("test", "X-Priority", "interactive", testClasses())
+
+	tests := []struct {
+		path, header, expected string
+	}{
+		{"/api/v1/query_range", "", "interactive"},
+		{"/api/v1/export", "", "bulk"},
+		{"/api/v1/export/series/1", "", "series"},
+		{"/api/v1/export", "interactive", "interactive"},
+		{"/api/v1/query_range", "bulk", "bulk"},
+		{"/api/v1/query_range", "unknown", "interactive"},
+	}
+
+	for i, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.header != "" {
+			r.Header.Set("X-Priority", test.header)
+		}
+		cl := c.classify(r)
+		if cl == nil || cl.opts.Name != test.expected {
+			t.Errorf("(%d) expected %s got %v", i, test.expected, cl)
+		}
+	}
+
+	// without a default class, untagged requests have no class
+	c = NewController("test", "", "", testClasses())
+	if cl := c.classify(httptest.NewRequest(http.MethodGet, "/api/v1/query_range", nil)); cl != nil {
+		t.Errorf("expected nil class got %s", cl.opts.Name)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/query_range", nil)
+	r.Header.Set("X-Priority", "bulk")
+	if cl := c.classify(r); cl != nil {
+		t.Errorf("expected nil class got %s", cl.opts.Name)
+	}
+}
+
+func TestHandler(t *testing.T) {
+
+	c := NewController("test", "X-Priority", "interactive", testClasses())
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+
+	serve := func(path string) chan int {
+		ch := make(chan int, 1)
+		go func() {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			ch <- w.Code
+		}()
+		return ch
+	}
+
+	// the bulk class's single request is handled
+	b1 := serve("/api/v1/export")
+	<-started
+
+	// the next bulk request queues, and the one after it finds the queue full
+	b2 := serve("/api/v1/export")
+	time.Sleep(10 * time.Millisecond)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/export", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if w.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected %s got %s", "1", w.Header().Get("Retry-After"))
+	}
+
+	// the queued bulk request times out
+	if code := <-b2; code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d got %d", http.StatusServiceUnavailable, code)
+	}
+
+	// while interactive requests are handled within their own budget
+	i1 := serve("/api/v1/query_range")
+	i2 := serve("/api/v1/query_range")
+	<-started
+	<-started
+
+	close(release)
+	for _, ch := range []chan int{b1, i1, i2} {
+		if code := <-ch; code != http.StatusOK {
+			t.Errorf("expected %d got %d", http.StatusOK, code)
+		}
+	}
+}
+
+func TestHandlerCanceled(t *testing.T) {
+
+	classes := testClasses()
+	classes["bulk"].QueueTimeout = time.Minute
+	c := NewController("test", "", "", classes)
+	cl := c.classes["bulk"]
+	cl.slots <- struct{}{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.acquire(ctx, cl); err != context.Canceled {
+		t.Errorf("expected %v got %v", context.Canceled, err)
+	}
+
+	var called bool
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/export", nil).WithContext(ctx))
+	if called {
+		t.Error("expected request to not be handled")
+	}
+	if w.Body.Len() > 0 {
+		t.Error("expected no response to a canceled request")
+	}
+}
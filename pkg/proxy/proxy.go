@@ -26,7 +26,14 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/tricksterproxy/trickster/pkg/proxy/auth"
+	"github.com/tricksterproxy/trickster/pkg/proxy/concurrency"
+	"github.com/tricksterproxy/trickster/pkg/proxy/deadline"
+	"github.com/tricksterproxy/trickster/pkg/proxy/hedging"
 	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/resolver"
+	"github.com/tricksterproxy/trickster/pkg/proxy/targets"
+	"github.com/tricksterproxy/trickster/pkg/proxy/tls/spiffe"
 )
 
 // NewHTTPClient returns an HTTP client configured to the specifications of the
@@ -77,17 +84,61 @@ func NewHTTPClient(oc *oo.Options) (*http.Client, error) {
 		}
 	}
 
+	// the origin's SPIFFE identity takes the place of its TLS client options
+	if oc.SpiffeUpstream {
+		TLSConfig = spiffe.ClientTLSConfig(oc.SpiffeUpstreamIDs)
+	}
+
+	dialer := &net.Dialer{KeepAlive: time.Duration(oc.KeepAliveTimeoutSecs) * time.Second}
+	dial := dialer.DialContext
+	// origin hostnames may be pinned to static addresses or resolved by a custom DNS server,
+	// while the hostname is still used for the Host header and TLS server name
+	if len(oc.StaticHosts) > 0 || oc.DNSResolver != "" {
+		dial = resolver.NewDialer(dialer, oc.StaticHosts, oc.DNSResolver).DialContext
+	}
+
+	var transport http.RoundTripper = &http.Transport{
+		DialContext:         dial,
+		MaxIdleConns:        oc.MaxIdleConns,
+		MaxIdleConnsPerHost: oc.MaxIdleConns,
+		TLSClientConfig:     TLSConfig,
+	}
+
+	// the time remaining before each request to the origin is abandoned is sent with it,
+	// including with hedges, which are sent later and so have less time remaining
+	if oc.DeadlineHeader != "" {
+		transport = deadline.NewTransport(transport, oc.DeadlineHeader)
+	}
+
+	// requests that are slow to the origin are hedged to its replicas, when configured
+	if len(oc.HedgeTargets) > 0 {
+		transport = hedging.NewTransport(transport, oc.PathPrefix, oc.HedgeTargets,
+			oc.HedgeQuantile, oc.HedgeMinDelay)
+	}
+
+	// requests are sent to the origin's closest healthy target, when it has several; a
+	// hedged request is sent to the selected target first
+	if oc.TargetSelector != nil {
+		transport = targets.NewTransport(transport, oc.PathPrefix, oc.TargetSelector)
+	}
+
+	// the origin's credentials are attached to every upstream request, including hedges
+	if oc.Auth != nil {
+		transport = auth.NewTransport(transport, oc.Auth, oc.Timeout)
+	}
+
+	// requests beyond the origin's adaptive concurrency limit are shed before they are sent
+	if oc.AdaptiveConcurrency != nil {
+		transport = concurrency.NewTransport(transport,
+			concurrency.NewLimiter(oc.Name, oc.AdaptiveConcurrency))
+	}
+
 	return &http.Client{
 		Timeout: oc.Timeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
-		Transport: &http.Transport{
-			Dial:                (&net.Dialer{KeepAlive: time.Duration(oc.KeepAliveTimeoutSecs) * time.Second}).Dial,
-			MaxIdleConns:        oc.MaxIdleConns,
-			MaxIdleConnsPerHost: oc.MaxIdleConns,
-			TLSClientConfig:     TLSConfig,
-		},
+		Transport: transport,
 	}, nil
 
 }
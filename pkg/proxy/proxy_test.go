@@ -17,8 +17,16 @@
 package proxy
 
 import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
+	"github.com/tricksterproxy/trickster/pkg/proxy/auth"
+	ao "github.com/tricksterproxy/trickster/pkg/proxy/auth/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/hedging"
 	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 )
 
@@ -81,3 +89,72 @@ func TestNewHTTPClient(t *testing.T) {
 		t.Errorf("failed to find any PEM data in key input for file %s", oc.TLS.ClientKeyPath)
 	}
 }
+
+func TestNewHTTPClientHedging(t *testing.T) {
+
+	oc := oo.NewOptions()
+	c, err := NewHTTPClient(oc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Transport.(*http.Transport); !ok {
+		t.Errorf("expected %s got %T", "*http.Transport", c.Transport)
+	}
+
+	oc.HedgeTargets = []*url.URL{{Scheme: "http", Host: "replica"}}
+	c, err = NewHTTPClient(oc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Transport.(*hedging.Transport); !ok {
+		t.Errorf("expected %s got %T", "*hedging.Transport", c.Transport)
+	}
+
+	oc.Auth = &ao.Options{Token: "abc"}
+	c, err = NewHTTPClient(oc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Transport.(*auth.Transport); !ok {
+		t.Errorf("expected %s got %T", "*auth.Transport", c.Transport)
+	}
+}
+
+func TestNewHTTPClientStaticHosts(t *testing.T) {
+
+	var serverName string
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	ts.TLS = &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverName = hello.ServerName
+			return nil, nil
+		},
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	_, port, _ := net.SplitHostPort(u.Host)
+
+	oc := oo.NewOptions()
+	oc.TLS.InsecureSkipVerify = true
+	oc.StaticHosts = map[string][]string{"origin.example": {"127.0.0.1"}}
+	c, err := NewHTTPClient(oc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := c.Get("https://origin.example:" + port + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected %d got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	if serverName != "origin.example" {
+		t.Errorf("expected server name %s got %s", "origin.example", serverName)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package push provides HTTP/2 server push of the companion responses that a client
+// is expected to request after a response, such as the label and metadata responses
+// that a dashboard requests after its initial queries
+package push
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RepushInterval is the duration after which a path that was pushed on a connection
+// may be pushed on the connection again
+const RepushInterval = time.Minute
+
+// maxPushedPaths limits the number of pushed paths remembered for each connection
+const maxPushedPaths = 256
+
+type contextKey int
+
+const (
+	connKey contextKey = iota
+	pusherKey
+)
+
+// pushedPaths records when paths were last pushed on a connection
+type pushedPaths struct {
+	mtx   sync.Mutex
+	paths map[string]time.Time
+}
+
+// claim returns true if the path has not been pushed on the connection within the
+// RepushInterval, and records it as pushed
+func (pp *pushedPaths) claim(path string, now time.Time) bool {
+	pp.mtx.Lock()
+	defer pp.mtx.Unlock()
+	if t, ok := pp.paths[path]; ok && now.Sub(t) < RepushInterval {
+		return false
+	}
+	if len(pp.paths) >= maxPushedPaths {
+		for k, t := range pp.paths {
+			if now.Sub(t) >= RepushInterval {
+				delete(pp.paths, k)
+			}
+		}
+		if len(pp.paths) >= maxPushedPaths {
+			return false
+		}
+	}
+	pp.paths[path] = now
+	return true
+}
+
+// ConnContext is an http.Server ConnContext func that attaches a record of the
+// paths pushed on each connection to the connection's context
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connKey, &pushedPaths{paths: make(map[string]time.Time)})
+}
+
+// Handler makes the http.Pusher of each HTTP/2 request available to Push, before next
+// or its middleware wraps the ResponseWriter with one that does not implement it
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p, ok := w.(http.Pusher); ok && r.ProtoMajor == 2 {
+			r = r.WithContext(context.WithValue(r.Context(), pusherKey, p))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// promiseHeaders are the request headers that may not be included in a push promise
+var promiseHeaders = map[string]bool{
+	"Content-Length":   true,
+	"Content-Encoding": true,
+	"Trailer":          true,
+	"Te":               true,
+	"Expect":           true,
+	"Host":             true,
+}
+
+// Push pushes each of the paths to the client of r, unless it was already pushed on the
+// client's connection within the RepushInterval. Paths are relative to routePath, the
+// configured path that matched r, so that they keep any prefix that r was routed by
+// (e.g., /origin-name/). The pushed requests carry r's headers, so that they are
+// authorized and cached like r. Push returns the number of paths that were pushed
+func Push(r *http.Request, routePath string, paths []string) int {
+	if len(paths) == 0 {
+		return 0
+	}
+	p, ok := r.Context().Value(pusherKey).(http.Pusher)
+	if !ok {
+		return 0
+	}
+	pp, _ := r.Context().Value(connKey).(*pushedPaths)
+
+	var prefix string
+	if routePath != "" && routePath != "/" {
+		if i := strings.Index(r.URL.Path, routePath); i > 0 {
+			prefix = r.URL.Path[:i]
+		}
+	}
+
+	h := make(http.Header, len(r.Header))
+	for k, v := range r.Header {
+		if !promiseHeaders[k] {
+			h[k] = v
+		}
+	}
+
+	var n int
+	now := time.Now()
+	for _, path := range paths {
+		target := prefix + path
+		if pp != nil && !pp.claim(target, now) {
+			continue
+		}
+		// a failure means the client has disabled push or the connection is closing,
+		// in which case the remaining paths would fail as well
+		if err := p.Push(target, &http.PushOptions{Header: h}); err != nil {
+			break
+		}
+		n++
+	}
+	return n
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package push
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testPusher struct {
+	*httptest.ResponseRecorder
+	pushed  []string
+	headers []http.Header
+	err     error
+}
+
+func (p *testPusher) Push(target string, opts *http.PushOptions) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.pushed = append(p.pushed, target)
+	p.headers = append(p.headers, opts.Header)
+	return nil
+}
+
+// serve serves r through the Handler on the connection's context, and pushes the paths
+func serve(ctx context.Context, w http.ResponseWriter, path string, protoMajor int,
+	routePath string, paths []string) int {
+	var n int
+	r := httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx)
+	r.ProtoMajor = protoMajor
+	r.Header.Set("Authorization", "Bearer token")
+	r.Header.Set("Expect", "100-continue")
+	Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n = Push(r, routePath, paths)
+	})).ServeHTTP(w, r)
+	return n
+}
+
+func TestPush(t *testing.T) {
+
+	paths := []string{"/api/v1/labels", "/api/v1/metadata"}
+	ctx := ConnContext(context.Background(), nil)
+
+	w := &testPusher{ResponseRecorder: httptest.NewRecorder()}
+	n := serve(ctx, w, "/prom1/api/v1/query_range?query=up", 2, "/api/v1/query_range", paths)
+	if n != 2 {
+		t.Errorf("expected %d got %d", 2, n)
+	}
+	if len(w.pushed) != 2 || w.pushed[0] != "/prom1/api/v1/labels" ||
+		w.pushed[1] != "/prom1/api/v1/metadata" {
+		t.Errorf("unexpected pushed paths %v", w.pushed)
+	}
+	if len(w.headers) > 0 {
+		if w.headers[0].Get("Authorization") != "Bearer token" {
+			t.Errorf("expected %s got %s", "Bearer token", w.headers[0].Get("Authorization"))
+		}
+		if _, ok := w.headers[0]["Expect"]; ok {
+			t.Error("expected Expect header to be removed")
+		}
+	}
+
+	// paths that were pushed on the connection are not pushed again
+	n = serve(ctx, w, "/prom1/api/v1/query_range?query=up", 2, "/api/v1/query_range", paths)
+	if n != 0 {
+		t.Errorf("expected %d got %d", 0, n)
+	}
+
+	// but are pushed on other connections
+	w = &testPusher{ResponseRecorder: httptest.NewRecorder()}
+	n = serve(ConnContext(context.Background(), nil), w, "/api/v1/query_range", 2,
+		"/api/v1/query_range", paths)
+	if n != 2 || w.pushed[0] != "/api/v1/labels" {
+		t.Errorf("unexpected pushed paths %v", w.pushed)
+	}
+
+	// HTTP/1 requests are not pushed to
+	w = &testPusher{ResponseRecorder: httptest.NewRecorder()}
+	if n = serve(context.Background(), w, "/api/v1/query_range", 1,
+		"/api/v1/query_range", paths); n != 0 {
+		t.Errorf("expected %d got %d", 0, n)
+	}
+
+	// nor are clients that have disabled push
+	w = &testPusher{ResponseRecorder: httptest.NewRecorder(), err: http.ErrNotSupported}
+	if n = serve(context.Background(), w, "/api/v1/query_range", 2,
+		"/api/v1/query_range", paths); n != 0 {
+		t.Errorf("expected %d got %d", 0, n)
+	}
+
+	// nor ResponseWriters that are not Pushers
+	if n = serve(context.Background(), httptest.NewRecorder(), "/api/v1/query_range", 2,
+		"/api/v1/query_range", paths); n != 0 {
+		t.Errorf("expected %d got %d", 0, n)
+	}
+}
+
+func TestClaim(t *testing.T) {
+	pp := &pushedPaths{paths: make(map[string]time.Time)}
+	now := time.Now()
+	if !pp.claim("/a", now) {
+		t.Error("expected path to be claimed")
+	}
+	if pp.claim("/a", now.Add(RepushInterval/2)) {
+		t.Error("expected path to not be claimed within the repush interval")
+	}
+	if !pp.claim("/a", now.Add(RepushInterval)) {
+		t.Error("expected path to be claimed after the repush interval")
+	}
+
+	for i := 0; len(pp.paths) < maxPushedPaths; i++ {
+		pp.claim(string(rune('b'+i)), now)
+	}
+	if pp.claim("/z", now) {
+		t.Error("expected path to not be claimed when the connection's limit is reached")
+	}
+	if !pp.claim("/z", now.Add(RepushInterval*2)) {
+		t.Error("expected expired paths to be pruned")
+	}
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package hooks provides a registry of named functions that rewrite client requests and
+// the responses to them, which embedders of Trickster can register and paths can select
+// by name with their request_hook_name and response_hook_name options
+package hooks
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+// RequestHook rewrites a client request, including its URL, headers and body, before it
+// is handled. Since the request is rewritten before it is proxied or cached, its cache key
+// reflects the rewritten request. A non-nil error rejects the request with 400 Bad Request.
+// The params.GetRequestValues and params.SetRequestValues functions rewrite the request's
+// parameters wherever they are, for example to inject a label into every query
+type RequestHook func(r *http.Request) error
+
+// ResponseHook returns the rewritten body of a response to the client request r. The
+// headers can also be modified; Content-Length is set to the length of the returned body.
+// A non-nil error replaces the response with 502 Bad Gateway
+type ResponseHook func(r *http.Request, statusCode int, h http.Header, body []byte) ([]byte, error)
+
+var mtx sync.RWMutex
+var requestHooks = make(map[string]RequestHook)
+var responseHooks = make(map[string]ResponseHook)
+
+// RegisterRequestHook registers the RequestHook under the provided name, replacing any
+// hook already registered with that name. Hooks must be registered before the
+// configuration referencing them is loaded
+func RegisterRequestHook(name string, h RequestHook) {
+	mtx.Lock()
+	requestHooks[name] = h
+	mtx.Unlock()
+}
+
+// RegisterResponseHook registers the ResponseHook under the provided name, replacing any
+// hook already registered with that name. Hooks must be registered before the
+// configuration referencing them is loaded
+func RegisterResponseHook(name string, h ResponseHook) {
+	mtx.Lock()
+	responseHooks[name] = h
+	mtx.Unlock()
+}
+
+// GetRequestHook returns the RequestHook registered under the provided name
+func GetRequestHook(name string) (RequestHook, bool) {
+	mtx.RLock()
+	defer mtx.RUnlock()
+	h, ok := requestHooks[name]
+	return h, ok
+}
+
+// GetResponseHook returns the ResponseHook registered under the provided name
+func GetResponseHook(name string) (ResponseHook, bool) {
+	mtx.RLock()
+	defer mtx.RUnlock()
+	h, ok := responseHooks[name]
+	return h, ok
+}
+
+// RequestHookNames returns the sorted names of the registered RequestHooks
+func RequestHookNames() []string {
+	mtx.RLock()
+	names := make([]string, 0, len(requestHooks))
+	for k := range requestHooks {
+		names = append(names, k)
+	}
+	mtx.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// ResponseHookNames returns the sorted names of the registered ResponseHooks
+func ResponseHookNames() []string {
+	mtx.RLock()
+	names := make([]string, 0, len(responseHooks))
+	for k := range responseHooks {
+		names = append(names, k)
+	}
+	mtx.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// RewriteRequest returns a handler that rewrites the request with the RequestHook and
+// passes it to the next Handler
+func RewriteRequest(h RequestHook, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := h(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RewriteResponse returns a handler that buffers the response written by the next
+// Handler, and writes it to the client once it has been rewritten with the ResponseHook
+func RewriteResponse(h ResponseHook, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bw := &bufferedWriter{ResponseWriter: w}
+		next.ServeHTTP(bw, r)
+		if bw.statusCode == 0 {
+			bw.statusCode = http.StatusOK
+		}
+		body, err := h(r, bw.statusCode, w.Header(), bw.buf.Bytes())
+		if err != nil {
+			w.Header().Del(headers.NameContentLength)
+			w.Header().Del(headers.NameContentEncoding)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set(headers.NameContentLength, strconv.Itoa(len(body)))
+		w.WriteHeader(bw.statusCode)
+		w.Write(body)
+	})
+}
+
+// bufferedWriter holds the status code and body written to it, so they can be rewritten
+// before they are written to the wrapped ResponseWriter
+type bufferedWriter struct {
+	http.ResponseWriter
+	statusCode int
+	buf        bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	return w.buf.Write(b)
+}
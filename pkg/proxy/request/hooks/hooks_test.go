@@ -0,0 +1,161 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hooks
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/params"
+)
+
+// injectTenant is an example RequestHook that enforces a tenant label on each query
+func injectTenant(r *http.Request) error {
+	tenant := r.Header.Get("X-Tenant")
+	if tenant == "" {
+		return errors.New("missing tenant")
+	}
+	v := params.GetRequestValues(r)
+	q := v.Get("query")
+	v.Set("query", strings.Replace(q, "{", `{tenant="`+tenant+`",`, 1))
+	params.SetRequestValues(r, v)
+	return nil
+}
+
+func TestRegistry(t *testing.T) {
+
+	RegisterRequestHook("test-b", injectTenant)
+	RegisterRequestHook("test-a", injectTenant)
+	RegisterResponseHook("test-c", func(r *http.Request, statusCode int, h http.Header,
+		body []byte) ([]byte, error) {
+		return body, nil
+	})
+
+	if _, ok := GetRequestHook("test-a"); !ok {
+		t.Error("expected request hook test-a")
+	}
+	if _, ok := GetRequestHook("test-c"); ok {
+		t.Error("expected no request hook test-c")
+	}
+	if _, ok := GetResponseHook("test-c"); !ok {
+		t.Error("expected response hook test-c")
+	}
+
+	if v := strings.Join(RequestHookNames(), ","); !strings.Contains(v, "test-a,test-b") {
+		t.Errorf("expected sorted names, got %s", v)
+	}
+	if v := strings.Join(ResponseHookNames(), ","); !strings.Contains(v, "test-c") {
+		t.Errorf("expected test-c in names, got %s", v)
+	}
+}
+
+func TestRewriteRequest(t *testing.T) {
+
+	var got string
+	h := RewriteRequest(injectTenant, http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		got = string(b)
+	}))
+
+	form := url.Values{"query": {`up{job="api"}`}}
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/query", strings.NewReader(form.Encode()))
+	r.Header.Set(headers.NameContentType, headers.ValueXFormURLEncoded)
+	r.Header.Set("X-Tenant", "a")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	expected := url.Values{"query": {`up{tenant="a",job="api"}`}}.Encode()
+	if got != expected {
+		t.Errorf("expected %s got %s", expected, got)
+	}
+
+	got = ""
+	r = httptest.NewRequest(http.MethodGet, "/api/v1/query?"+form.Encode(), nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected %d got %d", http.StatusBadRequest, w.Code)
+	}
+	if got != "" {
+		t.Error("expected the request to be rejected")
+	}
+}
+
+func TestRewriteResponse(t *testing.T) {
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headers.NameContentLength, "5")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+		w.WriteHeader(http.StatusOK) // ignored, as by a ResponseWriter
+	})
+
+	h := RewriteResponse(func(r *http.Request, statusCode int, h http.Header,
+		body []byte) ([]byte, error) {
+		if statusCode != http.StatusCreated {
+			t.Errorf("expected %d got %d", http.StatusCreated, statusCode)
+		}
+		h.Set("X-Rewritten", "1")
+		return append(body, " world"...), nil
+	}, next)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected %d got %d", http.StatusCreated, w.Code)
+	}
+	if w.Body.String() != "hello world" {
+		t.Errorf("expected %s got %s", "hello world", w.Body.String())
+	}
+	if v := w.Header().Get(headers.NameContentLength); v != "11" {
+		t.Errorf("expected %s got %s", "11", v)
+	}
+	if v := w.Header().Get("X-Rewritten"); v != "1" {
+		t.Errorf("expected %s got %s", "1", v)
+	}
+
+	h = RewriteResponse(func(r *http.Request, statusCode int, h http.Header,
+		body []byte) ([]byte, error) {
+		return nil, errors.New("test error")
+	}, next)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected %d got %d", http.StatusBadGateway, w.Code)
+	}
+	if strings.TrimSpace(w.Body.String()) != "test error" {
+		t.Errorf("expected %s got %s", "test error", w.Body.String())
+	}
+
+	// a handler that writes nothing is an empty 200 OK
+	h = RewriteResponse(func(r *http.Request, statusCode int, h http.Header,
+		body []byte) ([]byte, error) {
+		return []byte("x"), nil
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "x" {
+		t.Errorf("expected 200 x got %d %s", w.Code, w.Body.String())
+	}
+}
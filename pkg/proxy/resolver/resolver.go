@@ -0,0 +1,121 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package resolver provides a dialer that connects to an origin using statically-mapped
+// addresses or a custom DNS server instead of the system resolver, while the original
+// hostname continues to be used for the Host header and TLS SNI
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// defaultDNSPort is the port used for a DNS resolver address that does not specify one
+const defaultDNSPort = "53"
+
+// Dialer dials origin connections, resolving hostnames via its static host map when
+// they are present in it, and otherwise via its resolver
+type Dialer struct {
+	dialer *net.Dialer
+	hosts  map[string][]string
+}
+
+// NewDialer returns a Dialer that dials with d. Hostnames in hosts are pinned to the
+// mapped IP addresses, which are tried in order. When resolverAddress is not empty,
+// all other hostnames are resolved by querying the DNS server at that address
+func NewDialer(d *net.Dialer, hosts map[string][]string, resolverAddress string) *Dialer {
+	if d == nil {
+		d = &net.Dialer{}
+	}
+	if resolverAddress != "" {
+		resolverAddress, _ = NormalizeAddress(resolverAddress)
+		d.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, resolverAddress)
+			},
+		}
+	}
+	m := make(map[string][]string, len(hosts))
+	for h, ips := range hosts {
+		m[strings.ToLower(h)] = ips
+	}
+	return &Dialer{dialer: d, hosts: m}
+}
+
+// DialContext connects to the address on the named network, using the static host map
+// or resolver to find the IP addresses of the address's host
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	ips, ok := d.hosts[strings.ToLower(host)]
+	if !ok {
+		return d.dialer.DialContext(ctx, network, address)
+	}
+	var firstErr error
+	for _, ip := range ips {
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if firstErr == nil {
+		firstErr = fmt.Errorf("no addresses mapped for host %s", host)
+	}
+	return nil, firstErr
+}
+
+// ValidateHosts returns an error if any mapped address in hosts is not an IP address
+func ValidateHosts(hosts map[string][]string) error {
+	for h, ips := range hosts {
+		if h == "" {
+			return errors.New("empty hostname")
+		}
+		if len(ips) == 0 {
+			return fmt.Errorf("no addresses for host %s", h)
+		}
+		for _, ip := range ips {
+			if net.ParseIP(ip) == nil {
+				return fmt.Errorf("invalid address %s for host %s", ip, h)
+			}
+		}
+	}
+	return nil
+}
+
+// NormalizeAddress returns the DNS resolver address as host:port, adding the default
+// DNS port if the address does not include one
+func NormalizeAddress(address string) (string, error) {
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address, nil
+	}
+	if net.ParseIP(strings.Trim(address, "[]")) == nil {
+		return "", fmt.Errorf("invalid address: %s", address)
+	}
+	return net.JoinHostPort(strings.Trim(address, "[]"), defaultDNSPort), nil
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resolver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestDialContextStaticHost(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	_, port, _ := net.SplitHostPort(u.Host)
+
+	// the first address refuses connections, so the second is used
+	d := NewDialer(nil, map[string][]string{"Origin.Example": {"127.0.0.2", "127.0.0.1"}}, "")
+	client := &http.Client{Transport: &http.Transport{DialContext: d.DialContext}}
+
+	resp, err := client.Get("http://origin.example:" + port + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b := make([]byte, 64)
+	n, _ := resp.Body.Read(b)
+	if string(b[:n]) != "origin.example:"+port {
+		t.Errorf("expected host %s got %s", "origin.example:"+port, string(b[:n]))
+	}
+}
+
+func TestDialContextNoAddresses(t *testing.T) {
+	d := NewDialer(nil, map[string][]string{"origin.example": {}}, "")
+	_, err := d.DialContext(context.Background(), "tcp", "origin.example:80")
+	if err == nil {
+		t.Error("expected error for host with no addresses")
+	}
+	_, err = d.DialContext(context.Background(), "tcp", "origin.example")
+	if err == nil {
+		t.Error("expected error for address with no port")
+	}
+}
+
+func TestNewDialerResolver(t *testing.T) {
+	d := NewDialer(&net.Dialer{}, nil, "10.0.0.53")
+	if d.dialer.Resolver == nil || !d.dialer.Resolver.PreferGo {
+		t.Error("expected custom resolver")
+	}
+}
+
+func TestValidateHosts(t *testing.T) {
+	tests := []struct {
+		hosts map[string][]string
+		isErr bool
+	}{
+		{map[string][]string{"origin.example": {"10.0.0.1", "::1"}}, false},
+		{map[string][]string{"origin.example": {"origin-1"}}, true},
+		{map[string][]string{"origin.example": {}}, true},
+		{map[string][]string{"": {"10.0.0.1"}}, true},
+	}
+	for i, test := range tests {
+		err := ValidateHosts(test.hosts)
+		if (err != nil) != test.isErr {
+			t.Errorf("case %d: unexpected error value %v", i, err)
+		}
+	}
+}
+
+func TestNormalizeAddress(t *testing.T) {
+	tests := []struct {
+		address, expected string
+		isErr             bool
+	}{
+		{"10.0.0.53", "10.0.0.53:53", false},
+		{"10.0.0.53:5353", "10.0.0.53:5353", false},
+		{"dns.example:53", "dns.example:53", false},
+		{"::1", "[::1]:53", false},
+		{"[::1]", "[::1]:53", false},
+		{"dns.example", "", true},
+	}
+	for _, test := range tests {
+		a, err := NormalizeAddress(test.address)
+		if (err != nil) != test.isErr {
+			t.Errorf("%s: unexpected error value %v", test.address, err)
+		}
+		if a != test.expected {
+			t.Errorf("expected %s got %s", test.expected, a)
+		}
+	}
+}
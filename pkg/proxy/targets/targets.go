@@ -0,0 +1,262 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package targets provides an http.RoundTripper that sends an origin's requests to
+// whichever of its targets, such as replicas of the origin in different regions, is the
+// closest healthy one, as measured by the round trip times of background health checks,
+// or that has the highest static weight
+package targets
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+)
+
+const (
+	// PolicyLatency selects the healthy target with the lowest health check round trip time
+	PolicyLatency = "latency"
+	// PolicyWeight selects the healthy target with the highest static weight
+	PolicyWeight = "weight"
+)
+
+// ewmaWeight is the weight of each new round trip time in a target's smoothed RTT
+const ewmaWeight = 0.3
+
+// Target is one of the URLs at which an origin is served
+type Target struct {
+	URL    *url.URL
+	Weight int
+
+	rtt      time.Duration
+	failures int
+	down     bool
+}
+
+// Check describes the health check request that is sent to each target
+type Check struct {
+	Method  string
+	Path    string
+	Query   string
+	Headers http.Header
+}
+
+// Selector selects the target to which an origin's requests are sent. The first target
+// is the origin's own URL, which is used when no target is healthy
+type Selector struct {
+	originName string
+	policy     string
+	threshold  int
+	targets    []*Target
+
+	mtx  sync.Mutex
+	quit chan struct{}
+	once sync.Once
+}
+
+// NewSelector returns a Selector for the origin's targets using the policy. A target is
+// considered down after threshold consecutive failed health checks, and up again after
+// its next successful one
+func NewSelector(originName, policy string, targets []*Target, threshold int) *Selector {
+	if threshold < 1 {
+		threshold = 1
+	}
+	for _, t := range targets {
+		metrics.ProxyTargetUp.WithLabelValues(originName, t.URL.String()).Set(1)
+	}
+	return &Selector{
+		originName: originName,
+		policy:     policy,
+		threshold:  threshold,
+		targets:    targets,
+		quit:       make(chan struct{}),
+	}
+}
+
+// Targets returns the Selector's targets
+func (s *Selector) Targets() []*Target {
+	return s.targets
+}
+
+// Select returns the target to which the next request is sent
+func (s *Selector) Select() *Target {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	var best *Target
+	for _, t := range s.targets {
+		if !t.down && (best == nil || s.better(t, best)) {
+			best = t
+		}
+	}
+	if best == nil {
+		return s.targets[0]
+	}
+	return best
+}
+
+// better returns true if target t is preferred over target u. Ties are broken in favor
+// of u, which precedes t in the target list
+func (s *Selector) better(t, u *Target) bool {
+	if s.policy == PolicyWeight {
+		return t.Weight > u.Weight
+	}
+	// a target whose RTT has not been measured yet is not preferred over one that has
+	return t.rtt > 0 && (u.rtt == 0 || t.rtt < u.rtt)
+}
+
+// RTT returns the target's smoothed health check round trip time, or 0 if it has not
+// been measured
+func (s *Selector) RTT(t *Target) time.Duration {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return t.rtt
+}
+
+// Down returns true if the target's health checks report it as down
+func (s *Selector) Down(t *Target) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return t.down
+}
+
+// Observe records the round trip time and outcome of a health check of the target
+func (s *Selector) Observe(t *Target, rtt time.Duration, ok bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	name := t.URL.String()
+	if !ok {
+		t.failures++
+		if !t.down && t.failures >= s.threshold {
+			t.down = true
+			metrics.ProxyTargetUp.WithLabelValues(s.originName, name).Set(0)
+		}
+		return
+	}
+	t.failures = 0
+	if t.down {
+		t.down = false
+		metrics.ProxyTargetUp.WithLabelValues(s.originName, name).Set(1)
+	}
+	if t.rtt == 0 {
+		t.rtt = rtt
+	} else {
+		t.rtt = time.Duration(ewmaWeight*float64(rtt) + (1-ewmaWeight)*float64(t.rtt))
+	}
+	metrics.ProxyTargetRTT.WithLabelValues(s.originName, name).Set(t.rtt.Seconds())
+}
+
+// Probe sends the health check to each target concurrently via the client, and observes
+// their round trip times and outcomes. A check succeeds when the target responds with a
+// status code below 400
+func (s *Selector) Probe(client *http.Client, c Check) {
+	var wg sync.WaitGroup
+	for _, t := range s.targets {
+		wg.Add(1)
+		go func(t *Target) {
+			defer wg.Done()
+			u := *t.URL
+			u.Path += c.Path
+			u.RawQuery = c.Query
+			r, err := http.NewRequest(c.Method, u.String(), nil)
+			if err != nil {
+				s.Observe(t, 0, false)
+				return
+			}
+			r = r.WithContext(context.WithValue(context.Background(), probeKey{}, true))
+			if c.Headers != nil {
+				r.Header = c.Headers.Clone()
+			}
+			start := time.Now()
+			resp, err := client.Do(r)
+			rtt := time.Since(start)
+			if err != nil {
+				s.Observe(t, rtt, false)
+				return
+			}
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+			s.Observe(t, rtt, resp.StatusCode < http.StatusBadRequest)
+		}(t)
+	}
+	wg.Wait()
+}
+
+// Start probes the targets immediately, and subsequently on the interval, until the
+// Selector is stopped
+func (s *Selector) Start(client *http.Client, c Check, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.Probe(client, c)
+			select {
+			case <-ticker.C:
+			case <-s.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the Selector's health checks
+func (s *Selector) Stop() {
+	s.once.Do(func() { close(s.quit) })
+}
+
+// probeKey is the context key of the flag that marks a request as a health check that is
+// addressed to a specific target
+type probeKey struct{}
+
+// Transport is an http.RoundTripper that sends each request to the selected target
+type Transport struct {
+	next     http.RoundTripper
+	prefix   string
+	selector *Selector
+}
+
+// NewTransport returns a Transport that sends requests via next. When a request is sent
+// to a target other than the origin's own URL, the origin's path prefix is replaced with
+// the path of the target
+func NewTransport(next http.RoundTripper, prefix string, s *Selector) *Transport {
+	return &Transport{next: next, prefix: prefix, selector: s}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if probe, _ := r.Context().Value(probeKey{}).(bool); probe {
+		return t.next.RoundTrip(r)
+	}
+	target := t.selector.Select()
+	if target == t.selector.targets[0] {
+		return t.next.RoundTrip(r)
+	}
+	r2 := r.Clone(r.Context())
+	u := *r.URL
+	u.Scheme = target.URL.Scheme
+	u.Host = target.URL.Host
+	u.Path = target.URL.Path + strings.TrimPrefix(r.URL.Path, t.prefix)
+	u.RawPath = ""
+	r2.URL = &u
+	r2.Host = target.URL.Host
+	return t.next.RoundTrip(r2)
+}
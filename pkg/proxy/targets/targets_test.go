@@ -0,0 +1,185 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package targets
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func newServer(name string, status *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != nil {
+			w.WriteHeader(*status)
+		}
+		w.Write([]byte(name + ":" + r.URL.Path))
+	}))
+}
+
+func mustParse(t *testing.T, s string) *url.URL {
+	u, err := url.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func testTargets(t *testing.T, urls ...string) []*Target {
+	ts := make([]*Target, len(urls))
+	for i, u := range urls {
+		ts[i] = &Target{URL: mustParse(t, u), Weight: 1}
+	}
+	return ts
+}
+
+func TestSelectLatency(t *testing.T) {
+	ts := testTargets(t, "http://primary", "http://near", "http://far")
+	s := NewSelector("test", PolicyLatency, ts, 2)
+
+	// the origin's own URL is used until RTTs are measured
+	if s.Select() != ts[0] {
+		t.Errorf("expected %s got %s", ts[0].URL, s.Select().URL)
+	}
+
+	s.Observe(ts[0], 50*time.Millisecond, true)
+	s.Observe(ts[1], 10*time.Millisecond, true)
+	s.Observe(ts[2], 100*time.Millisecond, true)
+	if s.Select() != ts[1] {
+		t.Errorf("expected %s got %s", ts[1].URL, s.Select().URL)
+	}
+
+	// the RTT is smoothed
+	s.Observe(ts[1], 110*time.Millisecond, true)
+	if rtt := s.RTT(ts[1]); rtt != 40*time.Millisecond {
+		t.Errorf("expected %s got %s", 40*time.Millisecond, rtt)
+	}
+	if s.Select() != ts[1] {
+		t.Errorf("expected %s got %s", ts[1].URL, s.Select().URL)
+	}
+
+	// the closest target is down after the failure threshold
+	s.Observe(ts[1], 0, false)
+	if s.Select() != ts[1] || s.Down(ts[1]) {
+		t.Errorf("expected %s got %s", ts[1].URL, s.Select().URL)
+	}
+	s.Observe(ts[1], 0, false)
+	if s.Select() != ts[0] || !s.Down(ts[1]) {
+		t.Errorf("expected %s got %s", ts[0].URL, s.Select().URL)
+	}
+
+	// and up again after its next successful check
+	s.Observe(ts[1], 10*time.Millisecond, true)
+	if s.Select() != ts[1] {
+		t.Errorf("expected %s got %s", ts[1].URL, s.Select().URL)
+	}
+
+	// the origin's own URL is used when no target is healthy
+	for _, target := range ts {
+		s.Observe(target, 0, false)
+		s.Observe(target, 0, false)
+	}
+	if s.Select() != ts[0] {
+		t.Errorf("expected %s got %s", ts[0].URL, s.Select().URL)
+	}
+}
+
+func TestSelectWeight(t *testing.T) {
+	ts := testTargets(t, "http://primary", "http://preferred", "http://other")
+	ts[1].Weight = 5
+	ts[2].Weight = 5
+	s := NewSelector("test", PolicyWeight, ts, 1)
+
+	// ties are broken by the target order
+	if s.Select() != ts[1] {
+		t.Errorf("expected %s got %s", ts[1].URL, s.Select().URL)
+	}
+	s.Observe(ts[1], 0, false)
+	if s.Select() != ts[2] {
+		t.Errorf("expected %s got %s", ts[2].URL, s.Select().URL)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	primary := newServer("primary", nil)
+	defer primary.Close()
+	near := newServer("near", nil)
+	defer near.Close()
+	status := http.StatusServiceUnavailable
+	down := newServer("down", &status)
+	defer down.Close()
+
+	ts := []*Target{
+		{URL: mustParse(t, primary.URL+"/prom"), Weight: 1},
+		{URL: mustParse(t, near.URL+"/near"), Weight: 2},
+		{URL: mustParse(t, down.URL), Weight: 3},
+	}
+	s := NewSelector("test", PolicyWeight, ts, 1)
+	tr := NewTransport(http.DefaultTransport, "/prom", s)
+	client := &http.Client{Transport: tr}
+
+	// health checks are sent to each target, rather than to the selected one
+	s.Probe(client, Check{Method: http.MethodGet, Path: "/-/healthy"})
+	if !s.Down(ts[2]) || s.Down(ts[0]) || s.Down(ts[1]) {
+		t.Error("expected only the failing target to be down")
+	}
+	if s.RTT(ts[1]) == 0 {
+		t.Error("expected a measured RTT")
+	}
+
+	// requests are sent to the selected target, with the path prefix replaced
+	resp, err := client.Get(primary.URL + "/prom/api/v1/query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(b) != "near:/near/api/v1/query" {
+		t.Errorf("expected %s got %s", "near:/near/api/v1/query", string(b))
+	}
+
+	// and to the origin's own URL when it is selected
+	s.Observe(ts[1], 0, false)
+	resp, err = client.Get(primary.URL + "/prom/api/v1/query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(b) != "primary:/prom/api/v1/query" {
+		t.Errorf("expected %s got %s", "primary:/prom/api/v1/query", string(b))
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	srv := newServer("primary", nil)
+	defer srv.Close()
+	ts := testTargets(t, srv.URL)
+	s := NewSelector("test", PolicyLatency, ts, 1)
+	s.Start(http.DefaultClient, Check{Method: http.MethodGet, Path: "/"}, time.Hour)
+	defer s.Stop()
+	for i := 0; i < 100 && s.RTT(ts[0]) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if s.RTT(ts[0]) == 0 {
+		t.Error("expected the targets to be probed on start")
+	}
+	s.Stop()
+}
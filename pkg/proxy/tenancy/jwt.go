@@ -0,0 +1,168 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tenancy
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256" // registers the SHA-256 hash
+	_ "crypto/sha512" // registers the SHA-384 and SHA-512 hashes
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+var errInvalidSignature = errors.New("invalid bearer token signature")
+
+// algHashes maps the suffixes of the supported JWT signature algorithms to their hashes
+var algHashes = map[string]crypto.Hash{
+	"256": crypto.SHA256,
+	"384": crypto.SHA384,
+	"512": crypto.SHA512,
+}
+
+// Verifier verifies the signatures and validity periods of bearer tokens, so that their
+// claims can be trusted
+type Verifier struct {
+	secret []byte
+	key    crypto.PublicKey
+}
+
+// NewVerifier returns a Verifier for tokens signed with the HMAC secret (HS256, HS384 or
+// HS512), or with the private key of the PEM-encoded RSA (RS256, RS384 or RS512) or ECDSA
+// (ES256, ES384 or ES512) public key. Exactly one of secret and keyPEM must be provided
+func NewVerifier(secret string, keyPEM []byte) (*Verifier, error) {
+	if (secret == "") == (len(keyPEM) == 0) {
+		return nil, errors.New("a jwt secret or key is required, but not both")
+	}
+	if secret != "" {
+		return &Verifier{secret: []byte(secret)}, nil
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("invalid jwt key: no PEM data found")
+	}
+	k, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwt key: %s", err.Error())
+	}
+	switch k.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return &Verifier{key: k}, nil
+	}
+	return nil, errors.New("invalid jwt key: only RSA and ECDSA keys are supported")
+}
+
+// Claims returns the claims of the request's bearer token, or an error if the token's
+// signature is invalid or the token is expired or not yet valid
+func (v *Verifier) Claims(r *http.Request) (map[string]interface{}, error) {
+	parts, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(b, &header); err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	if err := v.verify(header.Alg, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+	claims, err := decodeClaims(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	now := float64(time.Now().Unix())
+	if exp, ok := claims["exp"].(float64); ok && now >= exp {
+		return nil, errors.New("bearer token is expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now < nbf {
+		return nil, errors.New("bearer token is not yet valid")
+	}
+	return claims, nil
+}
+
+// verify returns an error if sig is not the signature of the signed input, using the alg
+func (v *Verifier) verify(alg, signed string, sig []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported jwt algorithm: %s", alg)
+	}
+	h, ok := algHashes[alg[2:]]
+	if !ok {
+		return fmt.Errorf("unsupported jwt algorithm: %s", alg)
+	}
+	switch alg[:2] {
+	case "HS":
+		if v.secret == nil {
+			break
+		}
+		mac := hmac.New(h.New, v.secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return errInvalidSignature
+		}
+		return nil
+	case "RS":
+		k, ok := v.key.(*rsa.PublicKey)
+		if !ok {
+			break
+		}
+		if rsa.VerifyPKCS1v15(k, h, digest(h, signed), sig) != nil {
+			return errInvalidSignature
+		}
+		return nil
+	case "ES":
+		k, ok := v.key.(*ecdsa.PublicKey)
+		if !ok {
+			break
+		}
+		n := (k.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*n {
+			return errInvalidSignature
+		}
+		r, s := new(big.Int).SetBytes(sig[:n]), new(big.Int).SetBytes(sig[n:])
+		if !ecdsa.Verify(k, digest(h, signed), r, s) {
+			return errInvalidSignature
+		}
+		return nil
+	}
+	// a token must not choose an algorithm that its verifier's key was not meant for
+	return fmt.Errorf("unsupported jwt algorithm: %s", alg)
+}
+
+func digest(h crypto.Hash, s string) []byte {
+	d := h.New()
+	d.Write([]byte(s))
+	return d.Sum(nil)
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tenancy
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+func signingInput(alg, claims string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"`+alg+`","typ":"JWT"}`)) +
+		"." + base64.RawURLEncoding.EncodeToString([]byte(claims))
+}
+
+func signHMAC(claims, secret string) string {
+	s := signingInput("HS256", claims)
+	mac := hmac.New(crypto.SHA256.New, []byte(secret))
+	mac.Write([]byte(s))
+	return s + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func publicKeyPEM(t *testing.T, k crypto.PublicKey) []byte {
+	b, err := x509.MarshalPKIXPublicKey(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b})
+}
+
+func TestNewVerifier(t *testing.T) {
+
+	ek, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	tests := []struct {
+		secret string
+		key    []byte
+		ok     bool
+	}{
+		{"secret", nil, true},
+		{"", publicKeyPEM(t, &ek.PublicKey), true},
+		{"", nil, false},
+		{"secret", publicKeyPEM(t, &ek.PublicKey), false},
+		{"", []byte("not a key"), false},
+		{"", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("x")}), false},
+	}
+
+	for i, test := range tests {
+		_, err := NewVerifier(test.secret, test.key)
+		if (err == nil) != test.ok {
+			t.Errorf("%d: expected ok %t got %v", i, test.ok, err)
+		}
+	}
+}
+
+func TestVerifierClaims(t *testing.T) {
+
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hv, _ := NewVerifier("secret", nil)
+	rv, _ := NewVerifier("", publicKeyPEM(t, &rk.PublicKey))
+	ev, _ := NewVerifier("", publicKeyPEM(t, &ek.PublicKey))
+
+	signRSA := func(claims string) string {
+		s := signingInput("RS256", claims)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, rk, crypto.SHA256, digest(crypto.SHA256, s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s + "." + base64.RawURLEncoding.EncodeToString(sig)
+	}
+
+	signECDSA := func(claims string) string {
+		s := signingInput("ES256", claims)
+		r, ss, err := ecdsa.Sign(rand.Reader, ek, digest(crypto.SHA256, s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig := make([]byte, 64)
+		rb, sb := r.Bytes(), ss.Bytes()
+		copy(sig[32-len(rb):32], rb)
+		copy(sig[64-len(sb):], sb)
+		return s + "." + base64.RawURLEncoding.EncodeToString(sig)
+	}
+
+	now := time.Now().Unix()
+	claims := `{"sub":"a"}`
+	expired := `{"sub":"a","exp":` + strconv.FormatInt(now-60, 10) + `}`
+	early := `{"sub":"a","nbf":` + strconv.FormatInt(now+60, 10) + `}`
+	valid := `{"sub":"a","exp":` + strconv.FormatInt(now+60, 10) + `,"nbf":` +
+		strconv.FormatInt(now-60, 10) + `}`
+
+	tests := []struct {
+		v     *Verifier
+		token string
+		ok    bool
+	}{
+		{hv, signHMAC(claims, "secret"), true},
+		{hv, signHMAC(valid, "secret"), true},
+		{hv, signHMAC(claims, "other"), false},
+		{hv, signHMAC(expired, "secret"), false},
+		{hv, signHMAC(early, "secret"), false},
+		{hv, signingInput("none", claims) + ".", false},
+		{hv, signRSA(claims), false},
+		{rv, signRSA(claims), true},
+		{rv, signHMAC(claims, "secret"), false},
+		{rv, signECDSA(claims), false},
+		{ev, signECDSA(claims), true},
+		{ev, signECDSA(claims)[:20] + "x" + signECDSA(claims)[21:], false},
+		{ev, "e30.e30", false},
+	}
+
+	for i, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(headers.NameAuthorization, "Bearer "+test.token)
+		c, err := test.v.Claims(r)
+		if (err == nil) != test.ok {
+			t.Errorf("%d: expected ok %t got %v", i, test.ok, err)
+			continue
+		}
+		if test.ok && c["sub"] != "a" {
+			t.Errorf("%d: expected claim %s got %v", i, "a", c["sub"])
+		}
+	}
+}
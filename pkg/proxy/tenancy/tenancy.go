@@ -0,0 +1,245 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tenancy provides the label values that are injected into queries to enforce
+// multi-tenancy, and the principals that partition cache keys, which are read from each
+// request's headers, basic auth credentials or JWT claims
+package tenancy
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/util/md5"
+	"github.com/tricksterproxy/trickster/pkg/util/promql"
+)
+
+// Source Kinds
+const (
+	// KindHeader reads the value from the named request header
+	KindHeader = "header"
+	// KindJWT reads the value from the named claim of the request's bearer token
+	KindJWT = "jwt"
+	// KindBasic reads the value from the username of the request's basic auth credentials
+	KindBasic = "basic"
+)
+
+// Source identifies where the value of an injected label is read from in each request
+type Source struct {
+	Kind string
+	Name string
+}
+
+// ParseSource returns the Source for a source string, like "header:X-Tenant", "jwt:tenant"
+// or "basic"
+func ParseSource(s string) (*Source, error) {
+	if s == KindBasic {
+		return &Source{Kind: KindBasic}, nil
+	}
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 || parts[1] == "" || (parts[0] != KindHeader && parts[0] != KindJWT) {
+		return nil, fmt.Errorf("invalid label source: %s", s)
+	}
+	return &Source{Kind: parts[0], Name: parts[1]}, nil
+}
+
+// String returns the string representation of the Source
+func (s *Source) String() string {
+	if s.Kind == KindBasic {
+		return s.Kind
+	}
+	return s.Kind + ":" + s.Name
+}
+
+// Value returns the Source's value for the request, and false if it has none. The bearer
+// token's signature is not verified here; an Enforcer verifies it for injected labels
+func (s *Source) Value(r *http.Request) (string, bool) {
+	switch s.Kind {
+	case KindHeader:
+		v := r.Header.Get(s.Name)
+		return v, v != ""
+	case KindBasic:
+		v, _, ok := r.BasicAuth()
+		return v, ok && v != ""
+	}
+	claims, err := bearerClaims(r)
+	if err != nil {
+		return "", false
+	}
+	return s.claim(claims)
+}
+
+// claim returns the value of the Source's claim, and false if the claims have none
+func (s *Source) claim(claims map[string]interface{}) (string, bool) {
+	switch v := claims[s.Name].(type) {
+	case string:
+		return v, v != ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// Principal returns the Source's value for the request as a cache key principal, and false
+// if it has none. Basic auth credentials and bearer tokens are not verified by Trickster, so
+// for those Sources, the principal is a hash of the full credential, rather than a username
+// or claim that any client could present
+func (s *Source) Principal(r *http.Request) (string, bool) {
+	v, ok := s.Value(r)
+	if !ok || s.Kind == KindHeader {
+		return v, ok
+	}
+	return md5.Checksum(r.Header.Get(headers.NameAuthorization)), true
+}
+
+var errNoBearerToken = errors.New("no bearer token")
+
+// bearerToken returns the header, claims and signature parts of the request's bearer token
+func bearerToken(r *http.Request) ([]string, error) {
+	auth := r.Header.Get(headers.NameAuthorization)
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "bearer ") {
+		return nil, errNoBearerToken
+	}
+	parts := strings.Split(strings.TrimSpace(auth[7:]), ".")
+	if len(parts) != 3 {
+		return nil, errNoBearerToken
+	}
+	return parts, nil
+}
+
+// bearerClaims returns the decoded claims of the request's bearer token, without verifying it
+func bearerClaims(r *http.Request) (map[string]interface{}, error) {
+	parts, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodeClaims(parts[1])
+}
+
+// decodeClaims returns the decoded claims part of a token
+func decodeClaims(part string) (map[string]interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(part, "="))
+	if err != nil {
+		return nil, err
+	}
+	claims := make(map[string]interface{})
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Enforcer reads the values of the labels that are injected into a request's queries from
+// authenticated sources only: bearer tokens whose signatures it verifies, and the headers
+// and bearer tokens of requests from trusted authenticating proxies
+type Enforcer struct {
+	// Sources maps the names of the injected labels to the Sources of their values
+	Sources map[string]*Source
+
+	verifier *Verifier
+	trusted  []*net.IPNet
+}
+
+// NewEnforcer returns an Enforcer for the sources. A non-nil verifier verifies the bearer
+// tokens of jwt Sources, and trustedProxies lists the CIDRs of the authenticating proxies in
+// front of Trickster, whose requests' headers and tokens are trusted as they are. Basic auth
+// Sources are rejected, since Trickster cannot verify their passwords
+func NewEnforcer(sources map[string]*Source, verifier *Verifier,
+	trustedProxies []string) (*Enforcer, error) {
+	e := &Enforcer{Sources: sources, verifier: verifier}
+	for _, c := range trustedProxies {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy: %s", c)
+		}
+		e.trusted = append(e.trusted, n)
+	}
+	for label, s := range sources {
+		switch {
+		case s.Kind == KindBasic:
+			return nil, fmt.Errorf("basic auth is not a trusted source for label %s", label)
+		case s.Kind == KindJWT && verifier == nil && len(e.trusted) == 0:
+			return nil, fmt.Errorf("%s for label %s requires a jwt secret or key, "+
+				"or a trusted proxy", s, label)
+		case s.Kind == KindHeader && len(e.trusted) == 0:
+			return nil, fmt.Errorf("%s for label %s requires a trusted proxy", s, label)
+		}
+	}
+	return e, nil
+}
+
+// Matchers returns the label matchers for the request, sorted by label name. It returns an
+// error if any of the sources has no trusted value for the request, so the request can be
+// rejected
+func (e *Enforcer) Matchers(r *http.Request) ([]promql.Matcher, error) {
+	trusted := e.fromTrustedProxy(r)
+	var claims map[string]interface{}
+	ms := make([]promql.Matcher, 0, len(e.Sources))
+	for label, s := range e.Sources {
+		var v string
+		var ok bool
+		switch {
+		case s.Kind == KindJWT && e.verifier != nil:
+			if claims == nil {
+				var err error
+				if claims, err = e.verifier.Claims(r); err != nil {
+					return nil, err
+				}
+			}
+			v, ok = s.claim(claims)
+		case trusted:
+			v, ok = s.Value(r)
+		default:
+			return nil, fmt.Errorf("%s for label %s is only trusted from a trusted proxy", s, label)
+		}
+		if !ok {
+			return nil, fmt.Errorf("missing %s for label %s", s, label)
+		}
+		ms = append(ms, promql.Matcher{Name: label, Value: v})
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Name < ms[j].Name })
+	return ms, nil
+}
+
+// fromTrustedProxy returns true if the request's remote address is in a trusted proxy's
+// CIDR. Forwarding headers are not considered, since any client can set them
+func (e *Enforcer) fromTrustedProxy(r *http.Request) bool {
+	if len(e.trusted) == 0 {
+		return false
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range e.trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,234 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tenancy
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+func testToken(claims string) string {
+	return "Bearer e30." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".sig"
+}
+
+func TestParseSource(t *testing.T) {
+
+	tests := []struct {
+		input    string
+		expected *Source
+	}{
+		{"header:X-Tenant", &Source{Kind: KindHeader, Name: "X-Tenant"}},
+		{"jwt:tenant", &Source{Kind: KindJWT, Name: "tenant"}},
+		{"basic", &Source{Kind: KindBasic}},
+		{"jwt:", nil},
+		{"cookie:tenant", nil},
+		{"tenant", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			s, err := ParseSource(test.input)
+			if test.expected == nil {
+				if err == nil {
+					t.Error("expected error for invalid source")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *s != *test.expected {
+				t.Errorf("expected %v got %v", test.expected, s)
+			}
+			if s.String() != test.input {
+				t.Errorf("expected %s got %s", test.input, s.String())
+			}
+		})
+	}
+}
+
+func TestSourceValue(t *testing.T) {
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Tenant", "team-a")
+
+	tests := []struct {
+		source   Source
+		auth     string
+		expected string
+		ok       bool
+	}{
+		{Source{KindHeader, "X-Tenant"}, "", "team-a", true},
+		{Source{KindHeader, "X-Other"}, "", "", false},
+		{Source{KindJWT, "tenant"}, testToken(`{"tenant":"team-b"}`), "team-b", true},
+		{Source{KindJWT, "org"}, testToken(`{"org":42}`), "42", true},
+		{Source{KindJWT, "tenant"}, testToken(`{"tenant":["a"]}`), "", false},
+		{Source{KindJWT, "tenant"}, testToken(`{"other":"a"}`), "", false},
+		{Source{KindJWT, "tenant"}, testToken(`not json`), "", false},
+		{Source{KindJWT, "tenant"}, "Bearer e30.!!!.sig", "", false},
+		{Source{KindJWT, "tenant"}, "Bearer e30", "", false},
+		{Source{KindJWT, "tenant"}, "Basic dXNlcjpwYXNz", "", false},
+		{Source{KindJWT, "tenant"}, "", "", false},
+		{Source{KindBasic, ""}, "Basic dXNlcjpwYXNz", "user", true},
+		{Source{KindBasic, ""}, "Basic OnBhc3M=", "", false},
+		{Source{KindBasic, ""}, testToken(`{"tenant":"team-b"}`), "", false},
+	}
+
+	for i, test := range tests {
+		r.Header.Set(headers.NameAuthorization, test.auth)
+		v, ok := test.source.Value(r)
+		if v != test.expected || ok != test.ok {
+			t.Errorf("%d: expected %s %t got %s %t", i, test.expected, test.ok, v, ok)
+		}
+	}
+}
+
+func TestSourcePrincipal(t *testing.T) {
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-User", "user-a")
+
+	s := &Source{KindHeader, "X-User"}
+	if v, ok := s.Principal(r); v != "user-a" || !ok {
+		t.Errorf("expected %s got %s", "user-a", v)
+	}
+
+	// unverified credentials with the same username or claim have different principals
+	tests := []struct {
+		source Source
+		auth1  string
+		auth2  string
+	}{
+		{Source{KindBasic, ""}, "Basic dXNlcjpwYXNz", "Basic dXNlcjpvdGhlcg=="},
+		{Source{KindJWT, "sub"}, testToken(`{"sub":"user-a"}`),
+			"Bearer e30." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"user-a"}`)) + ".forged"},
+	}
+
+	for i, test := range tests {
+		r.Header.Set(headers.NameAuthorization, test.auth1)
+		v1, ok1 := test.source.Principal(r)
+		r.Header.Set(headers.NameAuthorization, test.auth2)
+		v2, ok2 := test.source.Principal(r)
+		if !ok1 || !ok2 {
+			t.Errorf("%d: expected principals", i)
+		}
+		if v1 == v2 {
+			t.Errorf("%d: expected different principals", i)
+		}
+		if v, _ := test.source.Value(r); v == v2 {
+			t.Errorf("%d: expected principal to differ from the credential's value", i)
+		}
+	}
+
+	r.Header.Del(headers.NameAuthorization)
+	if _, ok := (&Source{KindBasic, ""}).Principal(r); ok {
+		t.Error("expected no principal")
+	}
+}
+
+func TestNewEnforcer(t *testing.T) {
+
+	v, _ := NewVerifier("secret", nil)
+
+	tests := []struct {
+		sources map[string]*Source
+		v       *Verifier
+		trusted []string
+		ok      bool
+	}{
+		{map[string]*Source{"ns": {KindBasic, ""}}, v, []string{"10.0.0.0/8"}, false},
+		{map[string]*Source{"ns": {KindHeader, "X-Tenant"}}, v, nil, false},
+		{map[string]*Source{"ns": {KindHeader, "X-Tenant"}}, nil, []string{"10.0.0.0/8"}, true},
+		{map[string]*Source{"ns": {KindJWT, "ns"}}, nil, nil, false},
+		{map[string]*Source{"ns": {KindJWT, "ns"}}, v, nil, true},
+		{map[string]*Source{"ns": {KindJWT, "ns"}}, nil, []string{"10.0.0.0/8"}, true},
+		{map[string]*Source{"ns": {KindJWT, "ns"}}, v, []string{"10.0.0.0"}, false},
+	}
+
+	for i, test := range tests {
+		_, err := NewEnforcer(test.sources, test.v, test.trusted)
+		if (err == nil) != test.ok {
+			t.Errorf("%d: expected ok %t got %v", i, test.ok, err)
+		}
+	}
+}
+
+func TestMatchers(t *testing.T) {
+
+	sources := map[string]*Source{
+		"namespace": {KindHeader, "X-Tenant"},
+		"cluster":   {KindJWT, "cluster"},
+	}
+	e, err := NewEnforcer(sources, nil, []string{"192.0.2.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Tenant", "team-a")
+	if _, err := e.Matchers(r); err == nil {
+		t.Error("expected error for missing claim")
+	}
+
+	r.Header.Set(headers.NameAuthorization, testToken(`{"cluster":"east"}`))
+	ms, err := e.Matchers(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 2 || ms[0].String() != `cluster="east"` ||
+		ms[1].String() != `namespace="team-a"` {
+		t.Errorf("unexpected matchers %v", ms)
+	}
+
+	// requests that are not from the trusted proxy are rejected
+	r.RemoteAddr = "198.51.100.1:1234"
+	if _, err := e.Matchers(r); err == nil {
+		t.Error("expected error for untrusted request")
+	}
+}
+
+func TestMatchersVerified(t *testing.T) {
+
+	v, err := NewVerifier("secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewEnforcer(map[string]*Source{"cluster": {KindJWT, "cluster"}}, v, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(headers.NameAuthorization, "Bearer "+signHMAC(`{"cluster":"east"}`, "secret"))
+	ms, err := e.Matchers(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 1 || ms[0].String() != `cluster="east"` {
+		t.Errorf("unexpected matchers %v", ms)
+	}
+
+	// a token whose signature can't be verified is rejected
+	r.Header.Set(headers.NameAuthorization, testToken(`{"cluster":"east"}`))
+	if _, err := e.Matchers(r); err == nil {
+		t.Error("expected error for unverified token")
+	}
+}
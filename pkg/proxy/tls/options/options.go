@@ -17,6 +17,7 @@
 package options
 
 import (
+	"errors"
 	"io/ioutil"
 
 	"github.com/tricksterproxy/trickster/pkg/util/strings"
@@ -82,11 +83,27 @@ func (o *Options) Equal(o2 *Options) bool {
 		o.ClientKeyPath == o2.ClientKeyPath
 }
 
-// Validate returns true if the TLS Options are validated
+// ErrIncompleteClientCert is returned when only one of the client cert and key paths is set
+var ErrIncompleteClientCert = errors.New("client_cert_path and client_key_path must be set together")
+
+// Validate validates the TLS Options, and returns true when they configure the frontend
+// to serve TLS. The frontend certificate and key are independent of the client
+// certificate, key and certificate authorities used on the upstream connections, so
+// either of them may be configured without the other
 func (o *Options) Validate() (bool, error) {
 
-	if (o.FullChainCertPath == "" || o.PrivateKeyPath == "") &&
-		(o.CertificateAuthorityPaths == nil || len(o.CertificateAuthorityPaths) == 0) {
+	if (o.ClientCertPath == "") != (o.ClientKeyPath == "") {
+		return false, ErrIncompleteClientCert
+	}
+
+	// Verify CA Paths
+	for _, path := range o.CertificateAuthorityPaths {
+		if _, err := ioutil.ReadFile(path); err != nil {
+			return false, err
+		}
+	}
+
+	if o.FullChainCertPath == "" && o.PrivateKeyPath == "" {
 		return false, nil
 	}
 
@@ -99,16 +116,6 @@ func (o *Options) Validate() (bool, error) {
 		return false, err
 	}
 
-	// Verify CA Paths
-	if o.CertificateAuthorityPaths != nil && len(o.CertificateAuthorityPaths) > 0 {
-		for _, path := range o.CertificateAuthorityPaths {
-			_, err = ioutil.ReadFile(path)
-			if err != nil {
-				return false, err
-			}
-		}
-	}
-
 	o.ServeTLS = true
 
 	return true, nil
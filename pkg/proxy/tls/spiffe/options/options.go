@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options provides options for the SPIFFE workload identity
+package options
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/config/defaults"
+	ts "github.com/tricksterproxy/trickster/pkg/util/strings"
+)
+
+// ErrMissingKeyPath is returned when an SVID certificate is configured without its key
+var ErrMissingKeyPath = errors.New("svid_key_path is required with svid_cert_path")
+
+// ErrMissingBundlePath is returned when an SVID is configured without its trust bundle
+var ErrMissingBundlePath = errors.New("bundle_path is required with svid_cert_path")
+
+// ErrMissingTrustDomain is returned when an SVID is configured without its trust domain
+var ErrMissingTrustDomain = errors.New("trust_domain is required with svid_cert_path")
+
+// ErrInvalidRefreshInterval is returned when the refresh interval is not a positive number
+var ErrInvalidRefreshInterval = errors.New("refresh_interval_secs must be greater than 0")
+
+// ErrInvalidID is returned when an allowed ID is not a SPIFFE ID
+var ErrInvalidID = errors.New("allowed ids must be spiffe:// URIs")
+
+// Options is a collection of configurations for the SPIFFE workload identity, which is
+// read from the X.509 SVID and trust bundle files that the SPIRE Agent or spiffe-helper
+// keep up to date
+type Options struct {
+	// SVIDCertPath is the path of the PEM file with the X.509 SVID and its intermediates.
+	// The workload identity is only used when an SVIDCertPath is configured
+	SVIDCertPath string `toml:"svid_cert_path"`
+	// SVIDKeyPath is the path of the PEM file with the X.509 SVID's private key
+	SVIDKeyPath string `toml:"svid_key_path"`
+	// BundlePath is the path of the PEM file with the trust domain's CA bundle, which
+	// verifies the SVIDs of peers
+	BundlePath string `toml:"bundle_path"`
+	// TrustDomain is the trust domain of the peers' SVIDs (e.g., example.org)
+	TrustDomain string `toml:"trust_domain"`
+	// RefreshIntervalSecs is the interval at which the files are checked for rotation
+	RefreshIntervalSecs int `toml:"refresh_interval_secs"`
+	// FrontendMTLS, when true, serves the TLS listener with the SVID, and requires each
+	// client to present an SVID from the trust domain
+	FrontendMTLS bool `toml:"frontend_mtls"`
+	// FrontendAllowedIDs limits the clients of the TLS listener to these SPIFFE IDs. When
+	// empty, any SVID from the trust domain is allowed
+	FrontendAllowedIDs []string `toml:"frontend_allowed_ids"`
+}
+
+// NewOptions returns a new Options reference with Default Values set
+func NewOptions() *Options {
+	return &Options{
+		RefreshIntervalSecs: defaults.DefaultSpiffeRefreshIntervalSecs,
+	}
+}
+
+// Clone returns an exact copy of the subject *Options
+func (o *Options) Clone() *Options {
+	return &Options{
+		SVIDCertPath:        o.SVIDCertPath,
+		SVIDKeyPath:         o.SVIDKeyPath,
+		BundlePath:          o.BundlePath,
+		TrustDomain:         o.TrustDomain,
+		RefreshIntervalSecs: o.RefreshIntervalSecs,
+		FrontendMTLS:        o.FrontendMTLS,
+		FrontendAllowedIDs:  ts.CloneList(o.FrontendAllowedIDs),
+	}
+}
+
+// Enabled returns true when the workload identity is configured
+func (o *Options) Enabled() bool {
+	return o != nil && o.SVIDCertPath != ""
+}
+
+// Equal returns true when the subject and o2 have the same values
+func (o *Options) Equal(o2 *Options) bool {
+	if o == nil || o2 == nil {
+		return o == o2
+	}
+	return o.SVIDCertPath == o2.SVIDCertPath &&
+		o.SVIDKeyPath == o2.SVIDKeyPath &&
+		o.BundlePath == o2.BundlePath &&
+		o.TrustDomain == o2.TrustDomain &&
+		o.RefreshIntervalSecs == o2.RefreshIntervalSecs &&
+		o.FrontendMTLS == o2.FrontendMTLS &&
+		ts.Equal(o.FrontendAllowedIDs, o2.FrontendAllowedIDs)
+}
+
+// Validate returns an error if the Options are not valid
+func (o *Options) Validate() error {
+	if !o.Enabled() {
+		return nil
+	}
+	if o.SVIDKeyPath == "" {
+		return ErrMissingKeyPath
+	}
+	if o.BundlePath == "" {
+		return ErrMissingBundlePath
+	}
+	if o.TrustDomain == "" {
+		return ErrMissingTrustDomain
+	}
+	if o.RefreshIntervalSecs <= 0 {
+		return ErrInvalidRefreshInterval
+	}
+	return ValidateIDs(o.FrontendAllowedIDs)
+}
+
+// ValidateIDs returns an error if any of the ids is not a SPIFFE ID
+func ValidateIDs(ids []string) error {
+	for _, id := range ids {
+		if !strings.HasPrefix(id, "spiffe://") {
+			return ErrInvalidID
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+
+	o := NewOptions()
+	if o.Enabled() {
+		t.Error("expected disabled options")
+	}
+	if err := o.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	o.SVIDCertPath = "svid.pem"
+	tests := []struct {
+		f        func(*Options)
+		expected error
+	}{
+		{func(o *Options) {}, ErrMissingKeyPath},
+		{func(o *Options) { o.SVIDKeyPath = "svid_key.pem" }, ErrMissingBundlePath},
+		{func(o *Options) { o.BundlePath = "bundle.pem" }, ErrMissingTrustDomain},
+		{func(o *Options) { o.TrustDomain = "example.org" }, nil},
+		{func(o *Options) { o.RefreshIntervalSecs = 0 }, ErrInvalidRefreshInterval},
+		{func(o *Options) { o.RefreshIntervalSecs = 10 }, nil},
+		{func(o *Options) { o.FrontendAllowedIDs = []string{"example.org/client"} }, ErrInvalidID},
+		{func(o *Options) { o.FrontendAllowedIDs = []string{"spiffe://example.org/client"} }, nil},
+	}
+
+	for i, test := range tests {
+		test.f(o)
+		if err := o.Validate(); err != test.expected {
+			t.Errorf("(%d) expected %v got %v", i, test.expected, err)
+		}
+	}
+
+	o2 := o.Clone()
+	if !o2.Equal(o) {
+		t.Error("expected clone to be equal")
+	}
+	o2.FrontendAllowedIDs[0] = "spiffe://example.org/other"
+	if o2.Equal(o) {
+		t.Error("expected clone to be independent")
+	}
+	if o.Equal(nil) {
+		t.Error("expected options to not equal nil")
+	}
+}
@@ -0,0 +1,286 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package spiffe provides the SPIFFE workload identity of the process: the X.509 SVID
+// that it presents to its peers, and the trust bundle that verifies theirs. The SVID and
+// bundle are read from the files that the SPIRE Agent or spiffe-helper keep up to date,
+// and are reloaded when those files are rotated
+package spiffe
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/tls/spiffe/options"
+	"github.com/tricksterproxy/trickster/pkg/util/strings"
+)
+
+// ErrNoSVID is returned by the TLS configs when the process has no workload identity
+var ErrNoSVID = errors.New("no spiffe svid is available")
+
+// ErrNoPeerCertificate is returned when a peer presents no certificate
+var ErrNoPeerCertificate = errors.New("peer presented no certificate")
+
+// ErrNoSpiffeID is returned when a certificate does not have exactly one SPIFFE ID
+var ErrNoSpiffeID = errors.New("certificate does not have exactly one spiffe id")
+
+// svid is a loaded SVID and trust bundle, and the modification times of their files
+type svid struct {
+	cert     *tls.Certificate
+	id       string
+	bundle   *x509.CertPool
+	modTimes [3]time.Time
+}
+
+// Source is a workload identity that is read from the SVID and bundle files
+type Source struct {
+	opts     *options.Options
+	mtx      sync.RWMutex
+	cur      *svid
+	quit     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewSource returns a Source for the Options, with the SVID and bundle files loaded
+func NewSource(o *options.Options) (*Source, error) {
+	v, err := load(o)
+	if err != nil {
+		return nil, err
+	}
+	return &Source{opts: o, cur: v, quit: make(chan struct{})}, nil
+}
+
+// Options returns the Options of the Source
+func (s *Source) Options() *options.Options {
+	return s.opts
+}
+
+// ID returns the SPIFFE ID of the Source's SVID
+func (s *Source) ID() string {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.cur.id
+}
+
+func (s *Source) certificate() *tls.Certificate {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.cur.cert
+}
+
+// Start checks the files for rotation at the refresh interval, until the Source is
+// stopped. A rotation that fails to load is reported to onError, and the current SVID
+// remains in use
+func (s *Source) Start(onError func(error)) {
+	go func() {
+		t := time.NewTicker(time.Duration(s.opts.RefreshIntervalSecs) * time.Second)
+		defer t.Stop()
+		for {
+			select {
+			case <-s.quit:
+				return
+			case <-t.C:
+				if err := s.refresh(); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops checking the files for rotation
+func (s *Source) Stop() {
+	s.stopOnce.Do(func() { close(s.quit) })
+}
+
+// refresh reloads the files when any of them was modified since they were loaded
+func (s *Source) refresh() error {
+	mt, err := modTimes(s.opts)
+	if err != nil {
+		return err
+	}
+	s.mtx.RLock()
+	unchanged := mt == s.cur.modTimes
+	s.mtx.RUnlock()
+	if unchanged {
+		return nil
+	}
+	v, err := load(s.opts)
+	if err != nil {
+		return err
+	}
+	s.mtx.Lock()
+	s.cur = v
+	s.mtx.Unlock()
+	return nil
+}
+
+// verifyPeer verifies that the peer's certificate chain is an SVID from the trust
+// domain that is signed by the trust bundle, and that its ID is one of ids, if provided
+func (s *Source) verifyPeer(rawCerts [][]byte, ids []string) error {
+	if len(rawCerts) == 0 {
+		return ErrNoPeerCertificate
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		c, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		certs[i] = c
+	}
+	id, err := IDFromCertificate(certs[0])
+	if err != nil {
+		return err
+	}
+	u, _ := url.Parse(id)
+	if u.Host != s.opts.TrustDomain {
+		return fmt.Errorf("spiffe id %s is not in trust domain %s", id, s.opts.TrustDomain)
+	}
+	if len(ids) > 0 && strings.IndexOfString(ids, id) < 0 {
+		return fmt.Errorf("spiffe id %s is not allowed", id)
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	s.mtx.RLock()
+	roots := s.cur.bundle
+	s.mtx.RUnlock()
+	_, err = certs[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+	return err
+}
+
+// IDFromCertificate returns the SPIFFE ID of the certificate, which is its only URI SAN
+func IDFromCertificate(c *x509.Certificate) (string, error) {
+	if len(c.URIs) != 1 || c.URIs[0].Scheme != "spiffe" || c.URIs[0].Host == "" {
+		return "", ErrNoSpiffeID
+	}
+	return c.URIs[0].String(), nil
+}
+
+func modTimes(o *options.Options) ([3]time.Time, error) {
+	var mt [3]time.Time
+	for i, path := range []string{o.SVIDCertPath, o.SVIDKeyPath, o.BundlePath} {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return mt, err
+		}
+		mt[i] = fi.ModTime()
+	}
+	return mt, nil
+}
+
+func load(o *options.Options) (*svid, error) {
+	mt, err := modTimes(o)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(o.SVIDCertPath, o.SVIDKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	id, err := IDFromCertificate(cert.Leaf)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(o.BundlePath)
+	if err != nil {
+		return nil, err
+	}
+	bundle := x509.NewCertPool()
+	if !bundle.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates in spiffe bundle %s", o.BundlePath)
+	}
+	return &svid{cert: &cert, id: id, bundle: bundle, modTimes: mt}, nil
+}
+
+var current *Source
+var currentLock sync.RWMutex
+
+// SetSource sets the workload identity of the process, and returns the previous one
+func SetSource(s *Source) *Source {
+	currentLock.Lock()
+	defer currentLock.Unlock()
+	prev := current
+	current = s
+	return prev
+}
+
+// CurrentSource returns the workload identity of the process, if any
+func CurrentSource() *Source {
+	currentLock.RLock()
+	defer currentLock.RUnlock()
+	return current
+}
+
+// ServerTLSConfig returns a TLS config that presents the process's SVID, and requires
+// each client to present an SVID that is allowed by the workload identity's options
+func ServerTLSConfig() *tls.Config {
+	return &tls.Config{
+		NextProtos: []string{"h2"},
+		ClientAuth: tls.RequireAnyClientCert,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			s := CurrentSource()
+			if s == nil {
+				return nil, ErrNoSVID
+			}
+			return s.certificate(), nil
+		},
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			s := CurrentSource()
+			if s == nil {
+				return ErrNoSVID
+			}
+			return s.verifyPeer(rawCerts, s.opts.FrontendAllowedIDs)
+		},
+	}
+}
+
+// ClientTLSConfig returns a TLS config that presents the process's SVID to upstream
+// servers, and requires each of them to present an SVID with one of ids, if provided
+func ClientTLSConfig(ids []string) *tls.Config {
+	return &tls.Config{
+		// the server's SVID is verified by its SPIFFE ID below, rather than its hostname
+		InsecureSkipVerify: true,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			s := CurrentSource()
+			if s == nil {
+				return nil, ErrNoSVID
+			}
+			return s.certificate(), nil
+		},
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			s := CurrentSource()
+			if s == nil {
+				return ErrNoSVID
+			}
+			return s.verifyPeer(rawCerts, ids)
+		},
+	}
+}
@@ -0,0 +1,253 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spiffe
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/tls/spiffe/options"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key}
+}
+
+// writeSVID writes an SVID for the id, signed by the CA, and the CA's bundle to dir
+func (ca *testCA) writeSVID(t *testing.T, dir, id string) *options.Options {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(id)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		URIs:         []*url.URL{u},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kb, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := options.NewOptions()
+	o.SVIDCertPath = filepath.Join(dir, "svid.pem")
+	o.SVIDKeyPath = filepath.Join(dir, "svid_key.pem")
+	o.BundlePath = filepath.Join(dir, "bundle.pem")
+	o.TrustDomain = "example.org"
+	for path, block := range map[string]*pem.Block{
+		o.SVIDCertPath: {Type: "CERTIFICATE", Bytes: der},
+		o.SVIDKeyPath:  {Type: "PRIVATE KEY", Bytes: kb},
+		o.BundlePath:   {Type: "CERTIFICATE", Bytes: ca.cert.Raw},
+	} {
+		if err := ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return o
+}
+
+func TestSource(t *testing.T) {
+
+	td, err := ioutil.TempDir("", "trickster-spiffe-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	ca := newTestCA(t)
+	o := ca.writeSVID(t, td, "spiffe://example.org/trickster")
+
+	s, err := NewSource(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ID() != "spiffe://example.org/trickster" {
+		t.Errorf("expected %s got %s", "spiffe://example.org/trickster", s.ID())
+	}
+
+	// unchanged files are not reloaded
+	cert := s.certificate()
+	if err = s.refresh(); err != nil {
+		t.Error(err)
+	}
+	if s.certificate() != cert {
+		t.Error("expected the svid to be unchanged")
+	}
+
+	// rotated files are reloaded
+	ca.writeSVID(t, td, "spiffe://example.org/rotated")
+	future := time.Now().Add(time.Minute)
+	for _, path := range []string{o.SVIDCertPath, o.SVIDKeyPath, o.BundlePath} {
+		os.Chtimes(path, future, future)
+	}
+	if err = s.refresh(); err != nil {
+		t.Error(err)
+	}
+	if s.ID() != "spiffe://example.org/rotated" {
+		t.Errorf("expected %s got %s", "spiffe://example.org/rotated", s.ID())
+	}
+
+	// a rotation that fails to load keeps the current svid
+	ioutil.WriteFile(o.SVIDKeyPath, []byte("invalid"), 0600)
+	future = future.Add(time.Minute)
+	os.Chtimes(o.SVIDKeyPath, future, future)
+	if err = s.refresh(); err == nil {
+		t.Error("expected error for invalid key")
+	}
+	if s.ID() != "spiffe://example.org/rotated" {
+		t.Errorf("expected %s got %s", "spiffe://example.org/rotated", s.ID())
+	}
+
+	o.SVIDCertPath = filepath.Join(td, "missing.pem")
+	if _, err = NewSource(o); err == nil {
+		t.Error("expected error for missing svid")
+	}
+}
+
+func TestTLSConfigs(t *testing.T) {
+
+	td, err := ioutil.TempDir("", "trickster-spiffe-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+
+	ca := newTestCA(t)
+	o := ca.writeSVID(t, td, "spiffe://example.org/trickster")
+	s, err := NewSource(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(ids []string) error {
+		svr := httptest.NewUnstartedServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {}))
+		svr.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+		svr.Listener = tls.NewListener(svr.Listener, ServerTLSConfig())
+		svr.Start()
+		defer svr.Close()
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: ClientTLSConfig(ids)}}
+		resp, err := client.Get(strings.Replace(svr.URL, "http:", "https:", 1))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	// without a workload identity, handshakes fail
+	SetSource(nil)
+	if err = get(nil); err == nil {
+		t.Error("expected error without an svid")
+	}
+
+	SetSource(s)
+	defer SetSource(nil)
+
+	if err = get(nil); err != nil {
+		t.Error(err)
+	}
+	if err = get([]string{"spiffe://example.org/trickster"}); err != nil {
+		t.Error(err)
+	}
+	if err = get([]string{"spiffe://example.org/other"}); err == nil {
+		t.Error("expected error for an upstream id that is not allowed")
+	}
+
+	o.FrontendAllowedIDs = []string{"spiffe://example.org/other"}
+	if err = get(nil); err == nil {
+		t.Error("expected error for a client id that is not allowed")
+	}
+	o.FrontendAllowedIDs = nil
+
+	o.TrustDomain = "example.com"
+	if err = get(nil); err == nil {
+		t.Error("expected error for an svid from another trust domain")
+	}
+	o.TrustDomain = "example.org"
+
+	// an svid that is not signed by the bundle is rejected
+	other := newTestCA(t)
+	os.Mkdir(filepath.Join(td, "other"), 0700)
+	oo := other.writeSVID(t, filepath.Join(td, "other"), "spiffe://example.org/trickster")
+	s2, err := NewSource(oo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s2.verifyPeer([][]byte{s.certificate().Certificate[0]}, nil); err == nil {
+		t.Error("expected error for an svid signed by another ca")
+	}
+	if err = s2.verifyPeer(nil, nil); err != ErrNoPeerCertificate {
+		t.Errorf("expected %v got %v", ErrNoPeerCertificate, err)
+	}
+}
+
+func TestIDFromCertificate(t *testing.T) {
+	if _, err := IDFromCertificate(&x509.Certificate{}); err != ErrNoSpiffeID {
+		t.Errorf("expected %v got %v", ErrNoSpiffeID, err)
+	}
+	u, _ := url.Parse("https://example.org/trickster")
+	if _, err := IDFromCertificate(&x509.Certificate{URIs: []*url.URL{u}}); err != ErrNoSpiffeID {
+		t.Errorf("expected %v got %v", ErrNoSpiffeID, err)
+	}
+}
@@ -18,8 +18,12 @@ package tls
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"fmt"
+	"os"
 	"sync"
+	"time"
 )
 
 // CertSwapper is used by a TLSConfig to dynamically update the running Listener's Certificate list
@@ -27,6 +31,14 @@ import (
 type CertSwapper struct {
 	*sync.Mutex
 	Certificates []tls.Certificate
+	// quit stops the running watch, if any
+	quit chan struct{}
+}
+
+// KeyPair is the paths of a certificate file and its private key file
+type KeyPair struct {
+	CertPath string
+	KeyPath  string
 }
 
 var errNoCertificates = errors.New("tls: no certificates configured")
@@ -69,3 +81,123 @@ func (c *CertSwapper) SetCerts(certs []tls.Certificate) {
 	defer c.Unlock()
 	c.Certificates = certs
 }
+
+// Watch checks the files of the key pairs for changes at the interval, until the watch is
+// stopped, and swaps in the certificates loaded from them when any of them change. When the
+// files fail to load or validate, such as when a certificate has been written without its
+// new key, the error is reported to onError, the current certificates remain in use, and
+// the files are loaded again on the next check. onSwap, when not nil, is called after each
+// swap. Watch replaces any running watch
+func (c *CertSwapper) Watch(pairs []KeyPair, interval time.Duration, onSwap func(),
+	onError func(error)) {
+	c.StopWatch()
+	if len(pairs) == 0 || interval <= 0 {
+		return
+	}
+	quit := make(chan struct{})
+	c.Lock()
+	c.quit = quit
+	c.Unlock()
+	// the running certificates are assumed to have been loaded from the files as they are now
+	mt, err := keyPairModTimes(pairs)
+	if err != nil {
+		// the files are loaded once they can all be found
+		mt = make([]time.Time, len(pairs)*2)
+	}
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case <-t.C:
+				swapped, err := c.refresh(pairs, mt)
+				if err != nil {
+					if onError != nil {
+						onError(err)
+					}
+					continue
+				}
+				if swapped && onSwap != nil {
+					onSwap()
+				}
+			}
+		}
+	}()
+}
+
+// StopWatch stops the running watch, if any
+func (c *CertSwapper) StopWatch() {
+	c.Lock()
+	defer c.Unlock()
+	if c.quit != nil {
+		close(c.quit)
+		c.quit = nil
+	}
+}
+
+// refresh swaps in the certificates of the key pairs when the modification time of any of
+// their files differs from mt, which is then updated, and returns true if they were swapped
+func (c *CertSwapper) refresh(pairs []KeyPair, mt []time.Time) (bool, error) {
+	cur, err := keyPairModTimes(pairs)
+	if err != nil {
+		return false, err
+	}
+	changed := false
+	for i := range cur {
+		if !cur[i].Equal(mt[i]) {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+	certs, err := LoadKeyPairs(pairs)
+	if err != nil {
+		return false, err
+	}
+	c.SetCerts(certs)
+	copy(mt, cur)
+	return true, nil
+}
+
+// LoadKeyPairs loads the certificates of the key pairs, and validates that each of them
+// parses and is within its validity period
+func LoadKeyPairs(pairs []KeyPair) ([]tls.Certificate, error) {
+	certs := make([]tls.Certificate, len(pairs))
+	now := time.Now()
+	for i, p := range pairs {
+		cert, err := tls.LoadX509KeyPair(p.CertPath, p.KeyPath)
+		if err != nil {
+			return nil, err
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return nil, err
+		}
+		if now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+			return nil, fmt.Errorf("tls: certificate %s is not valid at the current time", p.CertPath)
+		}
+		cert.Leaf = leaf
+		certs[i] = cert
+	}
+	return certs, nil
+}
+
+// keyPairModTimes returns the modification times of the certificate and key files of the
+// key pairs, in order
+func keyPairModTimes(pairs []KeyPair) ([]time.Time, error) {
+	mt := make([]time.Time, 0, len(pairs)*2)
+	for _, p := range pairs {
+		for _, path := range []string{p.CertPath, p.KeyPath} {
+			fi, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			mt = append(mt, fi.ModTime())
+		}
+	}
+	return mt, nil
+}
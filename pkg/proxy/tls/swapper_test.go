@@ -17,8 +17,13 @@
 package tls
 
 import (
+	"bytes"
 	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func getSwapper(id string, t *testing.T) (*CertSwapper, *tls.Config) {
@@ -66,3 +71,95 @@ func TestGetSetCert(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+// writeKeyPair copies the test key pair with the id to the paths, with a modification
+// time of mt
+func writeKeyPair(t *testing.T, id string, p KeyPair, mt time.Time) {
+	o := tlsConfig(id)
+	for src, dst := range map[string]string{o.FullChainCertPath: p.CertPath,
+		o.PrivateKeyPath: p.KeyPath} {
+		b, err := ioutil.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(dst, b, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.Chtimes(dst, mt, mt); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "trickster-certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := KeyPair{CertPath: filepath.Join(dir, "cert.pem"), KeyPath: filepath.Join(dir, "key.pem")}
+	mt := time.Now().Add(-time.Hour)
+	writeKeyPair(t, "01", p, mt)
+
+	certs, err := LoadKeyPairs([]KeyPair{p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sw := NewSwapper(certs)
+
+	swaps := make(chan struct{}, 10)
+	errs := make(chan error, 10)
+	sw.Watch([]KeyPair{p}, 10*time.Millisecond, func() { swaps <- struct{}{} },
+		func(err error) { errs <- err })
+	defer sw.StopWatch()
+
+	// a rotated key pair is swapped in
+	writeKeyPair(t, "02", p, mt.Add(time.Minute))
+	select {
+	case <-swaps:
+	case err := <-errs:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the certificates to be swapped")
+	}
+	expected, _ := LoadKeyPairs([]KeyPair{{CertPath: tlsConfig("02").FullChainCertPath,
+		KeyPath: tlsConfig("02").PrivateKeyPath}})
+	sw.Lock()
+	got := sw.Certificates[0].Certificate[0]
+	sw.Unlock()
+	if !bytes.Equal(got, expected[0].Certificate[0]) {
+		t.Error("expected the rotated certificate")
+	}
+
+	// a certificate that fails to parse leaves the current certificates in use
+	if err = ioutil.WriteFile(p.CertPath, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(p.CertPath, mt.Add(2*time.Minute), mt.Add(2*time.Minute))
+	select {
+	case <-errs:
+	case <-swaps:
+		t.Fatal("expected the invalid certificate not to be swapped in")
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected an error for the invalid certificate")
+	}
+	sw.Lock()
+	got = sw.Certificates[0].Certificate[0]
+	sw.Unlock()
+	if !bytes.Equal(got, expected[0].Certificate[0]) {
+		t.Error("expected the current certificate to remain in use")
+	}
+
+	// a stopped watch no longer swaps certificates
+	sw.StopWatch()
+	for len(errs) > 0 {
+		<-errs
+	}
+	writeKeyPair(t, "01", p, mt.Add(3*time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	if len(swaps) != 0 {
+		t.Error("expected no swaps after the watch was stopped")
+	}
+}
@@ -94,6 +94,26 @@ func TestVerifyTLSConfigs(t *testing.T) {
 	if err == nil {
 		t.Error("expected no such file or directory error")
 	}
+
+	// the upstream client options are valid without a frontend cert and key
+	upstream := &options.Options{
+		CertificateAuthorityPaths: []string{originalFile},
+		ClientCertPath:            "../../../testdata/test.01.cert.pem",
+		ClientKeyPath:             "../../../testdata/test.01.key.pem",
+	}
+	b, err := upstream.Validate()
+	if err != nil {
+		t.Error(err)
+	}
+	if b || upstream.ServeTLS {
+		t.Error("expected the frontend to not serve tls")
+	}
+
+	upstream.ClientKeyPath = ""
+	_, err = upstream.Validate()
+	if err != options.ErrIncompleteClientCert {
+		t.Errorf("expected %v got %v", options.ErrIncompleteClientCert, err)
+	}
 }
 
 func TestProcessTLSConfigs(t *testing.T) {
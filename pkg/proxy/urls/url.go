@@ -19,8 +19,12 @@
 package urls
 
 import (
+	"fmt"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 )
 
 // Clone returns a deep copy of a *url.URL
@@ -59,3 +63,35 @@ func BuildUpstreamURL(r *http.Request, u *url.URL) *url.URL {
 	u2.User = r.URL.User
 	return u2
 }
+
+// ValidateHost returns an error if the host portion of a URL contains an IPv6 literal
+// that is not enclosed in brackets, or brackets that do not enclose a valid IPv6 literal.
+// Without the brackets, the port of a URL like http://::1:9090/ can't be told apart from
+// the address, and the URL is dialed inconsistently
+func ValidateHost(host string) error {
+	if strings.HasPrefix(host, "[") {
+		i := strings.LastIndex(host, "]")
+		if i < 0 {
+			return fmt.Errorf("missing ']' in host %q", host)
+		}
+		addr := host[1:i]
+		// a zone identifier, as in [fe80::1%25en0], is not part of the address
+		if z := strings.Index(addr, "%"); z >= 0 {
+			addr = addr[:z]
+		}
+		if net.ParseIP(addr) == nil || !strings.Contains(addr, ":") {
+			return fmt.Errorf("invalid IPv6 literal in host %q", host)
+		}
+		if rest := host[i+1:]; rest != "" {
+			if _, err := strconv.ParseUint(strings.TrimPrefix(rest, ":"), 10, 16); err != nil ||
+				rest[0] != ':' {
+				return fmt.Errorf("invalid port %q after IPv6 literal in host %q", rest, host)
+			}
+		}
+		return nil
+	}
+	if strings.Count(host, ":") > 1 {
+		return fmt.Errorf("IPv6 literal must be enclosed in brackets in host %q", host)
+	}
+	return nil
+}
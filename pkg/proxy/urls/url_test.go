@@ -47,3 +47,33 @@ func TestBuildUpstreamURL(t *testing.T) {
 		t.Errorf("expected %s got %s", expected, u2.Path)
 	}
 }
+
+func TestValidateHost(t *testing.T) {
+	tests := []struct {
+		host    string
+		isValid bool
+	}{
+		{"127.0.0.1:8080", true},
+		{"example.com", true},
+		{"[::1]", true},
+		{"[::1]:9090", true},
+		{"[fe80::1%25en0]:9090", true},
+		{"::1:9090", false},
+		{"::1", false},
+		{"[::1", false},
+		{"[127.0.0.1]:9090", false},
+		{"[not-an-ip]", false},
+		{"[::1]9090", false},
+		{"[::1]:port", false},
+	}
+	for _, test := range tests {
+		t.Run(test.host, func(t *testing.T) {
+			err := ValidateHost(test.host)
+			if test.isValid && err != nil {
+				t.Error(err)
+			} else if !test.isValid && err == nil {
+				t.Errorf("expected error for host %s", test.host)
+			}
+		})
+	}
+}
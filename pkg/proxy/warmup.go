@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+)
+
+// WarmUp resolves the origin's host name, and then opens the origin's configured number of
+// warm connections by sending concurrent HEAD requests to the origin URL. The connections
+// are kept alive in the HTTP client's idle pool, so that the first requests proxied to the
+// origin do not wait on DNS resolution or TLS handshakes. It returns the number of
+// connections that were opened
+func WarmUp(ctx context.Context, oc *oo.Options) (int, error) {
+
+	if oc == nil || oc.HTTPClient == nil || oc.WarmConnections < 1 {
+		return 0, nil
+	}
+
+	host, _, err := net.SplitHostPort(oc.Host)
+	if err != nil {
+		host = oc.Host
+	}
+	if _, err := net.DefaultResolver.LookupHost(ctx, host); err != nil {
+		return 0, err
+	}
+
+	// connections beyond the idle limit would be closed as soon as they are released
+	n := oc.WarmConnections
+	if oc.MaxIdleConns > 0 && n > oc.MaxIdleConns {
+		n = oc.MaxIdleConns
+	}
+
+	u := oc.Scheme + "://" + oc.Host + oc.PathPrefix + "/"
+	var opened int32
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := http.NewRequestWithContext(ctx, http.MethodHead, u, nil)
+			if err != nil {
+				return
+			}
+			resp, err := oc.HTTPClient.Do(r)
+			if err != nil {
+				return
+			}
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+			atomic.AddInt32(&opened, 1)
+		}()
+	}
+	wg.Wait()
+
+	return int(opened), nil
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+)
+
+func TestWarmUp(t *testing.T) {
+
+	var conns int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/prefix/" {
+			t.Errorf("unexpected request path %s", r.URL.Path)
+		}
+	}))
+	ts.Config.ConnState = func(c net.Conn, s http.ConnState) {
+		if s == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	oc := oo.NewOptions()
+	oc.Scheme = u.Scheme
+	oc.Host = u.Host
+	oc.PathPrefix = "/prefix"
+	oc.MaxIdleConns = 3
+	oc.WarmConnections = 5
+
+	// no client
+	n, err := WarmUp(context.Background(), oc)
+	if n != 0 || err != nil {
+		t.Errorf("expected 0 <nil> got %d %v", n, err)
+	}
+
+	oc.HTTPClient, _ = NewHTTPClient(oc)
+	n, err = WarmUp(context.Background(), oc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the warm connections are limited to the idle connection limit
+	if n != 3 {
+		t.Errorf("expected %d got %d", 3, n)
+	}
+
+	// the warm connections are reused by subsequent requests
+	resp, err := oc.HTTPClient.Get(ts.URL + "/prefix/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if c := atomic.LoadInt32(&conns); c > 3 {
+		t.Errorf("expected at most %d connections got %d", 3, c)
+	}
+
+	oc.Host = "invalid.invalid:80"
+	if _, err = WarmUp(context.Background(), oc); err == nil {
+		t.Error("expected error for unresolvable host")
+	}
+}
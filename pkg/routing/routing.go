@@ -18,14 +18,24 @@
 package routing
 
 import (
+	"context"
+	"expvar"
 	"fmt"
 	"net/http"
 	"net/http/pprof"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/tricksterproxy/trickster/pkg/cache"
 	"github.com/tricksterproxy/trickster/pkg/config"
+	do "github.com/tricksterproxy/trickster/pkg/config/debug/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy"
+	"github.com/tricksterproxy/trickster/pkg/proxy/engines"
+	"github.com/tricksterproxy/trickster/pkg/proxy/handlers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/health"
+	"github.com/tricksterproxy/trickster/pkg/proxy/hosts"
 	"github.com/tricksterproxy/trickster/pkg/proxy/methods"
 	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
 	"github.com/tricksterproxy/trickster/pkg/proxy/origins/clickhouse"
@@ -38,7 +48,10 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/proxy/origins/types"
 	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/priority"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request/hooks"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter"
+	"github.com/tricksterproxy/trickster/pkg/proxy/targets"
 	"github.com/tricksterproxy/trickster/pkg/tracing"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
 	"github.com/tricksterproxy/trickster/pkg/util/middleware"
@@ -46,14 +59,27 @@ import (
 	"github.com/gorilla/mux"
 )
 
-// RegisterPprofRoutes will register the Pprof Debugging endpoints to the provided router
-func RegisterPprofRoutes(routerName string, h *http.ServeMux, log *tl.Logger) {
+// RegisterDebugRoutes will register the pprof debugging endpoints to the provided router,
+// along with the expvar and runtime snapshot endpoints when the debug options enable them.
+// When the debug options have a username, the endpoints require HTTP Basic Authentication
+func RegisterDebugRoutes(routerName string, h *http.ServeMux, o *do.Options, log *tl.Logger) {
 	log.Info("registering pprof /debug routes", tl.Pairs{"routerName": routerName})
-	h.HandleFunc("/debug/pprof/", pprof.Index)
-	h.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
-	h.HandleFunc("/debug/pprof/profile", pprof.Profile)
-	h.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-	h.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	handle := func(path string, f http.HandlerFunc) {
+		var hf http.Handler = f
+		if o.AuthEnabled() {
+			hf = middleware.BasicAuth("trickster debug", o.Username, o.Password, hf)
+		}
+		h.Handle(path, hf)
+	}
+	handle("/debug/pprof/", pprof.Index)
+	handle("/debug/pprof/cmdline", pprof.Cmdline)
+	handle("/debug/pprof/profile", pprof.Profile)
+	handle("/debug/pprof/symbol", pprof.Symbol)
+	handle("/debug/pprof/trace", pprof.Trace)
+	if o.RuntimeEndpointsEnabled() {
+		handle("/debug/vars", expvar.Handler().ServeHTTP)
+		handle("/debug/snapshot", handlers.RuntimeSnapshotHandleFunc())
+	}
 }
 
 // RegisterProxyRoutes iterates the Trickster Configuration and
@@ -61,6 +87,57 @@ func RegisterPprofRoutes(routerName string, h *http.ServeMux, log *tl.Logger) {
 func RegisterProxyRoutes(conf *config.Config, router *mux.Router,
 	caches map[string]cache.Cache, tracers tracing.Tracers,
 	log *tl.Logger, dryRun bool) (origins.Origins, error) {
+	pr, err := buildProxyRoutes(conf, router, caches, tracers, log, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		pr.Start()
+	}
+	return pr.Clients, nil
+}
+
+// ProxyRoutes are the origin clients whose routes were registered for a config. Their
+// origin loggers and background tasks are not registered or started until Start is called
+type ProxyRoutes struct {
+	// Clients are the origin clients, by origin name
+	Clients origins.Origins
+
+	caches        map[string]cache.Cache
+	tracers       tracing.Tracers
+	log           *tl.Logger
+	originLoggers *tl.OriginLoggers
+	loggers       map[string]*tl.Logger
+}
+
+// BuildProxyRoutes registers the routes for the configured origins, as
+// RegisterProxyRoutes does, without affecting the running config's origin loggers
+// and background tasks, so that the routes can be discarded
+func BuildProxyRoutes(conf *config.Config, router *mux.Router,
+	caches map[string]cache.Cache, tracers tracing.Tracers,
+	log *tl.Logger) (*ProxyRoutes, error) {
+	return buildProxyRoutes(conf, router, caches, tracers, log, false)
+}
+
+// Start registers the origin loggers in place of those of the running config, warms up
+// the origins' connections, and starts their background tasks in place of those of the
+// running config
+func (pr *ProxyRoutes) Start() {
+	pr.originLoggers.Register()
+	for k, client := range pr.Clients {
+		if o := client.Configuration(); o != nil && o.WarmConnections > 0 &&
+			o.HTTPClient != nil {
+			go warmUpOrigin(k, o, pr.log)
+		}
+	}
+	startHealthMonitors(pr.Clients, pr.tracers, pr.log)
+	startTargetSelectors(pr.Clients, pr.log)
+	startRetentionJanitors(pr.Clients, pr.caches, pr.loggers)
+}
+
+func buildProxyRoutes(conf *config.Config, router *mux.Router,
+	caches map[string]cache.Cache, tracers tracing.Tracers,
+	log *tl.Logger, dryRun bool) (*ProxyRoutes, error) {
 
 	// a fake "top-level" origin representing the main frontend, so rules can route
 	// to it via the clients map
@@ -70,12 +147,38 @@ func RegisterProxyRoutes(conf *config.Config, router *mux.Router,
 	var clients = origins.Origins{"frontend": tlo}
 	var err error
 
+	pr := &ProxyRoutes{caches: caches, tracers: tracers, log: log,
+		originLoggers: log.NewOriginLoggers(), loggers: make(map[string]*tl.Logger)}
+
+	// the aggregated health handler must be registered ahead of the origin routes, so
+	// that it is not shadowed by a default origin's catch-all route
+	var hc *health.Checker
+	if !dryRun && conf.Main.HealthHandlerPath != "" {
+		hc = health.NewChecker()
+		router.Handle(conf.Main.HealthHandlerPath, hc).Methods(http.MethodGet, http.MethodHead)
+	}
+
+	// batch queries are routed by the routers of the origins registered below, which are
+	// resolved by name, so that origins without path routing can be queried
+	if !dryRun && conf.Main.BatchHandlerPath != "" {
+		router.HandleFunc(conf.Main.BatchHandlerPath,
+			handlers.BatchHandleFunc(originRouter(conf), conf.Main.BatchConcurrency)).
+			Methods(http.MethodPost)
+	}
+
+	// inspected requests are also routed back through the router, marked for inspection
+	if !dryRun && conf.Main.InspectHandlerPath != "" {
+		p := strings.TrimSuffix(conf.Main.InspectHandlerPath, "/")
+		router.PathPrefix(p + "/").HandlerFunc(handlers.InspectHandleFunc(p, router))
+	}
+
 	defaultOrigin := ""
 	var ndo *oo.Options // points to the origin config named "default"
 	var cdo *oo.Options // points to the origin config with IsDefault set to true
 
 	// This iteration will ensure default origins are handled properly
-	for k, o := range conf.Origins {
+	for _, k := range sortedOriginNames(conf.Origins) {
+		o := conf.Origins[k]
 
 		if !types.IsValidOriginType(o.OriginType) {
 			return nil,
@@ -103,7 +206,7 @@ func RegisterProxyRoutes(conf *config.Config, router *mux.Router,
 			continue
 		}
 
-		_, err = registerOriginRoutes(router, conf, k, o, clients, caches, tracers, log, dryRun)
+		_, err = registerOriginRoutes(router, conf, k, o, clients, caches, tracers, pr, dryRun)
 		if err != nil {
 			return nil, err
 		}
@@ -115,7 +218,8 @@ func RegisterProxyRoutes(conf *config.Config, router *mux.Router,
 			cdo = ndo
 			defaultOrigin = "default"
 		} else {
-			_, err = registerOriginRoutes(router, conf, "default", ndo, clients, caches, tracers, log, dryRun)
+			_, err = registerOriginRoutes(router, conf, "default", ndo, clients, caches, tracers, pr,
+				dryRun)
 			if err != nil {
 				return nil, err
 			}
@@ -123,7 +227,8 @@ func RegisterProxyRoutes(conf *config.Config, router *mux.Router,
 	}
 
 	if cdo != nil {
-		clients, err = registerOriginRoutes(router, conf, defaultOrigin, cdo, clients, caches, tracers, log, dryRun)
+		clients, err = registerOriginRoutes(router, conf, defaultOrigin, cdo, clients, caches,
+			tracers, pr, dryRun)
 		if err != nil {
 			return nil, err
 		}
@@ -134,7 +239,220 @@ func RegisterProxyRoutes(conf *config.Config, router *mux.Router,
 		return nil, err
 	}
 
-	return clients, nil
+	// the origins are only given their HTTP clients once all of their routes are registered
+	for k, o := range conf.Origins {
+		if client, ok := clients[k]; ok {
+			o.HTTPClient = client.HTTPClient()
+		}
+	}
+
+	if hc != nil {
+		registerHealthChecks(hc, clients, caches, tracers, log)
+	}
+
+	pr.Clients = clients
+	return pr, nil
+}
+
+// originRouter returns a lookup of the routers of the config's origins, which are set
+// once their routes are registered
+func originRouter(conf *config.Config) handlers.OriginRouter {
+	return func(originName string) http.Handler {
+		if o, ok := conf.Origins[originName]; ok && o.Router != nil {
+			return o.Router
+		}
+		return nil
+	}
+}
+
+// monitors are the background health monitors of the running config's origins
+var monitors []*health.Monitor
+var monitorsLock sync.Mutex
+
+// startHealthMonitors stops the health monitors of any previous config, and starts one for
+// each origin with degraded mode enabled, which marks the origin as down or up
+func startHealthMonitors(clients origins.Origins, tracers tracing.Tracers, log *tl.Logger) {
+	monitorsLock.Lock()
+	defer monitorsLock.Unlock()
+	for _, m := range monitors {
+		m.Stop()
+	}
+	monitors = nil
+	for k, client := range clients {
+		oc := client.Configuration()
+		if oc == nil || !oc.DegradedMode {
+			continue
+		}
+		h, ok := client.Handlers()["health"]
+		if !ok || oc.HealthCheckUpstreamPath == "" || oc.HealthCheckVerb == "" {
+			log.Warn("degraded mode requires a health check", tl.Pairs{"originName": k})
+			continue
+		}
+		name := k
+		m := health.NewMonitor(oc.OriginType, middleware.WithResourcesContext(client, oc, nil, nil,
+			tracers[oc.TracingConfigName], log, h), oc.HealthCheckInterval,
+			oc.HealthCheckFailureThreshold, func(down bool) {
+				oc.SetDown(down)
+				if down {
+					log.Warn("origin is down, serving cached data only",
+						tl.Pairs{"originName": name})
+					return
+				}
+				log.Info("origin is back up", tl.Pairs{"originName": name})
+			})
+		m.Start()
+		monitors = append(monitors, m)
+	}
+}
+
+// selectors are the target selectors of the running config's origins, whose background
+// health checks are running
+var selectors []*targets.Selector
+var selectorsLock sync.Mutex
+
+// startTargetSelectors stops the target health checks of any previous config, and starts
+// them for each origin with target URLs, so that its requests are sent to its closest
+// healthy target
+func startTargetSelectors(clients origins.Origins, log *tl.Logger) {
+	selectorsLock.Lock()
+	defer selectorsLock.Unlock()
+	for _, s := range selectors {
+		s.Stop()
+	}
+	selectors = nil
+	for k, client := range clients {
+		oc := client.Configuration()
+		if oc == nil || oc.TargetSelector == nil {
+			continue
+		}
+		if oc.HealthCheckUpstreamPath == "" || oc.HealthCheckVerb == "" {
+			log.Warn("target selection requires a health check", tl.Pairs{"originName": k})
+			continue
+		}
+		c := targets.Check{Method: oc.HealthCheckVerb, Path: oc.HealthCheckUpstreamPath}
+		// an unset health check is sent to the root path, as the origin's health handler does
+		if c.Method == "-" {
+			c.Method = http.MethodGet
+		}
+		if c.Path == "-" {
+			c.Path = "/"
+		}
+		if oc.HealthCheckQuery != "-" {
+			c.Query = oc.HealthCheckQuery
+		}
+		if len(oc.HealthCheckHeaders) > 0 {
+			c.Headers = http.Header{}
+			headers.UpdateHeaders(c.Headers, oc.HealthCheckHeaders)
+		}
+		oc.TargetSelector.Start(client.HTTPClient(), c, oc.HealthCheckInterval)
+		selectors = append(selectors, oc.TargetSelector)
+	}
+}
+
+// janitors are the background retention janitors of the running config's origins
+var janitors []*engines.RetentionJanitor
+var janitorsLock sync.Mutex
+
+// startRetentionJanitors stops the retention janitors of any previous config, and starts one
+// for each timeseries origin with a max age, which trims its cache objects to the max age
+func startRetentionJanitors(clients origins.Origins, caches map[string]cache.Cache,
+	loggers map[string]*tl.Logger) {
+	janitorsLock.Lock()
+	defer janitorsLock.Unlock()
+	for _, j := range janitors {
+		j.Stop()
+	}
+	janitors = nil
+	for k, client := range clients {
+		oc := client.Configuration()
+		if oc == nil || oc.TimeseriesMaxAge <= 0 {
+			continue
+		}
+		tsc, ok := client.(origins.TimeseriesClient)
+		if !ok {
+			continue
+		}
+		c, ok := caches[oc.CacheName]
+		if !ok {
+			continue
+		}
+		j := engines.NewRetentionJanitor(tsc, c, loggers[k])
+		j.Start()
+		janitors = append(janitors, j)
+	}
+}
+
+// StopBackgroundTasks stops the health monitors, target health checks and retention
+// janitors of the running config's origins
+func StopBackgroundTasks() {
+	monitorsLock.Lock()
+	for _, m := range monitors {
+		m.Stop()
+	}
+	monitors = nil
+	monitorsLock.Unlock()
+	selectorsLock.Lock()
+	for _, s := range selectors {
+		s.Stop()
+	}
+	selectors = nil
+	selectorsLock.Unlock()
+	janitorsLock.Lock()
+	for _, j := range janitors {
+		j.Stop()
+	}
+	janitors = nil
+	janitorsLock.Unlock()
+}
+
+// warmUpOrigin opens the origin's warm connections, and logs the outcome
+func warmUpOrigin(k string, o *oo.Options, log *tl.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), o.Timeout)
+	defer cancel()
+	n, err := proxy.WarmUp(ctx, o)
+	if err != nil {
+		log.Warn("could not warm up origin connections", tl.Pairs{"originName": k,
+			"upstreamHost": o.Host, "detail": err.Error()})
+		return
+	}
+	log.Info("warmed up origin connections", tl.Pairs{"originName": k,
+		"upstreamHost": o.Host, "connections": n})
+}
+
+// registerHealthChecks adds each origin that has a health check configured, and each
+// cache, to the aggregated health Checker
+func registerHealthChecks(hc *health.Checker, clients origins.Origins,
+	caches map[string]cache.Cache, tracers tracing.Tracers, log *tl.Logger) {
+	for k, client := range clients {
+		oc := client.Configuration()
+		h, ok := client.Handlers()["health"]
+		if !ok || oc == nil || oc.HealthCheckUpstreamPath == "" || oc.HealthCheckVerb == "" {
+			continue
+		}
+		hc.AddOrigin(k, oc.OriginType, middleware.WithResourcesContext(client, oc, nil, nil,
+			tracers[oc.TracingConfigName], log, h))
+	}
+	for k, c := range caches {
+		hc.AddCache(k, c)
+	}
+}
+
+// sortedOriginNames returns the origin names in the order their routes should be
+// registered, so that origins with exact host names take precedence over those with
+// wildcard host patterns, which in turn take precedence over path-only origins
+func sortedOriginNames(o map[string]*oo.Options) []string {
+	names := make([]string, 0, len(o))
+	for k := range o {
+		names = append(names, k)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		si, sj := hosts.Specificity(o[names[i]].Hosts), hosts.Specificity(o[names[j]].Hosts)
+		if si != sj {
+			return si < sj
+		}
+		return names[i] < names[j]
+	})
+	return names
 }
 
 // This ensures that rule clients are fully loaded, which can't be done
@@ -159,8 +477,9 @@ func validateRuleClients(clients origins.Origins,
 
 func registerOriginRoutes(router *mux.Router, conf *config.Config, k string,
 	o *oo.Options, clients origins.Origins, caches map[string]cache.Cache,
-	tracers tracing.Tracers, log *tl.Logger, dryRun bool) (origins.Origins, error) {
+	tracers tracing.Tracers, pr *ProxyRoutes, dryRun bool) (origins.Origins, error) {
 
+	log := pr.log
 	var client origins.Client
 	var c cache.Cache
 	var ok bool
@@ -171,6 +490,11 @@ func registerOriginRoutes(router *mux.Router, conf *config.Config, k string,
 		return nil, fmt.Errorf("could not find cache named [%s]", o.CacheName)
 	}
 
+	hm, err := hosts.Compile(o.Hosts)
+	if err != nil {
+		return nil, fmt.Errorf("origin %s: %s", k, err.Error())
+	}
+
 	if !dryRun {
 		log.Info("registering route paths", tl.Pairs{"originName": k,
 			"originType": o.OriginType, "upstreamHost": o.Host})
@@ -195,11 +519,12 @@ func registerOriginRoutes(router *mux.Router, conf *config.Config, k string,
 	}
 
 	if client != nil && !dryRun {
-		o.HTTPClient = client.HTTPClient()
 		clients[k] = client
+		ol := pr.originLoggers.Logger(k, o.LogLevel)
+		pr.loggers[k] = ol
 		defaultPaths := client.DefaultPathConfigs(o)
 		registerPathRoutes(router, client.Handlers(), client, o, c, defaultPaths,
-			tracers, conf.Main.HealthHandlerPath, log)
+			tracers, hm, conf.Main.HealthHandlerPath, ol)
 	}
 	return clients, nil
 }
@@ -209,7 +534,7 @@ func registerOriginRoutes(router *mux.Router, conf *config.Config, k string,
 // the path routes to the appropriate handler from the provided handlers map
 func registerPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 	client origins.Client, oo *oo.Options, c cache.Cache,
-	defaultPaths map[string]*po.Options, tracers tracing.Tracers,
+	defaultPaths map[string]*po.Options, tracers tracing.Tracers, hm *hosts.Matcher,
 	healthHandlerPath string, log *tl.Logger) {
 
 	if oo == nil {
@@ -224,6 +549,10 @@ func registerPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 		}
 	}
 
+	// requests to the origin's paths share the concurrency budgets of its priority classes
+	admission := priority.NewController(oo.Name, oo.PriorityHeader, oo.DefaultPriorityClass,
+		oo.PriorityClasses)
+
 	decorate := func(po *po.Options) http.Handler {
 		// default base route is the path handler
 		h := po.Handler
@@ -233,6 +562,14 @@ func registerPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 		}
 		// add Origin, Cache, and Path Configs to the HTTP Request's context
 		h = middleware.WithResourcesContext(client, oo, c, po, tr, log, h)
+		// attach any body rewrite hooks, which are attached before the request
+		// rewriters so that the hooks see the rewritten request
+		if len(po.RequestHook) > 0 && po.RequestHook[0] != nil {
+			h = hooks.RewriteRequest(po.RequestHook[0], h)
+		}
+		if len(po.ResponseHook) > 0 && po.ResponseHook[0] != nil {
+			h = hooks.RewriteResponse(po.ResponseHook[0], h)
+		}
 		// attach any request rewriters
 		if len(oo.ReqRewriter) > 0 {
 			h = rewriter.Rewrite(oo.ReqRewriter, h)
@@ -240,9 +577,17 @@ func registerPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 		if len(po.ReqRewriter) > 0 {
 			h = rewriter.Rewrite(po.ReqRewriter, h)
 		}
+		// replace the frontend's security headers with the path's own
+		if po.SecurityHeaderSet != nil {
+			h = middleware.PathSecurityHeaders(po.SecurityHeaderSet, h)
+		}
+		// remove any cache-busting params before the request is rewritten or keyed
+		h = middleware.StripParams(oo.CacheBusterParams, h)
+		// admit the request within the budget of its priority class
+		h = admission.Handler(h)
 		// decorate frontend prometheus metrics
 		if !po.NoMetrics {
-			h = middleware.Decorate(oo.Name, oo.OriginType, po.Path, h)
+			h = middleware.Decorate(oo.Name, oo.OriginType, po.Path, !oo.ExemplarsDisabled, h)
 		}
 		return h
 	}
@@ -309,6 +654,11 @@ func registerPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 
 	or := client.Router().(*mux.Router)
 
+	// matchHost matches requests whose Host header matches one of the origin's host patterns
+	matchHost := func(r *http.Request, _ *mux.RouteMatch) bool {
+		return hm.Match(r.Host)
+	}
+
 	for _, v := range plist {
 		p := pathsWithVerbs[v]
 
@@ -329,8 +679,8 @@ func registerPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 			case matching.PathMatchTypePrefix:
 				// Case where we path match by prefix
 				// Host Header Routing
-				for _, h := range oo.Hosts {
-					router.PathPrefix(p.Path).Handler(decorate(p)).Methods(p.Methods...).Host(h)
+				if len(oo.Hosts) > 0 {
+					router.PathPrefix(p.Path).Handler(decorate(p)).Methods(p.Methods...).MatcherFunc(matchHost)
 				}
 				if !oo.PathRoutingDisabled {
 					// Path Routing
@@ -340,8 +690,8 @@ func registerPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 			default:
 				// default to exact match
 				// Host Header Routing
-				for _, h := range oo.Hosts {
-					router.Handle(p.Path, decorate(p)).Methods(p.Methods...).Host(h)
+				if len(oo.Hosts) > 0 {
+					router.Handle(p.Path, decorate(p)).Methods(p.Methods...).MatcherFunc(matchHost)
 				}
 				if !oo.PathRoutingDisabled {
 					// Path Routing
@@ -17,16 +17,22 @@
 package routing
 
 import (
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/cache/registration"
 	"github.com/tricksterproxy/trickster/pkg/config"
+	do "github.com/tricksterproxy/trickster/pkg/config/debug/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/health"
 	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
 	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/origins/reverseproxycache"
 	"github.com/tricksterproxy/trickster/pkg/proxy/origins/rule"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request/hooks"
 	"github.com/tricksterproxy/trickster/pkg/tracing"
 	"github.com/tricksterproxy/trickster/pkg/tracing/exporters/zipkin"
 	to "github.com/tricksterproxy/trickster/pkg/tracing/options"
@@ -35,15 +41,50 @@ import (
 	"github.com/gorilla/mux"
 )
 
-func TestRegisterPprofRoutes(t *testing.T) {
+func TestRegisterDebugRoutes(t *testing.T) {
 	router := http.NewServeMux()
 	log := tl.ConsoleLogger("info")
-	RegisterPprofRoutes("test", router, log)
+	RegisterDebugRoutes("test", router, nil, log)
 	r, _ := http.NewRequest("GET", "http://0/debug/pprof", nil)
 	_, p := router.Handler(r)
 	if p != "/debug/pprof/" {
 		t.Error("expected pprof route path")
 	}
+	// the runtime endpoints are not served unless they are enabled
+	for _, path := range []string{"/debug/vars", "/debug/snapshot"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "http://0"+path, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected %d got %d for %s", http.StatusNotFound, w.Code, path)
+		}
+	}
+
+	router = http.NewServeMux()
+	RegisterDebugRoutes("test", router, &do.Options{RuntimeEndpoints: true}, log)
+	for _, path := range []string{"/debug/vars", "/debug/snapshot"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "http://0"+path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected %d got %d for %s", http.StatusOK, w.Code, path)
+		}
+	}
+
+	// with a username configured, the debug routes require basic auth
+	router = http.NewServeMux()
+	RegisterDebugRoutes("test", router, &do.Options{Username: "user", Password: "pass",
+		RuntimeEndpoints: true}, log)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "http://0/debug/vars", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected %d got %d", http.StatusUnauthorized, w.Code)
+	}
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "http://0/debug/vars", nil)
+	r.SetBasicAuth("user", "pass")
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
 }
 
 func TestRegisterProxyRoutes(t *testing.T) {
@@ -265,7 +306,7 @@ func TestRegisterProxyRoutesInvalidCert(t *testing.T) {
 }
 
 func TestRegisterProxyRoutesBadCacheName(t *testing.T) {
-	expected := "invalid cache name [test2] provided in origin config [test]"
+	expected := "origins.test.cache_name: invalid cache name [test2] provided in origin config [test]"
 	a := []string{"-config", "../../testdata/test.bad_cache_name.conf"}
 	_, _, err := config.Load("trickster", "test", a)
 	if err == nil {
@@ -325,7 +366,7 @@ func TestRegisterMultipleOriginsPlusDefault(t *testing.T) {
 
 func TestRegisterPathRoutes(t *testing.T) {
 	p := map[string]*po.Options{"test": {}}
-	registerPathRoutes(nil, nil, nil, nil, nil, p, nil, "", nil)
+	registerPathRoutes(nil, nil, nil, nil, nil, p, nil, nil, "", nil)
 
 	conf, _, err := config.Load("trickster", "test",
 		[]string{"-log-level", "debug", "-origin-url", "http://1", "-origin-type", "rpc"})
@@ -337,10 +378,52 @@ func TestRegisterPathRoutes(t *testing.T) {
 	rpc, _ := reverseproxycache.NewClient("test", oo, mux.NewRouter(), nil)
 	dpc := rpc.DefaultPathConfigs(oo)
 	dpc["/-GET-HEAD"].Methods = nil
-	registerPathRoutes(nil, nil, rpc, oo, nil, dpc, nil, "", tl.ConsoleLogger("INFO"))
+	registerPathRoutes(nil, nil, rpc, oo, nil, dpc, nil, nil, "", tl.ConsoleLogger("INFO"))
 
 }
 
+func TestRegisterPathRoutesWithHooks(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Query().Get("tenant")))
+	}))
+	defer ts.Close()
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-origin-url", ts.URL, "-origin-type", "rpc"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	oo := conf.Origins["default"]
+	oo.HTTPClient = ts.Client()
+	tpo := po.NewOptions()
+	tpo.RequestHook = []hooks.RequestHook{func(r *http.Request) error {
+		r.URL.RawQuery = "tenant=a"
+		return nil
+	}}
+	tpo.ResponseHook = []hooks.ResponseHook{func(r *http.Request, statusCode int,
+		h http.Header, body []byte) ([]byte, error) {
+		return append([]byte("tenant="), body...), nil
+	}}
+	tpo.Custom = []string{"request_hook_name", "response_hook_name"}
+	oo.Paths = map[string]*po.Options{"/-GET-HEAD": tpo}
+
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+
+	router := mux.NewRouter()
+	rpc, _ := reverseproxycache.NewClient("default", oo, router, caches["default"])
+	registerPathRoutes(router, rpc.Handlers(), rpc, oo, caches["default"],
+		rpc.DefaultPathConfigs(oo), nil, nil, "", tl.ConsoleLogger("error"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://0/default/test", nil))
+	if w.Body.String() != "tenant=a" {
+		t.Errorf("expected %s got %s", "tenant=a", w.Body.String())
+	}
+}
+
 func TestValidateRuleClients(t *testing.T) {
 
 	var cl = origins.Origins{"test": &rule.Client{}}
@@ -365,3 +448,192 @@ func TestValidateRuleClients(t *testing.T) {
 	}
 
 }
+
+func TestRegisterProxyRoutesHosts(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-config", "../../testdata/test.hosts.conf"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+
+	router := mux.NewRouter()
+	_, err = RegisterProxyRoutes(conf, router, caches, nil, tl.ConsoleLogger("error"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host, expected string
+	}{
+		{"prom.example.com", "prom"},
+		{"prom.example.com:8480", "prom"},
+		{"clickhouse.example.com", "any"},
+		{"anything.example.com", "any"},
+		{"other.example.org", "default"},
+	}
+
+	for _, test := range tests {
+		r, _ := http.NewRequest(http.MethodGet, "http://"+test.host+"/", nil)
+		var match mux.RouteMatch
+		if !router.Match(r, &match) {
+			t.Errorf("host %s: expected route match", test.host)
+			continue
+		}
+		w := &nopResponseWriter{h: http.Header{}}
+		match.Handler.ServeHTTP(w, r)
+		if on := w.h.Get("X-Origin-Name"); on != test.expected {
+			t.Errorf("host %s: expected origin %s got %s", test.host, test.expected, on)
+		}
+	}
+
+	// batch queries reach a host-routed origin with path routing disabled
+	origins := originRouter(conf)
+	if origins("unknown") != nil {
+		t.Error("expected nil handler for unknown origin")
+	}
+	r, _ := http.NewRequest(http.MethodGet, "http://0/api/v1/query", nil)
+	w := &nopResponseWriter{h: http.Header{}}
+	origins("prom").ServeHTTP(w, r)
+	if on := w.h.Get("X-Origin-Name"); on != "prom" {
+		t.Errorf("batch: expected origin %s got %s", "prom", on)
+	}
+
+	conf.Origins["prom"].Hosts = []string{"bad host"}
+	_, err = RegisterProxyRoutes(conf, mux.NewRouter(), caches, nil, tl.ConsoleLogger("error"), false)
+	if err == nil {
+		t.Error("expected error for invalid host pattern")
+	}
+}
+
+type nopResponseWriter struct {
+	h http.Header
+}
+
+func (w *nopResponseWriter) Header() http.Header         { return w.h }
+func (w *nopResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *nopResponseWriter) WriteHeader(int)             {}
+
+func TestRegisterHealthChecks(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-origin-url", "http://1", "-origin-type", "prometheus"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	router := mux.NewRouter()
+	_, err = RegisterProxyRoutes(conf, router, caches, nil, tl.ConsoleLogger("error"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the aggregated health handler must not be shadowed by the default origin's routes,
+	// and the unreachable origin must be reported as failing
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, conf.Main.HealthHandlerPath, nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	s := &health.Status{}
+	if err = json.Unmarshal(w.Body.Bytes(), s); err != nil {
+		t.Fatal(err)
+	}
+	if cr, ok := s.Origins["default"]; !ok || cr.Status != health.StatusFailing {
+		t.Errorf("unexpected origin result: %s", w.Body.String())
+	}
+	if cr, ok := s.Caches["default"]; !ok || cr.Status != health.StatusOK {
+		t.Errorf("unexpected cache result: %s", w.Body.String())
+	}
+}
+
+func TestStartHealthMonitors(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-origin-url", "http://1", "-origin-type", "prometheus"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	oc := conf.Origins["default"]
+	oc.DegradedMode = true
+	oc.HealthCheckInterval = time.Millisecond
+	oc.HealthCheckFailureThreshold = 1
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	_, err = RegisterProxyRoutes(conf, mux.NewRouter(), caches, nil, tl.ConsoleLogger("error"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer StopBackgroundTasks()
+
+	// the unreachable origin is marked down by its health monitor
+	for i := 0; i < 500 && !oc.Down(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !oc.Down() {
+		t.Error("expected origin to be down")
+	}
+
+	monitorsLock.Lock()
+	n := len(monitors)
+	monitorsLock.Unlock()
+	if n != 1 {
+		t.Errorf("expected %d got %d", 1, n)
+	}
+
+	StopBackgroundTasks()
+	monitorsLock.Lock()
+	n = len(monitors)
+	monitorsLock.Unlock()
+	if n != 0 {
+		t.Errorf("expected %d got %d", 0, n)
+	}
+}
+
+func TestBuildProxyRoutes(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-origin-url", "http://1", "-origin-type", "prometheus"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	oc := conf.Origins["default"]
+	oc.DegradedMode = true
+	oc.HealthCheckInterval = time.Millisecond
+	oc.LogLevel = "debug"
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	defer StopBackgroundTasks()
+
+	log := tl.ConsoleLogger("error")
+	log.OriginLogger("running", "warn")
+
+	pr, err := BuildProxyRoutes(conf, mux.NewRouter(), caches, nil, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pr.Clients["default"]; !ok || oc.HTTPClient == nil {
+		t.Error("expected a client for the default origin")
+	}
+
+	// the running origin loggers and monitors are untouched until the routes are started
+	monitorsLock.Lock()
+	n := len(monitors)
+	monitorsLock.Unlock()
+	if levels := log.OriginLogLevels(); n != 0 || len(levels) != 1 ||
+		levels["running"] != "warn" {
+		t.Errorf("unexpected monitors %d and origin levels %v", n, levels)
+	}
+
+	pr.Start()
+	monitorsLock.Lock()
+	n = len(monitors)
+	monitorsLock.Unlock()
+	if levels := log.OriginLogLevels(); n != 1 || len(levels) != 1 ||
+		levels["default"] != "debug" {
+		t.Errorf("unexpected monitors %d and origin levels %v", n, levels)
+	}
+}
@@ -17,7 +17,10 @@
 // Package runtime holds application runtime information
 package runtime
 
-import "os"
+import (
+	"os"
+	"sync/atomic"
+)
 
 // ApplicationName is the name of the Application
 var ApplicationName string
@@ -25,6 +28,34 @@ var ApplicationName string
 // ApplicationVersion holds the version of the Application
 var ApplicationVersion string
 
-// Server is the name, hostname or ip of the server as advertised in HTTP Headers
-// By default uses the hostname reported by the kernel
-var Server, _ = os.Hostname()
+// server holds the name, hostname or ip of the server as advertised in HTTP Headers. It is
+// replaced when the config is reloaded, while requests are served
+var server atomic.Value
+
+func init() {
+	// By default uses the hostname reported by the kernel
+	h, _ := os.Hostname()
+	server.Store(h)
+}
+
+// Server returns the name, hostname or ip of the server as advertised in HTTP Headers
+func Server() string {
+	return server.Load().(string)
+}
+
+// SetServer sets the name, hostname or ip of the server as advertised in HTTP Headers
+func SetServer(name string) {
+	server.Store(name)
+}
+
+// GitCommitID is the git commit of the Application's build
+var GitCommitID string
+
+// BuildTime is the time of the Application's build
+var BuildTime string
+
+// GoVersion is the version of Go that built the Application
+var GoVersion string
+
+// GoArch is the architecture for which the Application was built
+var GoArch string
@@ -0,0 +1,240 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options provides configuration options for secrets providers
+package options
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
+	"github.com/tricksterproxy/trickster/pkg/secrets/types"
+)
+
+// Options is a collection of configurations for a Secrets Provider
+type Options struct {
+	// Name is the name of the Secrets Provider, used in secret references
+	Name string `toml:"-"`
+	// ProviderType is the type of secrets backend ("env", "file", "vault", "aws", "gcp")
+	ProviderType string `toml:"provider_type"`
+	// TimeoutSecs is the amount of time allowed to fetch a secret from the backend
+	TimeoutSecs int `toml:"timeout_secs"`
+	// RefreshIntervalSecs is the interval at which secrets from this provider are re-fetched,
+	// triggering a config reload when any value has changed. 0 disables refreshing
+	RefreshIntervalSecs int `toml:"refresh_interval_secs"`
+
+	// Vault provides HashiCorp Vault-specific options
+	Vault *VaultOptions `toml:"vault"`
+	// AWS provides AWS Secrets Manager-specific options
+	AWS *AWSOptions `toml:"aws"`
+	// GCP provides Google Cloud Secret Manager-specific options
+	GCP *GCPOptions `toml:"gcp"`
+
+	// ProviderTypeID is the internal ID of the ProviderType
+	ProviderTypeID types.ProviderType `toml:"-"`
+	// Timeout is the time.Duration representation of TimeoutSecs
+	Timeout time.Duration `toml:"-"`
+	// RefreshInterval is the time.Duration representation of RefreshIntervalSecs
+	RefreshInterval time.Duration `toml:"-"`
+}
+
+// VaultOptions is a collection of HashiCorp Vault-specific options
+type VaultOptions struct {
+	// Address is the base URL of the Vault server. Defaults to the VAULT_ADDR environment variable
+	Address string `toml:"address"`
+	// Token is the Vault token used to authenticate. Defaults to the VAULT_TOKEN environment variable
+	Token string `toml:"token"`
+	// TokenPath is the path to a file containing the Vault token, such as one written by Vault Agent.
+	// When set, it takes precedence over Token
+	TokenPath string `toml:"token_path"`
+	// Namespace is the Vault Enterprise namespace to send with each request
+	Namespace string `toml:"namespace"`
+	// KVVersion is the version (1 or 2) of the KV Secrets Engine being read
+	KVVersion int `toml:"kv_version"`
+}
+
+// AWSOptions is a collection of AWS Secrets Manager-specific options
+type AWSOptions struct {
+	// Region is the AWS Region hosting the secrets. Defaults to the AWS_REGION environment variable
+	Region string `toml:"region"`
+	// Endpoint overrides the regional Secrets Manager endpoint URL (e.g., for VPC endpoints)
+	Endpoint string `toml:"endpoint"`
+	// AccessKeyID is the AWS Access Key ID. Defaults to the AWS_ACCESS_KEY_ID environment variable
+	AccessKeyID string `toml:"access_key_id"`
+	// SecretAccessKey is the AWS Secret Access Key. Defaults to the AWS_SECRET_ACCESS_KEY
+	// environment variable
+	SecretAccessKey string `toml:"secret_access_key"`
+	// SessionToken is the optional AWS Session Token. Defaults to the AWS_SESSION_TOKEN
+	// environment variable
+	SessionToken string `toml:"session_token"`
+}
+
+// GCPOptions is a collection of Google Cloud Secret Manager-specific options
+type GCPOptions struct {
+	// ProjectID is the Google Cloud Project hosting the secrets
+	ProjectID string `toml:"project_id"`
+	// Endpoint overrides the Secret Manager API base URL
+	Endpoint string `toml:"endpoint"`
+	// AccessToken is an OAuth2 access token used to authenticate. When empty, a token is
+	// requested from the GCE metadata server
+	AccessToken string `toml:"access_token"`
+	// Version is the secret version to access. Default is 'latest'
+	Version string `toml:"version"`
+}
+
+// NewOptions returns a new *Options with the default values
+func NewOptions() *Options {
+	return &Options{
+		ProviderType:        d.DefaultSecretsProviderType,
+		TimeoutSecs:         d.DefaultSecretsTimeoutSecs,
+		RefreshIntervalSecs: d.DefaultSecretsRefreshIntervalSecs,
+		Vault:               &VaultOptions{KVVersion: d.DefaultVaultKVVersion},
+		AWS:                 &AWSOptions{},
+		GCP:                 &GCPOptions{Version: d.DefaultGCPSecretVersion},
+	}
+}
+
+// Clone returns an exact copy of the subject *Options
+func (o *Options) Clone() *Options {
+	o2 := &Options{
+		Name:                o.Name,
+		ProviderType:        o.ProviderType,
+		TimeoutSecs:         o.TimeoutSecs,
+		RefreshIntervalSecs: o.RefreshIntervalSecs,
+		ProviderTypeID:      o.ProviderTypeID,
+		Timeout:             o.Timeout,
+		RefreshInterval:     o.RefreshInterval,
+	}
+	if o.Vault != nil {
+		v := *o.Vault
+		o2.Vault = &v
+	}
+	if o.AWS != nil {
+		a := *o.AWS
+		o2.AWS = &a
+	}
+	if o.GCP != nil {
+		g := *o.GCP
+		o2.GCP = &g
+	}
+	return o2
+}
+
+// HideCredentials masks any credentials in the subject *Options, for use when printing
+func (o *Options) HideCredentials() {
+	mask := func(s *string) {
+		if *s != "" {
+			*s = "*****"
+		}
+	}
+	if o.Vault != nil {
+		mask(&o.Vault.Token)
+	}
+	if o.AWS != nil {
+		mask(&o.AWS.SecretAccessKey)
+		mask(&o.AWS.SessionToken)
+	}
+	if o.GCP != nil {
+		mask(&o.GCP.AccessToken)
+	}
+}
+
+// ProcessSecretsOptions validates and enriches the provided Secrets Options collection
+func ProcessSecretsOptions(mo map[string]*Options, metadata *toml.MetaData) error {
+	for k, v := range mo {
+		if v == nil {
+			return fmt.Errorf("invalid secrets provider config [%s]", k)
+		}
+		o := NewOptions()
+		o.Name = k
+		if metadata != nil {
+			if metadata.IsDefined("secrets", k, "provider_type") {
+				o.ProviderType = v.ProviderType
+			}
+			if metadata.IsDefined("secrets", k, "timeout_secs") {
+				o.TimeoutSecs = v.TimeoutSecs
+			}
+			if metadata.IsDefined("secrets", k, "refresh_interval_secs") {
+				o.RefreshIntervalSecs = v.RefreshIntervalSecs
+			}
+			if metadata.IsDefined("secrets", k, "vault") && v.Vault != nil {
+				kv := o.Vault.KVVersion
+				o.Vault = v.Vault
+				if !metadata.IsDefined("secrets", k, "vault", "kv_version") {
+					o.Vault.KVVersion = kv
+				}
+			}
+			if metadata.IsDefined("secrets", k, "aws") && v.AWS != nil {
+				o.AWS = v.AWS
+			}
+			if metadata.IsDefined("secrets", k, "gcp") && v.GCP != nil {
+				ver := o.GCP.Version
+				o.GCP = v.GCP
+				if !metadata.IsDefined("secrets", k, "gcp", "version") {
+					o.GCP.Version = ver
+				}
+			}
+		}
+		pt, ok := types.Names[o.ProviderType]
+		if !ok {
+			return fmt.Errorf("invalid provider_type [%s] in secrets provider config [%s]",
+				o.ProviderType, k)
+		}
+		o.ProviderTypeID = pt
+		if o.TimeoutSecs <= 0 {
+			o.TimeoutSecs = d.DefaultSecretsTimeoutSecs
+		}
+		o.Timeout = time.Duration(o.TimeoutSecs) * time.Second
+		if o.RefreshIntervalSecs > 0 {
+			o.RefreshInterval = time.Duration(o.RefreshIntervalSecs) * time.Second
+		}
+		o.setEnvDefaults()
+		mo[k] = o
+	}
+	return nil
+}
+
+// setEnvDefaults fills any unset credentials from the provider's customary environment variables
+func (o *Options) setEnvDefaults() {
+	switch o.ProviderTypeID {
+	case types.ProviderTypeVault:
+		if o.Vault.Address == "" {
+			o.Vault.Address = os.Getenv("VAULT_ADDR")
+		}
+		if o.Vault.Token == "" {
+			o.Vault.Token = os.Getenv("VAULT_TOKEN")
+		}
+		if o.Vault.Namespace == "" {
+			o.Vault.Namespace = os.Getenv("VAULT_NAMESPACE")
+		}
+	case types.ProviderTypeAWS:
+		if o.AWS.Region == "" {
+			o.AWS.Region = os.Getenv("AWS_REGION")
+		}
+		if o.AWS.AccessKeyID == "" {
+			o.AWS.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		}
+		if o.AWS.SecretAccessKey == "" {
+			o.AWS.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		}
+		if o.AWS.SessionToken == "" {
+			o.AWS.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+		}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/tricksterproxy/trickster/pkg/secrets/types"
+)
+
+func TestProcessSecretsOptions(t *testing.T) {
+
+	tml := `
+[secrets]
+  [secrets.vault]
+  provider_type = 'vault'
+  refresh_interval_secs = 60
+    [secrets.vault.vault]
+    address = 'http://vault:8200'
+    token = 'abc'
+  [secrets.gcp]
+  provider_type = 'gcp'
+    [secrets.gcp.gcp]
+    project_id = 'test'
+`
+	c := struct {
+		Secrets map[string]*Options `toml:"secrets"`
+	}{}
+	md, err := toml.Decode(tml, &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ProcessSecretsOptions(c.Secrets, &md)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := c.Secrets["vault"]
+	if v.Name != "vault" || v.ProviderTypeID != types.ProviderTypeVault {
+		t.Errorf("unexpected name or type: %s %s", v.Name, v.ProviderTypeID)
+	}
+	if v.Vault.KVVersion != 2 {
+		t.Errorf("expected %d got %d", 2, v.Vault.KVVersion)
+	}
+	if v.RefreshInterval.Seconds() != 60 {
+		t.Errorf("expected %d got %f", 60, v.RefreshInterval.Seconds())
+	}
+
+	g := c.Secrets["gcp"]
+	if g.GCP.Version != "latest" {
+		t.Errorf("expected %s got %s", "latest", g.GCP.Version)
+	}
+	if g.TimeoutSecs != 10 {
+		t.Errorf("expected %d got %d", 10, g.TimeoutSecs)
+	}
+
+	v2 := v.Clone()
+	v2.HideCredentials()
+	if v2.Vault.Token != "*****" {
+		t.Errorf("expected masked token, got %s", v2.Vault.Token)
+	}
+	if v.Vault.Token != "abc" {
+		t.Errorf("expected clone to be independent, got %s", v.Vault.Token)
+	}
+
+	c.Secrets["bad"] = &Options{ProviderType: "invalid"}
+	md, _ = toml.Decode(`[secrets.bad]
+provider_type = 'invalid'`, &struct{}{})
+	err = ProcessSecretsOptions(c.Secrets, &md)
+	if err == nil {
+		t.Error("expected error for invalid provider type")
+	}
+}
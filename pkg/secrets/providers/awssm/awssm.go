@@ -0,0 +1,188 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package awssm provides a Secrets Provider for AWS Secrets Manager
+package awssm
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/secrets"
+	"github.com/tricksterproxy/trickster/pkg/secrets/options"
+)
+
+const (
+	serviceName   = "secretsmanager"
+	targetGet     = "secretsmanager.GetSecretValue"
+	contentType   = "application/x-amz-json-1.1"
+	signAlgorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat = "20060102T150405Z"
+)
+
+// Provider reads secrets from AWS Secrets Manager using its JSON API,
+// signing requests with AWS Signature Version 4
+type Provider struct {
+	options  *options.Options
+	client   *http.Client
+	endpoint *url.URL
+	// now is swappable for testing signatures
+	now func() time.Time
+}
+
+// ErrMissingRegion is returned when no AWS Region is configured
+var ErrMissingRegion = errors.New("aws region is required")
+
+// ErrMissingCredentials is returned when no AWS credentials are configured
+var ErrMissingCredentials = errors.New("aws access_key_id and secret_access_key are required")
+
+type getSecretValueResponse struct {
+	SecretString string `json:"SecretString"`
+	Message      string `json:"message"`
+	Type         string `json:"__type"`
+}
+
+// NewProvider returns a new AWS Secrets Manager Secrets Provider
+func NewProvider(o *options.Options) (*Provider, error) {
+	if o == nil || o.AWS == nil || o.AWS.Region == "" {
+		return nil, ErrMissingRegion
+	}
+	if o.AWS.AccessKeyID == "" || o.AWS.SecretAccessKey == "" {
+		return nil, ErrMissingCredentials
+	}
+	ep := o.AWS.Endpoint
+	if ep == "" {
+		ep = fmt.Sprintf("https://%s.%s.amazonaws.com/", serviceName, o.AWS.Region)
+	}
+	u, err := url.Parse(ep)
+	if err != nil {
+		return nil, err
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return &Provider{options: o, client: &http.Client{Timeout: o.Timeout},
+		endpoint: u, now: time.Now}, nil
+}
+
+// Get returns the SecretString of the secret identified by path (a name or ARN).
+// If key is not empty, the SecretString is parsed as JSON and the value of key is returned
+func (p *Provider) Get(path, key string) (string, error) {
+
+	body, _ := json.Marshal(map[string]string{"SecretId": path})
+	req, err := http.NewRequest(http.MethodPost, p.endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Amz-Target", targetGet)
+	p.sign(req, body)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	gr := &getSecretValueResponse{}
+	json.Unmarshal(b, gr)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("aws secrets manager returned status %d: %s %s",
+			resp.StatusCode, gr.Type, gr.Message)
+	}
+	return secrets.ExtractKey(gr.SecretString, key)
+}
+
+// sign applies an AWS Signature Version 4 Authorization header to the request
+func (p *Provider) sign(req *http.Request, body []byte) {
+
+	t := p.now().UTC()
+	amzDate := t.Format(amzDateFormat)
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if p.options.AWS.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", p.options.AWS.SessionToken)
+	}
+
+	hdrs := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		hdrs[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(hdrs))
+	for k := range hdrs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var ch strings.Builder
+	for _, k := range names {
+		ch.WriteString(k + ":" + hdrs[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		ch.String(),
+		signedHeaders,
+		hashHex(body),
+	}, "\n")
+
+	scope := strings.Join([]string{date, p.options.AWS.Region, serviceName, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		signAlgorithm,
+		amzDate,
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+
+	k := hmacSHA256([]byte("AWS4"+p.options.AWS.SecretAccessKey), date)
+	k = hmacSHA256(k, p.options.AWS.Region)
+	k = hmacSHA256(k, serviceName)
+	k = hmacSHA256(k, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(k, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		signAlgorithm, p.options.AWS.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hashHex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
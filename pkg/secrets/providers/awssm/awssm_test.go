@@ -0,0 +1,119 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package awssm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/secrets/options"
+)
+
+func testOptions(endpoint string) *options.Options {
+	o := options.NewOptions()
+	o.AWS.Region = "us-east-1"
+	o.AWS.Endpoint = endpoint
+	o.AWS.AccessKeyID = "AKIDEXAMPLE"
+	o.AWS.SecretAccessKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	return o
+}
+
+func TestNewProvider(t *testing.T) {
+	o := options.NewOptions()
+	if _, err := NewProvider(o); err != ErrMissingRegion {
+		t.Errorf("expected %v got %v", ErrMissingRegion, err)
+	}
+	o.AWS.Region = "us-east-1"
+	if _, err := NewProvider(o); err != ErrMissingCredentials {
+		t.Errorf("expected %v got %v", ErrMissingCredentials, err)
+	}
+	o = testOptions("")
+	p, err := NewProvider(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.endpoint.Host != "secretsmanager.us-east-1.amazonaws.com" {
+		t.Errorf("unexpected endpoint %s", p.endpoint.String())
+	}
+}
+
+func TestGet(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != targetGet ||
+			!strings.HasPrefix(r.Header.Get("Authorization"),
+				"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20200101/us-east-1/secretsmanager/aws4_request") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		req := make(map[string]string)
+		json.NewDecoder(r.Body).Decode(&req)
+		switch req["SecretId"] {
+		case "prod/trickster":
+			w.Write([]byte(`{"SecretString":"{\"password\":\"s3cret\"}"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"not found"}`))
+		}
+	}))
+	defer ts.Close()
+
+	p, err := NewProvider(testOptions(ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.now = func() time.Time { return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	v, err := p.Get("prod/trickster", "password")
+	if err != nil {
+		t.Error(err)
+	}
+	if v != "s3cret" {
+		t.Errorf("expected %s got %s", "s3cret", v)
+	}
+
+	_, err = p.Get("prod/missing", "")
+	if err == nil || !strings.Contains(err.Error(), "ResourceNotFoundException") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestSign(t *testing.T) {
+	p, _ := NewProvider(testOptions("https://secretsmanager.us-east-1.amazonaws.com/"))
+	p.now = func() time.Time { return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) }
+	req, _ := http.NewRequest(http.MethodPost, p.endpoint.String(), nil)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Amz-Target", targetGet)
+	p.sign(req, []byte(`{"SecretId":"test"}`))
+	a := req.Header.Get("Authorization")
+	const expectedSigned = "SignedHeaders=content-type;host;x-amz-date;x-amz-target, "
+	if !strings.Contains(a, expectedSigned) {
+		t.Errorf("expected %s in %s", expectedSigned, a)
+	}
+	// the signature must be deterministic for a fixed time and request
+	req2, _ := http.NewRequest(http.MethodPost, p.endpoint.String(), nil)
+	req2.Header.Set("Content-Type", contentType)
+	req2.Header.Set("X-Amz-Target", targetGet)
+	p.sign(req2, []byte(`{"SecretId":"test"}`))
+	if req2.Header.Get("Authorization") != a {
+		t.Error("expected identical signatures")
+	}
+}
@@ -0,0 +1,46 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package env provides a Secrets Provider that reads secrets from environment variables
+package env
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/tricksterproxy/trickster/pkg/secrets"
+	"github.com/tricksterproxy/trickster/pkg/secrets/options"
+)
+
+// Provider reads secrets from environment variables, where the
+// secret path is the name of the variable
+type Provider struct {
+	options *options.Options
+}
+
+// NewProvider returns a new environment variable Secrets Provider
+func NewProvider(o *options.Options) (*Provider, error) {
+	return &Provider{options: o}, nil
+}
+
+// Get returns the value of the environment variable named by path
+func (p *Provider) Get(path, key string) (string, error) {
+	v, ok := os.LookupEnv(path)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", path)
+	}
+	return secrets.ExtractKey(v, key)
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package file provides a Secrets Provider that reads secrets from files,
+// such as those mounted by Kubernetes Secrets or written by Vault Agent
+package file
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/secrets"
+	"github.com/tricksterproxy/trickster/pkg/secrets/options"
+)
+
+// Provider reads secrets from files, where the secret path is the file path.
+// Relative paths are resolved from the filesystem root.
+type Provider struct {
+	options *options.Options
+}
+
+// NewProvider returns a new file-based Secrets Provider
+func NewProvider(o *options.Options) (*Provider, error) {
+	return &Provider{options: o}, nil
+}
+
+// Get returns the contents of the file at path, with any trailing newline removed
+func (p *Provider) Get(path, key string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = string(filepath.Separator) + path
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return secrets.ExtractKey(strings.TrimRight(string(b), "\r\n"), key)
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package gsm provides a Secrets Provider for Google Cloud Secret Manager
+package gsm
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/secrets"
+	"github.com/tricksterproxy/trickster/pkg/secrets/options"
+)
+
+const (
+	defaultEndpoint     = "https://secretmanager.googleapis.com"
+	defaultMetadataHost = "metadata.google.internal"
+	metadataTokenPath   = "/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// Provider reads secrets from Google Cloud Secret Manager over its REST API
+type Provider struct {
+	options      *options.Options
+	client       *http.Client
+	endpoint     string
+	metadataHost string
+
+	tokenLock   sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// ErrMissingProjectID is returned when no GCP Project ID is configured
+var ErrMissingProjectID = errors.New("gcp project_id is required")
+
+type accessResponse struct {
+	Payload struct {
+		Data string `json:"data"`
+	} `json:"payload"`
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// NewProvider returns a new Google Cloud Secret Manager Secrets Provider
+func NewProvider(o *options.Options) (*Provider, error) {
+	if o == nil || o.GCP == nil || o.GCP.ProjectID == "" {
+		return nil, ErrMissingProjectID
+	}
+	p := &Provider{
+		options:      o,
+		client:       &http.Client{Timeout: o.Timeout},
+		endpoint:     strings.TrimSuffix(o.GCP.Endpoint, "/"),
+		metadataHost: os.Getenv("GCE_METADATA_HOST"),
+	}
+	if p.endpoint == "" {
+		p.endpoint = defaultEndpoint
+	}
+	if p.metadataHost == "" {
+		p.metadataHost = defaultMetadataHost
+	}
+	return p, nil
+}
+
+// Get returns the payload of the secret named by path, which may be a bare secret name or
+// 'name/versions/N'. If key is not empty, the payload is parsed as JSON and the value of key
+// is returned
+func (p *Provider) Get(path, key string) (string, error) {
+
+	token, err := p.accessToken()
+	if err != nil {
+		return "", err
+	}
+
+	path = strings.Trim(path, "/")
+	if !strings.Contains(path, "/versions/") {
+		path += "/versions/" + p.options.GCP.Version
+	}
+	u := fmt.Sprintf("%s/v1/projects/%s/secrets/%s:access",
+		p.endpoint, p.options.GCP.ProjectID, path)
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	ar := &accessResponse{}
+	json.Unmarshal(b, ar)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("gcp secret manager returned status %d: %s",
+			resp.StatusCode, ar.Error.Message)
+	}
+	v, err := base64.StdEncoding.DecodeString(ar.Payload.Data)
+	if err != nil {
+		return "", err
+	}
+	return secrets.ExtractKey(string(v), key)
+}
+
+// accessToken returns the configured access token, or one fetched from the
+// GCE metadata server, which is cached until shortly before it expires
+func (p *Provider) accessToken() (string, error) {
+	if p.options.GCP.AccessToken != "" {
+		return p.options.GCP.AccessToken, nil
+	}
+
+	p.tokenLock.Lock()
+	defer p.tokenLock.Unlock()
+	if p.token != "" && time.Now().Before(p.tokenExpiry) {
+		return p.token, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+p.metadataHost+metadataTokenPath, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("gcp metadata server returned status %d", resp.StatusCode)
+	}
+	tr := &tokenResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(tr); err != nil {
+		return "", err
+	}
+	p.token = tr.AccessToken
+	// refresh a minute early to avoid using a token that expires in flight
+	p.tokenExpiry = time.Now().Add(time.Duration(tr.ExpiresIn-60) * time.Second)
+	return p.token, nil
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gsm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/secrets/options"
+)
+
+func TestNewProvider(t *testing.T) {
+	o := options.NewOptions()
+	if _, err := NewProvider(o); err != ErrMissingProjectID {
+		t.Errorf("expected %v got %v", ErrMissingProjectID, err)
+	}
+}
+
+func TestGet(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == metadataTokenPath {
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"access_token":"meta-token","expires_in":3600}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer meta-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"message":"unauthorized"}}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/projects/test-project/secrets/trickster/versions/latest:access":
+			// base64 of {"password":"s3cret"}
+			w.Write([]byte(`{"payload":{"data":"eyJwYXNzd29yZCI6InMzY3JldCJ9"}}`))
+		case "/v1/projects/test-project/secrets/trickster/versions/2:access":
+			// base64 of v2
+			w.Write([]byte(`{"payload":{"data":"djI="}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"message":"not found"}}`))
+		}
+	}))
+	defer ts.Close()
+
+	os.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(ts.URL, "http://"))
+	defer os.Unsetenv("GCE_METADATA_HOST")
+
+	o := options.NewOptions()
+	o.GCP.ProjectID = "test-project"
+	o.GCP.Endpoint = ts.URL
+
+	p, err := NewProvider(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := p.Get("trickster", "password")
+	if err != nil {
+		t.Error(err)
+	}
+	if v != "s3cret" {
+		t.Errorf("expected %s got %s", "s3cret", v)
+	}
+
+	v, err = p.Get("trickster/versions/2", "")
+	if err != nil {
+		t.Error(err)
+	}
+	if v != "v2" {
+		t.Errorf("expected %s got %s", "v2", v)
+	}
+
+	_, err = p.Get("missing", "")
+	if err == nil {
+		t.Error("expected error for missing secret")
+	}
+
+	o.GCP.AccessToken = "bad-token"
+	_, err = p.Get("trickster", "")
+	if err == nil {
+		t.Error("expected error for bad token")
+	}
+}
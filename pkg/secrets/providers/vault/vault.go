@@ -0,0 +1,126 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package vault provides a Secrets Provider for the HashiCorp Vault KV Secrets Engine
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/secrets"
+	"github.com/tricksterproxy/trickster/pkg/secrets/options"
+)
+
+// Provider reads secrets from HashiCorp Vault over its HTTP API
+type Provider struct {
+	options *options.Options
+	client  *http.Client
+}
+
+// ErrMissingAddress is returned when no Vault address is configured
+var ErrMissingAddress = errors.New("vault address is required")
+
+type kvResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []string               `json:"errors"`
+}
+
+// NewProvider returns a new Vault Secrets Provider
+func NewProvider(o *options.Options) (*Provider, error) {
+	if o == nil || o.Vault == nil || o.Vault.Address == "" {
+		return nil, ErrMissingAddress
+	}
+	return &Provider{options: o, client: &http.Client{Timeout: o.Timeout}}, nil
+}
+
+func (p *Provider) token() (string, error) {
+	if p.options.Vault.TokenPath != "" {
+		b, err := ioutil.ReadFile(p.options.Vault.TokenPath)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	return p.options.Vault.Token, nil
+}
+
+// Get returns the value of key from the Vault secret at path. For the KV v2 engine, path
+// must include the data segment (e.g., secret/data/trickster). If key is empty, the secret
+// must contain exactly one field, whose value is returned.
+func (p *Provider) Get(path, key string) (string, error) {
+
+	token, err := p.token()
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodGet,
+		strings.TrimSuffix(p.options.Vault.Address, "/")+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if p.options.Vault.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.options.Vault.Namespace)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	kr := &kvResponse{}
+	if err := json.Unmarshal(b, kr); err != nil {
+		return "", fmt.Errorf("unexpected vault response (%d)", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned status %d: %s", resp.StatusCode,
+			strings.Join(kr.Errors, "; "))
+	}
+
+	data := kr.Data
+	if p.options.Vault.KVVersion != 1 {
+		// KV v2 nests the secret's fields under data.data
+		d, ok := data["data"].(map[string]interface{})
+		if !ok {
+			return "", errors.New("vault response is missing data; is this a KV v2 path?")
+		}
+		data = d
+	}
+
+	if key == "" {
+		if len(data) != 1 {
+			return "", fmt.Errorf("vault secret at %s has %d keys; a #key must be specified",
+				path, len(data))
+		}
+		for k := range data {
+			key = k
+		}
+	}
+	return secrets.FieldValue(data, key)
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package vault
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/secrets/options"
+)
+
+func testServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/trickster":
+			w.Write([]byte(`{"data":{"data":{"password":"s3cret","user":"trickster"}}}`))
+		case "/v1/secret/data/single":
+			w.Write([]byte(`{"data":{"data":{"token":"abc123"}}}`))
+		case "/v1/kv1/trickster":
+			w.Write([]byte(`{"data":{"password":"v1pass"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+}
+
+func TestNewProvider(t *testing.T) {
+	o := options.NewOptions()
+	_, err := NewProvider(o)
+	if err != ErrMissingAddress {
+		t.Errorf("expected %v got %v", ErrMissingAddress, err)
+	}
+}
+
+func TestGet(t *testing.T) {
+
+	ts := testServer()
+	defer ts.Close()
+
+	o := options.NewOptions()
+	o.Vault.Address = ts.URL
+	o.Vault.Token = "test-token"
+
+	p, err := NewProvider(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := p.Get("secret/data/trickster", "password")
+	if err != nil {
+		t.Error(err)
+	}
+	if v != "s3cret" {
+		t.Errorf("expected %s got %s", "s3cret", v)
+	}
+
+	// a single-key secret doesn't require the key to be specified
+	v, err = p.Get("secret/data/single", "")
+	if err != nil {
+		t.Error(err)
+	}
+	if v != "abc123" {
+		t.Errorf("expected %s got %s", "abc123", v)
+	}
+
+	// a multi-key secret does
+	_, err = p.Get("secret/data/trickster", "")
+	if err == nil {
+		t.Error("expected error for missing key")
+	}
+
+	_, err = p.Get("secret/data/missing", "password")
+	if err == nil {
+		t.Error("expected error for missing secret")
+	}
+
+	o.Vault.KVVersion = 1
+	v, err = p.Get("kv1/trickster", "password")
+	if err != nil {
+		t.Error(err)
+	}
+	if v != "v1pass" {
+		t.Errorf("expected %s got %s", "v1pass", v)
+	}
+
+	o.Vault.Token = "bad-token"
+	_, err = p.Get("kv1/trickster", "password")
+	if err == nil {
+		t.Error("expected error for bad token")
+	}
+}
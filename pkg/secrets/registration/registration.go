@@ -0,0 +1,65 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package registration instantiates configured Secrets Providers
+package registration
+
+import (
+	"fmt"
+
+	"github.com/tricksterproxy/trickster/pkg/secrets"
+	"github.com/tricksterproxy/trickster/pkg/secrets/options"
+	"github.com/tricksterproxy/trickster/pkg/secrets/providers/awssm"
+	"github.com/tricksterproxy/trickster/pkg/secrets/providers/env"
+	"github.com/tricksterproxy/trickster/pkg/secrets/providers/file"
+	"github.com/tricksterproxy/trickster/pkg/secrets/providers/gsm"
+	"github.com/tricksterproxy/trickster/pkg/secrets/providers/vault"
+	"github.com/tricksterproxy/trickster/pkg/secrets/types"
+)
+
+// RegisterAll returns a Providers map containing a Provider for each of the
+// provided Secrets Options
+func RegisterAll(mo map[string]*options.Options) (secrets.Providers, error) {
+	p := make(secrets.Providers)
+	for k, o := range mo {
+		pr, err := GetProvider(o)
+		if err != nil {
+			return nil, fmt.Errorf("could not register secrets provider [%s]: %s", k, err.Error())
+		}
+		p[k] = pr
+	}
+	return p, nil
+}
+
+// GetProvider returns a Provider based on the provided options
+func GetProvider(o *options.Options) (secrets.Provider, error) {
+	if o == nil {
+		return nil, fmt.Errorf("nil secrets provider options")
+	}
+	switch o.ProviderTypeID {
+	case types.ProviderTypeEnv:
+		return env.NewProvider(o)
+	case types.ProviderTypeFile:
+		return file.NewProvider(o)
+	case types.ProviderTypeVault:
+		return vault.NewProvider(o)
+	case types.ProviderTypeAWS:
+		return awssm.NewProvider(o)
+	case types.ProviderTypeGCP:
+		return gsm.NewProvider(o)
+	}
+	return nil, fmt.Errorf("invalid provider_type [%s]", o.ProviderType)
+}
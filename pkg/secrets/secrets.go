@@ -0,0 +1,149 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package secrets provides the abstraction through which configuration values
+// such as passwords, tokens and TLS keys are fetched from external secrets
+// stores rather than persisted in the config file.
+//
+// A config value is treated as a secret reference when it is in the form
+//
+//	secret://<provider_name>/<path>[#<key>]
+//
+// where provider_name is a configured [secrets] provider, path is the
+// provider-specific location of the secret, and the optional key selects a
+// single field from a secret whose value is a JSON object.
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ReferencePrefix is the prefix that identifies a config value as a secret reference
+const ReferencePrefix = "secret://"
+
+// Provider is the interface for a backend that can fetch secret values
+type Provider interface {
+	// Get returns the value of the secret at path. When key is not empty,
+	// the secret is expected to be a JSON object and the value of key is returned
+	Get(path, key string) (string, error)
+}
+
+// Providers is a map of Secrets Providers keyed by name
+type Providers map[string]Provider
+
+// Reference is a parsed secret reference
+type Reference struct {
+	// ProviderName is the name of the configured provider that holds the secret
+	ProviderName string
+	// Path is the provider-specific location of the secret
+	Path string
+	// Key optionally selects a single field from a JSON-formatted secret
+	Key string
+}
+
+// ErrInvalidReference is returned when a secret reference cannot be parsed
+var ErrInvalidReference = errors.New("invalid secret reference")
+
+// IsReference returns true if the provided value is a secret reference
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, ReferencePrefix)
+}
+
+// ParseReference parses a secret reference string into a *Reference
+func ParseReference(value string) (*Reference, error) {
+	if !IsReference(value) {
+		return nil, ErrInvalidReference
+	}
+	s := value[len(ReferencePrefix):]
+	i := strings.Index(s, "/")
+	if i < 1 || i == len(s)-1 {
+		return nil, ErrInvalidReference
+	}
+	r := &Reference{ProviderName: s[:i], Path: s[i+1:]}
+	if j := strings.LastIndex(r.Path, "#"); j != -1 {
+		r.Key = r.Path[j+1:]
+		r.Path = r.Path[:j]
+		if r.Path == "" {
+			return nil, ErrInvalidReference
+		}
+	}
+	return r, nil
+}
+
+// String returns the reference in its config-file form
+func (r *Reference) String() string {
+	s := ReferencePrefix + r.ProviderName + "/" + r.Path
+	if r.Key != "" {
+		s += "#" + r.Key
+	}
+	return s
+}
+
+// Resolve returns the secret value for the provided config value when it is a
+// secret reference; otherwise, the value is returned unchanged
+func (p Providers) Resolve(value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+	r, err := ParseReference(value)
+	if err != nil {
+		return "", err
+	}
+	pr, ok := p[r.ProviderName]
+	if !ok || pr == nil {
+		return "", fmt.Errorf("unknown secrets provider [%s] in reference %s",
+			r.ProviderName, value)
+	}
+	v, err := pr.Get(r.Path, r.Key)
+	if err != nil {
+		return "", fmt.Errorf("could not fetch secret %s: %s", value, err.Error())
+	}
+	return v, nil
+}
+
+// ExtractKey returns the value of key from the JSON object in value. If key
+// is empty, value is returned unchanged. Non-string values are returned in
+// their JSON-encoded form.
+func ExtractKey(value, key string) (string, error) {
+	if key == "" {
+		return value, nil
+	}
+	m := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(value), &m); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object; cannot extract key [%s]", key)
+	}
+	return FieldValue(m, key)
+}
+
+// FieldValue returns the value of key from the provided map, stringifying
+// non-string values
+func FieldValue(m map[string]interface{}, key string) (string, error) {
+	v, ok := m[key]
+	if !ok {
+		return "", fmt.Errorf("key [%s] not found in secret", key)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
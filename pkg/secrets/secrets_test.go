@@ -0,0 +1,103 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secrets
+
+import (
+	"errors"
+	"testing"
+)
+
+type testProvider map[string]string
+
+func (p testProvider) Get(path, key string) (string, error) {
+	v, ok := p[path]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return ExtractKey(v, key)
+}
+
+func TestParseReference(t *testing.T) {
+
+	tests := []struct {
+		in       string
+		expected *Reference
+		err      error
+	}{
+		{"secret://vault/secret/data/trickster#redis",
+			&Reference{ProviderName: "vault", Path: "secret/data/trickster", Key: "redis"}, nil},
+		{"secret://env/REDIS_PASSWORD",
+			&Reference{ProviderName: "env", Path: "REDIS_PASSWORD"}, nil},
+		{"secret://aws/prod/trickster#a#b",
+			&Reference{ProviderName: "aws", Path: "prod/trickster#a", Key: "b"}, nil},
+		{"plaintext", nil, ErrInvalidReference},
+		{"secret://vault", nil, ErrInvalidReference},
+		{"secret://vault/", nil, ErrInvalidReference},
+		{"secret:///path", nil, ErrInvalidReference},
+		{"secret://vault/#key", nil, ErrInvalidReference},
+	}
+
+	for i, test := range tests {
+		r, err := ParseReference(test.in)
+		if err != test.err {
+			t.Errorf("test %d: expected error %v got %v", i, test.err, err)
+			continue
+		}
+		if test.expected == nil {
+			continue
+		}
+		if *r != *test.expected {
+			t.Errorf("test %d: expected %v got %v", i, test.expected, r)
+		}
+		if r.String() != test.in {
+			t.Errorf("test %d: expected %s got %s", i, test.in, r.String())
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+
+	p := Providers{"test": testProvider{
+		"plain": "value1",
+		"json":  `{"user":"trickster","pass":"s3cret","port":6379}`,
+	}}
+
+	tests := []struct {
+		in, expected string
+		expectErr    bool
+	}{
+		{"not-a-reference", "not-a-reference", false},
+		{"secret://test/plain", "value1", false},
+		{"secret://test/json#pass", "s3cret", false},
+		{"secret://test/json#port", "6379", false},
+		{"secret://test/json#missing", "", true},
+		{"secret://test/plain#key", "", true},
+		{"secret://test/missing", "", true},
+		{"secret://other/plain", "", true},
+		{"secret://test", "", true},
+	}
+
+	for i, test := range tests {
+		v, err := p.Resolve(test.in)
+		if (err != nil) != test.expectErr {
+			t.Errorf("test %d: unexpected error state: %v", i, err)
+		}
+		if v != test.expected {
+			t.Errorf("test %d: expected %s got %s", i, test.expected, v)
+		}
+	}
+}
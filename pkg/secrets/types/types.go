@@ -0,0 +1,61 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package types enumerates the supported secrets provider types
+package types
+
+import "strconv"
+
+// ProviderType enumerates the supported secrets provider backends
+type ProviderType int
+
+const (
+	// ProviderTypeEnv indicates secrets are read from environment variables
+	ProviderTypeEnv = ProviderType(iota)
+	// ProviderTypeFile indicates secrets are read from files on disk
+	ProviderTypeFile
+	// ProviderTypeVault indicates secrets are read from HashiCorp Vault
+	ProviderTypeVault
+	// ProviderTypeAWS indicates secrets are read from AWS Secrets Manager
+	ProviderTypeAWS
+	// ProviderTypeGCP indicates secrets are read from Google Cloud Secret Manager
+	ProviderTypeGCP
+)
+
+// Names is a map of provider types keyed by name
+var Names = map[string]ProviderType{
+	"env":   ProviderTypeEnv,
+	"file":  ProviderTypeFile,
+	"vault": ProviderTypeVault,
+	"aws":   ProviderTypeAWS,
+	"gcp":   ProviderTypeGCP,
+}
+
+// Values is a map of provider types keyed by internal id
+var Values = make(map[ProviderType]string)
+
+func init() {
+	for k, v := range Names {
+		Values[v] = k
+	}
+}
+
+func (t ProviderType) String() string {
+	if v, ok := Values[t]; ok {
+		return v
+	}
+	return strconv.Itoa(int(t))
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/util/accesslog"
+	ao "github.com/tricksterproxy/trickster/pkg/util/accesslog/options"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// accessLogger is the running access logger, if any. It is only accessed while
+// holding cfgLock
+var accessLogger *accesslog.Logger
+
+// applyAccessLogConfig opens, reopens or closes the access log so that it matches
+// the provided config, and returns router wrapped by the access log handler when
+// the access log is enabled
+func applyAccessLogConfig(conf, oldConf *config.Config, router http.Handler,
+	log *tl.Logger) http.Handler {
+
+	var o, old *ao.Options
+	if conf != nil && conf.Logging != nil {
+		o = conf.Logging.AccessLog
+	}
+	if oldConf != nil && oldConf.Logging != nil {
+		old = oldConf.Logging.AccessLog
+	}
+
+	if accessLogger != nil && !o.Equal(old) {
+		// outstanding requests on the old router may still write to the old
+		// access log, so it is closed after the listeners have drained
+		go delayedAccessLogCloser(accessLogger,
+			time.Duration(conf.ReloadConfig.DrainTimeoutSecs+1)*time.Second)
+		accessLogger = nil
+	}
+
+	if !o.Enabled() {
+		return router
+	}
+
+	if accessLogger == nil {
+		l, err := accesslog.New(o)
+		if err != nil {
+			log.Error("unable to open access log", tl.Pairs{"detail": err.Error()})
+			return router
+		}
+		log.Info("access log enabled", tl.Pairs{"output": o.Output})
+		accessLogger = l
+	}
+
+	return accesslog.Handler(accessLogger, router)
+}
+
+func delayedAccessLogCloser(l *accesslog.Logger, delay time.Duration) {
+	time.Sleep(delay)
+	l.Close()
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	io "github.com/tricksterproxy/trickster/pkg/cache/index/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/memory"
+	"github.com/tricksterproxy/trickster/pkg/config"
+	th "github.com/tricksterproxy/trickster/pkg/proxy/handlers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/tls/spiffe"
+	"github.com/tricksterproxy/trickster/pkg/routing"
+	"github.com/tricksterproxy/trickster/pkg/tracing"
+	tr "github.com/tricksterproxy/trickster/pkg/tracing/registration"
+	"github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// configBuild holds the components constructed for a config (re)load. They are built in
+// isolation from the running components, which they only replace once all of them have
+// been built successfully
+type configBuild struct {
+	log     *log.Logger
+	tracers tracing.Tracers
+	router  *mux.Router
+	// routes are the origins' proxy routes, whose origin loggers and background tasks
+	// are started once the build is applied
+	routes *routing.ProxyRoutes
+	caches map[string]cache.Cache
+	// newCaches are the caches opened for this build, which are closed on rollback
+	newCaches []cache.Cache
+	// retiredCaches are the running caches not carried into this build, which are
+	// closed after the listeners have drained once the build is applied
+	retiredCaches []cache.Cache
+	// indexUpdates are the index options of running memory caches carried into this
+	// build, which are updated once the build is applied
+	indexUpdates map[*memory.Cache]*io.Options
+	// spiffe is the workload identity loaded for this build, when it is new or changed
+	spiffe *spiffe.Source
+}
+
+// buildError reports the component of a config build that failed
+type buildError struct {
+	component string
+	err       error
+}
+
+func (e *buildError) Error() string {
+	return e.component + ": " + e.err.Error()
+}
+
+// buildConfig constructs the logger, tracers, caches and router for the provided config.
+// When any of them fail, those already built are rolled back, and the running components
+// are left untouched
+func buildConfig(conf, oldConf *config.Config, oldLog *log.Logger,
+	oldCaches map[string]cache.Cache) (*configBuild, error) {
+
+	b := &configBuild{log: buildLogger(conf, oldConf, oldLog)}
+
+	var err error
+	if conf.Frontend.ServeTLS && conf.Frontend.TLSListenPort > 0 {
+		if _, err = conf.TLSCertConfig(); err != nil {
+			return nil, b.rollback(oldLog, &buildError{"tls", err})
+		}
+	}
+	if _, err = conf.ReloadConfig.TLSConfig(); err != nil {
+		return nil, b.rollback(oldLog, &buildError{"reloading.tls", err})
+	}
+
+	if conf.Spiffe.Enabled() && (oldConf == nil || !conf.Spiffe.Equal(oldConf.Spiffe) ||
+		spiffe.CurrentSource() == nil) {
+		if b.spiffe, err = spiffe.NewSource(conf.Spiffe); err != nil {
+			return nil, b.rollback(oldLog, &buildError{"spiffe", err})
+		}
+	}
+
+	if b.tracers, err = tr.RegisterAll(conf, b.log, false); err != nil {
+		return nil, b.rollback(oldLog, &buildError{"tracing", err})
+	}
+
+	if err = buildCaches(b, conf, oldConf, oldCaches); err != nil {
+		return nil, b.rollback(oldLog, &buildError{"caches", err})
+	}
+
+	// every config (re)load is a new router
+	b.router = mux.NewRouter()
+	b.router.HandleFunc(conf.Main.PingHandlerPath, th.PingHandleFunc(conf)).Methods(http.MethodGet)
+	// custom handlers are registered ahead of the proxy routes, so that they take precedence
+	for _, h := range hooks.handlers {
+		if strings.HasSuffix(h.path, "/") {
+			b.router.PathPrefix(h.path).Handler(h.handler)
+			continue
+		}
+		b.router.Handle(h.path, h.handler)
+	}
+	if b.routes, err = routing.BuildProxyRoutes(conf, b.router, b.caches, b.tracers,
+		b.log); err != nil {
+		return nil, b.rollback(oldLog, &buildError{"routes", err})
+	}
+
+	return b, nil
+}
+
+// rollback releases the components opened for the build, and returns the provided error
+func (b *configBuild) rollback(oldLog *log.Logger, err error) error {
+	for _, c := range b.newCaches {
+		c.Close()
+	}
+	for _, t := range b.tracers {
+		if t != nil && t.Flusher != nil {
+			t.Flusher()
+		}
+	}
+	if b.log != nil && b.log != oldLog {
+		b.log.Close()
+	}
+	return err
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache/types"
+	"github.com/tricksterproxy/trickster/pkg/config"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/routing"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// newCountingUpstream returns an upstream server, and a func that returns the number of
+// requests it has received for a path
+func newCountingUpstream() (*httptest.Server, func(string) int) {
+	var mtx sync.Mutex
+	counts := make(map[string]int)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		counts[r.URL.Path]++
+		mtx.Unlock()
+	}))
+	return s, func(path string) int {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return counts[path]
+	}
+}
+
+// monitorHealth enables the origin's degraded mode, so that a health monitor checks the
+// upstream path while the origin's config is running
+func monitorHealth(o *oo.Options, path string) {
+	o.DegradedMode = true
+	o.HealthCheckUpstreamPath = path
+	o.HealthCheckVerb = http.MethodGet
+	o.HealthCheckInterval = 10 * time.Millisecond
+}
+
+// waitFor polls the condition until it is true, and returns false if it never is
+func waitFor(cond func() bool) bool {
+	for i := 0; i < 500; i++ {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestBuildConfig(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-origin-type", "rpc", "-origin-url", "http://tricksterproxy.io"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := tl.ConsoleLogger("error")
+
+	b, err := buildConfig(conf, nil, log, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.router == nil || len(b.newCaches) != 1 || b.caches["default"] != b.newCaches[0] {
+		t.Error("expected a router and a new default cache")
+	}
+
+	// an unchanged cache is carried into the next build, rather than reopened
+	oldConf, oldCaches := conf, b.caches
+	conf, _, _ = config.Load("trickster", "test",
+		[]string{"-origin-type", "rpc", "-origin-url", "http://tricksterproxy.io"})
+	b, err = buildConfig(conf, oldConf, log, oldCaches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.caches["default"] != oldCaches["default"] || len(b.newCaches) != 0 ||
+		len(b.retiredCaches) != 0 {
+		t.Error("expected the running default cache to be reused")
+	}
+
+	// a cache that fails to open fails the build, leaving the running caches intact
+	conf, _, _ = config.Load("trickster", "test",
+		[]string{"-origin-type", "rpc", "-origin-url", "http://tricksterproxy.io"})
+	conf.Caches["default"].CacheType = "filesystem"
+	conf.Caches["default"].CacheTypeID = types.CacheTypeFilesystem
+	conf.Caches["default"].Filesystem.CachePath = "/dev/null/trickster"
+	b, err = buildConfig(conf, oldConf, log, oldCaches)
+	if b != nil {
+		t.Error("expected nil build")
+	}
+	be, ok := err.(*buildError)
+	if !ok || be.component != "caches" {
+		t.Errorf("expected caches build error got %v", err)
+	}
+
+	// a config with an unknown tracer fails the build before any caches are opened
+	conf, _, _ = config.Load("trickster", "test",
+		[]string{"-origin-type", "rpc", "-origin-url", "http://tricksterproxy.io"})
+	conf.Origins["default"].TracingConfigName = "unknown"
+	_, err = buildConfig(conf, oldConf, log, oldCaches)
+	if be, ok := err.(*buildError); !ok || be.component != "tracing" {
+		t.Errorf("expected tracing build error got %v", err)
+	}
+}
+
+func TestBuildConfigFailure(t *testing.T) {
+
+	upstream, count := newCountingUpstream()
+	defer upstream.Close()
+	args := []string{"-origin-type", "rpc", "-origin-url", upstream.URL}
+	log := tl.ConsoleLogger("error")
+
+	conf, _, err := config.Load("trickster", "test", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	monitorHealth(conf.Origins["default"], "/health")
+	conf.Origins["default"].LogLevel = "warn"
+	b, err := buildConfig(conf, nil, log, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.rollback(log, nil)
+	// the build is running with its own logger
+	log = b.log
+	b.routes.Start()
+	defer routing.StopBackgroundTasks()
+	if !waitFor(func() bool { return count("/health") > 0 }) {
+		t.Fatal("expected the running origin to be health checked")
+	}
+
+	// a config whose routes fail after some of its origins are registered
+	failed, _, _ := config.Load("trickster", "test", args)
+	o := failed.Origins["default"]
+	delete(failed.Origins, "default")
+	o.Name = "a"
+	o.LogLevel = "debug"
+	o.WarmConnections = 1
+	monitorHealth(o, "/failed")
+	failed.Origins["a"] = o
+	b2 := oo.NewOptions()
+	b2.Name = "b"
+	b2.OriginType = "rpc"
+	b2.CacheName = "unknown"
+	failed.Origins["b"] = b2
+
+	_, err = buildConfig(failed, conf, log, b.caches)
+	if be, ok := err.(*buildError); !ok || be.component != "routes" {
+		t.Fatalf("expected routes build error got %v", err)
+	}
+
+	// the failed build leaves the running loggers and monitors in place
+	if levels := log.OriginLogLevels(); len(levels) != 1 || levels["default"] != "warn" {
+		t.Errorf("expected the running origin loggers got %v", levels)
+	}
+	if o.HTTPClient != nil {
+		t.Error("expected the failed origin to have no HTTP client")
+	}
+	n := count("/health")
+	if !waitFor(func() bool { return count("/health") > n }) {
+		t.Error("expected the running origin to still be health checked")
+	}
+	if c := count("/failed"); c != 0 {
+		t.Errorf("expected no health checks of the failed origin got %d", c)
+	}
+}
@@ -0,0 +1,485 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tricksterproxy/trickster/pkg/admin"
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	io "github.com/tricksterproxy/trickster/pkg/cache/index/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/memory"
+	"github.com/tricksterproxy/trickster/pkg/cache/registration"
+	"github.com/tricksterproxy/trickster/pkg/cache/types"
+	"github.com/tricksterproxy/trickster/pkg/config"
+	ro "github.com/tricksterproxy/trickster/pkg/config/reload/options"
+	"github.com/tricksterproxy/trickster/pkg/locks"
+	"github.com/tricksterproxy/trickster/pkg/proxy/engines"
+	"github.com/tricksterproxy/trickster/pkg/proxy/faults"
+	"github.com/tricksterproxy/trickster/pkg/proxy/handlers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/routing"
+	"github.com/tricksterproxy/trickster/pkg/runtime"
+	"github.com/tricksterproxy/trickster/pkg/simulator"
+	tr "github.com/tricksterproxy/trickster/pkg/tracing/registration"
+	"github.com/tricksterproxy/trickster/pkg/util/log"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+	"github.com/tricksterproxy/trickster/pkg/util/middleware"
+)
+
+var cfgLock = &sync.Mutex{}
+
+var errConfigNotRunning = errors.New("the config to reload is no longer running")
+
+// runConfig loads the config from the provided command line arguments, and applies it in
+// place of the running config. It is the reloader of the SIGHUP monitor, the reload
+// endpoint and the Admin API
+func runConfig(oldConf *config.Config, wg *sync.WaitGroup, log *log.Logger,
+	oldCaches map[string]cache.Cache, args []string, errorsFatal bool) error {
+
+	cfgLock.Lock()
+	defer cfgLock.Unlock()
+	var err error
+
+	// the server may have been stopped or reloaded while this reload waited for the lock
+	if oldConf != running.conf {
+		return errConfigNotRunning
+	}
+
+	// load the config
+	conf, _, err := config.Load(runtime.ApplicationName, runtime.ApplicationVersion, args)
+	if err != nil {
+		fmt.Println("\nERROR: Could not load configuration:", err.Error())
+		handleStartupIssue("", nil, nil, errorsFatal)
+		return err
+	}
+
+	err = ValidateConfig(conf)
+	if err != nil {
+		if log != nil {
+			handleStartupIssue("config validation failed, the running config was not changed",
+				tl.Pairs{"detail": err.Error()}, log, errorsFatal)
+		} else {
+			handleStartupIssue("ERROR: Could not load configuration: "+err.Error(),
+				nil, nil, errorsFatal)
+		}
+		return err
+	}
+
+	return applyConfig(conf, oldConf, wg, log, oldCaches, args, errorsFatal)
+
+}
+
+func applyConfig(conf, oldConf *config.Config, wg *sync.WaitGroup, log *log.Logger,
+	oldCaches map[string]cache.Cache, args []string, errorsFatal bool) error {
+
+	if conf == nil {
+		return nil
+	}
+
+	if conf.Main.ServerName == "" {
+		conf.Main.ServerName, _ = os.Hostname()
+	}
+
+	if conf.ReloadConfig == nil {
+		conf.ReloadConfig = ro.NewOptions()
+	}
+
+	// the simulated origins are served before the config is built, so that the origins'
+	// connections can be warmed
+	if err := applySimulatorConfig(conf); err != nil {
+		if log == nil {
+			handleStartupIssue("ERROR: Could not start the simulated origins: "+err.Error(),
+				nil, nil, errorsFatal)
+			return err
+		}
+		handleStartupIssue("could not start the simulated origins",
+			tl.Pairs{"detail": err.Error()}, log, errorsFatal)
+		return err
+	}
+
+	// the new config's components are fully built before any of them replace the
+	// running ones, so that a failure leaves the running config intact
+	b, err := buildConfig(conf, oldConf, log, oldCaches)
+	if err != nil {
+		if log == nil {
+			handleStartupIssue("ERROR: Could not load configuration: "+err.Error(),
+				nil, nil, errorsFatal)
+			return err
+		}
+		pairs := tl.Pairs{"detail": err.Error()}
+		if be, ok := err.(*buildError); ok {
+			pairs["component"] = be.component
+		}
+		handleStartupIssue("config load failed, the running config was not changed",
+			pairs, log, errorsFatal)
+		return err
+	}
+
+	// when the running config stages its reloads, the new config is served on the canary
+	// listener until it is promoted through the Admin API
+	if oldConf != nil && oldConf.ReloadConfig != nil && oldConf.ReloadConfig.Staged() {
+		return stageConfig(conf, oldConf, b, wg, log, args)
+	}
+
+	err = commitConfig(conf, oldConf, b, wg, log, args)
+	// on a reload, the config is applied even when one of its listeners fails to bind,
+	// which is logged, so that the remaining listeners serve it
+	if oldConf != nil {
+		return nil
+	}
+	return err
+}
+
+// commitConfig replaces the running components with those of the build, and serves the
+// provided config from the main listeners. It returns the first error binding one of the
+// listeners, once the config has been applied
+func commitConfig(conf, oldConf *config.Config, b *configBuild, wg *sync.WaitGroup,
+	log *log.Logger, args []string) error {
+
+	runtime.SetServer(conf.Main.ServerName)
+	applyLoggingConfig(conf, oldConf, log, b.log)
+	log = b.log
+	applyMetricsConfig(conf, oldConf, log)
+	applyLockConfig(conf, log)
+	applyCachingConfig(conf, b)
+	applyMemoryBudgetConfig(conf)
+	applySpiffeConfig(conf, b, log)
+	b.routes.Start()
+
+	for _, w := range conf.LoaderWarnings {
+		log.Warn(w, tl.Pairs{})
+	}
+
+	if conf.Main.Simulate {
+		log.Info("serving simulated origins",
+			tl.Pairs{"url": simulator.BaseURL(conf.Main.SimulatorListenPort)})
+	}
+
+	router, tracers, caches := b.router, b.tracers, b.caches
+	rh := handlers.ReloadHandleFunc(runConfig, conf, wg, log, caches, args)
+
+	if conf.AdminConfig.FaultInjection() {
+		log.Warn("fault injection is enabled", tl.Pairs{})
+	}
+	frontend := applyAccessLogConfig(conf, oldConf, frontendHandler(conf, router), log)
+	applySlowLogConfig(conf, oldConf, log)
+
+	var adminAPI *admin.API
+	if conf.AdminConfig.Enabled() {
+		var st admin.Stager
+		if conf.ReloadConfig.Staged() {
+			st = stager{}
+		}
+		adminAPI = admin.New(conf.AdminConfig, func() (bool, error) {
+			return handlers.Reload(runConfig, conf, wg, log, caches, args, "adminAPI")
+		}, caches, lg, st, log)
+	}
+
+	err := applyListenerConfigs(conf, oldConf, frontend, http.HandlerFunc(rh), adminAPI, log, tracers)
+	applyStatsDConfig(conf, oldConf, log)
+	applyOTLPMetricsConfig(conf, oldConf, log)
+
+	metrics.LastReloadSuccessfulTimestamp.Set(float64(time.Now().Unix()))
+	metrics.LastReloadSuccessful.Set(1)
+	// add Config Reload HUP Signal Monitor
+	if oldConf != nil && oldConf.Resources != nil {
+		oldConf.Resources.QuitChan <- true // this signals the old hup monitor goroutine to exit
+		stopSecretsMonitor(oldConf)
+	}
+	startHupMonitor(conf, wg, log, caches, args)
+	startSecretsMonitor(conf, wg, log, caches, args)
+
+	running = runState{conf: conf, log: log, caches: caches}
+	return err
+}
+
+// frontendHandler returns the router wrapped by the middleware that the frontend serves
+// it through, other than the access log
+func frontendHandler(conf *config.Config, router http.Handler) http.Handler {
+	fh := router
+	for i := len(hooks.middleware) - 1; i >= 0; i-- {
+		fh = hooks.middleware[i](fh)
+	}
+	if conf.AdminConfig.FaultInjection() {
+		// faults are injected inside of the access log, so that it records them
+		fh = faults.Handler(fh)
+	}
+	if sh, _ := headers.SecurityHeaders(conf.Frontend.SecurityHeaders,
+		conf.Frontend.ContentSecurityPolicy); len(sh) > 0 {
+		fh = middleware.SecurityHeaders(sh, fh)
+	}
+	return fh
+}
+
+// buildLogger returns the logger for the provided config, which is the running logger
+// when the logging output is unchanged
+func buildLogger(c, oc *config.Config, oldLog *log.Logger) *log.Logger {
+	if c == nil || c.Logging == nil {
+		return oldLog
+	}
+	if oc != nil && oc.Logging != nil && oldLog != nil && logOutputEqual(c.Logging, oc.Logging) {
+		return oldLog
+	}
+	return initLogger(c)
+}
+
+// applyLoggingConfig swaps the running logger for the logger built for the provided config
+func applyLoggingConfig(c, oc *config.Config, oldLog, newLog *log.Logger) {
+	if c == nil || c.Logging == nil {
+		return
+	}
+	if newLog == oldLog {
+		// no changes in the logging output, so we keep the old logger intact and
+		// (re)apply the configured level, which may have been changed at runtime
+		oldLog.SetLogLevel(c.Logging.LogLevel)
+		return
+	}
+	if oc != nil && oc.Logging != nil &&
+		(oc.Logging.LogFile != "" || oc.Logging.SyslogAddress != "") {
+		// if we're changing from file1 -> console or file1 -> file2, close file1 handle
+		// the extra 1s allows HTTP listeners to close first and finish their log writes
+		go delayedLogCloser(oldLog,
+			time.Duration(c.ReloadConfig.DrainTimeoutSecs+1)*time.Second)
+	}
+}
+
+// logOutputEqual returns true when the logging configs write to the same output
+// with the same rotation settings
+func logOutputEqual(c, oc *config.LoggingConfig) bool {
+	return c.LogFile == oc.LogFile &&
+		c.LogMaxSizeMB == oc.LogMaxSizeMB &&
+		c.LogMaxBackups == oc.LogMaxBackups &&
+		c.LogMaxAgeDays == oc.LogMaxAgeDays &&
+		c.LogCompress == oc.LogCompress &&
+		c.SyslogAddress == oc.SyslogAddress &&
+		c.SyslogFacility == oc.SyslogFacility &&
+		c.ErrorsToStderr == oc.ErrorsToStderr
+}
+
+// applyMetricsConfig sets whether exemplars are attached to the request duration
+// histograms, and sets the proxy request duration histogram buckets on startup. The
+// histogram is not safe to replace while serving, so a change in buckets on reload is
+// only logged
+func applyMetricsConfig(c, oc *config.Config, logger *log.Logger) {
+	if c.Metrics == nil {
+		return
+	}
+	metrics.SetExemplarsEnabled(c.Metrics.Exemplars)
+	if oc != nil {
+		if oc.Metrics != nil && !reflect.DeepEqual(oc.Metrics.ProxyDurationBuckets,
+			c.Metrics.ProxyDurationBuckets) {
+			logger.Warn("proxy duration buckets change requires a process restart. buckets not updated.",
+				tl.Pairs{"oldBuckets": oc.Metrics.ProxyDurationBuckets,
+					"newBuckets": c.Metrics.ProxyDurationBuckets})
+		}
+		return
+	}
+	if err := metrics.SetProxyDurationBuckets(c.Metrics.ProxyDurationBuckets); err != nil {
+		logger.Error("unable to set proxy duration buckets", tl.Pairs{"detail": err.Error()})
+	}
+}
+
+// applyLockConfig sets the duration after which held cache key locks are logged
+func applyLockConfig(c *config.Config, logger *log.Logger) {
+	if c.Main == nil {
+		return
+	}
+	locks.SetLongHoldThreshold(time.Duration(c.Main.LockHoldWarningMS)*time.Millisecond, logger)
+}
+
+// buildCaches opens the caches of the provided config into the build, reusing the
+// running caches whose configs are unchanged
+func buildCaches(b *configBuild, c, oc *config.Config, oldCaches map[string]cache.Cache) error {
+
+	b.caches = make(map[string]cache.Cache)
+	b.indexUpdates = make(map[*memory.Cache]*io.Options)
+
+	for k, v := range c.Caches {
+
+		if w, ok := oldCaches[k]; ok && oc != nil {
+
+			ocfg := w.Configuration()
+
+			// if a cache is in both the old and new config, and unchanged, pass the
+			// pre-existing object instead of making a new one
+			if v.Equal(ocfg) {
+				b.caches[k] = w
+				continue
+			}
+
+			// if the new and old caches with the same name are the same type, then assume
+			// the cache should be preserved between reconfigurations, but only if the Index
+			// is the only change. In this case, the new index configuration is applied to
+			// the old cache when the build is applied
+			// read-only memory caches are wrapped, so they are made anew
+			if mc, ok := w.(*memory.Cache); ok && ocfg.CacheTypeID == v.CacheTypeID &&
+				ocfg.CacheTypeID == types.CacheTypeMemory && !v.ReadOnly {
+				if v.Index != nil {
+					b.indexUpdates[mc] = v.Index
+				}
+				b.caches[k] = w
+				continue
+			}
+		}
+
+		// the newly-named cache is not in the old config or couldn't be reused, so make it anew
+		nc, err := registration.ConnectCache(k, v, b.log)
+		b.newCaches = append(b.newCaches, nc)
+		if err != nil {
+			return fmt.Errorf("cache %s: %s", k, err.Error())
+		}
+		b.caches[k] = nc
+	}
+
+	// any running cache that was not carried into the build is retired
+	for k, w := range oldCaches {
+		if nc, ok := b.caches[k]; !ok || nc != w {
+			b.retiredCaches = append(b.retiredCaches, w)
+		}
+	}
+
+	return nil
+}
+
+// applyCachingConfig updates the index options of the running caches carried into the
+// build, and closes the retired caches once the listeners have drained
+func applyCachingConfig(c *config.Config, b *configBuild) {
+	for mc, o := range b.indexUpdates {
+		mc.Index.UpdateOptions(o)
+	}
+	for _, w := range b.retiredCaches {
+		go func(w cache.Cache) {
+			time.Sleep(time.Second * time.Duration(c.ReloadConfig.DrainTimeoutSecs))
+			w.Close()
+		}(w)
+	}
+}
+
+// applyMemoryBudgetConfig sets the memory that the caching engines may buffer across all
+// in-flight requests before new requests are proxied without caching
+func applyMemoryBudgetConfig(c *config.Config) {
+	if c.Main == nil {
+		return
+	}
+	engines.SetMemoryBudget(int64(c.Main.MemoryBudgetMB) << 20)
+}
+
+func initLogger(c *config.Config) *log.Logger {
+	log := tl.New(c)
+	log.Info("application loaded from configuration",
+		tl.Pairs{
+			"name":      runtime.ApplicationName,
+			"version":   runtime.ApplicationVersion,
+			"goVersion": runtime.GoVersion,
+			"goArch":    runtime.GoArch,
+			"commitID":  runtime.GitCommitID,
+			"buildTime": runtime.BuildTime,
+			"logLevel":  c.Logging.LogLevel,
+			"config":    c.ConfigFilePath(),
+			"pid":       os.Getpid(),
+		},
+	)
+	return log
+}
+
+func delayedLogCloser(log *log.Logger, delay time.Duration) {
+	// we can't immediately close the log, because some outstanding
+	// http requests might still be on the old reference, so this will
+	// allow time for those connections to drain
+	if log == nil {
+		return
+	}
+	time.Sleep(delay)
+	log.Close()
+}
+
+// applySimulatorConfig serves the simulated origins when the config enables them, and
+// otherwise stops serving them
+func applySimulatorConfig(conf *config.Config) error {
+	if !conf.Main.Simulate {
+		simulator.Stop()
+		return nil
+	}
+	return simulator.Start(conf.Main.SimulatorListenPort)
+}
+
+func handleStartupIssue(event string, detail log.Pairs, logger *log.Logger, exitFatal bool) {
+	metrics.LastReloadSuccessful.Set(0)
+	if event != "" {
+		if logger != nil {
+			if exitFatal {
+				logger.Fatal(1, event, detail)
+				return
+			}
+			logger.Error(event, detail)
+			return
+		}
+		fmt.Println(event)
+	}
+	if exitFatal {
+		os.Exit(1)
+	}
+}
+
+// ValidateConfig validates that the config's origins, tracers and listeners can be set
+// up, without starting them, and prints the config's loader warnings
+func ValidateConfig(conf *config.Config) error {
+
+	for _, w := range conf.LoaderWarnings {
+		fmt.Println(w)
+	}
+
+	var caches = make(map[string]cache.Cache)
+	for k := range conf.Caches {
+		caches[k] = nil
+	}
+
+	router := mux.NewRouter()
+	log := log.ConsoleLogger(conf.Logging.LogLevel)
+
+	tracers, err := tr.RegisterAll(conf, log, true)
+	if err != nil {
+		return err
+	}
+
+	_, err = routing.RegisterProxyRoutes(conf, router, caches, tracers, log, true)
+	if err != nil {
+		return err
+	}
+
+	if conf.Frontend.TLSListenPort < 1 && conf.Frontend.ListenPort < 1 {
+		return errors.New("no http or https listeners configured")
+	}
+
+	if conf.Frontend.ServeTLS && conf.Frontend.TLSListenPort > 0 {
+		_, err = conf.TLSCertConfig()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
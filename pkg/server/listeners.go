@@ -0,0 +1,299 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/admin"
+	"github.com/tricksterproxy/trickster/pkg/config"
+	ph "github.com/tricksterproxy/trickster/pkg/proxy/handlers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/listeners"
+	ttls "github.com/tricksterproxy/trickster/pkg/proxy/tls"
+	"github.com/tricksterproxy/trickster/pkg/routing"
+	"github.com/tricksterproxy/trickster/pkg/tracing"
+	"github.com/tricksterproxy/trickster/pkg/util/log"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+	"github.com/tricksterproxy/trickster/pkg/util/middleware"
+)
+
+var lg = listeners.NewListenerGroup()
+
+// listenerErrorsFatal is true when a listener that fails to serve exits the process
+var listenerErrorsFatal = true
+
+// applyListenerConfigs starts, restarts and closes the listeners for the provided config,
+// and updates the routers of those that keep running. It returns the first error binding
+// a listener, once every listener has been applied
+func applyListenerConfigs(conf, oldConf *config.Config,
+	router, reloadHandler http.Handler, adminAPI *admin.API, log *log.Logger,
+	tracers tracing.Tracers) error {
+
+	var err, bindErr error
+	var tlsConfig *tls.Config
+
+	if conf == nil || conf.Frontend == nil {
+		return nil
+	}
+
+	// start binds the listener and serves it in the background, and records a bind error
+	start := func(listenerName, address, listenFamily string, port int, tlsConfig *tls.Config,
+		router http.Handler, tracers tracing.Tracers, drainTimeout time.Duration) {
+		err := startListener(listenerName, address, listenFamily, port,
+			conf.Frontend.ConnectionsLimit, tlsConfig, router, wg, tracers, listenerErrorsFatal,
+			drainTimeout, log)
+		if err != nil && bindErr == nil {
+			bindErr = err
+		}
+	}
+
+	adminRouter := reloadRouter(conf, reloadHandler, adminAPI, log)
+
+	// the running frontend listeners serve the new routers, including when other frontend
+	// options have changed, since only changes in their addresses restart them
+	lg.UpdateFrontendRouters(router, adminRouter)
+	applyCertWatch(conf, log)
+
+	// No changes in frontend config
+	if oldConf != nil && oldConf.Frontend != nil &&
+		oldConf.Frontend.Equal(conf.Frontend) {
+		if ttls.OptionsChanged(conf, oldConf) {
+			tlsConfig, _ = conf.TLSCertConfig()
+			l := lg.Get("tlsListener")
+			if l != nil {
+				cs := l.CertSwapper()
+				if cs != nil {
+					cs.SetCerts(tlsConfig.Certificates)
+				}
+			}
+		}
+	}
+
+	if oldConf != nil && oldConf.Frontend.ConnectionsLimit != conf.Frontend.ConnectionsLimit {
+		log.Warn("connections limit change requires a process restart. listeners not updated.",
+			tl.Pairs{"oldLimit": oldConf.Frontend.ConnectionsLimit,
+				"newLimit": conf.Frontend.ConnectionsLimit})
+		return nil
+	}
+
+	hasOldFC := oldConf != nil && oldConf.Frontend != nil
+	hasOldMC := oldConf != nil && oldConf.Metrics != nil
+	hasOldRC := oldConf != nil && oldConf.ReloadConfig != nil
+	var tracerFlusherSet bool
+
+	// if TLS port is configured and at least one origin is mapped to a good tls config,
+	// then set up the tls server listener instance
+	if conf.Frontend.ServeTLS && conf.Frontend.TLSListenPort > 0 && (!hasOldFC ||
+		!oldConf.Frontend.ServeTLS ||
+		(oldConf.Frontend.TLSListenAddress != conf.Frontend.TLSListenAddress ||
+			oldConf.Frontend.TLSListenPort != conf.Frontend.TLSListenPort ||
+			oldConf.Frontend.TLSListenFamily != conf.Frontend.TLSListenFamily)) {
+		drainAndClose(conf, "tls", log)
+		tlsConfig, err = conf.TLSCertConfig()
+		if err != nil {
+			log.Error("unable to start tls listener due to certificate error", tl.Pairs{"detail": err})
+		} else {
+			tracerFlusherSet = true
+			start("tlsListener",
+				conf.Frontend.TLSListenAddress, conf.Frontend.TLSListenFamily,
+				conf.Frontend.TLSListenPort, tlsConfig, router, tracers,
+				conf.ReloadConfig.DrainTimeout("tls"))
+		}
+	} else if !conf.Frontend.ServeTLS && hasOldFC && oldConf.Frontend.ServeTLS {
+		// the TLS configs have been removed between the last config load and this one,
+		// the TLS listener port needs to be stopped
+		drainAndClose(conf, "tls", log)
+	} else if conf.Frontend.ServeTLS && ttls.OptionsChanged(conf, oldConf) {
+		tlsConfig, _ = conf.TLSCertConfig()
+		if err != nil {
+			log.Error("unable to update tls config to certificate error", tl.Pairs{"detail": err})
+			return nil
+		}
+		l := lg.Get("tlsListener")
+		if l != nil {
+			cs := l.CertSwapper()
+			if cs != nil {
+				cs.SetCerts(tlsConfig.Certificates)
+			}
+		}
+	}
+
+	// if the plaintext HTTP port is configured, then set up the http listener instance
+	if conf.Frontend.ListenPort > 0 && (!hasOldFC ||
+		(oldConf.Frontend.ListenAddress != conf.Frontend.ListenAddress ||
+			oldConf.Frontend.ListenPort != conf.Frontend.ListenPort ||
+			oldConf.Frontend.ListenFamily != conf.Frontend.ListenFamily)) {
+		drainAndClose(conf, "http", log)
+		var t2 tracing.Tracers
+		if !tracerFlusherSet {
+			t2 = tracers
+		}
+		start("httpListener",
+			conf.Frontend.ListenAddress, conf.Frontend.ListenFamily, conf.Frontend.ListenPort,
+			nil, router, t2, 0)
+	}
+
+	// if the Metrics HTTP port is configured, then set up the http listener instance
+	if conf.Metrics != nil && conf.Metrics.ListenPort > 0 &&
+		(!hasOldMC || (conf.Metrics.ListenAddress != oldConf.Metrics.ListenAddress ||
+			conf.Metrics.ListenPort != oldConf.Metrics.ListenPort ||
+			conf.Metrics.ListenFamily != oldConf.Metrics.ListenFamily)) {
+		drainAndClose(conf, "metrics", log)
+		mr := http.NewServeMux()
+		mr.Handle("/metrics", metrics.Handler())
+		mr.HandleFunc(conf.Main.ConfigHandlerPath, ph.ConfigHandleFunc(conf))
+		if conf.Main.PprofServer == "both" || conf.Main.PprofServer == "metrics" {
+			routing.RegisterDebugRoutes("metrics", mr, conf.DebugConfig, log)
+		}
+		start("metricsListener",
+			conf.Metrics.ListenAddress, conf.Metrics.ListenFamily, conf.Metrics.ListenPort,
+			nil, mr, nil, 0)
+	} else {
+		mr := http.NewServeMux()
+		mr.Handle("/metrics", metrics.Handler())
+		mr.HandleFunc(conf.Main.ConfigHandlerPath, ph.ConfigHandleFunc(conf))
+		if conf.Main.PprofServer == "both" || conf.Main.PprofServer == "metrics" {
+			routing.RegisterDebugRoutes("metrics", mr, conf.DebugConfig, log)
+		}
+		lg.UpdateRouter("metricsListener", mr)
+	}
+
+	// if the Reload HTTP port is configured, then set up the http listener instance
+	if conf.ReloadConfig != nil && conf.ReloadConfig.ListenPort > 0 &&
+		(!hasOldRC || !conf.ReloadConfig.ListenerEqual(oldConf.ReloadConfig)) {
+		drainAndClose(conf, "reload", log)
+		// the TLS config was validated when the config was loaded
+		rtc, err := conf.ReloadConfig.TLSConfig()
+		if err != nil {
+			log.Error("unable to start reload listener due to tls error", tl.Pairs{"detail": err})
+		} else {
+			if conf.ReloadConfig.AuthToken == "" && len(conf.ReloadConfig.ClientCAPaths) == 0 {
+				log.Warn("reload listener has no authentication configured", tl.Pairs{
+					"listenAddress": conf.ReloadConfig.ListenAddress,
+					"listenPort":    conf.ReloadConfig.ListenPort})
+			}
+			start("reloadListener",
+				conf.ReloadConfig.ListenAddress, conf.ReloadConfig.ListenFamily,
+				conf.ReloadConfig.ListenPort, rtc, adminRouter, nil, 0)
+		}
+	} else {
+		lg.UpdateRouter("reloadListener", adminRouter)
+	}
+
+	// if the debug routes are configured for the dedicated Debug listener, then set up
+	// the http listener instance
+	hasOldDC := oldConf != nil && oldConf.DebugConfig != nil &&
+		oldConf.Main.PprofServer == "debug"
+	if conf.Main.PprofServer == "debug" && conf.DebugConfig != nil &&
+		conf.DebugConfig.ListenPort > 0 {
+		mr := http.NewServeMux()
+		routing.RegisterDebugRoutes("debug", mr, conf.DebugConfig, log)
+		if !hasOldDC || conf.DebugConfig.ListenAddress != oldConf.DebugConfig.ListenAddress ||
+			conf.DebugConfig.ListenPort != oldConf.DebugConfig.ListenPort ||
+			conf.DebugConfig.ListenFamily != oldConf.DebugConfig.ListenFamily {
+			drainAndClose(conf, "debug", log)
+			start("debugListener",
+				conf.DebugConfig.ListenAddress, conf.DebugConfig.ListenFamily,
+				conf.DebugConfig.ListenPort, nil, mr, nil, 0)
+		} else {
+			lg.UpdateRouter("debugListener", mr)
+		}
+	} else if hasOldDC {
+		drainAndClose(conf, "debug", log)
+	}
+	return bindErr
+}
+
+// startListener binds the named listener, and then serves it in the background, so that
+// the listener accepts connections once it returns
+func startListener(listenerName, address, listenFamily string, port, connectionsLimit int,
+	tlsConfig *tls.Config, router http.Handler, wg *sync.WaitGroup, tracers tracing.Tracers,
+	exitOnError bool, drainTimeout time.Duration, log *tl.Logger) error {
+	l, err := lg.BindListener(listenerName, address, listenFamily, port, connectionsLimit,
+		tlsConfig, router, exitOnError, drainTimeout, log)
+	if err != nil {
+		return err
+	}
+	wg.Add(1)
+	go lg.ServeListener(listenerName, l, wg, tracers, log)
+	return nil
+}
+
+// applyCertWatch sets the certificate and key files that the tls listener watches for
+// changes, when the config sets a watch interval, and otherwise stops the watch
+func applyCertWatch(conf *config.Config, log *tl.Logger) {
+	var pairs []ttls.KeyPair
+	// the certificates of spiffe frontend mTLS are rotated by the spiffe source
+	if conf.Frontend.ServeTLS && conf.Frontend.TLSWatchIntervalSecs > 0 &&
+		!(conf.Spiffe.Enabled() && conf.Spiffe.FrontendMTLS) {
+		for _, oc := range conf.Origins {
+			if oc.TLS != nil && oc.TLS.ServeTLS {
+				pairs = append(pairs, ttls.KeyPair{CertPath: oc.TLS.FullChainCertPath,
+					KeyPath: oc.TLS.PrivateKeyPath})
+			}
+		}
+	}
+	lg.WatchCerts("tlsListener", pairs,
+		time.Duration(conf.Frontend.TLSWatchIntervalSecs)*time.Second,
+		func() {
+			log.Info("tls certificates rotated", tl.Pairs{"certificates": len(pairs)})
+		},
+		func(err error) {
+			log.Error("tls certificate rotation failed, the current certificates remain in use",
+				tl.Pairs{"detail": err.Error()})
+		})
+}
+
+// drainAndClose closes the named listener, using the drain policy of the provided config
+func drainAndClose(conf *config.Config, listenerName string, log *tl.Logger) {
+	lg.DrainAndClose(listenerName+"Listener", conf.ReloadConfig.DrainTimeout(listenerName),
+		!conf.ReloadConfig.DrainForceCloseDisabled, log)
+}
+
+// reloadRouter returns the handler of the reload listener. When the reload listener has an
+// auth token, it is required by every route other than the Admin API and debug routes that
+// authenticate requests with their own credentials. Every request is recorded in the audit log
+func reloadRouter(conf *config.Config, reloadHandler http.Handler, adminAPI *admin.API,
+	log *tl.Logger) http.Handler {
+
+	mr := http.NewServeMux()
+	mr.HandleFunc(conf.Main.ConfigHandlerPath, ph.ConfigHandleFunc(conf))
+	mr.Handle(conf.ReloadConfig.HandlerPath, reloadHandler)
+	mr.HandleFunc(conf.Main.LogLevelHandlerPath, ph.LogLevelHandleFunc(log))
+
+	root := mr
+	if conf.ReloadConfig.AuthToken != "" {
+		root = http.NewServeMux()
+		root.Handle("/", middleware.BearerAuth("trickster reload", conf.ReloadConfig.AuthToken, mr, nil))
+	}
+	if adminAPI != nil {
+		root.Handle(adminAPI.Path(), adminAPI)
+	}
+	if conf.Main.PprofServer == "both" || conf.Main.PprofServer == "reload" {
+		if conf.DebugConfig.AuthEnabled() {
+			routing.RegisterDebugRoutes("reload", root, conf.DebugConfig, log)
+		} else {
+			routing.RegisterDebugRoutes("reload", mr, conf.DebugConfig, log)
+		}
+	}
+
+	return middleware.Audit(log, "reload listener request", root)
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/admin"
+	"github.com/tricksterproxy/trickster/pkg/config"
+	ao "github.com/tricksterproxy/trickster/pkg/config/admin/options"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+func TestReloadRouter(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-origin-type", "rpc", "-origin-url", "http://tricksterproxy.io"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.ReloadConfig.AuthToken = "reload-token"
+	conf.Main.PprofServer = "reload"
+	conf.DebugConfig.Username = "debug"
+	conf.DebugConfig.Password = "debug-pass"
+
+	log := tl.ConsoleLogger("error")
+	reloadHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	adminAPI := admin.New(&ao.Options{HandlerPath: "/trickster/admin", AuthToken: "admin-token"},
+		func() (bool, error) { return false, nil }, nil, lg, nil, log)
+	h := reloadRouter(conf, reloadHandler, adminAPI, log)
+
+	tests := []struct {
+		path, auth string
+		basic      bool
+		expected   int
+	}{
+		{conf.ReloadConfig.HandlerPath, "", false, http.StatusUnauthorized},
+		{conf.ReloadConfig.HandlerPath, "Bearer wrong", false, http.StatusUnauthorized},
+		{conf.ReloadConfig.HandlerPath, "Bearer reload-token", false, http.StatusOK},
+		{conf.Main.ConfigHandlerPath, "", false, http.StatusUnauthorized},
+		{conf.Main.ConfigHandlerPath, "Bearer reload-token", false, http.StatusOK},
+		// the Admin API and authenticated debug routes use their own credentials
+		{"/trickster/admin/v1/stats", "Bearer reload-token", false, http.StatusUnauthorized},
+		{"/trickster/admin/v1/stats", "Bearer admin-token", false, http.StatusOK},
+		{"/debug/pprof/", "", true, http.StatusOK},
+		{"/debug/pprof/", "Bearer reload-token", false, http.StatusUnauthorized},
+	}
+
+	for i, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.basic {
+			r.SetBasicAuth("debug", "debug-pass")
+		} else if test.auth != "" {
+			r.Header.Set("Authorization", test.auth)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != test.expected {
+			t.Errorf("test %d: expected %d got %d", i, test.expected, w.Code)
+		}
+	}
+
+	// without an auth token, the routes are not authenticated
+	conf.ReloadConfig.AuthToken = ""
+	conf.DebugConfig.Username = ""
+	h = reloadRouter(conf, reloadHandler, nil, log)
+	for _, path := range []string{conf.ReloadConfig.HandlerPath, "/debug/pprof/"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("expected %d got %d for %s", http.StatusOK, w.Code, path)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/tricksterproxy/trickster/pkg/config"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics/otlp"
+	oo "github.com/tricksterproxy/trickster/pkg/util/metrics/otlp/options"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// otlpMetricsPusher is the running OTLP metrics pusher, if any. It is only
+// accessed while holding cfgLock
+var otlpMetricsPusher *otlp.Pusher
+
+// applyOTLPMetricsConfig starts, restarts or stops exporting metrics via OTLP,
+// so that it matches the provided config
+func applyOTLPMetricsConfig(conf, oldConf *config.Config, log *tl.Logger) {
+
+	var o, old *oo.Options
+	if conf != nil && conf.Metrics != nil {
+		o = conf.Metrics.OTLP
+	}
+	if oldConf != nil && oldConf.Metrics != nil {
+		old = oldConf.Metrics.OTLP
+	}
+
+	if otlpMetricsPusher != nil {
+		if o.Equal(old) {
+			return
+		}
+		otlpMetricsPusher.Stop()
+		otlpMetricsPusher = nil
+	}
+
+	if !o.Enabled() {
+		return
+	}
+
+	p, err := otlp.New(o, prometheus.DefaultGatherer)
+	if err != nil {
+		log.Error("unable to export metrics via otlp", tl.Pairs{"detail": err.Error()})
+		return
+	}
+	p.Start(func(err error) {
+		log.WarnOnce("otlp.metrics.push", "unable to export metrics via otlp",
+			tl.Pairs{"collectorURL": o.CollectorURL, "detail": err.Error()})
+	})
+	log.Info("exporting metrics via otlp", tl.Pairs{"collectorURL": o.CollectorURL})
+	otlpMetricsPusher = p
+}
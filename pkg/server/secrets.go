@@ -0,0 +1,77 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/config"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// startSecretsMonitor periodically re-fetches the secrets used by the running config,
+// and reloads the config when any have been rotated
+func startSecretsMonitor(conf *config.Config, wg *sync.WaitGroup, log *tl.Logger,
+	caches map[string]cache.Cache, args []string) {
+	if conf == nil || conf.Resources == nil || conf.Resources.SecretsQuitChan == nil {
+		return
+	}
+	interval := conf.SecretsRefreshInterval()
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				changed, err := conf.SecretsChanged()
+				if err != nil {
+					log.Warn("unable to refresh secrets", tl.Pairs{"detail": err.Error()})
+					continue
+				}
+				if !changed {
+					continue
+				}
+				conf.Main.ReloaderLock.Lock()
+				log.Warn("configuration reload starting now", tl.Pairs{"source": "secrets"})
+				err = runConfig(conf, wg, log, caches, args, false)
+				conf.Main.ReloaderLock.Unlock()
+				if err == nil {
+					return // runConfig will start a new secrets monitor in place of this one
+				}
+				log.Warn("configuration NOT reloaded", tl.Pairs{})
+			case <-conf.Resources.SecretsQuitChan:
+				return
+			}
+		}
+	}()
+}
+
+// stopSecretsMonitor signals the config's secrets monitor goroutine, if any, to exit
+func stopSecretsMonitor(conf *config.Config) {
+	if conf == nil || conf.Resources == nil || conf.Resources.SecretsQuitChan == nil {
+		return
+	}
+	select {
+	case conf.Resources.SecretsQuitChan <- true:
+	default:
+	}
+}
@@ -0,0 +1,231 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package server runs Trickster's caching proxy, so that it can be run by the trickster
+// command or embedded in-process by other Go services
+package server
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/proxy/handlers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/tls/spiffe"
+	"github.com/tricksterproxy/trickster/pkg/routing"
+	"github.com/tricksterproxy/trickster/pkg/simulator"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/slowlog"
+)
+
+// ErrServerRunning is returned when starting a Server while one is already running
+var ErrServerRunning = errors.New("a trickster server is already running in this process")
+
+// ErrServerNotRunning is returned when reloading a Server that is not running
+var ErrServerNotRunning = errors.New("the trickster server is not running")
+
+// listenerNames are the names of the listeners that a config may start
+var listenerNames = []string{"tls", "http", "metrics", "reload", "debug", "canary"}
+
+// wg tracks the running listeners
+var wg = &sync.WaitGroup{}
+
+// runState is the running config and the components built for it. It is only accessed
+// while holding cfgLock
+type runState struct {
+	conf   *config.Config
+	log    *tl.Logger
+	caches map[string]cache.Cache
+}
+
+var running runState
+
+// customHandler is a handler that the embedding service serves from the frontend router
+type customHandler struct {
+	path    string
+	handler http.Handler
+}
+
+// hookSet is the custom handlers and middleware of a Server
+type hookSet struct {
+	handlers   []customHandler
+	middleware []func(http.Handler) http.Handler
+}
+
+// hooks are the hooks of the running Server, which are applied on every config (re)load.
+// They are only accessed while holding cfgLock
+var hooks hookSet
+
+// Server is a Trickster caching proxy. Its listeners, caches, metrics and signal handling
+// are process-wide, so only one Server may run in a process at a time
+type Server struct {
+	conf  *config.Config
+	args  []string
+	fatal bool
+	hooks hookSet
+}
+
+// Option is a functional option for configuring a Server
+type Option func(*Server)
+
+// WithArgs sets the command line arguments that the config was loaded from. The config
+// is reloaded from them when its config file changes
+func WithArgs(args []string) Option {
+	return func(s *Server) {
+		s.args = args
+	}
+}
+
+// WithHandler serves the handler from the frontend listeners at the path, ahead of the
+// proxy routes. A path ending in a slash matches every path under it, like http.ServeMux
+func WithHandler(path string, handler http.Handler) Option {
+	return func(s *Server) {
+		s.hooks.handlers = append(s.hooks.handlers, customHandler{path: path, handler: handler})
+	}
+}
+
+// WithMiddleware wraps the frontend router with the middleware. Middleware is applied in
+// the order provided, so that the first one receives requests first
+func WithMiddleware(middleware func(http.Handler) http.Handler) Option {
+	return func(s *Server) {
+		s.hooks.middleware = append(s.hooks.middleware, middleware)
+	}
+}
+
+// WithFatalStartupErrors sets whether a failure to start the Server or one of its
+// listeners exits the process, which is how the trickster command behaves. By default,
+// the error is returned by Start instead
+func WithFatalStartupErrors(fatal bool) Option {
+	return func(s *Server) {
+		s.fatal = fatal
+	}
+}
+
+// New returns a Server for the config, which should be loaded with config.Load
+func New(conf *config.Config, opts ...Option) *Server {
+	s := &Server{conf: conf}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Start validates the Server's config, and starts its listeners and background tasks.
+// It returns once the listeners are bound and accepting connections. When a listener fails
+// to bind, the Server is stopped and the error is returned
+func (s *Server) Start() error {
+	cfgLock.Lock()
+	defer cfgLock.Unlock()
+	if running.conf != nil {
+		return ErrServerRunning
+	}
+	hooks = s.hooks
+	listenerErrorsFatal = s.fatal
+	if err := ValidateConfig(s.conf); err != nil {
+		handleStartupIssue("ERROR: Could not load configuration: "+err.Error(),
+			nil, nil, s.fatal)
+		return err
+	}
+	if err := applyConfig(s.conf, nil, wg, nil, nil, s.args, s.fatal); err != nil {
+		// the components started before the failure are stopped, so that the Server
+		// can be started again
+		stop()
+		return err
+	}
+	return nil
+}
+
+// Reload reloads the running config from the Server's args if its config file has
+// changed, and returns true if the config was reloaded
+func (s *Server) Reload() (bool, error) {
+	cfgLock.Lock()
+	r := running
+	cfgLock.Unlock()
+	if r.conf == nil {
+		return false, ErrServerNotRunning
+	}
+	return handlers.Reload(runConfig, r.conf, wg, r.log, r.caches, s.args, "server")
+}
+
+// Stop drains and closes the Server's listeners, and then stops its background tasks and
+// closes its caches. It returns once the listeners have been closed
+func (s *Server) Stop() {
+	cfgLock.Lock()
+	defer cfgLock.Unlock()
+	stop()
+}
+
+// stop stops the running Server. It must be called while holding cfgLock
+func stop() {
+	r := running
+	if r.conf == nil {
+		return
+	}
+	running = runState{}
+
+	stopHupMonitor(r.conf)
+	stopSecretsMonitor(r.conf)
+
+	lwg := &sync.WaitGroup{}
+	for _, name := range listenerNames {
+		lwg.Add(1)
+		go func(name string) {
+			lg.Shutdown(name+"Listener", r.conf.ReloadConfig.DrainTimeout(name),
+				!r.conf.ReloadConfig.DrainForceCloseDisabled, r.log)
+			lwg.Done()
+		}(name)
+	}
+	lwg.Wait()
+
+	if staged != nil {
+		staged.build.rollback(r.log, nil)
+		staged = nil
+	}
+
+	routing.StopBackgroundTasks()
+	if statsdPusher != nil {
+		statsdPusher.Stop()
+		statsdPusher = nil
+	}
+	if otlpMetricsPusher != nil {
+		otlpMetricsPusher.Stop()
+		otlpMetricsPusher = nil
+	}
+	if accessLogger != nil {
+		accessLogger.Close()
+		accessLogger = nil
+	}
+	if l := slowlog.SetLogger(nil); l != nil {
+		l.Close()
+	}
+	for _, c := range r.caches {
+		c.Close()
+	}
+	if prev := spiffe.SetSource(nil); prev != nil {
+		prev.Stop()
+	}
+	simulator.Stop()
+
+	r.log.Info("trickster server stopped", tl.Pairs{})
+	r.log.Close()
+}
+
+// Wait blocks until the Server's listeners have exited
+func (s *Server) Wait() {
+	wg.Wait()
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/config"
+)
+
+func TestServer(t *testing.T) {
+
+	args := []string{"-origin-type", "rpc", "-origin-url", "http://tricksterproxy.io",
+		"-proxy-port", "18490", "-metrics-port", "18491", "-log-level", "error"}
+	conf, _, err := config.Load("trickster", "test", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.ReloadConfig.ListenPort = 0
+
+	var seen int32
+	s := New(conf, WithArgs(args),
+		WithHandler("/custom", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("custom"))
+		})),
+		WithMiddleware(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.StoreInt32(&seen, 1)
+				next.ServeHTTP(w, r)
+			})
+		}),
+	)
+
+	if _, err = s.Reload(); err != ErrServerNotRunning {
+		t.Errorf("expected %v got %v", ErrServerNotRunning, err)
+	}
+
+	if err = s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err = New(conf).Start(); err != ErrServerRunning {
+		t.Errorf("expected %v got %v", ErrServerRunning, err)
+	}
+
+	// the listeners accept connections once Start returns
+	resp, err := http.Get("http://127.0.0.1:18490/custom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(b) != "custom" {
+		t.Errorf("expected %s got %s", "custom", string(b))
+	}
+	if atomic.LoadInt32(&seen) == 0 {
+		t.Error("expected the middleware to serve the request")
+	}
+
+	// the config has no config file, so it is never stale
+	if reloaded, err := s.Reload(); reloaded || err != nil {
+		t.Errorf("expected no reload got %t %v", reloaded, err)
+	}
+
+	s.Stop()
+	s.Wait()
+	if _, err = http.Get("http://127.0.0.1:18490/custom"); err == nil {
+		t.Error("expected the listener to be closed")
+	}
+
+	// a stopped server can be started again
+	conf, _, _ = config.Load("trickster", "test", args)
+	conf.ReloadConfig.ListenPort = 0
+	s = New(conf)
+	if err = s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	s.Stop()
+	s.Wait()
+
+	// a listener that fails to bind fails the start, and leaves the server stopped
+	nl, err := net.Listen("tcp", "127.0.0.1:18491")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf, _, _ = config.Load("trickster", "test", args)
+	conf.ReloadConfig.ListenPort = 0
+	s = New(conf)
+	if err = s.Start(); err == nil {
+		t.Error("expected port in use error")
+	}
+	nl.Close()
+	s.Wait()
+	if _, err = http.Get("http://127.0.0.1:18490/custom"); err == nil {
+		t.Error("expected the listener to be closed")
+	}
+	if err = s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	s.Stop()
+}
@@ -14,7 +14,7 @@
  * limitations under the License.
  */
 
-package main
+package server
 
 import (
 	"os"
@@ -29,15 +29,16 @@ import (
 
 var hups = make(chan os.Signal, 1)
 
-func init() {
-	signal.Notify(hups, syscall.SIGHUP)
-}
+// hupsOnce subscribes to SIGHUP when the first monitor starts, rather than on import, so
+// that a process embedding the server only has the signal captured once it starts one
+var hupsOnce sync.Once
 
 func startHupMonitor(conf *config.Config, wg *sync.WaitGroup, log *tl.Logger,
 	caches map[string]cache.Cache, args []string) {
 	if conf == nil || conf.Resources == nil {
 		return
 	}
+	hupsOnce.Do(func() { signal.Notify(hups, syscall.SIGHUP) })
 	// assumes all parameters are instantiated
 	go func() {
 		for {
@@ -60,3 +61,14 @@ func startHupMonitor(conf *config.Config, wg *sync.WaitGroup, log *tl.Logger,
 		}
 	}()
 }
+
+// stopHupMonitor signals the config's hup monitor goroutine, if any, to exit
+func stopHupMonitor(conf *config.Config) {
+	if conf == nil || conf.Resources == nil || conf.Resources.QuitChan == nil {
+		return
+	}
+	select {
+	case conf.Resources.QuitChan <- true:
+	default:
+	}
+}
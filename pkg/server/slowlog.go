@@ -0,0 +1,61 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/config"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/slowlog"
+	so "github.com/tricksterproxy/trickster/pkg/util/slowlog/options"
+)
+
+// applySlowLogConfig opens, reopens or closes the slow query log so that it
+// matches the provided config
+func applySlowLogConfig(conf, oldConf *config.Config, log *tl.Logger) {
+
+	var o, old *so.Options
+	if conf != nil && conf.Logging != nil {
+		o = conf.Logging.SlowLog
+	}
+	if oldConf != nil && oldConf.Logging != nil {
+		old = oldConf.Logging.SlowLog
+	}
+
+	if slowlog.Enabled() && o.Equal(old) {
+		return
+	}
+
+	var l *slowlog.Logger
+	if o.Enabled() {
+		l = slowlog.New(o)
+		log.Info("slow query log enabled", tl.Pairs{"output": o.Output})
+	}
+
+	if prev := slowlog.SetLogger(l); prev != nil {
+		// fetches that are already in flight may still hold the old logger, so it
+		// is closed after the listeners have drained
+		go delayedSlowLogCloser(prev,
+			time.Duration(conf.ReloadConfig.DrainTimeoutSecs+1)*time.Second)
+	}
+}
+
+func delayedSlowLogCloser(l *slowlog.Logger, delay time.Duration) {
+	time.Sleep(delay)
+	l.Close()
+}
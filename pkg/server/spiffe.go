@@ -0,0 +1,44 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/proxy/tls/spiffe"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// applySpiffeConfig starts the workload identity loaded by the build in place of the
+// running one, or stops the running one when the provided config has none
+func applySpiffeConfig(conf *config.Config, b *configBuild, log *tl.Logger) {
+	if b.spiffe == nil {
+		if !conf.Spiffe.Enabled() {
+			if prev := spiffe.SetSource(nil); prev != nil {
+				prev.Stop()
+			}
+		}
+		return
+	}
+	b.spiffe.Start(func(err error) {
+		log.WarnOnce("spiffe.refresh", "unable to reload the spiffe svid",
+			tl.Pairs{"detail": err.Error()})
+	})
+	if prev := spiffe.SetSource(b.spiffe); prev != nil {
+		prev.Stop()
+	}
+	log.Info("spiffe workload identity loaded", tl.Pairs{"spiffeID": b.spiffe.ID()})
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/admin"
+	"github.com/tricksterproxy/trickster/pkg/config"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// canaryListenerName is the name of the listener that serves a staged config
+const canaryListenerName = "canaryListener"
+
+// stagedConfig is a reloaded config whose components are built and served on the canary
+// listener, but which is not yet applied to the main listeners
+type stagedConfig struct {
+	conf, oldConf *config.Config
+	build         *configBuild
+	wg            *sync.WaitGroup
+	args          []string
+	canaryAddress string
+	stagedAt      time.Time
+}
+
+// staged is the staged config, if any. It is only accessed while holding cfgLock
+var staged *stagedConfig
+
+// stageConfig serves the provided config's frontend on the canary listener of the running
+// config, which is started if it is not already serving a previously staged config. A
+// previously staged config is replaced, and its build is rolled back. When the canary
+// listener fails to bind, the provided config's build is rolled back and the error returned
+func stageConfig(conf, oldConf *config.Config, b *configBuild, wg *sync.WaitGroup,
+	log *tl.Logger, args []string) error {
+
+	rc := oldConf.ReloadConfig
+	handler := frontendHandler(conf, b.router)
+
+	if staged != nil {
+		staged.build.rollback(log, nil)
+		staged = nil
+	}
+	if lg.Get(canaryListenerName) != nil {
+		lg.UpdateRouter(canaryListenerName, handler)
+	} else if err := startListener(canaryListenerName,
+		rc.CanaryListenAddress, oldConf.Frontend.ListenFamily, rc.CanaryListenPort,
+		oldConf.Frontend.ConnectionsLimit, nil, handler, wg, nil, false,
+		rc.DrainTimeout("canary"), log); err != nil {
+		return b.rollback(log, err)
+	}
+
+	staged = &stagedConfig{
+		conf:          conf,
+		oldConf:       oldConf,
+		build:         b,
+		wg:            wg,
+		args:          args,
+		canaryAddress: net.JoinHostPort(rc.CanaryListenAddress, strconv.Itoa(rc.CanaryListenPort)),
+		stagedAt:      time.Now(),
+	}
+	log.Warn("configuration staged on the canary listener, pending promotion",
+		tl.Pairs{"canaryAddress": staged.canaryAddress})
+	return nil
+}
+
+// unstage removes the staged config and closes the canary listener, and returns the
+// removed config, which is nil when no config was staged. It must be called while
+// holding cfgLock
+func unstage(log *tl.Logger) *stagedConfig {
+	s := staged
+	if s == nil {
+		return nil
+	}
+	staged = nil
+	drainAndClose(s.oldConf, "canary", log)
+	return s
+}
+
+// stager promotes or discards the staged config on behalf of the Admin API
+type stager struct{}
+
+// Staged returns the staged config, or nil when no config is staged
+func (stager) Staged() *admin.StagedConfig {
+	cfgLock.Lock()
+	defer cfgLock.Unlock()
+	if staged == nil {
+		return nil
+	}
+	return &admin.StagedConfig{CanaryAddress: staged.canaryAddress, StagedAt: staged.stagedAt}
+}
+
+// Promote applies the staged config to the main listeners in place of the running config
+func (stager) Promote() error {
+	cfgLock.Lock()
+	defer cfgLock.Unlock()
+	log := running.log
+	s := unstage(log)
+	if s == nil {
+		return admin.ErrNotStaged
+	}
+	// the server may have been stopped while the config was staged
+	if s.oldConf != running.conf {
+		s.build.rollback(log, nil)
+		return errConfigNotRunning
+	}
+	// like a reload, the promoted config is applied even when one of its listeners fails
+	// to bind, which is logged
+	commitConfig(s.conf, s.oldConf, s.build, s.wg, log, s.args)
+	running.log.Info("staged configuration promoted", tl.Pairs{"stagedAt": s.stagedAt})
+	return nil
+}
+
+// Discard rolls back the staged config, leaving the running config in place
+func (stager) Discard() error {
+	cfgLock.Lock()
+	defer cfgLock.Unlock()
+	s := unstage(running.log)
+	if s == nil {
+		return admin.ErrNotStaged
+	}
+	s.build.rollback(running.log, nil)
+	running.log.Info("staged configuration discarded", tl.Pairs{"stagedAt": s.stagedAt})
+	return nil
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/admin"
+	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+// frameOptions requests the url, retrying until its listener is serving, and returns
+// the X-Frame-Options header of the response
+func frameOptions(t *testing.T, url string) string {
+	var resp *http.Response
+	var err error
+	for i := 0; i < 100; i++ {
+		resp, err = http.Get(url)
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.Header.Get(headers.NameXFrameOptions)
+}
+
+func TestStagedReload(t *testing.T) {
+
+	upstream, count := newCountingUpstream()
+	defer upstream.Close()
+	args := []string{"-origin-type", "rpc", "-origin-url", upstream.URL,
+		"-proxy-port", "18492", "-metrics-port", "18493", "-log-level", "error"}
+	load := func(securityHeaders, healthPath string) *config.Config {
+		conf, _, err := config.Load("trickster", "test", args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conf.ReloadConfig.ListenPort = 0
+		conf.ReloadConfig.CanaryListenAddress = "127.0.0.1"
+		conf.ReloadConfig.CanaryListenPort = 18494
+		conf.AdminConfig.AuthToken = "admin-token"
+		conf.Frontend.SecurityHeaders = securityHeaders
+		monitorHealth(conf.Origins["default"], healthPath)
+		return conf
+	}
+	stage := func(conf *config.Config) {
+		cfgLock.Lock()
+		defer cfgLock.Unlock()
+		if err := applyConfig(conf, running.conf, wg, running.log, running.caches,
+			args, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := New(load(headers.SecurityPresetNone, "/health"), WithArgs(args),
+		WithHandler("/custom", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("custom"))
+		})),
+	)
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	st := stager{}
+	if st.Staged() != nil {
+		t.Error("expected no staged config")
+	}
+	if err := st.Promote(); err != admin.ErrNotStaged {
+		t.Errorf("expected %v got %v", admin.ErrNotStaged, err)
+	}
+
+	conf := load(headers.SecurityPresetStandard, "/promoted")
+	stage(conf)
+	sc := st.Staged()
+	if sc == nil || sc.CanaryAddress != "127.0.0.1:18494" {
+		t.Fatalf("unexpected staged config %v", sc)
+	}
+	if running.conf == conf {
+		t.Error("expected the staged config not to be running")
+	}
+	if v := frameOptions(t, "http://127.0.0.1:18494/custom"); v != "DENY" {
+		t.Errorf("expected the canary to serve the staged config, got %s", v)
+	}
+	if v := frameOptions(t, "http://127.0.0.1:18492/custom"); v != "" {
+		t.Errorf("expected the main listener to serve the running config, got %s", v)
+	}
+
+	if err := st.Promote(); err != nil {
+		t.Fatal(err)
+	}
+	if running.conf != conf || st.Staged() != nil {
+		t.Error("expected the staged config to be running")
+	}
+	if v := frameOptions(t, "http://127.0.0.1:18492/custom"); v != "DENY" {
+		t.Errorf("expected the main listener to serve the promoted config, got %s", v)
+	}
+
+	if !waitFor(func() bool { return count("/promoted") > 0 }) {
+		t.Error("expected the promoted config's origin to be health checked")
+	}
+
+	// a discarded config's origin loggers and monitors never replace the running ones
+	discarded := load(headers.SecurityPresetNone, "/discarded")
+	discarded.Origins["default"].LogLevel = "debug"
+	stage(discarded)
+	if v := frameOptions(t, "http://127.0.0.1:18494/custom"); v != "" {
+		t.Errorf("expected the canary to serve the staged config, got %s", v)
+	}
+	if err := st.Discard(); err != nil {
+		t.Fatal(err)
+	}
+	if running.conf != conf || st.Staged() != nil {
+		t.Error("expected the running config to be unchanged")
+	}
+	if l := running.log.OriginLogLevels()["default"]; l != "error" {
+		t.Errorf("expected the running origin log level %s got %s", "error", l)
+	}
+	n := count("/promoted")
+	if !waitFor(func() bool { return count("/promoted") > n }) {
+		t.Error("expected the running config's origin to still be health checked")
+	}
+	if c := count("/discarded"); c != 0 {
+		t.Errorf("expected no health checks of the discarded config got %d", c)
+	}
+	if err := st.Discard(); err != admin.ErrNotStaged {
+		t.Errorf("expected %v got %v", admin.ErrNotStaged, err)
+	}
+	if lg.Get(canaryListenerName) != nil {
+		t.Error("expected the canary listener to be closed")
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"github.com/tricksterproxy/trickster/pkg/config"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics/statsd"
+	so "github.com/tricksterproxy/trickster/pkg/util/metrics/statsd/options"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// statsdPusher is the running StatsD metrics pusher, if any. It is only
+// accessed while holding cfgLock
+var statsdPusher *statsd.Pusher
+
+// applyStatsDConfig starts, restarts or stops pushing metrics to StatsD,
+// so that it matches the provided config
+func applyStatsDConfig(conf, oldConf *config.Config, log *tl.Logger) {
+
+	var o, oo *so.Options
+	if conf != nil && conf.Metrics != nil {
+		o = conf.Metrics.StatsD
+	}
+	if oldConf != nil && oldConf.Metrics != nil {
+		oo = oldConf.Metrics.StatsD
+	}
+
+	if statsdPusher != nil {
+		if o.Equal(oo) {
+			return
+		}
+		statsdPusher.Stop()
+		statsdPusher = nil
+	}
+
+	if !o.Enabled() {
+		return
+	}
+
+	p, err := statsd.New(o, prometheus.DefaultGatherer)
+	if err != nil {
+		log.Error("unable to push metrics to statsd", tl.Pairs{"detail": err.Error()})
+		return
+	}
+	p.Start(func(err error) {
+		log.WarnOnce("statsd.push", "unable to push metrics to statsd",
+			tl.Pairs{"address": o.Address, "detail": err.Error()})
+	})
+	log.Info("pushing metrics to statsd", tl.Pairs{"address": o.Address, "flavor": o.Flavor})
+	statsdPusher = p
+}
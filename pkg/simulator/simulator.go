@@ -0,0 +1,102 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package simulator serves synthetic origins from within the Trickster process, so
+// that its caching behavior can be evaluated, and configurations load tested, without
+// a real backend
+package simulator
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+
+	"github.com/tricksterproxy/mockster/pkg/routes"
+)
+
+const (
+	// PrometheusOriginName is the name of the simulated Prometheus origin
+	PrometheusOriginName = "sim-prometheus"
+	// ReverseProxyCacheOriginName is the name of the simulated HTTP origin, which serves
+	// byte range requests
+	ReverseProxyCacheOriginName = "sim-rpc"
+
+	// PrometheusPath is the path prefix of the simulated Prometheus API
+	PrometheusPath = "/prometheus"
+	// ByteRangePath is the path prefix of the simulated HTTP objects
+	ByteRangePath = "/byterange"
+
+	listenAddress = "127.0.0.1"
+)
+
+// BaseURL returns the base URL of the simulator listening on the port
+func BaseURL(port int) string {
+	return "http://" + net.JoinHostPort(listenAddress, strconv.Itoa(port))
+}
+
+// Origins returns the options of the simulated origins, served by the simulator
+// listening on the port
+func Origins(port int) map[string]*oo.Options {
+	p := oo.NewOptions()
+	p.OriginType = "prometheus"
+	p.OriginURL = BaseURL(port) + PrometheusPath
+
+	r := oo.NewOptions()
+	r.OriginType = "reverseproxycache"
+	r.OriginURL = BaseURL(port) + ByteRangePath
+
+	return map[string]*oo.Options{PrometheusOriginName: p, ReverseProxyCacheOriginName: r}
+}
+
+var (
+	server     *http.Server
+	serverPort int
+	serverLock sync.Mutex
+)
+
+// Start serves the simulated origins on the loopback interface at the port, until Stop is
+// called. If the simulator is already serving on the port, Start does nothing
+func Start(port int) error {
+	serverLock.Lock()
+	defer serverLock.Unlock()
+	if server != nil {
+		if serverPort == port {
+			return nil
+		}
+		server.Close()
+		server = nil
+	}
+	l, err := net.Listen("tcp", net.JoinHostPort(listenAddress, strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	server = &http.Server{Handler: routes.GetRouter()}
+	serverPort = port
+	go server.Serve(l)
+	return nil
+}
+
+// Stop stops serving the simulated origins
+func Stop() {
+	serverLock.Lock()
+	defer serverLock.Unlock()
+	if server != nil {
+		server.Close()
+		server = nil
+	}
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simulator
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func freePort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestOrigins(t *testing.T) {
+	o := Origins(8482)
+	if len(o) != 2 {
+		t.Errorf("expected 2 origins got %d", len(o))
+	}
+	if p := o[PrometheusOriginName]; p == nil || p.OriginType != "prometheus" ||
+		p.OriginURL != "http://127.0.0.1:8482/prometheus" {
+		t.Errorf("unexpected prometheus origin %v", p)
+	}
+	if r := o[ReverseProxyCacheOriginName]; r == nil || r.OriginType != "reverseproxycache" ||
+		r.OriginURL != "http://127.0.0.1:8482/byterange" {
+		t.Errorf("unexpected reverseproxycache origin %v", r)
+	}
+}
+
+func TestStart(t *testing.T) {
+	port := freePort(t)
+	if err := Start(port); err != nil {
+		t.Fatal(err)
+	}
+	defer Stop()
+	// starting again on the same port is a no-op
+	if err := Start(port); err != nil {
+		t.Error(err)
+	}
+
+	u := BaseURL(port) + PrometheusPath + "/api/v1/query?query=up&time=" +
+		strconv.FormatInt(time.Now().Unix(), 10)
+	resp, err := http.Get(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	Stop()
+	if _, err = http.Get(u); err == nil {
+		t.Error("expected connection error after stop")
+	}
+}
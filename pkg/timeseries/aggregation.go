@@ -0,0 +1,55 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package timeseries
+
+import "strconv"
+
+// Aggregation enumerates the functions for combining the values of a series that fall
+// within the same bucket when a Timeseries is aggregated to a coarser step
+type Aggregation int
+
+const (
+	// AggregationAvg combines the values in a bucket into their average
+	AggregationAvg = Aggregation(iota)
+	// AggregationMin combines the values in a bucket into their minimum
+	AggregationMin
+	// AggregationMax combines the values in a bucket into their maximum
+	AggregationMax
+)
+
+// AggregationNames is a map of Aggregations keyed by string name
+var AggregationNames = map[string]Aggregation{
+	"avg": AggregationAvg,
+	"min": AggregationMin,
+	"max": AggregationMax,
+}
+
+// AggregationValues is a map of Aggregations valued by string name
+var AggregationValues = make(map[Aggregation]string)
+
+func init() {
+	for k, v := range AggregationNames {
+		AggregationValues[v] = k
+	}
+}
+
+func (a Aggregation) String() string {
+	if v, ok := AggregationValues[a]; ok {
+		return v
+	}
+	return strconv.Itoa(int(a))
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options provides options for query-specific backfill tolerance rules
+package options
+
+import (
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Options is a backfill tolerance rule, which applies a backfill tolerance to the
+// timeseries queries whose statement matches Pattern
+type Options struct {
+	// Pattern is the regular expression that a query statement must match for the rule to apply
+	Pattern string `toml:"pattern"`
+	// BackfillToleranceSecs is the backfill tolerance, in seconds, for matching queries
+	BackfillToleranceSecs int64 `toml:"backfill_tolerance_secs"`
+
+	// Name is the name of the rule
+	Name string `toml:"-"`
+	// BackfillTolerance is the parsed value of BackfillToleranceSecs
+	BackfillTolerance time.Duration `toml:"-"`
+
+	re *regexp.Regexp
+}
+
+// NewOptions returns a new Options reference with Default Values set
+func NewOptions() *Options {
+	return &Options{}
+}
+
+// Clone returns an exact copy of the subject *Options
+func (o *Options) Clone() *Options {
+	return &Options{
+		Pattern:               o.Pattern,
+		BackfillToleranceSecs: o.BackfillToleranceSecs,
+		Name:                  o.Name,
+		BackfillTolerance:     o.BackfillTolerance,
+		re:                    o.re,
+	}
+}
+
+// Compile compiles the rule's Pattern, and must be called before the rule is matched
+func (o *Options) Compile() error {
+	re, err := regexp.Compile(o.Pattern)
+	if err != nil {
+		return err
+	}
+	o.re = re
+	return nil
+}
+
+// Matches returns true if the provided query statement matches the rule
+func (o *Options) Matches(statement string) bool {
+	return o.re != nil && o.re.MatchString(statement)
+}
+
+// Rules is a list of backfill tolerance rules, sorted by Name
+type Rules []*Options
+
+// NewRules returns the sorted list of the provided rules
+func NewRules(m map[string]*Options) Rules {
+	if len(m) == 0 {
+		return nil
+	}
+	r := make(Rules, 0, len(m))
+	for _, o := range m {
+		r = append(r, o)
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].Name < r[j].Name })
+	return r
+}
+
+// Match returns the first rule that matches the provided query statement, or nil
+// if no rule matches
+func (r Rules) Match(statement string) *Options {
+	for _, o := range r {
+		if o.Matches(statement) {
+			return o
+		}
+	}
+	return nil
+}
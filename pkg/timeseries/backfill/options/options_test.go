@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"testing"
+	"time"
+)
+
+func testRules(t *testing.T) Rules {
+	r := NewRules(map[string]*Options{
+		"counters": {Name: "counters", Pattern: `rate\(`, BackfillTolerance: 5 * time.Minute},
+		"all":      {Name: "all", Pattern: `^flaky_`, BackfillTolerance: time.Minute},
+	})
+	for _, o := range r {
+		if err := o.Compile(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+func TestNewRules(t *testing.T) {
+	if NewRules(nil) != nil {
+		t.Error("expected nil rules")
+	}
+	r := testRules(t)
+	if len(r) != 2 || r[0].Name != "all" || r[1].Name != "counters" {
+		t.Errorf("expected rules sorted by name, got %v", r)
+	}
+}
+
+func TestClone(t *testing.T) {
+	o := &Options{Pattern: `rate\(`, BackfillToleranceSecs: 300, Name: "counters",
+		BackfillTolerance: 5 * time.Minute}
+	if err := o.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	o2 := o.Clone()
+	if *o2 != *o {
+		t.Errorf("clone mismatch: expected %v got %v", o, o2)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	o := &Options{Pattern: `rate(`}
+	if err := o.Compile(); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+	if o.Matches("rate(") {
+		t.Error("expected uncompiled rule not to match")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	r := testRules(t)
+	tests := []struct {
+		statement string
+		expected  string
+	}{
+		{"up", ""},
+		{"sum(rate(http_requests_total[5m]))", "counters"},
+		{"flaky_requests_total", "all"},
+		{"rate(flaky_requests_total[5m])", "counters"},
+		{"flaky_requests_total + rate(x[1m])", "all"},
+	}
+	for _, test := range tests {
+		o := r.Match(test.statement)
+		var name string
+		if o != nil {
+			name = o.Name
+		}
+		if name != test.expected {
+			t.Errorf("%s: expected %s got %s", test.statement, test.expected, name)
+		}
+	}
+	if Rules(nil).Match("up") != nil {
+		t.Error("expected no match for nil rules")
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package timeseries
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Versions of Trickster's binary Timeseries cache format, which is MessagePack,
+// preceded by a version byte. Since JSON-encoded Timeseries always begin with '{',
+// the version byte also distinguishes binary cache entries from the JSON entries
+// written by earlier versions of Trickster
+const (
+	// BinaryFormatVersion encodes each sample individually
+	BinaryFormatVersion byte = 1
+	// BinaryFormatGorilla encodes each series' samples as a gorilla-compressed stream
+	BinaryFormatGorilla byte = 2
+)
+
+// BinaryFormatNames maps the names of the binary cache format versions, as used by the
+// timeseries_cache_encoding origin setting, to their version bytes
+var BinaryFormatNames = map[string]byte{
+	"msgpack": BinaryFormatVersion,
+	"gorilla": BinaryFormatGorilla,
+}
+
+// ErrUnsupportedBinaryFormat is returned when decoding a binary Timeseries with an
+// unknown format version
+var ErrUnsupportedBinaryFormat = errors.New("unsupported binary timeseries format")
+
+// IsBinaryFormat returns true if the data is a Timeseries in the binary cache format
+func IsBinaryFormat(data []byte) bool {
+	return len(data) > 0 &&
+		(data[0] == BinaryFormatVersion || data[0] == BinaryFormatGorilla)
+}
+
+// BinaryBody returns the format version and body of a Timeseries in the binary cache
+// format
+func BinaryBody(data []byte) (byte, []byte, error) {
+	if !IsBinaryFormat(data) {
+		return 0, nil, ErrUnsupportedBinaryFormat
+	}
+	return data[0], data[1:], nil
+}
+
+// AppendExtentList appends the MessagePack encoding of the ExtentList to b
+func AppendExtentList(b []byte, el ExtentList) []byte {
+	b = msgp.AppendArrayHeader(b, uint32(len(el)))
+	for _, e := range el {
+		b = msgp.AppendInt64(b, e.Start.UnixNano())
+		b = msgp.AppendInt64(b, e.End.UnixNano())
+	}
+	return b
+}
+
+// ReadExtentListBytes reads an ExtentList encoded by AppendExtentList from b, and
+// returns the remaining bytes
+func ReadExtentListBytes(b []byte) (ExtentList, []byte, error) {
+	sz, b, err := msgp.ReadArrayHeaderBytes(b)
+	if err != nil {
+		return nil, b, err
+	}
+	// each extent occupies at least two bytes, so larger headers are corrupt
+	if int(sz)*2 > len(b) {
+		return nil, b, msgp.ErrShortBytes
+	}
+	var el ExtentList
+	if sz > 0 {
+		el = make(ExtentList, sz)
+	}
+	for i := range el {
+		var start, end int64
+		if start, b, err = msgp.ReadInt64Bytes(b); err != nil {
+			return nil, b, err
+		}
+		if end, b, err = msgp.ReadInt64Bytes(b); err != nil {
+			return nil, b, err
+		}
+		el[i] = Extent{Start: time.Unix(0, start), End: time.Unix(0, end)}
+	}
+	return el, b, nil
+}
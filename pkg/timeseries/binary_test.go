@@ -0,0 +1,93 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package timeseries
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsBinaryFormat(t *testing.T) {
+	if IsBinaryFormat(nil) {
+		t.Error("expected false for empty data")
+	}
+	if IsBinaryFormat([]byte(`{"status":"success"}`)) {
+		t.Error("expected false for json data")
+	}
+	if !IsBinaryFormat([]byte{BinaryFormatVersion, 0x90}) {
+		t.Error("expected true for binary data")
+	}
+	if !IsBinaryFormat([]byte{BinaryFormatGorilla, 0x90}) {
+		t.Error("expected true for gorilla data")
+	}
+}
+
+func TestBinaryBody(t *testing.T) {
+	v, b, err := BinaryBody([]byte{BinaryFormatGorilla, 0x90})
+	if err != nil {
+		t.Error(err)
+	}
+	if v != BinaryFormatGorilla {
+		t.Errorf("expected version %d got %d", BinaryFormatGorilla, v)
+	}
+	if len(b) != 1 || b[0] != 0x90 {
+		t.Errorf("unexpected body %v", b)
+	}
+	_, _, err = BinaryBody([]byte("{}"))
+	if err != ErrUnsupportedBinaryFormat {
+		t.Errorf("expected %v got %v", ErrUnsupportedBinaryFormat, err)
+	}
+}
+
+func TestExtentListBinary(t *testing.T) {
+
+	el := ExtentList{
+		Extent{Start: time.Unix(100, 0), End: time.Unix(200, 0)},
+		Extent{Start: time.Unix(300, 500), End: time.Unix(400, 0)},
+	}
+	b := AppendExtentList(nil, el)
+	b = append(b, 0xc0) // trailing data must be left in place
+
+	el2, rest, err := ReadExtentListBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if el.String() != el2.String() || !el2[1].Start.Equal(el[1].Start) {
+		t.Errorf("expected %s got %s", el, el2)
+	}
+	if len(rest) != 1 {
+		t.Errorf("expected 1 remaining byte got %d", len(rest))
+	}
+
+	b = AppendExtentList(nil, nil)
+	el2, _, err = ReadExtentListBytes(b)
+	if err != nil {
+		t.Error(err)
+	}
+	if el2 != nil {
+		t.Errorf("expected nil extent list got %s", el2)
+	}
+
+	_, _, err = ReadExtentListBytes(b[:0])
+	if err == nil {
+		t.Error("expected error for empty data")
+	}
+	_, _, err = ReadExtentListBytes(AppendExtentList(nil, el)[:5])
+	if err == nil {
+		t.Error("expected error for truncated data")
+	}
+}
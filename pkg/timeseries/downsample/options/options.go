@@ -0,0 +1,98 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options provides options for downsampling cached timeseries
+package options
+
+import (
+	"sort"
+	"time"
+)
+
+// Options is a downsample tier, which stores cached timeseries data that is older than
+// MinAge at a coarser Step than it was requested at
+type Options struct {
+	// StepSecs is the step, in seconds, at which the tier stores and serves timeseries data
+	StepSecs int `toml:"step_secs"`
+	// MinAgeSecs is the age, in seconds, beyond which timeseries data belongs to the tier
+	MinAgeSecs int `toml:"min_age_secs"`
+
+	// Name is the name of the tier
+	Name string `toml:"-"`
+	// Step is the parsed value of StepSecs
+	Step time.Duration `toml:"-"`
+	// MinAge is the parsed value of MinAgeSecs
+	MinAge time.Duration `toml:"-"`
+}
+
+// NewOptions returns a new Options reference with Default Values set
+func NewOptions() *Options {
+	return &Options{}
+}
+
+// Clone returns an exact copy of the subject *Options
+func (o *Options) Clone() *Options {
+	return &Options{
+		StepSecs:   o.StepSecs,
+		MinAgeSecs: o.MinAgeSecs,
+		Name:       o.Name,
+		Step:       o.Step,
+		MinAge:     o.MinAge,
+	}
+}
+
+// Tiers is a list of downsample tiers, sorted by MinAge
+type Tiers []*Options
+
+// NewTiers returns the sorted list of the provided tiers
+func NewTiers(m map[string]*Options) Tiers {
+	if len(m) == 0 {
+		return nil
+	}
+	t := make(Tiers, 0, len(m))
+	for _, o := range m {
+		t = append(t, o)
+	}
+	sort.Slice(t, func(i, j int) bool {
+		if t[i].MinAge == t[j].MinAge {
+			return t[i].Name < t[j].Name
+		}
+		return t[i].MinAge < t[j].MinAge
+	})
+	return t
+}
+
+// ForAge returns the tier to which timeseries data of the provided age belongs, or
+// nil if the data is too recent to belong to any tier
+func (t Tiers) ForAge(age time.Duration) *Options {
+	for i := len(t) - 1; i >= 0; i-- {
+		if age > t[i].MinAge {
+			return t[i]
+		}
+	}
+	return nil
+}
+
+// CoarserThan returns the tiers that store timeseries data at a coarser step than the
+// provided step
+func (t Tiers) CoarserThan(step time.Duration) Tiers {
+	for i := range t {
+		if t[i].Step > step {
+			return t[i:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"testing"
+	"time"
+)
+
+func testTiers() Tiers {
+	return NewTiers(map[string]*Options{
+		"1h": {Name: "1h", Step: time.Hour, MinAge: 7 * 24 * time.Hour},
+		"5m": {Name: "5m", Step: 5 * time.Minute, MinAge: 24 * time.Hour},
+	})
+}
+
+func TestNewTiers(t *testing.T) {
+	if NewTiers(nil) != nil {
+		t.Error("expected nil tiers")
+	}
+	tiers := testTiers()
+	if len(tiers) != 2 || tiers[0].Name != "5m" || tiers[1].Name != "1h" {
+		t.Errorf("expected tiers sorted by min age, got %v", tiers)
+	}
+}
+
+func TestClone(t *testing.T) {
+	o := &Options{StepSecs: 300, MinAgeSecs: 86400, Name: "5m",
+		Step: 5 * time.Minute, MinAge: 24 * time.Hour}
+	o2 := o.Clone()
+	if *o2 != *o {
+		t.Errorf("clone mismatch: expected %v got %v", o, o2)
+	}
+}
+
+func TestForAge(t *testing.T) {
+	tiers := testTiers()
+	tests := []struct {
+		age      time.Duration
+		expected string
+	}{
+		{time.Hour, ""},
+		{24 * time.Hour, ""},
+		{48 * time.Hour, "5m"},
+		{30 * 24 * time.Hour, "1h"},
+	}
+	for _, test := range tests {
+		o := tiers.ForAge(test.age)
+		var name string
+		if o != nil {
+			name = o.Name
+		}
+		if name != test.expected {
+			t.Errorf("age %s: expected tier %q got %q", test.age, test.expected, name)
+		}
+	}
+}
+
+func TestCoarserThan(t *testing.T) {
+	tiers := testTiers()
+	if l := len(tiers.CoarserThan(time.Minute)); l != 2 {
+		t.Errorf("expected 2 tiers got %d", l)
+	}
+	if c := tiers.CoarserThan(5 * time.Minute); len(c) != 1 || c[0].Name != "1h" {
+		t.Errorf("expected the 1h tier got %v", c)
+	}
+	if c := tiers.CoarserThan(time.Hour); c != nil {
+		t.Errorf("expected no tiers got %v", c)
+	}
+}
@@ -138,6 +138,86 @@ func (el ExtentList) Compress(step time.Duration) ExtentList {
 	return compressed
 }
 
+// Compact sorts an ExtentList and merges its overlapping and time-adjacent Extents regardless
+// of when they were last used, keeping the most recent LastUsed time of the merged Extents, so
+// that a fragmented ExtentList is represented in as few Extents as possible
+func (el ExtentList) Compact(step time.Duration) ExtentList {
+	if len(el) == 0 {
+		return ExtentList{}
+	}
+	exc := el.Clone()
+	sort.Sort(exc)
+	compacted := make(ExtentList, 0, len(exc))
+	e := exc[0]
+	for _, e2 := range exc[1:] {
+		if e2.Start.After(e.End.Add(step)) {
+			compacted = append(compacted, e)
+			e = e2
+			continue
+		}
+		if e2.End.After(e.End) {
+			e.End = e2.End
+		}
+		if e2.LastUsed.After(e.LastUsed) {
+			e.LastUsed = e2.LastUsed
+		}
+	}
+	return append(compacted, e)
+}
+
+// Split divides the step-aligned Extents in the ExtentList into about n sub-ranges of equal
+// duration, but no shorter than minSize, so that they can be fetched concurrently. The
+// ExtentList is returned unchanged when it is too small to be split
+func (el ExtentList) Split(step, minSize time.Duration, n int) ExtentList {
+	if n < 2 || step <= 0 || len(el) == 0 {
+		return el
+	}
+	var total time.Duration
+	for _, e := range el {
+		total += e.End.Sub(e.Start) + step
+	}
+	size := total / time.Duration(n)
+	if size < minSize {
+		size = minSize
+	}
+	// round the sub-range size up to a whole number of steps
+	if r := size % step; r != 0 {
+		size += step - r
+	}
+	if size >= total {
+		return el
+	}
+	out := make(ExtentList, 0, n+len(el))
+	for _, e := range el {
+		for s := e.Start; !s.After(e.End); s = s.Add(size) {
+			end := s.Add(size - step)
+			if end.After(e.End) {
+				end = e.End
+			}
+			out = append(out, Extent{Start: s, End: end})
+		}
+	}
+	return out
+}
+
+// Coarsen returns the ExtentList reduced to the timestamps that fall on the provided step,
+// which must be a multiple of the step of the Extents, dropping those that contain none
+func (el ExtentList) Coarsen(step time.Duration) ExtentList {
+	out := make(ExtentList, 0, len(el))
+	for _, e := range el {
+		start := e.Start.Truncate(step)
+		if start.Before(e.Start) {
+			start = start.Add(step)
+		}
+		end := e.End.Truncate(step)
+		if end.Before(start) {
+			continue
+		}
+		out = append(out, Extent{Start: start, End: end, LastUsed: e.LastUsed})
+	}
+	return out.Compress(step)
+}
+
 // Len returns the length of a slice of type ExtentList
 func (el ExtentList) Len() int {
 	return len(el)
@@ -604,6 +604,129 @@ func TestCompress(t *testing.T) {
 	}
 }
 
+func TestSplit(t *testing.T) {
+
+	tests := []struct {
+		el       ExtentList
+		minSize  time.Duration
+		n        int
+		expected ExtentList
+	}{
+		{ // 0 - splitting disabled
+			ExtentList{Extent{Start: time.Unix(0, 0), End: time.Unix(570, 0)}},
+			0, 1,
+			ExtentList{Extent{Start: time.Unix(0, 0), End: time.Unix(570, 0)}},
+		},
+		{ // 1 - even split
+			ExtentList{Extent{Start: time.Unix(0, 0), End: time.Unix(570, 0)}},
+			0, 4,
+			ExtentList{
+				Extent{Start: time.Unix(0, 0), End: time.Unix(120, 0)},
+				Extent{Start: time.Unix(150, 0), End: time.Unix(270, 0)},
+				Extent{Start: time.Unix(300, 0), End: time.Unix(420, 0)},
+				Extent{Start: time.Unix(450, 0), End: time.Unix(570, 0)},
+			},
+		},
+		{ // 2 - sub-range size is rounded up to the step
+			ExtentList{Extent{Start: time.Unix(0, 0), End: time.Unix(270, 0)}},
+			0, 3,
+			ExtentList{
+				Extent{Start: time.Unix(0, 0), End: time.Unix(90, 0)},
+				Extent{Start: time.Unix(120, 0), End: time.Unix(210, 0)},
+				Extent{Start: time.Unix(240, 0), End: time.Unix(270, 0)},
+			},
+		},
+		{ // 3 - minimum sub-range size limits the split
+			ExtentList{Extent{Start: time.Unix(0, 0), End: time.Unix(570, 0)}},
+			300 * time.Second, 4,
+			ExtentList{
+				Extent{Start: time.Unix(0, 0), End: time.Unix(270, 0)},
+				Extent{Start: time.Unix(300, 0), End: time.Unix(570, 0)},
+			},
+		},
+		{ // 4 - too small to split
+			ExtentList{Extent{Start: time.Unix(0, 0), End: time.Unix(270, 0)}},
+			300 * time.Second, 4,
+			ExtentList{Extent{Start: time.Unix(0, 0), End: time.Unix(270, 0)}},
+		},
+		{ // 5 - multiple extents
+			ExtentList{
+				Extent{Start: time.Unix(0, 0), End: time.Unix(60, 0)},
+				Extent{Start: time.Unix(300, 0), End: time.Unix(510, 0)},
+			},
+			0, 2,
+			ExtentList{
+				Extent{Start: time.Unix(0, 0), End: time.Unix(60, 0)},
+				Extent{Start: time.Unix(300, 0), End: time.Unix(450, 0)},
+				Extent{Start: time.Unix(480, 0), End: time.Unix(510, 0)},
+			},
+		},
+		{ // 6 - empty list
+			ExtentList{},
+			0, 4,
+			ExtentList{},
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			result := test.el.Split(30*time.Second, test.minSize, test.n)
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("mismatch in Split: expected=%s got=%s", test.expected, result)
+			}
+		})
+	}
+}
+
+func TestCompact(t *testing.T) {
+
+	if el := (ExtentList{}).Compact(time.Second * 30); len(el) != 0 {
+		t.Errorf("expected empty list got %s", el)
+	}
+
+	el := ExtentList{
+		Extent{Start: time.Unix(180, 0), End: time.Unix(210, 0), LastUsed: time.Unix(3, 0)},
+		Extent{Start: time.Unix(0, 0), End: time.Unix(0, 0), LastUsed: time.Unix(1, 0)},
+		Extent{Start: time.Unix(60, 0), End: time.Unix(120, 0), LastUsed: time.Unix(2, 0)},
+		Extent{Start: time.Unix(90, 0), End: time.Unix(150, 0), LastUsed: time.Unix(1, 0)},
+		Extent{Start: time.Unix(300, 0), End: time.Unix(330, 0), LastUsed: time.Unix(1, 0)},
+	}
+
+	expected := ExtentList{
+		Extent{Start: time.Unix(0, 0), End: time.Unix(0, 0), LastUsed: time.Unix(1, 0)},
+		Extent{Start: time.Unix(60, 0), End: time.Unix(210, 0), LastUsed: time.Unix(3, 0)},
+		Extent{Start: time.Unix(300, 0), End: time.Unix(330, 0), LastUsed: time.Unix(1, 0)},
+	}
+
+	result := el.Compact(time.Second * 30)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("mismatch in Compact: expected=%s got=%s", expected, result)
+	}
+
+}
+
+func TestCoarsen(t *testing.T) {
+
+	el := ExtentList{
+		Extent{Start: time.Unix(0, 0), End: time.Unix(270, 0)},
+		Extent{Start: time.Unix(330, 0), End: time.Unix(350, 0)},
+		Extent{Start: time.Unix(420, 0), End: time.Unix(600, 0)},
+		Extent{Start: time.Unix(810, 0), End: time.Unix(960, 0)},
+	}
+
+	expected := ExtentList{
+		Extent{Start: time.Unix(0, 0), End: time.Unix(240, 0)},
+		Extent{Start: time.Unix(480, 0), End: time.Unix(600, 0)},
+		Extent{Start: time.Unix(840, 0), End: time.Unix(960, 0)},
+	}
+
+	result := el.Coarsen(2 * time.Minute)
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("mismatch in Coarsen: expected=%s got=%s", expected, result)
+	}
+
+}
+
 func TestSize(t *testing.T) {
 
 	el := ExtentList{
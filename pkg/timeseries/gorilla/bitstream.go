@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gorilla
+
+import "errors"
+
+// ErrShortBuffer is returned when decoding a stream that ends before all of its
+// values have been read
+var ErrShortBuffer = errors.New("gorilla stream is truncated")
+
+// bitWriter appends individual bits to a byte slice, most significant bit first
+type bitWriter struct {
+	b     []byte
+	count uint8 // the number of unused bits in the last byte of b
+}
+
+func (w *bitWriter) writeBit(bit bool) {
+	if w.count == 0 {
+		w.b = append(w.b, 0)
+		w.count = 8
+	}
+	w.count--
+	if bit {
+		w.b[len(w.b)-1] |= 1 << w.count
+	}
+}
+
+// writeBits writes the nbits least significant bits of u
+func (w *bitWriter) writeBits(u uint64, nbits int) {
+	for nbits > 0 {
+		if w.count == 0 {
+			w.b = append(w.b, 0)
+			w.count = 8
+		}
+		// fill the unused bits of the last byte with as many bits as fit
+		take := int(w.count)
+		if take > nbits {
+			take = nbits
+		}
+		nbits -= take
+		w.count -= uint8(take)
+		w.b[len(w.b)-1] |= byte((u>>uint(nbits))&(1<<uint(take)-1)) << w.count
+	}
+}
+
+// bitReader reads individual bits from a byte slice written by a bitWriter
+type bitReader struct {
+	b   []byte
+	pos int // the index of the next bit to read
+}
+
+func (r *bitReader) readBit() (bool, error) {
+	if r.pos >= len(r.b)*8 {
+		return false, ErrShortBuffer
+	}
+	bit := r.b[r.pos/8]&(0x80>>uint(r.pos%8)) != 0
+	r.pos++
+	return bit, nil
+}
+
+// readBits reads nbits bits into the least significant bits of the returned value
+func (r *bitReader) readBits(nbits int) (uint64, error) {
+	if r.pos+nbits > len(r.b)*8 {
+		return 0, ErrShortBuffer
+	}
+	var u uint64
+	for nbits > 0 {
+		// read as many bits as remain in the current byte
+		avail := 8 - r.pos%8
+		take := avail
+		if take > nbits {
+			take = nbits
+		}
+		chunk := (r.b[r.pos/8] >> uint(avail-take)) & (1<<uint(take) - 1)
+		u = u<<uint(take) | uint64(chunk)
+		r.pos += take
+		nbits -= take
+	}
+	return u, nil
+}
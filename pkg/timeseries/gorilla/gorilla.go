@@ -0,0 +1,197 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package gorilla compresses a series of timestamped float64 samples using the
+// delta-of-delta timestamp and XOR value encodings described in the Facebook
+// Gorilla paper (http://www.vldb.org/pvldb/vol8/p1816-teller.pdf). The encoding
+// is lossless, including for NaN and infinite values
+package gorilla
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+)
+
+// timestamp delta-of-delta ranges, each encoded with a distinct prefix as a
+// two's complement value of the corresponding bit width
+var dodBuckets = []struct {
+	prefix, prefixBits, valueBits int
+}{
+	{0x02, 2, 7},  // '10'
+	{0x06, 3, 9},  // '110'
+	{0x0e, 4, 12}, // '1110'
+}
+
+// Encode returns the compressed encoding of the samples. timestamps and values must
+// have the same length
+func Encode(timestamps []int64, values []float64) []byte {
+	w := &bitWriter{b: make([]byte, binary.MaxVarintLen64, 32+len(timestamps)*2)}
+	w.b = w.b[:binary.PutUvarint(w.b, uint64(len(timestamps)))]
+	if len(timestamps) == 0 {
+		return w.b
+	}
+
+	w.writeBits(uint64(timestamps[0]), 64)
+	w.writeBits(math.Float64bits(values[0]), 64)
+
+	var delta int64
+	prevLeading, prevTrailing := -1, 0
+	for i := 1; i < len(timestamps); i++ {
+		d := timestamps[i] - timestamps[i-1]
+		writeDoD(w, d-delta)
+		delta = d
+
+		x := math.Float64bits(values[i]) ^ math.Float64bits(values[i-1])
+		if x == 0 {
+			w.writeBit(false)
+			continue
+		}
+		w.writeBit(true)
+		leading, trailing := bits.LeadingZeros64(x), bits.TrailingZeros64(x)
+		if leading > 31 {
+			leading = 31 // the leading zero count is written in 5 bits
+		}
+		if prevLeading >= 0 && leading >= prevLeading && trailing >= prevTrailing {
+			// the meaningful bits fit within the previous value's window
+			w.writeBit(false)
+			w.writeBits(x>>uint(prevTrailing), 64-prevLeading-prevTrailing)
+			continue
+		}
+		w.writeBit(true)
+		meaningful := 64 - leading - trailing
+		w.writeBits(uint64(leading), 5)
+		w.writeBits(uint64(meaningful-1), 6) // 1-64 is written as 0-63
+		w.writeBits(x>>uint(trailing), meaningful)
+		prevLeading, prevTrailing = leading, trailing
+	}
+	return w.b
+}
+
+func writeDoD(w *bitWriter, dod int64) {
+	if dod == 0 {
+		w.writeBit(false)
+		return
+	}
+	for _, bk := range dodBuckets {
+		if min, max := -int64(1)<<uint(bk.valueBits-1), int64(1)<<uint(bk.valueBits-1)-1; dod >= min && dod <= max {
+			w.writeBits(uint64(bk.prefix), bk.prefixBits)
+			w.writeBits(uint64(dod), bk.valueBits)
+			return
+		}
+	}
+	w.writeBits(0x0f, 4) // '1111'
+	w.writeBits(uint64(dod), 64)
+}
+
+// Decode returns the samples from an encoding produced by Encode, and the number
+// of bytes of data that were read
+func Decode(data []byte) ([]int64, []float64, int, error) {
+	n, hl := binary.Uvarint(data)
+	if hl <= 0 {
+		return nil, nil, 0, ErrShortBuffer
+	}
+	// every sample after the first occupies at least two bits
+	if n > uint64(len(data)-hl)*4+1 {
+		return nil, nil, 0, ErrShortBuffer
+	}
+	timestamps := make([]int64, n)
+	values := make([]float64, n)
+	if n == 0 {
+		return timestamps, values, hl, nil
+	}
+
+	r := &bitReader{b: data[hl:]}
+	u, err := r.readBits(64)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	timestamps[0] = int64(u)
+	if u, err = r.readBits(64); err != nil {
+		return nil, nil, 0, err
+	}
+	values[0] = math.Float64frombits(u)
+
+	var delta int64
+	var leading, trailing int
+	for i := 1; i < len(timestamps); i++ {
+		dod, err := readDoD(r)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		delta += dod
+		timestamps[i] = timestamps[i-1] + delta
+
+		changed, err := r.readBit()
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if !changed {
+			values[i] = values[i-1]
+			continue
+		}
+		newWindow, err := r.readBit()
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if newWindow {
+			if u, err = r.readBits(5); err != nil {
+				return nil, nil, 0, err
+			}
+			leading = int(u)
+			if u, err = r.readBits(6); err != nil {
+				return nil, nil, 0, err
+			}
+			trailing = 64 - leading - (int(u) + 1)
+			if trailing < 0 {
+				return nil, nil, 0, ErrShortBuffer
+			}
+		}
+		if u, err = r.readBits(64 - leading - trailing); err != nil {
+			return nil, nil, 0, err
+		}
+		values[i] = math.Float64frombits(math.Float64bits(values[i-1]) ^ u<<uint(trailing))
+	}
+	return timestamps, values, hl + (r.pos+7)/8, nil
+}
+
+func readDoD(r *bitReader) (int64, error) {
+	var prefix int
+	for prefix < 4 {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if !bit {
+			break
+		}
+		prefix++
+	}
+	if prefix == 0 {
+		return 0, nil
+	}
+	valueBits := 64
+	if prefix <= len(dodBuckets) {
+		valueBits = dodBuckets[prefix-1].valueBits
+	}
+	u, err := r.readBits(valueBits)
+	if err != nil {
+		return 0, err
+	}
+	// sign-extend the two's complement value
+	shift := uint(64 - valueBits)
+	return int64(u<<shift) >> shift, nil
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gorilla
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+
+	tests := []struct {
+		name       string
+		timestamps []int64
+		values     []float64
+	}{
+		{"empty", []int64{}, []float64{}},
+		{"single", []int64{1590000000000}, []float64{42}},
+		{
+			"regular",
+			[]int64{1590000000000, 1590000015000, 1590000030000, 1590000045000, 1590000060000},
+			[]float64{1.5, 1.5, 2.25, 2.25, 1024.125},
+		},
+		{
+			"irregular",
+			[]int64{-5, 0, 60, 61, 400, 4000, 1 << 40, 1<<40 + 1, math.MaxInt64},
+			[]float64{0, -0, 1e300, -1e-300, 3, 3, 7, 0.1, 0.2},
+		},
+		{
+			"special",
+			[]int64{1000, 2000, 3000, 4000, 5000},
+			[]float64{math.NaN(), math.Inf(1), math.Inf(-1), math.Float64frombits(0x7ff0000000000002), 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := Encode(test.timestamps, test.values)
+			b = append(b, 0xff) // trailing data must not be consumed
+			ts, vs, n, err := Decode(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != len(b)-1 {
+				t.Errorf("expected %d bytes read got %d", len(b)-1, n)
+			}
+			if len(ts) != len(test.timestamps) || len(vs) != len(test.values) {
+				t.Fatalf("expected %d samples got %d", len(test.timestamps), len(ts))
+			}
+			for i := range ts {
+				if ts[i] != test.timestamps[i] {
+					t.Errorf("sample %d: expected timestamp %d got %d", i, test.timestamps[i], ts[i])
+				}
+				if math.Float64bits(vs[i]) != math.Float64bits(test.values[i]) {
+					t.Errorf("sample %d: expected value %v got %v", i, test.values[i], vs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	b := Encode([]int64{1000, 2000, 3500, 3600}, []float64{1, 2, 2, 1e10})
+	for i := 0; i < len(b); i++ {
+		if _, _, _, err := Decode(b[:i]); err != ErrShortBuffer {
+			t.Errorf("expected %v for data truncated to %d bytes, got %v", ErrShortBuffer, i, err)
+		}
+	}
+}
+
+func TestCompressionRatio(t *testing.T) {
+	const n = 1000
+	timestamps := make([]int64, n)
+	values := make([]float64, n)
+	for i := range timestamps {
+		timestamps[i] = 1590000000000 + int64(i)*15000
+		values[i] = float64(i % 10)
+	}
+	// a regular series compresses to a small fraction of its 16 bytes per sample
+	if b := Encode(timestamps, values); len(b) > n*4 {
+		t.Errorf("expected at most %d bytes got %d", n*4, len(b))
+	}
+}
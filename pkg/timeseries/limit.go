@@ -0,0 +1,60 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package timeseries
+
+import "strconv"
+
+// LimitAction enumerates the methodologies for handling a Timeseries result that exceeds
+// the series or sample limits of its origin
+type LimitAction int
+
+const (
+	// LimitActionTruncate returns the portion of the result that is within the limits,
+	// along with a warning that the result is partial
+	LimitActionTruncate = LimitAction(iota)
+	// LimitActionReject responds with an error instead of the result
+	LimitActionReject
+)
+
+// LimitActionNames is a map of LimitActions keyed by string name
+var LimitActionNames = map[string]LimitAction{
+	"truncate": LimitActionTruncate,
+	"reject":   LimitActionReject,
+}
+
+// TimeRangeLimitActionNames is a map of the LimitActions for queries whose time range exceeds
+// the limits of its origin, keyed by string name. Clamping truncates the queried time range
+var TimeRangeLimitActionNames = map[string]LimitAction{
+	"clamp":  LimitActionTruncate,
+	"reject": LimitActionReject,
+}
+
+// LimitActionValues is a map of LimitActions valued by string name
+var LimitActionValues = make(map[LimitAction]string)
+
+func init() {
+	for k, v := range LimitActionNames {
+		LimitActionValues[v] = k
+	}
+}
+
+func (a LimitAction) String() string {
+	if v, ok := LimitActionValues[a]; ok {
+		return v
+	}
+	return strconv.Itoa(int(a))
+}
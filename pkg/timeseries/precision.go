@@ -0,0 +1,41 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package timeseries
+
+import (
+	"math"
+	"strconv"
+)
+
+// MaxSignificantDigits is the most significant digits of a float64 that can be retained,
+// beyond which no precision is reduced
+const MaxSignificantDigits = 17
+
+// RoundSignificant returns the value rounded to the provided number of significant digits.
+// Values that aren't finite, and digits outside of 1 to MaxSignificantDigits, return the
+// value unchanged. Rounding a value more than once has no further effect
+func RoundSignificant(v float64, digits int) float64 {
+	if digits < 1 || digits >= MaxSignificantDigits || v == 0 ||
+		math.IsNaN(v) || math.IsInf(v, 0) {
+		return v
+	}
+	r, err := strconv.ParseFloat(strconv.FormatFloat(v, 'g', digits, 64), 64)
+	if err != nil {
+		return v
+	}
+	return r
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package timeseries
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRoundSignificant(t *testing.T) {
+
+	tests := []struct {
+		v        float64
+		digits   int
+		expected float64
+	}{
+		{0.123456789, 3, 0.123},
+		{123456.789, 4, 123500},
+		{-98.7654, 2, -99},
+		{1.5, 0, 1.5},
+		{1.23456789, MaxSignificantDigits, 1.23456789},
+		{0, 3, 0},
+	}
+
+	for _, test := range tests {
+		if v := RoundSignificant(test.v, test.digits); v != test.expected {
+			t.Errorf("expected %v got %v", test.expected, v)
+		}
+		// rounding again has no further effect
+		if v := RoundSignificant(RoundSignificant(test.v, test.digits), test.digits); v != test.expected {
+			t.Errorf("expected %v got %v", test.expected, v)
+		}
+	}
+
+	if v := RoundSignificant(math.Inf(1), 3); !math.IsInf(v, 1) {
+		t.Errorf("expected %v got %v", math.Inf(1), v)
+	}
+	if v := RoundSignificant(math.NaN(), 3); !math.IsNaN(v) {
+		t.Errorf("expected %v got %v", math.NaN(), v)
+	}
+}
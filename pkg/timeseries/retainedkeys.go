@@ -0,0 +1,73 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package timeseries
+
+import (
+	"sync"
+	"time"
+)
+
+// RetainedKeys records the cache keys of timeseries objects and when each one expires,
+// so that a background janitor can revisit the objects without listing the cache
+type RetainedKeys struct {
+	mtx  sync.Mutex
+	keys map[string]time.Time
+}
+
+// NewRetainedKeys returns a new, empty RetainedKeys
+func NewRetainedKeys() *RetainedKeys {
+	return &RetainedKeys{keys: make(map[string]time.Time)}
+}
+
+// Add records that the object at key was written, and expires at the provided time
+func (rk *RetainedKeys) Add(key string, expires time.Time) {
+	if rk == nil {
+		return
+	}
+	rk.mtx.Lock()
+	rk.keys[key] = expires
+	rk.mtx.Unlock()
+}
+
+// Remove stops recording the object at key
+func (rk *RetainedKeys) Remove(key string) {
+	if rk == nil {
+		return
+	}
+	rk.mtx.Lock()
+	delete(rk.keys, key)
+	rk.mtx.Unlock()
+}
+
+// Keys returns the keys of the unexpired objects and their expiration times.
+// Expired keys are pruned
+func (rk *RetainedKeys) Keys(now time.Time) map[string]time.Time {
+	if rk == nil {
+		return nil
+	}
+	rk.mtx.Lock()
+	defer rk.mtx.Unlock()
+	out := make(map[string]time.Time, len(rk.keys))
+	for k, t := range rk.keys {
+		if !t.After(now) {
+			delete(rk.keys, k)
+			continue
+		}
+		out[k] = t
+	}
+	return out
+}
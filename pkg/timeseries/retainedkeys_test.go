@@ -0,0 +1,50 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package timeseries
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetainedKeys(t *testing.T) {
+
+	now := time.Unix(1000, 0)
+	rk := NewRetainedKeys()
+	rk.Add("a", now.Add(time.Minute))
+	rk.Add("b", now.Add(-time.Second))
+	rk.Add("c", now.Add(time.Hour))
+	rk.Remove("c")
+
+	keys := rk.Keys(now)
+	if len(keys) != 1 {
+		t.Fatalf("expected %d got %d", 1, len(keys))
+	}
+	if !keys["a"].Equal(now.Add(time.Minute)) {
+		t.Errorf("expected %v got %v", now.Add(time.Minute), keys["a"])
+	}
+	if _, ok := rk.keys["b"]; ok {
+		t.Error("expected expired key to be pruned")
+	}
+
+	var nrk *RetainedKeys
+	nrk.Add("a", now)
+	nrk.Remove("a")
+	if nrk.Keys(now) != nil {
+		t.Error("expected nil keys")
+	}
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package timeseries
+
+import "strconv"
+
+// SnapMode enumerates the methodologies for aligning a TimeRangeQuery's Extent to its Step
+type SnapMode int
+
+const (
+	// SnapFloor truncates the Start and End of the Extent to the Step
+	SnapFloor = SnapMode(iota)
+	// SnapOutward truncates the Start and rounds the End of the Extent up to the Step,
+	// so that the Extent includes the entire requested range
+	SnapOutward
+	// SnapNearest rounds the Start and End of the Extent to the nearest Step boundary
+	SnapNearest
+)
+
+// SnapModeNames is a map of SnapModes keyed by string name
+var SnapModeNames = map[string]SnapMode{
+	"floor":   SnapFloor,
+	"outward": SnapOutward,
+	"nearest": SnapNearest,
+}
+
+// SnapModeValues is a map of SnapModes valued by string name
+var SnapModeValues = make(map[SnapMode]string)
+
+func init() {
+	for k, v := range SnapModeNames {
+		SnapModeValues[v] = k
+	}
+}
+
+func (m SnapMode) String() string {
+	if v, ok := SnapModeValues[m]; ok {
+		return v
+	}
+	return strconv.Itoa(int(m))
+}
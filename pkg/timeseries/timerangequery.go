@@ -42,6 +42,9 @@ type TimeRangeQuery struct {
 	FastForwardDisable bool
 	// IsOffset is true if the query uses a relative offset modifier
 	IsOffset bool
+	// MaxDataPoints is the maximum number of timestamps requested in the Time Range Query's
+	// response. 0 is unlimited
+	MaxDataPoints int
 }
 
 // Clone returns an exact copy of a TimeRangeQuery
@@ -53,6 +56,7 @@ func (trq *TimeRangeQuery) Clone() *TimeRangeQuery {
 		IsOffset:           trq.IsOffset,
 		TimestampFieldName: trq.TimestampFieldName,
 		FastForwardDisable: trq.FastForwardDisable,
+		MaxDataPoints:      trq.MaxDataPoints,
 	}
 
 	if trq.TemplateURL != nil {
@@ -64,12 +68,33 @@ func (trq *TimeRangeQuery) Clone() *TimeRangeQuery {
 
 // NormalizeExtent adjusts the Start and End of a TimeRangeQuery's Extent to align against normalized boundaries.
 func (trq *TimeRangeQuery) NormalizeExtent() {
+	trq.SnapExtent(SnapFloor)
+}
+
+// SnapExtent adjusts the Start and End of a TimeRangeQuery's Extent to align against Step boundaries
+// using the provided SnapMode. The End is never snapped to a boundary in the future.
+func (trq *TimeRangeQuery) SnapExtent(mode SnapMode) {
 	if trq.Step.Seconds() > 0 {
-		if !trq.IsOffset && trq.Extent.End.After(time.Now()) {
-			trq.Extent.End = time.Now()
+		now := time.Now()
+		if !trq.IsOffset && trq.Extent.End.After(now) {
+			trq.Extent.End = now
+		}
+		switch mode {
+		case SnapOutward:
+			trq.Extent.Start = trq.Extent.Start.Truncate(trq.Step)
+			if end := trq.Extent.End.Truncate(trq.Step); end.Before(trq.Extent.End) {
+				trq.Extent.End = end.Add(trq.Step)
+			}
+		case SnapNearest:
+			trq.Extent.Start = trq.Extent.Start.Round(trq.Step)
+			trq.Extent.End = trq.Extent.End.Round(trq.Step)
+		default:
+			trq.Extent.Start = trq.Extent.Start.Truncate(trq.Step)
+			trq.Extent.End = trq.Extent.End.Truncate(trq.Step)
+		}
+		if !trq.IsOffset && trq.Extent.End.After(now) {
+			trq.Extent.End = trq.Extent.End.Add(-trq.Step)
 		}
-		trq.Extent.Start = trq.Extent.Start.Truncate(trq.Step)
-		trq.Extent.End = trq.Extent.End.Truncate(trq.Step)
 	}
 }
 
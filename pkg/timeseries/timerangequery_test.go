@@ -132,6 +132,53 @@ func TestNormalizeExtent(t *testing.T) {
 	}
 }
 
+func TestSnapExtent(t *testing.T) {
+
+	tmrw := time.Now().Add(time.Duration(24) * time.Hour).Unix()
+	expected := (time.Now().Unix() / 10) * 10
+
+	tests := []struct {
+		mode                 SnapMode
+		start, end, stepSecs int64
+		rangeStart, rangeEnd int64
+	}{
+		{SnapFloor, 7, 103, 10, 0, 100},
+		{SnapOutward, 7, 103, 10, 0, 110},
+		{SnapOutward, 10, 100, 10, 10, 100},
+		{SnapNearest, 7, 103, 10, 10, 100},
+		{SnapNearest, 4, 106, 10, 0, 110},
+		// the end is never snapped into the future
+		{SnapOutward, 1, tmrw, 10, 0, expected},
+		{SnapNearest, 1, tmrw, 10, 0, expected},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+
+			trq := TimeRangeQuery{Statement: "up", Extent: Extent{Start: time.Unix(test.start, 0),
+				End: time.Unix(test.end, 0)}, Step: time.Duration(test.stepSecs) * time.Second}
+
+			trq.SnapExtent(test.mode)
+
+			if trq.Extent.Start.Unix() != test.rangeStart {
+				t.Errorf("Mismatch in rangeStart: expected=%d actual=%d", test.rangeStart, trq.Extent.Start.Unix())
+			}
+			if trq.Extent.End.Unix() != test.rangeEnd {
+				t.Errorf("Mismatch in rangeEnd: expected=%d actual=%d", test.rangeEnd, trq.Extent.End.Unix())
+			}
+		})
+	}
+}
+
+func TestSnapModeString(t *testing.T) {
+	if SnapOutward.String() != "outward" {
+		t.Errorf("expected %s got %s", "outward", SnapOutward.String())
+	}
+	if SnapMode(42).String() != "42" {
+		t.Errorf("expected %s got %s", "42", SnapMode(42).String())
+	}
+}
+
 func TestClone(t *testing.T) {
 	u, _ := url.Parse("http://127.0.0.1/")
 	trq := &TimeRangeQuery{Statement: "1234", Extent: Extent{Start: time.Unix(5, 0),
@@ -18,12 +18,19 @@
 // and provides time range manipulation capabilities
 package timeseries
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 // FastForwardUserDisableFlag is a string that is checked to determine if Fast Forward
 // should be selectively disabled for the provided query
 const FastForwardUserDisableFlag = "trickster-fast-forward:off"
 
+// ErrUnsupportedResponseFormat is returned when an upstream response is in a format that
+// can't be decoded into a Timeseries, so the response is proxied without being cached
+var ErrUnsupportedResponseFormat = errors.New("unsupported response format")
+
 // Timeseries represents a Response Object from a Timeseries Database
 type Timeseries interface {
 	// SetExtents sets the Extents of the Timeseries
@@ -55,3 +62,55 @@ type Timeseries interface {
 	// Size returns the approximate memory byte size of the timeseries object
 	Size() int
 }
+
+// Downsampler is implemented by Timeseries that can be reduced to a coarser step, which
+// allows their cached data to be re-stored in downsample tiers
+type Downsampler interface {
+	// Downsample should return a copy of the Timeseries that only includes the values, and
+	// the portions of its Extents, whose timestamps fall on the provided step
+	Downsample(time.Duration) Timeseries
+}
+
+// Truncator is implemented by Timeseries that can be reduced to a maximum number of series
+// and values and can carry warnings to the client, which allows an oversized result to be
+// returned partially
+type Truncator interface {
+	// Truncate should remove the series beyond maxSeries, and the values beyond maxValues
+	// across all series, where 0 is unlimited. It should return true if anything was removed
+	Truncate(maxSeries, maxValues int) bool
+	// AddWarning should add a warning to be included in the client response
+	AddWarning(string)
+}
+
+// Deduplicator is implemented by Timeseries whose series can be merged across the labels that
+// distinguish the replicas of a highly-available origin
+type Deduplicator interface {
+	// Deduplicate should remove the provided labels from each series, and merge any series
+	// whose remaining labels are identical
+	Deduplicate(labels []string)
+}
+
+// Aggregator is implemented by Timeseries that can be thinned to a coarser step when they
+// are served, which allows large cached results to be rendered without fetching them again
+type Aggregator interface {
+	// Aggregate should replace the values of each series with one value for each bucket of
+	// the provided step, aligned to the epoch, computed by the provided Aggregation
+	Aggregate(time.Duration, Aggregation)
+}
+
+// PrecisionReducer is implemented by Timeseries whose values can be rounded to fewer
+// significant digits, which makes their encodings smaller and more compressible
+type PrecisionReducer interface {
+	// ReducePrecision should round each value to the provided number of significant digits,
+	// without modifying any value arrays shared with clones of the Timeseries
+	ReducePrecision(digits int)
+}
+
+// Differ is implemented by Timeseries whose samples can be compared with those of another
+// Timeseries, which allows data fetched from the origin to be verified against the cached
+// data that it overlaps
+type Differ interface {
+	// Diff should return the number of samples within the provided Extent that differ between
+	// the Timeseries, because their values differ or because only one of them has the sample
+	Diff(Timeseries, Extent) int
+}
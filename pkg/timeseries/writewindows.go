@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package timeseries
+
+import (
+	"sync"
+	"time"
+)
+
+// WriteWindows records the time ranges of samples recently written through to an
+// origin (e.g., by Prometheus remote write), by metric name, so that cached extents
+// overlapping them can be refetched until the writes have settled. Samples for
+// series without a metric name are recorded under the empty name, and apply to
+// every query
+type WriteWindows struct {
+	ttl     time.Duration
+	mtx     sync.Mutex
+	windows map[string]writeWindow
+}
+
+type writeWindow struct {
+	extent  Extent
+	expires time.Time
+}
+
+// NewWriteWindows returns a new WriteWindows that retains each window for the
+// provided duration after the last write to it
+func NewWriteWindows(ttl time.Duration) *WriteWindows {
+	return &WriteWindows{ttl: ttl, windows: make(map[string]writeWindow)}
+}
+
+// Add records that samples in the provided Extent were written for the named metric.
+// Windows for the same name are widened to cover both writes
+func (ww *WriteWindows) Add(name string, e Extent, now time.Time) {
+	ww.mtx.Lock()
+	defer ww.mtx.Unlock()
+	if w, ok := ww.windows[name]; ok && w.expires.After(now) {
+		if w.extent.Start.Before(e.Start) {
+			e.Start = w.extent.Start
+		}
+		if w.extent.End.After(e.End) {
+			e.End = w.extent.End
+		}
+	}
+	ww.windows[name] = writeWindow{extent: e, expires: now.Add(ww.ttl)}
+	// expired windows are pruned as new ones are added, so the map stays bounded
+	// by the number of metrics written within the ttl
+	for k, w := range ww.windows {
+		if !w.expires.After(now) {
+			delete(ww.windows, k)
+		}
+	}
+}
+
+// Earliest returns the earliest start time of the unexpired windows that overlap the
+// provided Extent and whose metric names appear in the provided query statement
+func (ww *WriteWindows) Earliest(statement string, e Extent, now time.Time) (time.Time, bool) {
+	if ww == nil {
+		return time.Time{}, false
+	}
+	ww.mtx.Lock()
+	defer ww.mtx.Unlock()
+	if len(ww.windows) == 0 {
+		return time.Time{}, false
+	}
+	var t time.Time
+	var found bool
+	check := func(name string) {
+		w, ok := ww.windows[name]
+		if !ok || !w.expires.After(now) || w.extent.End.Before(e.Start) || w.extent.Start.After(e.End) {
+			return
+		}
+		if !found || w.extent.Start.Before(t) {
+			t = w.extent.Start
+			found = true
+		}
+	}
+	check("")
+	for _, name := range identifiers(statement) {
+		check(name)
+	}
+	return t, found
+}
+
+// identifiers returns the tokens in the statement that could be metric names
+func identifiers(statement string) []string {
+	var ids []string
+	start := -1
+	for i := 0; i <= len(statement); i++ {
+		var c byte
+		if i < len(statement) {
+			c = statement[i]
+		}
+		isIdent := c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+			(start >= 0 && c >= '0' && c <= '9')
+		if isIdent {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			ids = append(ids, statement[start:i])
+			start = -1
+		}
+	}
+	return ids
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package timeseries
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIdentifiers(t *testing.T) {
+	ids := identifiers(`sum(rate(http_requests_total{job="api"}[5m])) by (code)`)
+	expected := []string{"sum", "rate", "http_requests_total", "job", "api", "m", "by", "code"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected %v got %v", expected, ids)
+	}
+}
+
+func TestWriteWindows(t *testing.T) {
+
+	now := time.Unix(3600, 0)
+	ww := NewWriteWindows(time.Minute)
+
+	if _, ok := ww.Earliest("up", Extent{End: now}, now); ok {
+		t.Error("expected no windows")
+	}
+
+	ww.Add("up", Extent{Start: time.Unix(1200, 0), End: time.Unix(1800, 0)}, now)
+	ww.Add("up", Extent{Start: time.Unix(600, 0), End: time.Unix(900, 0)}, now)
+	ww.Add("node_load1", Extent{Start: time.Unix(300, 0), End: time.Unix(400, 0)}, now)
+
+	tests := []struct {
+		statement string
+		extent    Extent
+		expected  int64
+		ok        bool
+	}{
+		{"up", Extent{Start: time.Unix(0, 0), End: now}, 600, true},
+		{"sum(up)", Extent{Start: time.Unix(2000, 0), End: now}, 0, false},
+		{"up or node_load1", Extent{Start: time.Unix(0, 0), End: now}, 300, true},
+		{"node_load15", Extent{Start: time.Unix(0, 0), End: now}, 0, false},
+	}
+
+	for _, test := range tests {
+		st, ok := ww.Earliest(test.statement, test.extent, now)
+		if ok != test.ok {
+			t.Errorf("%s: expected %t got %t", test.statement, test.ok, ok)
+		} else if ok && st.Unix() != test.expected {
+			t.Errorf("%s: expected %d got %d", test.statement, test.expected, st.Unix())
+		}
+	}
+
+	// windows expire after the ttl
+	if _, ok := ww.Earliest("up", Extent{End: now}, now.Add(time.Minute)); ok {
+		t.Error("expected expired window")
+	}
+
+	// unnamed series apply to every statement
+	ww.Add("", Extent{Start: time.Unix(100, 0), End: time.Unix(200, 0)}, now)
+	if st, ok := ww.Earliest("node_load15", Extent{End: now}, now); !ok || st.Unix() != 100 {
+		t.Errorf("expected %d got %d", 100, st.Unix())
+	}
+
+	var nilWW *WriteWindows
+	if _, ok := nilWW.Earliest("up", Extent{End: now}, now); ok {
+		t.Error("expected no windows")
+	}
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datadog
+
+import (
+	"encoding/binary"
+
+	"github.com/tinylib/msgp/msgp"
+	"go.opentelemetry.io/otel/api/kv"
+	"go.opentelemetry.io/otel/api/kv/value"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// samplingPriorityKey tells the agent that Trickster already made the sampling decision
+	samplingPriorityKey = "_sampling_priority_v1"
+	// samplingPriorityKeep is the sampling priority of a trace that must be kept
+	samplingPriorityKeep = 1
+	// spanTypeWeb is the Datadog span type of spans that serve an incoming request
+	spanTypeWeb = "web"
+)
+
+// span is a Datadog APM span, as accepted by the agent's /v0.4/traces endpoint
+type span struct {
+	Service  string
+	Name     string
+	Resource string
+	TraceID  uint64
+	SpanID   uint64
+	ParentID uint64
+	Start    int64
+	Duration int64
+	Error    int32
+	Meta     map[string]string
+	Metrics  map[string]float64
+	Type     string
+}
+
+// convertSpan converts an OpenTelemetry span into a Datadog span. The meta tags are
+// attached to the span in addition to its own string attributes
+func convertSpan(sd *export.SpanData, service string, meta map[string]string) *span {
+
+	s := &span{
+		Service:  service,
+		Name:     sd.Name,
+		Resource: sd.Name,
+		// Datadog trace ids are 64 bits, so only the low-order bytes are used
+		TraceID:  binary.BigEndian.Uint64(sd.SpanContext.TraceID[8:]),
+		SpanID:   binary.BigEndian.Uint64(sd.SpanContext.SpanID[:]),
+		Start:    sd.StartTime.UnixNano(),
+		Duration: sd.EndTime.Sub(sd.StartTime).Nanoseconds(),
+		Meta:     make(map[string]string, len(meta)+len(sd.Attributes)),
+		Metrics:  make(map[string]float64),
+	}
+
+	for k, v := range meta {
+		s.Meta[k] = v
+	}
+
+	if sd.ParentSpanID.IsValid() {
+		s.ParentID = binary.BigEndian.Uint64(sd.ParentSpanID[:])
+	}
+
+	// a span without a local parent is the entry point of this request into Trickster
+	if !sd.ParentSpanID.IsValid() || sd.HasRemoteParent {
+		s.Type = spanTypeWeb
+		s.Metrics[samplingPriorityKey] = samplingPriorityKeep
+	}
+
+	for _, a := range sd.Attributes {
+		setAttribute(s, a)
+	}
+
+	if sd.StatusCode != codes.OK {
+		s.Error = 1
+		s.Meta["error.type"] = sd.StatusCode.String()
+		if sd.StatusMessage != "" {
+			s.Meta["error.msg"] = sd.StatusMessage
+		}
+	}
+
+	return s
+}
+
+// setAttribute adds numeric attributes to the span's metrics and all others to its meta
+func setAttribute(s *span, a kv.KeyValue) {
+	k := string(a.Key)
+	switch a.Value.Type() {
+	case value.INT32:
+		s.Metrics[k] = float64(a.Value.AsInt32())
+	case value.INT64:
+		s.Metrics[k] = float64(a.Value.AsInt64())
+	case value.UINT32:
+		s.Metrics[k] = float64(a.Value.AsUint32())
+	case value.UINT64:
+		s.Metrics[k] = float64(a.Value.AsUint64())
+	case value.FLOAT32:
+		s.Metrics[k] = float64(a.Value.AsFloat32())
+	case value.FLOAT64:
+		s.Metrics[k] = a.Value.AsFloat64()
+	default:
+		s.Meta[k] = a.Value.Emit()
+	}
+}
+
+// appendSpan appends the msgpack encoding of the span to b
+func appendSpan(b []byte, s *span) []byte {
+	b = msgp.AppendMapHeader(b, 12)
+	b = msgp.AppendString(b, "service")
+	b = msgp.AppendString(b, s.Service)
+	b = msgp.AppendString(b, "name")
+	b = msgp.AppendString(b, s.Name)
+	b = msgp.AppendString(b, "resource")
+	b = msgp.AppendString(b, s.Resource)
+	b = msgp.AppendString(b, "trace_id")
+	b = msgp.AppendUint64(b, s.TraceID)
+	b = msgp.AppendString(b, "span_id")
+	b = msgp.AppendUint64(b, s.SpanID)
+	b = msgp.AppendString(b, "parent_id")
+	b = msgp.AppendUint64(b, s.ParentID)
+	b = msgp.AppendString(b, "start")
+	b = msgp.AppendInt64(b, s.Start)
+	b = msgp.AppendString(b, "duration")
+	b = msgp.AppendInt64(b, s.Duration)
+	b = msgp.AppendString(b, "error")
+	b = msgp.AppendInt32(b, s.Error)
+	b = msgp.AppendString(b, "meta")
+	b = msgp.AppendMapStrStr(b, s.Meta)
+	b = msgp.AppendString(b, "metrics")
+	b = msgp.AppendMapHeader(b, uint32(len(s.Metrics)))
+	for k, v := range s.Metrics {
+		b = msgp.AppendString(b, k)
+		b = msgp.AppendFloat64(b, v)
+	}
+	b = msgp.AppendString(b, "type")
+	b = msgp.AppendString(b, s.Type)
+	return b
+}
+
+// encodeTraces returns the msgpack encoding of the spans, grouped by trace as the agent
+// expects, and the number of traces in the payload
+func encodeTraces(spans []*span) ([]byte, int) {
+	order := make([]uint64, 0, len(spans))
+	traces := make(map[uint64][]*span)
+	for _, s := range spans {
+		if _, ok := traces[s.TraceID]; !ok {
+			order = append(order, s.TraceID)
+		}
+		traces[s.TraceID] = append(traces[s.TraceID], s)
+	}
+	b := msgp.AppendArrayHeader(nil, uint32(len(order)))
+	for _, id := range order {
+		t := traces[id]
+		b = msgp.AppendArrayHeader(b, uint32(len(t)))
+		for _, s := range t {
+			b = appendSpan(b, s)
+		}
+	}
+	return b, len(order)
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datadog
+
+import (
+	"testing"
+)
+
+func TestConvertSpan(t *testing.T) {
+
+	spans := testSpans()
+
+	s := convertSpan(spans[1], "trickster", map[string]string{"env": "prod"})
+	if s.ParentID != 1 || s.SpanID != 2 || s.TraceID != 9 {
+		t.Errorf("unexpected ids %d %d %d", s.TraceID, s.SpanID, s.ParentID)
+	}
+	if s.Type != "" {
+		t.Errorf("expected empty span type got %s", s.Type)
+	}
+	if s.Error != 1 || s.Meta["error.msg"] != "test error" {
+		t.Errorf("expected error span got %d %s", s.Error, s.Meta["error.msg"])
+	}
+	if s.Duration != 500 {
+		t.Errorf("expected %d got %d", 500, s.Duration)
+	}
+	if s.Meta["env"] != "prod" {
+		t.Errorf("expected %s got %s", "prod", s.Meta["env"])
+	}
+
+	// a span with a remote parent is the entry point into Trickster
+	spans[1].HasRemoteParent = true
+	s = convertSpan(spans[1], "trickster", nil)
+	if s.Type != spanTypeWeb {
+		t.Errorf("expected %s got %s", spanTypeWeb, s.Type)
+	}
+}
+
+func TestEncodeTraces(t *testing.T) {
+	spans := testSpans()
+	s1 := convertSpan(spans[0], "trickster", nil)
+	s2 := convertSpan(spans[1], "trickster", nil)
+	s3 := convertSpan(spans[1], "trickster", nil)
+	s3.TraceID = 10
+	_, count := encodeTraces([]*span{s1, s3, s2})
+	if count != 2 {
+		t.Errorf("expected %d got %d", 2, count)
+	}
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datadog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/tracing/exporters/datadog/options"
+
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+)
+
+const (
+	// DefaultAgentURL is the default address of the Datadog agent's trace intake
+	DefaultAgentURL = "http://localhost:8126"
+	// DefaultTimeout is the default time to wait for the agent to accept a batch
+	DefaultTimeout = 10 * time.Second
+
+	tracesPath = "/v0.4/traces"
+)
+
+// Exporter exports spans to a Datadog agent
+type Exporter struct {
+	url     string
+	service string
+	meta    map[string]string
+	client  *http.Client
+	logger  *log.Logger
+}
+
+// NewExporter returns a new Datadog Exporter for the provided agent URL and options.
+// The tags are attached to every exported span, along with the env and version tags
+func NewExporter(agentURL, service string, o *options.Options,
+	tags map[string]string) (*Exporter, error) {
+
+	if o == nil {
+		o = options.NewOptions()
+	}
+
+	if agentURL == "" {
+		agentURL = DefaultAgentURL
+	}
+	u, err := url.Parse(agentURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid datadog collector_url: %s", agentURL)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = tracesPath
+	}
+
+	meta := make(map[string]string, len(tags)+2)
+	for k, v := range tags {
+		meta[k] = v
+	}
+	if o.Env != "" {
+		meta["env"] = o.Env
+	}
+	if o.Version != "" {
+		meta["version"] = o.Version
+	}
+
+	timeout := DefaultTimeout
+	if o.TimeoutMS > 0 {
+		timeout = time.Duration(o.TimeoutMS) * time.Millisecond
+	}
+
+	return &Exporter{
+		url:     u.String(),
+		service: service,
+		meta:    meta,
+		logger:  log.New(os.Stderr, "datadog exporter: ", log.LstdFlags),
+		client:  &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// ExportSpans is a part of an implementation of the SpanBatcher interface
+func (e *Exporter) ExportSpans(ctx context.Context, batch []*export.SpanData) {
+	if len(batch) == 0 {
+		return
+	}
+	spans := make([]*span, len(batch))
+	for i, sd := range batch {
+		spans[i] = convertSpan(sd, e.service, e.meta)
+	}
+	body, count := encodeTraces(spans)
+	if err := e.export(ctx, body, count); err != nil {
+		e.logf("datadog export to %s failed: %v", e.url, err)
+	}
+}
+
+func (e *Exporter) export(ctx context.Context, body []byte, traceCount int) error {
+
+	req, err := http.NewRequest(http.MethodPut, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/msgpack")
+	req.Header.Set("X-Datadog-Trace-Count", strconv.Itoa(traceCount))
+	req.Header.Set("Datadog-Meta-Lang", "go")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("agent responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (e *Exporter) logf(format string, args ...interface{}) {
+	if e.logger != nil {
+		e.logger.Printf(format, args...)
+	}
+}
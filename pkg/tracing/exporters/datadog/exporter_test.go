@@ -0,0 +1,176 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datadog
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/tracing/exporters/datadog/options"
+
+	"github.com/tinylib/msgp/msgp"
+	"go.opentelemetry.io/otel/api/kv"
+	apitrace "go.opentelemetry.io/otel/api/trace"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	"google.golang.org/grpc/codes"
+)
+
+func testSpans() []*export.SpanData {
+	return []*export.SpanData{
+		{
+			SpanContext: apitrace.SpanContext{
+				TraceID: apitrace.ID{1, 2, 3, 4, 5, 6, 7, 8, 0, 0, 0, 0, 0, 0, 0, 9},
+				SpanID:  apitrace.SpanID{0, 0, 0, 0, 0, 0, 0, 1},
+			},
+			Name:       "request",
+			StartTime:  time.Unix(1, 0),
+			EndTime:    time.Unix(2, 0),
+			Attributes: []kv.KeyValue{kv.String("path", "/test"), kv.Int("bytes", 10)},
+		},
+		{
+			SpanContext: apitrace.SpanContext{
+				TraceID: apitrace.ID{1, 2, 3, 4, 5, 6, 7, 8, 0, 0, 0, 0, 0, 0, 0, 9},
+				SpanID:  apitrace.SpanID{0, 0, 0, 0, 0, 0, 0, 2},
+			},
+			ParentSpanID:  apitrace.SpanID{0, 0, 0, 0, 0, 0, 0, 1},
+			Name:          "QueryCache",
+			StartTime:     time.Unix(1, 0),
+			EndTime:       time.Unix(1, 500),
+			StatusCode:    codes.Internal,
+			StatusMessage: "test error",
+		},
+	}
+}
+
+func TestNewExporter(t *testing.T) {
+
+	tests := []struct {
+		url         string
+		expectedURL string
+		expectErr   bool
+	}{
+		{"", DefaultAgentURL + tracesPath, false},
+		{"http://agent:8126", "http://agent:8126" + tracesPath, false},
+		{"http://agent:8126/custom", "http://agent:8126/custom", false},
+		{"agent:8126", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			e, err := NewExporter(test.url, "trickster", nil, nil)
+			if test.expectErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.url != test.expectedURL {
+				t.Errorf("expected %s got %s", test.expectedURL, e.url)
+			}
+		})
+	}
+}
+
+func TestExportSpans(t *testing.T) {
+
+	var body []byte
+	var hdr http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("expected %s got %s", http.MethodPut, r.Method)
+		}
+		hdr = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer ts.Close()
+
+	e, err := NewExporter(ts.URL, "trickster",
+		&options.Options{Env: "prod", Version: "1.0"}, map[string]string{"region": "us"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.ExportSpans(context.Background(), nil)
+	if body != nil {
+		t.Error("expected no request for an empty batch")
+	}
+
+	e.ExportSpans(context.Background(), testSpans())
+
+	if v := hdr.Get("X-Datadog-Trace-Count"); v != "1" {
+		t.Errorf("expected %s got %s", "1", v)
+	}
+	if v := hdr.Get("Content-Type"); v != "application/msgpack" {
+		t.Errorf("expected %s got %s", "application/msgpack", v)
+	}
+
+	// the payload is an array of traces, each an array of spans
+	sz, b, err := msgp.ReadArrayHeaderBytes(body)
+	if err != nil || sz != 1 {
+		t.Fatalf("expected 1 trace got %d %v", sz, err)
+	}
+	sz, b, err = msgp.ReadArrayHeaderBytes(b)
+	if err != nil || sz != 2 {
+		t.Fatalf("expected 2 spans got %d %v", sz, err)
+	}
+	v, _, err := msgp.ReadIntfBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := v.(map[string]interface{})
+	if s["service"] != "trickster" || s["name"] != "request" || s["type"] != "web" {
+		t.Errorf("unexpected span %v", s)
+	}
+	// msgpack encodes small integers compactly, so compare their printed values
+	if fmt.Sprint(s["trace_id"]) != "9" || fmt.Sprint(s["span_id"]) != "1" ||
+		fmt.Sprint(s["parent_id"]) != "0" {
+		t.Errorf("unexpected span ids %v", s)
+	}
+	meta := s["meta"].(map[string]interface{})
+	if meta["env"] != "prod" || meta["version"] != "1.0" || meta["region"] != "us" ||
+		meta["path"] != "/test" {
+		t.Errorf("unexpected meta %v", meta)
+	}
+	metrics := s["metrics"].(map[string]interface{})
+	if metrics["bytes"] != float64(10) || metrics[samplingPriorityKey] != float64(1) {
+		t.Errorf("unexpected metrics %v", metrics)
+	}
+}
+
+func TestExportSpansError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer ts.Close()
+	e, err := NewExporter(ts.URL, "trickster", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, count := encodeTraces(nil)
+	err = e.export(context.Background(), body, count)
+	if err == nil {
+		t.Error("expected error for bad request")
+	}
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+// Options is a collection of Datadog-specific options
+type Options struct {
+	// Env is the Datadog environment tag (e.g., 'prod') attached to every span
+	Env string `toml:"env"`
+	// Version is the Datadog version tag attached to every span
+	Version string `toml:"version"`
+	// TimeoutMS is the maximum time in milliseconds to wait for the agent to accept a batch
+	TimeoutMS int `toml:"timeout_ms"`
+}
+
+// NewOptions returns a new *Options with the default values
+func NewOptions() *Options {
+	return &Options{}
+}
+
+// Clone returns a perfect copy of the subject *Options
+func (o *Options) Clone() *Options {
+	return &Options{
+		Env:       o.Env,
+		Version:   o.Version,
+		TimeoutMS: o.TimeoutMS,
+	}
+}
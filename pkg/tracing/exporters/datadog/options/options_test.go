@@ -0,0 +1,34 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import "testing"
+
+func TestClone(t *testing.T) {
+
+	o := &Options{
+		Env:       "prod",
+		Version:   "1.0",
+		TimeoutMS: 100,
+	}
+
+	o2 := o.Clone()
+	if o2.Env != "prod" || o2.Version != "1.0" || o2.TimeoutMS != 100 {
+		t.Errorf("clone failed")
+	}
+
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/tracing"
 	errs "github.com/tricksterproxy/trickster/pkg/tracing/errors"
 	"github.com/tricksterproxy/trickster/pkg/tracing/options"
+	"github.com/tricksterproxy/trickster/pkg/tracing/sampling"
 
 	"go.opentelemetry.io/otel/api/kv"
 	"go.opentelemetry.io/otel/exporters/trace/jaeger"
@@ -30,22 +31,13 @@ import (
 // NewTracer returns a new Jaeger Tracer based on the provided options
 func NewTracer(options *options.Options) (*tracing.Tracer, error) {
 
-	var tp *sdktrace.Provider
-	var err error
-	var flusher func()
-
 	if options == nil {
 		return nil, errs.ErrNoTracerOptions
 	}
 
-	var sampler sdktrace.Sampler
-	switch options.SampleRate {
-	case 0:
-		sampler = sdktrace.NeverSample()
-	case 1:
-		sampler = sdktrace.AlwaysSample()
-	default:
-		sampler = sdktrace.ProbabilitySampler(options.SampleRate)
+	sampler, err := sampling.New(options)
+	if err != nil {
+		return nil, err
 	}
 
 	var tags []kv.KeyValue
@@ -73,9 +65,7 @@ func NewTracer(options *options.Options) (*tracing.Tracer, error) {
 		eo = jaeger.WithCollectorEndpoint(options.CollectorURL, ceo...)
 	}
 
-	// Create Tracing Provider
-	tp, flusher, err = jaeger.NewExportPipeline(eo,
-		jaeger.WithSDK(&sdktrace.Config{DefaultSampler: sampler}),
+	exporter, err := jaeger.NewRawExporter(eo,
 		jaeger.WithProcess(jaeger.Process{
 			ServiceName: options.ServiceName,
 			Tags:        tags,
@@ -85,13 +75,22 @@ func NewTracer(options *options.Options) (*tracing.Tracer, error) {
 		return nil, err
 	}
 
+	// Create Tracing Provider
+	tp, err := sdktrace.NewProvider(
+		sdktrace.WithSyncer(sampler.WrapSyncer(exporter)),
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sampler}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	tracer := tp.Tracer(options.Name)
 
 	return &tracing.Tracer{
 		Name:    options.Name,
 		Tracer:  tracer,
 		Options: options,
-		Flusher: flusher,
+		Flusher: exporter.Flush,
 	}, nil
 
 }
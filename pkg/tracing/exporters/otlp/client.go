@@ -0,0 +1,249 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/tracing/exporters/otlp/options"
+	"github.com/tricksterproxy/trickster/pkg/util/compress"
+)
+
+// Supported OTLP transport protocols
+const (
+	ProtocolHTTPProtobuf = "http/protobuf"
+	ProtocolGRPC         = "grpc"
+)
+
+// Supported export compression types
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+)
+
+const (
+	// DefaultTimeout is the default time to wait for the collector to accept a batch
+	DefaultTimeout = 10 * time.Second
+)
+
+// ErrGRPCRequiresTLS is returned when the grpc protocol is configured with a non-https collector
+// URL, since Trickster's gRPC transport rides on HTTP/2, which it only negotiates over TLS
+var ErrGRPCRequiresTLS = errors.New("otlp grpc protocol requires an https collector_url")
+
+// Client sends encoded OTLP export requests to an OpenTelemetry Collector. It is
+// shared by the span exporter and the metrics pusher, which differ only in the
+// request message and the collector path it is sent to
+type Client struct {
+	url         string
+	protocol    string
+	compression string
+	headers     map[string]string
+	client      *http.Client
+}
+
+// NewClient returns a new OTLP Client for the provided collector URL and options.
+// httpPath is used when the collector URL has no path and the protocol is http/protobuf,
+// and grpcPath is the full gRPC method path of the Export service
+func NewClient(collectorURL, httpPath, grpcPath string, o *options.Options) (*Client, error) {
+
+	if o == nil {
+		o = options.NewOptions()
+	}
+
+	protocol := o.Protocol
+	if protocol == "" {
+		protocol = ProtocolHTTPProtobuf
+	}
+	if protocol != ProtocolHTTPProtobuf && protocol != ProtocolGRPC {
+		return nil, fmt.Errorf("invalid otlp protocol: %s", protocol)
+	}
+
+	compression := o.Compression
+	if compression == "" {
+		compression = CompressionNone
+	}
+	if compression != CompressionNone && compression != CompressionGzip {
+		return nil, fmt.Errorf("invalid otlp compression: %s", compression)
+	}
+
+	u, err := url.Parse(collectorURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid otlp collector_url: %s", collectorURL)
+	}
+	switch protocol {
+	case ProtocolGRPC:
+		if u.Scheme != "https" {
+			return nil, ErrGRPCRequiresTLS
+		}
+		u.Path = grpcPath
+	default:
+		if u.Path == "" || u.Path == "/" {
+			u.Path = httpPath
+		}
+	}
+
+	tc, err := tlsConfig(o)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := DefaultTimeout
+	if o.TimeoutMS > 0 {
+		timeout = time.Duration(o.TimeoutMS) * time.Millisecond
+	}
+
+	return &Client{
+		url:         u.String(),
+		protocol:    protocol,
+		compression: compression,
+		headers:     o.Headers,
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:             http.ProxyFromEnvironment,
+				TLSClientConfig:   tc,
+				ForceAttemptHTTP2: true,
+			},
+		},
+	}, nil
+}
+
+// URL returns the full URL that export requests are sent to
+func (c *Client) URL() string {
+	return c.url
+}
+
+func tlsConfig(o *options.Options) (*tls.Config, error) {
+	if o.TLS == nil {
+		return nil, nil
+	}
+	tc := &tls.Config{InsecureSkipVerify: o.TLS.InsecureSkipVerify}
+	if o.TLS.ClientCertPath != "" && o.TLS.ClientKeyPath != "" {
+		cert, err := tls.LoadX509KeyPair(o.TLS.ClientCertPath, o.TLS.ClientKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	if len(o.TLS.CertificateAuthorityPaths) > 0 {
+		rootCAs, _ := x509.SystemCertPool()
+		if rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+		for _, path := range o.TLS.CertificateAuthorityPaths {
+			certs, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if ok := rootCAs.AppendCertsFromPEM(certs); !ok {
+				return nil, fmt.Errorf("unable to append to CA Certs from file %s", path)
+			}
+		}
+		tc.RootCAs = rootCAs
+	}
+	return tc, nil
+}
+
+// Export sends the encoded export request body to the collector
+func (c *Client) Export(ctx context.Context, body []byte) error {
+
+	compressed := c.compression == CompressionGzip
+	if compressed {
+		var err error
+		if body, err = compress.Encode(compress.NameGzip, body); err != nil {
+			return err
+		}
+	}
+
+	if c.protocol == ProtocolGRPC {
+		// gRPC Length-Prefixed Message: 1-byte compressed flag and a 4-byte big-endian length
+		frame := make([]byte, 5, 5+len(body))
+		if compressed {
+			frame[0] = 1
+		}
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(body)))
+		body = append(frame, body...)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	if c.protocol == ProtocolGRPC {
+		req.Header.Set("Content-Type", "application/grpc+proto")
+		req.Header.Set("TE", "trailers")
+		if compressed {
+			req.Header.Set("Grpc-Encoding", CompressionGzip)
+		}
+	} else {
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		if compressed {
+			req.Header.Set("Content-Encoding", CompressionGzip)
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// the body must be fully read before gRPC trailers are available
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("collector responded with status %d", resp.StatusCode)
+	}
+
+	if c.protocol == ProtocolGRPC {
+		status := resp.Trailer.Get("Grpc-Status")
+		msg := resp.Trailer.Get("Grpc-Message")
+		if status == "" {
+			// Trailers-Only responses carry the status in the headers
+			status = resp.Header.Get("Grpc-Status")
+			msg = resp.Header.Get("Grpc-Message")
+		}
+		if status != "0" {
+			if m, err := url.PathUnescape(msg); err == nil {
+				msg = m
+			}
+			return fmt.Errorf("collector responded with grpc status %s: %s",
+				status, strings.TrimSpace(msg))
+		}
+	}
+
+	return nil
+}
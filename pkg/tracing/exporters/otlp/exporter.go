@@ -0,0 +1,72 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otlp
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/tricksterproxy/trickster/pkg/tracing/exporters/otlp/options"
+
+	"go.opentelemetry.io/otel/api/kv"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+)
+
+const (
+	httpTracesPath = "/v1/traces"
+	grpcTracesPath = "/opentelemetry.proto.collector.trace.v1.TraceService/Export"
+	scopeName      = "trickster"
+)
+
+// Exporter exports spans to an OpenTelemetry Collector using OTLP
+type Exporter struct {
+	*Client
+	resource []kv.KeyValue
+	logger   *log.Logger
+}
+
+// NewExporter returns a new OTLP Exporter for the provided collector URL and options.
+// The resource attributes are attached to every batch of exported spans
+func NewExporter(collectorURL string, o *options.Options,
+	resource []kv.KeyValue) (*Exporter, error) {
+	c, err := NewClient(collectorURL, httpTracesPath, grpcTracesPath, o)
+	if err != nil {
+		return nil, err
+	}
+	return &Exporter{
+		Client:   c,
+		resource: resource,
+		logger:   log.New(os.Stderr, "otlp exporter: ", log.LstdFlags),
+	}, nil
+}
+
+// ExportSpans is a part of an implementation of the SpanBatcher interface
+func (e *Exporter) ExportSpans(ctx context.Context, batch []*export.SpanData) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := e.Export(ctx, encodeExportRequest(e.resource, scopeName, batch)); err != nil {
+		e.logf("otlp export to %s failed: %v", e.url, err)
+	}
+}
+
+func (e *Exporter) logf(format string, args ...interface{}) {
+	if e.logger != nil {
+		e.logger.Printf(format, args...)
+	}
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tlsopts "github.com/tricksterproxy/trickster/pkg/proxy/tls/options"
+	"github.com/tricksterproxy/trickster/pkg/tracing/exporters/otlp/options"
+
+	"go.opentelemetry.io/otel/api/kv"
+	apitrace "go.opentelemetry.io/otel/api/trace"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+)
+
+func testSpans() []*export.SpanData {
+	return []*export.SpanData{
+		{
+			SpanContext: apitrace.SpanContext{
+				TraceID: apitrace.ID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+				SpanID:  apitrace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+			},
+			Name:       "test-span",
+			SpanKind:   apitrace.SpanKindServer,
+			StartTime:  time.Unix(1, 0),
+			EndTime:    time.Unix(2, 0),
+			Attributes: []kv.KeyValue{kv.String("path", "/test")},
+		},
+	}
+}
+
+func TestNewExporter(t *testing.T) {
+
+	tests := []struct {
+		url         string
+		opts        *options.Options
+		expectedURL string
+		expectErr   bool
+	}{
+		{"http://collector:4318", nil, "http://collector:4318/v1/traces", false},
+		{"http://collector:4318/custom", nil, "http://collector:4318/custom", false},
+		{"https://collector:4317", &options.Options{Protocol: "grpc"},
+			"https://collector:4317" + grpcTracesPath, false},
+		{"http://collector:4317", &options.Options{Protocol: "grpc"}, "", true},
+		{"http://collector:4318", &options.Options{Protocol: "thrift"}, "", true},
+		{"http://collector:4318", &options.Options{Compression: "zstd"}, "", true},
+		{"collector:4318", nil, "", true},
+		{"http://collector:4318", &options.Options{TLS: &tlsopts.Options{
+			CertificateAuthorityPaths: []string{"../../../../testdata/does-not-exist.pem"}}}, "", true},
+		{"http://collector:4318", &options.Options{TLS: &tlsopts.Options{
+			ClientCertPath: "../../../../testdata/does-not-exist.pem",
+			ClientKeyPath:  "../../../../testdata/does-not-exist.pem"}}, "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			e, err := NewExporter(test.url, test.opts, nil)
+			if test.expectErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e.url != test.expectedURL {
+				t.Errorf("expected %s got %s", test.expectedURL, e.url)
+			}
+		})
+	}
+}
+
+func TestExportSpansHTTP(t *testing.T) {
+
+	var body []byte
+	var hdr http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hdr = r.Header
+		b, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gr, err := gzip.NewReader(bytes.NewReader(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ = ioutil.ReadAll(gr)
+		}
+		body = b
+		if r.URL.Path != httpTracesPath {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	e, err := NewExporter(ts.URL, &options.Options{
+		Headers:     map[string]string{"X-Api-Key": "test"},
+		Compression: "gzip",
+	}, []kv.KeyValue{kv.String("service.name", "trickster")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = e.Export(context.Background(), encodeExportRequest(e.resource, scopeName, testSpans()))
+	if err != nil {
+		t.Error(err)
+	}
+	if hdr.Get("Content-Type") != "application/x-protobuf" {
+		t.Errorf("unexpected content type %s", hdr.Get("Content-Type"))
+	}
+	if hdr.Get("X-Api-Key") != "test" {
+		t.Error("expected custom header")
+	}
+	if !bytes.Contains(body, []byte("test-span")) || !bytes.Contains(body, []byte("trickster")) {
+		t.Error("expected span name and service name in request body")
+	}
+
+	// exercise the no-op and logging paths
+	e.logger = nil
+	e.ExportSpans(context.Background(), nil)
+	e.ExportSpans(context.Background(), testSpans())
+
+	e.url = ts.URL + "/invalid"
+	err = e.Export(context.Background(), []byte{})
+	if err == nil {
+		t.Error("expected error for non-200 response")
+	}
+}
+
+func TestExportSpansGRPC(t *testing.T) {
+
+	var body []byte
+	status := "0"
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || r.Header.Get("Content-Type") != "application/grpc+proto" ||
+			r.URL.Path != grpcTracesPath {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ = ioutil.ReadAll(r.Body)
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		w.Header().Set("Content-Type", "application/grpc+proto")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte{0, 0, 0, 0, 0})
+		w.Header().Set("Grpc-Status", status)
+		if status != "0" {
+			w.Header().Set("Grpc-Message", "test%20failure")
+		}
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	e, err := NewExporter(ts.URL, &options.Options{Protocol: "grpc",
+		TLS: &tlsopts.Options{InsecureSkipVerify: true}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = e.Export(context.Background(), encodeExportRequest(e.resource, scopeName, testSpans()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body) < 5 || body[0] != 0 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+		t.Error("invalid grpc message framing")
+	}
+
+	status = "14"
+	err = e.Export(context.Background(), []byte{})
+	if err == nil || err.Error() != "collector responded with grpc status 14: test failure" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	tlsopts "github.com/tricksterproxy/trickster/pkg/proxy/tls/options"
+	"github.com/tricksterproxy/trickster/pkg/util/strings"
+)
+
+// Options is a collection of OTLP-specific options
+type Options struct {
+	// Protocol is the OTLP transport used to reach the collector: 'http/protobuf' or 'grpc'
+	Protocol string `toml:"protocol"`
+	// Headers are added to every export request sent to the collector (e.g., for authentication)
+	Headers map[string]string `toml:"headers"`
+	// Compression is the compression applied to export requests: 'gzip' or 'none'
+	Compression string `toml:"compression"`
+	// TimeoutMS is the maximum time in milliseconds to wait for the collector to accept a batch
+	TimeoutMS int `toml:"timeout_ms"`
+	// TLS provides the client TLS configuration for connecting to the collector
+	TLS *tlsopts.Options `toml:"tls"`
+}
+
+// NewOptions returns a new *Options with the default values
+func NewOptions() *Options {
+	return &Options{}
+}
+
+// Clone returns a perfect copy of the subject *Options
+func (o *Options) Clone() *Options {
+	var to *tlsopts.Options
+	if o.TLS != nil {
+		to = o.TLS.Clone()
+	}
+	return &Options{
+		Protocol:    o.Protocol,
+		Headers:     strings.CloneMap(o.Headers),
+		Compression: o.Compression,
+		TimeoutMS:   o.TimeoutMS,
+		TLS:         to,
+	}
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"testing"
+
+	tlsopts "github.com/tricksterproxy/trickster/pkg/proxy/tls/options"
+)
+
+func TestClone(t *testing.T) {
+
+	o := &Options{
+		Protocol:    "grpc",
+		Headers:     map[string]string{"test": "test"},
+		Compression: "gzip",
+		TimeoutMS:   100,
+		TLS:         &tlsopts.Options{InsecureSkipVerify: true},
+	}
+
+	o2 := o.Clone()
+	o2.Headers["test"] = "changed"
+
+	if o2.Protocol != "grpc" || o2.Compression != "gzip" || o2.TimeoutMS != 100 ||
+		o2.TLS == nil || !o2.TLS.InsecureSkipVerify {
+		t.Errorf("clone failed")
+	}
+
+	if o.Headers["test"] != "test" {
+		t.Errorf("clone did not copy headers")
+	}
+
+	o3 := NewOptions().Clone()
+	if o3.TLS != nil {
+		t.Errorf("expected nil tls options")
+	}
+
+}
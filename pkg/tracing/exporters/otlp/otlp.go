@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package otlp provides an OpenTelemetry Protocol (OTLP) Tracer
+package otlp
+
+import (
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/tracing"
+	errs "github.com/tricksterproxy/trickster/pkg/tracing/errors"
+	"github.com/tricksterproxy/trickster/pkg/tracing/options"
+	"github.com/tricksterproxy/trickster/pkg/tracing/sampling"
+
+	"go.opentelemetry.io/otel/api/kv"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// NewTracer returns a new OTLP Tracer based on the provided options
+func NewTracer(options *options.Options) (*tracing.Tracer, error) {
+
+	if options == nil {
+		return nil, errs.ErrNoTracerOptions
+	}
+
+	sampler, err := sampling.New(options)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := []kv.KeyValue{kv.String("service.name", options.ServiceName)}
+	for k, v := range options.Tags {
+		resource = append(resource, kv.String(k, v))
+	}
+
+	exporter, err := NewExporter(options.CollectorURL, options.OTLPOptions, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	tp, err := sdktrace.NewProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sampler}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	bsp, err := sdktrace.NewBatchSpanProcessor(sampler.WrapBatcher(exporter),
+		sdktrace.WithBatchTimeout(5*time.Second),
+		sdktrace.WithMaxExportBatchSize(512),
+	)
+	if err != nil {
+		return nil, err
+	}
+	tp.RegisterSpanProcessor(bsp)
+
+	tracer := tp.Tracer(options.Name)
+
+	return &tracing.Tracer{
+		Name:    options.Name,
+		Tracer:  tracer,
+		Options: options,
+		Flusher: bsp.Shutdown,
+	}, nil
+
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otlp
+
+import (
+	pw "github.com/tricksterproxy/trickster/pkg/internal/protowire"
+
+	"go.opentelemetry.io/otel/api/kv"
+	"go.opentelemetry.io/otel/api/kv/value"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	"google.golang.org/grpc/codes"
+)
+
+// This file encodes the OTLP ExportTraceServiceRequest message
+// (opentelemetry/proto/collector/trace/v1), so that spans can be exported
+// without importing the generated OTLP packages.
+
+// OTLP Status Codes
+const (
+	statusCodeUnset = 0
+	statusCodeError = 2
+)
+
+// encodeAnyValue encodes an opentelemetry.proto.common.v1.AnyValue
+func encodeAnyValue(v value.Value) pw.Buffer {
+	var b pw.Buffer
+	switch v.Type() {
+	case value.BOOL:
+		b.Tag(2, pw.WireVarint)
+		if v.AsBool() {
+			b.Varint(1)
+		} else {
+			b.Varint(0)
+		}
+	case value.INT32:
+		b.Tag(3, pw.WireVarint)
+		b.Varint(uint64(int64(v.AsInt32())))
+	case value.INT64:
+		b.Tag(3, pw.WireVarint)
+		b.Varint(uint64(v.AsInt64()))
+	case value.UINT32:
+		b.Tag(3, pw.WireVarint)
+		b.Varint(uint64(v.AsUint32()))
+	case value.UINT64:
+		b.Tag(3, pw.WireVarint)
+		b.Varint(v.AsUint64())
+	case value.FLOAT32:
+		b.DoubleField(4, float64(v.AsFloat32()))
+	case value.FLOAT64:
+		b.DoubleField(4, v.AsFloat64())
+	default:
+		b.StringValue(1, v.Emit())
+	}
+	return b
+}
+
+// encodeAttributes writes each attribute as an opentelemetry.proto.common.v1.KeyValue
+func encodeAttributes(b *pw.Buffer, field int, attrs []kv.KeyValue) {
+	for _, a := range attrs {
+		var m pw.Buffer
+		m.StringField(1, string(a.Key))
+		m.MessageField(2, encodeAnyValue(a.Value))
+		b.MessageField(field, m)
+	}
+}
+
+// encodeSpan encodes an opentelemetry.proto.trace.v1.Span
+func encodeSpan(sd *export.SpanData) pw.Buffer {
+	var b pw.Buffer
+	b.BytesField(1, sd.SpanContext.TraceID[:])
+	b.BytesField(2, sd.SpanContext.SpanID[:])
+	if sd.ParentSpanID.IsValid() {
+		b.BytesField(4, sd.ParentSpanID[:])
+	}
+	b.StringField(5, sd.Name)
+	b.VarintField(6, uint64(sd.SpanKind))
+	b.Fixed64Field(7, pw.UnixNano(sd.StartTime))
+	b.Fixed64Field(8, pw.UnixNano(sd.EndTime))
+	encodeAttributes(&b, 9, sd.Attributes)
+	b.VarintField(10, uint64(sd.DroppedAttributeCount))
+	for _, e := range sd.MessageEvents {
+		var m pw.Buffer
+		m.Fixed64Field(1, pw.UnixNano(e.Time))
+		m.StringField(2, e.Name)
+		encodeAttributes(&m, 3, e.Attributes)
+		b.MessageField(11, m)
+	}
+	b.VarintField(12, uint64(sd.DroppedMessageEventCount))
+	for _, l := range sd.Links {
+		var m pw.Buffer
+		m.BytesField(1, l.TraceID[:])
+		m.BytesField(2, l.SpanID[:])
+		encodeAttributes(&m, 4, l.Attributes)
+		b.MessageField(13, m)
+	}
+	b.VarintField(14, uint64(sd.DroppedLinkCount))
+	var s pw.Buffer
+	s.StringField(2, sd.StatusMessage)
+	if sd.StatusCode != codes.OK {
+		s.VarintField(3, statusCodeError)
+	} else {
+		s.VarintField(3, statusCodeUnset)
+	}
+	b.MessageField(15, s)
+	return b
+}
+
+// encodeExportRequest encodes an opentelemetry.proto.collector.trace.v1.ExportTraceServiceRequest
+// containing a single ResourceSpans for the provided resource attributes and spans
+func encodeExportRequest(resource []kv.KeyValue, scopeName string,
+	spans []*export.SpanData) []byte {
+
+	var r pw.Buffer
+	encodeAttributes(&r, 1, resource)
+
+	var scope pw.Buffer
+	scope.StringField(1, scopeName)
+
+	var ss pw.Buffer
+	ss.MessageField(1, scope)
+	for _, sd := range spans {
+		if sd == nil {
+			continue
+		}
+		ss.MessageField(2, encodeSpan(sd))
+	}
+
+	var rs pw.Buffer
+	rs.MessageField(1, r)
+	rs.MessageField(2, ss)
+
+	var req pw.Buffer
+	req.MessageField(1, rs)
+	return req
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otlp
+
+import (
+	"math"
+	"testing"
+
+	pw "github.com/tricksterproxy/trickster/pkg/internal/protowire"
+
+	"go.opentelemetry.io/otel/api/kv"
+	"go.opentelemetry.io/otel/api/kv/value"
+	"google.golang.org/grpc/codes"
+)
+
+func TestEncodeAnyValue(t *testing.T) {
+
+	tests := []struct {
+		v        value.Value
+		field    int
+		expected uint64
+	}{
+		{value.Bool(true), 2, 1},
+		{value.Int32(-1), 3, math.MaxUint64},
+		{value.Int64(42), 3, 42},
+		{value.Uint32(42), 3, 42},
+		{value.Uint64(42), 3, 42},
+		{value.Float32(1.5), 4, math.Float64bits(1.5)},
+		{value.Float64(2.5), 4, math.Float64bits(2.5)},
+	}
+
+	for _, test := range tests {
+		f := pw.DecodeFields(t, encodeAnyValue(test.v))
+		if len(f) != 1 || f[0].Num != test.field || f[0].Varint != test.expected {
+			t.Errorf("unexpected encoding for %v: %+v", test.v.AsInterface(), f)
+		}
+	}
+
+	f := pw.DecodeFields(t, encodeAnyValue(value.String("test")))
+	if len(f) != 1 || f[0].Num != 1 || string(f[0].Data) != "test" {
+		t.Errorf("unexpected encoding for string: %+v", f)
+	}
+}
+
+func TestEncodeExportRequest(t *testing.T) {
+
+	spans := testSpans()
+	spans[0].ParentSpanID = [8]byte{8, 7, 6, 5, 4, 3, 2, 1}
+	spans[0].StatusCode = codes.Internal
+	spans[0].StatusMessage = "failed"
+
+	b := encodeExportRequest([]kv.KeyValue{kv.String("service.name", "trickster")},
+		scopeName, append(spans, nil))
+
+	// ExportTraceServiceRequest.resource_spans
+	rs := pw.FieldByNum(pw.DecodeFields(t, b), 1)
+	if rs == nil {
+		t.Fatal("missing resource_spans")
+	}
+	rsf := pw.DecodeFields(t, rs.Data)
+
+	// ResourceSpans.resource.attributes[0].key
+	res := pw.FieldByNum(rsf, 1)
+	attr := pw.FieldByNum(pw.DecodeFields(t, res.Data), 1)
+	if k := pw.FieldByNum(pw.DecodeFields(t, attr.Data), 1); k == nil || string(k.Data) != "service.name" {
+		t.Error("missing service.name resource attribute")
+	}
+
+	// ResourceSpans.scope_spans
+	ss := pw.DecodeFields(t, pw.FieldByNum(rsf, 2).Data)
+	scope := pw.FieldByNum(pw.DecodeFields(t, pw.FieldByNum(ss, 1).Data), 1)
+	if string(scope.Data) != scopeName {
+		t.Errorf("expected scope name %s got %s", scopeName, scope.Data)
+	}
+
+	var spanCount int
+	for _, f := range ss {
+		if f.Num == 2 {
+			spanCount++
+		}
+	}
+	if spanCount != 1 {
+		t.Fatalf("expected 1 span got %d", spanCount)
+	}
+
+	span := pw.DecodeFields(t, pw.FieldByNum(ss, 2).Data)
+	if f := pw.FieldByNum(span, 1); len(f.Data) != 16 || f.Data[0] != 1 {
+		t.Error("invalid trace id")
+	}
+	if f := pw.FieldByNum(span, 2); len(f.Data) != 8 || f.Data[0] != 1 {
+		t.Error("invalid span id")
+	}
+	if f := pw.FieldByNum(span, 4); f == nil || f.Data[0] != 8 {
+		t.Error("invalid parent span id")
+	}
+	if f := pw.FieldByNum(span, 5); string(f.Data) != "test-span" {
+		t.Error("invalid span name")
+	}
+	if f := pw.FieldByNum(span, 6); f.Varint != 2 {
+		t.Error("invalid span kind")
+	}
+	if f := pw.FieldByNum(span, 7); f.Varint != uint64(1e9) {
+		t.Error("invalid start time")
+	}
+	if f := pw.FieldByNum(span, 8); f.Varint != uint64(2e9) {
+		t.Error("invalid end time")
+	}
+	status := pw.DecodeFields(t, pw.FieldByNum(span, 15).Data)
+	if f := pw.FieldByNum(status, 3); f == nil || f.Varint != statusCodeError {
+		t.Error("invalid status code")
+	}
+	if f := pw.FieldByNum(status, 2); f == nil || string(f.Data) != "failed" {
+		t.Error("invalid status message")
+	}
+}
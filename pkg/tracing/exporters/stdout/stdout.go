@@ -21,6 +21,7 @@ import (
 	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
 	"github.com/tricksterproxy/trickster/pkg/tracing"
 	"github.com/tricksterproxy/trickster/pkg/tracing/options"
+	"github.com/tricksterproxy/trickster/pkg/tracing/sampling"
 
 	"go.opentelemetry.io/otel/api/kv"
 	"go.opentelemetry.io/otel/exporters/trace/stdout"
@@ -54,15 +55,9 @@ func NewTracer(opts *options.Options) (*tracing.Tracer, error) {
 		return nil, err
 	}
 
-	var sampler sdktrace.Sampler
-
-	switch opts.SampleRate {
-	case 0:
-		sampler = sdktrace.NeverSample()
-	case 1:
-		sampler = sdktrace.AlwaysSample()
-	default:
-		sampler = sdktrace.ProbabilitySampler(opts.SampleRate)
+	sampler, err := sampling.New(opts)
+	if err != nil {
+		return nil, err
 	}
 
 	serviceKey := kv.String("service.name", opts.ServiceName)
@@ -78,7 +73,7 @@ func NewTracer(opts *options.Options) (*tracing.Tracer, error) {
 		tags = []kv.KeyValue{serviceKey}
 	}
 
-	tp, err := sdktrace.NewProvider(sdktrace.WithSyncer(exp),
+	tp, err := sdktrace.NewProvider(sdktrace.WithSyncer(sampler.WrapSyncer(exp)),
 		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sampler}),
 		sdktrace.WithResource(resource.New(tags...)),
 	)
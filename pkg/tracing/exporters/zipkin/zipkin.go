@@ -21,6 +21,7 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/tracing"
 	errs "github.com/tricksterproxy/trickster/pkg/tracing/errors"
 	"github.com/tricksterproxy/trickster/pkg/tracing/options"
+	"github.com/tricksterproxy/trickster/pkg/tracing/sampling"
 
 	"go.opentelemetry.io/otel/exporters/trace/zipkin"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
@@ -30,20 +31,14 @@ import (
 func NewTracer(options *options.Options) (*tracing.Tracer, error) {
 
 	var tp *sdktrace.Provider
-	var err error
 
 	if options == nil {
 		return nil, errs.ErrNoTracerOptions
 	}
 
-	var sampler sdktrace.Sampler
-	switch options.SampleRate {
-	case 0:
-		sampler = sdktrace.NeverSample()
-	case 1:
-		sampler = sdktrace.AlwaysSample()
-	default:
-		sampler = sdktrace.ProbabilitySampler(options.SampleRate)
+	sampler, err := sampling.New(options)
+	if err != nil {
+		return nil, err
 	}
 
 	exporter, err := zipkin.NewExporter(
@@ -56,7 +51,7 @@ func NewTracer(options *options.Options) (*tracing.Tracer, error) {
 
 	tp, err = sdktrace.NewProvider(
 		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sampler}),
-		sdktrace.WithBatcher(exporter,
+		sdktrace.WithBatcher(sampler.WrapBatcher(exporter),
 			sdktrace.WithBatchTimeout(5),
 			sdktrace.WithMaxExportBatchSize(10),
 		),
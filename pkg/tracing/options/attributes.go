@@ -0,0 +1,126 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Span Attribute Sources that are extracted from the client request
+const (
+	AttributeSourceHeader     = "header"
+	AttributeSourceParam      = "param"
+	AttributeSourceMethod     = "method"
+	AttributeSourcePath       = "path"
+	AttributeSourceOriginName = "origin_name"
+	AttributeSourceOriginType = "origin_type"
+)
+
+// Span Attribute Sources that are derived from the result of the request
+const (
+	AttributeSourceCacheStatus     = "cache_status"
+	AttributeSourceRequestedExtent = "requested_extent"
+	AttributeSourceRequestedSecs   = "requested_secs"
+	AttributeSourceFetchedExtents  = "fetched_extents"
+	AttributeSourceFetchedSecs     = "fetched_secs"
+	AttributeSourceFetchedCount    = "fetched_count"
+)
+
+var requestAttributeSources = map[string]bool{
+	AttributeSourceHeader:     true,
+	AttributeSourceParam:      true,
+	AttributeSourceMethod:     true,
+	AttributeSourcePath:       true,
+	AttributeSourceOriginName: true,
+	AttributeSourceOriginType: true,
+}
+
+var resultAttributeSources = map[string]bool{
+	AttributeSourceCacheStatus:     true,
+	AttributeSourceRequestedExtent: true,
+	AttributeSourceRequestedSecs:   true,
+	AttributeSourceFetchedExtents:  true,
+	AttributeSourceFetchedSecs:     true,
+	AttributeSourceFetchedCount:    true,
+}
+
+// SpanAttribute describes a custom attribute attached to spans, and where its value comes from
+type SpanAttribute struct {
+	// Name is the attribute key
+	Name string
+	// Source is the data source of the attribute's value
+	Source string
+	// Arg is the header or param name when the Source is header or param
+	Arg string
+}
+
+// parseSpanAttribute parses a source definition like 'origin_name' or 'header:X-Grafana-User'
+func parseSpanAttribute(name, definition string) (*SpanAttribute, error) {
+	a := &SpanAttribute{Name: name, Source: definition}
+	if i := strings.Index(definition, ":"); i > 0 {
+		a.Source = definition[:i]
+		a.Arg = definition[i+1:]
+	}
+	switch {
+	case a.Source == AttributeSourceHeader || a.Source == AttributeSourceParam:
+		if a.Arg == "" {
+			return nil, fmt.Errorf("span attribute [%s] source [%s] requires a name (e.g., '%s:name')",
+				name, a.Source, a.Source)
+		}
+	case requestAttributeSources[a.Source] || resultAttributeSources[a.Source]:
+		if a.Arg != "" {
+			return nil, fmt.Errorf("span attribute [%s] source [%s] does not accept a name",
+				name, a.Source)
+		}
+	default:
+		return nil, fmt.Errorf("invalid source [%s] for span attribute [%s]", definition, name)
+	}
+	return a, nil
+}
+
+func (o *Options) generateSpanAttributes() error {
+	o.SpanAttributes = nil
+	if len(o.SpanAttributeSources) == 0 {
+		return nil
+	}
+	o.SpanAttributes = make([]*SpanAttribute, 0, len(o.SpanAttributeSources))
+	for k, v := range o.SpanAttributeSources {
+		a, err := parseSpanAttribute(k, v)
+		if err != nil {
+			return fmt.Errorf("tracing config [%s]: %s", o.Name, err.Error())
+		}
+		o.SpanAttributes = append(o.SpanAttributes, a)
+	}
+	sort.Slice(o.SpanAttributes, func(i, j int) bool {
+		return o.SpanAttributes[i].Name < o.SpanAttributes[j].Name
+	})
+	return nil
+}
+
+func cloneSpanAttributes(attrs []*SpanAttribute) []*SpanAttribute {
+	if attrs == nil {
+		return nil
+	}
+	out := make([]*SpanAttribute, len(attrs))
+	for i, a := range attrs {
+		c := *a
+		out[i] = &c
+	}
+	return out
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateSpanAttributes(t *testing.T) {
+
+	tests := []struct {
+		sources  map[string]string
+		expected string
+		err      string
+	}{
+		{nil, "", ""},
+		{map[string]string{"b": "header:X-User", "a": "cache_status"}, "a,b", ""},
+		{map[string]string{"a": "header"}, "", "requires a name"},
+		{map[string]string{"a": "param:"}, "", "requires a name"},
+		{map[string]string{"a": "origin_name:foo"}, "", "does not accept a name"},
+		{map[string]string{"a": "foo"}, "", "invalid source [foo]"},
+	}
+
+	for _, test := range tests {
+		o := NewOptions()
+		o.Name = "test"
+		o.SpanAttributeSources = test.sources
+		err := o.generateSpanAttributes()
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("expected error containing %s got %v", test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		names := make([]string, len(o.SpanAttributes))
+		for i, a := range o.SpanAttributes {
+			names[i] = a.Name
+		}
+		if strings.Join(names, ",") != test.expected {
+			t.Errorf("expected %s got %s", test.expected, strings.Join(names, ","))
+		}
+	}
+
+}
+
+func TestProcessTracingOptionsSpanAttributes(t *testing.T) {
+	o := NewOptions()
+	o.SpanAttributeSources = map[string]string{"a": "foo"}
+	err := ProcessTracingOptions(map[string]*Options{"test": o}, nil)
+	if err == nil || !strings.Contains(err.Error(), "tracing config [test]") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
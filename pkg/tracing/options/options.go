@@ -17,29 +17,46 @@
 package options
 
 import (
+	"fmt"
+	gostrings "strings"
+
 	"github.com/BurntSushi/toml"
 	"github.com/tricksterproxy/trickster/pkg/config/defaults"
+	ddopts "github.com/tricksterproxy/trickster/pkg/tracing/exporters/datadog/options"
 	jaegeropts "github.com/tricksterproxy/trickster/pkg/tracing/exporters/jaeger/options"
+	otlpopts "github.com/tricksterproxy/trickster/pkg/tracing/exporters/otlp/options"
 	stdoutopts "github.com/tricksterproxy/trickster/pkg/tracing/exporters/stdout/options"
+	"github.com/tricksterproxy/trickster/pkg/tracing/propagation"
 	"github.com/tricksterproxy/trickster/pkg/util/strings"
 )
 
 // Options is a Tracing Options collection
 type Options struct {
-	Name          string            `toml:"-"`
-	TracerType    string            `toml:"tracer_type"`
-	ServiceName   string            `toml:"service_name"`
-	CollectorURL  string            `toml:"collector_url"`
-	CollectorUser string            `toml:"collector_user"`
-	CollectorPass string            `toml:"collector_pass"`
-	SampleRate    float64           `toml:"sample_rate"`
-	Tags          map[string]string `toml:"tags"`
-	OmitTagsList  []string          `toml:"omit_tags"`
+	Name                    string            `toml:"-"`
+	TracerType              string            `toml:"tracer_type"`
+	ServiceName             string            `toml:"service_name"`
+	CollectorURL            string            `toml:"collector_url"`
+	CollectorUser           string            `toml:"collector_user"`
+	CollectorPass           string            `toml:"collector_pass"`
+	SampleRate              float64           `toml:"sample_rate"`
+	Sampler                 string            `toml:"sampler"`
+	SampleRateLimit         float64           `toml:"sample_rate_limit"`
+	AlwaysSampleCacheMisses bool              `toml:"always_sample_cache_misses"`
+	AlwaysSampleErrors      bool              `toml:"always_sample_errors"`
+	Tags                    map[string]string `toml:"tags"`
+	OmitTagsList            []string          `toml:"omit_tags"`
+	SpanAttributeSources    map[string]string `toml:"span_attributes"`
+	Propagation             []string          `toml:"propagation"`
+	StartupMode             string            `toml:"startup_mode"`
 
-	StdOutOptions *stdoutopts.Options `toml:"stdout"`
-	JaegerOptions *jaegeropts.Options `toml:"jaeger"`
+	StdOutOptions  *stdoutopts.Options `toml:"stdout"`
+	JaegerOptions  *jaegeropts.Options `toml:"jaeger"`
+	OTLPOptions    *otlpopts.Options   `toml:"otlp"`
+	DatadogOptions *ddopts.Options     `toml:"datadog"`
 
 	OmitTags map[string]bool `toml:"-"`
+	// SpanAttributes is the parsed list of custom span attributes, sorted by name
+	SpanAttributes []*SpanAttribute `toml:"-"`
 	// for tracers that don't support WithProcess (e.g., Zipkin)
 	attachTagsToSpan bool
 }
@@ -47,10 +64,15 @@ type Options struct {
 // NewOptions returns a new *Options with the default values
 func NewOptions() *Options {
 	return &Options{
-		TracerType:    defaults.DefaultTracerType,
-		ServiceName:   defaults.DefaultTracerServiceName,
-		StdOutOptions: &stdoutopts.Options{},
-		JaegerOptions: &jaegeropts.Options{},
+		TracerType:     defaults.DefaultTracerType,
+		ServiceName:    defaults.DefaultTracerServiceName,
+		Sampler:        defaults.DefaultTracerSampler,
+		Propagation:    strings.CloneList(propagation.DefaultFormats),
+		StartupMode:    defaults.DefaultTracerStartupMode,
+		StdOutOptions:  &stdoutopts.Options{},
+		JaegerOptions:  &jaegeropts.Options{},
+		OTLPOptions:    otlpopts.NewOptions(),
+		DatadogOptions: ddopts.NewOptions(),
 	}
 }
 
@@ -64,27 +86,45 @@ func (o *Options) Clone() *Options {
 	if o.JaegerOptions != nil {
 		jo = o.JaegerOptions.Clone()
 	}
+	var oo *otlpopts.Options
+	if o.OTLPOptions != nil {
+		oo = o.OTLPOptions.Clone()
+	}
+	var do *ddopts.Options
+	if o.DatadogOptions != nil {
+		do = o.DatadogOptions.Clone()
+	}
 	return &Options{
-		Name:             o.Name,
-		TracerType:       o.TracerType,
-		ServiceName:      o.ServiceName,
-		CollectorURL:     o.CollectorURL,
-		CollectorUser:    o.CollectorUser,
-		CollectorPass:    o.CollectorPass,
-		SampleRate:       o.SampleRate,
-		Tags:             strings.CloneMap(o.Tags),
-		OmitTags:         strings.CloneBoolMap(o.OmitTags),
-		OmitTagsList:     strings.CloneList(o.OmitTagsList),
-		StdOutOptions:    so,
-		JaegerOptions:    jo,
-		attachTagsToSpan: o.attachTagsToSpan,
+		Name:                    o.Name,
+		TracerType:              o.TracerType,
+		ServiceName:             o.ServiceName,
+		CollectorURL:            o.CollectorURL,
+		CollectorUser:           o.CollectorUser,
+		CollectorPass:           o.CollectorPass,
+		SampleRate:              o.SampleRate,
+		Sampler:                 o.Sampler,
+		SampleRateLimit:         o.SampleRateLimit,
+		AlwaysSampleCacheMisses: o.AlwaysSampleCacheMisses,
+		AlwaysSampleErrors:      o.AlwaysSampleErrors,
+		Tags:                    strings.CloneMap(o.Tags),
+		OmitTags:                strings.CloneBoolMap(o.OmitTags),
+		OmitTagsList:            strings.CloneList(o.OmitTagsList),
+		SpanAttributeSources:    strings.CloneMap(o.SpanAttributeSources),
+		SpanAttributes:          cloneSpanAttributes(o.SpanAttributes),
+		Propagation:             strings.CloneList(o.Propagation),
+		StartupMode:             o.StartupMode,
+		StdOutOptions:           so,
+		JaegerOptions:           jo,
+		OTLPOptions:             oo,
+		DatadogOptions:          do,
+		attachTagsToSpan:        o.attachTagsToSpan,
 	}
 }
 
 // ProcessTracingOptions enriches the configuration data of the provided Tracing Options collection
-func ProcessTracingOptions(mo map[string]*Options, metadata *toml.MetaData) {
+func ProcessTracingOptions(mo map[string]*Options, metadata *toml.MetaData) error {
 	if len(mo) == 0 {
-		return
+		return nil
 	}
 	for k, v := range mo {
 		if metadata != nil {
@@ -97,10 +137,65 @@ func ProcessTracingOptions(mo map[string]*Options, metadata *toml.MetaData) {
 			if !metadata.IsDefined("tracing", k, "tracer_type") {
 				v.TracerType = defaults.DefaultTracerType
 			}
+			if !metadata.IsDefined("tracing", k, "sampler") {
+				v.Sampler = defaults.DefaultTracerSampler
+			}
+			if !metadata.IsDefined("tracing", k, "startup_mode") {
+				v.StartupMode = defaults.DefaultTracerStartupMode
+			}
 		}
 		v.generateOmitTags()
 		v.setAttachTags()
+		if v.Name == "" {
+			v.Name = k
+		}
+		if err := v.generateSpanAttributes(); err != nil {
+			return err
+		}
+		if err := v.validatePropagation(); err != nil {
+			return err
+		}
+		if err := v.validateStartupMode(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Startup modes determine how a tracer is served when its collector is unreachable
+// when the config is loaded
+const (
+	// StartupModeFail fails the config load when the collector is unreachable
+	StartupModeFail = "fail"
+	// StartupModeRetry logs a warning when the collector is unreachable, and checks it
+	// with backoff in the background until it is reachable
+	StartupModeRetry = "retry"
+	// StartupModeLazy does not check the collector, which the exporter connects to when
+	// it exports its first spans
+	StartupModeLazy = "lazy"
+)
+
+func (o *Options) validateStartupMode() error {
+	o.StartupMode = gostrings.ToLower(o.StartupMode)
+	switch o.StartupMode {
+	case "":
+		o.StartupMode = defaults.DefaultTracerStartupMode
+	case StartupModeFail, StartupModeRetry, StartupModeLazy:
+	default:
+		return fmt.Errorf("tracing config [%s]: invalid startup mode: %s", o.Name, o.StartupMode)
+	}
+	return nil
+}
+
+func (o *Options) validatePropagation() error {
+	if len(o.Propagation) == 0 {
+		o.Propagation = strings.CloneList(propagation.DefaultFormats)
+		return nil
+	}
+	if err := propagation.Validate(o.Propagation); err != nil {
+		return fmt.Errorf("tracing config [%s]: %s", o.Name, err.Error())
 	}
+	return nil
 }
 
 func (o *Options) generateOmitTags() {
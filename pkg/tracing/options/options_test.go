@@ -17,6 +17,7 @@
 package options
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/BurntSushi/toml"
@@ -37,6 +38,7 @@ func TestProcessTracingConfigs(t *testing.T) {
 
 	o := NewOptions()
 	o.SampleRate = 0
+	o.Sampler = ""
 
 	mo := map[string]*Options{
 		"test": o,
@@ -48,6 +50,10 @@ func TestProcessTracingConfigs(t *testing.T) {
 		t.Errorf("expected 1 got %d", int(o.SampleRate))
 	}
 
+	if o.Sampler != "ratio" {
+		t.Errorf("expected %s got %s", "ratio", o.Sampler)
+	}
+
 }
 
 func TestGenerateOmitTags(t *testing.T) {
@@ -71,3 +77,37 @@ func TestAttachTagsToSpan(t *testing.T) {
 	}
 
 }
+
+func TestProcessTracingOptionsPropagation(t *testing.T) {
+	o := NewOptions()
+	o.Propagation = nil
+	err := ProcessTracingOptions(map[string]*Options{"test": o}, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(o.Propagation) != 2 {
+		t.Errorf("expected 2 got %d", len(o.Propagation))
+	}
+	o.Propagation = []string{"b3", "foo"}
+	err = ProcessTracingOptions(map[string]*Options{"test": o}, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid propagation format [foo]") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestProcessTracingOptionsStartupMode(t *testing.T) {
+	o := NewOptions()
+	o.StartupMode = "RETRY"
+	err := ProcessTracingOptions(map[string]*Options{"test": o}, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if o.StartupMode != StartupModeRetry {
+		t.Errorf("expected %s got %s", StartupModeRetry, o.StartupMode)
+	}
+	o.StartupMode = "foo"
+	err = ProcessTracingOptions(map[string]*Options{"test": o}, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid startup mode: foo") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package propagation provides the trace context propagation formats used by
+// Trickster to continue incoming traces and to pass them along to origins
+package propagation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/api/correlation"
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/propagation"
+	"go.opentelemetry.io/otel/api/trace"
+)
+
+const (
+	// FormatTraceContext is the W3C Trace Context format (traceparent and tracestate headers)
+	FormatTraceContext = "tracecontext"
+	// FormatB3 is the single-header B3 format (X-B3 header)
+	FormatB3 = "b3"
+	// FormatB3Multi is the multi-header B3 format (X-B3-TraceId, X-B3-SpanId, etc.)
+	FormatB3Multi = "b3multi"
+	// FormatCorrelation is the W3C Correlation Context format (Correlation-Context header)
+	FormatCorrelation = "correlation"
+)
+
+// Names is a map of propagators keyed by format name
+var Names = map[string]propagation.HTTPPropagator{
+	FormatTraceContext: trace.TraceContext{},
+	FormatB3:           trace.B3{SingleHeader: true},
+	FormatB3Multi:      trace.B3{},
+	FormatCorrelation:  correlation.CorrelationContext{},
+}
+
+// DefaultFormats is the list of formats used when none are configured
+var DefaultFormats = []string{FormatTraceContext, FormatCorrelation}
+
+// New returns a Propagators that extracts and injects the provided formats.
+// When an incoming request carries more than one format, the format listed
+// first takes precedence. An empty list uses DefaultFormats.
+func New(formats []string) (propagation.Propagators, error) {
+	if len(formats) == 0 {
+		formats = DefaultFormats
+	}
+	if err := Validate(formats); err != nil {
+		return nil, err
+	}
+	props := make([]propagation.HTTPPropagator, len(formats))
+	for i, f := range formats {
+		props[i] = Names[f]
+	}
+	// extractors run in order and each overwrites the span context of the ones
+	// before it, so they are registered in reverse to let the first format win
+	ex := make([]propagation.HTTPExtractor, len(props))
+	in := make([]propagation.HTTPInjector, len(props))
+	for i, p := range props {
+		ex[len(props)-1-i] = p
+		in[i] = p
+	}
+	return propagation.New(propagation.WithExtractors(ex...),
+		propagation.WithInjectors(in...)), nil
+}
+
+// Validate returns an error if any of the provided formats is not supported
+func Validate(formats []string) error {
+	for _, f := range formats {
+		if _, ok := Names[f]; !ok {
+			return fmt.Errorf("invalid propagation format [%s]", f)
+		}
+	}
+	return nil
+}
+
+func orGlobal(props propagation.Propagators) propagation.Propagators {
+	if props == nil {
+		return global.Propagators()
+	}
+	return props
+}
+
+// Extract returns a copy of ctx that includes the remote span context and
+// correlation entries found in the headers. When props is nil, the global
+// propagators are used.
+func Extract(ctx context.Context, props propagation.Propagators,
+	h http.Header) context.Context {
+	return propagation.ExtractHTTP(ctx, orGlobal(props), h)
+}
+
+// Inject writes the span context in ctx to the headers. When props is nil,
+// the global propagators are used. Any existing headers for those formats are removed first, so an
+// origin never sees a mix of the client's and Trickster's span contexts.
+// Headers for formats that are not configured pass through untouched.
+func Inject(ctx context.Context, props propagation.Propagators, h http.Header) {
+	props = orGlobal(props)
+	for _, in := range props.HTTPInjectors() {
+		if p, ok := in.(propagation.HTTPPropagator); ok {
+			for _, k := range p.GetAllKeys() {
+				h.Del(k)
+			}
+		}
+		if b3, ok := in.(trace.B3); ok && !b3.SingleHeader {
+			h.Del(trace.B3ParentSpanIDHeader)
+			h.Del(trace.B3DebugFlagHeader)
+		}
+	}
+	propagation.InjectHTTP(ctx, props, h)
+}
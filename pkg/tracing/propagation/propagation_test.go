@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package propagation
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel/api/trace"
+)
+
+const (
+	testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID  = "00f067aa0ba902b7"
+)
+
+type testSpan struct {
+	trace.NoopSpan
+	sc trace.SpanContext
+}
+
+func (s *testSpan) SpanContext() trace.SpanContext {
+	return s.sc
+}
+
+func TestNew(t *testing.T) {
+	p, err := New(nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(p.HTTPInjectors()) != len(DefaultFormats) {
+		t.Errorf("expected %d got %d", len(DefaultFormats), len(p.HTTPInjectors()))
+	}
+	_, err = New([]string{FormatB3, "foo"})
+	if err == nil || err.Error() != "invalid propagation format [foo]" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestExtract(t *testing.T) {
+
+	h := http.Header{}
+	h.Set("traceparent", "00-"+testTraceID+"-"+testSpanID+"-01")
+	h.Set(trace.B3SingleHeader, "a3ce929d0e0e47364bf92f3577b34da6-a3ce929d0e0e4736-1")
+
+	tests := []struct {
+		formats  []string
+		expected string
+	}{
+		{[]string{FormatTraceContext}, testTraceID},
+		{[]string{FormatB3}, "a3ce929d0e0e47364bf92f3577b34da6"},
+		{[]string{FormatTraceContext, FormatB3}, testTraceID},
+		{[]string{FormatB3, FormatTraceContext}, "a3ce929d0e0e47364bf92f3577b34da6"},
+		{[]string{FormatB3Multi}, ""},
+	}
+
+	for i, test := range tests {
+		p, err := New(test.formats)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sc := trace.RemoteSpanContextFromContext(Extract(context.Background(), p, h))
+		if test.expected == "" {
+			if sc.IsValid() {
+				t.Errorf("test %d: expected invalid span context", i)
+			}
+			continue
+		}
+		if sc.TraceID.String() != test.expected {
+			t.Errorf("test %d: expected %s got %s", i, test.expected, sc.TraceID.String())
+		}
+	}
+}
+
+func TestInject(t *testing.T) {
+
+	tid, _ := trace.IDFromHex(testTraceID)
+	sid, _ := trace.SpanIDFromHex(testSpanID)
+	ctx := trace.ContextWithSpan(context.Background(), &testSpan{
+		sc: trace.SpanContext{TraceID: tid, SpanID: sid, TraceFlags: trace.FlagsSampled}})
+
+	p, err := New([]string{FormatB3Multi})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := http.Header{}
+	h.Set(trace.B3ParentSpanIDHeader, "a3ce929d0e0e4736")
+	h.Set(trace.B3SpanIDHeader, "a3ce929d0e0e4736")
+	h.Set("traceparent", "00-"+testTraceID+"-a3ce929d0e0e4736-01")
+
+	Inject(ctx, p, h)
+
+	if v := h.Get(trace.B3SpanIDHeader); v != testSpanID {
+		t.Errorf("expected %s got %s", testSpanID, v)
+	}
+	if v := h.Get(trace.B3TraceIDHeader); v != testTraceID {
+		t.Errorf("expected %s got %s", testTraceID, v)
+	}
+	if v := h.Get(trace.B3ParentSpanIDHeader); v != "" {
+		t.Errorf("expected empty parent span id got %s", v)
+	}
+	// formats that are not configured pass through untouched
+	if v := h.Get("traceparent"); v != "00-"+testTraceID+"-a3ce929d0e0e4736-01" {
+		t.Errorf("unexpected traceparent %s", v)
+	}
+}
@@ -23,11 +23,14 @@ import (
 
 	"github.com/tricksterproxy/trickster/pkg/config"
 	"github.com/tricksterproxy/trickster/pkg/tracing"
+	"github.com/tricksterproxy/trickster/pkg/tracing/exporters/datadog"
 	"github.com/tricksterproxy/trickster/pkg/tracing/exporters/jaeger"
 	"github.com/tricksterproxy/trickster/pkg/tracing/exporters/noop"
+	"github.com/tricksterproxy/trickster/pkg/tracing/exporters/otlp"
 	"github.com/tricksterproxy/trickster/pkg/tracing/exporters/stdout"
 	"github.com/tricksterproxy/trickster/pkg/tracing/exporters/zipkin"
 	"github.com/tricksterproxy/trickster/pkg/tracing/options"
+	"github.com/tricksterproxy/trickster/pkg/tracing/propagation"
 	"github.com/tricksterproxy/trickster/pkg/tracing/types"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
 	"github.com/tricksterproxy/trickster/pkg/util/strings"
@@ -73,10 +76,23 @@ func RegisterAll(cfg *config.Config, log *tl.Logger, isDryRun bool) (tracing.Tra
 			return nil, fmt.Errorf("invalid tracer type [%s] for tracing config [%s]",
 				tc.TracerType, k)
 		}
+		var probeErr error
+		if !isDryRun && tc.StartupMode != options.StartupModeLazy {
+			probeErr = probeCollector(tc)
+			if probeErr != nil && tc.StartupMode == options.StartupModeFail {
+				return nil, fmt.Errorf("tracing config [%s]: collector %s is unreachable: %v",
+					k, tc.CollectorURL, probeErr)
+			}
+		}
 		tracer, err := GetTracer(tc, log, isDryRun)
 		if err != nil {
 			return nil, err
 		}
+		if probeErr != nil && tracer != nil {
+			log.Warn("tracer collector is unreachable, retrying in the background",
+				tl.Pairs{"name": k, "collectorURL": tc.CollectorURL, "detail": probeErr.Error()})
+			probeUntilReachable(tracer, log)
+		}
 		tracers[k] = tracer
 	}
 	return tracers, nil
@@ -107,17 +123,35 @@ func GetTracer(options *options.Options, log *tl.Logger, isDryRun bool) (*tracin
 		)
 	}
 
+	var tracer *tracing.Tracer
+	var err error
+
 	switch options.TracerType {
 	case types.TracerTypeStdout.String():
 		logTracerRegistration()
-		return stdout.NewTracer(options)
+		tracer, err = stdout.NewTracer(options)
 	case types.TracerTypeJaeger.String():
 		logTracerRegistration()
-		return jaeger.NewTracer(options)
+		tracer, err = jaeger.NewTracer(options)
 	case types.TracerTypeZipkin.String():
 		logTracerRegistration()
-		return zipkin.NewTracer(options)
+		tracer, err = zipkin.NewTracer(options)
+	case types.TracerTypeOTLP.String():
+		logTracerRegistration()
+		tracer, err = otlp.NewTracer(options)
+	case types.TracerTypeDatadog.String():
+		logTracerRegistration()
+		tracer, err = datadog.NewTracer(options)
+	}
+
+	if err != nil || tracer == nil {
+		return tracer, err
+	}
+
+	tracer.Propagators, err = propagation.New(options.Propagation)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, nil
+	return tracer, nil
 }
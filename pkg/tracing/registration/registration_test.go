@@ -77,6 +77,18 @@ func TestRegisterAll(t *testing.T) {
 		t.Error(err)
 	}
 
+	tc.TracerType = "otlp"
+	_, err = RegisterAll(cfg, tl.ConsoleLogger("error"), true)
+	if err != nil {
+		t.Error(err)
+	}
+
+	tc.TracerType = "datadog"
+	_, err = RegisterAll(cfg, tl.ConsoleLogger("error"), true)
+	if err != nil {
+		t.Error(err)
+	}
+
 	tc.TracerType = "foo"
 
 	_, err = RegisterAll(cfg, tl.ConsoleLogger("error"), true)
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registration
+
+import (
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/tracing"
+	"github.com/tricksterproxy/trickster/pkg/tracing/options"
+	"github.com/tricksterproxy/trickster/pkg/tracing/types"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// probeTimeout is the maximum time to wait when dialing a tracer's collector
+var probeTimeout = 3 * time.Second
+
+// bounds of the backoff between collector probes in the retry startup mode
+var minProbeBackoff, maxProbeBackoff = time.Second, time.Minute
+
+// collectorAddress returns the host:port of the tracer's collector, or an empty
+// string when the tracer has no collector that can be probed over TCP
+func collectorAddress(o *options.Options) string {
+	if o.CollectorURL == "" {
+		return ""
+	}
+	switch o.TracerType {
+	case types.TracerTypeNone.String(), types.TracerTypeStdout.String():
+		return ""
+	case types.TracerTypeJaeger.String():
+		// the jaeger agent endpoint is UDP, so there is no connection to probe
+		if o.JaegerOptions != nil && o.JaegerOptions.EndpointType == "agent" {
+			return ""
+		}
+	}
+	u, err := url.Parse(o.CollectorURL)
+	if err != nil || u.Host == "" {
+		// collector urls such as host:port have no scheme
+		return o.CollectorURL
+	}
+	if u.Port() != "" {
+		return u.Host
+	}
+	switch u.Scheme {
+	case "https":
+		return net.JoinHostPort(u.Hostname(), "443")
+	default:
+		return net.JoinHostPort(u.Hostname(), "80")
+	}
+}
+
+// probeCollector returns an error if the tracer's collector is not accepting connections
+func probeCollector(o *options.Options) error {
+	addr := collectorAddress(o)
+	if addr == "" {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", addr, probeTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probeUntilReachable probes the tracer's collector with backoff until it is reachable,
+// and stops probing once the tracer is flushed
+func probeUntilReachable(tracer *tracing.Tracer, log *tl.Logger) {
+	quit := make(chan struct{})
+	var once sync.Once
+	flush := tracer.Flusher
+	tracer.Flusher = func() {
+		once.Do(func() { close(quit) })
+		if flush != nil {
+			flush()
+		}
+	}
+	o := tracer.Options
+	go func() {
+		backoff := minProbeBackoff
+		for {
+			select {
+			case <-quit:
+				return
+			case <-time.After(backoff):
+			}
+			if err := probeCollector(o); err == nil {
+				log.Info("tracer collector is reachable",
+					tl.Pairs{"name": o.Name, "collectorURL": o.CollectorURL})
+				return
+			}
+			backoff *= 2
+			if backoff > maxProbeBackoff {
+				backoff = maxProbeBackoff
+			}
+		}
+	}()
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package registration
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/tracing/options"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// unusedAddress returns the address of a TCP listener that has been closed
+func unusedAddress(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func TestCollectorAddress(t *testing.T) {
+	tests := []struct {
+		tracerType, endpointType, url, expected string
+	}{
+		{"zipkin", "", "", ""},
+		{"stdout", "", "http://example.com", ""},
+		{"jaeger", "agent", "127.0.0.1:6831", ""},
+		{"jaeger", "", "http://example.com/api/traces", "example.com:80"},
+		{"zipkin", "", "https://example.com/api/v2/spans", "example.com:443"},
+		{"otlp", "", "http://127.0.0.1:4318", "127.0.0.1:4318"},
+		{"jaeger", "", "127.0.0.1:8000", "127.0.0.1:8000"},
+	}
+	for i, test := range tests {
+		o := options.NewOptions()
+		o.TracerType = test.tracerType
+		o.JaegerOptions.EndpointType = test.endpointType
+		o.CollectorURL = test.url
+		if v := collectorAddress(o); v != test.expected {
+			t.Errorf("test %d: expected %s got %s", i, test.expected, v)
+		}
+	}
+}
+
+func TestRegisterAllStartupModes(t *testing.T) {
+
+	probeTimeout = 500 * time.Millisecond
+	minProbeBackoff = 10 * time.Millisecond
+	defer func() {
+		probeTimeout = 3 * time.Second
+		minProbeBackoff = time.Second
+	}()
+
+	cfg := config.NewConfig()
+	tc := options.NewOptions()
+	tc.TracerType = "zipkin"
+	tc.CollectorURL = "http://" + unusedAddress(t) + "/api/v2/spans"
+	cfg.TracingConfigs = map[string]*options.Options{"test": tc}
+	cfg.Origins["default"].TracingConfigName = "test"
+
+	tc.StartupMode = options.StartupModeFail
+	_, err := RegisterAll(cfg, tl.ConsoleLogger("error"), false)
+	if err == nil {
+		t.Error("expected error for unreachable collector")
+	}
+
+	// dry runs do not probe the collector
+	_, err = RegisterAll(cfg, tl.ConsoleLogger("error"), true)
+	if err != nil {
+		t.Error(err)
+	}
+
+	tc.StartupMode = options.StartupModeLazy
+	_, err = RegisterAll(cfg, tl.ConsoleLogger("error"), false)
+	if err != nil {
+		t.Error(err)
+	}
+
+	tc.StartupMode = options.StartupModeRetry
+	tracers, err := RegisterAll(cfg, tl.ConsoleLogger("error"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// flushing the tracer stops the background probe, and may be called repeatedly
+	tracers["test"].Flusher()
+	tracers["test"].Flusher()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	tc.CollectorURL = "http://" + l.Addr().String() + "/api/v2/spans"
+	tc.StartupMode = options.StartupModeFail
+	_, err = RegisterAll(cfg, tl.ConsoleLogger("error"), false)
+	if err != nil {
+		t.Error(err)
+	}
+}
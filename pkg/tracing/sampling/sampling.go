@@ -0,0 +1,337 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package sampling provides the trace sampling strategies available to Trickster tracers
+package sampling
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	"github.com/tricksterproxy/trickster/pkg/tracing/options"
+
+	apitrace "go.opentelemetry.io/otel/api/trace"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/codes"
+)
+
+// Supported Sampler names
+const (
+	// SamplerRatio samples the sample_rate fraction of new traces, and any trace
+	// whose remote parent span was sampled
+	SamplerRatio = "ratio"
+	// SamplerParent follows the sampling decision of the remote parent span, and
+	// samples the sample_rate fraction of traces that have no remote parent
+	SamplerParent = "parent"
+	// SamplerRateLimited samples at most sample_rate_limit new traces per second,
+	// and any trace whose remote parent span was sampled
+	SamplerRateLimited = "rate_limited"
+)
+
+// maxPendingTraces limits the number of in-flight traces buffered while
+// awaiting a sampling decision
+const maxPendingTraces = 10000
+
+// Sampler is a sdktrace.Sampler that implements the configured sampling strategy.
+// When the options call for always sampling cache misses or errors, the Sampler
+// records every trace, and defers the export decision until the trace's local
+// root span ends, by way of the exporter wrappers provided by WrapSyncer and WrapBatcher
+type Sampler struct {
+	head          sdktrace.Sampler
+	description   string
+	sampleMisses  bool
+	sampleErrors  bool
+	mtx           sync.Mutex
+	headDecisions map[apitrace.ID]bool
+	pending       map[apitrace.ID][]*export.SpanData
+	decided       map[apitrace.ID]bool
+}
+
+// New returns a new *Sampler based on the provided options
+func New(o *options.Options) (*Sampler, error) {
+
+	if o == nil {
+		return nil, fmt.Errorf("no tracing options provided")
+	}
+
+	s := &Sampler{
+		sampleMisses: o.AlwaysSampleCacheMisses,
+		sampleErrors: o.AlwaysSampleErrors,
+	}
+
+	var root sdktrace.Sampler
+	switch o.SampleRate {
+	case 0:
+		root = sdktrace.NeverSample()
+	case 1:
+		root = sdktrace.AlwaysSample()
+	default:
+		root = sdktrace.ProbabilitySampler(o.SampleRate)
+	}
+
+	switch o.Sampler {
+	case SamplerRatio, "":
+		s.head = root
+	case SamplerParent:
+		s.head = &parentSampler{root: root}
+	case SamplerRateLimited:
+		if o.SampleRateLimit <= 0 {
+			return nil, fmt.Errorf("invalid sample_rate_limit %g for tracing config %s",
+				o.SampleRateLimit, o.Name)
+		}
+		s.head = newRateLimitedSampler(o.SampleRateLimit)
+	default:
+		return nil, fmt.Errorf("invalid sampler %s for tracing config %s", o.Sampler, o.Name)
+	}
+
+	s.description = s.head.Description()
+	if s.isDeferred() {
+		s.headDecisions = make(map[apitrace.ID]bool)
+		s.pending = make(map[apitrace.ID][]*export.SpanData)
+		s.decided = make(map[apitrace.ID]bool)
+		s.description = fmt.Sprintf("DeferredSampler{%s,misses=%t,errors=%t}",
+			s.description, s.sampleMisses, s.sampleErrors)
+	}
+
+	return s, nil
+}
+
+func (s *Sampler) isDeferred() bool {
+	return s.sampleMisses || s.sampleErrors
+}
+
+// ShouldSample implements sdktrace.Sampler
+func (s *Sampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	r := s.head.ShouldSample(p)
+	if !s.isDeferred() {
+		return r
+	}
+	// record everything, and remember the head decision for when the trace completes
+	s.mtx.Lock()
+	if len(s.headDecisions) < maxPendingTraces {
+		s.headDecisions[p.TraceID] = r.Decision == sdktrace.RecordAndSampled
+	}
+	s.mtx.Unlock()
+	return sdktrace.SamplingResult{Decision: sdktrace.RecordAndSampled}
+}
+
+// Description implements sdktrace.Sampler
+func (s *Sampler) Description() string {
+	return s.description
+}
+
+// isLocalRoot returns true if the span is the root of the trace within this process
+func isLocalRoot(sd *export.SpanData) bool {
+	return sd.HasRemoteParent || !sd.ParentSpanID.IsValid()
+}
+
+// isNotable returns true if the span qualifies its trace for export regardless
+// of the head sampling decision
+func (s *Sampler) isNotable(sd *export.SpanData) bool {
+	if s.sampleErrors && sd.StatusCode != codes.OK {
+		return true
+	}
+	for _, a := range sd.Attributes {
+		if a.Key != "cache.status" {
+			continue
+		}
+		switch a.Value.AsString() {
+		case status.LookupStatusKeyMiss.String(), status.LookupStatusRangeMiss.String():
+			if s.sampleMisses {
+				return true
+			}
+		case status.LookupStatusProxyError.String(), status.LookupStatusError.String():
+			if s.sampleErrors {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filter accepts an ended span, and returns the spans that are ready to be exported
+func (s *Sampler) filter(sd *export.SpanData) []*export.SpanData {
+
+	if sd == nil {
+		return nil
+	}
+	if !s.isDeferred() {
+		return []*export.SpanData{sd}
+	}
+
+	id := sd.SpanContext.TraceID
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	// spans ending after their local root follow the decision already made for the trace
+	if keep, ok := s.decided[id]; ok {
+		if keep || s.isNotable(sd) {
+			return []*export.SpanData{sd}
+		}
+		return nil
+	}
+
+	spans := append(s.pending[id], sd)
+
+	if !isLocalRoot(sd) {
+		if _, ok := s.pending[id]; ok || len(s.pending) < maxPendingTraces {
+			s.pending[id] = spans
+			return nil
+		}
+		// the buffer is full, so fall back to the head decision
+		if s.headDecisions[id] || s.isNotable(sd) {
+			return spans
+		}
+		return nil
+	}
+
+	keep := s.headDecisions[id]
+	for _, v := range spans {
+		if keep {
+			break
+		}
+		keep = s.isNotable(v)
+	}
+	delete(s.pending, id)
+	delete(s.headDecisions, id)
+	if len(s.decided) >= maxPendingTraces {
+		s.decided = make(map[apitrace.ID]bool)
+	}
+	s.decided[id] = keep
+
+	if keep {
+		return spans
+	}
+	return nil
+}
+
+// WrapSyncer returns a SpanSyncer that applies the Sampler's deferred
+// decisions before handing spans to the provided SpanSyncer
+func (s *Sampler) WrapSyncer(e export.SpanSyncer) export.SpanSyncer {
+	if !s.isDeferred() {
+		return e
+	}
+	return &syncer{s: s, e: e}
+}
+
+// WrapBatcher returns a SpanBatcher that applies the Sampler's deferred
+// decisions before handing spans to the provided SpanBatcher
+func (s *Sampler) WrapBatcher(e export.SpanBatcher) export.SpanBatcher {
+	if !s.isDeferred() {
+		return e
+	}
+	return &batcher{s: s, e: e}
+}
+
+type syncer struct {
+	s *Sampler
+	e export.SpanSyncer
+}
+
+func (w *syncer) ExportSpan(ctx context.Context, sd *export.SpanData) {
+	for _, v := range w.s.filter(sd) {
+		w.e.ExportSpan(ctx, v)
+	}
+}
+
+type batcher struct {
+	s *Sampler
+	e export.SpanBatcher
+}
+
+func (w *batcher) ExportSpans(ctx context.Context, batch []*export.SpanData) {
+	out := make([]*export.SpanData, 0, len(batch))
+	for _, sd := range batch {
+		out = append(out, w.s.filter(sd)...)
+	}
+	if len(out) > 0 {
+		w.e.ExportSpans(ctx, out)
+	}
+}
+
+// parentSampler follows the decision of a remote parent, and otherwise defers
+// to the root sampler
+type parentSampler struct {
+	root sdktrace.Sampler
+}
+
+func (ps *parentSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if p.ParentContext.IsValid() {
+		if p.ParentContext.IsSampled() {
+			return sdktrace.SamplingResult{Decision: sdktrace.RecordAndSampled}
+		}
+		return sdktrace.SamplingResult{Decision: sdktrace.NotRecord}
+	}
+	return ps.root.ShouldSample(p)
+}
+
+func (ps *parentSampler) Description() string {
+	return fmt.Sprintf("ParentSampler{%s}", ps.root.Description())
+}
+
+// rateLimitedSampler is a token bucket that samples up to limit new traces per second
+type rateLimitedSampler struct {
+	limit  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+	mtx    sync.Mutex
+}
+
+func newRateLimitedSampler(limit float64) *rateLimitedSampler {
+	burst := limit
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimitedSampler{
+		limit:  limit,
+		tokens: burst,
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+func (rs *rateLimitedSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if p.ParentContext.IsSampled() {
+		return sdktrace.SamplingResult{Decision: sdktrace.RecordAndSampled}
+	}
+	rs.mtx.Lock()
+	defer rs.mtx.Unlock()
+	now := rs.now()
+	burst := rs.limit
+	if burst < 1 {
+		burst = 1
+	}
+	rs.tokens += now.Sub(rs.last).Seconds() * rs.limit
+	if rs.tokens > burst {
+		rs.tokens = burst
+	}
+	rs.last = now
+	if rs.tokens < 1 {
+		return sdktrace.SamplingResult{Decision: sdktrace.NotRecord}
+	}
+	rs.tokens--
+	return sdktrace.SamplingResult{Decision: sdktrace.RecordAndSampled}
+}
+
+func (rs *rateLimitedSampler) Description() string {
+	return fmt.Sprintf("RateLimitedSampler{%g}", rs.limit)
+}
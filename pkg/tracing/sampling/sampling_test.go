@@ -0,0 +1,264 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sampling
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/tracing/options"
+
+	"go.opentelemetry.io/otel/api/kv"
+	apitrace "go.opentelemetry.io/otel/api/trace"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"google.golang.org/grpc/codes"
+)
+
+type testExporter struct {
+	spans []*export.SpanData
+}
+
+func (e *testExporter) ExportSpan(ctx context.Context, sd *export.SpanData) {
+	e.spans = append(e.spans, sd)
+}
+
+func (e *testExporter) ExportSpans(ctx context.Context, batch []*export.SpanData) {
+	e.spans = append(e.spans, batch...)
+}
+
+func testTraceID(b byte) apitrace.ID {
+	return apitrace.ID{b, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+}
+
+func testSpan(trace apitrace.ID, span, parent byte, attrs ...kv.KeyValue) *export.SpanData {
+	sd := &export.SpanData{
+		SpanContext: apitrace.SpanContext{TraceID: trace, SpanID: apitrace.SpanID{span}},
+		Attributes:  attrs,
+	}
+	if parent > 0 {
+		sd.ParentSpanID = apitrace.SpanID{parent}
+	}
+	return sd
+}
+
+func TestNew(t *testing.T) {
+
+	_, err := New(nil)
+	if err == nil {
+		t.Error("expected error for nil options")
+	}
+
+	tests := []struct {
+		sampler     string
+		rate, limit float64
+		misses      bool
+		expected    string
+		expectErr   bool
+	}{
+		{"", 1, 0, false, "AlwaysOnSampler", false},
+		{"ratio", 0, 0, false, "AlwaysOffSampler", false},
+		{"ratio", 0.5, 0, false, "ProbabilitySampler{0.5}", false},
+		{"parent", 1, 0, false, "ParentSampler{AlwaysOnSampler}", false},
+		{"rate_limited", 1, 10, false, "RateLimitedSampler{10}", false},
+		{"rate_limited", 1, 0, false, "", true},
+		{"ratio", 1, 0, true, "DeferredSampler{AlwaysOnSampler,misses=true,errors=false}", false},
+		{"foo", 1, 0, false, "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.sampler, func(t *testing.T) {
+			o := options.NewOptions()
+			o.Sampler = test.sampler
+			o.SampleRate = test.rate
+			o.SampleRateLimit = test.limit
+			o.AlwaysSampleCacheMisses = test.misses
+			s, err := New(o)
+			if test.expectErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Description() != test.expected {
+				t.Errorf("expected %s got %s", test.expected, s.Description())
+			}
+		})
+	}
+}
+
+func TestParentSampler(t *testing.T) {
+
+	ps := &parentSampler{root: sdktrace.NeverSample()}
+
+	r := ps.ShouldSample(sdktrace.SamplingParameters{})
+	if r.Decision != sdktrace.NotRecord {
+		t.Error("expected root sampler decision")
+	}
+
+	parent := apitrace.SpanContext{TraceID: testTraceID(1), SpanID: apitrace.SpanID{1}}
+	r = ps.ShouldSample(sdktrace.SamplingParameters{ParentContext: parent})
+	if r.Decision != sdktrace.NotRecord {
+		t.Error("expected unsampled parent to not be sampled")
+	}
+
+	ps.root = sdktrace.AlwaysSample()
+	r = ps.ShouldSample(sdktrace.SamplingParameters{ParentContext: parent})
+	if r.Decision != sdktrace.NotRecord {
+		t.Error("expected unsampled parent to override the root sampler")
+	}
+
+	parent.TraceFlags = apitrace.FlagsSampled
+	ps.root = sdktrace.NeverSample()
+	r = ps.ShouldSample(sdktrace.SamplingParameters{ParentContext: parent})
+	if r.Decision != sdktrace.RecordAndSampled {
+		t.Error("expected sampled parent to be sampled")
+	}
+}
+
+func TestRateLimitedSampler(t *testing.T) {
+
+	now := time.Unix(0, 0)
+	rs := newRateLimitedSampler(2)
+	rs.now = func() time.Time { return now }
+	rs.last = now
+
+	count := func(n int) int {
+		var sampled int
+		for i := 0; i < n; i++ {
+			if rs.ShouldSample(sdktrace.SamplingParameters{}).Decision == sdktrace.RecordAndSampled {
+				sampled++
+			}
+		}
+		return sampled
+	}
+
+	if c := count(5); c != 2 {
+		t.Errorf("expected %d got %d", 2, c)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if c := count(5); c != 1 {
+		t.Errorf("expected %d got %d", 1, c)
+	}
+
+	now = now.Add(time.Minute)
+	if c := count(5); c != 2 {
+		t.Errorf("expected %d got %d", 2, c)
+	}
+
+	parent := apitrace.SpanContext{TraceFlags: apitrace.FlagsSampled}
+	r := rs.ShouldSample(sdktrace.SamplingParameters{ParentContext: parent})
+	if r.Decision != sdktrace.RecordAndSampled {
+		t.Error("expected sampled parent to be sampled")
+	}
+
+	rs = newRateLimitedSampler(0.5)
+	if rs.tokens != 1 {
+		t.Errorf("expected minimum burst of 1, got %g", rs.tokens)
+	}
+}
+
+func TestDeferredSampling(t *testing.T) {
+
+	o := options.NewOptions()
+	o.SampleRate = 0
+	o.AlwaysSampleCacheMisses = true
+	o.AlwaysSampleErrors = true
+
+	s, err := New(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the head sampler declines, but everything must still be recorded
+	r := s.ShouldSample(sdktrace.SamplingParameters{TraceID: testTraceID(1)})
+	if r.Decision != sdktrace.RecordAndSampled {
+		t.Error("expected deferred sampler to record")
+	}
+	s.ShouldSample(sdktrace.SamplingParameters{TraceID: testTraceID(2)})
+	s.ShouldSample(sdktrace.SamplingParameters{TraceID: testTraceID(3)})
+
+	e := &testExporter{}
+	sy := s.WrapSyncer(e)
+	ctx := context.Background()
+
+	// trace 1 is a cache hit, and is dropped
+	sy.ExportSpan(ctx, testSpan(testTraceID(1), 2, 1, kv.String("cache.status", "hit")))
+	sy.ExportSpan(ctx, testSpan(testTraceID(1), 1, 0))
+	if len(e.spans) != 0 {
+		t.Errorf("expected %d spans got %d", 0, len(e.spans))
+	}
+
+	// trace 2 is a cache miss, and is exported once its root span ends
+	sy.ExportSpan(ctx, testSpan(testTraceID(2), 2, 1, kv.String("cache.status", "kmiss")))
+	if len(e.spans) != 0 {
+		t.Errorf("expected %d spans got %d", 0, len(e.spans))
+	}
+	sy.ExportSpan(ctx, testSpan(testTraceID(2), 1, 0))
+	if len(e.spans) != 2 {
+		t.Errorf("expected %d spans got %d", 2, len(e.spans))
+	}
+
+	// spans ending after the root follow the trace's decision
+	sy.ExportSpan(ctx, testSpan(testTraceID(2), 3, 1))
+	sy.ExportSpan(ctx, testSpan(testTraceID(1), 3, 1))
+	if len(e.spans) != 3 {
+		t.Errorf("expected %d spans got %d", 3, len(e.spans))
+	}
+
+	// trace 3 has an error, and is exported via the batcher
+	eb := &testExporter{}
+	b := s.WrapBatcher(eb)
+	errSpan := testSpan(testTraceID(3), 2, 1)
+	errSpan.StatusCode = codes.Internal
+	b.ExportSpans(ctx, []*export.SpanData{errSpan, nil})
+	b.ExportSpans(ctx, []*export.SpanData{testSpan(testTraceID(3), 1, 0)})
+	if len(eb.spans) != 2 {
+		t.Errorf("expected %d spans got %d", 2, len(eb.spans))
+	}
+
+	if len(s.pending) != 0 || len(s.headDecisions) != 0 {
+		t.Error("expected no pending traces")
+	}
+}
+
+func TestWrapPassthrough(t *testing.T) {
+
+	s, err := New(options.NewOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := &testExporter{}
+	if s.WrapSyncer(e) != e {
+		t.Error("expected unwrapped syncer")
+	}
+	if s.WrapBatcher(e) != e {
+		t.Error("expected unwrapped batcher")
+	}
+	if len(s.filter(testSpan(testTraceID(1), 1, 0))) != 1 {
+		t.Error("expected span to pass through")
+	}
+	if s.filter(nil) != nil {
+		t.Error("expected nil")
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package span
+
+import (
+	"net/http"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/tracing"
+	"github.com/tricksterproxy/trickster/pkg/tracing/options"
+
+	"go.opentelemetry.io/otel/api/kv"
+	"go.opentelemetry.io/otel/api/trace"
+)
+
+// Results describes the outcome of a request, from which result-based custom
+// span attributes are derived
+type Results struct {
+	CacheStatus string
+	Requested   *timeseries.Extent
+	Fetched     timeseries.ExtentList
+}
+
+// SetRequestAttributes attaches the tracer's custom span attributes that are
+// extracted from the client request
+func SetRequestAttributes(tr *tracing.Tracer, span trace.Span, r *http.Request,
+	originName, originType string) {
+	if tr == nil || span == nil || r == nil || tr.Options == nil ||
+		len(tr.Options.SpanAttributes) == 0 {
+		return
+	}
+	kvs := make([]kv.KeyValue, 0, len(tr.Options.SpanAttributes))
+	for _, a := range tr.Options.SpanAttributes {
+		var v string
+		switch a.Source {
+		case options.AttributeSourceHeader:
+			v = r.Header.Get(a.Arg)
+		case options.AttributeSourceParam:
+			if r.URL != nil {
+				v = r.URL.Query().Get(a.Arg)
+			}
+		case options.AttributeSourceMethod:
+			v = r.Method
+		case options.AttributeSourcePath:
+			if r.URL != nil {
+				v = r.URL.Path
+			}
+		case options.AttributeSourceOriginName:
+			v = originName
+		case options.AttributeSourceOriginType:
+			v = originType
+		default:
+			continue
+		}
+		if v != "" {
+			kvs = append(kvs, kv.String(a.Name, v))
+		}
+	}
+	SetAttributes(tr, span, kvs...)
+}
+
+// SetResultAttributes attaches the tracer's custom span attributes that are
+// derived from the result of the request
+func SetResultAttributes(tr *tracing.Tracer, span trace.Span, res Results) {
+	if tr == nil || span == nil || tr.Options == nil ||
+		len(tr.Options.SpanAttributes) == 0 {
+		return
+	}
+	kvs := make([]kv.KeyValue, 0, len(tr.Options.SpanAttributes))
+	for _, a := range tr.Options.SpanAttributes {
+		switch a.Source {
+		case options.AttributeSourceCacheStatus:
+			if res.CacheStatus != "" {
+				kvs = append(kvs, kv.String(a.Name, res.CacheStatus))
+			}
+		case options.AttributeSourceRequestedExtent:
+			if res.Requested != nil {
+				kvs = append(kvs, kv.String(a.Name, res.Requested.String()))
+			}
+		case options.AttributeSourceRequestedSecs:
+			if res.Requested != nil {
+				kvs = append(kvs, kv.Int64(a.Name,
+					int64(res.Requested.End.Sub(res.Requested.Start).Seconds())))
+			}
+		case options.AttributeSourceFetchedExtents:
+			if res.Requested != nil {
+				kvs = append(kvs, kv.String(a.Name, res.Fetched.String()))
+			}
+		case options.AttributeSourceFetchedSecs:
+			if res.Requested != nil {
+				var secs int64
+				for _, e := range res.Fetched {
+					secs += int64(e.End.Sub(e.Start).Seconds())
+				}
+				kvs = append(kvs, kv.Int64(a.Name, secs))
+			}
+		case options.AttributeSourceFetchedCount:
+			if res.Requested != nil {
+				kvs = append(kvs, kv.Int64(a.Name, int64(len(res.Fetched))))
+			}
+		}
+	}
+	SetAttributes(tr, span, kvs...)
+}
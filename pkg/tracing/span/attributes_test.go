@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package span
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/tracing"
+	"github.com/tricksterproxy/trickster/pkg/tracing/options"
+
+	"go.opentelemetry.io/otel/api/kv"
+	"go.opentelemetry.io/otel/api/trace"
+)
+
+type recordingSpan struct {
+	trace.NoopSpan
+	attrs map[string]string
+}
+
+func (s *recordingSpan) SetAttributes(kvs ...kv.KeyValue) {
+	for _, v := range kvs {
+		s.attrs[string(v.Key)] = v.Value.Emit()
+	}
+}
+
+func newAttributesTracer(t *testing.T) *tracing.Tracer {
+	o := options.NewOptions()
+	o.SpanAttributeSources = map[string]string{
+		"user":       "header:X-Grafana-User",
+		"query":      "param:query",
+		"method":     "method",
+		"path":       "path",
+		"origin":     "origin_name",
+		"originType": "origin_type",
+		"cache":      "cache_status",
+		"requested":  "requested_extent",
+		"reqSecs":    "requested_secs",
+		"fetched":    "fetched_extents",
+		"fetchSecs":  "fetched_secs",
+		"fetchCount": "fetched_count",
+	}
+	if err := options.ProcessTracingOptions(map[string]*options.Options{"test": o}, nil); err != nil {
+		t.Fatal(err)
+	}
+	return &tracing.Tracer{Options: o}
+}
+
+func TestSetRequestAttributes(t *testing.T) {
+
+	SetRequestAttributes(nil, nil, nil, "", "")
+
+	tr := newAttributesTracer(t)
+	sp := &recordingSpan{attrs: make(map[string]string)}
+
+	r, _ := http.NewRequest(http.MethodGet, "http://example.com/api/v1/query?query=up", nil)
+	r.Header.Set("X-Grafana-User", "johndoe")
+
+	SetRequestAttributes(tr, sp, r, "prom1", "prometheus")
+
+	expected := map[string]string{
+		"user":       "johndoe",
+		"query":      "up",
+		"method":     "GET",
+		"path":       "/api/v1/query",
+		"origin":     "prom1",
+		"originType": "prometheus",
+	}
+	if len(sp.attrs) != len(expected) {
+		t.Errorf("expected %d attributes got %d", len(expected), len(sp.attrs))
+	}
+	for k, v := range expected {
+		if sp.attrs[k] != v {
+			t.Errorf("expected %s for %s got %s", v, k, sp.attrs[k])
+		}
+	}
+}
+
+func TestSetResultAttributes(t *testing.T) {
+
+	SetResultAttributes(nil, nil, Results{})
+
+	tr := newAttributesTracer(t)
+	sp := &recordingSpan{attrs: make(map[string]string)}
+
+	SetResultAttributes(tr, sp, Results{CacheStatus: "hit"})
+	if len(sp.attrs) != 1 || sp.attrs["cache"] != "hit" {
+		t.Errorf("unexpected attributes %v", sp.attrs)
+	}
+
+	sp = &recordingSpan{attrs: make(map[string]string)}
+	requested := timeseries.Extent{Start: time.Unix(0, 0), End: time.Unix(3600, 0)}
+	SetResultAttributes(tr, sp, Results{
+		CacheStatus: "phit",
+		Requested:   &requested,
+		Fetched: timeseries.ExtentList{
+			{Start: time.Unix(0, 0), End: time.Unix(60, 0)},
+			{Start: time.Unix(3540, 0), End: time.Unix(3600, 0)},
+		},
+	})
+
+	expected := map[string]string{
+		"cache":      "phit",
+		"requested":  "0-3600",
+		"reqSecs":    "3600",
+		"fetched":    "0-60;3540-3600",
+		"fetchSecs":  "120",
+		"fetchCount": "2",
+	}
+	if len(sp.attrs) != len(expected) {
+		t.Errorf("expected %d attributes got %d", len(expected), len(sp.attrs))
+	}
+	for k, v := range expected {
+		if sp.attrs[k] != v {
+			t.Errorf("expected %s for %s got %s", v, k, sp.attrs[k])
+		}
+	}
+}
@@ -22,8 +22,8 @@ import (
 
 	tctx "github.com/tricksterproxy/trickster/pkg/proxy/context"
 	"github.com/tricksterproxy/trickster/pkg/tracing"
+	"github.com/tricksterproxy/trickster/pkg/tracing/propagation"
 
-	"go.opentelemetry.io/otel/api/correlation"
 	"go.opentelemetry.io/otel/api/kv"
 	"go.opentelemetry.io/otel/api/trace"
 	"go.opentelemetry.io/otel/plugin/httptrace"
@@ -43,14 +43,10 @@ func PrepareRequest(r *http.Request, tr *tracing.Tracer) (*http.Request, trace.S
 		return r, nil
 	}
 
-	attrs, entries, spanCtx := httptrace.Extract(r.Context(), r)
+	ctx := propagation.Extract(r.Context(), tr.Propagators, r.Header)
+	r = r.WithContext(ctx)
 
-	attrs = filterAttributes(tr, attrs)
-
-	r = r.WithContext(correlation.ContextWithMap(r.Context(),
-		correlation.NewMap(correlation.MapUpdate{
-			MultiKV: entries,
-		})))
+	attrs := filterAttributes(tr, []kv.KeyValue{httptrace.URLKey.String(r.URL.String())})
 
 	// This will add any configured static tags to the span for Zipkin
 	// For Jaeger, they are automatically included in the Process section of the Trace
@@ -62,7 +58,7 @@ func PrepareRequest(r *http.Request, tr *tracing.Tracer) (*http.Request, trace.S
 	}
 
 	ctx, span := tr.Start(
-		trace.ContextWithRemoteSpanContext(r.Context(), spanCtx),
+		ctx,
 		"request",
 		trace.WithAttributes(attrs...),
 	)
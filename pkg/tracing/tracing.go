@@ -23,6 +23,7 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/tracing/options"
 
 	"go.opentelemetry.io/otel/api/kv"
+	"go.opentelemetry.io/otel/api/propagation"
 	"go.opentelemetry.io/otel/api/trace"
 	"google.golang.org/grpc/codes"
 )
@@ -36,6 +37,8 @@ type Tracer struct {
 	Name    string
 	Flusher FlusherFunc
 	Options *options.Options
+	// Propagators extract incoming trace context and inject it into origin requests
+	Propagators propagation.Propagators
 }
 
 // Tracers is a map of *Tracer objects
@@ -30,14 +30,20 @@ const (
 	TracerTypeJaeger
 	// TracerTypeZipkin indicates Zipkin tracing
 	TracerTypeZipkin
+	// TracerTypeOTLP indicates OpenTelemetry Protocol (OTLP) tracing
+	TracerTypeOTLP
+	// TracerTypeDatadog indicates Datadog APM tracing
+	TracerTypeDatadog
 )
 
 // Names is a map of cache types keyed by name
 var Names = map[string]TracerType{
-	"none":   TracerTypeNone,
-	"stdout": TracerTypeStdout,
-	"jaeger": TracerTypeJaeger,
-	"zipkin": TracerTypeZipkin,
+	"none":    TracerTypeNone,
+	"stdout":  TracerTypeStdout,
+	"jaeger":  TracerTypeJaeger,
+	"zipkin":  TracerTypeZipkin,
+	"otlp":    TracerTypeOTLP,
+	"datadog": TracerTypeDatadog,
 }
 
 // Values is a map of cache types keyed by internal id
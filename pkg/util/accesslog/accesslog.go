@@ -0,0 +1,342 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package accesslog writes a structured JSON record for each request handled by
+// Trickster's frontend, separately from the application log
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/context"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/util/accesslog/options"
+
+	lumberjack "gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Record is the access log record of a single request. The handlers that serve the
+// request add the details they know about (e.g., the origin) to the Record in the
+// request's context, and the Record is written once the response is complete
+type Record struct {
+	Time        time.Time
+	Client      string
+	User        string
+	Method      string
+	Host        string
+	Path        string
+	Query       string
+	Protocol    string
+	Status      int
+	Bytes       int64
+	Duration    time.Duration
+	Origin      string
+	OriginType  string
+	CacheStatus string
+	TraceID     string
+	UserAgent   string
+	Referer     string
+}
+
+// GetRecord returns the access log Record from the HTTP Request's context, or nil
+// when the access log is not enabled
+func GetRecord(r *http.Request) *Record {
+	if r == nil {
+		return nil
+	}
+	rec, _ := context.AccessLogRecord(r.Context()).(*Record)
+	return rec
+}
+
+// Logger writes access log records to its output
+type Logger struct {
+	format string
+	fields []string
+	w      io.Writer
+	closer io.Closer
+	mtx    sync.Mutex
+}
+
+// New returns a new Logger for the provided options
+func New(o *options.Options) (*Logger, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	fields := o.Fields
+	if len(fields) == 0 {
+		fields = options.DefaultFields
+	}
+	l := &Logger{format: strings.ToLower(o.Format), fields: make([]string, len(fields))}
+	for i, f := range fields {
+		l.fields[i] = strings.ToLower(f)
+	}
+	switch o.Output {
+	case options.OutputStdout, "":
+		l.w = os.Stdout
+	case options.OutputStderr:
+		l.w = os.Stderr
+	default:
+		lj := &lumberjack.Logger{
+			Filename:   o.Output,
+			MaxSize:    256,  // megabytes
+			MaxBackups: 80,   // 256 megs @ 80 backups is 20GB of Logs
+			MaxAge:     7,    // days
+			Compress:   true, // Compress Rolled Backups
+		}
+		l.w = lj
+		l.closer = lj
+	}
+	return l, nil
+}
+
+// Close closes the Logger's output file, if any
+func (l *Logger) Close() error {
+	if l == nil || l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// Log writes the Record to the access log as a single line in the configured format
+func (l *Logger) Log(rec *Record) error {
+	var b []byte
+	switch l.format {
+	case options.FormatCommon:
+		b = encodeCLF(rec, false)
+	case options.FormatCombined:
+		b = encodeCLF(rec, true)
+	default:
+		b = l.encode(rec)
+	}
+	l.mtx.Lock()
+	_, err := l.w.Write(b)
+	l.mtx.Unlock()
+	return err
+}
+
+// encode returns the JSON encoding of the configured fields of the Record, in the
+// configured order, followed by a newline
+func (l *Logger) encode(rec *Record) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range l.fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('"')
+		buf.WriteString(f)
+		buf.WriteString(`":`)
+		switch f {
+		case options.FieldTime:
+			writeString(&buf, rec.Time.UTC().Format(time.RFC3339Nano))
+		case options.FieldClient:
+			writeString(&buf, rec.Client)
+		case options.FieldMethod:
+			writeString(&buf, rec.Method)
+		case options.FieldHost:
+			writeString(&buf, rec.Host)
+		case options.FieldPath:
+			writeString(&buf, rec.Path)
+		case options.FieldQuery:
+			writeString(&buf, rec.Query)
+		case options.FieldProtocol:
+			writeString(&buf, rec.Protocol)
+		case options.FieldStatus:
+			buf.WriteString(strconv.Itoa(rec.Status))
+		case options.FieldBytes:
+			buf.WriteString(strconv.FormatInt(rec.Bytes, 10))
+		case options.FieldDurationMS:
+			buf.WriteString(strconv.FormatFloat(float64(rec.Duration)/float64(time.Millisecond),
+				'f', 3, 64))
+		case options.FieldOrigin:
+			writeString(&buf, rec.Origin)
+		case options.FieldOriginType:
+			writeString(&buf, rec.OriginType)
+		case options.FieldCacheStatus:
+			writeString(&buf, rec.CacheStatus)
+		case options.FieldTraceID:
+			writeString(&buf, rec.TraceID)
+		case options.FieldUserAgent:
+			writeString(&buf, rec.UserAgent)
+		case options.FieldReferer:
+			writeString(&buf, rec.Referer)
+		default:
+			buf.WriteString("null")
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	buf.Write(b)
+}
+
+// clfTimeFormat is the timestamp layout of the Apache Common Log Format
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// encodeCLF returns the Record in the Apache Common Log Format, or in the Apache
+// Combined Log Format when combined is true, followed by a newline
+func encodeCLF(rec *Record, combined bool) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(clfValue(rec.Client))
+	buf.WriteString(" - ")
+	buf.WriteString(clfValue(rec.User))
+	buf.WriteString(" [")
+	buf.WriteString(rec.Time.Format(clfTimeFormat))
+	buf.WriteString(`] "`)
+	buf.WriteString(clfEscape(rec.Method))
+	buf.WriteByte(' ')
+	buf.WriteString(clfEscape(rec.Path))
+	if rec.Query != "" {
+		buf.WriteByte('?')
+		buf.WriteString(clfEscape(rec.Query))
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(clfEscape(rec.Protocol))
+	buf.WriteString(`" `)
+	buf.WriteString(strconv.Itoa(rec.Status))
+	buf.WriteByte(' ')
+	if rec.Bytes > 0 {
+		buf.WriteString(strconv.FormatInt(rec.Bytes, 10))
+	} else {
+		buf.WriteByte('-')
+	}
+	if combined {
+		buf.WriteString(` "`)
+		buf.WriteString(clfValue(rec.Referer))
+		buf.WriteString(`" "`)
+		buf.WriteString(clfValue(rec.UserAgent))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// clfValue returns the escaped value, or "-" when the value is empty
+func clfValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return clfEscape(s)
+}
+
+// clfEscape escapes quotes, backslashes and non-printable characters the same way
+// as Apache, so that each record remains a single, parseable line
+func clfEscape(s string) string {
+	var buf *strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 0x20 && c < 0x7f && c != '"' && c != '\\' {
+			if buf != nil {
+				buf.WriteByte(c)
+			}
+			continue
+		}
+		if buf == nil {
+			buf = &strings.Builder{}
+			buf.WriteString(s[:i])
+		}
+		switch c {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		default:
+			fmt.Fprintf(buf, "\\x%02x", c)
+		}
+	}
+	if buf == nil {
+		return s
+	}
+	return buf.String()
+}
+
+// Handler writes an access log record for each request served by next
+func Handler(l *Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &Record{
+			Time:      time.Now(),
+			Client:    r.RemoteAddr,
+			Method:    r.Method,
+			Host:      r.Host,
+			Path:      r.URL.Path,
+			Query:     r.URL.RawQuery,
+			Protocol:  r.Proto,
+			UserAgent: r.UserAgent(),
+			Referer:   r.Referer(),
+		}
+		if user, _, ok := r.BasicAuth(); ok {
+			rec.User = user
+		}
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			rec.Client = host
+		}
+		rw := &responseRecorder{ResponseWriter: w}
+		next.ServeHTTP(rw, r.WithContext(context.WithAccessLogRecord(r.Context(), rec)))
+		rec.Duration = time.Since(rec.Time)
+		rec.Status = rw.status
+		if rec.Status == 0 {
+			rec.Status = http.StatusOK
+		}
+		rec.Bytes = rw.bytes
+		// requests handled by a proxy engine report their cache lookup status
+		// in the results header
+		if cs, ok := headers.ResultsHeaderStatus(rw.Header()); ok {
+			rec.CacheStatus = cs
+		}
+		l.Log(rec)
+	})
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *responseRecorder) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Flush implements http.Flusher, so that streamed responses are not buffered
+func (w *responseRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
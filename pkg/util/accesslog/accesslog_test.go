@@ -0,0 +1,281 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/util/accesslog/options"
+)
+
+func TestNew(t *testing.T) {
+
+	o := options.NewOptions()
+	o.Output = options.OutputStdout
+	l, err := New(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.w != os.Stdout {
+		t.Error("expected stdout writer")
+	}
+
+	o.Output = options.OutputStderr
+	l, err = New(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.w != os.Stderr {
+		t.Error("expected stderr writer")
+	}
+	if err = l.Close(); err != nil {
+		t.Error(err)
+	}
+
+	td, err := ioutil.TempDir("", "trickster-accesslog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+	o.Output = filepath.Join(td, "access.log")
+	l, err = New(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = l.Log(&Record{Status: 200}); err != nil {
+		t.Error(err)
+	}
+	if err = l.Close(); err != nil {
+		t.Error(err)
+	}
+	b, err := ioutil.ReadFile(o.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"status":200`) {
+		t.Errorf("unexpected log content: %s", string(b))
+	}
+
+	o.Fields = []string{"invalid"}
+	_, err = New(o)
+	if err == nil {
+		t.Error("expected error for invalid field")
+	}
+}
+
+func TestLog(t *testing.T) {
+
+	o := options.NewOptions()
+	o.Fields = []string{"STATUS", "time", "duration_ms", "client", "origin"}
+	l, err := New(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	l.w = buf
+
+	rec := &Record{
+		Time:     time.Unix(1577836800, 0),
+		Client:   `"quoted"`,
+		Status:   404,
+		Duration: 1500 * time.Microsecond,
+	}
+	if err = l.Log(rec); err != nil {
+		t.Fatal(err)
+	}
+
+	const expected = `{"status":404,"time":"2020-01-01T00:00:00Z",` +
+		`"duration_ms":1.500,"client":"\"quoted\"","origin":""}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("expected %s got %s", expected, buf.String())
+	}
+}
+
+func TestHandler(t *testing.T) {
+
+	o := options.NewOptions()
+	o.Fields = append(o.Fields, options.FieldUserAgent, options.FieldQuery)
+	l, err := New(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	l.w = buf
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := GetRecord(r)
+		if rec == nil {
+			t.Fatal("expected non-nil record")
+		}
+		rec.Origin = "test-origin"
+		rec.TraceID = "test-trace-id"
+		headers.SetResultsHeader(w.Header(), "ObjectProxyCache", "hit", "", nil)
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("trickster"))
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://0/test/path?q=1", nil)
+	r.RemoteAddr = "192.0.2.1:12345"
+	r.Header.Set("User-Agent", "test-agent")
+	Handler(l, next).ServeHTTP(w, r)
+
+	m := make(map[string]interface{})
+	if err = json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		options.FieldClient:      "192.0.2.1",
+		options.FieldMethod:      http.MethodGet,
+		options.FieldPath:        "/test/path",
+		options.FieldQuery:       "q=1",
+		options.FieldStatus:      float64(http.StatusTeapot),
+		options.FieldBytes:       float64(9),
+		options.FieldOrigin:      "test-origin",
+		options.FieldCacheStatus: "hit",
+		options.FieldTraceID:     "test-trace-id",
+		options.FieldUserAgent:   "test-agent",
+	}
+	for k, v := range expected {
+		if m[k] != v {
+			t.Errorf("expected %v got %v for field %s", v, m[k], k)
+		}
+	}
+	if _, ok := m[options.FieldTime]; !ok {
+		t.Error("expected time field")
+	}
+	if _, ok := m[options.FieldDurationMS]; !ok {
+		t.Error("expected duration_ms field")
+	}
+
+	// a handler that never calls WriteHeader or Write logs a 200
+	buf.Reset()
+	Handler(l, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(buf.String(), `"status":200`) {
+		t.Errorf("expected status 200 got %s", buf.String())
+	}
+}
+
+func TestHandlerFlush(t *testing.T) {
+
+	o := options.NewOptions()
+	l, err := New(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.w = &bytes.Buffer{}
+
+	// streamed responses can be flushed through the recorder
+	w := httptest.NewRecorder()
+	Handler(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("expected http.Flusher")
+		}
+		w.Write([]byte("trickster"))
+		f.Flush()
+	})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !w.Flushed {
+		t.Error("expected response to be flushed")
+	}
+}
+
+func TestGetRecord(t *testing.T) {
+	if GetRecord(nil) != nil {
+		t.Error("expected nil record")
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if GetRecord(r) != nil {
+		t.Error("expected nil record")
+	}
+}
+
+func TestLogCLF(t *testing.T) {
+
+	rec := &Record{
+		Time:      time.Date(2020, 1, 1, 0, 0, 0, 0, time.FixedZone("", -7*3600)),
+		Client:    "192.0.2.1",
+		User:      "frank",
+		Method:    http.MethodGet,
+		Path:      "/api/v1/query",
+		Query:     `query="up"`,
+		Protocol:  "HTTP/1.1",
+		Status:    200,
+		Bytes:     2326,
+		UserAgent: "agent\x01",
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			options.FormatCommon,
+			`192.0.2.1 - frank [01/Jan/2020:00:00:00 -0700] ` +
+				`"GET /api/v1/query?query=\"up\" HTTP/1.1" 200 2326` + "\n",
+		},
+		{
+			options.FormatCombined,
+			`192.0.2.1 - frank [01/Jan/2020:00:00:00 -0700] ` +
+				`"GET /api/v1/query?query=\"up\" HTTP/1.1" 200 2326 "-" "agent\x01"` + "\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			o := options.NewOptions()
+			o.Format = test.format
+			l, err := New(o)
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf := &bytes.Buffer{}
+			l.w = buf
+			if err = l.Log(rec); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != test.expected {
+				t.Errorf("expected %s got %s", test.expected, buf.String())
+			}
+		})
+	}
+
+	// an empty response body and anonymous user are logged as "-"
+	o := options.NewOptions()
+	o.Format = options.FormatCommon
+	l, _ := New(o)
+	buf := &bytes.Buffer{}
+	l.w = buf
+	l.Log(&Record{Time: rec.Time, Client: rec.Client, Method: http.MethodHead,
+		Path: "/", Protocol: "HTTP/1.1", Status: 304})
+	const expected = `192.0.2.1 - - [01/Jan/2020:00:00:00 -0700] "HEAD / HTTP/1.1" 304 -` + "\n"
+	if buf.String() != expected {
+		t.Errorf("expected %s got %s", expected, buf.String())
+	}
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options provides options for the access log
+package options
+
+import (
+	"fmt"
+	"strings"
+
+	ts "github.com/tricksterproxy/trickster/pkg/util/strings"
+)
+
+// Supported access log outputs, other than a file path
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+)
+
+// Supported access log record formats
+const (
+	// FormatJSON writes each record as a single line of JSON with the configured Fields
+	FormatJSON = "json"
+	// FormatCommon writes each record in the Apache Common Log Format
+	FormatCommon = "common"
+	// FormatCombined writes each record in the Apache Combined Log Format
+	FormatCombined = "combined"
+)
+
+// Field names that can be included in access log records
+const (
+	FieldTime        = "time"
+	FieldClient      = "client"
+	FieldMethod      = "method"
+	FieldHost        = "host"
+	FieldPath        = "path"
+	FieldQuery       = "query"
+	FieldProtocol    = "protocol"
+	FieldStatus      = "status"
+	FieldBytes       = "bytes"
+	FieldDurationMS  = "duration_ms"
+	FieldOrigin      = "origin"
+	FieldOriginType  = "origin_type"
+	FieldCacheStatus = "cache_status"
+	FieldTraceID     = "trace_id"
+	FieldUserAgent   = "user_agent"
+	FieldReferer     = "referer"
+)
+
+// FieldNames is the set of supported access log field names
+var FieldNames = map[string]bool{
+	FieldTime: true, FieldClient: true, FieldMethod: true, FieldHost: true,
+	FieldPath: true, FieldQuery: true, FieldProtocol: true, FieldStatus: true,
+	FieldBytes: true, FieldDurationMS: true, FieldOrigin: true, FieldOriginType: true,
+	FieldCacheStatus: true, FieldTraceID: true, FieldUserAgent: true, FieldReferer: true,
+}
+
+// DefaultFields is the list of fields included in access log records by default
+var DefaultFields = []string{FieldTime, FieldClient, FieldMethod, FieldPath, FieldStatus,
+	FieldBytes, FieldDurationMS, FieldOrigin, FieldCacheStatus, FieldTraceID}
+
+// Options is a collection of configurations for the access log
+type Options struct {
+	// Output is where access log records are written: 'stdout', 'stderr' or a file path.
+	// The access log is only enabled when an Output is configured
+	Output string `toml:"output"`
+	// Format is the format of each access log record: 'json', 'common' or 'combined'
+	Format string `toml:"format"`
+	// Fields is the ordered list of fields included in each access log record. Only
+	// applicable to the 'json' Format
+	Fields []string `toml:"fields"`
+}
+
+// NewOptions returns a new Options reference with Default Values set
+func NewOptions() *Options {
+	return &Options{Format: FormatJSON, Fields: ts.CloneList(DefaultFields)}
+}
+
+// Clone returns an exact copy of the subject *Options
+func (o *Options) Clone() *Options {
+	return &Options{
+		Output: o.Output,
+		Format: o.Format,
+		Fields: ts.CloneList(o.Fields),
+	}
+}
+
+// Enabled returns true when the access log should be written
+func (o *Options) Enabled() bool {
+	return o != nil && o.Output != ""
+}
+
+// Equal returns true when the subject and o2 have the same values
+func (o *Options) Equal(o2 *Options) bool {
+	if o == nil || o2 == nil {
+		return o == o2
+	}
+	return o.Output == o2.Output && o.Format == o2.Format && ts.Equal(o.Fields, o2.Fields)
+}
+
+// Validate returns an error if the Options are not valid
+func (o *Options) Validate() error {
+	switch strings.ToLower(o.Format) {
+	case "", FormatJSON, FormatCommon, FormatCombined:
+	default:
+		return fmt.Errorf("invalid access_log format: %s", o.Format)
+	}
+	for _, f := range o.Fields {
+		if _, ok := FieldNames[strings.ToLower(f)]; !ok {
+			return fmt.Errorf("invalid access_log field: %s", f)
+		}
+	}
+	return nil
+}
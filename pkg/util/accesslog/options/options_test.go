@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import "testing"
+
+func TestClone(t *testing.T) {
+	o := NewOptions()
+	o.Output = OutputStdout
+	o2 := o.Clone()
+	if !o.Equal(o2) {
+		t.Error("expected equal options")
+	}
+	o2.Fields[0] = FieldHost
+	if o.Fields[0] != FieldTime {
+		t.Error("clone did not copy fields")
+	}
+	if o.Equal(o2) {
+		t.Error("expected unequal options")
+	}
+	o2 = o.Clone()
+	o2.Format = FormatCommon
+	if o.Equal(o2) {
+		t.Error("expected unequal options")
+	}
+	var nilOpts *Options
+	if !nilOpts.Equal(nil) || nilOpts.Equal(o) || o.Equal(nil) {
+		t.Error("unexpected nil equality")
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	var o *Options
+	if o.Enabled() {
+		t.Error("expected disabled")
+	}
+	o = NewOptions()
+	if o.Enabled() {
+		t.Error("expected disabled")
+	}
+	o.Output = "/var/log/trickster/access.log"
+	if !o.Enabled() {
+		t.Error("expected enabled")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	o := NewOptions()
+	if err := o.Validate(); err != nil {
+		t.Error(err)
+	}
+	o.Fields = append(o.Fields, "Referer")
+	if err := o.Validate(); err != nil {
+		t.Error(err)
+	}
+	o.Format = "Combined"
+	if err := o.Validate(); err != nil {
+		t.Error(err)
+	}
+	o.Fields = append(o.Fields, "invalid")
+	const expected = "invalid access_log field: invalid"
+	if err := o.Validate(); err == nil || err.Error() != expected {
+		t.Errorf("expected %s got %v", expected, err)
+	}
+	o.Format = "invalid"
+	const expected2 = "invalid access_log format: invalid"
+	if err := o.Validate(); err == nil || err.Error() != expected2 {
+		t.Errorf("expected %s got %v", expected2, err)
+	}
+}
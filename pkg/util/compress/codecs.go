@@ -0,0 +1,154 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compress
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+
+	"github.com/tricksterproxy/trickster/pkg/util/compress/gzip"
+
+	"github.com/andybalholm/brotli"
+	"github.com/golang/snappy"
+)
+
+// Names of the built-in Codecs
+const (
+	// NameGzip is the name of the gzip Codec
+	NameGzip = "gzip"
+	// NameDeflate is the name of the deflate Codec, which, as with HTTP's
+	// "deflate" Content-Encoding, uses the zlib format
+	NameDeflate = "deflate"
+	// NameSnappy is the name of the snappy Codec
+	NameSnappy = "snappy"
+	// NameBrotli is the name of the brotli Codec, matching HTTP's "br" Content-Encoding
+	NameBrotli = "br"
+)
+
+func init() {
+	Register(gzipCodec{})
+	Register(deflateCodec{})
+	Register(snappyCodec{})
+	Register(brotliCodec{})
+}
+
+// encodeWithWriter compresses in using a Writer from the provided Codec
+func encodeWithWriter(c Codec, in []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w, err := c.NewWriter(buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(in); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeWithReader decompresses in using a Reader from the provided Codec
+func decodeWithReader(c Codec, in []byte) ([]byte, error) {
+	r, err := c.NewReader(bytes.NewReader(in))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// gzipCodec uses pooled compressors and decompressors from the gzip package
+type gzipCodec struct{}
+
+func (c gzipCodec) Name() string                                  { return NameGzip }
+func (c gzipCodec) Encode(in []byte) ([]byte, error)              { return gzip.Deflate(in) }
+func (c gzipCodec) Decode(in []byte) ([]byte, error)              { return gzip.Inflate(in) }
+func (c gzipCodec) NewWriter(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriter(w), nil }
+func (c gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error)  { return gzip.NewReader(r) }
+
+type deflateCodec struct{}
+
+func (c deflateCodec) Name() string                                  { return NameDeflate }
+func (c deflateCodec) Encode(in []byte) ([]byte, error)              { return encodeWithWriter(c, in) }
+func (c deflateCodec) Decode(in []byte) ([]byte, error)              { return decodeWithReader(c, in) }
+func (c deflateCodec) NewWriter(w io.Writer) (io.WriteCloser, error) { return zlib.NewWriter(w), nil }
+func (c deflateCodec) NewReader(r io.Reader) (io.ReadCloser, error)  { return zlib.NewReader(r) }
+
+// brotliCodec uses the default brotli quality, which favors ratio over speed
+// more than gzip does and suits large, text-heavy bodies
+type brotliCodec struct{}
+
+func (c brotliCodec) Name() string                     { return NameBrotli }
+func (c brotliCodec) Encode(in []byte) ([]byte, error) { return encodeWithWriter(c, in) }
+func (c brotliCodec) Decode(in []byte) ([]byte, error) { return decodeWithReader(c, in) }
+
+func (c brotliCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return brotli.NewWriter(w), nil
+}
+
+func (c brotliCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(brotli.NewReader(r)), nil
+}
+
+// snappyCodec uses the snappy block format for Encode and Decode, since the
+// whole slice is available, and the snappy framing format for streams
+type snappyCodec struct{}
+
+func (c snappyCodec) Name() string                     { return NameSnappy }
+func (c snappyCodec) Encode(in []byte) ([]byte, error) { return snappy.Encode(nil, in), nil }
+func (c snappyCodec) Decode(in []byte) ([]byte, error) { return snappy.Decode(nil, in) }
+
+func (c snappyCodec) AppendEncode(dst, in []byte) ([]byte, error) {
+	l := len(dst)
+	dst = grow(dst, snappy.MaxEncodedLen(len(in)))
+	b := snappy.Encode(dst[l:], in)
+	return dst[:l+len(b)], nil
+}
+
+func (c snappyCodec) AppendDecode(dst, in []byte) ([]byte, error) {
+	n, err := snappy.DecodedLen(in)
+	if err != nil {
+		return nil, err
+	}
+	l := len(dst)
+	dst = grow(dst, n)
+	if _, err = snappy.Decode(dst[l:], in); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// grow returns b extended by n bytes, reallocating only if b lacks the capacity
+func grow(b []byte, n int) []byte {
+	if l := len(b) + n; l <= cap(b) {
+		return b[:l]
+	}
+	nb := make([]byte, len(b)+n)
+	copy(nb, b)
+	return nb
+}
+
+func (c snappyCodec) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	return snappy.NewBufferedWriter(w), nil
+}
+
+func (c snappyCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return ioutil.NopCloser(snappy.NewReader(r)), nil
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package compress provides a registry of compression codecs that can be
+// looked up by name, for use in cache serialization and HTTP body handling
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Codec compresses and decompresses data in a specific format, either as whole
+// byte slices or as streams
+type Codec interface {
+	// Name returns the name of the codec, which is its HTTP Content-Encoding token where one exists
+	Name() string
+	// Encode returns the compressed version of the provided byte slice
+	Encode([]byte) ([]byte, error)
+	// Decode returns the decompressed version of the provided byte slice
+	Decode([]byte) ([]byte, error)
+	// NewReader returns a ReadCloser that decompresses the data read from the provided Reader
+	NewReader(io.Reader) (io.ReadCloser, error)
+	// NewWriter returns a WriteCloser that compresses data written to it into the provided
+	// Writer. The compressed stream is not complete until the WriteCloser is closed
+	NewWriter(io.Writer) (io.WriteCloser, error)
+}
+
+// Appender is implemented by Codecs that can encode and decode directly into a
+// caller-provided buffer, which lets callers reuse buffers and avoid copies
+type Appender interface {
+	// AppendEncode appends the compressed version of in to dst
+	AppendEncode(dst, in []byte) ([]byte, error)
+	// AppendDecode appends the decompressed version of in to dst
+	AppendDecode(dst, in []byte) ([]byte, error)
+}
+
+var codecs = make(map[string]Codec)
+var codecsLock sync.RWMutex
+
+// Register adds the provided Codec to the registry, replacing any Codec already
+// registered with the same name. zstd and lz4 are not provided, since their Go
+// implementations require a newer Go release than this module supports
+func Register(c Codec) {
+	codecsLock.Lock()
+	codecs[c.Name()] = c
+	codecsLock.Unlock()
+}
+
+// Get returns the Codec registered with the provided name
+func Get(name string) (Codec, bool) {
+	codecsLock.RLock()
+	c, ok := codecs[name]
+	codecsLock.RUnlock()
+	return c, ok
+}
+
+// Names returns the sorted list of registered Codec names
+func Names() []string {
+	codecsLock.RLock()
+	names := make([]string, 0, len(codecs))
+	for n := range codecs {
+		names = append(names, n)
+	}
+	codecsLock.RUnlock()
+	sort.Strings(names)
+	return names
+}
+
+// Encode compresses the provided byte slice using the named Codec
+func Encode(name string, in []byte) ([]byte, error) {
+	c, ok := Get(name)
+	if !ok {
+		return nil, ErrUnknownCodec(name)
+	}
+	return c.Encode(in)
+}
+
+// Decode decompresses the provided byte slice using the named Codec
+func Decode(name string, in []byte) ([]byte, error) {
+	c, ok := Get(name)
+	if !ok {
+		return nil, ErrUnknownCodec(name)
+	}
+	return c.Decode(in)
+}
+
+// AppendEncode compresses in using the named Codec, appending the result to dst
+func AppendEncode(name string, dst, in []byte) ([]byte, error) {
+	c, ok := Get(name)
+	if !ok {
+		return nil, ErrUnknownCodec(name)
+	}
+	if a, ok := c.(Appender); ok {
+		return a.AppendEncode(dst, in)
+	}
+	buf := bytes.NewBuffer(dst)
+	w, err := c.NewWriter(buf)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(in); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// AppendDecode decompresses in using the named Codec, appending the result to dst
+func AppendDecode(name string, dst, in []byte) ([]byte, error) {
+	c, ok := Get(name)
+	if !ok {
+		return nil, ErrUnknownCodec(name)
+	}
+	if a, ok := c.(Appender); ok {
+		return a.AppendDecode(dst, in)
+	}
+	r, err := c.NewReader(bytes.NewReader(in))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	buf := bytes.NewBuffer(dst)
+	if _, err = buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ErrUnknownCodec returns an error indicating that no Codec is registered with the provided name
+func ErrUnknownCodec(name string) error {
+	return fmt.Errorf("unknown compression codec: %s", name)
+}
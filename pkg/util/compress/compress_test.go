@@ -0,0 +1,135 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compress
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testData = []byte(strings.Repeat("this is the inflated text string. ", 64))
+
+func TestNames(t *testing.T) {
+	expected := []string{NameBrotli, NameDeflate, NameGzip, NameSnappy}
+	if n := Names(); !reflect.DeepEqual(n, expected) {
+		t.Errorf("expected %v got %v", expected, n)
+	}
+}
+
+func TestCodecs(t *testing.T) {
+	for _, name := range Names() {
+		t.Run(name, func(t *testing.T) {
+
+			c, ok := Get(name)
+			if !ok {
+				t.Fatalf("expected codec %s", name)
+			}
+			if c.Name() != name {
+				t.Errorf("expected %s got %s", name, c.Name())
+			}
+
+			b, err := Encode(name, testData)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(b) >= len(testData) {
+				t.Errorf("expected compressed length < %d got %d", len(testData), len(b))
+			}
+			b, err = Decode(name, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b, testData) {
+				t.Error("expected decoded data to match")
+			}
+
+			_, err = Decode(name, []byte("trickster"))
+			if err == nil {
+				t.Error("expected error for invalid compressed data")
+			}
+
+			// streams written in two chunks must read back in full
+			buf := &bytes.Buffer{}
+			w, err := c.NewWriter(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write(testData[:100])
+			w.Write(testData[100:])
+			if err = w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			r, err := c.NewReader(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err = ioutil.ReadAll(r)
+			r.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b, testData) {
+				t.Error("expected streamed data to match")
+			}
+		})
+	}
+}
+
+type testCodec struct{}
+
+func (c testCodec) Name() string                                  { return "test" }
+func (c testCodec) Encode(in []byte) ([]byte, error)              { return in, nil }
+func (c testCodec) Decode(in []byte) ([]byte, error)              { return in, nil }
+func (c testCodec) NewWriter(w io.Writer) (io.WriteCloser, error) { return nil, io.ErrClosedPipe }
+func (c testCodec) NewReader(r io.Reader) (io.ReadCloser, error)  { return nil, io.ErrClosedPipe }
+
+func TestRegister(t *testing.T) {
+
+	_, err := Encode("test", testData)
+	if err == nil || err.Error() != "unknown compression codec: test" {
+		t.Errorf("expected error for unknown codec, got %v", err)
+	}
+	_, err = Decode("test", testData)
+	if err == nil {
+		t.Error("expected error for unknown codec")
+	}
+
+	Register(testCodec{})
+	defer func() {
+		codecsLock.Lock()
+		delete(codecs, "test")
+		codecsLock.Unlock()
+	}()
+
+	b, err := Encode("test", testData)
+	if err != nil || !bytes.Equal(b, testData) {
+		t.Errorf("expected registered codec to encode, got %v", err)
+	}
+
+	_, err = encodeWithWriter(testCodec{}, testData)
+	if err != io.ErrClosedPipe {
+		t.Errorf("expected %v got %v", io.ErrClosedPipe, err)
+	}
+	_, err = decodeWithReader(testCodec{}, testData)
+	if err != io.ErrClosedPipe {
+		t.Errorf("expected %v got %v", io.ErrClosedPipe, err)
+	}
+}
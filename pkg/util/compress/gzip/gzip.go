@@ -14,22 +14,104 @@
  * limitations under the License.
  */
 
-// Package gzip provides gzip capabilities for byte slices
+// Package gzip provides gzip capabilities for byte slices and streams,
+// using pooled compressors and decompressors
 package gzip
 
 import (
 	"bytes"
 	"compress/gzip"
+	"io"
 	"io/ioutil"
+	"sync"
 )
 
+var readerPool = sync.Pool{}
+
+var writerPool = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
 // Inflate returns the inflated version of a gzip-deflated byte slice
 func Inflate(in []byte) ([]byte, error) {
-	gr, err := gzip.NewReader(bytes.NewBuffer(in))
+	gr, err := NewReader(bytes.NewReader(in))
 	if err != nil {
 		return []byte{}, err
 	}
-
+	defer gr.Close()
 	out, err := ioutil.ReadAll(gr)
 	return out, err
 }
+
+// Deflate returns the gzip-deflated version of a byte slice
+func Deflate(in []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	gw := NewWriter(buf)
+	if _, err := gw.Write(in); err != nil {
+		gw.Close()
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type reader struct {
+	*gzip.Reader
+}
+
+// NewReader returns a ReadCloser that inflates the gzip stream read from r. The
+// underlying decompressor is returned to a pool when the ReadCloser is closed, so
+// it must not be used after Close
+func NewReader(r io.Reader) (io.ReadCloser, error) {
+	if gr, ok := readerPool.Get().(*gzip.Reader); ok {
+		if err := gr.Reset(r); err != nil {
+			readerPool.Put(gr)
+			return nil, err
+		}
+		return &reader{Reader: gr}, nil
+	}
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &reader{Reader: gr}, nil
+}
+
+// Close closes the reader and returns its decompressor to the pool
+func (r *reader) Close() error {
+	if r.Reader == nil {
+		return nil
+	}
+	err := r.Reader.Close()
+	readerPool.Put(r.Reader)
+	r.Reader = nil
+	return err
+}
+
+type writer struct {
+	*gzip.Writer
+}
+
+// NewWriter returns a WriteCloser that writes a gzip stream to w. The stream is
+// not complete until the WriteCloser is closed, at which point the underlying
+// compressor is returned to a pool, so it must not be used after Close
+func NewWriter(w io.Writer) io.WriteCloser {
+	gw := writerPool.Get().(*gzip.Writer)
+	gw.Reset(w)
+	return &writer{Writer: gw}
+}
+
+// Close flushes and closes the gzip stream and returns its compressor to the pool
+func (w *writer) Close() error {
+	if w.Writer == nil {
+		return nil
+	}
+	err := w.Writer.Close()
+	writerPool.Put(w.Writer)
+	w.Writer = nil
+	return err
+}
@@ -17,7 +17,10 @@
 package gzip
 
 import (
+	"bytes"
+	"io"
 	"io/ioutil"
+	"strings"
 	"testing"
 )
 
@@ -41,3 +44,86 @@ func TestInflate(t *testing.T) {
 	}
 
 }
+
+func TestDeflate(t *testing.T) {
+	in := []byte(strings.Repeat("this is the inflated text string ", 100))
+	b, err := Deflate(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) >= len(in) {
+		t.Errorf("expected compressed length < %d got %d", len(in), len(b))
+	}
+	// inflate twice so the second uses a pooled decompressor
+	for i := 0; i < 2; i++ {
+		u, err := Inflate(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(u, in) {
+			t.Error("expected inflated data to match")
+		}
+	}
+	// and a pooled decompressor must still report invalid input
+	if _, err = Inflate([]byte("trickster")); err == nil {
+		t.Error("expected error for invalid gzip data")
+	}
+}
+
+func TestWriterError(t *testing.T) {
+	pr, pw := io.Pipe()
+	pr.CloseWithError(io.ErrClosedPipe)
+	gw := NewWriter(pw)
+	if _, err := gw.Write(make([]byte, 1<<20)); err == nil {
+		gw.Close()
+		t.Error("expected write error")
+	}
+}
+
+func TestStreams(t *testing.T) {
+
+	// a body larger than the compressor's window, written in small chunks,
+	// must be streamed through without being buffered by the caller
+	const chunks = 1024
+	chunk := []byte(strings.Repeat("0123456789abcdef", 64))
+
+	pr, pw := io.Pipe()
+	go func() {
+		gw := NewWriter(pw)
+		for i := 0; i < chunks; i++ {
+			if _, err := gw.Write(chunk); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.CloseWithError(gw.Close())
+	}()
+
+	gr, err := NewReader(pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := io.Copy(ioutil.Discard, gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(chunks*len(chunk)) {
+		t.Errorf("expected %d got %d", chunks*len(chunk), n)
+	}
+
+	if err = gr.Close(); err != nil {
+		t.Error(err)
+	}
+	// closing again is a no-op
+	if err = gr.Close(); err != nil {
+		t.Error(err)
+	}
+
+	gw := NewWriter(ioutil.Discard)
+	if err = gw.Close(); err != nil {
+		t.Error(err)
+	}
+	if err = gw.Close(); err != nil {
+		t.Error(err)
+	}
+}
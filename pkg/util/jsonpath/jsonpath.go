@@ -0,0 +1,127 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package jsonpath provides a minimal JSONPath implementation for extracting
+// single values from decoded JSON documents
+package jsonpath
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrNotFound is returned when a Path does not resolve to a value in the document
+var ErrNotFound = errors.New("value not found")
+
+// Path is a compiled JSONPath expression that selects a single value
+type Path struct {
+	expr     string
+	segments []segment
+}
+
+type segment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// Parse compiles a JSONPath expression such as $.queries[0].expr or $['range']['from'].
+// Only child member and array index selectors are supported; wildcards, filters,
+// slices and recursive descent are not, since the Path must resolve to a single value.
+func Parse(expr string) (Path, error) {
+
+	p := Path{expr: expr}
+
+	if !strings.HasPrefix(expr, "$") {
+		return p, fmt.Errorf("jsonpath must begin with '$': %s", expr)
+	}
+
+	s := expr[1:]
+	for len(s) > 0 {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			i := strings.IndexAny(s, ".[")
+			if i == -1 {
+				i = len(s)
+			}
+			if i == 0 || s[:i] == "*" {
+				return p, fmt.Errorf("invalid jsonpath member at %s", expr)
+			}
+			p.segments = append(p.segments, segment{key: s[:i]})
+			s = s[i:]
+		case '[':
+			i := strings.IndexByte(s, ']')
+			if i == -1 {
+				return p, fmt.Errorf("unterminated bracket in jsonpath %s", expr)
+			}
+			b := s[1:i]
+			s = s[i+1:]
+			if l := len(b); l >= 2 && (b[0] == '\'' || b[0] == '"') && b[l-1] == b[0] {
+				p.segments = append(p.segments, segment{key: b[1 : l-1]})
+				continue
+			}
+			n, err := strconv.Atoi(b)
+			if err != nil {
+				return p, fmt.Errorf("invalid jsonpath index %s in %s", b, expr)
+			}
+			p.segments = append(p.segments, segment{index: n, isIndex: true})
+		default:
+			return p, fmt.Errorf("invalid jsonpath %s", expr)
+		}
+	}
+
+	return p, nil
+}
+
+// String returns the expression the Path was compiled from
+func (p Path) String() string {
+	return p.expr
+}
+
+// Get returns the value selected by the Path from a document decoded into
+// map[string]interface{} and []interface{} values. Negative array indexes
+// select from the end of the array
+func (p Path) Get(document interface{}) (interface{}, error) {
+	v := document
+	for _, s := range p.segments {
+		if s.isIndex {
+			a, ok := v.([]interface{})
+			if !ok {
+				return nil, ErrNotFound
+			}
+			i := s.index
+			if i < 0 {
+				i += len(a)
+			}
+			if i < 0 || i >= len(a) {
+				return nil, ErrNotFound
+			}
+			v = a[i]
+			continue
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, ErrNotFound
+		}
+		if v, ok = m[s.key]; !ok {
+			return nil, ErrNotFound
+		}
+	}
+	return v, nil
+}
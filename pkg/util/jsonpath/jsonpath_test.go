@@ -0,0 +1,116 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jsonpath
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const testDocument = `{
+	"range": { "from": "now-1h", "to": "now" },
+	"queries": [
+		{ "refId": "A", "expr": "up", "intervalMs": 15000 },
+		{ "refId": "B", "expr": "rate(x[5m])" }
+	],
+	"dotted.key": true
+}`
+
+func TestParse(t *testing.T) {
+
+	tests := []struct {
+		expr     string
+		segments int
+		isErr    bool
+	}{
+		{"$", 0, false},
+		{"$.range.from", 2, false},
+		{"$.queries[0].expr", 3, false},
+		{"$['dotted.key']", 1, false},
+		{`$["range"]["to"]`, 2, false},
+		{"$.queries[-1]", 2, false},
+		{"range.from", 0, true},
+		{"$.", 0, true},
+		{"$..expr", 0, true},
+		{"$.queries[*]", 0, true},
+		{"$.queries[0", 0, true},
+		{"$.queries[a]", 0, true},
+		{"$range", 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			p, err := Parse(test.expr)
+			if test.isErr {
+				if err == nil {
+					t.Errorf("expected error for %s", test.expr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(p.segments) != test.segments {
+				t.Errorf("expected %d got %d", test.segments, len(p.segments))
+			}
+			if p.String() != test.expr {
+				t.Errorf("expected %s got %s", test.expr, p.String())
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+
+	var document map[string]interface{}
+	if err := json.Unmarshal([]byte(testDocument), &document); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr     string
+		expected interface{}
+		err      error
+	}{
+		{"$.range.from", "now-1h", nil},
+		{"$.queries[0].expr", "up", nil},
+		{"$.queries[0].intervalMs", float64(15000), nil},
+		{"$.queries[-1].refId", "B", nil},
+		{"$['dotted.key']", true, nil},
+		{"$.range.missing", nil, ErrNotFound},
+		{"$.queries[2]", nil, ErrNotFound},
+		{"$.queries[-3]", nil, ErrNotFound},
+		{"$.range[0]", nil, ErrNotFound},
+		{"$.queries.expr", nil, ErrNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			v, err := Parse(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			i, err := v.Get(document)
+			if err != test.err {
+				t.Errorf("expected %v got %v", test.err, err)
+			}
+			if i != test.expected {
+				t.Errorf("expected %v got %v", test.expected, i)
+			}
+		})
+	}
+}
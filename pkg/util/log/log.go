@@ -18,14 +18,17 @@
 package log
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/util/log/syslog"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
@@ -39,9 +42,26 @@ type Logger struct {
 	logger     log.Logger // the logger after leveling, which is used by importing packages
 	closer     io.Closer
 	level      string
+	levelMtx   sync.RWMutex // guards logger and level, which may change at runtime
 
 	onceMutex      *sync.Mutex
 	onceRanEntries map[string]bool
+
+	// origins is the registry of origin Loggers derived from this Logger, if any
+	origins *originLoggers
+}
+
+// originLoggers is a registry of the Loggers used by each origin
+type originLoggers struct {
+	loggers map[string]*originLogger
+	mtx     sync.Mutex
+}
+
+// originLogger is a Logger used by an origin, which follows the level of its
+// parent Logger unless a level has been set for the origin
+type originLogger struct {
+	*Logger
+	inheritsLevel bool
 }
 
 func mapToArray(event string, detail Pairs) []interface{} {
@@ -78,6 +98,7 @@ func noopLogger() *Logger {
 	return &Logger{
 		onceRanEntries: make(map[string]bool),
 		onceMutex:      &sync.Mutex{},
+		origins:        &originLoggers{loggers: make(map[string]*originLogger)},
 	}
 }
 
@@ -85,21 +106,56 @@ func noopLogger() *Logger {
 func ConsoleLogger(logLevel string) *Logger {
 
 	l := noopLogger()
-	wr := os.Stdout
-	l.baseLogger = log.NewLogfmtLogger(log.NewSyncWriter(wr))
-	l.baseLogger = log.With(l.baseLogger,
+	l.setBaseLogger(log.NewLogfmtLogger(log.NewSyncWriter(os.Stdout)))
+	l.SetLogLevel(logLevel)
+	return l
+}
+
+// setBaseLogger sets the Logger's base logger to one that writes events to base,
+// with the standard fields included
+func (tl *Logger) setBaseLogger(base log.Logger) {
+	tl.baseLogger = log.With(base,
 		"time", log.DefaultTimestampUTC,
 		"app", "trickster",
 		"caller", log.Valuer(func() interface{} {
 			return pkgCaller{stack.Caller(6)}
 		}),
 	)
-	l.SetLogLevel(logLevel)
-	return l
 }
 
-// SetLogLevel sets the log level, defaulting to "Info" if the provided level is unknown
+// Levels is the list of supported log levels
+var Levels = []string{"trace", "debug", "info", "warn", "error", "none"}
+
+// IsValidLevel returns true if the provided log level is supported
+func IsValidLevel(logLevel string) bool {
+	logLevel = strings.ToLower(logLevel)
+	for _, l := range Levels {
+		if l == logLevel {
+			return true
+		}
+	}
+	return false
+}
+
+// SetLogLevel sets the log level, defaulting to "Info" if the provided level is unknown.
+// Origin Loggers that do not have their own level follow the new level
 func (tl *Logger) SetLogLevel(logLevel string) {
+	tl.setLogLevel(logLevel)
+	if tl.origins == nil {
+		return
+	}
+	tl.origins.mtx.Lock()
+	for _, ol := range tl.origins.loggers {
+		if ol.inheritsLevel {
+			ol.setLogLevel(logLevel)
+		}
+	}
+	tl.origins.mtx.Unlock()
+}
+
+func (tl *Logger) setLogLevel(logLevel string) {
+	tl.levelMtx.Lock()
+	defer tl.levelMtx.Unlock()
 	tl.level = strings.ToLower(logLevel)
 	// wrap logger depending on log level
 	switch tl.level {
@@ -120,55 +176,271 @@ func (tl *Logger) SetLogLevel(logLevel string) {
 	}
 }
 
+// leveled returns the leveled logger and its level
+func (tl *Logger) leveled() (log.Logger, string) {
+	tl.levelMtx.RLock()
+	defer tl.levelMtx.RUnlock()
+	return tl.logger, tl.level
+}
+
+// OriginLogger returns a Logger for the named origin, which writes to the same output
+// as the subject Logger. The origin Logger logs at the provided level, or follows the
+// subject Logger's level when logLevel is empty. The origin Logger is registered so
+// that its level can be changed at runtime with SetOriginLogLevel
+func (tl *Logger) OriginLogger(name, logLevel string) *Logger {
+	if tl == nil || tl.origins == nil {
+		return tl
+	}
+	ol := tl.newOriginLogger(logLevel)
+	tl.origins.mtx.Lock()
+	tl.origins.loggers[name] = ol
+	tl.origins.mtx.Unlock()
+	return ol.Logger
+}
+
+// newOriginLogger returns an unregistered origin Logger derived from the subject Logger
+func (tl *Logger) newOriginLogger(logLevel string) *originLogger {
+	ol := &originLogger{
+		Logger: &Logger{
+			baseLogger:     tl.baseLogger,
+			onceMutex:      tl.onceMutex,
+			onceRanEntries: tl.onceRanEntries,
+		},
+		inheritsLevel: logLevel == "",
+	}
+	if ol.inheritsLevel {
+		logLevel = tl.Level()
+	}
+	ol.setLogLevel(logLevel)
+	return ol
+}
+
+// OriginLoggers is a set of origin Loggers derived from a Logger, which are not
+// registered with the Logger until the set is registered
+type OriginLoggers struct {
+	parent  *Logger
+	loggers map[string]*originLogger
+}
+
+// NewOriginLoggers returns an empty set of origin Loggers derived from the subject Logger
+func (tl *Logger) NewOriginLoggers() *OriginLoggers {
+	return &OriginLoggers{parent: tl, loggers: make(map[string]*originLogger)}
+}
+
+// Logger returns a Logger for the named origin, as OriginLogger does, without
+// registering it with the parent Logger
+func (ols *OriginLoggers) Logger(name, logLevel string) *Logger {
+	tl := ols.parent
+	if tl == nil || tl.origins == nil {
+		return tl
+	}
+	ol := tl.newOriginLogger(logLevel)
+	ols.loggers[name] = ol
+	return ol.Logger
+}
+
+// Register replaces the parent Logger's origin Loggers with those of the set. Origin
+// Loggers that do not have their own level take on the parent Logger's current level
+func (ols *OriginLoggers) Register() {
+	tl := ols.parent
+	if tl == nil || tl.origins == nil {
+		return
+	}
+	logLevel := tl.Level()
+	loggers := make(map[string]*originLogger, len(ols.loggers))
+	for k, ol := range ols.loggers {
+		if ol.inheritsLevel {
+			ol.setLogLevel(logLevel)
+		}
+		loggers[k] = ol
+	}
+	tl.origins.mtx.Lock()
+	tl.origins.loggers = loggers
+	tl.origins.mtx.Unlock()
+}
+
+// ClearOriginLoggers removes all origin Loggers from the subject Logger's registry
+func (tl *Logger) ClearOriginLoggers() {
+	if tl == nil || tl.origins == nil {
+		return
+	}
+	tl.origins.mtx.Lock()
+	tl.origins.loggers = make(map[string]*originLogger)
+	tl.origins.mtx.Unlock()
+}
+
+// SetOriginLogLevel sets the log level of the named origin's Logger. An empty logLevel
+// reverts the origin Logger to following the subject Logger's level. It returns false
+// if there is no Logger registered for the origin
+func (tl *Logger) SetOriginLogLevel(name, logLevel string) bool {
+	if tl == nil || tl.origins == nil {
+		return false
+	}
+	tl.origins.mtx.Lock()
+	defer tl.origins.mtx.Unlock()
+	ol, ok := tl.origins.loggers[name]
+	if !ok {
+		return false
+	}
+	ol.inheritsLevel = logLevel == ""
+	if ol.inheritsLevel {
+		logLevel = tl.Level()
+	}
+	ol.setLogLevel(logLevel)
+	return true
+}
+
+// OriginLogLevels returns the log level of each registered origin Logger
+func (tl *Logger) OriginLogLevels() map[string]string {
+	m := make(map[string]string)
+	if tl == nil || tl.origins == nil {
+		return m
+	}
+	tl.origins.mtx.Lock()
+	for k, ol := range tl.origins.loggers {
+		m[k] = ol.Level()
+	}
+	tl.origins.mtx.Unlock()
+	return m
+}
+
 // New returns a Logger for the provided logging configuration. The
 // returned Logger will write to files distinguished from other Loggers by the
 // instance string.
 func New(conf *config.Config) *Logger {
 
 	l := noopLogger()
-	var wr io.Writer
-
-	if conf.Logging.LogFile == "" {
-		wr = os.Stdout
-	} else {
-		logFile := conf.Logging.LogFile
+	lc := conf.Logging
+	var base log.Logger
+	var syslogErr error
+
+	if lc.SyslogAddress != "" {
+		var w *syslog.Writer
+		w, syslogErr = syslog.Dial(lc.SyslogAddress, lc.SyslogFacility, "trickster")
+		if syslogErr == nil {
+			base = &syslogLogger{w: w}
+			l.closer = w
+		}
+	} else if lc.LogFile != "" {
+		logFile := lc.LogFile
 		if conf.Main.InstanceID > 0 {
 			logFile = strings.Replace(logFile, ".log", "."+strconv.Itoa(conf.Main.InstanceID)+".log", 1)
 		}
-
-		wr = &lumberjack.Logger{
+		wr := &lumberjack.Logger{
 			Filename:   logFile,
-			MaxSize:    256,  // megabytes
-			MaxBackups: 80,   // 256 megs @ 80 backups is 20GB of Logs
-			MaxAge:     7,    // days
-			Compress:   true, // Compress Rolled Backups
+			MaxSize:    lc.LogMaxSizeMB,  // megabytes
+			MaxBackups: lc.LogMaxBackups, // 0 retains all rotated files
+			MaxAge:     lc.LogMaxAgeDays, // days
+			Compress:   lc.LogCompress,   // Compress Rolled Backups
 		}
+		base = log.NewLogfmtLogger(log.NewSyncWriter(wr))
+		l.closer = wr
 	}
 
-	l.baseLogger = log.NewLogfmtLogger(log.NewSyncWriter(wr))
-	l.baseLogger = log.With(l.baseLogger,
-		"time", log.DefaultTimestampUTC,
-		"app", "trickster",
-		"caller", log.Valuer(func() interface{} {
-			return pkgCaller{stack.Caller(6)}
-		}),
-	)
+	if base == nil {
+		base = log.NewLogfmtLogger(log.NewSyncWriter(os.Stdout))
+		if lc.ErrorsToStderr {
+			base = &splitLogger{
+				logger:      base,
+				errorLogger: log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr)),
+			}
+		}
+	}
 
-	l.SetLogLevel(conf.Logging.LogLevel)
+	l.setBaseLogger(base)
+	l.SetLogLevel(lc.LogLevel)
 
-	if c, ok := wr.(io.Closer); ok && c != nil {
-		l.closer = c
+	if syslogErr != nil {
+		l.Error("unable to connect to syslog server, logging to console instead",
+			Pairs{"syslogAddress": lc.SyslogAddress, "detail": syslogErr.Error()})
 	}
 
 	return l
 }
 
+// eventLevel returns the level of the log event described by keyvals
+func eventLevel(keyvals []interface{}) string {
+	for i := 0; i < len(keyvals)-1; i += 2 {
+		if fmt.Sprint(keyvals[i]) == "level" {
+			return fmt.Sprint(keyvals[i+1])
+		}
+	}
+	return ""
+}
+
+// splitLogger writes error and fatal events to errorLogger, and all other events to logger
+type splitLogger struct {
+	logger      log.Logger
+	errorLogger log.Logger
+}
+
+func (sl *splitLogger) Log(keyvals ...interface{}) error {
+	switch eventLevel(keyvals) {
+	case "error", "fatal":
+		return sl.errorLogger.Log(keyvals...)
+	}
+	return sl.logger.Log(keyvals...)
+}
+
+// syslogLogger sends logfmt-formatted events to a syslog server, with the
+// syslog severity that corresponds to the event's level
+type syslogLogger struct {
+	w *syslog.Writer
+}
+
+func (sl *syslogLogger) Log(keyvals ...interface{}) error {
+	var sev syslog.Severity
+	switch eventLevel(keyvals) {
+	case "fatal":
+		sev = syslog.SeverityCritical
+	case "error":
+		sev = syslog.SeverityError
+	case "warn":
+		sev = syslog.SeverityWarning
+	case "debug", "trace":
+		sev = syslog.SeverityDebug
+	default:
+		sev = syslog.SeverityInfo
+	}
+	buf := &bytes.Buffer{}
+	if err := log.NewLogfmtLogger(buf).Log(keyvals...); err != nil {
+		return err
+	}
+	return sl.w.Write(sev, buf.Bytes())
+}
+
+// With returns a Logger that includes the provided detail in every event it sends,
+// in addition to the detail provided to each call. This is used to attach
+// request-scoped fields, such as trace and span IDs, to log events
+func (tl *Logger) With(detail Pairs) *Logger {
+	if tl == nil || tl.baseLogger == nil || len(detail) == 0 {
+		return tl
+	}
+	keys := make([]string, 0, len(detail))
+	for k := range detail {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kvs := make([]interface{}, 0, len(keys)*2)
+	for _, k := range keys {
+		kvs = append(kvs, k, detail[k])
+	}
+	l := &Logger{
+		baseLogger:     log.With(tl.baseLogger, kvs...),
+		onceMutex:      tl.onceMutex,
+		onceRanEntries: tl.onceRanEntries,
+	}
+	l.setLogLevel(tl.Level())
+	return l
+}
+
 // Pairs represents a key=value pair that helps to describe a log event
 type Pairs map[string]interface{}
 
 // Info sends an "INFO" event to the Logger
 func (tl *Logger) Info(event string, detail Pairs) {
-	level.Info(tl.logger).Log(mapToArray(event, detail)...)
+	l, _ := tl.leveled()
+	level.Info(l).Log(mapToArray(event, detail)...)
 }
 
 // InfoOnce sends a "INFO" event to the Logger only once per key.
@@ -187,7 +459,8 @@ func (tl *Logger) InfoOnce(key string, event string, detail Pairs) bool {
 
 // Warn sends an "WARN" event to the Logger
 func (tl *Logger) Warn(event string, detail Pairs) {
-	level.Warn(tl.logger).Log(mapToArray(event, detail)...)
+	l, _ := tl.leveled()
+	level.Warn(l).Log(mapToArray(event, detail)...)
 }
 
 // WarnOnce sends a "WARN" event to the Logger only once per key.
@@ -215,7 +488,8 @@ func (tl *Logger) HasWarnedOnce(key string) bool {
 
 // Error sends an "ERROR" event to the Logger
 func (tl *Logger) Error(event string, detail Pairs) {
-	level.Error(tl.logger).Log(mapToArray(event, detail)...)
+	l, _ := tl.leveled()
+	level.Error(l).Log(mapToArray(event, detail)...)
 }
 
 // ErrorOnce sends an "ERROR" event to the Logger only once per key
@@ -234,15 +508,16 @@ func (tl *Logger) ErrorOnce(key string, event string, detail Pairs) bool {
 
 // Debug sends an "DEBUG" event to the Logger
 func (tl *Logger) Debug(event string, detail Pairs) {
-	level.Debug(tl.logger).Log(mapToArray(event, detail)...)
+	l, _ := tl.leveled()
+	level.Debug(l).Log(mapToArray(event, detail)...)
 }
 
 // Trace sends a "TRACE" event to the Logger
 func (tl *Logger) Trace(event string, detail Pairs) {
 	// go-kit/log/level does not support Trace, so implemented separately here
-	if tl.level == "trace" {
+	if l, lvl := tl.leveled(); lvl == "trace" {
 		detail["level"] = "trace"
-		tl.logger.Log(mapToArray(event, detail)...)
+		l.Log(mapToArray(event, detail)...)
 	}
 }
 
@@ -250,7 +525,8 @@ func (tl *Logger) Trace(event string, detail Pairs) {
 func (tl *Logger) Fatal(code int, event string, detail Pairs) {
 	// go-kit/log/level does not support Fatal, so implemented separately here
 	detail["level"] = "fatal"
-	tl.logger.Log(mapToArray(event, detail)...)
+	l, _ := tl.leveled()
+	l.Log(mapToArray(event, detail)...)
 	if code >= 0 {
 		os.Exit(code)
 	}
@@ -258,7 +534,8 @@ func (tl *Logger) Fatal(code int, event string, detail Pairs) {
 
 // Level returns the configured Log Level
 func (tl *Logger) Level() string {
-	return tl.level
+	_, lvl := tl.leveled()
+	return lvl
 }
 
 // Close closes any opened file handles that were used for logging.
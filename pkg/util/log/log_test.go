@@ -17,10 +17,16 @@
 package log
 
 import (
+	"bytes"
+	"net"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/config"
+
+	"github.com/go-kit/kit/log"
 )
 
 func TestConsoleLogger(t *testing.T) {
@@ -274,3 +280,207 @@ func TestSetLogLevel(t *testing.T) {
 	}
 
 }
+
+func TestWith(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+	l := noopLogger()
+	l.baseLogger = log.NewLogfmtLogger(buf)
+	l.SetLogLevel("info")
+
+	// empty detail and nil loggers return the subject logger
+	if l.With(nil) != l {
+		t.Error("expected same logger")
+	}
+	var nl *Logger
+	if nl.With(Pairs{"a": "b"}) != nil {
+		t.Error("expected nil logger")
+	}
+
+	l2 := l.With(Pairs{"trace_id": "1234", "span_id": "5678"})
+	if l2.Level() != "info" {
+		t.Errorf("expected %s got %s", "info", l2.Level())
+	}
+
+	l2.Debug("test debug", Pairs{})
+	if buf.Len() != 0 {
+		t.Errorf("expected empty log got %s", buf.String())
+	}
+
+	l2.Info("test entry", Pairs{"testKey": "testVal"})
+	line := buf.String()
+	if !strings.Contains(line, "span_id=5678 trace_id=1234") ||
+		!strings.Contains(line, "testKey=testVal") {
+		t.Errorf("unexpected log line %s", line)
+	}
+
+	// the parent logger does not include the detail
+	buf.Reset()
+	l.Info("test entry", Pairs{})
+	if strings.Contains(buf.String(), "trace_id") {
+		t.Errorf("unexpected log line %s", buf.String())
+	}
+}
+
+func TestSplitLogger(t *testing.T) {
+
+	out := &bytes.Buffer{}
+	errOut := &bytes.Buffer{}
+	l := noopLogger()
+	l.setBaseLogger(&splitLogger{
+		logger:      log.NewLogfmtLogger(out),
+		errorLogger: log.NewLogfmtLogger(errOut),
+	})
+	l.SetLogLevel("info")
+
+	l.Info("info event", Pairs{})
+	l.Error("error event", Pairs{})
+	l.Fatal(-1, "fatal event", Pairs{})
+
+	if s := out.String(); !strings.Contains(s, "info event") || strings.Contains(s, "error event") {
+		t.Errorf("unexpected stdout content: %s", s)
+	}
+	if s := errOut.String(); !strings.Contains(s, "error event") ||
+		!strings.Contains(s, "fatal event") || strings.Contains(s, "info event") {
+		t.Errorf("unexpected stderr content: %s", s)
+	}
+
+	conf := config.NewConfig()
+	conf.Logging.ErrorsToStderr = true
+	l = New(conf)
+	if l.closer != nil {
+		t.Error("expected nil closer for console logger")
+	}
+}
+
+func TestNewLogger_Syslog(t *testing.T) {
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	conf := config.NewConfig()
+	conf.Logging.SyslogAddress = "udp://" + pc.LocalAddr().String()
+	l := New(conf)
+	defer l.Close()
+	l.Warn("test entry", Pairs{"testKey": "testVal"})
+
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	b := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(b[:n])
+	// local0 (16) * 8 + warning (4)
+	if !strings.HasPrefix(msg, "<132>1 ") {
+		t.Errorf("unexpected syslog priority: %s", msg)
+	}
+	if !strings.Contains(msg, `level=warn event="test entry"`) ||
+		!strings.Contains(msg, "testKey=testVal") {
+		t.Errorf("unexpected syslog message: %s", msg)
+	}
+
+	// an unreachable syslog server falls back to the console
+	conf.Logging.SyslogAddress = "unix:///nonexistent/syslog.sock"
+	l = New(conf)
+	if l.closer != nil {
+		t.Error("expected nil closer for console logger")
+	}
+}
+
+func TestOriginLogger(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+	l := noopLogger()
+	l.setBaseLogger(log.NewLogfmtLogger(buf))
+	l.SetLogLevel("info")
+
+	ol1 := l.OriginLogger("origin1", "")
+	ol2 := l.OriginLogger("origin2", "debug")
+
+	ol1.Debug("origin1 debug event", Pairs{})
+	ol2.Debug("origin2 debug event", Pairs{})
+	if strings.Contains(buf.String(), "origin1 debug event") {
+		t.Error("unexpected debug event for origin1")
+	}
+	if !strings.Contains(buf.String(), "origin2 debug event") {
+		t.Error("expected debug event for origin2")
+	}
+
+	// origin1 follows the instance level, while origin2 keeps its own level
+	l.SetLogLevel("warn")
+	if ol1.Level() != "warn" || ol2.Level() != "debug" {
+		t.Errorf("unexpected levels: %s %s", ol1.Level(), ol2.Level())
+	}
+
+	if !l.SetOriginLogLevel("origin1", "ERROR") || ol1.Level() != "error" {
+		t.Error("expected origin1 level to be error")
+	}
+	if !l.SetOriginLogLevel("origin2", "") || ol2.Level() != "warn" {
+		t.Error("expected origin2 level to be warn")
+	}
+	if l.SetOriginLogLevel("origin3", "info") {
+		t.Error("expected false for unknown origin")
+	}
+
+	levels := l.OriginLogLevels()
+	if len(levels) != 2 || levels["origin1"] != "error" || levels["origin2"] != "warn" {
+		t.Errorf("unexpected origin levels: %v", levels)
+	}
+
+	l.ClearOriginLoggers()
+	if len(l.OriginLogLevels()) != 0 {
+		t.Error("expected no origin loggers")
+	}
+
+	var nl *Logger
+	if nl.OriginLogger("origin1", "debug") != nil || nl.SetOriginLogLevel("origin1", "") ||
+		len(nl.OriginLogLevels()) != 0 {
+		t.Error("unexpected result for nil logger")
+	}
+	nl.ClearOriginLoggers()
+}
+
+func TestOriginLoggers(t *testing.T) {
+
+	l := noopLogger()
+	l.setBaseLogger(log.NewLogfmtLogger(&bytes.Buffer{}))
+	l.SetLogLevel("info")
+	l.OriginLogger("running", "debug")
+
+	ols := l.NewOriginLoggers()
+	ol1 := ols.Logger("origin1", "")
+	ol2 := ols.Logger("origin2", "error")
+
+	// the set's loggers are not registered until the set is
+	if levels := l.OriginLogLevels(); len(levels) != 1 || levels["running"] != "debug" {
+		t.Errorf("unexpected origin levels: %v", levels)
+	}
+
+	l.SetLogLevel("warn")
+	ols.Register()
+	levels := l.OriginLogLevels()
+	if len(levels) != 2 || levels["origin1"] != "warn" || levels["origin2"] != "error" {
+		t.Errorf("unexpected origin levels: %v", levels)
+	}
+	if !l.SetOriginLogLevel("origin1", "debug") || ol1.Level() != "debug" ||
+		ol2.Level() != "error" {
+		t.Errorf("unexpected levels: %s %s", ol1.Level(), ol2.Level())
+	}
+
+	var nl *Logger
+	nols := nl.NewOriginLoggers()
+	if nols.Logger("origin1", "debug") != nil {
+		t.Error("unexpected result for nil logger")
+	}
+	nols.Register()
+}
+
+func TestIsValidLevel(t *testing.T) {
+	if !IsValidLevel("DEBUG") || !IsValidLevel("none") || IsValidLevel("x") || IsValidLevel("") {
+		t.Error("unexpected level validity")
+	}
+}
@@ -0,0 +1,179 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package syslog provides a client that sends RFC 5424 formatted messages to a
+// syslog server over UDP, TCP or a Unix domain socket
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Severity is the severity of a syslog message
+type Severity int
+
+// Syslog message severities, as defined in RFC 5424 section 6.2.1
+const (
+	SeverityEmergency Severity = iota
+	SeverityAlert
+	SeverityCritical
+	SeverityError
+	SeverityWarning
+	SeverityNotice
+	SeverityInfo
+	SeverityDebug
+)
+
+// Facilities maps the supported facility names to their RFC 5424 codes
+var Facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// ErrInvalidAddress is returned when a syslog server address is not valid
+var ErrInvalidAddress = errors.New(
+	"syslog address must be in the form of udp://host:port, tcp://host:port, unix:///path or unixgram:///path")
+
+// ParseAddress returns the network and address to dial for the provided syslog
+// server address (e.g., udp://127.0.0.1:514 or unix:///dev/log)
+func ParseAddress(address string) (string, string, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", "", ErrInvalidAddress
+	}
+	switch u.Scheme {
+	case "udp", "tcp":
+		if u.Host == "" || u.Port() == "" {
+			return "", "", ErrInvalidAddress
+		}
+		return u.Scheme, u.Host, nil
+	case "unix", "unixgram":
+		if u.Path == "" {
+			return "", "", ErrInvalidAddress
+		}
+		return u.Scheme, u.Path, nil
+	}
+	return "", "", ErrInvalidAddress
+}
+
+// Writer sends messages to a syslog server. It is safe for concurrent use
+type Writer struct {
+	network  string
+	address  string
+	facility int
+	hostname string
+	appName  string
+	procID   string
+
+	conn net.Conn
+	mtx  sync.Mutex
+}
+
+// Dial connects to the syslog server at the provided address, and returns a
+// Writer that sends messages with the provided facility and application name
+func Dial(address, facility, appName string) (*Writer, error) {
+	network, addr, err := ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	fc, ok := Facilities[strings.ToLower(facility)]
+	if !ok {
+		return nil, fmt.Errorf("invalid syslog facility: %s", facility)
+	}
+	hn, err := os.Hostname()
+	if err != nil || hn == "" {
+		hn = "-"
+	}
+	w := &Writer{
+		network:  network,
+		address:  addr,
+		facility: fc,
+		hostname: hn,
+		appName:  appName,
+		procID:   strconv.Itoa(os.Getpid()),
+	}
+	if err = w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) connect() error {
+	conn, err := net.Dial(w.network, w.address)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+// Write sends msg to the syslog server with the provided severity. If the message
+// cannot be sent, the Writer reconnects to the server and retries once
+func (w *Writer) Write(sev Severity, msg []byte) error {
+	b := w.format(sev, time.Now(), msg)
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.conn != nil {
+		if _, err := w.conn.Write(b); err == nil {
+			return nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	if err := w.connect(); err != nil {
+		return err
+	}
+	_, err := w.conn.Write(b)
+	return err
+}
+
+// format returns msg as an RFC 5424 message. Messages sent over TCP are framed
+// using octet counting, as described in RFC 6587 section 3.4.1
+func (w *Writer) format(sev Severity, t time.Time, msg []byte) []byte {
+	msg = bytes.TrimRight(msg, "\n")
+	header := fmt.Sprintf("<%d>1 %s %s %s %s - - ", w.facility*8+int(sev),
+		t.UTC().Format("2006-01-02T15:04:05.000000Z07:00"), w.hostname, w.appName, w.procID)
+	b := make([]byte, 0, len(header)+len(msg)+8)
+	if w.network == "tcp" {
+		b = append(b, strconv.Itoa(len(header)+len(msg))...)
+		b = append(b, ' ')
+	}
+	b = append(b, header...)
+	return append(b, msg...)
+}
+
+// Close closes the connection to the syslog server
+func (w *Writer) Close() error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package syslog
+
+import (
+	"bufio"
+	"net"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParseAddress(t *testing.T) {
+
+	tests := []struct {
+		address, network, addr string
+		expectErr              bool
+	}{
+		{"udp://127.0.0.1:514", "udp", "127.0.0.1:514", false},
+		{"tcp://syslog.example.com:601", "tcp", "syslog.example.com:601", false},
+		{"unix:///dev/log", "unix", "/dev/log", false},
+		{"unixgram:///dev/log", "unixgram", "/dev/log", false},
+		{"udp://127.0.0.1", "", "", true},
+		{"unix://", "", "", true},
+		{"http://127.0.0.1:514", "", "", true},
+		{"127.0.0.1:514", "", "", true},
+		{"%", "", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.address, func(t *testing.T) {
+			network, addr, err := ParseAddress(test.address)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("unexpected error result: %v", err)
+			}
+			if network != test.network || addr != test.addr {
+				t.Errorf("expected %s %s got %s %s", test.network, test.addr, network, addr)
+			}
+		})
+	}
+}
+
+func TestDialInvalid(t *testing.T) {
+	if _, err := Dial("invalid", "local0", "trickster"); err != ErrInvalidAddress {
+		t.Errorf("expected %v got %v", ErrInvalidAddress, err)
+	}
+	const expected = "invalid syslog facility: invalid"
+	if _, err := Dial("udp://127.0.0.1:514", "invalid", "trickster"); err == nil ||
+		err.Error() != expected {
+		t.Errorf("expected %s got %v", expected, err)
+	}
+}
+
+var messageRE = regexp.MustCompile(`^<(\d+)>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}Z \S+ trickster \d+ - - (.*)$`)
+
+func TestWriteUDP(t *testing.T) {
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	w, err := Dial("udp://"+pc.LocalAddr().String(), "LOCAL0", "trickster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err = w.Write(SeverityError, []byte("level=error event=test\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	b := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := messageRE.FindStringSubmatch(string(b[:n]))
+	if m == nil {
+		t.Fatalf("unexpected message: %s", string(b[:n]))
+	}
+	// local0 (16) * 8 + error (3)
+	if m[1] != "131" {
+		t.Errorf("expected priority %s got %s", "131", m[1])
+	}
+	if m[2] != "level=error event=test" {
+		t.Errorf("unexpected message body: %s", m[2])
+	}
+
+	if err = w.Close(); err != nil {
+		t.Error(err)
+	}
+	// a closed writer reconnects on the next write
+	if err = w.Write(SeverityInfo, []byte("reconnected")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWriteTCP(t *testing.T) {
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	w, err := Dial("tcp://"+ln.Addr().String(), "daemon", "trickster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if err = w.Write(SeverityWarning, []byte("event=test")); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	length, err := r.ReadString(' ')
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 1024)
+	n, err := r.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if length != strconv.Itoa(n)+" " {
+		t.Errorf("expected frame length %d got %s", n, length)
+	}
+	m := messageRE.FindStringSubmatch(string(b[:n]))
+	if m == nil {
+		t.Fatalf("unexpected message: %s", string(b[:n]))
+	}
+	// daemon (3) * 8 + warning (4)
+	if m[1] != "28" {
+		t.Errorf("expected priority %s got %s", "28", m[1])
+	}
+}
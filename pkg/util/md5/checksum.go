@@ -26,3 +26,8 @@ import (
 func Checksum(input string) string {
 	return fmt.Sprintf("%x", md5.Sum([]byte(input)))
 }
+
+// ChecksumBytes returns the calculated hex string version of the md5 checksum for the input bytes
+func ChecksumBytes(input []byte) string {
+	return fmt.Sprintf("%x", md5.Sum(input))
+}
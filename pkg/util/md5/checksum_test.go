@@ -35,3 +35,14 @@ func TestChecksum(t *testing.T) {
 	}
 
 }
+
+func TestChecksumBytes(t *testing.T) {
+
+	input := []byte("test")
+	expected := "098f6bcd4621d373cade4e832627b4f6"
+	result := ChecksumBytes(input)
+	if expected != result {
+		t.Errorf("unexpected checksum for '%s', wanted %s got %s", input, expected, result)
+	}
+
+}
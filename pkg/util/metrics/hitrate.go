@@ -0,0 +1,154 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// hitRateSlotSecs is the width, in seconds, of each slot of the rolling hit rate windows
+const hitRateSlotSecs = 10
+
+// hitRateWindows are the rolling windows over which the hit rate of each origin is reported,
+// with the number of slots in each. The longest window determines the ring size
+var hitRateWindows = []struct {
+	name  string
+	slots int64
+}{
+	{"5m", 30},
+	{"1h", 360},
+}
+
+const hitRateRingSize = 360
+
+// hitRateHits are the cache lookup statuses that count as hits. Partial hits count, since
+// the bulk of their response is served from the cache
+var hitRateHits = map[string]bool{"hit": true, "phit": true, "rhit": true, "nchit": true}
+
+// hitRateExclusions are the cache lookup statuses that don't count as lookups, since the
+// requests were not eligible to be served from the cache
+var hitRateExclusions = map[string]bool{"proxy-only": true, "purge": true}
+
+// ProxyCacheHitRate is a Gauge of the rolling cache hit rate of each origin, by window,
+// which is computed internally so that alert rules need not derive it
+var ProxyCacheHitRate = newHitRates(time.Now)
+
+// ObserveCacheLookup records the cache lookup status of a request to the origin in its
+// rolling hit rates
+func ObserveCacheLookup(originName, cacheStatus string) {
+	ProxyCacheHitRate.observe(originName, cacheStatus)
+}
+
+type hitRateSlot struct {
+	slot    int64
+	hits    uint64
+	lookups uint64
+}
+
+// hitRateRing holds the lookups of an origin, in slots of hitRateSlotSecs, over the longest
+// hit rate window
+type hitRateRing struct {
+	mtx   sync.Mutex
+	slots [hitRateRingSize]hitRateSlot
+}
+
+func (r *hitRateRing) observe(slot int64, hit bool) {
+	r.mtx.Lock()
+	s := &r.slots[slot%hitRateRingSize]
+	if s.slot != slot {
+		*s = hitRateSlot{slot: slot}
+	}
+	s.lookups++
+	if hit {
+		s.hits++
+	}
+	r.mtx.Unlock()
+}
+
+// sum returns the hits and lookups in the n slots ending with the current slot
+func (r *hitRateRing) sum(slot, n int64) (uint64, uint64) {
+	var hits, lookups uint64
+	r.mtx.Lock()
+	for i := range r.slots {
+		s := &r.slots[i]
+		if s.slot > slot-n && s.slot <= slot {
+			hits += s.hits
+			lookups += s.lookups
+		}
+	}
+	r.mtx.Unlock()
+	return hits, lookups
+}
+
+// HitRates is a prometheus.Collector that reports the rolling cache hit rates of each origin
+type HitRates struct {
+	desc  *prometheus.Desc
+	now   func() time.Time
+	rings sync.Map
+}
+
+func newHitRates(now func() time.Time) *HitRates {
+	return &HitRates{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricNamespace, proxySubsystem, "cache_hit_rate"),
+			"Rolling ratio of cache lookups that were hits, by origin and window.",
+			[]string{"origin_name", "window"}, nil,
+		),
+		now: now,
+	}
+}
+
+func (h *HitRates) observe(originName, cacheStatus string) {
+	if hitRateExclusions[cacheStatus] {
+		return
+	}
+	v, ok := h.rings.Load(originName)
+	if !ok {
+		v, _ = h.rings.LoadOrStore(originName, &hitRateRing{})
+	}
+	v.(*hitRateRing).observe(h.now().Unix()/hitRateSlotSecs, hitRateHits[cacheStatus])
+}
+
+// Describe implements prometheus.Collector
+func (h *HitRates) Describe(ch chan<- *prometheus.Desc) {
+	ch <- h.desc
+}
+
+// Collect implements prometheus.Collector. Windows without lookups are not reported, and
+// origins without lookups in any window are forgotten, so removed origins age out
+func (h *HitRates) Collect(ch chan<- prometheus.Metric) {
+	slot := h.now().Unix() / hitRateSlotSecs
+	h.rings.Range(func(k, v interface{}) bool {
+		var active bool
+		for _, w := range hitRateWindows {
+			hits, lookups := v.(*hitRateRing).sum(slot, w.slots)
+			if lookups == 0 {
+				continue
+			}
+			active = true
+			ch <- prometheus.MustNewConstMetric(h.desc, prometheus.GaugeValue,
+				float64(hits)/float64(lookups), k.(string), w.name)
+		}
+		if !active {
+			h.rings.Delete(k)
+		}
+		return true
+	})
+}
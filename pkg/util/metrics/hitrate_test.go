@@ -0,0 +1,93 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func collectHitRates(t *testing.T, h *HitRates) map[string]float64 {
+	ch := make(chan prometheus.Metric, 16)
+	h.Collect(ch)
+	close(ch)
+	rates := make(map[string]float64)
+	for m := range ch {
+		d := &dto.Metric{}
+		if err := m.Write(d); err != nil {
+			t.Fatal(err)
+		}
+		var origin, window string
+		for _, l := range d.Label {
+			switch l.GetName() {
+			case "origin_name":
+				origin = l.GetValue()
+			case "window":
+				window = l.GetValue()
+			}
+		}
+		rates[origin+"/"+window] = d.GetGauge().GetValue()
+	}
+	return rates
+}
+
+func TestHitRates(t *testing.T) {
+
+	now := time.Unix(1600000000, 0)
+	h := newHitRates(func() time.Time { return now })
+
+	// lookups 30m ago only fall in the 1h window
+	now = now.Add(-30 * time.Minute)
+	h.observe("prom1", "kmiss")
+	h.observe("prom1", "kmiss")
+	now = now.Add(30 * time.Minute)
+
+	h.observe("prom1", "hit")
+	h.observe("prom1", "phit")
+	h.observe("prom1", "proxy-only")
+	h.observe("prom1", "purge")
+
+	rates := collectHitRates(t, h)
+	if len(rates) != 2 {
+		t.Fatalf("expected 2 rates got %v", rates)
+	}
+	if v := rates["prom1/5m"]; v != 1 {
+		t.Errorf("expected %f got %f", 1.0, v)
+	}
+	if v := rates["prom1/1h"]; v != 0.5 {
+		t.Errorf("expected %f got %f", 0.5, v)
+	}
+
+	// after 10m, only the 1h window has lookups
+	now = now.Add(10 * time.Minute)
+	rates = collectHitRates(t, h)
+	if _, ok := rates["prom1/5m"]; ok || len(rates) != 1 {
+		t.Errorf("expected only the 1h rate got %v", rates)
+	}
+
+	// after an hour without lookups, the origin is forgotten
+	now = now.Add(time.Hour)
+	if rates = collectHitRates(t, h); len(rates) != 0 {
+		t.Errorf("expected no rates got %v", rates)
+	}
+	if _, ok := h.rings.Load("prom1"); ok {
+		t.Error("expected origin to be forgotten")
+	}
+}
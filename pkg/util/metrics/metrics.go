@@ -18,7 +18,9 @@
 package metrics
 
 import (
+	"errors"
 	"net/http"
+	"sync/atomic"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -31,13 +33,18 @@ const (
 	configSubsystem   = "config"
 	buildSubsystem    = "build"
 	frontendSubsystem = "frontend"
+	locksSubsystem    = "locks"
 )
 
 // Default histogram buckets used by trickster
 var (
-	defaultBuckets = []float64{0.05, 0.1, 0.5, 1, 5, 10, 20}
+	defaultBuckets  = []float64{0.05, 0.1, 0.5, 1, 5, 10, 20}
+	lockWaitBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
 )
 
+// ErrInvalidBuckets is returned when histogram buckets are not in strictly increasing order
+var ErrInvalidBuckets = errors.New("histogram buckets must be in strictly increasing order")
+
 // BuildInfo is a Gauge representing the Trickster binary build information of the running server instance
 var BuildInfo *prometheus.GaugeVec
 
@@ -65,6 +72,59 @@ var ProxyRequestElements *prometheus.CounterVec
 // ProxyRequestDuration is a Histogram of time required in seconds to proxy a given Prometheus query
 var ProxyRequestDuration *prometheus.HistogramVec
 
+// ProxyPushes is a Counter of companion responses pushed to HTTP/2 clients, by the path
+// of the response that they accompanied
+var ProxyPushes *prometheus.CounterVec
+
+// ProxyPriorityActive is a Gauge of the requests being handled by origin and priority class
+var ProxyPriorityActive *prometheus.GaugeVec
+
+// ProxyPriorityQueued is a Gauge of the requests waiting for a turn by origin and priority class
+var ProxyPriorityQueued *prometheus.GaugeVec
+
+// ProxyPriorityRejected is a Counter of the requests rejected by their priority class, by
+// origin, priority class and reason
+var ProxyPriorityRejected *prometheus.CounterVec
+
+// ProxyConcurrencyLimit is a Gauge of the adaptive concurrency limit of each origin
+var ProxyConcurrencyLimit *prometheus.GaugeVec
+
+// ProxyConcurrencyRejected is a Counter of upstream requests shed by the adaptive
+// concurrency limit of each origin
+var ProxyConcurrencyRejected *prometheus.CounterVec
+
+// ProxyTargetRTT is a Gauge of the smoothed health check round trip time, in seconds, of
+// each of an origin's targets
+var ProxyTargetRTT *prometheus.GaugeVec
+
+// ProxyTargetUp is a Gauge that is 1 while an origin's target is healthy, and 0 while its
+// health checks report it as down
+var ProxyTargetUp *prometheus.GaugeVec
+
+// ProxyCanceledFetches is a Counter of timeseries requests whose upstream fetches were
+// canceled because their client disconnected, by origin and path
+var ProxyCanceledFetches *prometheus.CounterVec
+
+// ProxyDeltaVerifications is a Counter of the delta fetch overlaps that were compared with
+// the cached data, by origin and whether their samples matched
+var ProxyDeltaVerifications *prometheus.CounterVec
+
+// ProxyBufferedBytes is a Gauge of the bytes that the caching engines are buffering for
+// in-flight requests, which count against the memory budget
+var ProxyBufferedBytes prometheus.Gauge
+
+// ProxyMemoryBudgetDegraded is a Counter of cacheable requests that were proxied without
+// caching because the memory budget was exceeded, by origin and path
+var ProxyMemoryBudgetDegraded *prometheus.CounterVec
+
+// ProxyCacheRequests is a Counter of proxied requests by path config, cache lookup status and
+// response status class, from which per-path cache hit rates can be calculated
+var ProxyCacheRequests *prometheus.CounterVec
+
+// ProxyCacheBytes is a Counter of bytes written to clients by path config, cache lookup status
+// and response status class
+var ProxyCacheBytes *prometheus.CounterVec
+
 // CacheObjectOperations is a Counter of operations (in # of objects) performed on a Trickster cache
 var CacheObjectOperations *prometheus.CounterVec
 
@@ -104,6 +164,19 @@ var ProxyConnectionClosed prometheus.Counter
 // ProxyConnectionFailed is a counter for the total number of connections failed to connect for whatever reason
 var ProxyConnectionFailed prometheus.Counter
 
+// ProxyListenerRestarts is a counter of the listeners restarted after failing, by listener
+// name and reason
+var ProxyListenerRestarts *prometheus.CounterVec
+
+// LockWaitDuration is a Histogram of the time in seconds spent waiting to acquire named locks, by lock mode
+var LockWaitDuration *prometheus.HistogramVec
+
+// LockQueueSize is a Gauge of the number of callers holding or waiting to acquire named locks
+var LockQueueSize prometheus.Gauge
+
+// LockLongHolds is a Counter of named locks held longer than the long hold threshold, by lock mode
+var LockLongHolds *prometheus.CounterVec
+
 func init() {
 
 	BuildInfo = prometheus.NewGaugeVec(
@@ -185,15 +258,145 @@ func init() {
 		[]string{"origin_name", "origin_type", "cache_status", "path"},
 	)
 
-	ProxyRequestDuration = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
+	ProxyRequestDuration = newProxyRequestDuration(defaultBuckets)
+
+	ProxyPushes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
 			Namespace: metricNamespace,
 			Subsystem: proxySubsystem,
-			Name:      "request_duration_seconds",
-			Help:      "Time required in seconds to proxy a given Prometheus query.",
-			Buckets:   defaultBuckets,
+			Name:      "pushes_total",
+			Help:      "Count of companion responses pushed to HTTP/2 clients.",
 		},
-		[]string{"origin_name", "origin_type", "method", "status", "http_status", "path"},
+		[]string{"origin_name", "origin_type", "path"},
+	)
+
+	ProxyPriorityActive = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "priority_active_requests",
+			Help:      "Number of requests being handled, by priority class.",
+		},
+		[]string{"origin_name", "priority_class"},
+	)
+
+	ProxyPriorityQueued = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "priority_queued_requests",
+			Help:      "Number of requests waiting for a turn, by priority class.",
+		},
+		[]string{"origin_name", "priority_class"},
+	)
+
+	ProxyPriorityRejected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "priority_rejected_requests_total",
+			Help:      "Count of requests rejected by their priority class.",
+		},
+		[]string{"origin_name", "priority_class", "reason"},
+	)
+
+	ProxyConcurrencyLimit = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "origin_concurrency_limit",
+			Help:      "Adaptive limit of concurrent requests to the origin.",
+		},
+		[]string{"origin_name"},
+	)
+
+	ProxyConcurrencyRejected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "origin_concurrency_rejected_total",
+			Help:      "Count of upstream requests shed by the origin's adaptive concurrency limit.",
+		},
+		[]string{"origin_name"},
+	)
+
+	ProxyTargetRTT = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "origin_target_rtt_seconds",
+			Help:      "Smoothed health check round trip time of the origin's target.",
+		},
+		[]string{"origin_name", "target"},
+	)
+
+	ProxyTargetUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "origin_target_up",
+			Help:      "Whether the origin's target is healthy (1) or down (0).",
+		},
+		[]string{"origin_name", "target"},
+	)
+
+	ProxyCanceledFetches = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "canceled_fetches_total",
+			Help:      "Count of requests whose upstream fetches were canceled because the client disconnected.",
+		},
+		[]string{"origin_name", "origin_type", "path"},
+	)
+
+	ProxyDeltaVerifications = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "delta_verifications_total",
+			Help:      "Count of delta fetch overlaps compared with the cached data, by whether their samples matched.",
+		},
+		[]string{"origin_name", "origin_type", "result"},
+	)
+
+	ProxyBufferedBytes = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "buffered_bytes",
+			Help:      "Number of bytes buffered by the caching engines for in-flight requests.",
+		},
+	)
+
+	ProxyMemoryBudgetDegraded = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "memory_budget_degraded_total",
+			Help:      "Count of cacheable requests proxied without caching because the memory budget was exceeded.",
+		},
+		[]string{"origin_name", "origin_type", "path"},
+	)
+
+	ProxyCacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "cache_requests_total",
+			Help:      "Count of proxied requests by path config, cache lookup status and response status class.",
+		},
+		[]string{"origin_name", "origin_type", "path", "cache_status", "http_status_class"},
+	)
+
+	ProxyCacheBytes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "cache_written_bytes_total",
+			Help:      "Count of bytes written to clients by path config, cache lookup status and response status class.",
+		},
+		[]string{"origin_name", "origin_type", "path", "cache_status", "http_status_class"},
 	)
 
 	ProxyMaxConnections = prometheus.NewGauge(
@@ -249,6 +452,16 @@ func init() {
 		},
 	)
 
+	ProxyListenerRestarts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "listener_restarts_total",
+			Help:      "Trickster total number of listeners restarted after failing.",
+		},
+		[]string{"listener_name", "reason"},
+	)
+
 	CacheObjectOperations = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: metricNamespace,
@@ -319,6 +532,36 @@ func init() {
 		[]string{"cache_name", "cache_type"},
 	)
 
+	LockWaitDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Subsystem: locksSubsystem,
+			Name:      "wait_duration_seconds",
+			Help:      "Histogram of the time spent waiting to acquire named locks.",
+			Buckets:   lockWaitBuckets,
+		},
+		[]string{"mode"},
+	)
+
+	LockQueueSize = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: locksSubsystem,
+			Name:      "queued",
+			Help:      "Number of callers holding or waiting to acquire named locks.",
+		},
+	)
+
+	LockLongHolds = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: locksSubsystem,
+			Name:      "long_holds_total",
+			Help:      "Count of named locks held longer than the long hold threshold.",
+		},
+		[]string{"mode"},
+	)
+
 	// Register Metrics
 	prometheus.MustRegister(FrontendRequestStatus)
 	prometheus.MustRegister(FrontendRequestDuration)
@@ -326,12 +569,28 @@ func init() {
 	prometheus.MustRegister(ProxyRequestStatus)
 	prometheus.MustRegister(ProxyRequestElements)
 	prometheus.MustRegister(ProxyRequestDuration)
+	prometheus.MustRegister(ProxyPushes)
+	prometheus.MustRegister(ProxyPriorityActive)
+	prometheus.MustRegister(ProxyPriorityQueued)
+	prometheus.MustRegister(ProxyPriorityRejected)
+	prometheus.MustRegister(ProxyConcurrencyLimit)
+	prometheus.MustRegister(ProxyConcurrencyRejected)
+	prometheus.MustRegister(ProxyTargetRTT)
+	prometheus.MustRegister(ProxyTargetUp)
+	prometheus.MustRegister(ProxyCanceledFetches)
+	prometheus.MustRegister(ProxyDeltaVerifications)
+	prometheus.MustRegister(ProxyBufferedBytes)
+	prometheus.MustRegister(ProxyMemoryBudgetDegraded)
+	prometheus.MustRegister(ProxyCacheRequests)
+	prometheus.MustRegister(ProxyCacheBytes)
+	prometheus.MustRegister(ProxyCacheHitRate)
 	prometheus.MustRegister(ProxyMaxConnections)
 	prometheus.MustRegister(ProxyActiveConnections)
 	prometheus.MustRegister(ProxyConnectionRequested)
 	prometheus.MustRegister(ProxyConnectionAccepted)
 	prometheus.MustRegister(ProxyConnectionClosed)
 	prometheus.MustRegister(ProxyConnectionFailed)
+	prometheus.MustRegister(ProxyListenerRestarts)
 	prometheus.MustRegister(CacheObjectOperations)
 	prometheus.MustRegister(CacheByteOperations)
 	prometheus.MustRegister(CacheEvents)
@@ -339,12 +598,94 @@ func init() {
 	prometheus.MustRegister(CacheBytes)
 	prometheus.MustRegister(CacheMaxObjects)
 	prometheus.MustRegister(CacheMaxBytes)
+	prometheus.MustRegister(LockWaitDuration)
+	prometheus.MustRegister(LockQueueSize)
+	prometheus.MustRegister(LockLongHolds)
 	prometheus.MustRegister(BuildInfo)
 	prometheus.MustRegister(LastReloadSuccessful)
 	prometheus.MustRegister(LastReloadSuccessfulTimestamp)
 }
 
+// exemplarsEnabled is 1 when trace exemplars are attached to the request duration histograms
+var exemplarsEnabled int32
+
+// SetExemplarsEnabled sets whether trace exemplars are attached to the request duration
+// histograms, and whether the metrics Handler offers the OpenMetrics format that exposes them
+func SetExemplarsEnabled(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&exemplarsEnabled, v)
+}
+
+// ExemplarsEnabled returns true when trace exemplars are attached to the request duration histograms
+func ExemplarsEnabled() bool {
+	return atomic.LoadInt32(&exemplarsEnabled) == 1
+}
+
+// ObserveWithTraceID observes the value in the provided Observer. When exemplars are enabled
+// and a trace ID is provided, the trace ID is attached to the observation as an exemplar
+func ObserveWithTraceID(o prometheus.Observer, v float64, traceID string) {
+	if traceID != "" && ExemplarsEnabled() {
+		if eo, ok := o.(prometheus.ExemplarObserver); ok {
+			eo.ObserveWithExemplar(v, prometheus.Labels{"trace_id": traceID})
+			return
+		}
+	}
+	o.Observe(v)
+}
+
 // Handler returns the http handler for the listener
 func Handler() http.Handler {
-	return promhttp.Handler()
+	if !ExemplarsEnabled() {
+		return promhttp.Handler()
+	}
+	// exemplars are only exposed by the OpenMetrics format
+	return promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer,
+			promhttp.HandlerOpts{EnableOpenMetrics: true}))
+}
+
+func newProxyRequestDuration(buckets []float64) *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "request_duration_seconds",
+			Help:      "Time required in seconds to proxy a given Prometheus query.",
+			Buckets:   buckets,
+		},
+		[]string{"origin_name", "origin_type", "method", "status", "http_status", "path"},
+	)
+}
+
+// ValidateBuckets returns an error if the provided histogram buckets are not valid
+func ValidateBuckets(buckets []float64) error {
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			return ErrInvalidBuckets
+		}
+	}
+	return nil
+}
+
+// SetProxyDurationBuckets replaces ProxyRequestDuration with a histogram using the provided
+// buckets, or the default buckets when none are provided. Since ProxyRequestDuration is not
+// guarded for concurrent access, this must only be called before the proxy starts serving
+func SetProxyDurationBuckets(buckets []float64) error {
+	if len(buckets) == 0 {
+		buckets = defaultBuckets
+	}
+	if err := ValidateBuckets(buckets); err != nil {
+		return err
+	}
+	h := newProxyRequestDuration(buckets)
+	prometheus.Unregister(ProxyRequestDuration)
+	if err := prometheus.Register(h); err != nil {
+		prometheus.MustRegister(ProxyRequestDuration)
+		return err
+	}
+	ProxyRequestDuration = h
+	return nil
 }
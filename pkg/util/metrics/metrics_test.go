@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestValidateBuckets(t *testing.T) {
+	if err := ValidateBuckets(nil); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateBuckets([]float64{0.1, 1, 10}); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateBuckets([]float64{0.1, 10, 1}); err != ErrInvalidBuckets {
+		t.Errorf("expected %v got %v", ErrInvalidBuckets, err)
+	}
+}
+
+func TestSetProxyDurationBuckets(t *testing.T) {
+	defer SetProxyDurationBuckets(nil)
+
+	if err := SetProxyDurationBuckets([]float64{1, 0.5}); err != ErrInvalidBuckets {
+		t.Errorf("expected %v got %v", ErrInvalidBuckets, err)
+	}
+
+	h := ProxyRequestDuration
+	if err := SetProxyDurationBuckets([]float64{0.25, 2.5}); err != nil {
+		t.Fatal(err)
+	}
+	if ProxyRequestDuration == h {
+		t.Error("expected histogram to be replaced")
+	}
+	ProxyRequestDuration.WithLabelValues("a", "b", "c", "d", "e", "f").Observe(1)
+}
+
+func TestObserveWithTraceID(t *testing.T) {
+	defer SetExemplarsEnabled(false)
+
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test", Buckets: []float64{1}})
+	m := &dto.Metric{}
+
+	ObserveWithTraceID(h, 0.5, "abc")
+	h.Write(m)
+	if m.Histogram.Bucket[0].Exemplar != nil {
+		t.Error("expected nil exemplar while exemplars are disabled")
+	}
+
+	SetExemplarsEnabled(true)
+	if !ExemplarsEnabled() {
+		t.Error("expected exemplars to be enabled")
+	}
+
+	ObserveWithTraceID(h, 0.5, "")
+	h.Write(m)
+	if m.Histogram.Bucket[0].Exemplar != nil {
+		t.Error("expected nil exemplar without a trace id")
+	}
+
+	ObserveWithTraceID(h, 0.5, "abc")
+	h.Write(m)
+	e := m.Histogram.Bucket[0].Exemplar
+	if e == nil || len(e.Label) != 1 || e.Label[0].GetValue() != "abc" {
+		t.Errorf("expected exemplar with trace_id abc got %v", e)
+	}
+	if m.Histogram.GetSampleCount() != 3 {
+		t.Errorf("expected %d got %d", 3, m.Histogram.GetSampleCount())
+	}
+}
+
+func TestHandlerOpenMetrics(t *testing.T) {
+	defer SetExemplarsEnabled(false)
+
+	r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	r.Header.Set("Accept", "application/openmetrics-text; version=0.0.1")
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, r)
+	if ct := w.Header().Get("Content-Type"); strings.HasPrefix(ct, "application/openmetrics-text") {
+		t.Errorf("unexpected content type %s", ct)
+	}
+
+	SetExemplarsEnabled(true)
+	w = httptest.NewRecorder()
+	Handler().ServeHTTP(w, r)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/openmetrics-text") {
+		t.Errorf("unexpected content type %s", ct)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options provides options for exporting metrics via OTLP
+package options
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+
+	"github.com/tricksterproxy/trickster/pkg/config/defaults"
+	otlpopts "github.com/tricksterproxy/trickster/pkg/tracing/exporters/otlp/options"
+	"github.com/tricksterproxy/trickster/pkg/util/strings"
+)
+
+// ErrInvalidInterval is returned when the export interval is not a positive number
+var ErrInvalidInterval = errors.New("interval_ms must be greater than 0")
+
+// Options is a collection of configurations for exporting metrics to an
+// OpenTelemetry Collector using OTLP
+type Options struct {
+	// CollectorURL is the URL of the OpenTelemetry Collector. Metrics are only
+	// exported when a CollectorURL is configured
+	CollectorURL string `toml:"collector_url"`
+	// ServiceName is the service.name resource attribute of the exported metrics
+	ServiceName string `toml:"service_name"`
+	// IntervalMS is the interval at which metrics are exported
+	IntervalMS int `toml:"interval_ms"`
+	// ResourceAttributes are attached to the resource of the exported metrics
+	ResourceAttributes map[string]string `toml:"resource_attributes"`
+	// Options provides the protocol, headers, compression, timeout and TLS configurations,
+	// which are shared with the OTLP tracer
+	otlpopts.Options
+}
+
+// NewOptions returns a new Options reference with Default Values set
+func NewOptions() *Options {
+	return &Options{
+		ServiceName: defaults.DefaultTracerServiceName,
+		IntervalMS:  defaults.DefaultOTLPMetricsIntervalMS,
+	}
+}
+
+// Clone returns an exact copy of the subject *Options
+func (o *Options) Clone() *Options {
+	return &Options{
+		CollectorURL:       o.CollectorURL,
+		ServiceName:        o.ServiceName,
+		IntervalMS:         o.IntervalMS,
+		ResourceAttributes: strings.CloneMap(o.ResourceAttributes),
+		Options:            *o.Options.Clone(),
+	}
+}
+
+// Enabled returns true when metrics should be exported via OTLP
+func (o *Options) Enabled() bool {
+	return o != nil && o.CollectorURL != ""
+}
+
+// Equal returns true when the subject and o2 have the same values
+func (o *Options) Equal(o2 *Options) bool {
+	if o == nil || o2 == nil {
+		return o == o2
+	}
+	return reflect.DeepEqual(o, o2)
+}
+
+// Validate returns an error if the Options are not valid
+func (o *Options) Validate() error {
+	if o.IntervalMS <= 0 {
+		return ErrInvalidInterval
+	}
+	if o.CollectorURL != "" {
+		u, err := url.Parse(o.CollectorURL)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("invalid otlp collector_url: %s", o.CollectorURL)
+		}
+	}
+	return nil
+}
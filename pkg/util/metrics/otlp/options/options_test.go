@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import "testing"
+
+func TestClone(t *testing.T) {
+	o := NewOptions()
+	o.CollectorURL = "http://collector:4318"
+	o.ResourceAttributes = map[string]string{"env": "test"}
+	o.Headers = map[string]string{"Authorization": "Bearer test"}
+	o2 := o.Clone()
+	if !o.Equal(o2) {
+		t.Error("expected equal options")
+	}
+	o2.ResourceAttributes["env"] = "changed"
+	o2.Headers["Authorization"] = "changed"
+	if o.ResourceAttributes["env"] != "test" || o.Headers["Authorization"] != "Bearer test" {
+		t.Error("clone did not copy maps")
+	}
+	if o.Equal(o2) {
+		t.Error("expected unequal options")
+	}
+	var nilOpts *Options
+	if !nilOpts.Equal(nil) || nilOpts.Equal(o) || o.Equal(nil) {
+		t.Error("unexpected nil equality")
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	var o *Options
+	if o.Enabled() {
+		t.Error("expected disabled")
+	}
+	o = NewOptions()
+	if o.Enabled() {
+		t.Error("expected disabled")
+	}
+	o.CollectorURL = "http://collector:4318"
+	if !o.Enabled() {
+		t.Error("expected enabled")
+	}
+}
+
+func TestValidate(t *testing.T) {
+
+	tests := []struct {
+		url      string
+		interval int
+		err      string
+	}{
+		{"", 1000, ""},
+		{"http://collector:4318", 1000, ""},
+		{"collector:4318", 1000, "invalid otlp collector_url: collector:4318"},
+		{"", 0, ErrInvalidInterval.Error()},
+	}
+
+	for _, test := range tests {
+		o := &Options{CollectorURL: test.url, IntervalMS: test.interval}
+		err := o.Validate()
+		if test.err == "" {
+			if err != nil {
+				t.Error(err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != test.err {
+			t.Errorf("expected %s got %v", test.err, err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package otlp exports Trickster's metrics to an OpenTelemetry Collector using OTLP
+package otlp
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/tracing/exporters/otlp"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics/otlp/options"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	httpMetricsPath = "/v1/metrics"
+	grpcMetricsPath = "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+	scopeName       = "trickster"
+	// metricPrefix is the namespace of Trickster's Prometheus metrics
+	metricPrefix = "trickster_"
+)
+
+// startTime is the start time of the exported cumulative metrics. Prometheus
+// metrics accumulate for the life of the process, across config reloads
+var startTime = time.Now()
+
+// Pusher periodically gathers metrics from a Prometheus Gatherer and exports them to
+// an OpenTelemetry Collector, using the same OTLP transport as the OTLP tracer
+type Pusher struct {
+	options  *options.Options
+	gatherer prometheus.Gatherer
+	client   *otlp.Client
+	resource map[string]string
+	quit     chan bool
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// New returns a new Pusher for the provided options, which gathers metrics from g
+func New(o *options.Options, g prometheus.Gatherer) (*Pusher, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	c, err := otlp.NewClient(o.CollectorURL, httpMetricsPath, grpcMetricsPath, &o.Options)
+	if err != nil {
+		return nil, err
+	}
+	resource := make(map[string]string, len(o.ResourceAttributes)+1)
+	for k, v := range o.ResourceAttributes {
+		resource[k] = v
+	}
+	if o.ServiceName != "" {
+		resource["service.name"] = o.ServiceName
+	}
+	return &Pusher{
+		options:  o,
+		gatherer: g,
+		client:   c,
+		resource: resource,
+		quit:     make(chan bool),
+	}, nil
+}
+
+// Start begins exporting metrics at the configured interval until Stop is called.
+// Errors are passed to onError, when it is not nil
+func (p *Pusher) Start(onError func(error)) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(time.Duration(p.options.IntervalMS) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := p.Push(); err != nil && onError != nil {
+					onError(err)
+				}
+			case <-p.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop exports the final metric values and stops the Pusher
+func (p *Pusher) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.quit)
+		p.wg.Wait()
+		p.Push()
+	})
+}
+
+// Push gathers the current metrics and exports them to the collector
+func (p *Pusher) Push() error {
+	mfs, err := p.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	// only Trickster's own metrics are exported, not the Go runtime and process metrics
+	filtered := make([]*dto.MetricFamily, 0, len(mfs))
+	for _, mf := range mfs {
+		if strings.HasPrefix(mf.GetName(), metricPrefix) {
+			filtered = append(filtered, mf)
+		}
+	}
+	if len(filtered) == 0 {
+		return nil
+	}
+	return p.client.Export(context.Background(),
+		encodeExportRequest(p.resource, scopeName, filtered, startTime, time.Now()))
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otlp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pw "github.com/tricksterproxy/trickster/pkg/internal/protowire"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics/otlp/options"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestNew(t *testing.T) {
+	o := options.NewOptions()
+	o.CollectorURL = "collector:4318"
+	if _, err := New(o, prometheus.NewRegistry()); err == nil {
+		t.Error("expected error for invalid collector_url")
+	}
+	o.CollectorURL = "http://collector:4318"
+	o.Protocol = "grpc"
+	if _, err := New(o, prometheus.NewRegistry()); err == nil {
+		t.Error("expected error for grpc without tls")
+	}
+}
+
+func TestPush(t *testing.T) {
+
+	bodies := make(chan []byte, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != httpMetricsPath {
+			t.Errorf("expected path %s got %s", httpMetricsPath, r.URL.Path)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-protobuf" {
+			t.Errorf("unexpected content type %s", ct)
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies <- b
+	}))
+	defer ts.Close()
+
+	r := prometheus.NewRegistry()
+	c := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "trickster_test_total",
+		Help: "test",
+	})
+	g := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "go_goroutines",
+		Help: "test",
+	})
+	r.MustRegister(c, g)
+	c.Inc()
+
+	o := options.NewOptions()
+	o.CollectorURL = ts.URL
+	o.ResourceAttributes = map[string]string{"env": "test"}
+	p, err := New(o, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Push(); err != nil {
+		t.Fatal(err)
+	}
+
+	b := <-bodies
+	rm := pw.DecodeFields(t, pw.FieldByNum(pw.DecodeFields(t, b), 1).Data)
+	if res := pw.DecodeFields(t, pw.FieldByNum(rm, 1).Data); len(res) != 2 {
+		t.Errorf("expected 2 resource attributes got %d", len(res))
+	}
+	// only the trickster_ metric is exported
+	var metrics int
+	for _, f := range pw.DecodeFields(t, pw.FieldByNum(rm, 2).Data) {
+		if f.Num == 2 {
+			metrics++
+		}
+	}
+	if metrics != 1 {
+		t.Errorf("expected 1 metric got %d", metrics)
+	}
+
+	// stopping the pusher exports the final values
+	p.Start(nil)
+	p.Stop()
+	if len(bodies) != 1 {
+		t.Error("expected a final export on stop")
+	}
+	p.Stop()
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otlp
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	pw "github.com/tricksterproxy/trickster/pkg/internal/protowire"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// This file encodes the OTLP ExportMetricsServiceRequest message
+// (opentelemetry/proto/collector/metrics/v1), which converts gathered
+// Prometheus metric families into OTLP metrics.
+
+// aggregationTemporalityCumulative is the OTLP AggregationTemporality of Prometheus
+// counters and histograms, which accumulate from the start of the process
+const aggregationTemporalityCumulative = 2
+
+// encodeAttribute writes an opentelemetry.proto.common.v1.KeyValue with a string value
+func encodeAttribute(b *pw.Buffer, field int, key, value string) {
+	var v pw.Buffer
+	// AnyValue.string_value is written even when empty, so the value is not unset
+	v.StringValue(1, value)
+	var m pw.Buffer
+	m.StringField(1, key)
+	m.MessageField(2, v)
+	b.MessageField(field, m)
+}
+
+func encodeLabels(b *pw.Buffer, field int, labels []*dto.LabelPair) {
+	for _, lp := range labels {
+		encodeAttribute(b, field, lp.GetName(), lp.GetValue())
+	}
+}
+
+// encodeMetric encodes a Prometheus metric family as an opentelemetry.proto.metrics.v1.Metric.
+// Counters are encoded as cumulative monotonic Sums, gauges and untyped metrics as Gauges,
+// histograms as cumulative Histograms and summaries as Summaries. ok is false when the
+// family has no supported type
+func encodeMetric(mf *dto.MetricFamily, start, now time.Time) (b pw.Buffer, ok bool) {
+
+	b.StringField(1, mf.GetName())
+	b.StringField(2, mf.GetHelp())
+
+	var data pw.Buffer
+	var field int
+
+	switch mf.GetType() {
+	case dto.MetricType_COUNTER:
+		field = 7
+		for _, m := range mf.Metric {
+			data.MessageField(1, encodeNumberDataPoint(m.Label, m.GetCounter().GetValue(), start, now))
+		}
+		data.VarintField(2, aggregationTemporalityCumulative)
+		data.VarintField(3, 1)
+	case dto.MetricType_GAUGE:
+		field = 5
+		for _, m := range mf.Metric {
+			data.MessageField(1, encodeNumberDataPoint(m.Label, m.GetGauge().GetValue(), start, now))
+		}
+	case dto.MetricType_UNTYPED:
+		field = 5
+		for _, m := range mf.Metric {
+			data.MessageField(1, encodeNumberDataPoint(m.Label, m.GetUntyped().GetValue(), start, now))
+		}
+	case dto.MetricType_HISTOGRAM:
+		field = 9
+		for _, m := range mf.Metric {
+			data.MessageField(1, encodeHistogramDataPoint(m.Label, m.GetHistogram(), start, now))
+		}
+		data.VarintField(2, aggregationTemporalityCumulative)
+	case dto.MetricType_SUMMARY:
+		field = 11
+		for _, m := range mf.Metric {
+			data.MessageField(1, encodeSummaryDataPoint(m.Label, m.GetSummary(), start, now))
+		}
+	default:
+		return nil, false
+	}
+
+	b.MessageField(field, data)
+	return b, true
+}
+
+// encodeNumberDataPoint encodes an opentelemetry.proto.metrics.v1.NumberDataPoint
+func encodeNumberDataPoint(labels []*dto.LabelPair, v float64, start, now time.Time) pw.Buffer {
+	var b pw.Buffer
+	b.Fixed64Field(2, pw.UnixNano(start))
+	b.Fixed64Field(3, pw.UnixNano(now))
+	b.DoubleField(4, v)
+	encodeLabels(&b, 7, labels)
+	return b
+}
+
+// encodeHistogramDataPoint encodes an opentelemetry.proto.metrics.v1.HistogramDataPoint.
+// Prometheus buckets are cumulative, while OTLP bucket counts are per-bucket, with a final
+// bucket for observations above the highest explicit bound
+func encodeHistogramDataPoint(labels []*dto.LabelPair, h *dto.Histogram,
+	start, now time.Time) pw.Buffer {
+
+	bounds := make([]uint64, 0, len(h.Bucket))
+	counts := make([]uint64, 0, len(h.Bucket)+1)
+	var prev uint64
+	for _, bk := range h.Bucket {
+		if math.IsInf(bk.GetUpperBound(), 1) {
+			continue
+		}
+		bounds = append(bounds, math.Float64bits(bk.GetUpperBound()))
+		counts = append(counts, bk.GetCumulativeCount()-prev)
+		prev = bk.GetCumulativeCount()
+	}
+	counts = append(counts, h.GetSampleCount()-prev)
+
+	var b pw.Buffer
+	b.Fixed64Field(2, pw.UnixNano(start))
+	b.Fixed64Field(3, pw.UnixNano(now))
+	b.Fixed64Field(4, h.GetSampleCount())
+	b.DoubleField(5, h.GetSampleSum())
+	b.PackedFixed64Field(6, counts)
+	b.PackedFixed64Field(7, bounds)
+	encodeLabels(&b, 9, labels)
+	return b
+}
+
+// encodeSummaryDataPoint encodes an opentelemetry.proto.metrics.v1.SummaryDataPoint
+func encodeSummaryDataPoint(labels []*dto.LabelPair, s *dto.Summary,
+	start, now time.Time) pw.Buffer {
+	var b pw.Buffer
+	b.Fixed64Field(2, pw.UnixNano(start))
+	b.Fixed64Field(3, pw.UnixNano(now))
+	b.Fixed64Field(4, s.GetSampleCount())
+	b.DoubleField(5, s.GetSampleSum())
+	for _, q := range s.Quantile {
+		var m pw.Buffer
+		m.DoubleField(1, q.GetQuantile())
+		m.DoubleField(2, q.GetValue())
+		b.MessageField(6, m)
+	}
+	encodeLabels(&b, 7, labels)
+	return b
+}
+
+// encodeExportRequest encodes an opentelemetry.proto.collector.metrics.v1.ExportMetricsServiceRequest
+// containing a single ResourceMetrics for the provided resource attributes and metric families
+func encodeExportRequest(resource map[string]string, scopeName string,
+	mfs []*dto.MetricFamily, start, now time.Time) []byte {
+
+	keys := make([]string, 0, len(resource))
+	for k := range resource {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var r pw.Buffer
+	for _, k := range keys {
+		encodeAttribute(&r, 1, k, resource[k])
+	}
+
+	var scope pw.Buffer
+	scope.StringField(1, scopeName)
+
+	var sm pw.Buffer
+	sm.MessageField(1, scope)
+	for _, mf := range mfs {
+		if m, ok := encodeMetric(mf, start, now); ok {
+			sm.MessageField(2, m)
+		}
+	}
+
+	var rm pw.Buffer
+	rm.MessageField(1, r)
+	rm.MessageField(2, sm)
+
+	var req pw.Buffer
+	req.MessageField(1, rm)
+	return req
+}
@@ -0,0 +1,174 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package otlp
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	pw "github.com/tricksterproxy/trickster/pkg/internal/protowire"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func gather(t *testing.T, cs ...prometheus.Collector) []*dto.MetricFamily {
+	r := prometheus.NewRegistry()
+	r.MustRegister(cs...)
+	mfs, err := r.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mfs
+}
+
+func TestEncodeCounter(t *testing.T) {
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "trickster_proxy_requests_total",
+		Help: "test help",
+	}, []string{"origin_name"})
+	c.WithLabelValues("test").Add(3)
+
+	start, now := time.Unix(1, 0), time.Unix(2, 0)
+	b, ok := encodeMetric(gather(t, c)[0], start, now)
+	if !ok {
+		t.Fatal("expected counter to be encoded")
+	}
+	m := pw.DecodeFields(t, b)
+	if f := pw.FieldByNum(m, 1); string(f.Data) != "trickster_proxy_requests_total" {
+		t.Error("invalid metric name")
+	}
+	if f := pw.FieldByNum(m, 2); string(f.Data) != "test help" {
+		t.Error("invalid metric description")
+	}
+	sum := pw.FieldByNum(m, 7)
+	if sum == nil {
+		t.Fatal("expected counter to be encoded as a sum")
+	}
+	sf := pw.DecodeFields(t, sum.Data)
+	if f := pw.FieldByNum(sf, 2); f == nil || f.Varint != aggregationTemporalityCumulative {
+		t.Error("expected cumulative aggregation temporality")
+	}
+	if f := pw.FieldByNum(sf, 3); f == nil || f.Varint != 1 {
+		t.Error("expected monotonic sum")
+	}
+	dp := pw.DecodeFields(t, pw.FieldByNum(sf, 1).Data)
+	if f := pw.FieldByNum(dp, 2); f.Varint != uint64(1e9) {
+		t.Error("invalid start time")
+	}
+	if f := pw.FieldByNum(dp, 3); f.Varint != uint64(2e9) {
+		t.Error("invalid time")
+	}
+	if f := pw.FieldByNum(dp, 4); math.Float64frombits(f.Varint) != 3 {
+		t.Error("invalid value")
+	}
+	attr := pw.DecodeFields(t, pw.FieldByNum(dp, 7).Data)
+	if string(pw.FieldByNum(attr, 1).Data) != "origin_name" {
+		t.Error("invalid attribute key")
+	}
+	v := pw.DecodeFields(t, pw.FieldByNum(attr, 2).Data)
+	if string(pw.FieldByNum(v, 1).Data) != "test" {
+		t.Error("invalid attribute value")
+	}
+}
+
+func TestEncodeGauge(t *testing.T) {
+	g := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "trickster_proxy_active_connections",
+		Help: "test",
+	})
+	b, ok := encodeMetric(gather(t, g)[0], time.Now(), time.Now())
+	if !ok {
+		t.Fatal("expected gauge to be encoded")
+	}
+	gf := pw.FieldByNum(pw.DecodeFields(t, b), 5)
+	if gf == nil {
+		t.Fatal("expected gauge")
+	}
+	// a zero value must still be present in the data point
+	dp := pw.DecodeFields(t, pw.FieldByNum(pw.DecodeFields(t, gf.Data), 1).Data)
+	if f := pw.FieldByNum(dp, 4); f == nil || math.Float64frombits(f.Varint) != 0 {
+		t.Error("expected zero value")
+	}
+}
+
+func TestEncodeHistogram(t *testing.T) {
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "trickster_proxy_request_duration_seconds",
+		Help:    "test",
+		Buckets: []float64{0.1, 1},
+	})
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(0.5)
+	h.Observe(5)
+
+	b, ok := encodeMetric(gather(t, h)[0], time.Now(), time.Now())
+	if !ok {
+		t.Fatal("expected histogram to be encoded")
+	}
+	hf := pw.DecodeFields(t, pw.FieldByNum(pw.DecodeFields(t, b), 9).Data)
+	if f := pw.FieldByNum(hf, 2); f == nil || f.Varint != aggregationTemporalityCumulative {
+		t.Error("expected cumulative aggregation temporality")
+	}
+	dp := pw.DecodeFields(t, pw.FieldByNum(hf, 1).Data)
+	if f := pw.FieldByNum(dp, 4); f.Varint != 4 {
+		t.Errorf("expected count 4 got %d", f.Varint)
+	}
+	if f := pw.FieldByNum(dp, 5); math.Float64frombits(f.Varint) != 6.05 {
+		t.Errorf("expected sum 6.05 got %f", math.Float64frombits(f.Varint))
+	}
+	counts := pw.DecodePacked(pw.FieldByNum(dp, 6).Data)
+	if len(counts) != 3 || counts[0] != 1 || counts[1] != 2 || counts[2] != 1 {
+		t.Errorf("unexpected bucket counts %v", counts)
+	}
+	bounds := pw.DecodePacked(pw.FieldByNum(dp, 7).Data)
+	if len(bounds) != 2 || math.Float64frombits(bounds[0]) != 0.1 ||
+		math.Float64frombits(bounds[1]) != 1 {
+		t.Errorf("unexpected explicit bounds %v", bounds)
+	}
+}
+
+func TestEncodeExportRequest(t *testing.T) {
+	c := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "trickster_test_total",
+		Help: "test",
+	})
+	b := encodeExportRequest(map[string]string{"service.name": "trickster", "env": "test"},
+		scopeName, gather(t, c), time.Now(), time.Now())
+
+	rm := pw.DecodeFields(t, pw.FieldByNum(pw.DecodeFields(t, b), 1).Data)
+
+	// resource attributes are sorted by key
+	res := pw.DecodeFields(t, pw.FieldByNum(rm, 1).Data)
+	if len(res) != 2 {
+		t.Fatalf("expected 2 resource attributes got %d", len(res))
+	}
+	if k := pw.FieldByNum(pw.DecodeFields(t, res[0].Data), 1); string(k.Data) != "env" {
+		t.Errorf("expected env got %s", k.Data)
+	}
+
+	sm := pw.DecodeFields(t, pw.FieldByNum(rm, 2).Data)
+	scope := pw.FieldByNum(pw.DecodeFields(t, pw.FieldByNum(sm, 1).Data), 1)
+	if string(scope.Data) != scopeName {
+		t.Errorf("expected scope name %s got %s", scopeName, scope.Data)
+	}
+	if pw.FieldByNum(sm, 2) == nil {
+		t.Error("missing metric")
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options provides options for pushing metrics to StatsD
+package options
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/tricksterproxy/trickster/pkg/config/defaults"
+	"github.com/tricksterproxy/trickster/pkg/util/strings"
+)
+
+// Supported StatsD line protocol flavors
+const (
+	// FlavorStatsD is the plain StatsD protocol. Since it has no tags, metric labels
+	// are appended to the metric name
+	FlavorStatsD = "statsd"
+	// FlavorDogStatsD is the DogStatsD protocol, which carries metric labels as tags
+	FlavorDogStatsD = "dogstatsd"
+)
+
+// ErrInvalidFlushInterval is returned when the flush interval is not a positive number
+var ErrInvalidFlushInterval = errors.New("flush_interval_ms must be greater than 0")
+
+// Options is a collection of configurations for pushing metrics to StatsD
+type Options struct {
+	// Address is the host:port of the StatsD server's UDP listener. Metrics are only
+	// pushed when an Address is configured
+	Address string `toml:"address"`
+	// Flavor is the line protocol used: 'statsd' or 'dogstatsd'
+	Flavor string `toml:"flavor"`
+	// Prefix is prepended to each metric name
+	Prefix string `toml:"prefix"`
+	// FlushIntervalMS is the interval at which metrics are pushed
+	FlushIntervalMS int `toml:"flush_interval_ms"`
+	// Tags are attached to each metric (dogstatsd only)
+	Tags map[string]string `toml:"tags"`
+}
+
+// NewOptions returns a new Options reference with Default Values set
+func NewOptions() *Options {
+	return &Options{
+		Flavor:          defaults.DefaultStatsDFlavor,
+		Prefix:          defaults.DefaultStatsDPrefix,
+		FlushIntervalMS: defaults.DefaultStatsDFlushIntervalMS,
+	}
+}
+
+// Clone returns an exact copy of the subject *Options
+func (o *Options) Clone() *Options {
+	return &Options{
+		Address:         o.Address,
+		Flavor:          o.Flavor,
+		Prefix:          o.Prefix,
+		FlushIntervalMS: o.FlushIntervalMS,
+		Tags:            strings.CloneMap(o.Tags),
+	}
+}
+
+// Enabled returns true when metrics should be pushed to StatsD
+func (o *Options) Enabled() bool {
+	return o != nil && o.Address != ""
+}
+
+// Equal returns true when the subject and o2 have the same values
+func (o *Options) Equal(o2 *Options) bool {
+	if o == nil || o2 == nil {
+		return o == o2
+	}
+	if o.Address != o2.Address || o.Flavor != o2.Flavor || o.Prefix != o2.Prefix ||
+		o.FlushIntervalMS != o2.FlushIntervalMS || len(o.Tags) != len(o2.Tags) {
+		return false
+	}
+	for k, v := range o.Tags {
+		if v2, ok := o2.Tags[k]; !ok || v != v2 {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate returns an error if the Options are not valid
+func (o *Options) Validate() error {
+	if o.Flavor != FlavorStatsD && o.Flavor != FlavorDogStatsD {
+		return fmt.Errorf("invalid statsd flavor: %s", o.Flavor)
+	}
+	if o.FlushIntervalMS <= 0 {
+		return ErrInvalidFlushInterval
+	}
+	if o.Address != "" {
+		if _, _, err := net.SplitHostPort(o.Address); err != nil {
+			return fmt.Errorf("invalid statsd address: %s", o.Address)
+		}
+	}
+	return nil
+}
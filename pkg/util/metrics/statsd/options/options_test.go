@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import "testing"
+
+func TestClone(t *testing.T) {
+	o := NewOptions()
+	o.Address = "127.0.0.1:8125"
+	o.Tags = map[string]string{"env": "test"}
+	o2 := o.Clone()
+	if !o.Equal(o2) {
+		t.Error("expected equal options")
+	}
+	o2.Tags["env"] = "changed"
+	if o.Tags["env"] != "test" {
+		t.Error("clone did not copy tags")
+	}
+	if o.Equal(o2) {
+		t.Error("expected unequal options")
+	}
+	var nilOpts *Options
+	if !nilOpts.Equal(nil) || nilOpts.Equal(o) || o.Equal(nil) {
+		t.Error("unexpected nil equality")
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	var o *Options
+	if o.Enabled() {
+		t.Error("expected disabled")
+	}
+	o = NewOptions()
+	if o.Enabled() {
+		t.Error("expected disabled")
+	}
+	o.Address = "127.0.0.1:8125"
+	if !o.Enabled() {
+		t.Error("expected enabled")
+	}
+}
+
+func TestValidate(t *testing.T) {
+
+	tests := []struct {
+		address  string
+		flavor   string
+		interval int
+		err      string
+	}{
+		{"", FlavorStatsD, 1000, ""},
+		{"127.0.0.1:8125", FlavorDogStatsD, 1000, ""},
+		{"127.0.0.1", FlavorStatsD, 1000, "invalid statsd address: 127.0.0.1"},
+		{"", "graphite", 1000, "invalid statsd flavor: graphite"},
+		{"", FlavorStatsD, 0, ErrInvalidFlushInterval.Error()},
+	}
+
+	for _, test := range tests {
+		o := &Options{Address: test.address, Flavor: test.flavor, FlushIntervalMS: test.interval}
+		err := o.Validate()
+		if test.err == "" {
+			if err != nil {
+				t.Error(err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != test.err {
+			t.Errorf("expected %s got %v", test.err, err)
+		}
+	}
+}
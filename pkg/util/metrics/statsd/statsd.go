@@ -0,0 +1,257 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package statsd pushes Trickster's metrics to a StatsD or DogStatsD server
+package statsd
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/util/metrics/statsd/options"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	// maxPacketSize keeps each UDP datagram within a typical network MTU
+	maxPacketSize = 1432
+	// metricPrefix is the namespace of Trickster's Prometheus metrics
+	metricPrefix = "trickster_"
+)
+
+// Pusher periodically gathers metrics from a Prometheus Gatherer and pushes them to
+// StatsD. Counters are pushed as the change since the previous push, gauges as their
+// current value, and histograms as a timer of the mean observation since the previous
+// push, sampled at a rate that lets StatsD reconstruct the observation count.
+type Pusher struct {
+	options  *options.Options
+	gatherer prometheus.Gatherer
+	conn     net.Conn
+	tags     string
+	counters map[string]float64
+	hists    map[string]histogramState
+	quit     chan bool
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+type histogramState struct {
+	count uint64
+	sum   float64
+}
+
+// New returns a new Pusher for the provided options, which gathers metrics from g
+func New(o *options.Options, g prometheus.Gatherer) (*Pusher, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	conn, err := net.Dial("udp", o.Address)
+	if err != nil {
+		return nil, err
+	}
+	return &Pusher{
+		options:  o,
+		gatherer: g,
+		conn:     conn,
+		tags:     formatTags(o.Tags),
+		counters: make(map[string]float64),
+		hists:    make(map[string]histogramState),
+		quit:     make(chan bool),
+	}, nil
+}
+
+// Start begins pushing metrics at the configured interval until Stop is called.
+// Errors are passed to onError, when it is not nil
+func (p *Pusher) Start(onError func(error)) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(time.Duration(p.options.FlushIntervalMS) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := p.Push(); err != nil && onError != nil {
+					onError(err)
+				}
+			case <-p.quit:
+				return
+			}
+		}
+	}()
+}
+
+// Stop pushes any outstanding metrics, stops the Pusher and closes its connection
+func (p *Pusher) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.quit)
+		p.wg.Wait()
+		p.Push()
+		p.conn.Close()
+	})
+}
+
+// Push gathers the current metrics and pushes them to StatsD
+func (p *Pusher) Push() error {
+	mfs, err := p.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	return p.write(p.lines(mfs))
+}
+
+// write sends the lines to StatsD, packing as many lines into each datagram as will fit
+func (p *Pusher) write(lines []string) error {
+	var buf bytes.Buffer
+	flush := func() error {
+		if buf.Len() == 0 {
+			return nil
+		}
+		_, err := p.conn.Write(buf.Bytes())
+		buf.Reset()
+		return err
+	}
+	for _, l := range lines {
+		if buf.Len() > 0 && buf.Len()+len(l)+1 > maxPacketSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(l)
+	}
+	return flush()
+}
+
+// lines returns the StatsD lines for the metric families, updating the previous
+// counter and histogram values used to calculate changes between pushes
+func (p *Pusher) lines(mfs []*dto.MetricFamily) []string {
+	out := make([]string, 0, len(mfs))
+	for _, mf := range mfs {
+		// only Trickster's own metrics are pushed, not the Go runtime and process metrics
+		if !strings.HasPrefix(mf.GetName(), metricPrefix) {
+			continue
+		}
+		name := p.metricName(mf.GetName())
+		for _, m := range mf.Metric {
+			series, tags := p.series(name, m.Label)
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				v := m.GetCounter().GetValue()
+				delta := v - p.counters[series]
+				p.counters[series] = v
+				if delta > 0 {
+					out = append(out, series+":"+formatFloat(delta)+"|c"+tags)
+				}
+			case dto.MetricType_GAUGE:
+				out = append(out, series+":"+formatFloat(m.GetGauge().GetValue())+"|g"+tags)
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				prev := p.hists[series]
+				cur := histogramState{count: h.GetSampleCount(), sum: h.GetSampleSum()}
+				p.hists[series] = cur
+				if cur.count <= prev.count {
+					continue
+				}
+				n := cur.count - prev.count
+				// observations are in seconds, and StatsD timers are in milliseconds
+				mean := (cur.sum - prev.sum) / float64(n) * 1000
+				l := series + ":" + formatFloat(mean) + "|ms"
+				if n > 1 {
+					l += "|@" + formatFloat(1/float64(n))
+				}
+				out = append(out, l+tags)
+			}
+		}
+	}
+	return out
+}
+
+// metricName converts a Prometheus metric name (e.g., trickster_proxy_requests_total)
+// into a dotted StatsD name under the configured prefix (e.g., trickster.proxy_requests_total)
+func (p *Pusher) metricName(name string) string {
+	name = strings.TrimPrefix(name, metricPrefix)
+	if p.options.Prefix == "" {
+		return name
+	}
+	return p.options.Prefix + "." + name
+}
+
+// series returns the name and tags suffix of a metric series. For dogstatsd, labels are
+// sent as tags. For plain statsd, label values are appended to the name, in label name order
+func (p *Pusher) series(name string, labels []*dto.LabelPair) (string, string) {
+	if p.options.Flavor == options.FlavorDogStatsD {
+		tags := p.tags
+		for _, lp := range labels {
+			if tags != "" {
+				tags += ","
+			}
+			tags += sanitize(lp.GetName()) + ":" + sanitize(lp.GetValue())
+		}
+		if tags != "" {
+			tags = "|#" + tags
+		}
+		return name, tags
+	}
+	for _, lp := range labels {
+		v := sanitize(lp.GetValue())
+		if v == "" {
+			v = "none"
+		}
+		name += "." + strings.NewReplacer(".", "_", "/", "_").Replace(v)
+	}
+	return name, ""
+}
+
+func formatTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	l := make([]string, len(keys))
+	for i, k := range keys {
+		l[i] = sanitize(k) + ":" + sanitize(tags[k])
+	}
+	return strings.Join(l, ",")
+}
+
+// sanitize replaces characters that are reserved by the StatsD line protocol
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', ',', '@', '#', '\n', ' ':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,189 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package statsd
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/util/metrics/statsd/options"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func testRegistry() (*prometheus.Registry, *prometheus.CounterVec,
+	prometheus.Gauge, *prometheus.HistogramVec) {
+	r := prometheus.NewRegistry()
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "trickster_proxy_requests_total",
+		Help: "test",
+	}, []string{"origin_name", "path"})
+	g := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "trickster_proxy_active_connections",
+		Help: "test",
+	})
+	h := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "trickster_proxy_request_duration_seconds",
+		Help: "test",
+	}, []string{"origin_name"})
+	o := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "go_goroutines",
+		Help: "test",
+	})
+	r.MustRegister(c, g, h, o)
+	return r, c, g, h
+}
+
+func testPusher(t *testing.T, flavor string) (*Pusher, *net.UDPConn,
+	*prometheus.CounterVec, prometheus.Gauge, *prometheus.HistogramVec) {
+	l, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, c, g, h := testRegistry()
+	o := options.NewOptions()
+	o.Address = l.LocalAddr().String()
+	o.Flavor = flavor
+	o.Tags = map[string]string{"env": "test"}
+	p, err := New(o, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, l, c, g, h
+}
+
+func readLines(t *testing.T, l *net.UDPConn) []string {
+	b := make([]byte, 65536)
+	l.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := l.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(string(b[:n]), "\n")
+	sort.Strings(lines)
+	return lines
+}
+
+func TestNew(t *testing.T) {
+	o := options.NewOptions()
+	o.Address = "127.0.0.1"
+	_, err := New(o, prometheus.NewRegistry())
+	if err == nil {
+		t.Error("expected error for invalid address")
+	}
+}
+
+func TestPushDogStatsD(t *testing.T) {
+
+	p, l, c, g, h := testPusher(t, options.FlavorDogStatsD)
+	defer l.Close()
+	defer p.Stop()
+
+	c.WithLabelValues("prom1", "/api/v1/query").Add(3)
+	g.Set(5)
+	h.WithLabelValues("prom1").Observe(0.5)
+	h.WithLabelValues("prom1").Observe(1.5)
+
+	if err := p.Push(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"trickster.proxy_active_connections:5|g|#env:test",
+		"trickster.proxy_request_duration_seconds:1000|ms|@0.5|#env:test,origin_name:prom1",
+		"trickster.proxy_requests_total:3|c|#env:test,origin_name:prom1,path:/api/v1/query",
+	}
+	lines := readLines(t, l)
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+	}
+
+	// counters are pushed as the change since the previous push,
+	// and unchanged counters and histograms are not pushed
+	c.WithLabelValues("prom1", "/api/v1/query").Add(2)
+	if err := p.Push(); err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{
+		"trickster.proxy_active_connections:5|g|#env:test",
+		"trickster.proxy_requests_total:2|c|#env:test,origin_name:prom1,path:/api/v1/query",
+	}
+	lines = readLines(t, l)
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+	}
+}
+
+func TestPushStatsD(t *testing.T) {
+
+	p, l, c, _, _ := testPusher(t, options.FlavorStatsD)
+	defer l.Close()
+	defer p.Stop()
+
+	c.WithLabelValues("prom1", "/api/v1/query").Add(1)
+	c.WithLabelValues("prom1", "").Add(1)
+
+	if err := p.Push(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"trickster.proxy_active_connections:0|g",
+		"trickster.proxy_requests_total.prom1._api_v1_query:1|c",
+		"trickster.proxy_requests_total.prom1.none:1|c",
+	}
+	lines := readLines(t, l)
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(lines, "\n"))
+	}
+}
+
+func TestWrite(t *testing.T) {
+
+	p, l, _, _, _ := testPusher(t, options.FlavorStatsD)
+	defer l.Close()
+	defer p.Stop()
+
+	// lines that don't fit in a single packet are split across packets
+	line := strings.Repeat("a", 1000) + ":1|c"
+	if err := p.write([]string{line, line}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		lines := readLines(t, l)
+		if len(lines) != 1 || lines[0] != line {
+			t.Errorf("unexpected packet %v", lines)
+		}
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	p, l, c, _, _ := testPusher(t, options.FlavorStatsD)
+	defer l.Close()
+	p.options.FlushIntervalMS = 10
+	p.Start(nil)
+	c.WithLabelValues("prom1", "x").Add(1)
+	lines := readLines(t, l)
+	if len(lines) == 0 {
+		t.Error("expected pushed metrics")
+	}
+	p.Stop()
+	p.Stop()
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+func TestAudit(t *testing.T) {
+
+	var rec *statusRecorder
+	h := Audit(tl.ConsoleLogger("error"), "test", http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		rec = w.(*statusRecorder)
+		w.WriteHeader(http.StatusTeapot)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("test"))
+		w.(http.Flusher).Flush()
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/trickster/config/reload", nil)
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+		{Subject: pkix.Name{CommonName: "client"}}}}}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if rec.status != http.StatusTeapot || w.Code != http.StatusTeapot {
+		t.Errorf("expected %d got %d", http.StatusTeapot, rec.status)
+	}
+	if !w.Flushed {
+		t.Error("expected flushed response")
+	}
+
+	// the status of a response with no explicit status code is 200 OK
+	h = Audit(tl.ConsoleLogger("error"), "test", http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		rec = w.(*statusRecorder)
+		w.Write([]byte("test"))
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	r.RemoteAddr = "invalid"
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if rec.status != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, rec.status)
+	}
+	if remoteHost(r) != "invalid" {
+		t.Errorf("expected %s got %s", "invalid", remoteHost(r))
+	}
+}
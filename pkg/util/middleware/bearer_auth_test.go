@@ -0,0 +1,83 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBearerAuth(t *testing.T) {
+
+	h := BearerAuth("test", "token", http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), nil)
+
+	tests := []struct {
+		auth     string
+		expected int
+	}{
+		{"", http.StatusUnauthorized},
+		{"token", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Basic dG9rZW46", http.StatusUnauthorized},
+		{"Bearer token", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/trickster/config/reload", nil)
+		if test.auth != "" {
+			r.Header.Set("Authorization", test.auth)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != test.expected {
+			t.Errorf("expected %d got %d for %s", test.expected, w.Code, test.auth)
+		}
+		if w.Code == http.StatusUnauthorized &&
+			w.Header().Get("WWW-Authenticate") != `Bearer realm="test"` {
+			t.Errorf("unexpected WWW-Authenticate header %s", w.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestBearerAuthUnauthorizedHandler(t *testing.T) {
+
+	h := BearerAuth("test", "token", http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"denied"}`))
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/trickster/admin/v1/stats", nil)
+	r.Header.Set("Authorization", "Bearer wrong")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected %d got %d", http.StatusUnauthorized, w.Code)
+	}
+	if w.Body.String() != `{"error":"denied"}` {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+	if w.Header().Get("WWW-Authenticate") != `Bearer realm="test"` {
+		t.Errorf("unexpected WWW-Authenticate header %s", w.Header().Get("WWW-Authenticate"))
+	}
+}
@@ -26,6 +26,7 @@ import (
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	"github.com/tricksterproxy/trickster/pkg/tracing"
+	"github.com/tricksterproxy/trickster/pkg/util/accesslog"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
 )
 
@@ -40,6 +41,10 @@ func WithResourcesContext(client origins.Client, oc *oo.Options,
 		} else {
 			resources = request.NewResources(oc, p, c.Configuration(), c, client, t, l)
 		}
+		if rec := accesslog.GetRecord(r); rec != nil && oc != nil {
+			rec.Origin = oc.Name
+			rec.OriginType = oc.OriginType
+		}
 		next.ServeHTTP(w, r.WithContext(context.WithResources(r.Context(), resources)))
 	})
 }
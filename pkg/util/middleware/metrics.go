@@ -20,13 +20,16 @@ import (
 	"net/http"
 	"time"
 
+	tctx "github.com/tricksterproxy/trickster/pkg/proxy/context"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/util/metrics"
 )
 
 // Decorate decorates a function in such a way that it captures both the
 // returned status and the time used to execute a request from the front end
-// perspective
-func Decorate(originName, originType, path string, next http.Handler) http.Handler {
+// perspective. When exemplars is true, the request's trace ID is attached to the
+// request duration as an exemplar
+func Decorate(originName, originType, path string, exemplars bool, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		observer := &responseObserver{
 			w,
@@ -34,15 +37,31 @@ func Decorate(originName, originType, path string, next http.Handler) http.Handl
 			0,
 		}
 
+		// the tracer is attached inside of this handler, so it writes the trace ID back
+		var traceID string
+		if exemplars && metrics.ExemplarsEnabled() {
+			r = r.WithContext(tctx.WithTraceIDRecorder(r.Context(), &traceID))
+		}
+
 		n := time.Now()
 		next.ServeHTTP(observer, r)
 
-		metrics.FrontendRequestDuration.WithLabelValues(originName, originType,
-			r.Method, path, observer.status).Observe(time.Since(n).Seconds())
+		metrics.ObserveWithTraceID(metrics.FrontendRequestDuration.WithLabelValues(originName,
+			originType, r.Method, path, observer.status), time.Since(n).Seconds(), traceID)
 		metrics.FrontendRequestStatus.WithLabelValues(originName, originType,
 			r.Method, path, observer.status).Inc()
 		metrics.FrontendRequestWrittenBytes.WithLabelValues(originName, originType,
 			r.Method, path, observer.status).Add(observer.bytesWritten)
+
+		// requests handled by a proxy engine report their cache lookup status in the
+		// results header, so the per-path cache performance can be recorded here
+		if cacheStatus, ok := headers.ResultsHeaderStatus(observer.Header()); ok {
+			metrics.ProxyCacheRequests.WithLabelValues(originName, originType,
+				path, cacheStatus, observer.status).Inc()
+			metrics.ProxyCacheBytes.WithLabelValues(originName, originType,
+				path, cacheStatus, observer.status).Add(observer.bytesWritten)
+			metrics.ObserveCacheLookup(originName, cacheStatus)
+		}
 	})
 }
 
@@ -69,6 +88,13 @@ func (w *responseObserver) WriteHeader(statusCode int) {
 	}
 }
 
+// Flush implements http.Flusher, so that streamed responses are not buffered
+func (w *responseObserver) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func (w *responseObserver) Write(b []byte) (int, error) {
 	bytesWritten, err := w.ResponseWriter.Write(b)
 
@@ -0,0 +1,45 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"net/http"
+)
+
+// StripParams removes the named query parameters from incoming HTTP Request URLs, so that
+// they are neither part of the request's cache key nor proxied to the origin
+func StripParams(names []string, next http.Handler) http.Handler {
+	if len(names) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r != nil && r.URL != nil && r.URL.RawQuery != "" {
+			qp := r.URL.Query()
+			var stripped bool
+			for _, n := range names {
+				if _, ok := qp[n]; ok {
+					qp.Del(n)
+					stripped = true
+				}
+			}
+			if stripped {
+				r.URL.RawQuery = qp.Encode()
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
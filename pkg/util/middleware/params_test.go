@@ -0,0 +1,53 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStripParams(t *testing.T) {
+
+	var rawQuery string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawQuery = r.URL.RawQuery
+	})
+
+	h := StripParams([]string{"_", "nocache"}, next)
+
+	tests := []struct {
+		url, expected string
+	}{
+		{"http://trickster/?query=up&_=1600000000&nocache=1", "query=up"},
+		{"http://trickster/?query=up&step=15", "query=up&step=15"},
+		{"http://trickster/?_=1", ""},
+		{"http://trickster/", ""},
+	}
+
+	for _, test := range tests {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, test.url, nil))
+		if rawQuery != test.expected {
+			t.Errorf("expected %s got %s", test.expected, rawQuery)
+		}
+	}
+
+	if h := StripParams(nil, next); h == nil {
+		t.Error("expected next handler")
+	}
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"net/http"
+
+	tctx "github.com/tricksterproxy/trickster/pkg/proxy/context"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+// SecurityHeaders adds the security headers to each response that does not already have
+// them, so headers set by the origin or by a path's response_headers are kept. The
+// Strict-Transport-Security header is only added to responses to TLS requests. The
+// handlers of a path may replace the headers with PathSecurityHeaders
+func SecurityHeaders(h http.Header, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &securityWriter{ResponseWriter: w, headers: h, tls: r.TLS != nil}
+		next.ServeHTTP(sw, r.WithContext(tctx.WithSecurityHeaders(r.Context(), &sw.headers)))
+	})
+}
+
+// PathSecurityHeaders replaces the security headers that SecurityHeaders adds to the
+// responses of the path
+func PathSecurityHeaders(h http.Header, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sh := tctx.SecurityHeaders(r.Context()); sh != nil {
+			*sh = h
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type securityWriter struct {
+	http.ResponseWriter
+
+	headers     http.Header
+	tls         bool
+	wroteHeader bool
+}
+
+func (w *securityWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		h := w.ResponseWriter.Header()
+		for k, v := range w.headers {
+			if (k == headers.NameStrictTransportSecurity && !w.tls) || len(h[k]) > 0 {
+				continue
+			}
+			h[k] = v
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Flush implements http.Flusher, so that streamed responses are not buffered
+func (w *securityWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *securityWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
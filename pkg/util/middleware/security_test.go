@@ -0,0 +1,69 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+
+	sh, _ := headers.SecurityHeaders(headers.SecurityPresetStandard, "default-src 'none'")
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headers.NameXFrameOptions, "SAMEORIGIN")
+		w.Write([]byte("ok"))
+	})
+
+	w := httptest.NewRecorder()
+	SecurityHeaders(sh, next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://0/", nil))
+	h := w.Header()
+	if v := h.Get(headers.NameXFrameOptions); v != "SAMEORIGIN" {
+		t.Errorf("expected %s got %s", "SAMEORIGIN", v)
+	}
+	if v := h.Get(headers.NameXContentTypeOptions); v != "nosniff" {
+		t.Errorf("expected %s got %s", "nosniff", v)
+	}
+	if v := h.Get(headers.NameContentSecurityPolicy); v != "default-src 'none'" {
+		t.Errorf("expected %s got %s", "default-src 'none'", v)
+	}
+	if v := h.Get(headers.NameStrictTransportSecurity); v != "" {
+		t.Errorf("expected no HSTS header for a plaintext request got %s", v)
+	}
+
+	// HSTS is added to TLS requests
+	r := httptest.NewRequest(http.MethodGet, "http://0/", nil)
+	r.TLS = &tls.ConnectionState{}
+	w = httptest.NewRecorder()
+	SecurityHeaders(sh, next).ServeHTTP(w, r)
+	if v := w.Header().Get(headers.NameStrictTransportSecurity); v == "" {
+		t.Error("expected HSTS header for a TLS request")
+	}
+
+	// the path's security headers replace the frontend's
+	none, _ := headers.SecurityHeaders(headers.SecurityPresetNone, "")
+	w = httptest.NewRecorder()
+	SecurityHeaders(sh, PathSecurityHeaders(none, next)).ServeHTTP(w,
+		httptest.NewRequest(http.MethodGet, "http://0/", nil))
+	if v := w.Header().Get(headers.NameXContentTypeOptions); v != "" {
+		t.Errorf("expected no security headers got %s", v)
+	}
+}
@@ -19,9 +19,12 @@ package middleware
 import (
 	"net/http"
 
+	tctx "github.com/tricksterproxy/trickster/pkg/proxy/context"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	"github.com/tricksterproxy/trickster/pkg/tracing"
 	tspan "github.com/tricksterproxy/trickster/pkg/tracing/span"
+	"github.com/tricksterproxy/trickster/pkg/util/accesslog"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
 
 	"go.opentelemetry.io/otel/api/kv"
 )
@@ -49,6 +52,28 @@ func Trace(tr *tracing.Tracer, next http.Handler) http.Handler {
 					}...,
 				)
 			}
+			if rsc != nil && rsc.OriginConfig != nil {
+				tspan.SetRequestAttributes(tr, span, r,
+					rsc.OriginConfig.Name, rsc.OriginConfig.OriginType)
+			}
+
+			// include the trace and span IDs in this request's log events, so they
+			// can be joined with the trace, when it is sampled
+			if sc := span.SpanContext(); rsc != nil && rsc.Logger != nil && sc.IsSampled() {
+				rsc.Logger = rsc.Logger.With(tl.Pairs{
+					"trace_id": sc.TraceID.String(),
+					"span_id":  sc.SpanID.String(),
+				})
+			}
+
+			if rec := accesslog.GetRecord(r); rec != nil && span.SpanContext().HasTraceID() {
+				rec.TraceID = span.SpanContext().TraceID.String()
+			}
+
+			// only sampled traces are exported, so only they are useful as exemplars
+			if id := tctx.TraceIDRecorder(r.Context()); id != nil && span.SpanContext().IsSampled() {
+				*id = span.SpanContext().TraceID.String()
+			}
 
 		}
 		next.ServeHTTP(w, r)
@@ -0,0 +1,49 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package pool provides reusable byte slices for serialization and
+// compression buffers on the proxy hot path, to reduce allocation and
+// garbage collection when large responses are being cached
+package pool
+
+import "sync"
+
+// MaxPooledBytes is the largest capacity of byte slice that is returned to
+// the pool; larger slices are left for garbage collection so that a single
+// very large response does not pin its buffer in memory indefinitely
+const MaxPooledBytes = 16 << 20
+
+var bytesPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 4096)
+		return &b
+	},
+}
+
+// GetBytes returns a zero-length byte slice from the pool. The caller must not
+// retain the slice, or any slice derived from it, after returning it with PutBytes
+func GetBytes() []byte {
+	return (*bytesPool.Get().(*[]byte))[:0]
+}
+
+// PutBytes returns a byte slice to the pool for reuse
+func PutBytes(b []byte) {
+	if cap(b) == 0 || cap(b) > MaxPooledBytes {
+		return
+	}
+	b = b[:0]
+	bytesPool.Put(&b)
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pool
+
+import "testing"
+
+func TestBytes(t *testing.T) {
+
+	b := GetBytes()
+	if len(b) != 0 || cap(b) == 0 {
+		t.Errorf("expected empty slice with capacity, got len %d cap %d", len(b), cap(b))
+	}
+	b = append(b, "trickster"...)
+	PutBytes(b)
+
+	b = GetBytes()
+	if len(b) != 0 {
+		t.Errorf("expected %d got %d", 0, len(b))
+	}
+
+	// these are ignored, but must not panic
+	PutBytes(nil)
+	PutBytes(make([]byte, 0, MaxPooledBytes+1))
+}
+
+func BenchmarkBytes(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := GetBytes()
+		buf = append(buf, make([]byte, 8192)...)
+		PutBytes(buf)
+	}
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package promql
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidSelector is returned when a query has a label matchers block that can't be normalized
+var ErrInvalidSelector = errors.New("invalid label matchers in query")
+
+// Normalize returns a canonical form of the query, so that queries that differ only in their
+// whitespace, the order of the label matchers in their selectors, or the case of their keywords
+// and aggregation operators, are equal. Comments are retained, since they may hold
+// instructions for Trickster. The result is only intended for deriving cache keys
+func Normalize(query string) (string, error) {
+
+	var sb strings.Builder
+	sb.Grow(len(query))
+	space := false
+
+	// write writes s, preceded by a single space when whitespace separated it from the
+	// previous token and the space is significant
+	write := func(s string) {
+		if space && sb.Len() > 0 {
+			prev := sb.String()[sb.Len()-1]
+			if !strings.ContainsRune("([{,\n", rune(prev)) && !strings.ContainsRune(")]},([{", rune(s[0])) {
+				sb.WriteByte(' ')
+			}
+		}
+		space = false
+		sb.WriteString(s)
+	}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			i++
+		case c == '"' || c == '\'' || c == '`':
+			j := skipString(query, i)
+			if j < 0 {
+				return "", ErrUnterminated
+			}
+			write(query[i:j])
+			i = j
+		case c == '#':
+			j := strings.IndexByte(query[i:], '\n')
+			if j < 0 {
+				j = len(query) - i
+			}
+			write(strings.TrimRight(query[i:i+j], " \t\r") + "\n")
+			i += j
+		case c == '[':
+			j := strings.IndexByte(query[i:], ']')
+			if j < 0 {
+				return "", ErrUnterminated
+			}
+			write(strings.Join(strings.Fields(query[i:i+j+1]), ""))
+			i += j + 1
+		case c == '{':
+			s, j, err := normalizeSelector(query, i)
+			if err != nil {
+				return "", err
+			}
+			write(s)
+			i = j
+		case isIdentStart(c):
+			j := scanIdent(query, i)
+			ident := query[i:j]
+			lc := strings.ToLower(ident)
+			k := skipSpace(query, j)
+			if keywords[lc] || groupings[lc] || (aggregations[lc] && ((k < len(query) &&
+				query[k] == '(') || identAt(query, k) == "by" || identAt(query, k) == "without")) {
+				ident = lc
+			}
+			write(ident)
+			i = j
+			// the parenthesized label names of a grouping are not selectors
+			if groupings[lc] && k < len(query) && query[k] == '(' {
+				l := strings.IndexByte(query[k:], ')')
+				if l < 0 {
+					return "", ErrUnterminated
+				}
+				labels := strings.Split(query[k+1:k+l], ",")
+				for n := range labels {
+					labels[n] = strings.TrimSpace(labels[n])
+				}
+				space = false
+				write("(" + strings.Join(labels, ",") + ")")
+				i = k + l + 1
+			}
+		case isDigit(c) || (c == '.' && i+1 < len(query) && isDigit(query[i+1])):
+			j := scanNumber(query, i)
+			write(query[i:j])
+			i = j
+		default:
+			write(query[i : i+1])
+			i++
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// normalizeSelector returns the label matchers block starting at query[i] with its
+// matchers sorted and without whitespace, and the index following the block
+func normalizeSelector(query string, i int) (string, int, error) {
+	var matchers []string
+	j := i + 1
+	for {
+		j = skipSpace(query, j)
+		if j >= len(query) {
+			return "", 0, ErrUnterminated
+		}
+		if query[j] == '}' {
+			break
+		}
+		k := scanIdent(query, j)
+		if k == j {
+			return "", 0, ErrInvalidSelector
+		}
+		name := query[j:k]
+		j = skipSpace(query, k)
+		var op string
+		for _, o := range []string{"=~", "!~", "!=", "="} {
+			if strings.HasPrefix(query[j:], o) {
+				op = o
+				break
+			}
+		}
+		if op == "" {
+			return "", 0, ErrInvalidSelector
+		}
+		j = skipSpace(query, j+len(op))
+		if j >= len(query) || (query[j] != '"' && query[j] != '\'' && query[j] != '`') {
+			return "", 0, ErrInvalidSelector
+		}
+		k = skipString(query, j)
+		if k < 0 {
+			return "", 0, ErrUnterminated
+		}
+		matchers = append(matchers, name+op+query[j:k])
+		j = skipSpace(query, k)
+		if j < len(query) && query[j] == ',' {
+			j++
+		}
+	}
+	sort.Strings(matchers)
+	return "{" + strings.Join(matchers, ",") + "}", j + 1, nil
+}
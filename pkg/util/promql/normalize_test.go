@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package promql
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+
+	tests := []struct {
+		query, expected string
+	}{
+		{`up`, `up`},
+		{"  up  {job = \"api\" , instance=~'a.*',}\n", `up{instance=~'a.*',job="api"}`},
+		{`up{instance=~'a.*',job="api"}`, `up{instance=~'a.*',job="api"}`},
+		{`SUM BY (job, instance) (rate(x [ 5m : 1m ]))`, `sum by(job,instance)(rate(x[5m:1m]))`},
+		{`sum by(job,instance)(rate(x[5m:1m]))`, `sum by(job,instance)(rate(x[5m:1m]))`},
+		{`Sum(x) BY (Job)`, `sum(x) by(Job)`},
+		{`a  /  ON(job) Group_Left(instance)  b`, `a / on(job) group_left(instance) b`},
+		{`a AND b Or c UNLESS d > BOOL 1e-3`, `a and b or c unless d > bool 1e-3`},
+		{`x Offset 5m`, `x offset 5m`},
+		{`Sum`, `Sum`},
+		{`{job="}", __name__="up"}`, `{__name__="up",job="}"}`},
+		{`count_values("a  b", x)`, `count_values("a  b",x)`},
+		{"x # trickster-fast-forward:off  \n + y", "x # trickster-fast-forward:off\n+ y"},
+		{`x{}`, `x{}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			q, err := Normalize(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q != test.expected {
+				t.Errorf("expected %s got %s", test.expected, q)
+			}
+		})
+	}
+
+	for _, bad := range []string{`up{job="api"`, `up{job="api}`, `rate(x[5m)`, `"abc`,
+		`sum by (job`, `x{`} {
+		if _, err := Normalize(bad); err != ErrUnterminated {
+			t.Errorf("expected %v for %s got %v", ErrUnterminated, bad, err)
+		}
+	}
+
+	for _, bad := range []string{`up{job}`, `up{job=api}`, `up{=""}`} {
+		if _, err := Normalize(bad); err != ErrInvalidSelector {
+			t.Errorf("expected %v for %s got %v", ErrInvalidSelector, bad, err)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package promql provides functions for rewriting PromQL queries without fully parsing them
+package promql
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrUnterminated is returned when a query has an unterminated string, selector or range
+var ErrUnterminated = errors.New("unterminated string, selector or range in query")
+
+// Matcher is an equality label matcher, like namespace="team-a"
+type Matcher struct {
+	Name  string
+	Value string
+}
+
+// String returns the PromQL representation of the Matcher
+func (m Matcher) String() string {
+	return m.Name + "=" + strconv.Quote(m.Value)
+}
+
+// keywords are the identifiers that are operators or modifiers rather than metric names
+var keywords = map[string]bool{
+	"and": true, "or": true, "unless": true, "atan2": true, "bool": true, "offset": true,
+	"inf": true, "nan": true,
+}
+
+// groupings are the keywords that can be followed by a parenthesized list of label names
+var groupings = map[string]bool{
+	"by": true, "without": true, "on": true, "ignoring": true,
+	"group_left": true, "group_right": true,
+}
+
+// aggregations are the aggregation operators, which can be followed by a grouping
+// before their parenthesized arguments
+var aggregations = map[string]bool{
+	"sum": true, "min": true, "max": true, "avg": true, "group": true, "stddev": true,
+	"stdvar": true, "count": true, "count_values": true, "bottomk": true, "topk": true,
+	"quantile": true,
+}
+
+// InjectMatchers adds the matchers to every vector selector in the query, so that the query
+// only selects series having those labels. Any matchers already in a selector are retained,
+// so a selector that conflicts with an injected matcher selects no series.
+func InjectMatchers(query string, matchers []Matcher) (string, error) {
+	if len(matchers) == 0 {
+		return query, nil
+	}
+	parts := make([]string, len(matchers))
+	for i, m := range matchers {
+		parts[i] = m.String()
+	}
+	inj := strings.Join(parts, ",")
+
+	var sb strings.Builder
+	sb.Grow(len(query) + 2*len(inj))
+	var err error
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			j := skipString(query, i)
+			if j < 0 {
+				return "", ErrUnterminated
+			}
+			sb.WriteString(query[i:j])
+			i = j
+		case c == '#':
+			j := strings.IndexByte(query[i:], '\n')
+			if j < 0 {
+				j = len(query) - i
+			}
+			sb.WriteString(query[i : i+j])
+			i += j
+		case c == '[':
+			// ranges and subqueries hold only durations
+			j := strings.IndexByte(query[i:], ']')
+			if j < 0 {
+				return "", ErrUnterminated
+			}
+			sb.WriteString(query[i : i+j+1])
+			i += j + 1
+		case c == '{':
+			if i, err = injectSelector(&sb, query, i, inj); err != nil {
+				return "", err
+			}
+		case isIdentStart(c):
+			j := scanIdent(query, i)
+			ident := strings.ToLower(query[i:j])
+			sb.WriteString(query[i:j])
+			k := skipSpace(query, j)
+			switch {
+			case groupings[ident]:
+				// the parenthesized label names of a grouping are not selectors
+				i = j
+				if k < len(query) && query[k] == '(' {
+					l := strings.IndexByte(query[k:], ')')
+					if l < 0 {
+						return "", ErrUnterminated
+					}
+					sb.WriteString(query[j : k+l+1])
+					i = k + l + 1
+				}
+			case keywords[ident] || (k < len(query) && query[k] == '(') ||
+				(aggregations[ident] && (identAt(query, k) == "by" || identAt(query, k) == "without")):
+				// operators, functions and aggregations
+				i = j
+			case k < len(query) && query[k] == '{':
+				sb.WriteString(query[j:k])
+				if i, err = injectSelector(&sb, query, k, inj); err != nil {
+					return "", err
+				}
+			default:
+				sb.WriteString("{" + inj + "}")
+				i = j
+			}
+		case isDigit(c) || (c == '.' && i+1 < len(query) && isDigit(query[i+1])):
+			j := scanNumber(query, i)
+			sb.WriteString(query[i:j])
+			i = j
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return sb.String(), nil
+}
+
+// injectSelector writes the label matchers block starting at query[i] with the injected
+// matchers appended to it, and returns the index following the block
+func injectSelector(sb *strings.Builder, query string, i int, inj string) (int, error) {
+	j := i + 1
+	for j < len(query) && query[j] != '}' {
+		if c := query[j]; c == '"' || c == '\'' || c == '`' {
+			if j = skipString(query, j); j < 0 {
+				return 0, ErrUnterminated
+			}
+			continue
+		}
+		j++
+	}
+	if j == len(query) {
+		return 0, ErrUnterminated
+	}
+	inner := query[i+1 : j]
+	sb.WriteByte('{')
+	sb.WriteString(inner)
+	if t := strings.TrimSpace(inner); t != "" && !strings.HasSuffix(t, ",") {
+		sb.WriteByte(',')
+	}
+	sb.WriteString(inj)
+	sb.WriteByte('}')
+	return j + 1, nil
+}
+
+// skipString returns the index following the quoted string starting at query[i],
+// or -1 if the string is unterminated
+func skipString(query string, i int) int {
+	q := query[i]
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			if q != '`' {
+				j++
+			}
+		case q:
+			return j + 1
+		}
+	}
+	return -1
+}
+
+func scanIdent(query string, i int) int {
+	for i < len(query) && (isIdentStart(query[i]) || isDigit(query[i])) {
+		i++
+	}
+	return i
+}
+
+// scanNumber returns the index following the number or duration starting at query[i]
+func scanNumber(query string, i int) int {
+	j := i + 1
+	for j < len(query) {
+		c := query[j]
+		if isIdentStart(c) || isDigit(c) || c == '.' ||
+			((c == '+' || c == '-') && (query[j-1] == 'e' || query[j-1] == 'E') &&
+				!strings.HasPrefix(query[i:], "0x")) {
+			j++
+			continue
+		}
+		break
+	}
+	return j
+}
+
+// identAt returns the lowercase identifier starting at query[i], if any
+func identAt(query string, i int) string {
+	if i >= len(query) || !isIdentStart(query[i]) {
+		return ""
+	}
+	return strings.ToLower(query[i:scanIdent(query, i)])
+}
+
+func skipSpace(query string, i int) int {
+	for i < len(query) && (query[i] == ' ' || query[i] == '\t' || query[i] == '\n' ||
+		query[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package promql
+
+import (
+	"testing"
+)
+
+func TestMatcherString(t *testing.T) {
+	m := Matcher{Name: "namespace", Value: `team-"a"`}
+	expected := `namespace="team-\"a\""`
+	if m.String() != expected {
+		t.Errorf("expected %s got %s", expected, m.String())
+	}
+}
+
+func TestInjectMatchers(t *testing.T) {
+
+	ms := []Matcher{{Name: "ns", Value: "a"}}
+
+	tests := []struct {
+		query, expected string
+	}{
+		{`up`, `up{ns="a"}`},
+		{`up{}`, `up{ns="a"}`},
+		{`up {job="api"}`, `up {job="api",ns="a"}`},
+		{`up{job="api",}`, `up{job="api",ns="a"}`},
+		{`{__name__=~"up|down"}`, `{__name__=~"up|down",ns="a"}`},
+		{`{job="}"}`, `{job="}",ns="a"}`},
+		{`rate(http_requests_total{code=~"5.."}[5m])`,
+			`rate(http_requests_total{code=~"5..",ns="a"}[5m])`},
+		{`sum by (job, instance) (rate(x[5m:1m]))`, `sum by (job, instance) (rate(x{ns="a"}[5m:1m]))`},
+		{`sum without(job) (x)`, `sum without(job) (x{ns="a"})`},
+		{`sum(x) by (job)`, `sum(x{ns="a"}) by (job)`},
+		{`a / on(job) group_left(instance) b`, `a{ns="a"} / on(job) group_left(instance) b{ns="a"}`},
+		{`a and ignoring (x) b or c unless d`,
+			`a{ns="a"} and ignoring (x) b{ns="a"} or c{ns="a"} unless d{ns="a"}`},
+		{`a > bool 1e-3`, `a{ns="a"} > bool 1e-3`},
+		{`a offset 5m + b @ start()`, `a{ns="a"} offset 5m + b{ns="a"} @ start()`},
+		{`topk(5, x) * 0x1F - .5 + Inf + nan`, `topk(5, x{ns="a"}) * 0x1F - .5 + Inf + nan`},
+		{`label_replace(up, "dst", "$1", 'src', "(.*)")`,
+			`label_replace(up{ns="a"}, "dst", "$1", 'src', "(.*)")`},
+		{"count_values(`v\\`, x) # a comment {\nfoo:bar", "count_values(`v\\`, x{ns=\"a\"}) # a comment {\nfoo:bar{ns=\"a\"}"},
+		{`"a string with up in it"`, `"a string with up in it"`},
+		{`group(x)`, `group(x{ns="a"})`},
+		{`group`, `group{ns="a"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			q, err := InjectMatchers(test.query, ms)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q != test.expected {
+				t.Errorf("expected %s got %s", test.expected, q)
+			}
+		})
+	}
+
+	q, err := InjectMatchers("up", []Matcher{{"a", "1"}, {"b", "2"}})
+	if err != nil {
+		t.Error(err)
+	}
+	if q != `up{a="1",b="2"}` {
+		t.Errorf("expected %s got %s", `up{a="1",b="2"}`, q)
+	}
+
+	q, err = InjectMatchers("up", nil)
+	if err != nil || q != "up" {
+		t.Errorf("expected up got %s %v", q, err)
+	}
+
+	for _, bad := range []string{`up{job="api"`, `up{job="api}`, `rate(x[5m)`, `"abc`,
+		`sum by (job`, `x{`} {
+		if _, err := InjectMatchers(bad, ms); err != ErrUnterminated {
+			t.Errorf("expected %v for %s got %v", ErrUnterminated, bad, err)
+		}
+	}
+}
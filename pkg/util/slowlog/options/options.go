@@ -0,0 +1,55 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options provides options for the slow query log
+package options
+
+// Supported slow query log outputs, other than a file path
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+)
+
+// Options is a collection of configurations for the slow query log
+type Options struct {
+	// Output is where slow query records are written: 'stdout', 'stderr' or a file path.
+	// The slow query log is only enabled when an Output is configured, and records are only
+	// written for origins with a slow_log_threshold_ms
+	Output string `toml:"output"`
+}
+
+// NewOptions returns a new Options reference with Default Values set
+func NewOptions() *Options {
+	return &Options{}
+}
+
+// Clone returns an exact copy of the subject *Options
+func (o *Options) Clone() *Options {
+	return &Options{Output: o.Output}
+}
+
+// Enabled returns true when the slow query log should be written
+func (o *Options) Enabled() bool {
+	return o != nil && o.Output != ""
+}
+
+// Equal returns true when the subject and o2 have the same values
+func (o *Options) Equal(o2 *Options) bool {
+	if o == nil || o2 == nil {
+		return o == o2
+	}
+	return o.Output == o2.Output
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package slowlog writes a structured JSON record for each origin fetch that takes
+// longer than its origin's slow_log_threshold_ms, so that operators can find the
+// queries (and the dashboards issuing them) that are most expensive for the origin
+package slowlog
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/util/slowlog/options"
+
+	lumberjack "gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Record is the slow query log record of a single origin fetch
+type Record struct {
+	Time        time.Time
+	Origin      string
+	OriginType  string
+	Query       string
+	Start       time.Time
+	End         time.Time
+	Step        time.Duration
+	Duration    time.Duration
+	Threshold   time.Duration
+	Bytes       int
+	Status      int
+	CacheStatus string
+	TraceID     string
+}
+
+// jsonRecord is the encoded form of a Record
+type jsonRecord struct {
+	Time        string  `json:"time"`
+	Origin      string  `json:"origin"`
+	OriginType  string  `json:"origin_type"`
+	Query       string  `json:"query"`
+	Start       string  `json:"start,omitempty"`
+	End         string  `json:"end,omitempty"`
+	StepSecs    float64 `json:"step_secs"`
+	DurationMS  float64 `json:"duration_ms"`
+	ThresholdMS int64   `json:"threshold_ms"`
+	Bytes       int     `json:"bytes"`
+	Status      int     `json:"status"`
+	CacheStatus string  `json:"cache_status"`
+	TraceID     string  `json:"trace_id,omitempty"`
+}
+
+// Logger writes slow query records to its output
+type Logger struct {
+	w      io.Writer
+	closer io.Closer
+	mtx    sync.Mutex
+}
+
+// New returns a new Logger for the provided options
+func New(o *options.Options) *Logger {
+	l := &Logger{}
+	switch o.Output {
+	case options.OutputStdout, "":
+		l.w = os.Stdout
+	case options.OutputStderr:
+		l.w = os.Stderr
+	default:
+		lj := &lumberjack.Logger{
+			Filename:   o.Output,
+			MaxSize:    256,  // megabytes
+			MaxBackups: 80,   // 256 megs @ 80 backups is 20GB of Logs
+			MaxAge:     7,    // days
+			Compress:   true, // Compress Rolled Backups
+		}
+		l.w = lj
+		l.closer = lj
+	}
+	return l
+}
+
+// Close closes the Logger's output file, if any
+func (l *Logger) Close() error {
+	if l == nil || l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// Log writes the Record to the slow query log as a single line of JSON
+func (l *Logger) Log(rec *Record) error {
+	b, err := json.Marshal(encode(rec))
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	l.mtx.Lock()
+	_, err = l.w.Write(b)
+	l.mtx.Unlock()
+	return err
+}
+
+func encode(rec *Record) *jsonRecord {
+	jr := &jsonRecord{
+		Time:        rec.Time.UTC().Format(time.RFC3339Nano),
+		Origin:      rec.Origin,
+		OriginType:  rec.OriginType,
+		Query:       rec.Query,
+		StepSecs:    rec.Step.Seconds(),
+		DurationMS:  float64(rec.Duration) / float64(time.Millisecond),
+		ThresholdMS: rec.Threshold.Milliseconds(),
+		Bytes:       rec.Bytes,
+		Status:      rec.Status,
+		CacheStatus: rec.CacheStatus,
+		TraceID:     rec.TraceID,
+	}
+	if !rec.Start.IsZero() {
+		jr.Start = rec.Start.UTC().Format(time.RFC3339)
+	}
+	if !rec.End.IsZero() {
+		jr.End = rec.End.UTC().Format(time.RFC3339)
+	}
+	return jr
+}
+
+var active *Logger
+var activeMtx sync.RWMutex
+
+// SetLogger sets the Logger used by Log, and returns the previously set Logger so
+// that the caller can close it. A nil Logger disables the slow query log
+func SetLogger(l *Logger) *Logger {
+	activeMtx.Lock()
+	prev := active
+	active = l
+	activeMtx.Unlock()
+	return prev
+}
+
+// Enabled returns true when a Logger has been set
+func Enabled() bool {
+	activeMtx.RLock()
+	defer activeMtx.RUnlock()
+	return active != nil
+}
+
+// Log writes the Record with the Logger set by SetLogger, if any
+func Log(rec *Record) error {
+	activeMtx.RLock()
+	l := active
+	activeMtx.RUnlock()
+	if l == nil {
+		return nil
+	}
+	return l.Log(rec)
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package slowlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/util/slowlog/options"
+)
+
+func TestNew(t *testing.T) {
+
+	o := options.NewOptions()
+	o.Output = options.OutputStdout
+	if l := New(o); l.w != os.Stdout {
+		t.Error("expected stdout writer")
+	}
+
+	o.Output = options.OutputStderr
+	l := New(o)
+	if l.w != os.Stderr {
+		t.Error("expected stderr writer")
+	}
+	if err := l.Close(); err != nil {
+		t.Error(err)
+	}
+
+	td, err := ioutil.TempDir("", "trickster-slowlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(td)
+	o.Output = filepath.Join(td, "slow.log")
+	l = New(o)
+	if err = l.Log(&Record{Status: 200}); err != nil {
+		t.Error(err)
+	}
+	if err = l.Close(); err != nil {
+		t.Error(err)
+	}
+	b, err := ioutil.ReadFile(o.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"status":200`) {
+		t.Errorf("unexpected log content: %s", string(b))
+	}
+}
+
+func TestLog(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+	l := &Logger{w: buf}
+
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	rec := &Record{
+		Time:        now,
+		Origin:      "prom1",
+		OriginType:  "prometheus",
+		Query:       "sum(rate(http_requests_total[5m]))",
+		Start:       now.Add(-time.Hour),
+		End:         now,
+		Step:        time.Minute,
+		Duration:    1500 * time.Millisecond,
+		Threshold:   time.Second,
+		Bytes:       2048,
+		Status:      200,
+		CacheStatus: "phit",
+	}
+	if err := l.Log(rec); err != nil {
+		t.Fatal(err)
+	}
+
+	m := make(map[string]interface{})
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"time":         "2020-06-01T12:00:00Z",
+		"origin":       "prom1",
+		"origin_type":  "prometheus",
+		"query":        "sum(rate(http_requests_total[5m]))",
+		"start":        "2020-06-01T11:00:00Z",
+		"end":          "2020-06-01T12:00:00Z",
+		"step_secs":    float64(60),
+		"duration_ms":  float64(1500),
+		"threshold_ms": float64(1000),
+		"bytes":        float64(2048),
+		"status":       float64(200),
+		"cache_status": "phit",
+	}
+	for k, v := range expected {
+		if m[k] != v {
+			t.Errorf("expected %v for %s got %v", v, k, m[k])
+		}
+	}
+	if _, ok := m["trace_id"]; ok {
+		t.Error("expected empty trace_id to be omitted")
+	}
+}
+
+func TestSetLogger(t *testing.T) {
+
+	if Enabled() {
+		t.Error("expected disabled")
+	}
+	// Log is a no-op when no Logger is set
+	if err := Log(&Record{}); err != nil {
+		t.Error(err)
+	}
+
+	buf := &bytes.Buffer{}
+	l := &Logger{w: buf}
+	if prev := SetLogger(l); prev != nil {
+		t.Error("expected nil previous logger")
+	}
+	if !Enabled() {
+		t.Error("expected enabled")
+	}
+	if err := Log(&Record{Origin: "prom1"}); err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(buf.String(), `"origin":"prom1"`) {
+		t.Errorf("unexpected log content: %s", buf.String())
+	}
+	if prev := SetLogger(nil); prev != l {
+		t.Error("expected previous logger")
+	}
+}